@@ -6697,10 +6697,10 @@ def go_deps():
         name = "com_github_nats_io_nats_go",
         build_file_proto_mode = "disable_global",
         importpath = "github.com/nats-io/nats.go",
-        sha256 = "34a735d158d70685faad1fc3153f08da0ddc21c0ae42f6a0cb09430d638364b2",
-        strip_prefix = "github.com/nats-io/nats.go@v1.9.1",
+        sha256 = "714627fb143f8b2e9ab670137e2d01e5dc3a33363ce9f8aaae02bc45a11bb28c",
+        strip_prefix = "github.com/nats-io/nats.go@v1.11.0",
         urls = [
-            "https://storage.googleapis.com/cockroach-godeps/gomod/github.com/nats-io/nats.go/com_github_nats_io_nats_go-v1.9.1.zip",
+            "https://storage.googleapis.com/cockroach-godeps/gomod/github.com/nats-io/nats.go/com_github_nats_io_nats_go-v1.11.0.zip",
         ],
     )
     go_repository(
@@ -6717,10 +6717,10 @@ def go_deps():
         name = "com_github_nats_io_nkeys",
         build_file_proto_mode = "disable_global",
         importpath = "github.com/nats-io/nkeys",
-        sha256 = "291930a7abcd84edcaffc2cadc75aeb830ebf561144313ead6b8e1fcc03b124f",
-        strip_prefix = "github.com/nats-io/nkeys@v0.1.3",
+        sha256 = "9383fa98356bb67ba1110814918e9997fdbcb83c08ffd6902b5aed7b9d96dfa2",
+        strip_prefix = "github.com/nats-io/nkeys@v0.3.0",
         urls = [
-            "https://storage.googleapis.com/cockroach-godeps/gomod/github.com/nats-io/nkeys/com_github_nats_io_nkeys-v0.1.3.zip",
+            "https://storage.googleapis.com/cockroach-godeps/gomod/github.com/nats-io/nkeys/com_github_nats_io_nkeys-v0.3.0.zip",
         ],
     )
     go_repository(
@@ -7580,6 +7580,16 @@ def go_deps():
             "https://storage.googleapis.com/cockroach-godeps/gomod/github.com/PuerkitoBio/urlesc/com_github_puerkitobio_urlesc-v0.0.0-20170810143723-de5bf2ad4578.zip",
         ],
     )
+    go_repository(
+        name = "com_github_rabbitmq_amqp091_go",
+        build_file_proto_mode = "disable_global",
+        importpath = "github.com/rabbitmq/amqp091-go",
+        sha256 = "6c8d2d766389a1bea060043166337caa552acb2e22cdb67c58c75666deb94c30",
+        strip_prefix = "github.com/rabbitmq/amqp091-go@v1.5.0",
+        urls = [
+            "https://storage.googleapis.com/cockroach-godeps/gomod/github.com/rabbitmq/amqp091-go/com_github_rabbitmq_amqp091_go-v1.5.0.zip",
+        ],
+    )
     go_repository(
         name = "com_github_rcrowley_go_metrics",
         build_file_proto_mode = "disable_global",
@@ -14,14 +14,19 @@ import (
 	"path"
 	"strings"
 
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
 	"github.com/cockroachdb/cockroach/pkg/featureflag"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
 	"github.com/cockroachdb/cockroach/pkg/sql/exprutil"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/errors"
 )
 
@@ -41,9 +46,45 @@ func alterBackupTypeCheck(
 	); err != nil {
 		return false, nil, err
 	}
+	if compact := getAlterBackupCompact(alterBackupStmt); compact != nil {
+		if err := exprutil.TypeCheck(
+			ctx, "ALTER BACKUP", p.SemaCtx(),
+			exprutil.Strings{compact.Options.EncryptionPassphrase},
+			exprutil.StringArrays{tree.Exprs(compact.Options.EncryptionKMSURI)},
+			exprutil.Bools{compact.Options.CaptureRevisionHistory},
+		); err != nil {
+			return false, nil, err
+		}
+		return true, alterBackupCompactHeader, nil
+	}
+	for _, cmd := range alterBackupStmt.Cmds {
+		if _, ok := cmd.(*tree.AlterBackupDeleteUnreferencedFiles); ok {
+			return true, alterBackupDeleteUnreferencedFilesHeader, nil
+		}
+	}
 	return true, nil, nil
 }
 
+// alterBackupCompactHeader is the header of the result of ALTER BACKUP ...
+// COMPACT, which describes the compaction job and the backup it wrote.
+var alterBackupCompactHeader = colinfo.ResultColumns{
+	{Name: "job_id", Typ: types.Int},
+	{Name: "path", Typ: types.String},
+	{Name: "rows", Typ: types.Int},
+	{Name: "index_entries", Typ: types.Int},
+	{Name: "bytes", Typ: types.Int},
+}
+
+// getAlterBackupCompact returns the COMPACT command of the statement, if any.
+func getAlterBackupCompact(stmt *tree.AlterBackup) *tree.AlterBackupCompact {
+	for _, cmd := range stmt.Cmds {
+		if v, ok := cmd.(*tree.AlterBackupCompact); ok {
+			return v
+		}
+	}
+	return nil
+}
+
 func alterBackupPlanHook(
 	ctx context.Context, stmt tree.Statement, p sql.PlanHookState,
 ) (sql.PlanHookRowFn, colinfo.ResultColumns, []sql.PlanNode, bool, error) {
@@ -77,9 +118,15 @@ func alterBackupPlanHook(
 
 	var newKms []string
 	var oldKms []string
+	var compact *tree.AlterBackupCompact
+	var deleteUnreferencedFiles bool
 
 	for _, cmd := range alterBackupStmt.Cmds {
 		switch v := cmd.(type) {
+		case *tree.AlterBackupCompact:
+			compact = v
+		case *tree.AlterBackupDeleteUnreferencedFiles:
+			deleteUnreferencedFiles = true
 		case *tree.AlterBackupKMS:
 			newKms, err = exprEval.StringArray(ctx, tree.Exprs(v.KMSInfo.NewKMSURI))
 			if err != nil {
@@ -92,6 +139,26 @@ func alterBackupPlanHook(
 		}
 	}
 
+	if compact != nil {
+		if len(alterBackupStmt.Cmds) > 1 {
+			return nil, nil, nil, false, errors.New("COMPACT cannot be combined with other ALTER BACKUP commands")
+		}
+		if subdir == "" {
+			return nil, nil, nil, false, errors.New("COMPACT requires a backup collection to be specified with IN")
+		}
+		return alterBackupCompactPlanHook(ctx, p, compact, backup, subdir)
+	}
+
+	if deleteUnreferencedFiles {
+		if len(alterBackupStmt.Cmds) > 1 {
+			return nil, nil, nil, false, errors.New("DELETE UNREFERENCED FILES cannot be combined with other ALTER BACKUP commands")
+		}
+		if subdir != "" {
+			return nil, nil, nil, false, errors.New("DELETE UNREFERENCED FILES applies to a whole backup collection and cannot be used with IN")
+		}
+		return alterBackupDeleteUnreferencedFilesPlanHook(p, backup)
+	}
+
 	fn := func(ctx context.Context, _ []sql.PlanNode, resultsCh chan<- tree.Datums) error {
 
 		if subdir != "" {
@@ -125,6 +192,117 @@ func alterBackupPlanHook(
 	return fn, nil, nil, false, nil
 }
 
+// alterBackupCompactPlanHook plans ALTER BACKUP ... COMPACT, which runs a job
+// that merges the full backup in the given subdirectory of the collection and
+// its incremental backups into a new full backup in the collection.
+func alterBackupCompactPlanHook(
+	ctx context.Context,
+	p sql.PlanHookState,
+	compact *tree.AlterBackupCompact,
+	collection string,
+	subdir string,
+) (sql.PlanHookRowFn, colinfo.ResultColumns, []sql.PlanNode, bool, error) {
+	exprEval := p.ExprEvaluator("ALTER BACKUP")
+
+	var revisionHistory bool
+	if compact.Options.CaptureRevisionHistory != nil {
+		var err error
+		revisionHistory, err = exprEval.Bool(ctx, compact.Options.CaptureRevisionHistory)
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
+	encryptionParams := jobspb.BackupEncryptionOptions{
+		Mode: jobspb.EncryptionMode_None,
+	}
+	if compact.Options.EncryptionPassphrase != nil {
+		pw, err := exprEval.String(ctx, compact.Options.EncryptionPassphrase)
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+		encryptionParams.Mode = jobspb.EncryptionMode_Passphrase
+		encryptionParams.RawPassphrase = pw
+	}
+	if compact.Options.EncryptionKMSURI != nil {
+		if encryptionParams.Mode != jobspb.EncryptionMode_None {
+			return nil, nil, nil, false,
+				errors.New("cannot have both encryption_passphrase and kms option set")
+		}
+		kms, err := exprEval.StringArray(ctx, tree.Exprs(compact.Options.EncryptionKMSURI))
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+		encryptionParams.Mode = jobspb.EncryptionMode_KMS
+		encryptionParams.RawKmsUris = kms
+	}
+
+	fn := func(ctx context.Context, _ []sql.PlanNode, resultsCh chan<- tree.Datums) error {
+		if !p.ExtendedEvalContext().TxnIsSingleStmt {
+			return errors.New("ALTER BACKUP ... COMPACT cannot be used inside a multi-statement transaction")
+		}
+		execCfg := p.ExecCfg()
+		mkStore := execCfg.DistSQLSrv.ExternalStorageFromURI
+		if strings.EqualFold(subdir, backupbase.LatestFileName) {
+			latest, err := backupdest.ReadLatestFile(ctx, collection, mkStore, p.User())
+			if err != nil {
+				return err
+			}
+			subdir = latest
+		}
+		fullyResolvedDest, err := backuputils.AppendPaths([]string{collection}, subdir)
+		if err != nil {
+			return err
+		}
+
+		kmsEnv := backupencryption.MakeBackupKMSEnv(
+			execCfg.Settings, &execCfg.ExternalIODirConfig, execCfg.InternalDB, p.User(),
+		)
+		encryption, err := backupencryption.GetEncryptionFromBase(
+			ctx, p.User(), mkStore, fullyResolvedDest[0], encryptionParams, &kmsEnv,
+		)
+		if err != nil {
+			return err
+		}
+
+		record, err := compactionJobRecord(p.User(), collection, subdir, encryption, revisionHistory)
+		if err != nil {
+			return err
+		}
+		jobID := execCfg.JobRegistry.MakeJobID()
+		var sj *jobs.StartableJob
+		if err := func() (err error) {
+			defer func() {
+				if err == nil || sj == nil {
+					return
+				}
+				if cleanupErr := sj.CleanupOnRollback(ctx); cleanupErr != nil {
+					log.Errorf(ctx, "failed to cleanup job: %v", cleanupErr)
+				}
+			}()
+			if err := execCfg.JobRegistry.CreateStartableJobWithTxn(
+				ctx, &sj, jobID, p.InternalSQLTxn(), record,
+			); err != nil {
+				return err
+			}
+			// We commit the transaction here so that the job can be started. This
+			// is safe because we're in an implicit transaction.
+			return p.Txn().Commit(ctx)
+		}(); err != nil {
+			return err
+		}
+		p.InternalSQLTxn().Descriptors().ReleaseAll(ctx)
+		if err := sj.Start(ctx); err != nil {
+			return err
+		}
+		if err := sj.AwaitCompletion(ctx); err != nil {
+			return err
+		}
+		return sj.ReportExecutionResults(ctx, resultsCh)
+	}
+	return fn, alterBackupCompactHeader, nil, false, nil
+}
+
 func doAlterBackupPlan(
 	ctx context.Context,
 	alterBackupStmt *tree.AlterBackup,
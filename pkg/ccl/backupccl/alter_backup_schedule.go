@@ -463,6 +463,7 @@ func processFullBackupRecurrence(
 			s.fullArgs.UpdatesLastBackupMetric,
 			s.incStmt,
 			s.fullArgs.ChainProtectedTimestampRecords,
+			0, /* compactAfterIncrementals */
 		)
 
 		if err != nil {
@@ -11,11 +11,14 @@ package backupccl
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/security/username"
 	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/testutils/jobutils"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/stretchr/testify/require"
@@ -210,3 +213,120 @@ func TestAlterBackupIncremental(t *testing.T) {
 	sqlDB.Exec(t, query)
 	sqlDB.ExecRowsAffected(t, 2, "SELECT * FROM bank")
 }
+
+// TestAlterBackupCompact tests that a full backup and its incremental backups
+// can be compacted into a new full backup that restores the same data.
+func TestAlterBackupCompact(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	for _, tc := range []struct {
+		name    string
+		dest    string
+		options string
+	}{
+		{name: "unencrypted", dest: "userfile:///a"},
+		{name: "passphrase", dest: "userfile:///b", options: " WITH encryption_passphrase = 'abc'"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB.Exec(t, "CREATE DATABASE "+tc.name)
+			defer sqlDB.Exec(t, "DROP DATABASE "+tc.name)
+
+			sqlDB.Exec(t, "BACKUP TABLE data.bank INTO $1"+tc.options, tc.dest)
+			sqlDB.Exec(t, "DELETE FROM data.bank WHERE id < 3")
+			sqlDB.Exec(t, "BACKUP TABLE data.bank INTO LATEST IN $1"+tc.options, tc.dest)
+			sqlDB.Exec(t, "UPDATE data.bank SET balance = balance + 1 WHERE id >= 5")
+			sqlDB.Exec(t, "UPSERT INTO data.bank VALUES (100, 100, 'new')")
+			sqlDB.Exec(t, "BACKUP TABLE data.bank INTO LATEST IN $1"+tc.options, tc.dest)
+			expected := sqlDB.QueryStr(t, "SELECT * FROM data.bank ORDER BY id")
+
+			var path string
+			var rows int
+			sqlDB.QueryRow(t, "ALTER BACKUP LATEST IN $1 COMPACT"+tc.options, tc.dest).Scan(
+				new(int), &path, &rows, new(int), new(int))
+			require.Equal(t, len(expected), rows)
+
+			// The compacted backup is a new full backup in the collection, and
+			// LATEST points to it.
+			backups := sqlDB.QueryStr(t, "SHOW BACKUPS IN $1", tc.dest)
+			require.Len(t, backups, 2)
+			require.Equal(t, path, backups[1][0])
+			sqlDB.ExpectErr(t, "no incremental backups to compact",
+				"ALTER BACKUP LATEST IN $1 COMPACT"+tc.options, tc.dest)
+
+			sqlDB.Exec(t, "RESTORE TABLE data.bank FROM LATEST IN $1 WITH into_db = $2"+
+				strings.Replace(tc.options, " WITH", ",", 1), tc.dest, tc.name)
+			sqlDB.CheckQueryResults(t, fmt.Sprintf("SELECT * FROM %s.bank ORDER BY id", tc.name), expected)
+		})
+	}
+
+	t.Run("revision-history", func(t *testing.T) {
+		const dest = "userfile:///c"
+		sqlDB.Exec(t, "CREATE DATABASE rev")
+
+		sqlDB.Exec(t, "BACKUP TABLE data.bank INTO $1 WITH revision_history", dest)
+		sqlDB.Exec(t, "UPDATE data.bank SET balance = 7 WHERE id = 5")
+		var ts string
+		sqlDB.QueryRow(t, "SELECT cluster_logical_timestamp()").Scan(&ts)
+		expected := sqlDB.QueryStr(t, "SELECT * FROM data.bank ORDER BY id")
+		sqlDB.Exec(t, "DELETE FROM data.bank WHERE id = 5")
+		sqlDB.Exec(t, "BACKUP TABLE data.bank INTO LATEST IN $1 WITH revision_history", dest)
+
+		sqlDB.Exec(t, "ALTER BACKUP LATEST IN $1 COMPACT WITH revision_history", dest)
+
+		// The revision that was overwritten between the backups can be restored
+		// from the compacted backup.
+		sqlDB.Exec(t, fmt.Sprintf(
+			"RESTORE TABLE data.bank FROM LATEST IN $1 AS OF SYSTEM TIME %s WITH into_db = 'rev'", ts), dest)
+		sqlDB.CheckQueryResults(t, "SELECT * FROM rev.bank ORDER BY id", expected)
+	})
+
+	t.Run("pause-resume", func(t *testing.T) {
+		const dest = "userfile:///e"
+		sqlDB.Exec(t, "CREATE DATABASE paused")
+
+		sqlDB.Exec(t, "BACKUP TABLE data.bank INTO $1", dest)
+		sqlDB.Exec(t, "UPDATE data.bank SET balance = balance + 1 WHERE id % 2 = 0")
+		sqlDB.Exec(t, "BACKUP TABLE data.bank INTO LATEST IN $1", dest)
+		expected := sqlDB.QueryStr(t, "SELECT * FROM data.bank ORDER BY id")
+
+		// Flush and checkpoint after every entry, and pause the compaction job
+		// once it wrote its first checkpoint.
+		sqlDB.Exec(t, "SET CLUSTER SETTING bulkio.backup.file_size = '1'")
+		sqlDB.Exec(t, "SET CLUSTER SETTING bulkio.backup.checkpoint_interval = '0s'")
+		defer sqlDB.Exec(t, "RESET CLUSTER SETTING bulkio.backup.file_size")
+		defer sqlDB.Exec(t, "RESET CLUSTER SETTING bulkio.backup.checkpoint_interval")
+		sqlDB.Exec(t, "SET CLUSTER SETTING jobs.debug.pausepoints = 'backup_compaction.after.write_checkpoint'")
+		sqlDB.ExpectErr(t, "pause", "ALTER BACKUP LATEST IN $1 COMPACT", dest)
+
+		var jobID jobspb.JobID
+		var fraction float64
+		sqlDB.QueryRow(t, `SELECT job_id, fraction_completed FROM [SHOW JOBS]
+WHERE job_type = 'BACKUP' AND description LIKE 'ALTER BACKUP%COMPACT'`).Scan(&jobID, &fraction)
+		jobutils.WaitForJobToPause(t, sqlDB, jobID)
+		require.Greater(t, fraction, 0.0)
+
+		// The compaction continues from its checkpoint once the job is resumed.
+		sqlDB.Exec(t, "SET CLUSTER SETTING jobs.debug.pausepoints = ''")
+		sqlDB.Exec(t, "RESUME JOB $1", jobID)
+		jobutils.WaitForJobToSucceed(t, sqlDB, jobID)
+		require.Len(t, sqlDB.QueryStr(t, "SHOW BACKUPS IN $1", dest), 2)
+
+		sqlDB.Exec(t, "RESTORE TABLE data.bank FROM LATEST IN $1 WITH into_db = 'paused'", dest)
+		sqlDB.CheckQueryResults(t, "SELECT * FROM paused.bank ORDER BY id", expected)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		sqlDB.ExpectErr(t, "COMPACT requires a backup collection",
+			"ALTER BACKUP 'userfile:///a' COMPACT")
+		sqlDB.Exec(t, "BACKUP TABLE data.bank INTO 'userfile:///d'")
+		sqlDB.Exec(t, "BACKUP TABLE data.bank INTO LATEST IN 'userfile:///d'")
+		sqlDB.ExpectErr(t, "the backups were not all taken with revision_history",
+			"ALTER BACKUP LATEST IN 'userfile:///d' COMPACT WITH revision_history")
+	})
+}
@@ -0,0 +1,336 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudprivilege"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/exprutil"
+	"github.com/cockroachdb/cockroach/pkg/sql/isql"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/catconstants"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/errors"
+)
+
+const attachBackupStmtName = "ATTACH BACKUP"
+
+func attachBackupTypeCheck(
+	ctx context.Context, stmt tree.Statement, p sql.PlanHookState,
+) (matched bool, header colinfo.ResultColumns, _ error) {
+	attach, ok := stmt.(*tree.AttachBackup)
+	if !ok {
+		return false, nil, nil
+	}
+	if err := exprutil.TypeCheck(ctx, attachBackupStmtName, p.SemaCtx(),
+		exprutil.Strings{
+			attach.Subdir,
+			attach.Options.EncryptionPassphrase,
+		},
+		exprutil.StringArrays{
+			tree.Exprs(attach.InCollection),
+			tree.Exprs(attach.Options.IncrementalStorage),
+			tree.Exprs(attach.Options.DecryptionKMSURI),
+		},
+	); err != nil {
+		return false, nil, err
+	}
+	return true, nil, nil
+}
+
+// attachBackupPlanHook implements ATTACH BACKUP, which creates a database with
+// a view for each table of a database in a backup. Each view returns the rows
+// of its table as of the end time of the backup, or the requested time, by
+// running SHOW BACKUP TABLE with the resolved backup and time, so that the
+// contents of the backup can be queried like the tables of a regular
+// database, including with placeholders, without restoring it:
+//
+//	ATTACH BACKUP FROM LATEST IN 'nodelocal://1/c' AS snapshot;
+//	SELECT * FROM snapshot.public.t WHERE k = $1;
+//
+// Attached tables are always full scans: the optimizer cannot push the
+// filters of a query on a view into the plan hook of SHOW BACKUP TABLE, so
+// every query reads all of the table's rows from the backup and only filters
+// them afterwards, even if it constrains the primary key. RESTORE ROWS, which
+// constrains the spans it reads by the primary key, is better suited to
+// fetching a few rows out of a large table.
+//
+// The views store the URIs of the backup, so URIs with secrets in them are
+// rejected: external connections are used instead. The attached backup is
+// detached by dropping the database.
+func attachBackupPlanHook(
+	ctx context.Context, stmt tree.Statement, p sql.PlanHookState,
+) (sql.PlanHookRowFn, colinfo.ResultColumns, []sql.PlanNode, bool, error) {
+	attach, ok := stmt.(*tree.AttachBackup)
+	if !ok {
+		return nil, nil, nil, false, nil
+	}
+	if attach.Options.EncryptionPassphrase != nil {
+		return nil, nil, nil, false, pgerror.Newf(pgcode.InvalidParameterValue,
+			"%s does not support the encryption_passphrase option, since the views it creates "+
+				"would store the passphrase; use the kms option instead", attachBackupStmtName)
+	}
+	opts := attach.Options
+	opts.DecryptionKMSURI, opts.IncrementalStorage = nil, nil
+	if !opts.IsDefault() {
+		return nil, nil, nil, false, pgerror.Newf(pgcode.InvalidParameterValue,
+			"%s only supports the kms and incremental_location options", attachBackupStmtName)
+	}
+	if !p.ExtendedEvalContext().TxnIsSingleStmt {
+		return nil, nil, nil, false, pgerror.Newf(pgcode.InvalidTransactionState,
+			"%s cannot be used inside a multi-statement transaction", attachBackupStmtName)
+	}
+
+	exprEval := p.ExprEvaluator(attachBackupStmtName)
+	subdir, err := exprEval.String(ctx, attach.Subdir)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	dest, err := exprEval.StringArray(ctx, tree.Exprs(attach.InCollection))
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	var incPaths, kms []string
+	if attach.Options.IncrementalStorage != nil {
+		incPaths, err = exprEval.StringArray(ctx, tree.Exprs(attach.Options.IncrementalStorage))
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+	encryptionParams := jobspb.BackupEncryptionOptions{Mode: jobspb.EncryptionMode_None}
+	if attach.Options.DecryptionKMSURI != nil {
+		kms, err = exprEval.StringArray(ctx, tree.Exprs(attach.Options.DecryptionKMSURI))
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+		encryptionParams.Mode = jobspb.EncryptionMode_KMS
+		encryptionParams.RawKmsUris = kms
+	}
+	for _, uris := range [][]string{dest, incPaths, kms} {
+		for _, uri := range uris {
+			if err := checkAttachBackupURI(uri); err != nil {
+				return nil, nil, nil, false, err
+			}
+		}
+	}
+	var asOf hlc.Timestamp
+	if attach.AsOf.Expr != nil {
+		ts, err := p.EvalAsOfTimestamp(ctx, attach.AsOf)
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+		asOf = ts.Timestamp
+	}
+
+	if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, dest); err != nil {
+		return nil, nil, nil, false, err
+	}
+	if len(incPaths) > 0 {
+		if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, incPaths); err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
+	fn := func(ctx context.Context, _ []sql.PlanNode, _ chan<- tree.Datums) error {
+		ctx, span := tracing.ChildSpan(ctx, attachBackupStmtName)
+		defer span.Finish()
+
+		chain, err := resolveBackupChain(
+			ctx, p.ExecCfg(), p.User(), dest, subdir, incPaths, encryptionParams, asOf,
+		)
+		if err != nil {
+			return err
+		}
+		defer chain.close(ctx)
+		readTime := asOf
+		if readTime.IsEmpty() {
+			readTime = chain.endTime()
+		} else if err := chain.validateTime(readTime); err != nil {
+			return err
+		}
+		allDescs, _, err := backupinfo.LoadSQLDescsFromBackupsAtTime(
+			ctx, chain.manifests, chain.layerToIterFactory, readTime,
+		)
+		if err != nil {
+			return err
+		}
+		stmts, err := attachBackupStatements(attach, chain, allDescs, dest, incPaths, kms, readTime)
+		if err != nil {
+			return err
+		}
+		return p.ExecCfg().InternalDB.Txn(ctx, func(ctx context.Context, txn isql.Txn) error {
+			for _, stmt := range stmts {
+				if _, err := txn.ExecEx(ctx, "attach-backup", txn.KV(),
+					sessiondata.InternalExecutorOverride{User: p.User()}, stmt,
+				); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return fn, nil, nil, false, nil
+}
+
+// checkAttachBackupURI returns an error if the URI has secrets in it, which
+// ATTACH BACKUP would store in the views it creates.
+func checkAttachBackupURI(uri string) error {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	hasSecrets := false
+	if _, ok := parsed.User.Password(); ok {
+		hasSecrets = true
+	}
+	sanitized, err := cloud.SanitizeExternalStorageURI(uri, nil /* extraParams */)
+	if err != nil {
+		return err
+	}
+	parsedSanitized, err := url.Parse(sanitized)
+	if err != nil {
+		return err
+	}
+	sanitizedParams := parsedSanitized.Query()
+	for param, values := range parsed.Query() {
+		if sanitizedParams.Get(param) != values[0] {
+			hasSecrets = true
+		}
+	}
+	if hasSecrets {
+		return errors.WithHint(pgerror.Newf(pgcode.InvalidParameterValue,
+			"%s does not support URIs with secrets in them, since the views it creates would "+
+				"store them", attachBackupStmtName),
+			"create an external connection with CREATE EXTERNAL CONNECTION and use its "+
+				"external:// URI instead")
+	}
+	return nil
+}
+
+// attachBackupStatements returns the statements that create the database of
+// an ATTACH BACKUP statement, its schemas, and a view for each of the tables
+// of the attached database in the backup.
+func attachBackupStatements(
+	attach *tree.AttachBackup,
+	chain *backupChain,
+	allDescs []catalog.Descriptor,
+	dest, incPaths, kms []string,
+	asOf hlc.Timestamp,
+) ([]string, error) {
+	var db catalog.DatabaseDescriptor
+	var dbNames []string
+	for _, desc := range allDescs {
+		d, ok := desc.(catalog.DatabaseDescriptor)
+		if !ok || d.Dropped() {
+			continue
+		}
+		dbNames = append(dbNames, d.GetName())
+		if attach.BackupDatabase == "" || d.GetName() == string(attach.BackupDatabase) {
+			db = d
+		}
+	}
+	if attach.BackupDatabase == "" && len(dbNames) > 1 {
+		sort.Strings(dbNames)
+		return nil, errors.WithHintf(pgerror.Newf(pgcode.InvalidParameterValue,
+			"the backup contains more than one database"),
+			"use ATTACH BACKUP DATABASE to choose one of %v", dbNames)
+	}
+	if db == nil {
+		if attach.BackupDatabase == "" {
+			return nil, pgerror.Newf(pgcode.UndefinedDatabase, "the backup does not contain a database")
+		}
+		return nil, pgerror.Newf(pgcode.UndefinedDatabase,
+			"database %q does not exist in the backup", attach.BackupDatabase)
+	}
+
+	schemaNames := map[descpb.ID]string{
+		keys.PublicSchemaIDForBackup: catconstants.PublicSchemaName,
+	}
+	dbName := tree.NameString(string(attach.Database))
+	stmts := []string{fmt.Sprintf("CREATE DATABASE %s", dbName)}
+	for _, desc := range allDescs {
+		sc, ok := desc.(catalog.SchemaDescriptor)
+		if !ok || sc.GetParentID() != db.GetID() || sc.Dropped() {
+			continue
+		}
+		schemaNames[sc.GetID()] = sc.GetName()
+		if sc.GetName() != catconstants.PublicSchemaName {
+			stmts = append(stmts, fmt.Sprintf("CREATE SCHEMA %s.%s",
+				dbName, tree.NameString(sc.GetName())))
+		}
+	}
+
+	stringList := func(values []string) tree.StringOrPlaceholderOptList {
+		exprs := make(tree.StringOrPlaceholderOptList, len(values))
+		for i, v := range values {
+			exprs[i] = tree.NewDString(v)
+		}
+		return exprs
+	}
+	for _, desc := range allDescs {
+		table, ok := desc.(catalog.TableDescriptor)
+		if !ok || table.GetParentID() != db.GetID() || !table.IsTable() ||
+			table.Dropped() || table.Offline() {
+			continue
+		}
+		scName, ok := schemaNames[table.GetParentSchemaID()]
+		if !ok {
+			continue
+		}
+		backupTable, err := tree.NewUnresolvedObjectName(3,
+			[3]string{table.GetName(), scName, db.GetName()}, tree.NoAnnotation)
+		if err != nil {
+			return nil, err
+		}
+		// The time and the subdirectory of the backup are pinned, so the view
+		// keeps returning the same rows when backups are added to the
+		// collection.
+		show := &tree.ShowBackup{
+			Details:      tree.BackupTableRowsDetails,
+			Table:        backupTable,
+			From:         true,
+			Path:         tree.NewDString(chain.subdir),
+			InCollection: stringList(dest),
+			AsOf:         tree.AsOfClause{Expr: eval.TimestampToDecimalDatum(asOf)},
+		}
+		if len(incPaths) > 0 {
+			show.Options.IncrementalStorage = stringList(incPaths)
+		}
+		if len(kms) > 0 {
+			show.Options.DecryptionKMSURI = stringList(kms)
+		}
+		view := tree.NewTableNameWithSchema(
+			attach.Database, tree.Name(scName), tree.Name(table.GetName()),
+		)
+		stmts = append(stmts, fmt.Sprintf("CREATE VIEW %s AS SELECT * FROM [%s]",
+			view, tree.AsString(show)))
+	}
+	return stmts, nil
+}
+
+func init() {
+	sql.AddPlanHook("backupccl.attachBackupPlanHook", attachBackupPlanHook, attachBackupTypeCheck)
+}
@@ -0,0 +1,801 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/build"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/isql"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/bulk"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/errors"
+)
+
+// compactedBackup describes the full backup written by compactBackupChain.
+type compactedBackup struct {
+	// subdir is the subdirectory of the collection the backup was written to.
+	subdir string
+	// numLayers is the number of backups in the chain that were compacted.
+	numLayers int
+	counts    roachpb.RowCount
+}
+
+// resumeCompaction runs a compaction job, i.e. a backup job with
+// details.Compact set.
+func (b *backupResumer) resumeCompaction(
+	ctx context.Context, p sql.JobExecContext, details jobspb.BackupDetails,
+) error {
+	res, err := b.compactBackupChain(ctx, p.ExecCfg(), p.User(), details)
+	if err != nil {
+		return err
+	}
+	b.compactedSubdir = res.subdir
+	b.backupStats = res.counts
+	log.Infof(ctx, "compacted backup %s and its %d incremental backups into %s",
+		details.Destination.Subdir, res.numLayers-1, res.subdir)
+	return nil
+}
+
+// compactBackupChain merges the full backup in details.Destination.Subdir of
+// the collection and all of its incremental backups into a new, synthetic full
+// backup in the collection as of the end time of the last incremental backup.
+// Only the backup files are read: the data is merged from the SSTs of the
+// chain, layered the same way RESTORE layers them, and written to new SSTs.
+//
+// If details.RevisionHistory is set, every revision in the chain is preserved
+// and the chain must have been taken with revision history. Otherwise only the
+// latest revision of each key is kept.
+//
+// The new backup is encrypted with the same data key as the chain. If the
+// chain is the one pointed to by the LATEST file of the collection, LATEST is
+// moved to the new backup so that subsequent incremental backups into LATEST
+// build on it. Locality-aware backups are not supported.
+//
+// The job periodically writes the files written so far to a BACKUP-CHECKPOINT
+// manifest in the directory of the new backup and updates its fraction
+// completed. When the job is resumed, e.g. after it was paused, the chain is
+// read as of the end time it was first resolved to, and the entries covered by
+// the checkpointed files are skipped.
+func (b *backupResumer) compactBackupChain(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	user username.SQLUsername,
+	details jobspb.BackupDetails,
+) (compactedBackup, error) {
+	mkStore := execCfg.DistSQLSrv.ExternalStorageFromURI
+	collectionURI, subdir := details.CollectionURI, details.Destination.Subdir
+	encryption := details.EncryptionOptions
+
+	latest, err := backupdest.ReadLatestFile(ctx, collectionURI, mkStore, user)
+	if err != nil && !errors.Is(err, cloud.ErrFileDoesNotExist) {
+		return compactedBackup{}, err
+	}
+
+	collections := []string{collectionURI}
+	fullyResolvedDest, err := backuputils.AppendPaths(collections, subdir)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+	fullyResolvedIncrementalsDirectory, err := backupdest.ResolveIncrementalsBackupLocation(
+		ctx, user, execCfg, nil /* explicitIncrementalCollections */, collections, subdir,
+	)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+
+	baseStores, cleanupBase, err := backupdest.MakeBackupDestinationStores(ctx, user, mkStore, fullyResolvedDest)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+	defer func() {
+		if err := cleanupBase(); err != nil {
+			log.Warningf(ctx, "failed to close base store: %+v", err)
+		}
+	}()
+	incStores, cleanupInc, err := backupdest.MakeBackupDestinationStores(ctx, user, mkStore,
+		fullyResolvedIncrementalsDirectory)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+	defer func() {
+		if err := cleanupInc(); err != nil {
+			log.Warningf(ctx, "failed to close incremental store: %+v", err)
+		}
+	}()
+
+	kmsEnv := backupencryption.MakeBackupKMSEnv(
+		execCfg.Settings, &execCfg.ExternalIODirConfig, execCfg.InternalDB, user,
+	)
+	mem := execCfg.RootMemoryMonitor.MakeBoundAccount()
+	defer mem.Close(ctx)
+
+	// On resumption, incremental backups which were added to the chain after
+	// the job first resolved it are ignored.
+	_, manifests, localityInfo, memReserved, err := backupdest.ResolveBackupManifests(
+		ctx, &mem, baseStores, incStores, mkStore, fullyResolvedDest,
+		fullyResolvedIncrementalsDirectory, details.EndTime, encryption, &kmsEnv, user,
+	)
+	defer mem.Shrink(ctx, memReserved)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+
+	if len(manifests) < 2 {
+		return compactedBackup{}, errors.Newf(
+			"backup %s has no incremental backups to compact", subdir)
+	}
+	for _, info := range localityInfo {
+		if len(info.URIsByOriginalLocalityKV) > 0 {
+			return compactedBackup{}, errors.New("compacting locality-aware backups is not supported")
+		}
+	}
+	if details.RevisionHistory {
+		for _, m := range manifests {
+			if m.MVCCFilter != backuppb.MVCCFilter_All {
+				return compactedBackup{}, errors.New(
+					"cannot preserve revision history: the backups were not all taken with revision_history")
+			}
+		}
+	}
+
+	last := manifests[len(manifests)-1]
+	endTime := last.EndTime
+	if err := checkCoverage(ctx, last.Spans, manifests); err != nil {
+		return compactedBackup{}, errors.Wrap(err, "backup chain does not cover its spans")
+	}
+
+	newSubdir := endTime.GoTime().Format(backupbase.DateBasedIntoFolderName)
+	newURIs, err := backuputils.AppendPaths(collections, newSubdir)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+	newURI := newURIs[0]
+
+	// Lay claim to the directory of the new backup and persist it, along with
+	// the end time of the chain, so that a resumption of the job continues
+	// writing the same backup.
+	if details.URI == "" {
+		if err := backupinfo.CheckForPreviousBackup(ctx, execCfg, newURI, b.job.ID(), user); err != nil {
+			return compactedBackup{}, err
+		}
+		if err := backupinfo.WriteBackupLock(ctx, execCfg, newURI, b.job.ID(), user); err != nil {
+			return compactedBackup{}, err
+		}
+		details.URI = newURI
+		details.EndTime = endTime
+		if err := b.job.NoTxn().Update(ctx, func(txn isql.Txn, md jobs.JobMetadata, ju *jobs.JobUpdater) error {
+			if err := md.CheckRunningOrReverting(); err != nil {
+				return err
+			}
+			md.Payload.Details = jobspb.WrapPayloadDetails(details)
+			ju.UpdatePayload(md.Payload)
+			return nil
+		}); err != nil {
+			return compactedBackup{}, err
+		}
+	} else if details.URI != newURI {
+		return compactedBackup{}, errors.AssertionFailedf(
+			"compacted backup was resolved to %s, previously %s",
+			backuputils.RedactURIForErrorMessage(newURI), backuputils.RedactURIForErrorMessage(details.URI))
+	}
+	dest, err := mkStore(ctx, newURI, user)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+	defer dest.Close()
+
+	layerToIterFactory, err := backupinfo.GetBackupManifestIterFactories(ctx,
+		execCfg.DistSQLSrv.ExternalStorage, manifests, encryption, &kmsEnv)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+
+	// The metadata of the new backup is that of the last backup in the chain,
+	// with the data files of a full backup.
+	descs, err := bulk.CollectToSlice(layerToIterFactory[len(manifests)-1].NewDescIter(ctx))
+	if err != nil {
+		return compactedBackup{}, err
+	}
+	compacted := last
+	compacted.ID = uuid.MakeV4()
+	compacted.StartTime = hlc.Timestamp{}
+	compacted.IntroducedSpans = nil
+	compacted.Files = nil
+	compacted.HasExternalManifestSSTs = false
+	compacted.BuildInfo = build.GetInfo()
+	compacted.Dir = dest.Conf()
+	compacted.Descriptors = make([]descpb.Descriptor, len(descs))
+	for i := range descs {
+		compacted.Descriptors[i] = *descs[i]
+	}
+	compacted.DescriptorChanges = nil
+	compacted.MVCCFilter = backuppb.MVCCFilter_Latest
+	compacted.RevisionStartTime = hlc.Timestamp{}
+	if details.RevisionHistory {
+		compacted.MVCCFilter = backuppb.MVCCFilter_All
+		compacted.RevisionStartTime = manifests[0].RevisionStartTime
+		for layer := range manifests {
+			revs, err := bulk.CollectToSlice(layerToIterFactory[layer].NewDescriptorChangesIter(ctx))
+			if err != nil {
+				return compactedBackup{}, err
+			}
+			for _, rev := range revs {
+				compacted.DescriptorChanges = append(compacted.DescriptorChanges, *rev)
+			}
+		}
+	}
+
+	if encryption != nil {
+		if err := copyEncryptionInfo(ctx, baseStores[0], dest); err != nil {
+			return compactedBackup{}, err
+		}
+	}
+
+	backupLocalityMap, err := makeBackupLocalityMap(localityInfo, user)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+	introducedSpanFrontier, err := createIntroducedSpanFrontier(manifests, endTime)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+	filter, err := makeSpanCoveringFilter(
+		nil, /* checkpointFrontier */
+		nil, /* highWater */
+		introducedSpanFrontier,
+		targetRestoreSpanSize.Get(&execCfg.Settings.SV),
+		false, /* useFrontierCheckpointing */
+	)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+
+	sink := compactionSink{
+		dest:       dest,
+		instanceID: execCfg.NodeInfo.NodeID.SQLInstanceID(),
+		endTime:    endTime,
+		revisions:  details.RevisionHistory,
+		pkIDs:      make(map[uint64]bool),
+	}
+	defer sink.Close()
+	for i := range compacted.Descriptors {
+		if t, _, _, _, _ := descpb.GetDescriptors(&compacted.Descriptors[i]); t != nil {
+			sink.pkIDs[kvpb.BulkOpSummaryID(uint64(t.ID), uint64(t.PrimaryIndex.ID))] = true
+		}
+	}
+	if encryption != nil {
+		key, err := backupencryption.GetEncryptionKey(ctx, encryption, &kmsEnv)
+		if err != nil {
+			return compactedBackup{}, err
+		}
+		sink.enc = &kvpb.FileEncryptionOptions{Key: key}
+	}
+
+	// Pick up the files written before the job was paused. Since the SSTs are
+	// only flushed at entry boundaries and entries are generated in key order,
+	// every entry which ends at or before the end of the last checkpointed file
+	// has been written. Entries after that without any data did not add a file
+	// and are simply merged again.
+	var resumeKey roachpb.Key
+	checkpoint, checkpointSize, err := backupinfo.ReadBackupCheckpointManifest(ctx, &mem, dest,
+		backupinfo.BackupManifestCheckpointName, encryption, &kmsEnv)
+	if err == nil {
+		defer mem.Shrink(ctx, checkpointSize)
+		sink.files = checkpoint.Files
+		for _, f := range sink.files {
+			if f.Span.EndKey.Compare(resumeKey) > 0 {
+				resumeKey = f.Span.EndKey
+			}
+		}
+		log.Infof(ctx, "resuming compaction of backup %s from %s with %d files",
+			subdir, resumeKey, len(sink.files))
+	} else if !errors.Is(err, cloud.ErrFileDoesNotExist) {
+		return compactedBackup{}, errors.Wrap(err, "reading compaction checkpoint")
+	}
+
+	// Pivot the chain, which is grouped by time, into entries grouped by key
+	// range, as RESTORE does, and merge the files of each entry.
+	genSpan := func(ctx context.Context, spanCh chan execinfrapb.RestoreSpanEntry) error {
+		defer close(spanCh)
+		return generateAndSendImportSpans(
+			ctx,
+			last.Spans,
+			manifests,
+			layerToIterFactory,
+			backupLocalityMap,
+			filter,
+			useSimpleImportSpans.Get(&execCfg.Settings.SV),
+			spanCh,
+		)
+	}
+
+	// Count the entries, so that the fraction of them that has been merged can
+	// be reported as the progress of the job.
+	var numEntries int
+	countSpansCh := make(chan execinfrapb.RestoreSpanEntry, 1000)
+	if err := ctxgroup.GoAndWait(ctx, func(ctx context.Context) error {
+		for range countSpansCh {
+			numEntries++
+		}
+		return nil
+	}, func(ctx context.Context) error {
+		return genSpan(ctx, countSpansCh)
+	}); err != nil {
+		return compactedBackup{}, errors.Wrap(err, "counting compaction entries")
+	}
+
+	checkpointProgress := func(ctx context.Context, fraction float32) error {
+		compacted.Files = sink.files
+		if err := backupinfo.WriteBackupManifestCheckpoint(
+			ctx, newURI, encryption, &kmsEnv, &compacted, execCfg, user,
+		); err != nil {
+			return errors.Wrap(err, "writing compaction checkpoint")
+		}
+		if err := b.job.NoTxn().FractionProgressed(ctx, jobs.FractionUpdater(fraction)); err != nil {
+			return err
+		}
+		return execCfg.JobRegistry.CheckPausepoint("backup_compaction.after.write_checkpoint")
+	}
+
+	spanCh := make(chan execinfrapb.RestoreSpanEntry, 1000)
+	g := ctxgroup.WithContext(ctx)
+	g.GoCtx(func(ctx context.Context) error {
+		return genSpan(ctx, spanCh)
+	})
+	g.GoCtx(func(ctx context.Context) error {
+		lastCheckpoint := timeutil.Now()
+		var done int
+		for entry := range spanCh {
+			done++
+			if entry.Span.EndKey.Compare(resumeKey) <= 0 {
+				continue
+			}
+			if err := sink.writeEntry(ctx, execCfg.DistSQLSrv.ExternalStorage, entry); err != nil {
+				return err
+			}
+			// Only checkpoint once all the files written so far are flushed.
+			if sink.out != nil ||
+				timeutil.Since(lastCheckpoint) < BackupCheckpointInterval.Get(&execCfg.Settings.SV) {
+				continue
+			}
+			if err := checkpointProgress(ctx, float32(done)/float32(numEntries)); err != nil {
+				return err
+			}
+			lastCheckpoint = timeutil.Now()
+		}
+		return sink.flush(ctx)
+	})
+	if err := g.Wait(); err != nil {
+		return compactedBackup{}, errors.Wrap(err, "compacting backup data")
+	}
+
+	compacted.Files = sink.files
+	sort.Sort(backupinfo.BackupFileDescriptors(compacted.Files))
+	compacted.EntryCounts = roachpb.RowCount{}
+	for _, f := range compacted.Files {
+		compacted.EntryCounts.Add(f.EntryCounts)
+	}
+
+	// The statistics of the last backup in the chain are written with it.
+	lastStore, err := execCfg.DistSQLSrv.ExternalStorage(ctx, last.Dir)
+	if err != nil {
+		return compactedBackup{}, err
+	}
+	defer lastStore.Close()
+	stats, err := backupinfo.GetStatisticsFromBackup(ctx, lastStore, encryption, &kmsEnv, last)
+	if err != nil {
+		return compactedBackup{}, errors.Wrap(err, "reading table statistics")
+	}
+	if err := backupinfo.WriteTableStatistics(ctx, dest, encryption, &kmsEnv,
+		&backuppb.StatsTable{Statistics: stats}); err != nil {
+		return compactedBackup{}, err
+	}
+
+	if err := backupinfo.WriteBackupManifest(ctx, dest, backupbase.BackupManifestName,
+		encryption, &kmsEnv, &compacted); err != nil {
+		return compactedBackup{}, err
+	}
+	if backupinfo.WriteMetadataWithExternalSSTsEnabled.Get(&execCfg.Settings.SV) {
+		if err := backupinfo.WriteMetadataWithExternalSSTs(ctx, dest, encryption,
+			&kmsEnv, &compacted); err != nil {
+			return compactedBackup{}, err
+		}
+	}
+
+	if latest == subdir {
+		collection, err := mkStore(ctx, collectionURI, user)
+		if err != nil {
+			return compactedBackup{}, err
+		}
+		defer collection.Close()
+		if err := backupdest.WriteNewLatestFile(ctx, execCfg.Settings, collection, newSubdir); err != nil {
+			return compactedBackup{}, err
+		}
+	}
+
+	return compactedBackup{
+		subdir:    newSubdir,
+		numLayers: len(manifests),
+		counts:    compacted.EntryCounts,
+	}, nil
+}
+
+// compactionJobRecord returns the record of a job which compacts the full
+// backup in the given subdirectory of the collection and its incremental
+// backups. encryption must already be resolved against the chain.
+func compactionJobRecord(
+	user username.SQLUsername,
+	collectionURI string,
+	subdir string,
+	encryption *jobspb.BackupEncryptionOptions,
+	revisionHistory bool,
+) (jobs.Record, error) {
+	redactedCollection, err := cloud.SanitizeExternalStorageURI(collectionURI, nil /* extraParams */)
+	if err != nil {
+		return jobs.Record{}, err
+	}
+	stmt := &tree.AlterBackup{
+		Subdir: tree.NewDString(subdir),
+		Backup: tree.NewDString(redactedCollection),
+		Cmds:   tree.AlterBackupCmds{&tree.AlterBackupCompact{}},
+	}
+	if revisionHistory {
+		stmt.Cmds[0].(*tree.AlterBackupCompact).Options.CaptureRevisionHistory = tree.DBoolTrue
+	}
+	return jobs.Record{
+		Description: tree.AsString(stmt),
+		Details: jobspb.BackupDetails{
+			Compact:           true,
+			CollectionURI:     collectionURI,
+			Destination:       jobspb.BackupDetails_Destination{Subdir: subdir},
+			EncryptionOptions: encryption,
+			RevisionHistory:   revisionHistory,
+		},
+		Progress: jobspb.BackupProgress{},
+		Username: user,
+	}, nil
+}
+
+// maybeCompactBackupChain starts a job which compacts the chain of the full
+// backup that the given incremental backup was taken into, if the chain has at
+// least details.CompactAfterIncrementals incremental backups. The backup has
+// already succeeded, so a failure to start the job is logged rather than
+// returned; the chain is considered again after the next incremental backup.
+func maybeCompactBackupChain(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	user username.SQLUsername,
+	details jobspb.BackupDetails,
+) {
+	if details.CollectionURI == "" || len(details.Destination.IncrementalStorage) > 0 {
+		log.Warningf(ctx, "cannot compact backup chain of %s: only backup chains in the "+
+			"default location of a collection can be compacted",
+			backuputils.RedactURIForErrorMessage(details.URI))
+		return
+	}
+	subdir := details.Destination.Subdir
+
+	numIncrementals, err := func() (int, error) {
+		incDirs, err := backupdest.ResolveIncrementalsBackupLocation(
+			ctx, user, execCfg, nil, /* explicitIncrementalCollections */
+			[]string{details.CollectionURI}, subdir,
+		)
+		if err != nil {
+			return 0, err
+		}
+		incStore, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, incDirs[0], user)
+		if err != nil {
+			return 0, err
+		}
+		defer incStore.Close()
+		prev, err := backupdest.FindPriorBackups(ctx, incStore, backupdest.OmitManifest)
+		return len(prev), err
+	}()
+	if err != nil {
+		log.Warningf(ctx, "failed to count incremental backups in %s: %v", subdir, err)
+		return
+	}
+	if numIncrementals < int(details.CompactAfterIncrementals) {
+		return
+	}
+
+	record, err := compactionJobRecord(user, details.CollectionURI, subdir,
+		details.EncryptionOptions, details.RevisionHistory)
+	if err != nil {
+		log.Warningf(ctx, "failed to compact backup chain %s: %v", subdir, err)
+		return
+	}
+	jobID := execCfg.JobRegistry.MakeJobID()
+	if err := execCfg.InternalDB.Txn(ctx, func(ctx context.Context, txn isql.Txn) error {
+		_, err := execCfg.JobRegistry.CreateAdoptableJobWithTxn(ctx, record, jobID, txn)
+		return err
+	}); err != nil {
+		log.Warningf(ctx, "failed to create job to compact backup chain %s: %v", subdir, err)
+		return
+	}
+	log.Infof(ctx, "created job %d to compact backup %s and its %d incremental backups",
+		jobID, subdir, numIncrementals)
+}
+
+// copyEncryptionInfo copies the ENCRYPTION-INFO files of a full backup to the
+// directory of another backup, so that it can be decrypted the same way.
+func copyEncryptionInfo(ctx context.Context, src, dest cloud.ExternalStorage) error {
+	files, err := backupencryption.GetEncryptionInfoFiles(ctx, src)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		r, _, err := src.ReadFile(ctx, f, cloud.ReadOptions{NoFileSize: true})
+		if err != nil {
+			return err
+		}
+		buf, err := ioctx.ReadAll(ctx, r)
+		r.Close(ctx)
+		if err != nil {
+			return err
+		}
+		if err := cloud.WriteFile(ctx, dest, f, bytes.NewReader(buf)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compactionSink merges the files of the entries of a backup chain's restore
+// span cover and writes the result to SSTs of a new backup.
+type compactionSink struct {
+	dest       cloud.ExternalStorage
+	enc        *kvpb.FileEncryptionOptions
+	instanceID base.SQLInstanceID
+	endTime    hlc.Timestamp
+	revisions  bool
+	pkIDs      map[uint64]bool
+
+	// files are the files written so far, including those in the SST that is
+	// currently being written.
+	files []backuppb.BackupManifest_File
+	// unflushed is the number of files at the end of files that are in the SST
+	// that is currently being written.
+	unflushed int
+
+	cancel func()
+	out    io.WriteCloser
+	sst    storage.SSTWriter
+	name   string
+}
+
+// Close aborts the SST that is currently being written, if any.
+func (s *compactionSink) Close() {
+	if s.out != nil {
+		s.cancel()
+		if err := s.out.Close(); err != nil {
+			log.Warningf(context.Background(), "failed to close compacted backup file: %v", err)
+		}
+		s.out = nil
+	}
+}
+
+func (s *compactionSink) open(ctx context.Context) error {
+	s.name = generateUniqueSSTName(s.instanceID)
+	var writerCtx context.Context
+	writerCtx, s.cancel = context.WithCancel(ctx)
+	w, err := s.dest.Writer(writerCtx, s.name)
+	if err != nil {
+		s.cancel()
+		return err
+	}
+	s.out = w
+	if s.enc != nil {
+		e, err := storageccl.EncryptingWriter(w, s.enc.Key)
+		if err != nil {
+			s.Close()
+			return err
+		}
+		s.out = e
+	}
+	s.sst = storage.MakeBackupSSTWriter(ctx, s.dest.Settings(), s.out)
+	return nil
+}
+
+// flush finishes the SST that is currently being written, if any.
+func (s *compactionSink) flush(ctx context.Context) error {
+	if s.out == nil {
+		return nil
+	}
+	if err := s.sst.Finish(); err != nil {
+		return err
+	}
+	err := s.out.Close()
+	s.out = nil
+	s.cancel()
+	if err != nil {
+		return errors.Wrap(err, "writing SST")
+	}
+	for i := len(s.files) - s.unflushed; i < len(s.files); i++ {
+		s.files[i].BackingFileSize = s.sst.Meta.Size
+	}
+	s.unflushed = 0
+	return nil
+}
+
+// writeEntry merges the files of the given entry into the current SST.
+func (s *compactionSink) writeEntry(
+	ctx context.Context, mkStore cloud.ExternalStorageFactory, entry execinfrapb.RestoreSpanEntry,
+) error {
+	if len(entry.Files) == 0 {
+		return nil
+	}
+	storeFiles := make([]storageccl.StoreFile, 0, len(entry.Files))
+	defer func() {
+		for _, f := range storeFiles {
+			if err := f.Store.Close(); err != nil {
+				log.Warningf(ctx, "close export storage failed %v", err)
+			}
+		}
+	}()
+	for _, file := range entry.Files {
+		dir, err := mkStore(ctx, file.Dir)
+		if err != nil {
+			return err
+		}
+		storeFiles = append(storeFiles, storageccl.StoreFile{Store: dir, FilePath: file.Path})
+	}
+
+	if s.out == nil {
+		if err := s.open(ctx); err != nil {
+			return err
+		}
+	}
+	log.VEventf(ctx, 2, "compacting %d files of span %s into %s", len(entry.Files), entry.Span, s.name)
+
+	var counter storage.RowCounter
+	if err := s.copyPointKeys(ctx, storeFiles, entry.Span, &counter); err != nil {
+		return err
+	}
+	if s.revisions {
+		if err := s.copyRangeKeys(ctx, storeFiles, entry.Span, &counter); err != nil {
+			return err
+		}
+	}
+	if counter.DataSize > 0 {
+		s.files = append(s.files, backuppb.BackupManifest_File{
+			Span:        entry.Span,
+			Path:        s.name,
+			EntryCounts: countRows(counter.BulkOpSummary, s.pkIDs),
+			EndTime:     s.endTime,
+		})
+		s.unflushed++
+	}
+
+	if s.sst.DataSize > targetFileSize.Get(&s.dest.Settings().SV) {
+		return s.flush(ctx)
+	}
+	return nil
+}
+
+// copyPointKeys copies the point keys of the entry's files. Without revision
+// history, only the latest live revision of each key as of the end time of the
+// chain is copied; shadowed revisions and point and range tombstones are
+// dropped.
+func (s *compactionSink) copyPointKeys(
+	ctx context.Context,
+	storeFiles []storageccl.StoreFile,
+	span roachpb.Span,
+	counter *storage.RowCounter,
+) error {
+	iterOpts := storage.IterOptions{
+		KeyTypes:   storage.IterKeyTypePointsOnly,
+		LowerBound: span.Key,
+		UpperBound: span.EndKey,
+	}
+	if !s.revisions {
+		iterOpts.KeyTypes = storage.IterKeyTypePointsAndRanges
+		iterOpts.RangeKeyMaskingBelow = s.endTime
+	}
+	iter, err := storageccl.ExternalSSTReader(ctx, storeFiles, s.enc, iterOpts)
+	if err != nil {
+		return err
+	}
+	next := iter.Next
+	if !s.revisions {
+		asOfIter := storage.NewReadAsOfIterator(iter, s.endTime)
+		iter, next = asOfIter, asOfIter.NextKey
+	}
+	defer iter.Close()
+
+	for iter.SeekGE(storage.MVCCKey{Key: span.Key}); ; next() {
+		if ok, err := iter.Valid(); err != nil {
+			return err
+		} else if !ok {
+			break
+		}
+		k := iter.UnsafeKey()
+		v, err := iter.UnsafeValue()
+		if err != nil {
+			return err
+		}
+		if k.Timestamp.IsEmpty() {
+			err = s.sst.PutUnversioned(k.Key, v)
+		} else {
+			err = s.sst.PutRawMVCC(k, v)
+		}
+		if err != nil {
+			return err
+		}
+		if err := counter.Count(k.Key); err != nil {
+			return err
+		}
+		counter.DataSize += int64(len(k.Key) + len(v))
+	}
+	return nil
+}
+
+// copyRangeKeys copies the MVCC range keys of the entry's files.
+func (s *compactionSink) copyRangeKeys(
+	ctx context.Context,
+	storeFiles []storageccl.StoreFile,
+	span roachpb.Span,
+	counter *storage.RowCounter,
+) error {
+	iterOpts := storage.IterOptions{
+		KeyTypes:   storage.IterKeyTypeRangesOnly,
+		LowerBound: span.Key,
+		UpperBound: span.EndKey,
+	}
+	iter, err := storageccl.ExternalSSTReader(ctx, storeFiles, s.enc, iterOpts)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for iter.SeekGE(storage.MVCCKey{Key: keys.MinKey}); ; iter.Next() {
+		if ok, err := iter.Valid(); err != nil {
+			return err
+		} else if !ok {
+			break
+		}
+		rangeKeys := iter.RangeKeys()
+		for _, v := range rangeKeys.Versions {
+			if err := s.sst.PutRawMVCCRangeKey(rangeKeys.AsRangeKey(v), v.Value); err != nil {
+				return err
+			}
+			counter.DataSize += int64(len(rangeKeys.Bounds.Key) + len(rangeKeys.Bounds.EndKey) + len(v.Value))
+		}
+	}
+	return nil
+}
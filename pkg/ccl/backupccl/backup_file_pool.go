@@ -0,0 +1,360 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// filePoolGCStateName is the path, relative to the collection, of the file
+// that records which files of the file pool were found to be unreferenced.
+const filePoolGCStateName = "filepool/GC_STATE"
+
+// filePoolGCLocksName is the name of the directory, next to the file pool,
+// holding the locks that ALTER BACKUP ... DELETE UNREFERENCED FILES takes on
+// the pool while it runs.
+const filePoolGCLocksName = "GC_LOCKS"
+
+var filePoolGCGracePeriod = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"bulkio.backup.file_pool.gc_grace_period",
+	"the minimum time a file in the file pool of a backup collection must have been "+
+		"unreferenced before ALTER BACKUP ... DELETE UNREFERENCED FILES deletes it",
+	24*time.Hour,
+	settings.NonNegativeDuration,
+)
+
+// alterBackupDeleteUnreferencedFilesHeader is the header of the result of
+// ALTER BACKUP ... DELETE UNREFERENCED FILES.
+var alterBackupDeleteUnreferencedFilesHeader = colinfo.ResultColumns{
+	{Name: "referenced_files", Typ: types.Int},
+	{Name: "unreferenced_files", Typ: types.Int},
+	{Name: "deleted_files", Typ: types.Int},
+}
+
+// filePoolGCResult describes the result of collecting the garbage of a file
+// pool.
+type filePoolGCResult struct {
+	// referenced is the number of files in the pool referenced by a backup.
+	referenced int
+	// unreferenced is the number of files in the pool that are not referenced
+	// by any backup but were kept because they have not been unreferenced for
+	// the grace period yet.
+	unreferenced int
+	// deleted is the number of files that were deleted.
+	deleted int
+}
+
+// alterBackupDeleteUnreferencedFilesPlanHook plans ALTER BACKUP ... DELETE
+// UNREFERENCED FILES.
+func alterBackupDeleteUnreferencedFilesPlanHook(
+	p sql.PlanHookState, collection string,
+) (sql.PlanHookRowFn, colinfo.ResultColumns, []sql.PlanNode, bool, error) {
+	fn := func(ctx context.Context, _ []sql.PlanNode, resultsCh chan<- tree.Datums) error {
+		res, err := deleteUnreferencedPoolFiles(ctx, p.ExecCfg(), p.User(), collection)
+		if err != nil {
+			return err
+		}
+		resultsCh <- tree.Datums{
+			tree.NewDInt(tree.DInt(res.referenced)),
+			tree.NewDInt(tree.DInt(res.unreferenced)),
+			tree.NewDInt(tree.DInt(res.deleted)),
+		}
+		return nil
+	}
+	return fn, alterBackupDeleteUnreferencedFilesHeader, nil, false, nil
+}
+
+// deleteUnreferencedPoolFiles deletes the files in the file pool of the
+// collection that no backup in the collection references. Backups expire by
+// being deleted from the collection, after which the files that only they
+// referenced become unreferenced.
+//
+// A file is only deleted once it has been found to be unreferenced by calls at
+// least the grace period apart, which protects the files written by backups
+// that are still running and have not written their manifest yet.
+//
+// Backups reuse the files already in the pool, including unreferenced ones, so
+// no backup into a file pool may run while files are deleted. This takes a
+// lock on the pool and then refuses to run if any such backup has not
+// finished, while the backups check for locks once they are running, see
+// checkFilePoolGCLocks. Either this finds a backup that started before the
+// lock was taken, or the backup finds the lock.
+func deleteUnreferencedPoolFiles(
+	ctx context.Context, execCfg *sql.ExecutorConfig, user username.SQLUsername, collectionURI string,
+) (filePoolGCResult, error) {
+	mkStore := execCfg.DistSQLSrv.ExternalStorageFromURI
+	store, err := mkStore(ctx, collectionURI, user)
+	if err != nil {
+		return filePoolGCResult{}, errors.Wrapf(err, "failed to open backup storage location")
+	}
+	defer store.Close()
+
+	lock := path.Join(filePoolGCLocksDir(backupbase.FilePoolDirectory),
+		fmt.Sprintf("%d", builtins.GenerateUniqueInt(
+			builtins.ProcessUniqueID(execCfg.NodeInfo.NodeID.SQLInstanceID()))))
+	if err := cloud.WriteFile(ctx, store, lock, bytes.NewReader(nil)); err != nil {
+		return filePoolGCResult{}, errors.Wrap(err, "locking the file pool")
+	}
+	defer func() {
+		if err := store.Delete(ctx, lock); err != nil {
+			log.Warningf(ctx, "failed to release file pool lock %s: %v", lock, err)
+		}
+	}()
+	if running, err := filePoolBackupsRunning(ctx, execCfg); err != nil {
+		return filePoolGCResult{}, err
+	} else if running {
+		return filePoolGCResult{}, pgerror.New(pgcode.ObjectInUse,
+			"cannot delete unreferenced files while content-addressed backups are running or paused")
+	}
+
+	// Find the files of the pool referenced by the backups in the collection.
+	// The pool is listed before the backups so that the files written by a
+	// backup that completes in between are considered referenced.
+	var pool []string
+	if err := store.List(ctx, backupbase.FilePoolDirectory+"/", "", func(f string) error {
+		pool = append(pool, path.Join(backupbase.FilePoolDirectory, strings.TrimPrefix(f, "/")))
+		return nil
+	}); err != nil {
+		return filePoolGCResult{}, errors.Wrap(err, "listing the file pool")
+	}
+	referenced, err := referencedPoolFiles(ctx, execCfg, user, store, collectionURI)
+	if err != nil {
+		return filePoolGCResult{}, err
+	}
+
+	state, err := readFilePoolGCState(ctx, store)
+	if err != nil {
+		return filePoolGCResult{}, err
+	}
+	now := execCfg.Clock.PhysicalTime()
+	gracePeriod := filePoolGCGracePeriod.Get(&execCfg.Settings.SV)
+
+	var res filePoolGCResult
+	newState := backuppb.FilePoolGCState{UnreferencedSince: make(map[string]int64)}
+	sort.Strings(pool)
+	for _, name := range pool {
+		if _, ok := referenced[name]; ok {
+			res.referenced++
+			continue
+		}
+		since, ok := state.UnreferencedSince[name]
+		if !ok {
+			since = now.UnixNano()
+		}
+		if now.Sub(time.Unix(0, since)) < gracePeriod {
+			newState.UnreferencedSince[name] = since
+			res.unreferenced++
+			continue
+		}
+		log.VEventf(ctx, 2, "deleting unreferenced file %s from the file pool", name)
+		if err := store.Delete(ctx, name); err != nil {
+			return filePoolGCResult{}, errors.Wrapf(err, "deleting %s", name)
+		}
+		res.deleted++
+	}
+
+	if err := writeFilePoolGCState(ctx, store, &newState); err != nil {
+		return filePoolGCResult{}, err
+	}
+	return res, nil
+}
+
+// filePoolGCLocksDir returns the path of the directory holding the locks on
+// the file pool at the given path.
+func filePoolGCLocksDir(filePoolPath string) string {
+	return path.Join(path.Dir(filePoolPath), filePoolGCLocksName)
+}
+
+// checkFilePoolGCLocks returns a retryable error if files are being deleted
+// from the file pool at the given path, relative to the store, by ALTER
+// BACKUP ... DELETE UNREFERENCED FILES. It must be called by backups into the
+// pool once their job is running, and before they look for files in the pool.
+func checkFilePoolGCLocks(
+	ctx context.Context, store cloud.ExternalStorage, filePoolPath string,
+) error {
+	dir := filePoolGCLocksDir(filePoolPath)
+	var lock string
+	if err := store.List(ctx, dir+"/", "", func(f string) error {
+		lock = path.Join(dir, strings.TrimPrefix(f, "/"))
+		return cloud.ErrListingDone
+	}); err != nil && !errors.Is(err, cloud.ErrListingDone) {
+		return errors.Wrap(err, "checking for locks on the file pool")
+	}
+	if lock != "" {
+		return jobs.MarkAsRetryJobError(errors.Newf(
+			"unreferenced files are being deleted from the file pool; if no ALTER BACKUP ... "+
+				"DELETE UNREFERENCED FILES is running, remove its lock %s", lock))
+	}
+	return nil
+}
+
+// filePoolBackupsRunning returns whether any backup into a file pool has not
+// finished, including paused backups.
+func filePoolBackupsRunning(ctx context.Context, execCfg *sql.ExecutorConfig) (bool, error) {
+	rows, err := execCfg.InternalDB.Executor().QueryBufferedEx(
+		ctx, "find-file-pool-backups", nil, /* txn */
+		sessiondata.NodeUserSessionDataOverride,
+		`SELECT payload FROM crdb_internal.system_jobs WHERE job_type = $1 AND status IN `+
+			jobs.NonTerminalStatusTupleString,
+		jobspb.TypeBackup.String(),
+	)
+	if err != nil {
+		return false, errors.Wrap(err, "finding running backups")
+	}
+	for _, row := range rows {
+		payload, err := jobs.UnmarshalPayload(row[0])
+		if err != nil {
+			return false, err
+		}
+		if details := payload.GetBackup(); details != nil && details.FilePoolPath != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// referencedPoolFiles returns the paths, relative to the collection, of the
+// files of the file pool referenced by the backups in the collection.
+func referencedPoolFiles(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	user username.SQLUsername,
+	store cloud.ExternalStorage,
+	collectionURI string,
+) (map[string]struct{}, error) {
+	var dirs []string
+	if err := store.List(ctx, "", backupbase.ListingDelimDataSlash, func(f string) error {
+		f = strings.TrimPrefix(f, "/")
+		if path.Base(f) == backupbase.BackupManifestName {
+			dirs = append(dirs, path.Dir(f))
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "listing the backups in the collection")
+	}
+
+	mem := execCfg.RootMemoryMonitor.MakeBoundAccount()
+	defer mem.Close(ctx)
+
+	referenced := make(map[string]struct{})
+	for _, dir := range dirs {
+		uris, err := backuputils.AppendPaths([]string{collectionURI}, dir)
+		if err != nil {
+			return nil, err
+		}
+		if err := func() error {
+			dirStore, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, uris[0], user)
+			if err != nil {
+				return err
+			}
+			defer dirStore.Close()
+
+			// Content-addressed backups cannot be encrypted, so encrypted backups
+			// do not reference the pool and are skipped without needing their
+			// keys.
+			encrypted, err := manifestAppearsEncrypted(ctx, dirStore)
+			if err != nil || encrypted {
+				return err
+			}
+			manifest, memSize, err := backupinfo.ReadBackupManifest(
+				ctx, &mem, dirStore, backupbase.BackupManifestName, nil /* encryption */, nil, /* kmsEnv */
+			)
+			if err != nil {
+				return err
+			}
+			defer mem.Shrink(ctx, memSize)
+			for _, f := range manifest.Files {
+				if name := path.Join(dir, f.Path); strings.HasPrefix(name, backupbase.FilePoolDirectory+"/") {
+					referenced[name] = struct{}{}
+				}
+			}
+			return nil
+		}(); err != nil {
+			return nil, errors.Wrapf(err, "reading the manifest of backup %s", dir)
+		}
+	}
+	return referenced, nil
+}
+
+// manifestAppearsEncrypted returns whether the manifest of the backup in the
+// store appears encrypted.
+func manifestAppearsEncrypted(ctx context.Context, store cloud.ExternalStorage) (bool, error) {
+	r, _, err := store.ReadFile(ctx, backupbase.BackupManifestName, cloud.ReadOptions{NoFileSize: true})
+	if err != nil {
+		return false, err
+	}
+	defer r.Close(ctx)
+	data, err := ioctx.ReadAll(ctx, r)
+	if err != nil {
+		return false, err
+	}
+	return storageccl.AppearsEncrypted(data), nil
+}
+
+func readFilePoolGCState(
+	ctx context.Context, store cloud.ExternalStorage,
+) (backuppb.FilePoolGCState, error) {
+	var state backuppb.FilePoolGCState
+	r, _, err := store.ReadFile(ctx, filePoolGCStateName, cloud.ReadOptions{NoFileSize: true})
+	if err != nil {
+		if errors.Is(err, cloud.ErrFileDoesNotExist) {
+			return state, nil
+		}
+		return state, err
+	}
+	defer r.Close(ctx)
+	data, err := ioctx.ReadAll(ctx, r)
+	if err != nil {
+		return state, err
+	}
+	if err := protoutil.Unmarshal(data, &state); err != nil {
+		return state, errors.Wrap(err, "reading the file pool GC state")
+	}
+	return state, nil
+}
+
+func writeFilePoolGCState(
+	ctx context.Context, store cloud.ExternalStorage, state *backuppb.FilePoolGCState,
+) error {
+	data, err := protoutil.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return cloud.WriteFile(ctx, store, filePoolGCStateName, bytes.NewReader(data))
+}
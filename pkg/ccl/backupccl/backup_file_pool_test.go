@@ -0,0 +1,119 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/testutils/jobutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentAddressedBackup(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 0
+	_, sqlDB, dir, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	const collection = "nodelocal://1/c"
+	collectionDir := filepath.Join(dir, "c")
+	poolFiles := func() int {
+		entries, err := os.ReadDir(filepath.Join(collectionDir, backupbase.FilePoolDirectory))
+		require.NoError(t, err)
+		return len(entries)
+	}
+	listBackups := func() []string {
+		var subdirs []string
+		for _, row := range sqlDB.QueryStr(t, `SHOW BACKUPS IN $1`, collection) {
+			subdirs = append(subdirs, row[0])
+		}
+		return subdirs
+	}
+	gc := func(referenced, unreferenced, deleted int) {
+		sqlDB.CheckQueryResults(t, `ALTER BACKUP 'nodelocal://1/c' DELETE UNREFERENCED FILES`,
+			[][]string{{strconv.Itoa(referenced), strconv.Itoa(unreferenced), strconv.Itoa(deleted)}})
+	}
+
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	sqlDB.Exec(t, `CREATE TABLE d.t (k INT PRIMARY KEY, v STRING)`)
+	sqlDB.Exec(t, `INSERT INTO d.t SELECT i, 'v' || i::STRING FROM generate_series(1, 100) AS g(i)`)
+
+	// A second full backup of unchanged data reuses the files of the first.
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO $1 WITH content_addressed`, collection)
+	numFiles := poolFiles()
+	require.Greater(t, numFiles, 0)
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO $1 WITH content_addressed`, collection)
+	require.Equal(t, numFiles, poolFiles())
+
+	backups := listBackups()
+	require.Len(t, backups, 2)
+	for _, subdir := range backups {
+		_, err := os.Stat(filepath.Join(collectionDir, subdir, "data"))
+		require.True(t, os.IsNotExist(err), "backup %s has its own data files", subdir)
+	}
+
+	// Incremental backups write their new data to the pool as well.
+	sqlDB.Exec(t, `INSERT INTO d.t SELECT i, 'v' || i::STRING FROM generate_series(101, 200) AS g(i)`)
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO LATEST IN $1 WITH content_addressed`, collection)
+	require.Greater(t, poolFiles(), numFiles)
+	numFiles = poolFiles()
+
+	sqlDB.Exec(t, `RESTORE DATABASE d FROM LATEST IN $1 WITH new_db_name = 'd2'`, collection)
+	sqlDB.CheckQueryResults(t, `SELECT count(*), sum(k) FROM d2.t`, [][]string{{"200", "20100"}})
+
+	// Expiring the first full backup leaves every file referenced by the second.
+	require.NoError(t, os.RemoveAll(filepath.Join(collectionDir, backups[0])))
+	gc(numFiles, 0, 0)
+
+	// Once every backup has expired, unreferenced files are only deleted after
+	// the grace period.
+	require.NoError(t, os.RemoveAll(filepath.Join(collectionDir, backups[1])))
+	require.NoError(t, os.RemoveAll(filepath.Join(collectionDir, backupbase.DefaultIncrementalsSubdir)))
+	gc(0, numFiles, 0)
+	require.Equal(t, numFiles, poolFiles())
+
+	sqlDB.Exec(t, `SET CLUSTER SETTING bulkio.backup.file_pool.gc_grace_period = '0s'`)
+	gc(0, 0, numFiles)
+	require.Equal(t, 0, poolFiles())
+
+	// Files are not deleted while a content-addressed backup, which may reuse
+	// them, has not finished.
+	sqlDB.Exec(t, `SET CLUSTER SETTING jobs.debug.pausepoints = 'backup.before.flow'`)
+	var jobID jobspb.JobID
+	sqlDB.QueryRow(t, `BACKUP DATABASE d INTO $1 WITH content_addressed, detached`, collection).Scan(&jobID)
+	jobutils.WaitForJobToPause(t, sqlDB, jobID)
+	sqlDB.ExpectErr(t, `cannot delete unreferenced files while content-addressed backups are running`,
+		`ALTER BACKUP 'nodelocal://1/c' DELETE UNREFERENCED FILES`)
+	sqlDB.Exec(t, `CANCEL JOB $1`, jobID)
+	jobutils.WaitForJobToCancel(t, sqlDB, jobID)
+	sqlDB.Exec(t, `RESET CLUSTER SETTING jobs.debug.pausepoints`)
+	gc(0, 0, 0)
+	_, err := os.Stat(filepath.Join(collectionDir, filePoolGCLocksDir(backupbase.FilePoolDirectory)))
+	if err == nil {
+		entries, err := os.ReadDir(filepath.Join(collectionDir, filePoolGCLocksDir(backupbase.FilePoolDirectory)))
+		require.NoError(t, err)
+		require.Empty(t, entries, "the file pool was left locked")
+	}
+
+	sqlDB.ExpectErr(t, `content_addressed backups cannot be encrypted`,
+		`BACKUP DATABASE d INTO $1 WITH content_addressed, encryption_passphrase = 'pw'`, collection)
+	sqlDB.ExpectErr(t, `only supported with .BACKUP INTO. syntax`,
+		`BACKUP DATABASE d TO 'nodelocal://1/to' WITH content_addressed`)
+	sqlDB.ExpectErr(t, `cannot be used with IN`,
+		`ALTER BACKUP LATEST IN 'nodelocal://1/c' DELETE UNREFERENCED FILES`)
+}
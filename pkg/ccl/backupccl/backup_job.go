@@ -122,6 +122,7 @@ func backup(
 	encryption *jobspb.BackupEncryptionOptions,
 	statsCache *stats.TableStatisticsCache,
 	execLocality roachpb.Locality,
+	filePoolPath string,
 ) (_ roachpb.RowCount, numBackupInstances int, _ error) {
 	resumerSpan := tracing.SpanFromContext(ctx)
 	var lastCheckpoint time.Time
@@ -213,6 +214,7 @@ func backup(
 		kvpb.MVCCFilter(backupManifest.MVCCFilter),
 		backupManifest.StartTime,
 		backupManifest.EndTime,
+		filePoolPath,
 	)
 	if err != nil {
 		return roachpb.RowCount{}, 0, err
@@ -506,6 +508,9 @@ func statShouldBeIncludedInBackupRestore(stat *stats.TableStatisticProto) bool {
 type backupResumer struct {
 	job         *jobs.Job
 	backupStats roachpb.RowCount
+	// compactedSubdir is the subdirectory of the collection that a compaction
+	// job wrote the compacted backup to.
+	compactedSubdir string
 
 	testingKnobs struct {
 		ignoreProtectedTimestamps bool
@@ -534,6 +539,10 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 		return err
 	}
 
+	if details.Compact {
+		return b.resumeCompaction(ctx, p, details)
+	}
+
 	kmsEnv := backupencryption.MakeBackupKMSEnv(
 		p.ExecCfg().Settings,
 		&p.ExecCfg().ExternalIODirConfig,
@@ -766,6 +775,12 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 		return err
 	}
 
+	if details.FilePoolPath != "" {
+		if err := checkFilePoolGCLocks(ctx, defaultStore, details.FilePoolPath); err != nil {
+			return err
+		}
+	}
+
 	// We want to retry a backup if there are transient failures (i.e. worker nodes
 	// dying), so if we receive a retryable error, re-plan and retry the backup.
 	var res roachpb.RowCount
@@ -785,6 +800,7 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 			details.EncryptionOptions,
 			statsCache,
 			details.ExecutionLocality,
+			details.FilePoolPath,
 		)
 		if err == nil {
 			break
@@ -876,6 +892,10 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 		}
 	}
 
+	if !backupManifest.StartTime.IsEmpty() && details.CompactAfterIncrementals > 0 {
+		maybeCompactBackupChain(ctx, p.ExecCfg(), p.User(), details)
+	}
+
 	b.backupStats = res
 
 	// Collect telemetry.
@@ -913,6 +933,20 @@ func (b *backupResumer) Resume(ctx context.Context, execCtx interface{}) error {
 
 // ReportResults implements JobResultsReporter interface.
 func (b *backupResumer) ReportResults(ctx context.Context, resultsCh chan<- tree.Datums) error {
+	if b.job.Details().(jobspb.BackupDetails).Compact {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case resultsCh <- tree.Datums{
+			tree.NewDInt(tree.DInt(b.job.ID())),
+			tree.NewDString(b.compactedSubdir),
+			tree.NewDInt(tree.DInt(b.backupStats.Rows)),
+			tree.NewDInt(tree.DInt(b.backupStats.IndexEntries)),
+			tree.NewDInt(tree.DInt(b.backupStats.DataSize)),
+		}:
+			return nil
+		}
+	}
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -18,6 +18,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/build"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
@@ -199,6 +200,7 @@ func resolveOptionsForBackupJobDescription(
 		CaptureRevisionHistory: opts.CaptureRevisionHistory,
 		Detached:               opts.Detached,
 		ExecutionLocality:      opts.ExecutionLocality,
+		ContentAddressed:       opts.ContentAddressed,
 	}
 
 	if opts.EncryptionPassphrase != nil {
@@ -307,6 +309,9 @@ func backupJobDescription(
 type annotatedBackupStatement struct {
 	*tree.Backup
 	*jobs.CreatedByInfo
+	// compactAfterIncrementals is the compact_after_incrementals option of the
+	// schedule that created the backup, if any.
+	compactAfterIncrementals int32
 }
 
 func getBackupStatement(stmt tree.Statement) *annotatedBackupStatement {
@@ -532,6 +537,7 @@ func backupTypeCheck(
 		exprutil.Bools{
 			backupStmt.Options.CaptureRevisionHistory,
 			backupStmt.Options.IncludeAllSecondaryTenants,
+			backupStmt.Options.ContentAddressed,
 		}); err != nil {
 		return false, nil, err
 	}
@@ -627,6 +633,16 @@ func backupPlanHook(
 		}
 	}
 
+	var contentAddressed bool
+	if backupStmt.Options.ContentAddressed != nil {
+		contentAddressed, err = exprEval.Bool(
+			ctx, backupStmt.Options.ContentAddressed,
+		)
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
 	encryptionParams := jobspb.BackupEncryptionOptions{
 		Mode: jobspb.EncryptionMode_None,
 	}
@@ -685,6 +701,19 @@ func backupPlanHook(
 			return errors.New("the include_all_virtual_clusters option is only supported for full cluster backups")
 		}
 
+		if contentAddressed {
+			switch {
+			case !backupStmt.Nested:
+				return errors.New("the content_addressed option is only supported with `BACKUP INTO` syntax")
+			case len(to) > 1:
+				return errors.New("the content_addressed option is not supported for locality aware backups")
+			case len(incrementalStorage) > 0:
+				return errors.New("the content_addressed option cannot be used with the incremental_location option")
+			case encryptionParams.Mode != jobspb.EncryptionMode_None:
+				return errors.New("content_addressed backups cannot be encrypted")
+			}
+		}
+
 		var asOfInterval int64
 		endTime := p.ExecCfg().Clock.Now()
 		if backupStmt.AsOf.Expr != nil {
@@ -763,9 +792,11 @@ func backupPlanHook(
 			Detached:                   detached,
 			ApplicationName:            p.SessionData().ApplicationName,
 			ExecutionLocality:          executionLocality,
+			ContentAddressed:           contentAddressed,
 		}
 		if backupStmt.CreatedByInfo != nil && backupStmt.CreatedByInfo.Name == jobs.CreatedByScheduledJobs {
 			initialDetails.ScheduleID = backupStmt.CreatedByInfo.ID
+			initialDetails.CompactAfterIncrementals = backupStmt.compactAfterIncrementals
 		}
 
 		// For backups of specific targets, those targets were resolved with this
@@ -1622,6 +1653,13 @@ func updateBackupDetails(
 	details.EncryptionInfo = encryptionInfo
 	details.CollectionURI = collectionURI
 
+	if details.ContentAddressed {
+		details.FilePoolPath, err = backupdest.FilePoolPath(collectionURI, defaultURI)
+		if err != nil {
+			return jobspb.BackupDetails{}, err
+		}
+	}
+
 	return details, nil
 }
 
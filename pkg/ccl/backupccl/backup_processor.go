@@ -389,10 +389,12 @@ func runBackupProcessor(
 	}
 
 	sinkConf := sstSinkConf{
-		id:       flowCtx.NodeID.SQLInstanceID(),
-		enc:      spec.Encryption,
-		progCh:   progCh,
-		settings: &flowCtx.Cfg.Settings.SV,
+		id:           flowCtx.NodeID.SQLInstanceID(),
+		enc:          spec.Encryption,
+		progCh:       progCh,
+		settings:     &flowCtx.Cfg.Settings.SV,
+		filePoolPath: spec.FilePoolPath,
+		memMonitor:   memAcc.Monitor(),
 	}
 	storage, err := flowCtx.Cfg.ExternalStorage(ctx, dest)
 	if err != nil {
@@ -0,0 +1,199 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
+)
+
+// The kinds of changes reported by SHOW BACKUP DIFF.
+const (
+	backupDiffInsert = "insert"
+	backupDiffUpdate = "update"
+	backupDiffDelete = "delete"
+)
+
+// backupTableDiffer computes the rows of a table that changed between two
+// times covered by a chain of backups.
+type backupTableDiffer struct {
+	start, end *backupTableReader
+}
+
+// newBackupTableDiffer returns a differ for the table read by the given
+// reader, between the given start time and the end time of the reader. The
+// primary index and the visible columns of the table must be the same at both
+// times.
+func newBackupTableDiffer(
+	ctx context.Context, end *backupTableReader, startTime hlc.Timestamp,
+) (*backupTableDiffer, error) {
+	if err := end.chain.validateTime(startTime); err != nil {
+		return nil, err
+	}
+	start, err := end.atTime(ctx, startTime)
+	if err != nil {
+		return nil, err
+	}
+	if start.table.GetPrimaryIndexID() != end.table.GetPrimaryIndexID() {
+		return nil, pgerror.Newf(pgcode.FeatureNotSupported,
+			"the primary index of table %q changed between the start and end times",
+			end.table.GetName())
+	}
+	if err := checkSameBackupTableColumns(start.columns, end.columns); err != nil {
+		return nil, errors.Wrapf(err, "the columns of table %q changed between the start and end times",
+			end.table.GetName())
+	}
+	return &backupTableDiffer{start: start, end: end}, nil
+}
+
+// checkSameBackupTableColumns returns an error unless the columns of a table at
+// the start and end times of a diff are the same columns, with the same types,
+// in the same order, so that the values of a row at both times can be
+// compared position by position.
+func checkSameBackupTableColumns(start, end []catalog.Column) error {
+	if len(start) != len(end) {
+		return pgerror.Newf(pgcode.FeatureNotSupported,
+			"the table has %d visible columns at the start time and %d at the end time",
+			len(start), len(end))
+	}
+	for i := range end {
+		if start[i].GetID() != end[i].GetID() {
+			return pgerror.Newf(pgcode.FeatureNotSupported,
+				"column %q at the end time is not the same column as %q at the start time",
+				end[i].GetName(), start[i].GetName())
+		}
+		if !start[i].GetType().Identical(end[i].GetType()) {
+			return pgerror.Newf(pgcode.FeatureNotSupported,
+				"the type of column %q changed from %s to %s",
+				end[i].GetName(), start[i].GetType().SQLString(), end[i].GetType().SQLString())
+		}
+	}
+	return nil
+}
+
+// header returns the result columns of the changes returned by the differ:
+// the kind of change, followed by the values of the table's columns before
+// and after the change.
+func (d *backupTableDiffer) header() colinfo.ResultColumns {
+	tableHeader := d.end.header()
+	header := make(colinfo.ResultColumns, 0, 1+2*len(tableHeader))
+	header = append(header, colinfo.ResultColumn{Name: "change", Typ: types.String})
+	for _, prefix := range []string{"before_", "after_"} {
+		for _, col := range tableHeader {
+			header = append(header, colinfo.ResultColumn{Name: prefix + col.Name, Typ: col.Typ})
+		}
+	}
+	return header
+}
+
+// diff calls fn with a row for each row of the table that was inserted,
+// updated or deleted between the start and end times, in primary key order.
+// The before values of an inserted row and the after values of a deleted row
+// are NULL.
+//
+// The rows of the table at both times are read concurrently and merged by
+// their primary key. A row is updated if the KVs of its column families
+// differ between the two times.
+func (d *backupTableDiffer) diff(
+	ctx context.Context, startTime, endTime hlc.Timestamp, fn func(tree.Datums) error,
+) error {
+	startRows := make(chan backupTableRow, 64)
+	emit := func(change string, before, after tree.Datums) error {
+		datums := make(tree.Datums, 0, 1+2*len(d.end.columns))
+		datums = append(datums, tree.NewDString(change))
+		for _, row := range []tree.Datums{before, after} {
+			if row == nil {
+				for range d.end.columns {
+					datums = append(datums, tree.DNull)
+				}
+			} else {
+				datums = append(datums, backupTableResultDatums(d.end.columns, row)...)
+			}
+		}
+		return fn(datums)
+	}
+
+	g := ctxgroup.WithContext(ctx)
+	g.GoCtx(func(ctx context.Context) error {
+		defer close(startRows)
+		return d.start.readRows(ctx, startTime, func(row backupTableRow) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case startRows <- row.clone():
+				return nil
+			}
+		})
+	})
+	g.GoCtx(func(ctx context.Context) error {
+		// If reading the start rows fails, startRows is closed and the error is
+		// returned by the group.
+		before, ok := <-startRows
+		if err := d.end.readRows(ctx, endTime, func(after backupTableRow) error {
+			for ok && before.key.Compare(after.key) < 0 {
+				if err := emit(backupDiffDelete, before.datums, nil); err != nil {
+					return err
+				}
+				before, ok = <-startRows
+			}
+			if ok && before.key.Equal(after.key) {
+				defer func() { before, ok = <-startRows }()
+				if sameKVs(before.kvs, after.kvs) {
+					return nil
+				}
+				return emit(backupDiffUpdate, before.datums, after.datums)
+			}
+			return emit(backupDiffInsert, nil, after.datums)
+		}); err != nil {
+			return err
+		}
+		for ; ok; before, ok = <-startRows {
+			if err := emit(backupDiffDelete, before.datums, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return g.Wait()
+}
+
+// clone returns a copy of the row that remains valid after the callback it
+// was passed to returns.
+func (r backupTableRow) clone() backupTableRow {
+	return backupTableRow{
+		key:    r.key,
+		kvs:    append([]roachpb.KeyValue(nil), r.kvs...),
+		datums: append(tree.Datums(nil), r.datums...),
+	}
+}
+
+// sameKVs returns whether the two sets of KVs of a row have the same keys and
+// values, regardless of the timestamps at which they were written.
+func sameKVs(a, b []roachpb.KeyValue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Key.Equal(b[i].Key) || !bytes.Equal(a[i].Value.RawBytes, b[i].Value.RawBytes) {
+			return false
+		}
+	}
+	return true
+}
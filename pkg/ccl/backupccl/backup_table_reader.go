@@ -0,0 +1,688 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupencryption"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuputils"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descs"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/fetchpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/nstree"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/catconstants"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/errors"
+)
+
+// backupChain is a chain of backups, a full backup and its incremental
+// backups, that has been resolved so that its contents can be read directly
+// from the backup files.
+type backupChain struct {
+	// subdir is the subdirectory of the chain's full backup in its collection,
+	// with LATEST resolved.
+	subdir             string
+	manifests          []backuppb.BackupManifest
+	layerToIterFactory backupinfo.LayerToBackupManifestFileIterFactory
+	uris               []string
+	localityInfo       []jobspb.RestoreDetails_BackupLocalityInfo
+	encryption         *jobspb.BackupEncryptionOptions
+	kmsEnv             backupencryption.BackupKMSEnv
+	user               username.SQLUsername
+
+	// fileEncryption is the key with which the chain's files are encrypted, if
+	// they are. It is resolved once, since resolving a KMS-encrypted key makes
+	// a call to the KMS.
+	fileEncryption *kvpb.FileEncryptionOptions
+
+	// mem holds a reservation of memReserved bytes for the manifests while the
+	// chain is open.
+	mem         mon.BoundAccount
+	memReserved int64
+}
+
+// resolveBackupChain resolves the chain of backups of the full backup in the
+// given subdirectory of the collection, up to the backup that contains asOf
+// if it is set. The returned chain is open and must be closed.
+func resolveBackupChain(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	user username.SQLUsername,
+	dest []string,
+	subdir string,
+	explicitIncPaths []string,
+	encryptionParams jobspb.BackupEncryptionOptions,
+	asOf hlc.Timestamp,
+) (_ *backupChain, retErr error) {
+	mkStore := execCfg.DistSQLSrv.ExternalStorageFromURI
+	if strings.EqualFold(subdir, backupbase.LatestFileName) {
+		latest, err := backupdest.ReadLatestFile(ctx, dest[0], mkStore, user)
+		if err != nil {
+			return nil, errors.Wrap(err, "read LATEST path")
+		}
+		subdir = latest
+	}
+	collections, computedSubdir, err := backupdest.CollectionsAndSubdir(dest, subdir)
+	if err != nil {
+		return nil, err
+	}
+	fullyResolvedDest, err := backuputils.AppendPaths(dest, subdir)
+	if err != nil {
+		return nil, err
+	}
+	fullyResolvedIncrementalsDirectory, err := backupdest.ResolveIncrementalsBackupLocation(
+		ctx, user, execCfg, explicitIncPaths, collections, computedSubdir,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := &backupChain{
+		kmsEnv: backupencryption.MakeBackupKMSEnv(
+			execCfg.Settings, &execCfg.ExternalIODirConfig, execCfg.InternalDB, user,
+		),
+		subdir: subdir,
+		user:   user,
+		mem:    execCfg.RootMemoryMonitor.MakeBoundAccount(),
+	}
+	defer func() {
+		if retErr != nil {
+			chain.close(ctx)
+		}
+	}()
+
+	chain.encryption, err = backupencryption.GetEncryptionFromBase(
+		ctx, user, mkStore, fullyResolvedDest[0], encryptionParams, &chain.kmsEnv,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if chain.encryption != nil {
+		key, err := backupencryption.GetEncryptionKey(ctx, chain.encryption, &chain.kmsEnv)
+		if err != nil {
+			return nil, err
+		}
+		chain.fileEncryption = &kvpb.FileEncryptionOptions{Key: key}
+	}
+
+	baseStores, cleanupBase, err := backupdest.MakeBackupDestinationStores(ctx, user, mkStore, fullyResolvedDest)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := cleanupBase(); err != nil {
+			log.Warningf(ctx, "failed to close base store: %+v", err)
+		}
+	}()
+	incStores, cleanupInc, err := backupdest.MakeBackupDestinationStores(ctx, user, mkStore,
+		fullyResolvedIncrementalsDirectory)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := cleanupInc(); err != nil {
+			log.Warningf(ctx, "failed to close incremental store: %+v", err)
+		}
+	}()
+
+	chain.uris, chain.manifests, chain.localityInfo, chain.memReserved, err = backupdest.ResolveBackupManifests(
+		ctx, &chain.mem, baseStores, incStores, mkStore, fullyResolvedDest,
+		fullyResolvedIncrementalsDirectory, asOf, chain.encryption, &chain.kmsEnv, user,
+	)
+	if err != nil {
+		return nil, err
+	}
+	chain.layerToIterFactory, err = backupinfo.GetBackupManifestIterFactories(ctx,
+		execCfg.DistSQLSrv.ExternalStorage, chain.manifests, chain.encryption, &chain.kmsEnv)
+	if err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// close releases the memory reserved for the chain's manifests. A closed
+// chain can still be used to plan a read of its files, but must be reopened
+// before the files are read.
+func (c *backupChain) close(ctx context.Context) {
+	c.mem.Clear(ctx)
+}
+
+// reopen reserves the memory for the chain's manifests again after the chain
+// was closed. This allows a statement to release the memory between its
+// planning and its execution, which may never happen.
+func (c *backupChain) reopen(ctx context.Context) error {
+	return c.mem.Grow(ctx, c.memReserved)
+}
+
+// validateTime returns an error if the chain cannot be read as of the given
+// time, which must either be the end time of one of its backups or be covered
+// by the revision history of one of them.
+func (c *backupChain) validateTime(t hlc.Timestamp) error {
+	_, _, _, err := backupinfo.ValidateEndTimeAndTruncate(c.uris, c.manifests, c.localityInfo, t)
+	return err
+}
+
+// endTime returns the end time of the last backup in the chain.
+func (c *backupChain) endTime() hlc.Timestamp {
+	return c.manifests[len(c.manifests)-1].EndTime
+}
+
+// backupTableReader reads the rows of a table in a chain of backups directly
+// from the backup files, without restoring the table.
+type backupTableReader struct {
+	execCfg *sql.ExecutorConfig
+	chain   *backupChain
+
+	// tenantID is the ID of the tenant that was backed up, and codec the codec
+	// of the keys in the backup.
+	tenantID roachpb.TenantID
+	codec    keys.SQLCodec
+	table    catalog.TableDescriptor
+	columns  []catalog.Column
+	spec     fetchpb.IndexFetchSpec
+
+	// descs are the descriptors needed to decode the table's rows: the table's
+	// and those of its database and of the schemas and types in the database.
+	descs []catalog.Descriptor
+
+	// spans, if set, restricts the rows that are read to those in the given
+	// spans of the table's primary index.
+	spans roachpb.Spans
+}
+
+// newBackupTableReader returns a reader for the table with the given name in
+// the backup chain, as of the given time, or the end time of the chain if it
+// is empty. The name is resolved against the databases and schemas in the
+// backup.
+func newBackupTableReader(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	chain *backupChain,
+	name *tree.UnresolvedObjectName,
+	asOf hlc.Timestamp,
+) (*backupTableReader, error) {
+	allDescs, _, err := backupinfo.LoadSQLDescsFromBackupsAtTime(
+		ctx, chain.manifests, chain.layerToIterFactory, asOf,
+	)
+	if err != nil {
+		return nil, err
+	}
+	table, err := findTableInBackup(allDescs, name)
+	if err != nil {
+		return nil, err
+	}
+	return makeBackupTableReader(ctx, execCfg, chain, allDescs, table.GetID())
+}
+
+// atTime returns a reader for the same table as of another time in the chain,
+// using the table's descriptor as of that time.
+func (r *backupTableReader) atTime(
+	ctx context.Context, asOf hlc.Timestamp,
+) (*backupTableReader, error) {
+	allDescs, _, err := backupinfo.LoadSQLDescsFromBackupsAtTime(
+		ctx, r.chain.manifests, r.chain.layerToIterFactory, asOf,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return makeBackupTableReader(ctx, r.execCfg, r.chain, allDescs, r.table.GetID())
+}
+
+func makeBackupTableReader(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	chain *backupChain,
+	allDescs []catalog.Descriptor,
+	tableID descpb.ID,
+) (*backupTableReader, error) {
+	var parentID descpb.ID
+	for _, desc := range allDescs {
+		if desc.GetID() == tableID {
+			parentID = desc.GetParentID()
+		}
+	}
+	var tableDescs []catalog.Descriptor
+	for _, desc := range allDescs {
+		switch desc.(type) {
+		case catalog.TableDescriptor:
+			if desc.GetID() != tableID {
+				continue
+			}
+		case catalog.DatabaseDescriptor:
+			if desc.GetID() != parentID {
+				continue
+			}
+		case catalog.SchemaDescriptor, catalog.TypeDescriptor:
+			if desc.GetParentID() != parentID {
+				continue
+			}
+		default:
+			continue
+		}
+		tableDescs = append(tableDescs, desc)
+	}
+	table, err := hydrateBackupTable(ctx, tableDescs, tableID)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID := roachpb.SystemTenantID
+	if spans := chain.manifests[len(chain.manifests)-1].Spans; len(spans) > 0 {
+		_, tenantID, err = keys.DecodeTenantPrefix(spans[0].Key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r := &backupTableReader{
+		execCfg:  execCfg,
+		chain:    chain,
+		tenantID: tenantID,
+		codec:    keys.MakeSQLCodec(tenantID),
+		table:    table,
+		columns:  table.VisibleColumns(),
+		descs:    tableDescs,
+	}
+	if err := initBackupTableFetchSpec(&r.spec, r.codec, table, r.columns); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// hydrateBackupTable returns the table with the given ID among the given
+// descriptors from a backup, with the metadata of the user-defined types in
+// the backup installed in its column types, so that values of those types can
+// be decoded.
+func hydrateBackupTable(
+	ctx context.Context, tableDescs []catalog.Descriptor, tableID descpb.ID,
+) (catalog.TableDescriptor, error) {
+	var c nstree.MutableCatalog
+	for _, desc := range tableDescs {
+		c.UpsertDescriptor(desc)
+	}
+	if err := descs.HydrateCatalog(ctx, c); err != nil {
+		return nil, err
+	}
+	table, ok := c.LookupDescriptor(tableID).(catalog.TableDescriptor)
+	if !ok || table.Dropped() {
+		return nil, pgerror.Newf(pgcode.UndefinedTable,
+			"table with ID %d does not exist in the backup at the requested time", tableID)
+	}
+	return table, nil
+}
+
+// initBackupTableFetchSpec initializes the spec with which the given columns
+// of a table in a backup are fetched from its primary index.
+func initBackupTableFetchSpec(
+	spec *fetchpb.IndexFetchSpec,
+	codec keys.SQLCodec,
+	table catalog.TableDescriptor,
+	columns []catalog.Column,
+) error {
+	colIDs := make([]descpb.ColumnID, len(columns))
+	for i, col := range columns {
+		colIDs[i] = col.GetID()
+	}
+	return rowenc.InitIndexFetchSpec(spec, codec, table, table.GetPrimaryIndex(), colIDs)
+}
+
+// findTableInBackup returns the table with the given name among the
+// descriptors of a backup. A name with two parts is resolved as either
+// <schema>.<table> or <database>.<table> in the public schema.
+func findTableInBackup(
+	allDescs []catalog.Descriptor, name *tree.UnresolvedObjectName,
+) (catalog.TableDescriptor, error) {
+	dbNames := make(map[descpb.ID]string)
+	schemaNames := map[descpb.ID]string{
+		keys.PublicSchemaIDForBackup: catconstants.PublicSchemaName,
+	}
+	for _, desc := range allDescs {
+		switch d := desc.(type) {
+		case catalog.DatabaseDescriptor:
+			dbNames[d.GetID()] = d.GetName()
+		case catalog.SchemaDescriptor:
+			schemaNames[d.GetID()] = d.GetName()
+		}
+	}
+
+	tableName, first, second := name.Parts[0], name.Parts[1], name.Parts[2]
+	var found catalog.TableDescriptor
+	for _, desc := range allDescs {
+		table, ok := desc.(catalog.TableDescriptor)
+		if !ok || table.Dropped() || table.GetName() != tableName {
+			continue
+		}
+		dbName, scName := dbNames[table.GetParentID()], schemaNames[table.GetParentSchemaID()]
+		switch name.NumParts {
+		case 2:
+			if first != scName && (first != dbName || scName != catconstants.PublicSchemaName) {
+				continue
+			}
+		case 3:
+			if first != scName || second != dbName {
+				continue
+			}
+		}
+		if found != nil {
+			return nil, pgerror.Newf(pgcode.AmbiguousAlias,
+				"table name %q is ambiguous in the backup; qualify it with its database and schema",
+				tree.ErrString(name))
+		}
+		found = table
+	}
+	if found == nil {
+		return nil, pgerror.Newf(pgcode.UndefinedTable,
+			"table %q does not exist in the backup", tree.ErrString(name))
+	}
+	if !found.IsPhysicalTable() {
+		return nil, pgerror.Newf(pgcode.WrongObjectType,
+			"%q is not a table", tree.ErrString(name))
+	}
+	return found, nil
+}
+
+// header returns the result columns of the rows returned by SHOW BACKUP
+// TABLE, which are the visible columns of the table.
+func (r *backupTableReader) header() colinfo.ResultColumns {
+	return backupTableResultColumns(r.columns)
+}
+
+// backupTableResultColumns returns the result columns with which the values
+// of the given columns of a table in a backup are returned. The values of
+// columns of user-defined types are returned as strings in their text format,
+// since the types only exist in the backup.
+func backupTableResultColumns(columns []catalog.Column) colinfo.ResultColumns {
+	header := make(colinfo.ResultColumns, len(columns))
+	for i, col := range columns {
+		typ := col.GetType()
+		if typ.UserDefined() {
+			typ = types.String
+		}
+		header[i] = colinfo.ResultColumn{Name: col.GetName(), Typ: typ}
+	}
+	return header
+}
+
+// backupTableResultDatums converts the datums of a row of the given columns
+// read from a backup to the types of backupTableResultColumns.
+func backupTableResultDatums(columns []catalog.Column, datums tree.Datums) tree.Datums {
+	res := make(tree.Datums, len(datums))
+	for i, d := range datums {
+		if d != tree.DNull && columns[i].GetType().UserDefined() {
+			d = tree.NewDString(tree.AsStringWithFlags(d, tree.FmtPgwireText))
+		}
+		res[i] = d
+	}
+	return res
+}
+
+// backupTableRow is a row of a table read from a backup.
+type backupTableRow struct {
+	// key is the prefix shared by the keys of the row's column families.
+	key roachpb.Key
+	// kvs are the KVs of the row's column families.
+	kvs    []roachpb.KeyValue
+	datums tree.Datums
+}
+
+// readRows calls fn with each row of the table as of the given time, or the
+// end time of the chain if it is empty, in primary key order. The row passed
+// to fn is only valid until fn returns.
+//
+// The rows are read directly from the backup files: the files of the chain
+// covering the table's primary index are layered the same way RESTORE layers
+// them, and the latest revision of each key as of the requested time is
+// decoded using the table's descriptor in the backup.
+func (r *backupTableReader) readRows(
+	ctx context.Context, asOf hlc.Timestamp, fn func(backupTableRow) error,
+) error {
+	if asOf.IsEmpty() {
+		asOf = r.chain.endTime()
+	}
+	entries, err := r.restoreSpanEntries(ctx, asOf)
+	if err != nil {
+		return err
+	}
+
+	var d backupRowDecoder
+	if err := d.init(ctx, &r.spec); err != nil {
+		return err
+	}
+	defer d.close(ctx)
+	mkStore := r.execCfg.DistSQLSrv.ExternalStorage
+	for _, entry := range entries {
+		if err := readBackupEntry(ctx, mkStore, r.chain.fileEncryption, entry, asOf,
+			func(kv roachpb.KeyValue) error {
+				d.add(kv)
+				return nil
+			}); err != nil {
+			return err
+		}
+		if err := d.flush(ctx, false /* force */, fn); err != nil {
+			return err
+		}
+	}
+	return d.flush(ctx, true /* force */, fn)
+}
+
+// restoreSpanEntries returns the entries of the restore span cover of the
+// table's primary index, or of the reader's spans if set, that have files, in
+// key order.
+func (r *backupTableReader) restoreSpanEntries(
+	ctx context.Context, asOf hlc.Timestamp,
+) ([]execinfrapb.RestoreSpanEntry, error) {
+	manifests := r.chain.manifests
+	spans := r.spans
+	if len(spans) == 0 {
+		spans = roachpb.Spans{r.table.PrimaryIndexSpan(r.codec)}
+	}
+	if err := checkCoverage(ctx, spans, manifests); err != nil {
+		return nil, err
+	}
+	backupLocalityMap, err := makeBackupLocalityMap(r.chain.localityInfo, r.chain.user)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolving locality locations")
+	}
+	introducedSpanFrontier, err := createIntroducedSpanFrontier(manifests, asOf)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := makeSpanCoveringFilter(
+		nil, /* checkpointFrontier */
+		nil, /* highWater */
+		introducedSpanFrontier,
+		targetRestoreSpanSize.Get(&r.execCfg.Settings.SV),
+		false, /* useFrontierCheckpointing */
+	)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := makeSimpleImportSpans(
+		ctx,
+		spans,
+		manifests,
+		r.chain.layerToIterFactory,
+		backupLocalityMap,
+		filter,
+	)
+	if err != nil {
+		return nil, err
+	}
+	nonEmpty := entries[:0]
+	for _, entry := range entries {
+		if len(entry.Files) > 0 {
+			nonEmpty = append(nonEmpty, entry)
+		}
+	}
+	return nonEmpty, nil
+}
+
+// backupRowDecoder decodes the rows of a table from the KVs of its primary
+// index read from a backup, in key order.
+type backupRowDecoder struct {
+	rf row.Fetcher
+	// kvs are the KVs that were added but not decoded yet.
+	kvs []roachpb.KeyValue
+}
+
+func (d *backupRowDecoder) init(ctx context.Context, spec *fetchpb.IndexFetchSpec) error {
+	return d.rf.Init(ctx, row.FetcherInitArgs{
+		WillUseKVProvider: true,
+		Alloc:             &tree.DatumAlloc{},
+		Spec:              spec,
+	})
+}
+
+func (d *backupRowDecoder) close(ctx context.Context) {
+	d.rf.Close(ctx)
+}
+
+// add adds a KV to be decoded. KVs must be added in key order.
+func (d *backupRowDecoder) add(kv roachpb.KeyValue) {
+	d.kvs = append(d.kvs, kv)
+}
+
+// pendingRow returns the key of the last row whose KVs were added but not
+// decoded yet, or nil if there is none.
+func (d *backupRowDecoder) pendingRow() (roachpb.Key, error) {
+	if len(d.kvs) == 0 {
+		return nil, nil
+	}
+	return keys.EnsureSafeSplitKey(d.kvs[len(d.kvs)-1].Key)
+}
+
+// flush decodes the rows whose KVs were added and calls fn with each, one row
+// at a time so that the KVs of each row can be passed to fn along with its
+// datums. Unless force is set, the KVs of the last row, which may continue in
+// KVs that have not been added yet, are held back.
+func (d *backupRowDecoder) flush(
+	ctx context.Context, force bool, fn func(backupTableRow) error,
+) error {
+	n := len(d.kvs)
+	if !force && n > 0 {
+		last, err := d.pendingRow()
+		if err != nil {
+			return err
+		}
+		for n > 0 && bytes.HasPrefix(d.kvs[n-1].Key, last) {
+			n--
+		}
+	}
+	for kvs := d.kvs[:n]; len(kvs) > 0; {
+		key, err := keys.EnsureSafeSplitKey(kvs[0].Key)
+		if err != nil {
+			return err
+		}
+		i := 1
+		for i < len(kvs) && bytes.HasPrefix(kvs[i].Key, key) {
+			i++
+		}
+		rowKVs := kvs[:i]
+		kvs = kvs[i:]
+		if err := d.rf.ConsumeKVProvider(ctx, &row.KVProvider{KVs: rowKVs}); err != nil {
+			return err
+		}
+		datums, err := d.rf.NextRowDecoded(ctx)
+		if err != nil {
+			return err
+		}
+		if datums == nil {
+			continue
+		}
+		if err := fn(backupTableRow{key: key, kvs: rowKVs, datums: datums}); err != nil {
+			return err
+		}
+	}
+	d.kvs = append(d.kvs[:0], d.kvs[n:]...)
+	return nil
+}
+
+// readBackupEntry calls fn with the latest revision of each live key in the
+// files of the given entry as of the given time, in key order.
+func readBackupEntry(
+	ctx context.Context,
+	mkStore cloud.ExternalStorageFactory,
+	encryption *kvpb.FileEncryptionOptions,
+	entry execinfrapb.RestoreSpanEntry,
+	asOf hlc.Timestamp,
+	fn func(roachpb.KeyValue) error,
+) error {
+	storeFiles := make([]storageccl.StoreFile, 0, len(entry.Files))
+	defer func() {
+		for _, f := range storeFiles {
+			if err := f.Store.Close(); err != nil {
+				log.Warningf(ctx, "close export storage failed %v", err)
+			}
+		}
+	}()
+	for _, file := range entry.Files {
+		dir, err := mkStore(ctx, file.Dir)
+		if err != nil {
+			return err
+		}
+		storeFiles = append(storeFiles, storageccl.StoreFile{Store: dir, FilePath: file.Path})
+	}
+
+	iter, err := storageccl.ExternalSSTReader(ctx, storeFiles, encryption, storage.IterOptions{
+		RangeKeyMaskingBelow: asOf,
+		KeyTypes:             storage.IterKeyTypePointsAndRanges,
+		LowerBound:           entry.Span.Key,
+		UpperBound:           entry.Span.EndKey,
+	})
+	if err != nil {
+		return err
+	}
+	readAsOfIter := storage.NewReadAsOfIterator(iter, asOf)
+	defer readAsOfIter.Close()
+
+	for readAsOfIter.SeekGE(storage.MVCCKey{Key: entry.Span.Key}); ; readAsOfIter.NextKey() {
+		if ok, err := readAsOfIter.Valid(); err != nil {
+			return err
+		} else if !ok {
+			return nil
+		}
+		key := readAsOfIter.UnsafeKey()
+		v, err := storage.DecodeMVCCValueAndErr(readAsOfIter.UnsafeValue())
+		if err != nil {
+			return err
+		}
+		value := v.Value
+		value.RawBytes = append([]byte(nil), value.RawBytes...)
+		value.Timestamp = key.Timestamp
+		if err := fn(roachpb.KeyValue{Key: key.Key.Clone(), Value: value}); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupinfo"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/fetchpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/physicalplan"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowexec"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/iterutil"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+)
+
+const backupTableReaderProcessorName = "backupTableReader"
+
+// backupTableReaderProcessor returns the rows of a table read directly from
+// the files of a backup, for SHOW BACKUP TABLE. The entries of the restore
+// span cover of the table's primary index are split among the processors of
+// a flow, and each returns the rows whose keys start in its entries.
+type backupTableReaderProcessor struct {
+	execinfra.ProcessorBase
+
+	spec      execinfrapb.BackupTableReaderSpec
+	codec     keys.SQLCodec
+	table     catalog.TableDescriptor
+	columns   []catalog.Column
+	fetchSpec fetchpb.IndexFetchSpec
+
+	// cancelAndWaitForWorker cancels the goroutine reading the rows and waits
+	// for it to finish. It can be called multiple times.
+	cancelAndWaitForWorker func()
+	rowCh                  chan tree.Datums
+	readErr                error
+}
+
+var (
+	_ execinfra.Processor = &backupTableReaderProcessor{}
+	_ execinfra.RowSource = &backupTableReaderProcessor{}
+)
+
+func newBackupTableReaderProcessor(
+	ctx context.Context,
+	flowCtx *execinfra.FlowCtx,
+	processorID int32,
+	spec execinfrapb.BackupTableReaderSpec,
+	post *execinfrapb.PostProcessSpec,
+) (execinfra.Processor, error) {
+	descs := make([]catalog.Descriptor, 0, len(spec.Descriptors))
+	for i := range spec.Descriptors {
+		if desc := backupinfo.NewDescriptorForManifest(&spec.Descriptors[i]); desc != nil {
+			descs = append(descs, desc)
+		}
+	}
+	table, err := hydrateBackupTable(ctx, descs, spec.TableID)
+	if err != nil {
+		return nil, err
+	}
+	p := &backupTableReaderProcessor{
+		spec:    spec,
+		codec:   keys.MakeSQLCodec(spec.TenantID),
+		table:   table,
+		columns: table.VisibleColumns(),
+	}
+	if err := initBackupTableFetchSpec(&p.fetchSpec, p.codec, table, p.columns); err != nil {
+		return nil, err
+	}
+	header := backupTableResultColumns(p.columns)
+	outputTypes := make([]*types.T, len(header))
+	for i := range header {
+		outputTypes[i] = header[i].Typ
+	}
+	if err := p.Init(ctx, p, post, outputTypes, flowCtx, processorID, nil, /* memMonitor */
+		execinfra.ProcStateOpts{
+			TrailingMetaCallback: func() []execinfrapb.ProducerMetadata {
+				p.close()
+				return nil
+			},
+		}); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Start is part of the RowSource interface.
+func (p *backupTableReaderProcessor) Start(ctx context.Context) {
+	ctx = p.StartInternal(ctx, backupTableReaderProcessorName)
+	ctx, cancel := context.WithCancel(ctx)
+	p.rowCh = make(chan tree.Datums, 64)
+	p.cancelAndWaitForWorker = func() {
+		cancel()
+		for range p.rowCh {
+		}
+	}
+	if err := p.FlowCtx.Stopper().RunAsyncTaskEx(ctx, stop.TaskOpts{
+		TaskName: "backupTableReaderProcessor.read",
+		SpanOpt:  stop.ChildSpan,
+	}, func(ctx context.Context) {
+		p.readErr = p.read(ctx)
+		cancel()
+		close(p.rowCh)
+	}); err != nil {
+		// The closure above hasn't run, so we have to do the cleanup.
+		p.readErr = err
+		cancel()
+		close(p.rowCh)
+	}
+}
+
+// Next is part of the RowSource interface.
+func (p *backupTableReaderProcessor) Next() (rowenc.EncDatumRow, *execinfrapb.ProducerMetadata) {
+	for p.State == execinfra.StateRunning {
+		datums, ok := <-p.rowCh
+		if !ok {
+			p.MoveToDraining(p.readErr)
+			break
+		}
+		row := make(rowenc.EncDatumRow, len(datums))
+		for i, d := range datums {
+			row[i] = rowenc.DatumToEncDatum(p.OutputTypes()[i], d)
+		}
+		if outRow := p.ProcessRowHelper(row); outRow != nil {
+			return outRow, nil
+		}
+	}
+	return nil, p.DrainHelper()
+}
+
+func (p *backupTableReaderProcessor) close() {
+	if p.cancelAndWaitForWorker != nil {
+		p.cancelAndWaitForWorker()
+	}
+	p.InternalClose()
+}
+
+// ConsumerClosed is part of the RowSource interface. We have to override the
+// implementation provided by ProcessorBase.
+func (p *backupTableReaderProcessor) ConsumerClosed() {
+	p.close()
+}
+
+// read reads the rows whose keys start in the processor's entries and sends
+// them on rowCh.
+//
+// A row whose key starts in the last entry may continue in the entries that
+// follow, which are read by the next processor, so the processor reads the
+// tail entries until it has read the rest of the row. For the same reason, the
+// processor skips the KVs at the start of its first entry that belong to a row
+// whose key starts before it.
+func (p *backupTableReaderProcessor) read(ctx context.Context) error {
+	var d backupRowDecoder
+	if err := d.init(ctx, &p.fetchSpec); err != nil {
+		return err
+	}
+	defer d.close(ctx)
+	emit := func(row backupTableRow) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case p.rowCh <- backupTableResultDatums(p.columns, row.datums):
+			return nil
+		}
+	}
+
+	mkStore := p.FlowCtx.Cfg.ExternalStorage
+	start := p.spec.Entries[0].Span.Key
+	skipping := true
+	for _, entry := range p.spec.Entries {
+		if err := readBackupEntry(ctx, mkStore, p.spec.Encryption, entry, p.spec.AsOf,
+			func(kv roachpb.KeyValue) error {
+				if skipping {
+					rowKey, err := keys.EnsureSafeSplitKey(kv.Key)
+					if err != nil {
+						return err
+					}
+					if rowKey.Compare(start) < 0 {
+						return nil
+					}
+					skipping = false
+				}
+				d.add(kv)
+				return nil
+			}); err != nil {
+			return err
+		}
+		if err := d.flush(ctx, false /* force */, emit); err != nil {
+			return err
+		}
+	}
+
+	if len(p.spec.TailEntries) > 0 {
+		end := p.spec.TailEntries[0].Span.Key
+		var rowEnded bool
+		for _, entry := range p.spec.TailEntries {
+			if err := iterutil.Map(readBackupEntry(ctx, mkStore, p.spec.Encryption, entry, p.spec.AsOf,
+				func(kv roachpb.KeyValue) error {
+					rowKey, err := keys.EnsureSafeSplitKey(kv.Key)
+					if err != nil {
+						return err
+					}
+					if rowKey.Compare(end) >= 0 {
+						rowEnded = true
+						return iterutil.StopIteration()
+					}
+					d.add(kv)
+					return nil
+				})); err != nil {
+				return err
+			}
+			if rowEnded {
+				break
+			}
+		}
+		lastTail := p.spec.TailEntries[len(p.spec.TailEntries)-1].Span.EndKey
+		if !rowEnded && lastTail.Compare(p.table.PrimaryIndexSpan(p.codec).EndKey) < 0 {
+			return pgerror.Newf(pgcode.FeatureNotSupported,
+				"a row of table %q spans too many backup files to be read", p.table.GetName())
+		}
+	}
+	return d.flush(ctx, true /* force */, emit)
+}
+
+// distReadRows calls fn with each row of the table as of the given time, or
+// the end time of the chain if it is empty, in no particular order. The rows
+// are read by a flow with a backupTableReaderProcessor on each SQL instance,
+// each of which reads a contiguous group of the entries of the restore span
+// cover of the table's primary index.
+func (r *backupTableReader) distReadRows(
+	ctx context.Context, p sql.PlanHookState, asOf hlc.Timestamp, fn func(tree.Datums) error,
+) error {
+	if asOf.IsEmpty() {
+		asOf = r.chain.endTime()
+	}
+	entries, err := r.restoreSpanEntries(ctx, asOf)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	dsp := p.DistSQLPlanner()
+	evalCtx := p.ExtendedEvalContext()
+	planCtx, sqlInstanceIDs, err := dsp.SetupAllNodesPlanning(ctx, evalCtx, p.ExecCfg())
+	if err != nil {
+		return err
+	}
+	descs := make([]descpb.Descriptor, len(r.descs))
+	for i, desc := range r.descs {
+		descs[i] = *desc.DescriptorProto()
+	}
+	numGroups := len(sqlInstanceIDs)
+	if numGroups > len(entries) {
+		numGroups = len(entries)
+	}
+	group := func(i int) []execinfrapb.RestoreSpanEntry {
+		return entries[i*len(entries)/numGroups : (i+1)*len(entries)/numGroups]
+	}
+	corePlacement := make([]physicalplan.ProcessorCorePlacement, numGroups)
+	for i := range corePlacement {
+		spec := &execinfrapb.BackupTableReaderSpec{
+			Entries:     group(i),
+			AsOf:        asOf,
+			Encryption:  r.chain.fileEncryption,
+			TenantID:    r.tenantID,
+			TableID:     r.table.GetID(),
+			Descriptors: descs,
+		}
+		if i+1 < numGroups {
+			spec.TailEntries = group(i + 1)
+		}
+		corePlacement[i].SQLInstanceID = sqlInstanceIDs[i]
+		corePlacement[i].Core.BackupTableReader = spec
+	}
+
+	header := r.header()
+	outputTypes := make([]*types.T, len(header))
+	for i := range header {
+		outputTypes[i] = header[i].Typ
+	}
+	plan := planCtx.NewPhysicalPlan()
+	plan.AddNoInputStage(corePlacement, execinfrapb.PostProcessSpec{}, outputTypes, execinfrapb.Ordering{})
+	plan.PlanToStreamColMap = make([]int, len(outputTypes))
+	for i := range plan.PlanToStreamColMap {
+		plan.PlanToStreamColMap[i] = i
+	}
+	sql.FinalizePlan(ctx, planCtx, plan)
+
+	rowResultWriter := sql.NewCallbackResultWriter(func(ctx context.Context, row tree.Datums) error {
+		return fn(append(tree.Datums(nil), row...))
+	})
+	recv := sql.MakeDistSQLReceiver(
+		ctx,
+		rowResultWriter,
+		tree.Rows,
+		nil, /* rangeCache */
+		nil, /* txn - the flow does not read or write the database */
+		nil, /* clockUpdater */
+		evalCtx.Tracing,
+	)
+	defer recv.Release()
+
+	evalCtxCopy := *evalCtx
+	dsp.Run(ctx, planCtx, nil, plan, recv, &evalCtxCopy, nil /* finishedSetupFn */)
+	return rowResultWriter.Err()
+}
+
+func init() {
+	rowexec.NewBackupTableReaderProcessor = newBackupTableReaderProcessor
+}
@@ -60,4 +60,10 @@ const (
 	// and groups all the data sst files in each backup, which start with "data/",
 	// into a single result that can be skipped over quickly.
 	ListingDelimDataSlash = "data/"
+
+	// FilePoolDirectory is the directory of a collection in which the data files
+	// of content-addressed backups are stored under the hash of their contents.
+	// It ends in data so that listings of the collection that use
+	// ListingDelimDataSlash group its files into a single result.
+	FilePoolDirectory = "filepool/data"
 )
@@ -484,6 +484,34 @@ func GetURIsByLocalityKV(
 	return defaultURI, urisByLocalityKV, nil
 }
 
+// FilePoolPath returns the path of the file pool of the collection at
+// collectionURI relative to the backup at backupURI, which must be stored
+// within the collection.
+func FilePoolPath(collectionURI, backupURI string) (string, error) {
+	collection, err := url.Parse(collectionURI)
+	if err != nil {
+		return "", err
+	}
+	backup, err := url.Parse(backupURI)
+	if err != nil {
+		return "", err
+	}
+	collectionPath := path.Clean("/" + collection.Path)
+	backupPath := path.Clean("/" + backup.Path)
+	if collection.Scheme != backup.Scheme || collection.Host != backup.Host ||
+		(collectionPath != "/" && !strings.HasPrefix(backupPath, collectionPath+"/")) {
+		return "", errors.Newf("backup %s is not stored within collection %s",
+			backuputils.RedactURIForErrorMessage(backupURI),
+			backuputils.RedactURIForErrorMessage(collectionURI))
+	}
+	rel := strings.Trim(strings.TrimPrefix(backupPath, collectionPath), "/")
+	if rel == "" {
+		return backupbase.FilePoolDirectory, nil
+	}
+	depth := strings.Count(rel, "/") + 1
+	return path.Join(strings.Repeat("../", depth), backupbase.FilePoolDirectory), nil
+}
+
 // ListFullBackupsInCollection lists full backup paths in the collection
 // of an export store
 func ListFullBackupsInCollection(
@@ -559,4 +559,47 @@ func TestBackupRestoreResolveDestination(t *testing.T) {
 	}
 }
 
+func TestFilePoolPath(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	for _, tc := range []struct {
+		collection, backup, expected, err string
+	}{
+		{
+			collection: "nodelocal://1/coll",
+			backup:     "nodelocal://1/coll/2020/12/25-120000.00",
+			expected:   "../../../filepool/data",
+		},
+		{
+			collection: "s3://bucket/coll?AUTH=implicit",
+			backup:     "s3://bucket/coll/incrementals/2020/12/25-120000.00/20201225/130000.00?AUTH=implicit",
+			expected:   "../../../../../../filepool/data",
+		},
+		{
+			collection: "s3://bucket?AUTH=implicit",
+			backup:     "s3://bucket/2020/12/25-120000.00?AUTH=implicit",
+			expected:   "../../../filepool/data",
+		},
+		{
+			collection: "nodelocal://1/coll",
+			backup:     "nodelocal://1/other/2020/12/25-120000.00",
+			err:        "is not stored within collection",
+		},
+		{
+			collection: "nodelocal://1/coll",
+			backup:     "nodelocal://2/coll/2020/12/25-120000.00",
+			err:        "is not stored within collection",
+		},
+	} {
+		actual, err := backupdest.FilePoolPath(tc.collection, tc.backup)
+		if tc.err != "" {
+			require.ErrorContains(t, err, tc.err)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, actual)
+	}
+}
+
 // TODO(pbardea): Add tests for resolveBackupCollection.
@@ -11,6 +11,7 @@ package backupccl
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backupdest"
@@ -38,19 +39,21 @@ import (
 )
 
 const (
-	optFirstRun                = "first_run"
-	optOnExecFailure           = "on_execution_failure"
-	optOnPreviousRunning       = "on_previous_running"
-	optIgnoreExistingBackups   = "ignore_existing_backups"
-	optUpdatesLastBackupMetric = "updates_cluster_last_backup_time_metric"
+	optFirstRun                 = "first_run"
+	optOnExecFailure            = "on_execution_failure"
+	optOnPreviousRunning        = "on_previous_running"
+	optIgnoreExistingBackups    = "ignore_existing_backups"
+	optUpdatesLastBackupMetric  = "updates_cluster_last_backup_time_metric"
+	optCompactAfterIncrementals = "compact_after_incrementals"
 )
 
 var scheduledBackupOptionExpectValues = map[string]exprutil.KVStringOptValidate{
-	optFirstRun:                exprutil.KVStringOptRequireValue,
-	optOnExecFailure:           exprutil.KVStringOptRequireValue,
-	optOnPreviousRunning:       exprutil.KVStringOptRequireValue,
-	optIgnoreExistingBackups:   exprutil.KVStringOptRequireNoValue,
-	optUpdatesLastBackupMetric: exprutil.KVStringOptRequireNoValue,
+	optFirstRun:                 exprutil.KVStringOptRequireValue,
+	optOnExecFailure:            exprutil.KVStringOptRequireValue,
+	optOnPreviousRunning:        exprutil.KVStringOptRequireValue,
+	optIgnoreExistingBackups:    exprutil.KVStringOptRequireNoValue,
+	optUpdatesLastBackupMetric:  exprutil.KVStringOptRequireNoValue,
+	optCompactAfterIncrementals: exprutil.KVStringOptRequireValue,
 }
 
 // scheduledBackupGCProtectionEnabled is used to enable and disable the chaining
@@ -115,6 +118,21 @@ func scheduleFirstRun(evalCtx *eval.Context, opts map[string]string) (*time.Time
 	return nil, nil
 }
 
+// scheduleCompactAfterIncrementals returns the number of incremental backups
+// after which the chain of a full backup taken by the schedule is compacted, or
+// 0 if it is not.
+func scheduleCompactAfterIncrementals(opts map[string]string) (int32, error) {
+	v, ok := opts[optCompactAfterIncrementals]
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil || n <= 0 {
+		return 0, errors.Newf("%s must be a positive integer, got %q", optCompactAfterIncrementals, v)
+	}
+	return int32(n), nil
+}
+
 func frequencyFromCron(now time.Time, cronStr string) (time.Duration, error) {
 	expr, err := cron.ParseStandard(cronStr)
 	if err != nil {
@@ -302,6 +320,14 @@ func doCreateBackupSchedules(
 		return err
 	}
 
+	compactAfterIncrementals, err := scheduleCompactAfterIncrementals(scheduleOptions)
+	if err != nil {
+		return err
+	}
+	if compactAfterIncrementals > 0 && incRecurrence == nil {
+		return errors.Newf("%s requires a schedule with incremental backups", optCompactAfterIncrementals)
+	}
+
 	unpauseOnSuccessID := jobs.InvalidScheduleID
 
 	var chainProtectedTimestampRecords bool
@@ -338,7 +364,7 @@ func doCreateBackupSchedules(
 		}
 		inc, incScheduledBackupArgs, err = makeBackupSchedule(
 			env, p.User(), scheduleLabel, incRecurrence, incrementalScheduleDetails, unpauseOnSuccessID,
-			updateMetricOnSuccess, backupNode, chainProtectedTimestampRecords)
+			updateMetricOnSuccess, backupNode, chainProtectedTimestampRecords, compactAfterIncrementals)
 		if err != nil {
 			return err
 		}
@@ -362,7 +388,7 @@ func doCreateBackupSchedules(
 	var fullScheduledBackupArgs *backuppb.ScheduledBackupExecutionArgs
 	full, fullScheduledBackupArgs, err := makeBackupSchedule(
 		env, p.User(), scheduleLabel, fullRecurrence, details, unpauseOnSuccessID,
-		updateMetricOnSuccess, backupNode, chainProtectedTimestampRecords)
+		updateMetricOnSuccess, backupNode, chainProtectedTimestampRecords, 0 /* compactAfterIncrementals */)
 	if err != nil {
 		return err
 	}
@@ -469,6 +495,7 @@ func makeBackupSchedule(
 	updateLastMetricOnSuccess bool,
 	backupNode *tree.Backup,
 	chainProtectedTimestampRecords bool,
+	compactAfterIncrementals int32,
 ) (*jobs.ScheduledJob, *backuppb.ScheduledBackupExecutionArgs, error) {
 	sj := jobs.NewScheduledJob(env)
 	sj.SetScheduleLabel(label)
@@ -479,6 +506,7 @@ func makeBackupSchedule(
 		UnpauseOnSuccess:               unpauseOnSuccess,
 		UpdatesLastBackupMetric:        updateLastMetricOnSuccess,
 		ChainProtectedTimestampRecords: chainProtectedTimestampRecords,
+		CompactAfterIncrementals:       compactAfterIncrementals,
 	}
 	if backupNode.AppendToLatest {
 		args.BackupType = backuppb.ScheduledBackupExecutionArgs_INCREMENTAL
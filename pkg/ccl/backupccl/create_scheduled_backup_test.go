@@ -665,6 +665,18 @@ func TestSerializesScheduledBackupExecutionArgs(t *testing.T) {
 			query:  `CREATE SCHEDULE FOR BACKUP TABLE system.public.jobs INTO $1 RECURRING '@hourly'`,
 			errMsg: "failed to evaluate backup destination paths",
 		},
+		{
+			name:   "compact-after-incrementals-invalid",
+			user:   enterpriseUser,
+			query:  `CREATE SCHEDULE FOR BACKUP INTO 'nodelocal://1/backup' RECURRING '@hourly' WITH SCHEDULE OPTIONS compact_after_incrementals = '0'`,
+			errMsg: "compact_after_incrementals must be a positive integer",
+		},
+		{
+			name:   "compact-after-incrementals-full-always",
+			user:   enterpriseUser,
+			query:  `CREATE SCHEDULE FOR BACKUP INTO 'nodelocal://1/backup' RECURRING '@hourly' FULL BACKUP ALWAYS WITH SCHEDULE OPTIONS compact_after_incrementals = '4'`,
+			errMsg: "compact_after_incrementals requires a schedule with incremental backups",
+		},
 		{
 			name:   "missing-encryption-placeholder",
 			user:   enterpriseUser,
@@ -9,9 +9,13 @@
 package backupccl
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	io "io"
+	"path"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
@@ -25,6 +29,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	hlc "github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
 	"github.com/cockroachdb/errors"
 	gogotypes "github.com/gogo/protobuf/types"
 	"github.com/kr/pretty"
@@ -35,6 +40,14 @@ type sstSinkConf struct {
 	enc      *kvpb.FileEncryptionOptions
 	id       base.SQLInstanceID
 	settings *settings.Values
+
+	// filePoolPath, if set, is the path of the collection's file pool relative
+	// to the destination. Files are then buffered in memory and written to the
+	// pool under the hash of their contents when flushed.
+	filePoolPath string
+	// memMonitor, if set, accounts for the files buffered in memory when
+	// writing to a file pool.
+	memMonitor *mon.BytesMonitor
 }
 
 type fileSSTSink struct {
@@ -47,6 +60,10 @@ type fileSSTSink struct {
 	out     io.WriteCloser
 	outName string
 
+	// buf holds the contents of the file being written when the sink writes to
+	// a file pool.
+	buf bufferCloser
+
 	flushedFiles []backuppb.BackupManifest_File
 	flushedSize  int64
 
@@ -70,7 +87,12 @@ type fileSSTSink struct {
 }
 
 func makeFileSSTSink(conf sstSinkConf, dest cloud.ExternalStorage) *fileSSTSink {
-	return &fileSSTSink{conf: conf, dest: dest}
+	s := &fileSSTSink{conf: conf, dest: dest}
+	if conf.filePoolPath != "" && conf.memMonitor != nil {
+		acc := conf.memMonitor.MakeBoundAccount()
+		s.buf.acc = &acc
+	}
+	return s
 }
 
 func (s *fileSSTSink) Close() error {
@@ -81,6 +103,7 @@ func (s *fileSSTSink) Close() error {
 	if s.cancel != nil {
 		s.cancel()
 	}
+	defer s.buf.close()
 	if s.out != nil {
 		return s.out.Close()
 	}
@@ -126,6 +149,15 @@ func (s *fileSSTSink) flushFile(ctx context.Context) error {
 		return errors.Wrap(err, "writing SST")
 	}
 	wroteSize := s.sst.Meta.Size
+	if s.conf.filePoolPath != "" {
+		name, err := s.writeToFilePool(ctx)
+		if err != nil {
+			return err
+		}
+		for i := range s.flushedFiles {
+			s.flushedFiles[i].Path = name
+		}
+	}
 	s.outName = ""
 	s.out = nil
 
@@ -158,11 +190,41 @@ func (s *fileSSTSink) flushFile(ctx context.Context) error {
 	return nil
 }
 
+// writeToFilePool writes the buffered file to the file pool under the hash of
+// its contents, unless a file with the same contents is already present there,
+// and returns its path relative to the destination.
+func (s *fileSSTSink) writeToFilePool(ctx context.Context) (string, error) {
+	sum := sha256.Sum256(s.buf.Bytes())
+	name := path.Join(s.conf.filePoolPath, hex.EncodeToString(sum[:])+".sst")
+	defer s.buf.reset()
+
+	if _, err := s.dest.Size(ctx, name); err == nil {
+		log.VEventf(ctx, 2, "backup file %s is already present in the file pool", name)
+		return name, nil
+	} else if !errors.Is(err, cloud.ErrFileDoesNotExist) {
+		return "", errors.Wrapf(err, "checking for backup file %s in the file pool", name)
+	}
+	if err := cloud.WriteFile(ctx, s.dest, name, bytes.NewReader(s.buf.Bytes())); err != nil {
+		return "", errors.Wrap(err, "writing SST to the file pool")
+	}
+	return name, nil
+}
+
 func (s *fileSSTSink) open(ctx context.Context) error {
-	s.outName = generateUniqueSSTName(s.conf.id)
 	if s.ctx == nil {
 		s.ctx, s.cancel = context.WithCancel(ctx)
 	}
+	if s.conf.filePoolPath != "" {
+		// The name of the file is only known once it has been written, so it is
+		// buffered until it is flushed.
+		s.outName = ""
+		s.buf.ctx = s.ctx
+		s.buf.reset()
+		s.out = &s.buf
+		s.sst = storage.MakeBackupSSTWriter(ctx, s.dest.Settings(), s.out)
+		return nil
+	}
+	s.outName = generateUniqueSSTName(s.conf.id)
 	w, err := s.dest.Writer(s.ctx, s.outName)
 	if err != nil {
 		return err
@@ -243,6 +305,15 @@ func (s *fileSSTSink) write(ctx context.Context, resp exportedSpan) error {
 	s.completedSpans += resp.completedSpans
 	s.flushedSize += int64(len(resp.dataSST))
 
+	// Files written to a file pool are flushed after every exported span that
+	// ends at a key boundary, so that their contents do not depend on what else
+	// was exported alongside them and unchanged data produces identical files
+	// across backups.
+	if s.conf.filePoolPath != "" && resp.atKeyBoundary {
+		log.VEventf(ctx, 2, "flushing backup file of size %d to the file pool", s.flushedSize)
+		return s.flushFile(ctx)
+	}
+
 	// If our accumulated SST is now big enough, and we are positioned at the end
 	// of a range flush it.
 	if s.flushedSize > targetFileSize.Get(s.conf.settings) && resp.atKeyBoundary {
@@ -322,6 +393,46 @@ func (s *fileSSTSink) copyRangeKeys(dataSST []byte) error {
 	return nil
 }
 
+// bufferCloser is a bytes.Buffer that implements io.WriteCloser. If acc is
+// set, the memory used by the buffer is accounted for in it, and writes fail
+// once the account cannot grow.
+type bufferCloser struct {
+	bytes.Buffer
+	ctx context.Context
+	acc *mon.BoundAccount
+}
+
+// Write implements io.Writer.
+func (b *bufferCloser) Write(p []byte) (int, error) {
+	if b.acc != nil {
+		if err := b.acc.ResizeTo(b.ctx, int64(b.Len()+len(p))); err != nil {
+			return 0, errors.Wrap(err, "buffering SST for the file pool")
+		}
+	}
+	return b.Buffer.Write(p)
+}
+
+// Close implements io.Closer.
+func (*bufferCloser) Close() error {
+	return nil
+}
+
+// reset empties the buffer and releases the memory accounted for it.
+func (b *bufferCloser) reset() {
+	b.Buffer.Reset()
+	if b.acc != nil {
+		b.acc.Empty(b.ctx)
+	}
+}
+
+// close releases the account of the buffer.
+func (b *bufferCloser) close() {
+	b.Buffer = bytes.Buffer{}
+	if b.acc != nil && b.ctx != nil {
+		b.acc.Close(b.ctx)
+	}
+}
+
 func generateUniqueSSTName(nodeID base.SQLInstanceID) string {
 	// The data/ prefix, including a /, is intended to group SSTs in most of the
 	// common file/bucket browse UIs.
@@ -1030,7 +1030,9 @@ func restoreTypeCheck(
 	); err != nil {
 		return false, nil, err
 	}
-	if restoreStmt.Options.Detached {
+	if restoreStmt.RowsTable != nil {
+		header = restoreRowsHeader
+	} else if restoreStmt.Options.Detached {
 		header = jobs.DetachedJobExecutionResultHeader
 	} else {
 		header = jobs.BulkJobExecutionResultHeader
@@ -1064,6 +1066,10 @@ func restorePlanHook(
 		return nil, nil, nil, false, err
 	}
 
+	if restoreStmt.RowsTable != nil {
+		return restoreRowsPlanHook(ctx, restoreStmt, p)
+	}
+
 	if !restoreStmt.Options.SchemaOnly && restoreStmt.Options.VerifyData {
 		return nil, nil, nil, false,
 			errors.New("to set the verify_backup_table_data option, the schema_only option must be set")
@@ -0,0 +1,478 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudprivilege"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemaexpr"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree/treecmp"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/errors"
+)
+
+const restoreRowsStmtName = "RESTORE ROWS"
+
+// restoreRowsMaxBatchRows is the maximum number of rows RESTORE ROWS writes to
+// the target table in a single INSERT statement.
+const restoreRowsMaxBatchRows = 1000
+
+// restoreRowsMaxPlaceholders bounds the number of placeholders in a single
+// INSERT statement written by RESTORE ROWS.
+const restoreRowsMaxPlaceholders = 1 << 15
+
+// restoreRowsHeader is the header of the result of RESTORE ROWS.
+var restoreRowsHeader = colinfo.ResultColumns{
+	{Name: "rows_read", Typ: types.Int},
+	{Name: "rows_restored", Typ: types.Int},
+}
+
+// restoreRowsPlanHook implements RESTORE ROWS FROM <table> [WHERE <filter>]
+// FROM <subdir> IN <collection> [AS OF SYSTEM TIME <t>] INTO <target>.
+//
+// Rather than restoring descriptors, it reads the rows of the table directly
+// from the backup files as of the requested time, which must either be the
+// end time of a backup in the chain or be covered by its revision history,
+// and inserts the rows that match the filter into the existing target table
+// as the current user. Only the spans of the primary index that can contain
+// matching rows are read when the filter constrains a prefix of the primary
+// key to constant values.
+//
+// The rows are inserted in batches, each in its own transaction, so a failed
+// RESTORE ROWS may have written some of the matching rows; the target is
+// expected to be a staging table that can be cleared and written again.
+func restoreRowsPlanHook(
+	ctx context.Context, restoreStmt *tree.Restore, p sql.PlanHookState,
+) (sql.PlanHookRowFn, colinfo.ResultColumns, []sql.PlanNode, bool, error) {
+	opts := restoreStmt.Options
+	opts.EncryptionPassphrase, opts.DecryptionKMSURI, opts.IncrementalStorage = nil, nil, nil
+	if !opts.IsDefault() {
+		return nil, nil, nil, false, pgerror.Newf(pgcode.InvalidParameterValue,
+			"%s only supports the encryption_passphrase, kms and incremental_location options",
+			restoreRowsStmtName)
+	}
+	if !p.ExtendedEvalContext().TxnIsSingleStmt {
+		return nil, nil, nil, false, pgerror.Newf(pgcode.InvalidTransactionState,
+			"%s cannot be used inside a multi-statement transaction", restoreRowsStmtName)
+	}
+
+	target := restoreStmt.RowsInto.ToTableName()
+	prefix, targetDesc, err := p.ResolveMutableTableDescriptor(
+		ctx, &target, true /* required */, tree.ResolveRequireTableDesc,
+	)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	if err := p.CheckPrivilege(ctx, targetDesc, privilege.INSERT); err != nil {
+		return nil, nil, nil, false, err
+	}
+	target = tree.MakeTableNameFromPrefix(prefix.NamePrefix(), tree.Name(targetDesc.GetName()))
+
+	fn := func(ctx context.Context, _ []sql.PlanNode, resultsCh chan<- tree.Datums) error {
+		ctx, span := tracing.ChildSpan(ctx, restoreRowsStmtName)
+		defer span.Finish()
+
+		sendResult := func(read, written int64) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case resultsCh <- tree.Datums{tree.NewDInt(tree.DInt(read)), tree.NewDInt(tree.DInt(written))}:
+				return nil
+			}
+		}
+
+		chain, asOf, err := resolveRestoreRowsChain(ctx, restoreStmt, p)
+		if err != nil {
+			return err
+		}
+		defer chain.close(ctx)
+		reader, err := newBackupTableReader(ctx, p.ExecCfg(), chain, restoreStmt.RowsTable, asOf)
+		if err != nil {
+			return err
+		}
+
+		var filter tree.TypedExpr
+		if restoreStmt.RowsFilter != nil {
+			filter, err = makeRestoreRowsFilter(ctx, reader, restoreStmt.RowsFilter, p)
+			if err != nil {
+				return err
+			}
+			var empty bool
+			reader.spans, empty, err = constrainRestoreRowsSpans(ctx, reader, restoreStmt.RowsFilter, p)
+			if err != nil {
+				return err
+			}
+			if empty {
+				return sendResult(0, 0)
+			}
+		}
+
+		w := makeRestoreRowsWriter(p, reader, target, targetDesc)
+		evalCtx := p.ExtendedEvalContext().Context.Copy()
+		ivars := &schemaexpr.RowIndexedVarContainer{Cols: reader.columns}
+		for i, col := range reader.columns {
+			ivars.Mapping.Set(col.GetID(), i)
+		}
+		evalCtx.PushIVarContainer(ivars)
+		defer evalCtx.PopIVarContainer()
+
+		var read int64
+		if err := reader.readRows(ctx, asOf, func(row backupTableRow) error {
+			read++
+			if filter != nil {
+				ivars.CurSourceRow = row.datums
+				ok, err := eval.Expr(ctx, evalCtx, filter)
+				if err != nil {
+					return err
+				}
+				if ok != tree.DBoolTrue {
+					return nil
+				}
+			}
+			return w.add(ctx, backupTableResultDatums(reader.columns, row.datums))
+		}); err != nil {
+			return err
+		}
+		if err := w.flush(ctx); err != nil {
+			return err
+		}
+		return sendResult(read, w.written)
+	}
+	return fn, restoreRowsHeader, nil, false, nil
+}
+
+// resolveRestoreRowsChain evaluates the location and encryption arguments of
+// a RESTORE ROWS statement and resolves the chain of backups it reads from,
+// along with the time as of which the rows are read. The returned chain must
+// be closed.
+func resolveRestoreRowsChain(
+	ctx context.Context, restoreStmt *tree.Restore, p sql.PlanHookState,
+) (*backupChain, hlc.Timestamp, error) {
+	exprEval := p.ExprEvaluator(restoreRowsStmtName)
+	subdir, err := exprEval.String(ctx, restoreStmt.Subdir)
+	if err != nil {
+		return nil, hlc.Timestamp{}, err
+	}
+	if len(restoreStmt.From) != 1 {
+		return nil, hlc.Timestamp{}, pgerror.Newf(pgcode.InvalidParameterValue,
+			"%s does not support multiple backup collections; use the incremental_location option to "+
+				"read incremental backups from another collection", restoreRowsStmtName)
+	}
+	dest, err := exprEval.StringArray(ctx, tree.Exprs(restoreStmt.From[0]))
+	if err != nil {
+		return nil, hlc.Timestamp{}, err
+	}
+	opts := restoreStmt.Options
+	var incPaths []string
+	if opts.IncrementalStorage != nil {
+		incPaths, err = exprEval.StringArray(ctx, tree.Exprs(opts.IncrementalStorage))
+		if err != nil {
+			return nil, hlc.Timestamp{}, err
+		}
+	}
+	encryptionParams := jobspb.BackupEncryptionOptions{Mode: jobspb.EncryptionMode_None}
+	if opts.EncryptionPassphrase != nil {
+		if opts.DecryptionKMSURI != nil {
+			return nil, hlc.Timestamp{}, errors.New("cannot have both encryption_passphrase and kms option set")
+		}
+		passphrase, err := exprEval.String(ctx, opts.EncryptionPassphrase)
+		if err != nil {
+			return nil, hlc.Timestamp{}, err
+		}
+		encryptionParams.Mode = jobspb.EncryptionMode_Passphrase
+		encryptionParams.RawPassphrase = passphrase
+	} else if opts.DecryptionKMSURI != nil {
+		kms, err := exprEval.StringArray(ctx, tree.Exprs(opts.DecryptionKMSURI))
+		if err != nil {
+			return nil, hlc.Timestamp{}, err
+		}
+		encryptionParams.Mode = jobspb.EncryptionMode_KMS
+		encryptionParams.RawKmsUris = kms
+	}
+
+	var asOf hlc.Timestamp
+	if restoreStmt.AsOf.Expr != nil {
+		ts, err := p.EvalAsOfTimestamp(ctx, restoreStmt.AsOf)
+		if err != nil {
+			return nil, hlc.Timestamp{}, err
+		}
+		asOf = ts.Timestamp
+	}
+
+	if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, dest); err != nil {
+		return nil, hlc.Timestamp{}, err
+	}
+	if len(incPaths) > 0 {
+		if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, incPaths); err != nil {
+			return nil, hlc.Timestamp{}, err
+		}
+	}
+
+	chain, err := resolveBackupChain(
+		ctx, p.ExecCfg(), p.User(), dest, subdir, incPaths, encryptionParams, asOf,
+	)
+	if err != nil {
+		return nil, hlc.Timestamp{}, err
+	}
+	if !asOf.IsEmpty() {
+		if err := chain.validateTime(asOf); err != nil {
+			chain.close(ctx)
+			return nil, hlc.Timestamp{}, err
+		}
+	}
+	return chain, asOf, nil
+}
+
+// makeRestoreRowsFilter type-checks the filter of a RESTORE ROWS statement
+// against the columns of the table read from the backup. The column
+// references in the returned expression are ordinal references to the
+// reader's columns.
+func makeRestoreRowsFilter(
+	ctx context.Context, reader *backupTableReader, filter tree.Expr, p sql.PlanHookState,
+) (tree.TypedExpr, error) {
+	expr, err := tree.SimpleVisit(filter, func(expr tree.Expr) (bool, tree.Expr, error) {
+		name, ok := restoreRowsColumnName(expr)
+		if !ok {
+			return true, expr, nil
+		}
+		for i, col := range reader.columns {
+			if col.GetName() == name {
+				return false, tree.NewTypedOrdinalReference(i, col.GetType()), nil
+			}
+		}
+		return false, nil, pgerror.Newf(pgcode.UndefinedColumn,
+			"column %q does not exist in table %s in the backup", name, reader.table.GetName())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	semaCtx := p.SemaCtx()
+	defer semaCtx.Properties.Restore(semaCtx.Properties)
+	semaCtx.Properties.Require(restoreRowsStmtName, tree.RejectSpecial)
+	return tree.TypeCheckAndRequire(ctx, expr, semaCtx, types.Bool, restoreRowsStmtName)
+}
+
+// restoreRowsColumnName returns the name of the column the expression refers
+// to, if it is an unqualified column reference.
+func restoreRowsColumnName(expr tree.Expr) (string, bool) {
+	v, ok := expr.(tree.VarName)
+	if !ok {
+		return "", false
+	}
+	v, err := v.NormalizeVarName()
+	if err != nil {
+		return "", false
+	}
+	c, ok := v.(*tree.ColumnItem)
+	if !ok || c.TableName != nil {
+		return "", false
+	}
+	return string(c.ColumnName), true
+}
+
+func isRestoreRowsConstant(expr tree.Expr) bool {
+	switch expr.(type) {
+	case tree.Constant, tree.Datum:
+		return true
+	}
+	return false
+}
+
+// constrainRestoreRowsSpans returns the spans of the primary index that can
+// contain rows matching the filter of a RESTORE ROWS statement, if the filter
+// is a conjunction that constrains a prefix of the primary key columns to
+// constant values. It returns no spans if the filter does not constrain the
+// primary key, in which case the whole index must be read, and reports
+// whether the filter cannot match any row.
+func constrainRestoreRowsSpans(
+	ctx context.Context, reader *backupTableReader, filter tree.Expr, p sql.PlanHookState,
+) (_ roachpb.Spans, empty bool, _ error) {
+	// Collect the constants that the conjuncts of the filter equate columns
+	// with.
+	constants := make(map[string]tree.Expr)
+	var collect func(tree.Expr)
+	collect = func(expr tree.Expr) {
+		switch e := expr.(type) {
+		case *tree.AndExpr:
+			collect(e.Left)
+			collect(e.Right)
+		case *tree.ParenExpr:
+			collect(e.Expr)
+		case *tree.ComparisonExpr:
+			if e.Operator.Symbol != treecmp.EQ {
+				return
+			}
+			left, right := e.Left, e.Right
+			if isRestoreRowsConstant(left) {
+				left, right = right, left
+			}
+			if name, ok := restoreRowsColumnName(left); ok && isRestoreRowsConstant(right) {
+				constants[name] = right
+			}
+		}
+	}
+	collect(filter)
+
+	var colMap catalog.TableColMap
+	var values tree.Datums
+	keyCols := reader.spec.KeyColumns()
+	for i := range keyCols {
+		constant, ok := constants[keyCols[i].Name]
+		if !ok {
+			break
+		}
+		typed, err := tree.TypeCheckAndRequire(ctx, constant, p.SemaCtx(), keyCols[i].Type, restoreRowsStmtName)
+		if err != nil {
+			// The filter will fail to type-check or will be evaluated against
+			// every row; either way, there is nothing to constrain.
+			break
+		}
+		d, err := eval.Expr(ctx, &p.ExtendedEvalContext().Context, typed)
+		if err != nil {
+			return nil, false, err
+		}
+		colMap.Set(keyCols[i].ColumnID, len(values))
+		values = append(values, d)
+	}
+	if len(values) == 0 {
+		return nil, false, nil
+	}
+
+	keyPrefix := rowenc.MakeIndexKeyPrefix(reader.codec, reader.table.GetID(), reader.table.GetPrimaryIndexID())
+	span, containsNull, err := rowenc.EncodePartialIndexSpan(keyCols[:len(values)], colMap, values, keyPrefix)
+	if err != nil {
+		return nil, false, err
+	}
+	if containsNull {
+		return nil, true, nil
+	}
+	return roachpb.Spans{span}, false, nil
+}
+
+// restoreRowsWriter inserts the rows restored by RESTORE ROWS into the target
+// table in batches.
+type restoreRowsWriter struct {
+	p      sql.PlanHookState
+	target tree.TableName
+
+	// colIdxs are the ordinals in the rows read from the backup of the columns
+	// that are inserted, which are the non-computed columns of the table.
+	colIdxs   []int
+	colNames  tree.NameList
+	batchSize int
+
+	// casts holds, for each inserted column of a user-defined type, the cast
+	// of its placeholder to the type of the column in the target table, and is
+	// empty for other columns. The values of such columns refer to the types
+	// in the backup, which need not be the types of the target table even if
+	// they have the same name, so they are added as text, see
+	// backupTableResultDatums.
+	casts []string
+
+	batch   []interface{}
+	written int64
+}
+
+func makeRestoreRowsWriter(
+	p sql.PlanHookState,
+	reader *backupTableReader,
+	target tree.TableName,
+	targetDesc catalog.TableDescriptor,
+) *restoreRowsWriter {
+	w := &restoreRowsWriter{p: p, target: target}
+	for i, col := range reader.columns {
+		if col.IsComputed() {
+			continue
+		}
+		w.colIdxs = append(w.colIdxs, i)
+		w.colNames = append(w.colNames, tree.Name(col.GetName()))
+		var cast string
+		if col.GetType().UserDefined() {
+			cast = "::STRING"
+			// Text is cast to a built-in type of the target column on
+			// assignment, and the INSERT fails if there is no such column.
+			if targetCol := catalog.FindColumnByName(targetDesc, col.GetName()); targetCol != nil &&
+				targetCol.GetType().UserDefined() {
+				ref := tree.OIDTypeReference{OID: targetCol.GetType().Oid()}
+				cast += "::" + ref.SQLString()
+			}
+		}
+		w.casts = append(w.casts, cast)
+	}
+	w.batchSize = restoreRowsMaxBatchRows
+	if len(w.colIdxs)*w.batchSize > restoreRowsMaxPlaceholders {
+		w.batchSize = restoreRowsMaxPlaceholders / len(w.colIdxs)
+	}
+	return w
+}
+
+func (w *restoreRowsWriter) add(ctx context.Context, datums tree.Datums) error {
+	for _, idx := range w.colIdxs {
+		w.batch = append(w.batch, datums[idx])
+	}
+	if len(w.batch) >= w.batchSize*len(w.colIdxs) {
+		return w.flush(ctx)
+	}
+	return nil
+}
+
+func (w *restoreRowsWriter) flush(ctx context.Context) error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	numRows := len(w.batch) / len(w.colIdxs)
+	var buf strings.Builder
+	buf.WriteString("INSERT INTO ")
+	buf.WriteString(w.target.String())
+	buf.WriteString(" (")
+	buf.WriteString(w.colNames.String())
+	buf.WriteString(") VALUES ")
+	for i := 0; i < numRows; i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteByte('(')
+		for j := range w.colIdxs {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString((&tree.Placeholder{Idx: tree.PlaceholderIdx(i*len(w.colIdxs) + j)}).String())
+			buf.WriteString(w.casts[j])
+		}
+		buf.WriteByte(')')
+	}
+	n, err := w.p.ExecCfg().InternalDB.Executor().ExecEx(
+		ctx, "restore-rows", nil, /* txn */
+		sessiondata.InternalExecutorOverride{User: w.p.User()},
+		buf.String(), w.batch...,
+	)
+	if err != nil {
+		return errors.Wrapf(err, "writing restored rows to %s", w.target.String())
+	}
+	w.written += int64(n)
+	w.batch = w.batch[:0]
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+func TestRestoreRows(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 0
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	sqlDB.Exec(t, `CREATE TABLE d.t (
+		tenant_id INT, id INT, name STRING, upper_name STRING AS (upper(name)) STORED,
+		PRIMARY KEY (tenant_id, id)
+	)`)
+	sqlDB.Exec(t, `INSERT INTO d.t VALUES (41, 1, 'a'), (42, 1, 'b'), (42, 2, 'c'), (43, 1, 'd')`)
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO 'nodelocal://1/c' WITH revision_history`)
+
+	var beforeDelete string
+	sqlDB.QueryRow(t, `SELECT cluster_logical_timestamp()`).Scan(&beforeDelete)
+	sqlDB.Exec(t, `DELETE FROM d.t WHERE tenant_id = 42`)
+	sqlDB.Exec(t, `UPDATE d.t SET name = 'z' WHERE tenant_id = 41`)
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO LATEST IN 'nodelocal://1/c' WITH revision_history`)
+
+	sqlDB.Exec(t, `CREATE TABLE d.staging (LIKE d.t INCLUDING ALL)`)
+	const query = `RESTORE ROWS FROM d.t %s FROM LATEST IN 'nodelocal://1/c' %s INTO d.staging`
+	asOf := fmt.Sprintf("AS OF SYSTEM TIME %s", beforeDelete)
+
+	// Only the rows of the requested tenant are read when the filter constrains
+	// a prefix of the primary key.
+	sqlDB.CheckQueryResults(t, fmt.Sprintf(query, "WHERE tenant_id = 42", asOf),
+		[][]string{{"2", "2"}})
+	sqlDB.CheckQueryResults(t, `SELECT * FROM d.staging`, [][]string{
+		{"42", "1", "b", "B"},
+		{"42", "2", "c", "C"},
+	})
+
+	sqlDB.Exec(t, `DELETE FROM d.staging`)
+	sqlDB.CheckQueryResults(t, fmt.Sprintf(query, "WHERE tenant_id = 42 AND id = 2", asOf),
+		[][]string{{"1", "1"}})
+	sqlDB.CheckQueryResults(t, `SELECT id, name FROM d.staging`, [][]string{{"2", "c"}})
+
+	// Filters that do not constrain the primary key are applied to every row.
+	sqlDB.Exec(t, `DELETE FROM d.staging`)
+	sqlDB.CheckQueryResults(t, fmt.Sprintf(query, "WHERE name IN ('a', 'd')", asOf),
+		[][]string{{"4", "2"}})
+	sqlDB.CheckQueryResults(t, `SELECT tenant_id, name FROM d.staging`, [][]string{
+		{"41", "a"},
+		{"43", "d"},
+	})
+
+	// Without AS OF SYSTEM TIME, the rows are read as of the end of the chain.
+	sqlDB.Exec(t, `DELETE FROM d.staging`)
+	sqlDB.CheckQueryResults(t, fmt.Sprintf(query, "", ""), [][]string{{"2", "2"}})
+	sqlDB.CheckQueryResults(t, `SELECT tenant_id, name FROM d.staging`, [][]string{
+		{"41", "z"},
+		{"43", "d"},
+	})
+
+	// Values of user-defined types are written as values of the types of the
+	// target table, rather than of the types in the backup.
+	sqlDB.Exec(t, `CREATE TYPE d.status AS ENUM ('open', 'closed')`)
+	sqlDB.Exec(t, `CREATE TABLE d.e (id INT PRIMARY KEY, s d.status, h d.status[])`)
+	sqlDB.Exec(t, `INSERT INTO d.e VALUES (1, 'open', ARRAY['open', 'closed']), (2, NULL, NULL)`)
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO 'nodelocal://1/e'`)
+	sqlDB.Exec(t, `CREATE DATABASE other`)
+	sqlDB.Exec(t, `CREATE TYPE other.status AS ENUM ('closed', 'open')`)
+	sqlDB.Exec(t, `CREATE TABLE other.e (id INT PRIMARY KEY, s other.status, h other.status[])`)
+	sqlDB.CheckQueryResults(t,
+		`RESTORE ROWS FROM d.e FROM LATEST IN 'nodelocal://1/e' INTO other.e`, [][]string{{"2", "2"}})
+	sqlDB.CheckQueryResults(t, `SELECT id, s, h FROM other.e`, [][]string{
+		{"1", "open", "{open,closed}"},
+		{"2", "NULL", "NULL"},
+	})
+
+	sqlDB.CheckQueryResults(t, fmt.Sprintf(query, "WHERE tenant_id = NULL", asOf),
+		[][]string{{"0", "0"}})
+
+	sqlDB.ExpectErr(t, `column "nope" does not exist in table t in the backup`,
+		fmt.Sprintf(query, "WHERE nope = 1", asOf))
+	sqlDB.ExpectErr(t, `RESTORE ROWS only supports`,
+		`RESTORE ROWS FROM d.t FROM LATEST IN 'nodelocal://1/c' INTO d.staging WITH detached`)
+	sqlDB.ExpectErr(t, `relation "d.nope" does not exist`,
+		`RESTORE ROWS FROM d.t FROM LATEST IN 'nodelocal://1/c' INTO d.nope`)
+}
@@ -10,6 +10,7 @@ package backupccl
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl/backuppb"
@@ -283,6 +284,22 @@ func (e *scheduledBackupExecutor) GetCreateScheduleStatement(
 		},
 	}
 
+	// The compaction option is stored on the incremental schedule.
+	compactAfterIncrementals := args.CompactAfterIncrementals
+	if dependentSchedule != nil && !backupNode.AppendToLatest {
+		incArgs := &backuppb.ScheduledBackupExecutionArgs{}
+		if err := pbtypes.UnmarshalAny(dependentSchedule.ExecutionArgs().Args, incArgs); err != nil {
+			return "", errors.Wrap(err, "un-marshaling args")
+		}
+		compactAfterIncrementals = incArgs.CompactAfterIncrementals
+	}
+	if compactAfterIncrementals > 0 {
+		scheduleOptions = append(scheduleOptions, tree.KVOption{
+			Key:   optCompactAfterIncrementals,
+			Value: tree.NewDString(strconv.Itoa(int(compactAfterIncrementals))),
+		})
+	}
+
 	var destinations []string
 	for i := range backupNode.To {
 		dest, ok := backupNode.To[i].(*tree.StrVal)
@@ -406,6 +423,7 @@ func extractBackupStatement(sj *jobs.ScheduledJob) (*annotatedBackupStatement, e
 				Name: jobs.CreatedByScheduledJobs,
 				ID:   sj.ScheduleID(),
 			},
+			compactAfterIncrementals: args.CompactAfterIncrementals,
 		}, nil
 	}
 
@@ -41,6 +41,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/nstree"
 	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
 	"github.com/cockroachdb/cockroach/pkg/sql/exprutil"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgnotice"
 	"github.com/cockroachdb/cockroach/pkg/sql/protoreflect"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/catconstants"
@@ -191,6 +193,9 @@ func showBackupTypeCheck(
 	if backup.Path == nil && backup.InCollection != nil {
 		return showBackupsInCollectionTypeCheck(ctx, backup, p)
 	}
+	if backup.Details == tree.BackupTableRowsDetails || backup.Details == tree.BackupTableDiffDetails {
+		return showBackupTableTypeCheck(ctx, backup, p)
+	}
 	if err := exprutil.TypeCheck(
 		ctx, "SHOW BACKUP", p.SemaCtx(),
 		exprutil.Ints{
@@ -267,6 +272,10 @@ func showBackupPlanHook(
 		return cloudcheck.ShowCloudStorageTestPlanHook(ctx, p, loc, params)
 	}
 
+	if showStmt.Details == tree.BackupTableRowsDetails || showStmt.Details == tree.BackupTableDiffDetails {
+		return showBackupTablePlanHook(ctx, showStmt, p)
+	}
+
 	if showStmt.Path == nil && showStmt.InCollection != nil {
 		collection, err := exprEval.StringArray(
 			ctx, tree.Exprs(showStmt.InCollection),
@@ -1543,6 +1552,246 @@ func showBackupsInCollectionPlanHook(
 	return fn, showBackupsInCollectionHeader, nil, false, nil
 }
 
+func showBackupTableTypeCheck(
+	ctx context.Context, backup *tree.ShowBackup, p sql.PlanHookState,
+) (matched bool, header colinfo.ResultColumns, _ error) {
+	if err := exprutil.TypeCheck(ctx, showBackupTableStmtName(backup), p.SemaCtx(),
+		exprutil.Strings{
+			backup.Path,
+			backup.Options.EncryptionPassphrase,
+		},
+		exprutil.StringArrays{
+			tree.Exprs(backup.InCollection),
+			tree.Exprs(backup.Options.IncrementalStorage),
+			tree.Exprs(backup.Options.DecryptionKMSURI),
+		},
+	); err != nil {
+		return false, nil, err
+	}
+	// The result columns are the columns of the table in the backup, so the
+	// backup has to be read to know them, which requires the values of all of
+	// the arguments. A table in a backup can be queried with placeholders
+	// through the views created by ATTACH BACKUP instead.
+	args := append(tree.Exprs{backup.Path, backup.Options.EncryptionPassphrase},
+		tree.Exprs(backup.InCollection)...)
+	args = append(args, tree.Exprs(backup.Options.IncrementalStorage)...)
+	args = append(args, tree.Exprs(backup.Options.DecryptionKMSURI)...)
+	args = append(args, backup.DiffStart, backup.DiffEnd)
+	for _, arg := range args {
+		if _, ok := arg.(*tree.Placeholder); ok {
+			return false, nil, errors.WithHint(pgerror.Newf(pgcode.FeatureNotSupported,
+				"%s cannot be prepared with placeholders", showBackupTableStmtName(backup)),
+				"ATTACH BACKUP creates views of the tables in a backup that can be queried with placeholders")
+		}
+	}
+	header, err := showBackupTableHeader(ctx, backup, p)
+	if err != nil {
+		return false, nil, err
+	}
+	return true, header, nil
+}
+
+// showBackupTablePlanHook plans SHOW BACKUP TABLE and SHOW BACKUP DIFF TABLE,
+// which return the rows of a table in a backup, or the rows that changed
+// between two times covered by the backup, by reading them directly from the
+// backup's files. The statements can be used as data sources, so that the
+// contents of a backup can be queried without restoring it, e.g.
+//
+//	SELECT * FROM [SHOW BACKUP TABLE db.t FROM LATEST IN 'nodelocal://1/c'] WHERE ...
+//
+// The rows of SHOW BACKUP TABLE are read by a distributed flow and are not
+// returned in any particular order. The filters of the enclosing query are
+// not pushed down, so all of the table's rows are read.
+func showBackupTablePlanHook(
+	ctx context.Context, showStmt *tree.ShowBackup, p sql.PlanHookState,
+) (sql.PlanHookRowFn, colinfo.ResultColumns, []sql.PlanNode, bool, error) {
+	src, err := openShowBackupTableSource(ctx, showStmt, p)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	header := src.header()
+	// The statement may never run, so the memory reserved for the chain is
+	// released until it does.
+	src.close(ctx)
+
+	fn := func(ctx context.Context, _ []sql.PlanNode, resultsCh chan<- tree.Datums) error {
+		ctx, span := tracing.ChildSpan(ctx, showStmt.StatementTag())
+		defer span.Finish()
+
+		if err := src.chain.reopen(ctx); err != nil {
+			return err
+		}
+		defer src.close(ctx)
+		return src.run(ctx, p, func(datums tree.Datums) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case resultsCh <- datums:
+				return nil
+			}
+		})
+	}
+	return fn, header, nil, false, nil
+}
+
+func showBackupTableStmtName(showStmt *tree.ShowBackup) string {
+	if showStmt.Details == tree.BackupTableDiffDetails {
+		return "SHOW BACKUP DIFF TABLE"
+	}
+	return "SHOW BACKUP TABLE"
+}
+
+func showBackupTableHeader(
+	ctx context.Context, showStmt *tree.ShowBackup, p sql.PlanHookState,
+) (colinfo.ResultColumns, error) {
+	src, err := openShowBackupTableSource(ctx, showStmt, p)
+	if err != nil {
+		return nil, err
+	}
+	defer src.close(ctx)
+	return src.header(), nil
+}
+
+// showBackupTableSource produces the rows of a SHOW BACKUP TABLE or SHOW
+// BACKUP DIFF TABLE statement.
+type showBackupTableSource struct {
+	chain  *backupChain
+	reader *backupTableReader
+	// asOf is the time as of which the table is read, which is the end time of
+	// the diff for SHOW BACKUP DIFF TABLE.
+	asOf hlc.Timestamp
+
+	// differ and diffStart are only set for SHOW BACKUP DIFF TABLE.
+	differ    *backupTableDiffer
+	diffStart hlc.Timestamp
+}
+
+func (s *showBackupTableSource) header() colinfo.ResultColumns {
+	if s.differ != nil {
+		return s.differ.header()
+	}
+	return s.reader.header()
+}
+
+func (s *showBackupTableSource) run(
+	ctx context.Context, p sql.PlanHookState, fn func(tree.Datums) error,
+) error {
+	if s.differ != nil {
+		return s.differ.diff(ctx, s.diffStart, s.asOf, fn)
+	}
+	return s.reader.distReadRows(ctx, p, s.asOf, fn)
+}
+
+func (s *showBackupTableSource) close(ctx context.Context) {
+	s.chain.close(ctx)
+}
+
+// openShowBackupTableSource evaluates the arguments of a SHOW BACKUP TABLE or
+// SHOW BACKUP DIFF TABLE statement and resolves the table it names in the
+// backup. The returned source must be closed.
+func openShowBackupTableSource(
+	ctx context.Context, showStmt *tree.ShowBackup, p sql.PlanHookState,
+) (_ *showBackupTableSource, retErr error) {
+	opts := showStmt.Options
+	if opts.AsJson || opts.CheckFiles || opts.DebugIDs || opts.Privileges || opts.SkipSize ||
+		opts.DebugMetadataSST || opts.EncryptionInfoDir != nil {
+		return nil, pgerror.Newf(pgcode.InvalidParameterValue,
+			"%s only supports the encryption_passphrase, kms and incremental_location options",
+			showBackupTableStmtName(showStmt))
+	}
+
+	exprEval := p.ExprEvaluator(showBackupTableStmtName(showStmt))
+	subdir, err := exprEval.String(ctx, showStmt.Path)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := exprEval.StringArray(ctx, tree.Exprs(showStmt.InCollection))
+	if err != nil {
+		return nil, err
+	}
+	var incPaths []string
+	if opts.IncrementalStorage != nil {
+		incPaths, err = exprEval.StringArray(ctx, tree.Exprs(opts.IncrementalStorage))
+		if err != nil {
+			return nil, err
+		}
+	}
+	encryptionParams := jobspb.BackupEncryptionOptions{Mode: jobspb.EncryptionMode_None}
+	if opts.EncryptionPassphrase != nil {
+		passphrase, err := exprEval.String(ctx, opts.EncryptionPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		encryptionParams.Mode = jobspb.EncryptionMode_Passphrase
+		encryptionParams.RawPassphrase = passphrase
+	} else if opts.DecryptionKMSURI != nil {
+		kms, err := exprEval.StringArray(ctx, tree.Exprs(opts.DecryptionKMSURI))
+		if err != nil {
+			return nil, err
+		}
+		encryptionParams.Mode = jobspb.EncryptionMode_KMS
+		encryptionParams.RawKmsUris = kms
+	}
+
+	evalTime := func(asOf tree.AsOfClause) (hlc.Timestamp, error) {
+		if asOf.Expr == nil {
+			return hlc.Timestamp{}, nil
+		}
+		ts, err := p.EvalAsOfTimestamp(ctx, asOf)
+		if err != nil {
+			return hlc.Timestamp{}, err
+		}
+		return ts.Timestamp, nil
+	}
+	src := &showBackupTableSource{}
+	if showStmt.Details == tree.BackupTableDiffDetails {
+		if src.diffStart, err = evalTime(tree.AsOfClause{Expr: showStmt.DiffStart}); err != nil {
+			return nil, err
+		}
+		if src.asOf, err = evalTime(tree.AsOfClause{Expr: showStmt.DiffEnd}); err != nil {
+			return nil, err
+		}
+		if !src.diffStart.Less(src.asOf) {
+			return nil, pgerror.Newf(pgcode.InvalidParameterValue,
+				"the start time %s of the diff must be before its end time %s", src.diffStart, src.asOf)
+		}
+	} else if src.asOf, err = evalTime(showStmt.AsOf); err != nil {
+		return nil, err
+	}
+
+	if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, dest); err != nil {
+		return nil, err
+	}
+	if len(incPaths) > 0 {
+		if err := cloudprivilege.CheckDestinationPrivileges(ctx, p, incPaths); err != nil {
+			return nil, err
+		}
+	}
+
+	src.chain, err = resolveBackupChain(
+		ctx, p.ExecCfg(), p.User(), dest, subdir, incPaths, encryptionParams, src.asOf,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if retErr != nil {
+			src.close(ctx)
+		}
+	}()
+	src.reader, err = newBackupTableReader(ctx, p.ExecCfg(), src.chain, showStmt.Table, src.asOf)
+	if err != nil {
+		return nil, err
+	}
+	if showStmt.Details == tree.BackupTableDiffDetails {
+		src.differ, err = newBackupTableDiffer(ctx, src.reader, src.diffStart)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return src, nil
+}
+
 func init() {
 	sql.AddPlanHook("backupccl.showBackupPlanHook", showBackupPlanHook, showBackupTypeCheck)
 }
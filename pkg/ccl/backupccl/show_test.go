@@ -950,3 +950,154 @@ func TestShowBackupCheckFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestShowBackupTable(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 0
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	sqlDB.Exec(t, `CREATE TYPE d.greeting AS ENUM ('hi', 'hello')`)
+	sqlDB.Exec(t, `CREATE TABLE d.t (
+		id INT PRIMARY KEY, name STRING, g d.greeting, FAMILY f1 (id, name), FAMILY f2 (g)
+	)`)
+	sqlDB.Exec(t, `CREATE SCHEMA d.sc`)
+	sqlDB.Exec(t, `CREATE TABLE d.sc.t (id INT PRIMARY KEY)`)
+	sqlDB.Exec(t, `INSERT INTO d.t VALUES (1, 'a', 'hi'), (2, 'b', 'hello'), (3, 'c', NULL)`)
+	sqlDB.Exec(t, `INSERT INTO d.sc.t VALUES (10)`)
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO 'nodelocal://1/c' WITH revision_history`)
+
+	var beforeUpdate string
+	sqlDB.QueryRow(t, `SELECT cluster_logical_timestamp()`).Scan(&beforeUpdate)
+	sqlDB.Exec(t, `UPDATE d.t SET name = 'z' WHERE id = 1`)
+	sqlDB.Exec(t, `DELETE FROM d.t WHERE id = 2`)
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO LATEST IN 'nodelocal://1/c' WITH revision_history`)
+
+	// Writes after the last backup are not visible.
+	sqlDB.Exec(t, `INSERT INTO d.t VALUES (4, 'd', 'hi')`)
+
+	const query = `SELECT * FROM [SHOW BACKUP TABLE %s FROM LATEST IN 'nodelocal://1/c'%s] ORDER BY id`
+	sqlDB.CheckQueryResults(t, fmt.Sprintf(query, "d.t", ""), [][]string{
+		{"1", "z", "hi"},
+		{"3", "c", "NULL"},
+	})
+	sqlDB.CheckQueryResults(t, fmt.Sprintf(query, "d.public.t",
+		fmt.Sprintf(" AS OF SYSTEM TIME %s", beforeUpdate)), [][]string{
+		{"1", "a", "hi"},
+		{"2", "b", "hello"},
+		{"3", "c", "NULL"},
+	})
+	sqlDB.CheckQueryResults(t, fmt.Sprintf(query, "sc.t", ""), [][]string{{"10"}})
+	sqlDB.CheckQueryResults(t,
+		`SELECT name FROM [SHOW BACKUP TABLE d.t FROM LATEST IN 'nodelocal://1/c'] WHERE id > 1`,
+		[][]string{{"c"}})
+
+	sqlDB.ExpectErr(t, `table "d.nope" does not exist in the backup`,
+		fmt.Sprintf(query, "d.nope", ""))
+	sqlDB.ExpectErr(t, `table name "t" is ambiguous in the backup`,
+		fmt.Sprintf(query, "t", ""))
+	sqlDB.ExpectErr(t, `SHOW BACKUP TABLE only supports`,
+		fmt.Sprintf(query, "d.t", " WITH check_files"))
+	sqlDB.ExpectErr(t, `SHOW BACKUP TABLE cannot be prepared with placeholders`,
+		`SELECT * FROM [SHOW BACKUP TABLE d.t FROM LATEST IN $1]`, "nodelocal://1/c")
+}
+
+func TestAttachBackup(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 100
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, multiNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	// Read the table with a processor per range of the bank table.
+	sqlDB.Exec(t, `SET CLUSTER SETTING backup.restore_span.target_size = '1B'`)
+	sqlDB.Exec(t, `CREATE SCHEMA data.sc`)
+	sqlDB.Exec(t, `CREATE TABLE data.sc.t (id INT PRIMARY KEY, name STRING)`)
+	sqlDB.Exec(t, `INSERT INTO data.sc.t VALUES (1, 'a')`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO 'nodelocal://1/c' WITH revision_history`)
+
+	var beforeUpdate string
+	sqlDB.QueryRow(t, `SELECT cluster_logical_timestamp()`).Scan(&beforeUpdate)
+	sqlDB.Exec(t, `UPDATE data.sc.t SET name = 'b' WHERE id = 1`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN 'nodelocal://1/c' WITH revision_history`)
+
+	sqlDB.Exec(t, `ATTACH BACKUP FROM LATEST IN 'nodelocal://1/c' AS snapshot`)
+	sqlDB.Exec(t, fmt.Sprintf(
+		`ATTACH BACKUP DATABASE data FROM LATEST IN 'nodelocal://1/c' AS before AS OF SYSTEM TIME %s`,
+		beforeUpdate))
+
+	// Later backups and writes are not visible in the attached backups.
+	sqlDB.Exec(t, `UPDATE data.sc.t SET name = 'c' WHERE id = 1`)
+	sqlDB.Exec(t, `DELETE FROM data.bank WHERE id < 10`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO 'nodelocal://1/c' WITH revision_history`)
+
+	sqlDB.CheckQueryResults(t, `SELECT count(*), sum(id) FROM snapshot.bank`,
+		[][]string{{"100", "4950"}})
+	require.Equal(t, [][]string{{"5"}},
+		sqlDB.QueryStr(t, `SELECT id FROM snapshot.public.bank WHERE id = $1`, 5))
+	require.Equal(t, [][]string{{"b"}},
+		sqlDB.QueryStr(t, `SELECT name FROM snapshot.sc.t WHERE id = $1`, 1))
+	sqlDB.CheckQueryResults(t, `SELECT name FROM before.sc.t`, [][]string{{"a"}})
+
+	sqlDB.ExpectErr(t, `ATTACH BACKUP does not support URIs with secrets in them`,
+		`ATTACH BACKUP FROM LATEST IN 'nodelocal://user:secret@1/c' AS other`)
+	sqlDB.ExpectErr(t, `ATTACH BACKUP does not support the encryption_passphrase option`,
+		`ATTACH BACKUP FROM LATEST IN 'nodelocal://1/c' AS other WITH encryption_passphrase = 'x'`)
+	sqlDB.ExpectErr(t, `database "nope" does not exist in the backup`,
+		`ATTACH BACKUP DATABASE nope FROM LATEST IN 'nodelocal://1/c' AS other`)
+
+	// Dropping the database detaches the backup.
+	sqlDB.Exec(t, `DROP DATABASE snapshot CASCADE`)
+	sqlDB.ExpectErr(t, `relation "snapshot.bank" does not exist`, `SELECT * FROM snapshot.bank`)
+}
+
+func TestShowBackupDiff(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 0
+	_, sqlDB, _, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts, InitManualReplication)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	sqlDB.Exec(t, `CREATE TABLE d.t (id INT PRIMARY KEY, name STRING)`)
+	sqlDB.Exec(t, `INSERT INTO d.t VALUES (1, 'a'), (2, 'b'), (3, 'c')`)
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO 'nodelocal://1/c' WITH revision_history`)
+
+	var start, end string
+	sqlDB.QueryRow(t, `SELECT cluster_logical_timestamp()`).Scan(&start)
+	sqlDB.Exec(t, `UPDATE d.t SET name = 'z' WHERE id = 1`)
+	sqlDB.Exec(t, `DELETE FROM d.t WHERE id = 2`)
+	// Rewriting a row with the same values is not a change.
+	sqlDB.Exec(t, `UPDATE d.t SET name = 'c' WHERE id = 3`)
+	sqlDB.Exec(t, `INSERT INTO d.t VALUES (4, 'd')`)
+	sqlDB.QueryRow(t, `SELECT cluster_logical_timestamp()`).Scan(&end)
+	sqlDB.Exec(t, `INSERT INTO d.t VALUES (5, 'e')`)
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO LATEST IN 'nodelocal://1/c' WITH revision_history`)
+
+	const query = `SELECT change, before_id, before_name, after_id, after_name
+FROM [SHOW BACKUP DIFF TABLE d.t FROM LATEST IN 'nodelocal://1/c' BETWEEN %s AND %s]`
+	sqlDB.CheckQueryResults(t, fmt.Sprintf(query, start, end), [][]string{
+		{"update", "1", "a", "1", "z"},
+		{"delete", "2", "b", "NULL", "NULL"},
+		{"insert", "NULL", "NULL", "4", "d"},
+	})
+
+	sqlDB.ExpectErr(t, `the start time .* of the diff must be before its end time`,
+		fmt.Sprintf(query, end, start))
+
+	// A column that is dropped and added again with the same name is a
+	// different column, so the rows cannot be compared.
+	sqlDB.Exec(t, `ALTER TABLE d.t DROP COLUMN name`)
+	sqlDB.Exec(t, `ALTER TABLE d.t ADD COLUMN name STRING`)
+	var afterReAdd string
+	sqlDB.QueryRow(t, `SELECT cluster_logical_timestamp()`).Scan(&afterReAdd)
+	sqlDB.Exec(t, `BACKUP DATABASE d INTO LATEST IN 'nodelocal://1/c' WITH revision_history`)
+	sqlDB.ExpectErr(t, `the columns of table "t" changed between the start and end times: `+
+		`column "name" at the end time is not the same column as "name" at the start time`,
+		fmt.Sprintf(query, start, afterReAdd))
+}
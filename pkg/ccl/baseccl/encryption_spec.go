@@ -34,6 +34,11 @@ type StoreEncryptionSpec struct {
 	Path           string
 	KeyPath        string
 	OldKeyPath     string
+	// KMSURI is set if store keys are generated by CockroachDB and wrapped by
+	// the KMS at this URI, instead of being read from KeyPath. OldKeyPath is
+	// optional in that case; it is only needed to read a store that was
+	// previously encrypted with key files.
+	KMSURI         string
 	RotationPeriod time.Duration
 }
 
@@ -47,12 +52,28 @@ func (es StoreEncryptionSpec) ToEncryptionOptions() ([]byte, error) {
 		},
 		DataKeyRotationPeriod: int64(es.RotationPeriod / time.Second),
 	}
+	if es.KMSURI != "" {
+		opts.KeySource = EncryptionKeySource_KMS
+		opts.KeyFiles = nil
+		opts.Kms = &EncryptionKMS{
+			Uri:    es.KMSURI,
+			OldKey: es.OldKeyPath,
+		}
+	}
 
 	return protoutil.Marshal(&opts)
 }
 
 // String returns a fully parsable version of the encryption spec.
 func (es StoreEncryptionSpec) String() string {
+	if es.KMSURI != "" {
+		if es.OldKeyPath == "" {
+			return fmt.Sprintf("path=%s,kms=%s,rotation-period=%s",
+				es.Path, es.KMSURI, es.RotationPeriod)
+		}
+		return fmt.Sprintf("path=%s,kms=%s,old-key=%s,rotation-period=%s",
+			es.Path, es.KMSURI, es.OldKeyPath, es.RotationPeriod)
+	}
 	// All fields are set.
 	return fmt.Sprintf("path=%s,key=%s,old-key=%s,rotation-period=%s",
 		es.Path, es.KeyPath, es.OldKeyPath, es.RotationPeriod)
@@ -116,6 +137,8 @@ func NewStoreEncryptionSpec(value string) (StoreEncryptionSpec, error) {
 					return StoreEncryptionSpec{}, err
 				}
 			}
+		case "kms":
+			es.KMSURI = value
 		case "rotation-period":
 			var err error
 			es.RotationPeriod, err = time.ParseDuration(value)
@@ -131,6 +154,12 @@ func NewStoreEncryptionSpec(value string) (StoreEncryptionSpec, error) {
 	if es.Path == "" {
 		return StoreEncryptionSpec{}, fmt.Errorf("no path specified")
 	}
+	if es.KMSURI != "" {
+		if es.KeyPath != "" {
+			return StoreEncryptionSpec{}, fmt.Errorf("key and kms cannot both be specified")
+		}
+		return es, nil
+	}
 	if es.KeyPath == "" {
 		return StoreEncryptionSpec{}, fmt.Errorf("no key specified")
 	}
@@ -39,6 +39,10 @@ func TestNewStoreEncryptionSpec(t *testing.T) {
 		{"path=data", "no key specified", StoreEncryptionSpec{}},
 		{"path=data,key=new.key", "no old-key specified", StoreEncryptionSpec{}},
 
+		// KMS.
+		{"path=data,kms=", "no value specified for kms", StoreEncryptionSpec{}},
+		{"path=data,key=new.key,kms=aws-kms:///key?REGION=us-east-1", "key and kms cannot both be specified", StoreEncryptionSpec{}},
+
 		// Rotation period.
 		{"path=data,key=new.key,old-key=old.key,rotation-period", "field not in the form <key>=<value>: rotation-period", StoreEncryptionSpec{}},
 		{"path=data,key=new.key,old-key=old.key,rotation-period=", "no value specified for rotation-period", StoreEncryptionSpec{}},
@@ -50,6 +54,8 @@ func TestNewStoreEncryptionSpec(t *testing.T) {
 		{"path=/data,key=/new.key,old-key=/old.key,rotation-period=1h", "", StoreEncryptionSpec{Path: "/data", KeyPath: "/new.key", OldKeyPath: "/old.key", RotationPeriod: time.Hour}},
 		{"path=/data,key=plain,old-key=/old.key,rotation-period=1h", "", StoreEncryptionSpec{Path: "/data", KeyPath: "plain", OldKeyPath: "/old.key", RotationPeriod: time.Hour}},
 		{"path=/data,key=/new.key,old-key=plain,rotation-period=1h", "", StoreEncryptionSpec{Path: "/data", KeyPath: "/new.key", OldKeyPath: "plain", RotationPeriod: time.Hour}},
+		{"path=/data,kms=aws-kms:///key?REGION=us-east-1", "", StoreEncryptionSpec{Path: "/data", KMSURI: "aws-kms:///key?REGION=us-east-1", RotationPeriod: DefaultRotationPeriod}},
+		{"path=/data,kms=gs:///key?AUTH=implicit,old-key=/old.key,rotation-period=1h", "", StoreEncryptionSpec{Path: "/data", KMSURI: "gs:///key?AUTH=implicit", OldKeyPath: "/old.key", RotationPeriod: time.Hour}},
 	}
 
 	for i, testCase := range testCases {
@@ -748,7 +748,7 @@ func TestAvroSchema(t *testing.T) {
 	})
 }
 
-func (f *avroSchemaField) defaultValueNative() (interface{}, bool) {
+func avroFieldDefaultValueNative(f *avroSchemaField) (interface{}, bool) {
 	schemaType := f.SchemaType
 	if union, ok := schemaType.([]avroSchemaType); ok {
 		// "Default values for union fields correspond to the first schema in
@@ -801,7 +801,7 @@ func adjustNative(native map[string]interface{}, writerSchema, readerSchema *avr
 			// default value, and writer's schema does not have a field with the
 			// same name, then the reader should use the default value from its
 			// field."
-			if readerFieldDefault, ok := readerField.defaultValueNative(); ok {
+			if readerFieldDefault, ok := avroFieldDefaultValueNative(readerField); ok {
 				native[readerField.Name] = readerFieldDefault
 			}
 		}
@@ -860,56 +860,6 @@ func TestAvroMigration(t *testing.T) {
 	}
 }
 
-func TestDecimalRatRoundtrip(t *testing.T) {
-	defer leaktest.AfterTest(t)()
-	defer log.Scope(t).Close(t)
-
-	t.Run(`table`, func(t *testing.T) {
-		tests := []struct {
-			scale int32
-			dec   *apd.Decimal
-		}{
-			{0, apd.New(0, 0)},
-			{0, apd.New(1, 0)},
-			{0, apd.New(-1, 0)},
-			{0, apd.New(123, 0)},
-			{1, apd.New(0, -1)},
-			{1, apd.New(1, -1)},
-			{1, apd.New(123, -1)},
-			{5, apd.New(1, -5)},
-		}
-		for d, test := range tests {
-			rat, err := decimalToRat(*test.dec, test.scale)
-			require.NoError(t, err)
-			roundtrip := ratToDecimal(rat, test.scale)
-			if test.dec.CmpTotal(&roundtrip) != 0 {
-				t.Errorf(`%d: %s != %s`, d, test.dec, &roundtrip)
-			}
-		}
-	})
-	t.Run(`error`, func(t *testing.T) {
-		_, err := decimalToRat(*apd.New(1, -2), 1)
-		require.EqualError(t, err, "0.01 will not roundtrip at scale 1")
-		_, err = decimalToRat(*apd.New(1, -1), 2)
-		require.EqualError(t, err, "0.1 will not roundtrip at scale 2")
-		_, err = decimalToRat(apd.Decimal{Form: apd.Infinite}, 0)
-		require.EqualError(t, err, "cannot convert Infinite form decimal")
-	})
-	t.Run(`rand`, func(t *testing.T) {
-		rng, _ := randutil.NewTestRand()
-		precision := rng.Int31n(10) + 1
-		scale := rng.Int31n(precision + 1)
-		coeff := rng.Int63n(int64(math.Pow10(int(precision))))
-		dec := apd.New(coeff, -scale)
-		rat, err := decimalToRat(*dec, scale)
-		require.NoError(t, err)
-		roundtrip := ratToDecimal(rat, scale)
-		if dec.CmpTotal(&roundtrip) != 0 {
-			t.Errorf(`%s != %s`, dec, &roundtrip)
-		}
-	})
-}
-
 func benchmarkEncodeType(b *testing.B, typ *types.T, encRow rowenc.EncDatumRow) {
 	defer leaktest.AfterTest(b)()
 	defer log.Scope(b).Close(b)
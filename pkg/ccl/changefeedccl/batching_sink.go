@@ -76,6 +76,10 @@ type batchingSink struct {
 	pacer        *admission.Pacer
 	pacerFactory func() *admission.Pacer
 
+	// dlq, if non-nil, receives the messages of batches which the sink
+	// permanently rejected instead of failing the sink.
+	dlq deadLetterQueue
+
 	termErr error
 	wg      ctxgroup.Group
 	hasher  hash.Hash32
@@ -230,6 +234,14 @@ func (s *batchingSink) getConcreteType() sinkType {
 	return s.concreteType
 }
 
+var _ sinkWithDeadLetterQueue = (*batchingSink)(nil)
+
+// setDeadLetterQueue implements the sinkWithDeadLetterQueue interface. It must
+// be called before any rows are emitted.
+func (s *batchingSink) setDeadLetterQueue(dlq deadLetterQueue) {
+	s.dlq = dlq
+}
+
 // sinkBatch stores an in-progress/complete batch of messages, along with
 // metadata related to the batch.
 type sinkBatch struct {
@@ -244,6 +256,11 @@ type sinkBatch struct {
 
 	alloc  kvevent.Alloc
 	hasher hash.Hash32
+
+	// messages retains the contents of the batch so that they can be routed to
+	// a dead letter queue should the sink reject the batch. It is only
+	// populated when the sink has a dead letter queue.
+	messages []deadLetter
 }
 
 // FinalizePayload closes the writer to produce a payload that is ready to be
@@ -273,7 +290,8 @@ func hashToInt(h hash.Hash32, buf []byte) int {
 }
 
 // Append adds the contents of a kvEvent to the batch, merging its alloc pool.
-func (sb *sinkBatch) Append(e *rowEvent) {
+// If retain is true, the message is also kept in the batch's messages.
+func (sb *sinkBatch) Append(e *rowEvent, topic string, retain bool) {
 	if sb.isEmpty() {
 		sb.bufferTime = timeutil.Now()
 	}
@@ -288,6 +306,15 @@ func (sb *sinkBatch) Append(e *rowEvent) {
 		sb.mvcc = e.mvcc
 	}
 
+	if retain {
+		sb.messages = append(sb.messages, deadLetter{
+			topic: topic,
+			key:   e.key,
+			value: e.val,
+			mvcc:  e.mvcc,
+		})
+	}
+
 	sb.alloc.Merge(&e.alloc)
 }
 
@@ -297,6 +324,20 @@ func (s *batchingSink) handleError(err error) {
 	}
 }
 
+// deadLetterBatch records every message of a batch which the sink permanently
+// rejected in the dead letter queue. Since the sink rejects batches as a whole,
+// the rejection error is attached to each of the messages.
+func (s *batchingSink) deadLetterBatch(ctx context.Context, batch *sinkBatch, rejectErr error) error {
+	for _, m := range batch.messages {
+		m.reason = deadLetterReasonSinkRejected
+		m.err = rejectErr
+		if err := s.dlq.Record(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *batchingSink) newBatchBuffer(topic string) *sinkBatch {
 	batch := newSinkBatch()
 	batch.buffer = s.client.MakeBatchBuffer(topic)
@@ -322,7 +363,12 @@ func (s *batchingSink) runBatchingWorker(ctx context.Context) {
 		s.metrics.recordSinkIOInflightChange(int64(batch.numMessages))
 		return s.client.Flush(ctx, batch.payload)
 	}
-	ioEmitter := newParallelIO(ctx, s.retryOpts, s.ioWorkers, ioHandler, s.metrics)
+	// Messages rejected by the sink are only given up on if they can be dead
+	// lettered; otherwise they're retried like any other error.
+	isPermanent := func(err error) bool {
+		return s.dlq != nil && isSinkRejectedMessage(err)
+	}
+	ioEmitter := newParallelIO(ctx, s.retryOpts, s.ioWorkers, ioHandler, s.metrics, isPermanent)
 	defer ioEmitter.Close()
 
 	// Flushing requires tracking the number of inflight messages and confirming
@@ -333,9 +379,17 @@ func (s *batchingSink) runBatchingWorker(ctx context.Context) {
 	handleResult := func(result *ioResult) {
 		batch, _ := result.request.(*sinkBatch)
 
+		deadLettered := false
+		if result.err != nil && s.dlq != nil && isSinkRejectedMessage(result.err) {
+			log.Warningf(ctx, "sink rejected batch of %d messages, routing to dead letter queue: %v",
+				batch.numMessages, result.err)
+			result.err = s.deadLetterBatch(ctx, batch, result.err)
+			deadLettered = result.err == nil
+		}
+
 		if result.err != nil {
 			s.handleError(result.err)
-		} else {
+		} else if !deadLettered {
 			s.metrics.recordEmittedBatch(
 				batch.bufferTime, batch.numMessages, batch.mvcc, batch.numKVBytes, sinkDoesNotCompress,
 			)
@@ -441,7 +495,7 @@ func (s *batchingSink) runBatchingWorker(ctx context.Context) {
 					topicBatches[topic] = batchBuffer
 				}
 
-				batchBuffer.Append(r)
+				batchBuffer.Append(r, topic, s.dlq != nil)
 				if s.knobs.OnAppend != nil {
 					s.knobs.OnAppend(r)
 				}
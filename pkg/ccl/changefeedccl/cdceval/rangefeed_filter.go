@@ -0,0 +1,263 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package cdceval
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree/treecmp"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/lib/pq/oid"
+)
+
+// RangefeedFilterForExpression returns a filter which the leaseholders of the
+// ranges watched by a changefeed evaluate in order to discard events before
+// they're sent to the changefeed. Select clause expression assumed to be
+// normalized.
+//
+// The filter restricts events to the target column family, and includes the
+// conjuncts of the WHERE clause which compare a key column or a column of the
+// family with a constant. The rest of the predicate is only evaluated by the
+// changefeed, which also re-evaluates the conjuncts included in the filter.
+// If the expression only references some of the columns of the family, the
+// filter also projects the events' values onto them. Returns nil if the
+// servers can't discard any data.
+func RangefeedFilterForExpression(
+	codec keys.SQLCodec,
+	descr catalog.TableDescriptor,
+	schemaTS hlc.Timestamp,
+	target jobspb.ChangefeedTargetSpecification,
+	sc *tree.SelectClause,
+) (*kvpb.RangeFeedFilter, error) {
+	d, err := newEventDescriptorForTarget(descr, target, schemaTS, false, false)
+	if err != nil {
+		return nil, err
+	}
+	family, err := catalog.MustFindFamilyByID(descr, d.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var preds []row.RangefeedFilterPredicate
+	if sc.Where != nil {
+		watched := descr.GetPrimaryIndex().CollectKeyColumnIDs()
+		watched.UnionWith(catalog.MakeTableColSet(family.ColumnIDs...))
+		preds = rangefeedPredicates(descr, watched, sc.Where.Expr)
+	}
+	projection := rangefeedProjection(descr, family, sc)
+	if len(preds) == 0 && !d.HasOtherFamilies && projection == nil {
+		return nil, nil
+	}
+	filter, err := row.MakeRangefeedFilter(codec, descr, d.FamilyID, preds)
+	if err != nil {
+		return nil, err
+	}
+	if projection != nil {
+		filter.Projection = &kvpb.RangeFeedFilterProjection{ColumnIDs: projection}
+	}
+	return filter, nil
+}
+
+// rangefeedProjection returns the IDs, in ascending order, of the columns of
+// the given family which are referenced by the select clause, either directly
+// or through cdc_prev. Returns nil if the clause may reference all of them:
+// because it references every column, references the whole row through a star
+// or the cdc_prev tuple, or references a virtual column, which may be computed
+// from any of them.
+func rangefeedProjection(
+	desc catalog.TableDescriptor, family *descpb.ColumnFamilyDescriptor, sc *tree.SelectClause,
+) []uint32 {
+	stored := catalog.MakeTableColSet(family.ColumnIDs...).Difference(
+		desc.GetPrimaryIndex().CollectKeyColumnIDs())
+	var referenced catalog.TableColSet
+	all := false
+	_, _ = tree.SimpleStmtVisit(sc, func(expr tree.Expr) (bool, tree.Expr, error) {
+		if all {
+			return false, expr, nil
+		}
+		if name, ok := expr.(*tree.UnresolvedName); ok {
+			vn, err := name.NormalizeVarName()
+			if err != nil {
+				all = true
+				return false, expr, nil
+			}
+			switch e := vn.(type) {
+			case *tree.ColumnItem:
+				// The current and the previous values of a column are referenced
+				// by the column's name, so the table qualifier doesn't matter.
+				col := catalog.FindColumnByTreeName(desc, e.ColumnName)
+				if col == nil || col.IsVirtual() {
+					all = true
+				} else {
+					referenced.Add(col.GetID())
+				}
+			default:
+				all = true
+			}
+			return false, expr, nil
+		}
+		switch expr.(type) {
+		case tree.UnqualifiedStar, *tree.AllColumnsSelector, *tree.TupleStar:
+			all = true
+			return false, expr, nil
+		}
+		return true, expr, nil
+	})
+	referenced = referenced.Intersection(stored)
+	if all || referenced.Equals(stored) {
+		return nil
+	}
+	ids := make([]uint32, 0, referenced.Len())
+	for _, id := range referenced.Ordered() {
+		ids = append(ids, uint32(id))
+	}
+	return ids
+}
+
+// rangefeedOperators maps the comparison operators which can be evaluated in a
+// rangefeed filter to the filter's operators.
+var rangefeedOperators = map[treecmp.ComparisonOperatorSymbol]kvpb.RangeFeedFilterPredicate_Operator{
+	treecmp.EQ: kvpb.RangeFeedFilterPredicate_EQ,
+	treecmp.NE: kvpb.RangeFeedFilterPredicate_NE,
+	treecmp.LT: kvpb.RangeFeedFilterPredicate_LT,
+	treecmp.LE: kvpb.RangeFeedFilterPredicate_LE,
+	treecmp.GT: kvpb.RangeFeedFilterPredicate_GT,
+	treecmp.GE: kvpb.RangeFeedFilterPredicate_GE,
+}
+
+// flippedRangefeedOperators maps operators to the operators which are
+// equivalent when their operands are swapped.
+var flippedRangefeedOperators = map[kvpb.RangeFeedFilterPredicate_Operator]kvpb.RangeFeedFilterPredicate_Operator{
+	kvpb.RangeFeedFilterPredicate_EQ: kvpb.RangeFeedFilterPredicate_EQ,
+	kvpb.RangeFeedFilterPredicate_NE: kvpb.RangeFeedFilterPredicate_NE,
+	kvpb.RangeFeedFilterPredicate_LT: kvpb.RangeFeedFilterPredicate_GT,
+	kvpb.RangeFeedFilterPredicate_LE: kvpb.RangeFeedFilterPredicate_GE,
+	kvpb.RangeFeedFilterPredicate_GT: kvpb.RangeFeedFilterPredicate_LT,
+	kvpb.RangeFeedFilterPredicate_GE: kvpb.RangeFeedFilterPredicate_LE,
+}
+
+// rangefeedPredicates returns the conjuncts of the given predicate which can be
+// evaluated in a rangefeed filter: NULL checks of a watched column, and
+// comparisons between a watched column and a constant.
+func rangefeedPredicates(
+	desc catalog.TableDescriptor, watched catalog.TableColSet, expr tree.Expr,
+) []row.RangefeedFilterPredicate {
+	switch e := tree.StripParens(expr).(type) {
+	case *tree.AndExpr:
+		return append(rangefeedPredicates(desc, watched, e.Left),
+			rangefeedPredicates(desc, watched, e.Right)...)
+	case *tree.IsNullExpr:
+		if col := rangefeedColumn(desc, watched, e.Expr); col != nil {
+			return []row.RangefeedFilterPredicate{
+				{ColumnID: col.GetID(), Op: kvpb.RangeFeedFilterPredicate_IS_NULL},
+			}
+		}
+	case *tree.IsNotNullExpr:
+		if col := rangefeedColumn(desc, watched, e.Expr); col != nil {
+			return []row.RangefeedFilterPredicate{
+				{ColumnID: col.GetID(), Op: kvpb.RangeFeedFilterPredicate_IS_NOT_NULL},
+			}
+		}
+	case *tree.ComparisonExpr:
+		op, ok := rangefeedOperators[e.Operator.Symbol]
+		if !ok {
+			return nil
+		}
+		col, constant := rangefeedColumn(desc, watched, e.Left), e.Right
+		if col == nil {
+			col, constant = rangefeedColumn(desc, watched, e.Right), e.Left
+			op = flippedRangefeedOperators[op]
+		}
+		if col == nil {
+			return nil
+		}
+		if d := rangefeedConstant(constant, col.GetType()); d != nil {
+			return []row.RangefeedFilterPredicate{{ColumnID: col.GetID(), Op: op, Value: d}}
+		}
+	}
+	return nil
+}
+
+// rangefeedColumn returns the column referenced by the given expression if it
+// is a watched column which can be evaluated in a rangefeed filter, and nil
+// otherwise.
+func rangefeedColumn(
+	desc catalog.TableDescriptor, watched catalog.TableColSet, expr tree.Expr,
+) catalog.Column {
+	name, ok := tree.StripParens(expr).(*tree.UnresolvedName)
+	if !ok || name.Star || name.NumParts != 1 {
+		return nil
+	}
+	col := catalog.FindColumnByTreeName(desc, tree.Name(name.Parts[0]))
+	if col == nil || !col.Public() || col.IsVirtual() || !watched.Contains(col.GetID()) {
+		return nil
+	}
+	// Only types whose datums compare the same way as the SQL comparison
+	// operators do are supported.
+	switch typ := col.GetType(); typ.Family() {
+	case types.IntFamily, types.DecimalFamily, types.BoolFamily, types.BytesFamily, types.UuidFamily:
+		return col
+	case types.StringFamily:
+		if typ.Oid() == oid.T_text || typ.Oid() == oid.T_varchar {
+			return col
+		}
+	}
+	return nil
+}
+
+// rangefeedConstant returns the value of the given expression as a datum of the
+// given type if it is a non-NULL literal, and nil otherwise.
+func rangefeedConstant(expr tree.Expr, typ *types.T) tree.Datum {
+	semaCtx := tree.MakeSemaContext()
+	var te tree.TypedExpr
+	var err error
+	switch c := tree.StripParens(expr).(type) {
+	case *tree.NumVal:
+		te, err = c.ResolveAsType(context.Background(), &semaCtx, typ)
+	case *tree.StrVal:
+		te, err = c.ResolveAsType(context.Background(), &semaCtx, typ)
+	case *tree.DBool:
+		te = c
+	case *tree.AnnotateTypeExpr:
+		if t, ok := c.Type.(*types.T); ok && t.Identical(typ) && rangefeedCastIsNoop(typ) {
+			return rangefeedConstant(c.Expr, typ)
+		}
+		return nil
+	case *tree.CastExpr:
+		if t, ok := c.Type.(*types.T); ok && t.Identical(typ) && rangefeedCastIsNoop(typ) {
+			return rangefeedConstant(c.Expr, typ)
+		}
+		return nil
+	default:
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	d, ok := te.(tree.Datum)
+	if !ok || d == tree.DNull || !d.ResolvedType().Equivalent(typ) {
+		return nil
+	}
+	return d
+}
+
+// rangefeedCastIsNoop returns whether casting a literal to the given type
+// yields the same value as resolving the literal as the type, i.e. whether the
+// type doesn't truncate or round its values.
+func rangefeedCastIsNoop(typ *types.T) bool {
+	return typ.Width() == 0 && typ.Precision() == 0
+}
@@ -0,0 +1,187 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package cdceval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/cdctest"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangefeedFilterForExpression(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.Background())
+
+	sqlDB := sqlutils.MakeSQLRunner(db)
+	sqlDB.ExecMultiple(t,
+		`CREATE TABLE foo (
+a INT PRIMARY KEY,
+b STRING,
+c INT,
+d CHAR(3),
+e INT AS (c + 1) VIRTUAL,
+extra STRING,
+FAMILY main (a, b, c, d),
+FAMILY extra (extra)
+)`,
+		`CREATE TABLE bar (a INT PRIMARY KEY, b STRING)`,
+	)
+
+	codec := s.ExecutorConfig().(sql.ExecutorConfig).Codec
+	fooDesc := cdctest.GetHydratedTableDescriptor(t, s.ExecutorConfig(), "foo")
+	barDesc := cdctest.GetHydratedTableDescriptor(t, s.ExecutorConfig(), "bar")
+	schemaTS := s.Clock().Now()
+	mainFamily := jobspb.ChangefeedTargetSpecification{
+		Type:       jobspb.ChangefeedTargetSpecification_COLUMN_FAMILY,
+		TableID:    fooDesc.GetID(),
+		FamilyName: "main",
+	}
+
+	type pred struct {
+		col string
+		op  kvpb.RangeFeedFilterPredicate_Operator
+	}
+	for _, tc := range []struct {
+		name   string
+		expr   string
+		target jobspb.ChangefeedTargetSpecification
+		// exp are the expected predicates; nil if no filter is expected.
+		exp []pred
+		// proj are the expected projected columns; nil if no projection is
+		// expected.
+		proj []uint32
+	}{
+		{
+			name:   "single family without predicate",
+			expr:   "SELECT * FROM bar",
+			target: jobspb.ChangefeedTargetSpecification{TableID: barDesc.GetID()},
+		},
+		{
+			name:   "single family with unsupported predicate",
+			expr:   "SELECT * FROM bar WHERE length(b) > 3 OR a = 1",
+			target: jobspb.ChangefeedTargetSpecification{TableID: barDesc.GetID()},
+		},
+		{
+			name:   "single family",
+			expr:   "SELECT * FROM bar WHERE a > 5 AND b IS NOT NULL",
+			target: jobspb.ChangefeedTargetSpecification{TableID: barDesc.GetID()},
+			exp: []pred{
+				{"a", kvpb.RangeFeedFilterPredicate_GT},
+				{"b", kvpb.RangeFeedFilterPredicate_IS_NOT_NULL},
+			},
+		},
+		{
+			name:   "single family with projection",
+			expr:   "SELECT a, event_op() FROM bar",
+			target: jobspb.ChangefeedTargetSpecification{TableID: barDesc.GetID()},
+			exp:    []pred{},
+			proj:   []uint32{},
+		},
+		{
+			name:   "other families",
+			expr:   "SELECT a, b FROM foo",
+			target: mainFamily,
+			exp:    []pred{},
+			proj:   []uint32{2},
+		},
+		{
+			name:   "other families with star",
+			expr:   "SELECT * FROM foo",
+			target: mainFamily,
+			exp:    []pred{},
+		},
+		{
+			name:   "previous values",
+			expr:   "SELECT a, cdc_prev.d FROM foo WHERE foo.b = cdc_prev.b",
+			target: mainFamily,
+			exp:    []pred{},
+			proj:   []uint32{2, 4},
+		},
+		{
+			name:   "previous row",
+			expr:   "SELECT a, b, row_to_json((cdc_prev).*) FROM foo",
+			target: mainFamily,
+			exp:    []pred{},
+		},
+		{
+			name:   "constant on the left",
+			expr:   "SELECT a, b FROM foo WHERE 5 <= c AND (b = 'x':::STRING)",
+			target: mainFamily,
+			exp: []pred{
+				{"c", kvpb.RangeFeedFilterPredicate_GE},
+				{"b", kvpb.RangeFeedFilterPredicate_EQ},
+			},
+			proj: []uint32{2, 3},
+		},
+		{
+			name:   "unsupported conjuncts",
+			expr:   "SELECT a, b FROM foo WHERE c = a AND d = 'abc' AND e > 1 AND c > 1.5 AND cdc_prev.c = 1 AND a != 3",
+			target: mainFamily,
+			exp: []pred{
+				{"a", kvpb.RangeFeedFilterPredicate_NE},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sc, err := ParseChangefeedExpression(tc.expr)
+			require.NoError(t, err)
+			desc := fooDesc
+			if tc.target.TableID == barDesc.GetID() {
+				desc = barDesc
+			}
+			filter, err := RangefeedFilterForExpression(codec, desc, schemaTS, tc.target, sc)
+			require.NoError(t, err)
+			if tc.exp == nil {
+				require.Nil(t, filter)
+				return
+			}
+			require.NotNil(t, filter)
+			var preds []pred
+			for _, p := range filter.Predicates {
+				col := filter.IndexFetchSpec.FetchedColumns[p.ColumnOrdinal]
+				preds = append(preds, pred{col.Name, p.Op})
+			}
+			if len(preds) == 0 {
+				preds = []pred{}
+			}
+			require.Equal(t, tc.exp, preds)
+			if tc.proj == nil {
+				require.Nil(t, filter.Projection)
+			} else {
+				require.NotNil(t, filter.Projection)
+				require.Equal(t, tc.proj, filter.Projection.ColumnIDs)
+			}
+		})
+	}
+
+	// Conjuncts on columns which aren't stored in the watched family are left
+	// to the changefeed.
+	extraFamily := mainFamily
+	extraFamily.FamilyName = "extra"
+	sc, err := ParseChangefeedExpression("SELECT extra FROM foo WHERE b = 'x'")
+	require.NoError(t, err)
+	filter, err := RangefeedFilterForExpression(codec, fooDesc, schemaTS, extraFamily, sc)
+	require.NoError(t, err)
+	require.Empty(t, filter.Predicates)
+	require.Equal(t, []uint32{1}, filter.FamilyIDs)
+	require.Nil(t, filter.Projection)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobsprofiler"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvclient/kvcoord"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql"
@@ -215,6 +216,30 @@ func fetchSpansForTables(
 		sd, tableDescs[0], initialHighwater, target, sc)
 }
 
+// rangefeedFilterForTables returns the filter evaluated by the leaseholders of
+// the watched ranges to discard events which the changefeed expression would
+// filter out anyway. Returns nil if the changefeed has no expression.
+func rangefeedFilterForTables(
+	execCtx sql.JobExecContext,
+	tableDescs []catalog.TableDescriptor,
+	details jobspb.ChangefeedDetails,
+	schemaTS hlc.Timestamp,
+) (*kvpb.RangeFeedFilter, error) {
+	// Expressions of changefeeds created prior to
+	// clusterversion.V23_1_ChangefeedExpressionProductionReady have different
+	// semantics; don't bother filtering their events.
+	if details.Select == "" || details.SessionData == nil || len(tableDescs) != 1 {
+		return nil, nil
+	}
+	sc, err := cdceval.ParseChangefeedExpression(details.Select)
+	if err != nil {
+		return nil, pgerror.Wrap(err, pgcode.InvalidParameterValue,
+			"could not parse changefeed expression")
+	}
+	return cdceval.RangefeedFilterForExpression(execCtx.ExecCfg().Codec,
+		tableDescs[0], schemaTS, details.TargetSpecifications[0], sc)
+}
+
 // startDistChangefeed starts distributed changefeed execution.
 func startDistChangefeed(
 	ctx context.Context,
@@ -240,6 +265,10 @@ func startDistChangefeed(
 		return err
 	}
 	localState.trackedSpans = trackedSpans
+	rangefeedFilter, err := rangefeedFilterForTables(execCtx, tableDescs, details, schemaTS)
+	if err != nil {
+		return err
+	}
 
 	// Changefeed flows handle transactional consistency themselves.
 	var noTxn *kv.Txn
@@ -252,7 +281,7 @@ func startDistChangefeed(
 		checkpoint = progress.Checkpoint
 	}
 	p, planCtx, err := makePlan(execCtx, jobID, details, initialHighWater,
-		trackedSpans, checkpoint, localState.drainingNodes)(ctx, dsp)
+		trackedSpans, rangefeedFilter, checkpoint, localState.drainingNodes)(ctx, dsp)
 	if err != nil {
 		return err
 	}
@@ -330,6 +359,7 @@ func makePlan(
 	details jobspb.ChangefeedDetails,
 	initialHighWater hlc.Timestamp,
 	trackedSpans []roachpb.Span,
+	rangefeedFilter *kvpb.RangeFeedFilter,
 	checkpoint *jobspb.ChangefeedProgress_Checkpoint,
 	drainingNodes []roachpb.NodeID,
 ) func(context.Context, *sql.DistSQLPlanner) (*sql.PhysicalPlan, *sql.PlanningCtx, error) {
@@ -413,12 +443,13 @@ func makePlan(
 			}
 
 			aggregatorSpecs[i] = &execinfrapb.ChangeAggregatorSpec{
-				Watches:    watches,
-				Checkpoint: aggregatorCheckpoint,
-				Feed:       details,
-				UserProto:  execCtx.User().EncodeProto(),
-				JobID:      jobID,
-				Select:     execinfrapb.Expression{Expr: details.Select},
+				Watches:         watches,
+				Checkpoint:      aggregatorCheckpoint,
+				Feed:            details,
+				UserProto:       execCtx.User().EncodeProto(),
+				JobID:           jobID,
+				Select:          execinfrapb.Expression{Expr: details.Select},
+				RangefeedFilter: rangefeedFilter,
 			}
 		}
 
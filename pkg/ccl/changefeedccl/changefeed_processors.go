@@ -66,6 +66,9 @@ type changeAggregator struct {
 	// sink is the Sink to write rows to. Resolved timestamps are never written
 	// by changeAggregator.
 	sink EventSink
+	// dlq, if non-nil, receives messages that could not be encoded or were
+	// permanently rejected by the sink when on_error='dead_letter' is set.
+	dlq deadLetterQueue
 	// changedRowBuf, if non-nil, contains changed rows to be emitted. Anything
 	// queued in `resolvedSpanBuf` is dependent on these having been emitted, so
 	// this one must be empty before moving on to that one.
@@ -299,6 +302,19 @@ func (ca *changeAggregator) Start(ctx context.Context) {
 		ca.changedRowBuf = &b.buf
 	}
 
+	ca.dlq, err = makeDeadLetterQueue(ctx, ca.flowCtx.Cfg, opts, ca.spec.User(), ca.spec.JobID, recorder)
+	if err != nil {
+		err = changefeedbase.MarkRetryableError(err)
+		ca.MoveToDraining(err)
+		ca.cancel()
+		return
+	}
+	if ca.dlq != nil {
+		if s, ok := ca.sink.(sinkWithDeadLetterQueue); ok {
+			s.setDeadLetterQueue(ca.dlq)
+		}
+	}
+
 	// If the initial scan was disabled the highwater would've already been forwarded
 	needsInitialScan := ca.frontier.Frontier().IsEmpty()
 
@@ -320,7 +336,7 @@ func (ca *changeAggregator) Start(ctx context.Context) {
 	ca.sink = &errorWrapperSink{wrapped: ca.sink}
 	ca.eventConsumer, ca.sink, err = newEventConsumer(
 		ctx, ca.flowCtx.Cfg, ca.spec, feed, ca.frontier.SpanFrontier(), kvFeedHighWater,
-		ca.sink, ca.metrics, ca.sliMetrics, ca.knobs)
+		ca.sink, ca.dlq, ca.metrics, ca.sliMetrics, ca.knobs)
 
 	if err != nil {
 		// Early abort in the case that there is an error setting up the consumption.
@@ -440,6 +456,7 @@ func (ca *changeAggregator) makeKVFeedCfg(
 		SchemaFeed:              sf,
 		Knobs:                   ca.knobs.FeedKnobs,
 		UseMux:                  changefeedbase.UseMuxRangeFeed.Get(&cfg.Settings.SV),
+		RangeFeedFilter:         ca.spec.RangefeedFilter,
 	}, nil
 }
 
@@ -520,6 +537,9 @@ func (ca *changeAggregator) close() {
 		// Best effort: context is often cancel by now, so we expect to see an error
 		_ = ca.sink.Close()
 	}
+	if ca.dlq != nil {
+		_ = ca.dlq.Close()
+	}
 	ca.memAcc.Close(ca.Ctx())
 	if ca.kvFeedMemMon != nil {
 		ca.kvFeedMemMon.Stop(ca.Ctx())
@@ -667,7 +687,15 @@ func (ca *changeAggregator) flushBufferedEvents() error {
 	if err := ca.eventConsumer.Flush(ca.Ctx()); err != nil {
 		return err
 	}
-	return ca.sink.Flush(ca.Ctx())
+	if err := ca.sink.Flush(ca.Ctx()); err != nil {
+		return err
+	}
+	// Messages rejected by the sink are recorded in the dead letter queue
+	// during the sink flush, so the queue must be flushed afterwards.
+	if ca.dlq != nil {
+		return ca.dlq.Flush(ca.Ctx())
+	}
+	return nil
 }
 
 // noteResolvedSpan periodically flushes Frontier progress from the current
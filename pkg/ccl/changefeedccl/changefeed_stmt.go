@@ -932,6 +932,21 @@ func validateSink(
 	if err := canarySink.Close(); err != nil {
 		return err
 	}
+	if canarySink.getConcreteType() == sinkTypePostgres &&
+		!opts.IsSet(changefeedbase.OptResolvedTimestamps) {
+		// The postgres sink only applies changes once they are resolved.
+		return errors.Errorf(`this sink requires the %s option`, changefeedbase.OptResolvedTimestamps)
+	}
+	canaryDLQ, err := makeDeadLetterQueue(ctx, &p.ExecCfg().DistSQLSrv.ServerConfig, opts,
+		p.User(), jobID, sli)
+	if err != nil {
+		return err
+	}
+	if canaryDLQ != nil {
+		if err := canaryDLQ.Close(); err != nil {
+			return err
+		}
+	}
 	// If there's no projection we may need to force some options to ensure messages
 	// have enough information.
 	if details.Select == `` {
@@ -1225,6 +1240,10 @@ func (b *changefeedResumer) handleChangefeedError(
 			log.Warningf(ctx, errorFmt, changefeedErr, changefeedbase.OptOnError, changefeedbase.OptOnErrorPause)
 			return nil
 		}, errorMessage)
+	// rows and messages which can be dead lettered never surface as job
+	// errors, so anything else fails the job.
+	case changefeedbase.OptOnErrorDeadLetter:
+		return changefeedErr
 	default:
 		return errors.Wrapf(changefeedErr, "unrecognized option value: %s=%s for handling error",
 			changefeedbase.OptOnError, details.Opts[changefeedbase.OptOnError])
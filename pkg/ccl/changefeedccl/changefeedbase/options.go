@@ -100,6 +100,7 @@ const (
 	OptUnordered               = `unordered`
 	OptVirtualColumns          = `virtual_columns`
 	OptExecutionLocality       = `execution_locality`
+	OptDeadLetterQueue         = `dead_letter_queue`
 
 	OptVirtualColumnsOmitted VirtualColumnVisibility = `omitted`
 	OptVirtualColumnsNull    VirtualColumnVisibility = `null`
@@ -153,6 +154,10 @@ const (
 
 	OptOnErrorFail  OnErrorType = `fail`
 	OptOnErrorPause OnErrorType = `pause`
+	// OptOnErrorDeadLetter routes rows that cannot be encoded, and messages
+	// that the sink permanently rejects, to the dead letter queue configured
+	// with OptDeadLetterQueue. All other errors fail the changefeed.
+	OptOnErrorDeadLetter OnErrorType = `dead_letter`
 
 	DeprecatedOptFormatAvro                   = `experimental_avro`
 	DeprecatedSinkSchemeCloudStorageAzure     = `experimental-azure`
@@ -168,9 +173,12 @@ const (
 	DeprecatedSinkSchemeHTTPS = `https`
 
 	// OptKafkaSinkConfig is a JSON configuration for kafka sink (kafkaSinkConfig).
-	OptKafkaSinkConfig   = `kafka_sink_config`
-	OptPubsubSinkConfig  = `pubsub_sink_config`
-	OptWebhookSinkConfig = `webhook_sink_config`
+	OptKafkaSinkConfig    = `kafka_sink_config`
+	OptPubsubSinkConfig   = `pubsub_sink_config`
+	OptWebhookSinkConfig  = `webhook_sink_config`
+	OptPostgresSinkConfig = `postgres_sink_config`
+	OptNATSSinkConfig     = `nats_sink_config`
+	OptAMQPSinkConfig     = `amqp_sink_config`
 
 	// OptSink allows users to alter the Sink URI of an existing changefeed.
 	// Note that this option is only allowed for alter changefeed statements.
@@ -182,9 +190,14 @@ const (
 	SinkParamCACert                 = `ca_cert`
 	SinkParamClientCert             = `client_cert`
 	SinkParamClientKey              = `client_key`
+	SinkParamCreds                  = `creds`
+	SinkParamExchange               = `exchange`
 	SinkParamFileSize               = `file_size`
+	SinkParamNKeySeed               = `nkey_seed`
 	SinkParamPartitionFormat        = `partition_format`
+	SinkParamSchema                 = `schema`
 	SinkParamSchemaTopic            = `schema_topic`
+	SinkParamTableFormat            = `table_format`
 	SinkParamTLSEnabled             = `tls_enabled`
 	SinkParamSkipTLSVerify          = `insecure_tls_skip_verify`
 	SinkParamTopicPrefix            = `topic_prefix`
@@ -195,9 +208,15 @@ const (
 	SinkSchemeCloudStorageHTTPS     = `file-https`
 	SinkSchemeCloudStorageNodelocal = `nodelocal`
 	SinkSchemeCloudStorageS3        = `s3`
+	SinkSchemeCloudStorageSFTP      = `sftp`
 	SinkSchemeExperimentalSQL       = `experimental-sql`
+	SinkSchemeAMQP                  = `amqp`
+	SinkSchemeAMQPS                 = `amqps`
 	SinkSchemeKafka                 = `kafka`
+	SinkSchemeNATS                  = `nats`
 	SinkSchemeNull                  = `null`
+	SinkSchemePostgres              = `postgres`
+	SinkSchemePostgresql            = `postgresql`
 	SinkSchemeWebhookHTTP           = `webhook-http`
 	SinkSchemeWebhookHTTPS          = `webhook-https`
 	SinkSchemeExternalConnection    = `external`
@@ -212,6 +231,10 @@ const (
 	SinkParamSASLScopes             = `sasl_scopes`
 	SinkParamSASLGrantType          = `sasl_grant_type`
 
+	// SinkTableFormatIceberg is the value of SinkParamTableFormat that writes
+	// cloud storage changefeed output as Apache Iceberg tables.
+	SinkTableFormatIceberg = `iceberg`
+
 	RegistryParamCACert     = `ca_cert`
 	RegistryParamClientCert = `client_cert`
 	RegistryParamClientKey  = `client_key`
@@ -341,13 +364,17 @@ var ChangefeedOptionExpectValues = map[string]OptionPermittedValues{
 	OptKafkaSinkConfig:                    jsonOption,
 	OptPubsubSinkConfig:                   jsonOption,
 	OptWebhookSinkConfig:                  jsonOption,
+	OptPostgresSinkConfig:                 jsonOption,
+	OptNATSSinkConfig:                     jsonOption,
+	OptAMQPSinkConfig:                     jsonOption,
 	OptWebhookAuthHeader:                  stringOption,
 	OptWebhookClientTimeout:               durationOption,
-	OptOnError:                            enum("pause", "fail"),
+	OptOnError:                            enum("pause", "fail", "dead_letter"),
 	OptMetricsScope:                       stringOption,
 	OptUnordered:                          flagOption,
 	OptVirtualColumns:                     enum("omitted", "null"),
 	OptExecutionLocality:                  stringOption,
+	OptDeadLetterQueue:                    stringOption,
 }
 
 // CommonOptions is options common to all sinks
@@ -360,7 +387,7 @@ var CommonOptions = makeStringSet(OptCursor, OptEndTime, OptEnvelope,
 	OptOnError,
 	OptInitialScan, OptNoInitialScan, OptInitialScanOnly, OptUnordered, OptCustomKeyColumn,
 	OptMinCheckpointFrequency, OptMetricsScope, OptVirtualColumns, Topics, OptExpirePTSAfter,
-	OptExecutionLocality,
+	OptExecutionLocality, OptDeadLetterQueue,
 )
 
 // SQLValidOptions is options exclusive to SQL sink
@@ -378,13 +405,23 @@ var WebhookValidOptions = makeStringSet(OptWebhookAuthHeader, OptWebhookClientTi
 // PubsubValidOptions is options exclusive to pubsub sink
 var PubsubValidOptions = makeStringSet(OptPubsubSinkConfig)
 
+// PostgresValidOptions is options exclusive to the postgres sink
+var PostgresValidOptions = makeStringSet(OptPostgresSinkConfig)
+
+// NATSValidOptions is options exclusive to the NATS JetStream sink
+var NATSValidOptions = makeStringSet(OptNATSSinkConfig)
+
+// AMQPValidOptions is options exclusive to the AMQP sink
+var AMQPValidOptions = makeStringSet(OptAMQPSinkConfig)
+
 // ExternalConnectionValidOptions is options exclusive to the external
 // connection sink.
 //
 // TODO(adityamaru): Some of these options should be supported when creating the
 // external connection rather than when setting up the changefeed. Move them once
 // we support `CREATE EXTERNAL CONNECTION ... WITH <options>`.
-var ExternalConnectionValidOptions = unionStringSets(SQLValidOptions, KafkaValidOptions, CloudStorageValidOptions, WebhookValidOptions, PubsubValidOptions)
+var ExternalConnectionValidOptions = unionStringSets(SQLValidOptions, KafkaValidOptions, CloudStorageValidOptions, WebhookValidOptions, PubsubValidOptions,
+	NATSValidOptions, AMQPValidOptions)
 
 // CaseInsensitiveOpts options which supports case Insensitive value
 var CaseInsensitiveOpts = makeStringSet(OptFormat, OptEnvelope, OptCompression, OptSchemaChangeEvents,
@@ -418,6 +455,7 @@ var RedactedOptions = map[string]redactionFunc{
 	OptWebhookAuthHeader:       redactSimple,
 	SinkParamClientKey:         redactSimple,
 	OptConfluentSchemaRegistry: RedactUserFromURI,
+	OptDeadLetterQueue:         redactSimple,
 }
 
 // NoLongerExperimental aliases options prefixed with experimental that no longer need to be
@@ -916,6 +954,24 @@ func (s StatementOptions) GetPubsubConfigJSON() SinkSpecificJSONConfig {
 	return s.getJSONValue(OptPubsubSinkConfig)
 }
 
+// GetPostgresConfigJSON returns arbitrary json to be interpreted
+// by the postgres sink.
+func (s StatementOptions) GetPostgresConfigJSON() SinkSpecificJSONConfig {
+	return s.getJSONValue(OptPostgresSinkConfig)
+}
+
+// GetNATSConfigJSON returns arbitrary json to be interpreted
+// by the NATS JetStream sink.
+func (s StatementOptions) GetNATSConfigJSON() SinkSpecificJSONConfig {
+	return s.getJSONValue(OptNATSSinkConfig)
+}
+
+// GetAMQPConfigJSON returns arbitrary json to be interpreted
+// by the AMQP sink.
+func (s StatementOptions) GetAMQPConfigJSON() SinkSpecificJSONConfig {
+	return s.getJSONValue(OptAMQPSinkConfig)
+}
+
 // GetResolvedTimestampInterval gets the best-effort interval at which resolved timestamps
 // should be emitted. Nil or 0 means emit as often as possible. False means do not emit at all.
 // Returns an error for negative or invalid duration value.
@@ -1023,6 +1079,13 @@ func (s StatementOptions) GetOnError() (OnErrorType, error) {
 	return OnErrorType(v), nil
 }
 
+// GetDeadLetterQueue returns the URI of the dead letter queue, or false if
+// none has been provided.
+func (s StatementOptions) GetDeadLetterQueue() (string, bool) {
+	v, ok := s.m[OptDeadLetterQueue]
+	return v, ok && v != ``
+}
+
 func describeEnum(strs ...string) string {
 	switch len(strs) {
 	case 1:
@@ -1087,6 +1150,9 @@ func (s StatementOptions) ValidateForCreateChangefeed(isPredicateChangefeed bool
 			return err
 		}
 	}
+	if err := s.validateDeadLetterQueue(); err != nil {
+		return err
+	}
 	for o := range s.m {
 		for _, pair := range incompatibleOptionsMap[o] {
 			if s.IsSet(pair.opt1) && s.IsSet(pair.opt2) {
@@ -1104,6 +1170,25 @@ func (s StatementOptions) ValidateForCreateChangefeed(isPredicateChangefeed bool
 	return nil
 }
 
+// validateDeadLetterQueue checks that a dead letter queue is configured if and
+// only if the dead_letter error handling mode is selected.
+func (s StatementOptions) validateDeadLetterQueue() error {
+	onError, err := s.GetOnError()
+	if err != nil {
+		return err
+	}
+	_, hasDLQ := s.GetDeadLetterQueue()
+	if onError == OptOnErrorDeadLetter && !hasDLQ {
+		return errors.Newf(`%s=%s requires the %s option`,
+			OptOnError, OptOnErrorDeadLetter, OptDeadLetterQueue)
+	}
+	if onError != OptOnErrorDeadLetter && hasDLQ {
+		return errors.Newf(`%s requires %s=%s`,
+			OptDeadLetterQueue, OptOnError, OptOnErrorDeadLetter)
+	}
+	return nil
+}
+
 func (s StatementOptions) validateAgainst(m map[string]OptionPermittedValues) error {
 	for k := range ChangefeedOptionExpectValues {
 		permitted := m[k]
@@ -40,6 +40,9 @@ func TestOptionsValidations(t *testing.T) {
 		{map[string]string{"initial_scan_only": "", "resolved": ""}, true, "cannot specify both initial_scan='only'"},
 		{map[string]string{"initial_scan_only": "", "resolved": ""}, true, "cannot specify both initial_scan='only'"},
 		{map[string]string{"key_column": "b"}, false, "requires the unordered option"},
+		{map[string]string{"on_error": "dead_letter"}, false, "requires the dead_letter_queue option"},
+		{map[string]string{"dead_letter_queue": "nodelocal://1/dlq"}, false, "requires on_error=dead_letter"},
+		{map[string]string{"on_error": "dead_letter", "dead_letter_queue": "nodelocal://1/dlq"}, false, ""},
 	}
 
 	for _, test := range tests {
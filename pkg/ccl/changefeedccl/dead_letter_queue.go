@@ -0,0 +1,420 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"bytes"
+	"context"
+	gosql "database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+// deadLetterReason describes why a message was routed to the dead letter
+// queue.
+type deadLetterReason string
+
+const (
+	// deadLetterReasonEncoding is used for rows which the changefeed encoder
+	// failed to encode (e.g. a value which violates the Avro schema).
+	deadLetterReasonEncoding deadLetterReason = `encoding_failed`
+	// deadLetterReasonSinkRejected is used for messages which the downstream
+	// sink permanently rejected (e.g. a webhook endpoint returning 400).
+	deadLetterReasonSinkRejected deadLetterReason = `sink_rejected`
+)
+
+const (
+	// sqlDeadLetterQueueDefaultTable is the table used by a SQL dead letter
+	// queue when the URI does not specify a table_name parameter.
+	sqlDeadLetterQueueDefaultTable = `changefeed_dead_letters`
+	sqlDeadLetterQueueTableParam   = `table_name`
+	sqlDeadLetterQueueCreateStmt   = `CREATE TABLE IF NOT EXISTS %s (
+		job_id INT8 NOT NULL,
+		recorded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		topic TEXT,
+		key BYTEA,
+		value BYTEA,
+		row_data TEXT,
+		updated TEXT,
+		mvcc_timestamp TEXT,
+		reason TEXT NOT NULL,
+		error TEXT NOT NULL
+	)`
+	sqlDeadLetterQueueInsertStmt = `INSERT INTO %s
+		(job_id, topic, key, value, row_data, updated, mvcc_timestamp, reason, error)`
+	sqlDeadLetterQueueNumCols = 9
+	// sqlDeadLetterQueueMaxBatchRows is the maximum number of dead letters
+	// inserted by a single statement. It keeps the number of placeholders well
+	// below the limit of 65535 imposed by the Postgres wire protocol.
+	sqlDeadLetterQueueMaxBatchRows = 1000
+	// sqlDeadLetterQueueMaxBufferedRows is the number of buffered dead letters
+	// at which Record flushes synchronously, which bounds the memory used by
+	// the queue.
+	sqlDeadLetterQueueMaxBufferedRows = 4 * sqlDeadLetterQueueMaxBatchRows
+
+	// cloudStorageDeadLetterQueueFileSize is the size at which buffered dead
+	// letters are written out to a file even if no flush has been requested.
+	cloudStorageDeadLetterQueueFileSize = 16 << 20 // 16MB
+)
+
+// deadLetter is a message that could not be delivered to the changefeed's
+// sink, along with the reason why.
+type deadLetter struct {
+	topic string
+	// key and value are the encoded message, if encoding succeeded.
+	key, value []byte
+	// row is a human readable representation of the row for messages which
+	// could not be encoded.
+	row           string
+	updated, mvcc hlc.Timestamp
+	reason        deadLetterReason
+	err           error
+}
+
+// deadLetterJSON is the representation of a deadLetter written to cloud
+// storage dead letter queues, one JSON object per line.
+type deadLetterJSON struct {
+	JobID         jobspb.JobID     `json:"job_id"`
+	Topic         string           `json:"topic,omitempty"`
+	Key           []byte           `json:"key,omitempty"`
+	Value         []byte           `json:"value,omitempty"`
+	Row           string           `json:"row,omitempty"`
+	Updated       string           `json:"updated,omitempty"`
+	MVCCTimestamp string           `json:"mvcc_timestamp,omitempty"`
+	Reason        deadLetterReason `json:"reason"`
+	Error         string           `json:"error"`
+}
+
+// deadLetterQueue is a secondary destination for messages which a changefeed
+// running with on_error='dead_letter' could not deliver to its sink. Recording
+// a dead letter allows the changefeed to keep advancing past the message.
+//
+// Implementations must be safe for concurrent use since messages may be
+// recorded by parallel event consumers as well as by the sink's IO workers.
+type deadLetterQueue interface {
+	// Record enqueues a dead letter. It may flush previously recorded dead
+	// letters synchronously.
+	Record(ctx context.Context, dl deadLetter) error
+	// Flush blocks until every recorded dead letter has been durably written.
+	// The changefeed flushes its dead letter queue before checkpointing so that
+	// no rejected message is lost on restart.
+	Flush(ctx context.Context) error
+	// Close releases the resources held by the queue without flushing.
+	Close() error
+}
+
+// errSinkRejectedMessage marks errors returned by a sink client when the
+// downstream system permanently rejected a payload. Retrying such a payload
+// cannot succeed.
+var errSinkRejectedMessage = errors.New("message permanently rejected by sink")
+
+// markSinkRejectedMessage marks the error as a permanent rejection of the
+// payload that produced it.
+func markSinkRejectedMessage(cause error) error {
+	if cause == nil {
+		return nil
+	}
+	return errors.Mark(cause, errSinkRejectedMessage)
+}
+
+// isSinkRejectedMessage returns true if the error indicates that the sink
+// permanently rejected the payload being delivered.
+func isSinkRejectedMessage(err error) bool {
+	return err != nil && errors.Is(err, errSinkRejectedMessage)
+}
+
+// isDeadLetterEncodingError returns true if an error returned by the encoder
+// is a property of the row being encoded rather than of the environment, and
+// the row can therefore be routed to the dead letter queue.
+func isDeadLetterEncodingError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// Errors marked as retryable, such as an unavailable schema registry, are
+	// transient and must not cause rows to be skipped.
+	return !changefeedbase.IsRetryableError(err)
+}
+
+// sinkWithDeadLetterQueue is implemented by sinks which are able to route
+// messages that the downstream system permanently rejected to a dead letter
+// queue instead of failing the changefeed.
+type sinkWithDeadLetterQueue interface {
+	setDeadLetterQueue(dlq deadLetterQueue)
+}
+
+// makeDeadLetterQueue returns the dead letter queue configured for the
+// changefeed, or nil if the changefeed does not use one.
+func makeDeadLetterQueue(
+	ctx context.Context,
+	serverCfg *execinfra.ServerConfig,
+	opts changefeedbase.StatementOptions,
+	user username.SQLUsername,
+	jobID jobspb.JobID,
+	m metricsRecorder,
+) (deadLetterQueue, error) {
+	onError, err := opts.GetOnError()
+	if err != nil {
+		return nil, err
+	}
+	uri, ok := opts.GetDeadLetterQueue()
+	if onError != changefeedbase.OptOnErrorDeadLetter || !ok {
+		return nil, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case `postgres`, `postgresql`:
+		q, err := makeSQLDeadLetterQueue(ctx, sinkURL{URL: u}, jobID, m)
+		if err != nil {
+			return nil, err
+		}
+		return q, nil
+	default:
+		var nodeID base.SQLInstanceID
+		if serverCfg.NodeID != nil {
+			nodeID = serverCfg.NodeID.SQLInstanceID()
+		}
+		es, err := serverCfg.ExternalStorageFromURI(ctx, uri, user)
+		if err != nil {
+			return nil, errors.Wrapf(err, "opening dead letter queue")
+		}
+		return &cloudStorageDeadLetterQueue{
+			es:      es,
+			jobID:   jobID,
+			nodeID:  nodeID,
+			metrics: m,
+		}, nil
+	}
+}
+
+// cloudStorageDeadLetterQueue writes dead letters as newline delimited JSON
+// files into an ExternalStorage.
+type cloudStorageDeadLetterQueue struct {
+	es      cloud.ExternalStorage
+	jobID   jobspb.JobID
+	nodeID  base.SQLInstanceID
+	metrics metricsRecorder
+
+	mu struct {
+		syncutil.Mutex
+		buf     bytes.Buffer
+		fileSeq int
+	}
+}
+
+var _ deadLetterQueue = (*cloudStorageDeadLetterQueue)(nil)
+
+// Record implements the deadLetterQueue interface.
+func (q *cloudStorageDeadLetterQueue) Record(ctx context.Context, dl deadLetter) error {
+	line, err := json.Marshal(q.toJSON(dl))
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.mu.buf.Write(line)
+	q.mu.buf.WriteByte('\n')
+	q.metrics.recordDeadLetters(1)
+	if q.mu.buf.Len() >= cloudStorageDeadLetterQueueFileSize {
+		return q.flushLocked(ctx)
+	}
+	return nil
+}
+
+func (q *cloudStorageDeadLetterQueue) toJSON(dl deadLetter) deadLetterJSON {
+	j := deadLetterJSON{
+		JobID:  q.jobID,
+		Topic:  dl.topic,
+		Key:    dl.key,
+		Value:  dl.value,
+		Row:    dl.row,
+		Reason: dl.reason,
+		Error:  dl.err.Error(),
+	}
+	if !dl.updated.IsEmpty() {
+		j.Updated = dl.updated.AsOfSystemTime()
+	}
+	if !dl.mvcc.IsEmpty() {
+		j.MVCCTimestamp = dl.mvcc.AsOfSystemTime()
+	}
+	return j
+}
+
+// Flush implements the deadLetterQueue interface.
+func (q *cloudStorageDeadLetterQueue) Flush(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.flushLocked(ctx)
+}
+
+func (q *cloudStorageDeadLetterQueue) flushLocked(ctx context.Context) error {
+	if q.mu.buf.Len() == 0 {
+		return nil
+	}
+	// Files are named so that they sort by the time they were written and
+	// never collide across jobs, nodes or restarts.
+	filename := fmt.Sprintf("%s-%d-%d-%d.ndjson",
+		timeutil.Now().Format("20060102150405.000000000"), q.jobID, q.nodeID, q.mu.fileSeq)
+	if err := cloud.WriteFile(ctx, q.es, filename, bytes.NewReader(q.mu.buf.Bytes())); err != nil {
+		return errors.Wrapf(err, "writing to dead letter queue")
+	}
+	q.mu.fileSeq++
+	q.mu.buf.Reset()
+	return nil
+}
+
+// Close implements the deadLetterQueue interface.
+func (q *cloudStorageDeadLetterQueue) Close() error {
+	return q.es.Close()
+}
+
+// sqlDeadLetterQueue inserts dead letters into a table of a Postgres-wire
+// database, which may be the changefeed's own cluster.
+type sqlDeadLetterQueue struct {
+	db *gosql.DB
+	// tableName is the (unqualified) name of the dead letter table, quoted as
+	// needed for use in a statement.
+	tableName string
+	jobID     jobspb.JobID
+	metrics   metricsRecorder
+
+	mu struct {
+		syncutil.Mutex
+		rowBuf []interface{}
+	}
+}
+
+var _ deadLetterQueue = (*sqlDeadLetterQueue)(nil)
+
+func makeSQLDeadLetterQueue(
+	ctx context.Context, u sinkURL, jobID jobspb.JobID, m metricsRecorder,
+) (*sqlDeadLetterQueue, error) {
+	tableName := u.consumeParam(sqlDeadLetterQueueTableParam)
+	if tableName == `` {
+		tableName = sqlDeadLetterQueueDefaultTable
+	}
+	// The table name is supplied by the user and interpolated into statements,
+	// so it must be quoted. The table is created in the database named by the
+	// URI.
+	tableName = tree.NameString(tableName)
+	// Any remaining parameters are connection parameters.
+	u.RawQuery = u.q.Encode()
+
+	db, err := gosql.Open(`postgres`, u.String())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(sqlDeadLetterQueueCreateStmt, tableName)); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrapf(err, "creating dead letter queue table %s", tableName)
+	}
+	return &sqlDeadLetterQueue{
+		db:        db,
+		tableName: tableName,
+		jobID:     jobID,
+		metrics:   m,
+	}, nil
+}
+
+// Record implements the deadLetterQueue interface.
+func (q *sqlDeadLetterQueue) Record(ctx context.Context, dl deadLetter) error {
+	var updated, mvcc interface{}
+	if !dl.updated.IsEmpty() {
+		updated = dl.updated.AsOfSystemTime()
+	}
+	if !dl.mvcc.IsEmpty() {
+		mvcc = dl.mvcc.AsOfSystemTime()
+	}
+
+	// The key and value are copied since the buffers backing them may be reused
+	// once the caller releases the message.
+	key := append([]byte(nil), dl.key...)
+	value := append([]byte(nil), dl.value...)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.mu.rowBuf = append(q.mu.rowBuf,
+		int64(q.jobID), dl.topic, key, value, dl.row, updated, mvcc, string(dl.reason), dl.err.Error())
+	q.metrics.recordDeadLetters(1)
+	if len(q.mu.rowBuf) >= sqlDeadLetterQueueMaxBufferedRows*sqlDeadLetterQueueNumCols {
+		return q.flushLocked(ctx)
+	}
+	return nil
+}
+
+// Flush implements the deadLetterQueue interface.
+func (q *sqlDeadLetterQueue) Flush(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.flushLocked(ctx)
+}
+
+func (q *sqlDeadLetterQueue) flushLocked(ctx context.Context) error {
+	for len(q.mu.rowBuf) > 0 {
+		n := len(q.mu.rowBuf)
+		if n > sqlDeadLetterQueueMaxBatchRows*sqlDeadLetterQueueNumCols {
+			n = sqlDeadLetterQueueMaxBatchRows * sqlDeadLetterQueueNumCols
+		}
+		if err := q.insert(ctx, q.mu.rowBuf[:n]); err != nil {
+			return err
+		}
+		// Drop the inserted rows so that they are not inserted again if a
+		// later batch fails.
+		q.mu.rowBuf = append(q.mu.rowBuf[:0], q.mu.rowBuf[n:]...)
+	}
+	return nil
+}
+
+// insert inserts a batch of dead letters, given as a flattened list of column
+// values.
+func (q *sqlDeadLetterQueue) insert(ctx context.Context, args []interface{}) error {
+	var stmt strings.Builder
+	fmt.Fprintf(&stmt, sqlDeadLetterQueueInsertStmt, q.tableName)
+	for i := range args {
+		if i == 0 {
+			stmt.WriteString(` VALUES (`)
+		} else if i%sqlDeadLetterQueueNumCols == 0 {
+			stmt.WriteString(`),(`)
+		} else {
+			stmt.WriteString(`,`)
+		}
+		fmt.Fprintf(&stmt, `$%d`, i+1)
+	}
+	stmt.WriteString(`)`)
+	if _, err := q.db.ExecContext(ctx, stmt.String(), args...); err != nil {
+		return errors.Wrapf(err, "writing to dead letter queue")
+	}
+	return nil
+}
+
+// Close implements the deadLetterQueue interface.
+func (q *sqlDeadLetterQueue) Close() error {
+	return q.db.Close()
+}
@@ -0,0 +1,230 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/blobs"
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/cdcevent"
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDeadLetterQueue is a deadLetterQueue which keeps the recorded dead
+// letters in memory.
+type recordingDeadLetterQueue struct {
+	syncutil.Mutex
+	letters []deadLetter
+}
+
+var _ deadLetterQueue = (*recordingDeadLetterQueue)(nil)
+
+func (q *recordingDeadLetterQueue) Record(_ context.Context, dl deadLetter) error {
+	q.Lock()
+	defer q.Unlock()
+	q.letters = append(q.letters, dl)
+	return nil
+}
+
+func (q *recordingDeadLetterQueue) Flush(context.Context) error { return nil }
+
+func (q *recordingDeadLetterQueue) Close() error { return nil }
+
+func TestKVEventToRowConsumerDeadLetter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	tableDesc, err := parseTableDesc(`CREATE TABLE foo (a INT PRIMARY KEY, b STRING)`)
+	require.NoError(t, err)
+	rows, err := parseValues(tableDesc, `VALUES (1, 'one')`)
+	require.NoError(t, err)
+	row := cdcevent.TestingMakeEventRow(tableDesc, 0, rows[0], false)
+	row.MvccTimestamp = hlc.Timestamp{WallTime: 2}
+	schemaTS := hlc.Timestamp{WallTime: 1}
+	topic := makeTopic(`foo`)
+	encodeErr := errors.New("value violates the schema")
+
+	// Without a dead letter queue, the encoding error fails the changefeed.
+	c := &kvEventToRowConsumer{}
+	err = c.maybeDeadLetter(ctx, topic, row, schemaTS, zeroAlloc, encodeErr)
+	require.ErrorIs(t, err, encodeErr)
+
+	dlq := &recordingDeadLetterQueue{}
+	c.dlq = dlq
+
+	// Transient errors are not routed to the dead letter queue.
+	retryableErr := changefeedbase.MarkRetryableError(errors.New("schema registry unavailable"))
+	err = c.maybeDeadLetter(ctx, topic, row, schemaTS, zeroAlloc, retryableErr)
+	require.ErrorIs(t, err, retryableErr)
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = c.maybeDeadLetter(canceledCtx, topic, row, schemaTS, zeroAlloc, encodeErr)
+	require.ErrorIs(t, err, encodeErr)
+	require.Empty(t, dlq.letters)
+
+	// Errors specific to the row are routed to the dead letter queue.
+	require.NoError(t, c.maybeDeadLetter(ctx, topic, row, schemaTS, zeroAlloc, encodeErr))
+	require.Len(t, dlq.letters, 1)
+	dl := dlq.letters[0]
+	require.Equal(t, `foo`, dl.topic)
+	require.Equal(t, row.DebugString(), dl.row)
+	require.Equal(t, schemaTS, dl.updated)
+	require.Equal(t, row.MvccTimestamp, dl.mvcc)
+	require.Equal(t, deadLetterReasonEncoding, dl.reason)
+	require.ErrorIs(t, dl.err, encodeErr)
+}
+
+func TestCloudStorageDeadLetterQueue(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	externalIODir, dirCleanupFn := testutils.TempDir(t)
+	defer dirCleanupFn()
+	settings := cluster.MakeTestingClusterSettings()
+	settings.ExternalIODir = externalIODir
+	es, err := cloud.ExternalStorageFromURI(ctx, "nodelocal://1/dlq", base.ExternalIODirConfig{}, settings,
+		blobs.TestBlobServiceClient(settings.ExternalIODir), username.RootUserName(),
+		nil /* db */, nil /* limiters */, cloud.NilMetrics)
+	require.NoError(t, err)
+
+	const jobID = 42
+	q := &cloudStorageDeadLetterQueue{es: es, jobID: jobID, nodeID: 1, metrics: (*sliMetrics)(nil)}
+	defer func() { require.NoError(t, q.Close()) }()
+
+	listFiles := func() []string {
+		var files []string
+		require.NoError(t, es.List(ctx, "", "", func(name string) error {
+			files = append(files, name)
+			return nil
+		}))
+		return files
+	}
+
+	require.NoError(t, q.Record(ctx, deadLetter{
+		topic: `foo`, row: `Row{a: 1}`, updated: hlc.Timestamp{WallTime: 1},
+		reason: deadLetterReasonEncoding, err: errors.New("boom"),
+	}))
+	require.NoError(t, q.Record(ctx, deadLetter{
+		topic: `foo`, key: []byte(`k`), value: []byte(`v`),
+		reason: deadLetterReasonSinkRejected, err: errors.New("400 Bad Request"),
+	}))
+	// Nothing is written until the queue is flushed.
+	require.Empty(t, listFiles())
+	require.NoError(t, q.Flush(ctx))
+	// Flushing an empty queue does not write a file.
+	require.NoError(t, q.Flush(ctx))
+
+	files := listFiles()
+	require.Len(t, files, 1)
+	r, _, err := es.ReadFile(ctx, files[0], cloud.ReadOptions{NoFileSize: true})
+	require.NoError(t, err)
+	content, err := ioctx.ReadAll(ctx, r)
+	require.NoError(t, r.Close(ctx))
+	require.NoError(t, err)
+
+	var letters []deadLetterJSON
+	for _, line := range bytes.Split(bytes.TrimSpace(content), []byte("\n")) {
+		var dl deadLetterJSON
+		require.NoError(t, json.Unmarshal(line, &dl))
+		letters = append(letters, dl)
+	}
+	require.Equal(t, []deadLetterJSON{{
+		JobID:   jobID,
+		Topic:   `foo`,
+		Row:     `Row{a: 1}`,
+		Updated: hlc.Timestamp{WallTime: 1}.AsOfSystemTime(),
+		Reason:  deadLetterReasonEncoding,
+		Error:   `boom`,
+	}, {
+		JobID:  jobID,
+		Topic:  `foo`,
+		Key:    []byte(`k`),
+		Value:  []byte(`v`),
+		Reason: deadLetterReasonSinkRejected,
+		Error:  `400 Bad Request`,
+	}}, letters)
+}
+
+func TestSQLDeadLetterQueue(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	s, sqlDBRaw, _ := serverutils.StartServer(t, base.TestServerArgs{UseDatabase: "d"})
+	defer s.Stopper().Stop(ctx)
+	sqlDB := sqlutils.MakeSQLRunner(sqlDBRaw)
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+
+	pgURL, cleanup := sqlutils.PGUrl(t, s.ApplicationLayer().AdvSQLAddr(), t.Name(), url.User(username.RootUser))
+	defer cleanup()
+	pgURL.Path = `d`
+	// The table name is quoted, so it may contain arbitrary characters.
+	const tableName = `dead letters; DROP TABLE x`
+	q := pgURL.Query()
+	q.Set(sqlDeadLetterQueueTableParam, tableName)
+	pgURL.RawQuery = q.Encode()
+
+	const jobID = 42
+	dlq, err := makeSQLDeadLetterQueue(ctx, sinkURL{URL: &pgURL}, jobID, (*sliMetrics)(nil))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, dlq.Close()) }()
+
+	countRows := func() int {
+		var n int
+		sqlDB.QueryRow(t, fmt.Sprintf(`SELECT count(*) FROM %s`, dlq.tableName)).Scan(&n)
+		return n
+	}
+	record := func(i int) {
+		require.NoError(t, dlq.Record(ctx, deadLetter{
+			topic:  `foo`,
+			key:    []byte(fmt.Sprintf(`k%d`, i)),
+			value:  []byte(`v`),
+			mvcc:   hlc.Timestamp{WallTime: int64(i + 1)},
+			reason: deadLetterReasonSinkRejected,
+			err:    errors.New("400 Bad Request"),
+		}))
+	}
+
+	record(0)
+	require.Equal(t, 0, countRows())
+	require.NoError(t, dlq.Flush(ctx))
+	require.Equal(t, 1, countRows())
+	sqlDB.CheckQueryResults(t,
+		fmt.Sprintf(`SELECT job_id, topic, key = 'k0', reason, error FROM %s`, dlq.tableName),
+		[][]string{{`42`, `foo`, `true`, `sink_rejected`, `400 Bad Request`}},
+	)
+
+	// Once enough dead letters are buffered, they are flushed synchronously in
+	// batches which stay below the placeholder limit.
+	for i := 1; i <= sqlDeadLetterQueueMaxBufferedRows; i++ {
+		record(i)
+	}
+	require.Equal(t, sqlDeadLetterQueueMaxBufferedRows+1, countRows())
+}
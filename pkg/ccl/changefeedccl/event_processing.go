@@ -73,6 +73,10 @@ type kvEventToRowConsumer struct {
 
 	metrics *sliMetrics
 
+	// dlq, if non-nil, receives rows which could not be encoded instead of
+	// failing the changefeed.
+	dlq deadLetterQueue
+
 	// This pacer is used to incorporate event consumption to elastic CPU
 	// control. This helps ensure that event encoding/decoding does not throttle
 	// foreground SQL traffic.
@@ -93,6 +97,7 @@ func newEventConsumer(
 	spanFrontier *span.Frontier,
 	cursor hlc.Timestamp,
 	sink EventSink,
+	dlq deadLetterQueue,
 	metrics *Metrics,
 	sliMetrics *sliMetrics,
 	knobs TestingKnobs,
@@ -143,7 +148,7 @@ func newEventConsumer(
 
 		execCfg := cfg.ExecutorConfig.(*sql.ExecutorConfig)
 		return newKVEventToRowConsumer(ctx, execCfg, frontier, cursor, s,
-			encoder, feed, spec, knobs, topicNamer, sliMetrics, pacer)
+			encoder, feed, spec, knobs, topicNamer, sliMetrics, pacer, dlq)
 	}
 
 	numWorkers := changefeedbase.EventConsumerWorkers.Get(&cfg.Settings.SV)
@@ -158,11 +163,12 @@ func newEventConsumer(
 	// TODO (ganeshb) Add support for parallel encoding when using parquet.
 	// We cannot have a separate encoder and sink for parquet format (see
 	// parquet_sink_cloudstorage.go). Because of this the current nprox solution
-	// does not work for parquet format.
+	// does not work for parquet format, or for any other sink which encodes rows
+	// itself.
 	//
 	// TODO (jayshrivastava) enable parallel consumers for sinkless changefeeds.
 	isSinkless := spec.JobID == 0
-	if numWorkers <= 1 || isSinkless || encodingOpts.Format == changefeedbase.OptFormatParquet {
+	if numWorkers <= 1 || isSinkless || sinkEncodesRows(sink, encodingOpts) {
 		c, err := makeConsumer(sink, spanFrontier)
 		if err != nil {
 			return nil, nil, err
@@ -224,6 +230,7 @@ func newKVEventToRowConsumer(
 	topicNamer *TopicNamer,
 	metrics *sliMetrics,
 	pacer *admission.Pacer,
+	dlq deadLetterQueue,
 ) (_ *kvEventToRowConsumer, err error) {
 	includeVirtual := details.Opts.IncludeVirtual()
 	keyOnly := details.Opts.KeyOnly()
@@ -259,6 +266,7 @@ func newKVEventToRowConsumer(
 		encodingOpts:         encodingOpts,
 		metrics:              metrics,
 		pacer:                pacer,
+		dlq:                  dlq,
 	}, nil
 }
 
@@ -429,8 +437,8 @@ func (c *kvEventToRowConsumer) encodeAndEmit(
 		}
 	}
 
-	if c.encodingOpts.Format == changefeedbase.OptFormatParquet {
-		return c.encodeForParquet(
+	if sinkEncodesRows(c.sink, c.encodingOpts) {
+		return c.encodeWithSink(
 			ctx, updatedRow, prevRow, topic, schemaTS, updatedRow.MvccTimestamp,
 			c.encodingOpts, alloc,
 		)
@@ -438,14 +446,14 @@ func (c *kvEventToRowConsumer) encodeAndEmit(
 	var keyCopy, valueCopy []byte
 	encodedKey, err := c.encoder.EncodeKey(ctx, updatedRow)
 	if err != nil {
-		return err
+		return c.maybeDeadLetter(ctx, topic, updatedRow, schemaTS, alloc, err)
 	}
 	c.scratch, keyCopy = c.scratch.Copy(encodedKey, 0 /* extraCap */)
 	// TODO(yevgeniy): Some refactoring is needed in the encoder: namely, prevRow
 	// might not be available at all when working with changefeed expressions.
 	encodedValue, err := c.encoder.EncodeValue(ctx, evCtx, updatedRow, prevRow)
 	if err != nil {
-		return err
+		return c.maybeDeadLetter(ctx, topic, updatedRow, schemaTS, alloc, err)
 	}
 	c.scratch, valueCopy = c.scratch.Copy(encodedValue, 0 /* extraCap */)
 
@@ -464,6 +472,41 @@ func (c *kvEventToRowConsumer) encodeAndEmit(
 	return nil
 }
 
+// maybeDeadLetter routes a row which failed to encode to the dead letter queue,
+// if one is configured and the error is specific to the row. Otherwise, the
+// encoding error is returned.
+func (c *kvEventToRowConsumer) maybeDeadLetter(
+	ctx context.Context,
+	topic TopicDescriptor,
+	updatedRow cdcevent.Row,
+	schemaTS hlc.Timestamp,
+	alloc kvevent.Alloc,
+	encodeErr error,
+) error {
+	if c.dlq == nil || !isDeadLetterEncodingError(ctx, encodeErr) {
+		return encodeErr
+	}
+	defer alloc.Release(ctx)
+
+	var topicName string
+	if c.topicNamer != nil {
+		// The topic name is informational only; fall back to the table name if
+		// it cannot be determined.
+		topicName, _ = c.topicNamer.Name(topic)
+	}
+	if topicName == "" {
+		topicName = updatedRow.TableName
+	}
+	return c.dlq.Record(ctx, deadLetter{
+		topic:   topicName,
+		row:     updatedRow.DebugString(),
+		updated: schemaTS,
+		mvcc:    updatedRow.MvccTimestamp,
+		reason:  deadLetterReasonEncoding,
+		err:     encodeErr,
+	})
+}
+
 // Close closes this consumer.
 func (c *kvEventToRowConsumer) Close() error {
 	c.pacer.Close()
@@ -473,7 +516,7 @@ func (c *kvEventToRowConsumer) Close() error {
 	return nil
 }
 
-func (c *kvEventToRowConsumer) encodeForParquet(
+func (c *kvEventToRowConsumer) encodeWithSink(
 	ctx context.Context,
 	updatedRow cdcevent.Row,
 	prevRow cdcevent.Row,
@@ -484,7 +527,7 @@ func (c *kvEventToRowConsumer) encodeForParquet(
 ) error {
 	sinkWithEncoder, ok := c.sink.(SinkWithEncoder)
 	if !ok {
-		return errors.AssertionFailedf("Expected a SinkWithEncoder for parquet format, found %T", c.sink)
+		return errors.AssertionFailedf("Expected a SinkWithEncoder, found %T", c.sink)
 	}
 	if err := sinkWithEncoder.EncodeAndEmitRow(
 		ctx, updatedRow, prevRow, topic, updated, mvcc, encodingOpts, alloc,
@@ -0,0 +1,988 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/cdcevent"
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/errors"
+	"github.com/linkedin/goavro/v2"
+)
+
+// A cloud storage changefeed with table_format=iceberg writes each topic as an
+// Apache Iceberg (format version 2) table rooted at `<sink>/<topic>`, using the
+// layout of Iceberg's Hadoop catalog so that engines like Spark, Trino or
+// DuckDB can read the output without any additional catalog service:
+//
+//	<topic>/data/<filename>.parquet         -- data files
+//	<topic>/metadata/v<N>.metadata.json     -- table metadata
+//	<topic>/metadata/version-hint.text      -- the current value of N
+//	<topic>/metadata/snap-*.avro            -- manifest lists
+//	<topic>/metadata/*-m0.avro              -- manifests
+//
+// Data files are written by the change aggregators exactly as they are for
+// plain parquet output, except that they are not partitioned by date. Next to
+// each data file the aggregator writes a small JSON descriptor into a shared
+// pending directory. The changeFrontier, which is the only writer of table
+// metadata, commits every pending data file whose name sorts before the new
+// resolved timestamp as a new snapshot when it emits that resolved timestamp.
+// The naming invariants of the cloudStorageSink guarantee that such a data
+// file has been completely written. Snapshots are therefore committed exactly
+// at resolved timestamps, and the resolved timestamp of the most recent
+// snapshot is recorded in the `crdb.resolved` table property.
+//
+// Table schemas are derived from the parquet schema of the data files. Columns
+// are never removed from the Iceberg schema when they are dropped in SQL, and
+// column types may only change in the ways that Iceberg permits.
+const (
+	icebergPendingDir      = `_crdb_iceberg_pending`
+	icebergDataDir         = `data`
+	icebergMetadataDir     = `metadata`
+	icebergVersionHintFile = `version-hint.text`
+
+	icebergResolvedProperty    = `crdb.resolved`
+	icebergNameMappingProperty = `schema.name-mapping.default`
+
+	icebergFormatVersion = 2
+)
+
+// icebergColumn describes the type of a column in a data file, before field
+// ids have been assigned to it.
+type icebergColumn struct {
+	Name string `json:"name"`
+	// Type is an Iceberg primitive type name, `list` or `struct`.
+	Type string `json:"type"`
+	// Element is the primitive type of the elements of a list.
+	Element string `json:"element,omitempty"`
+	// Fields are the fields of a struct.
+	Fields []icebergColumn `json:"fields,omitempty"`
+}
+
+// icebergPrimitiveType returns the Iceberg type which matches the physical
+// representation used by pkg/util/parquet for the given type.
+func icebergPrimitiveType(typ *types.T) (string, error) {
+	switch typ.Family() {
+	case types.BoolFamily:
+		return `boolean`, nil
+	case types.IntFamily:
+		if typ.Oid() == types.Int.Oid() {
+			return `long`, nil
+		}
+		return `int`, nil
+	case types.OidFamily:
+		return `int`, nil
+	case types.PGLSNFamily:
+		return `long`, nil
+	case types.FloatFamily:
+		if typ.Oid() == types.Float4.Oid() {
+			return `float`, nil
+		}
+		return `double`, nil
+	case types.UuidFamily:
+		return `uuid`, nil
+	case types.TimeFamily:
+		return `time`, nil
+	case types.BytesFamily, types.GeographyFamily, types.GeometryFamily:
+		return `binary`, nil
+	case types.StringFamily, types.CollatedStringFamily, types.EnumFamily,
+		types.DecimalFamily, types.JsonFamily, types.TimestampFamily,
+		types.TimestampTZFamily, types.DateFamily, types.IntervalFamily,
+		types.TimeTZFamily, types.Box2DFamily, types.INetFamily, types.BitFamily:
+		// These types are written as strings by the parquet writer.
+		return `string`, nil
+	default:
+		return "", pgerror.Newf(pgcode.FeatureNotSupported,
+			"iceberg tables do not support type %s", typ.SQLString())
+	}
+}
+
+// makeIcebergColumn returns the icebergColumn for a column of the given name
+// and type.
+func makeIcebergColumn(name string, typ *types.T) (icebergColumn, error) {
+	col := icebergColumn{Name: name}
+	var err error
+	switch typ.Family() {
+	case types.ArrayFamily:
+		col.Type = `list`
+		col.Element, err = icebergPrimitiveType(typ.ArrayContents())
+	case types.TupleFamily:
+		col.Type = `struct`
+		labels := typ.TupleLabels()
+		for i, innerTyp := range typ.TupleContents() {
+			// This mirrors the labels used by the parquet writer.
+			label := fmt.Sprintf("%s_col%d", name, i)
+			if labels != nil {
+				label = labels[i]
+			}
+			inner, err := makeIcebergColumn(label, innerTyp)
+			if err != nil {
+				return icebergColumn{}, err
+			}
+			col.Fields = append(col.Fields, inner)
+		}
+	default:
+		col.Type, err = icebergPrimitiveType(typ)
+	}
+	return col, err
+}
+
+// makeIcebergColumnsFromRow returns the columns of the parquet data files
+// which are written for the given row.
+func makeIcebergColumnsFromRow(
+	row cdcevent.Row, encodingOpts changefeedbase.EncodingOptions,
+) ([]icebergColumn, error) {
+	columnNames, columnTypes, err := parquetColumnsFromRow(row, encodingOpts)
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]icebergColumn, len(columnNames))
+	for i := range columnNames {
+		if cols[i], err = makeIcebergColumn(columnNames[i], columnTypes[i]); err != nil {
+			return nil, err
+		}
+	}
+	return cols, nil
+}
+
+// icebergPendingFile describes a data file which has been written, but which
+// has not yet been committed to its table.
+type icebergPendingFile struct {
+	Topic           string          `json:"topic"`
+	Path            string          `json:"path"`
+	RecordCount     int64           `json:"record_count"`
+	FileSizeInBytes int64           `json:"file_size_in_bytes"`
+	Columns         []icebergColumn `json:"columns"`
+}
+
+// writeIcebergPendingFile records that the data file f has been written.
+func writeIcebergPendingFile(
+	ctx context.Context, es cloud.ExternalStorage, f *icebergPendingFile,
+) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return cloud.WriteFile(ctx, es, icebergPendingFilePath(f.Path), bytes.NewReader(b))
+}
+
+func icebergPendingFilePath(dataFilePath string) string {
+	return path.Join(icebergPendingDir, path.Base(dataFilePath)+`.json`)
+}
+
+// icebergType is the JSON representation of an Iceberg type: either the name
+// of a primitive type, or a nested list or struct type.
+type icebergType struct {
+	Primitive string
+	// ElementID, Element and ElementRequired are set for lists.
+	ElementID       int
+	Element         string
+	ElementRequired bool
+	// Fields is set for structs.
+	Fields []icebergField
+}
+
+type icebergNestedTypeJSON struct {
+	Type            string         `json:"type"`
+	ElementID       int            `json:"element-id,omitempty"`
+	Element         string         `json:"element,omitempty"`
+	ElementRequired *bool          `json:"element-required,omitempty"`
+	Fields          []icebergField `json:"fields,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (t icebergType) MarshalJSON() ([]byte, error) {
+	switch {
+	case t.Primitive != "":
+		return json.Marshal(t.Primitive)
+	case t.Fields != nil:
+		return json.Marshal(icebergNestedTypeJSON{Type: `struct`, Fields: t.Fields})
+	default:
+		return json.Marshal(icebergNestedTypeJSON{
+			Type: `list`, ElementID: t.ElementID, Element: t.Element, ElementRequired: &t.ElementRequired,
+		})
+	}
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *icebergType) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, &t.Primitive)
+	}
+	var nested icebergNestedTypeJSON
+	if err := json.Unmarshal(b, &nested); err != nil {
+		return err
+	}
+	switch nested.Type {
+	case `list`:
+		*t = icebergType{ElementID: nested.ElementID, Element: nested.Element}
+		if nested.ElementRequired != nil {
+			t.ElementRequired = *nested.ElementRequired
+		}
+	case `struct`:
+		*t = icebergType{Fields: nested.Fields}
+		if t.Fields == nil {
+			t.Fields = []icebergField{}
+		}
+	default:
+		return errors.Newf("unsupported iceberg type %q", nested.Type)
+	}
+	return nil
+}
+
+func (t icebergType) String() string {
+	b, err := t.MarshalJSON()
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+type icebergField struct {
+	ID       int         `json:"id"`
+	Name     string      `json:"name"`
+	Required bool        `json:"required"`
+	Type     icebergType `json:"type"`
+}
+
+type icebergSchema struct {
+	Type     string         `json:"type"`
+	SchemaID int            `json:"schema-id"`
+	Fields   []icebergField `json:"fields"`
+}
+
+type icebergPartitionSpec struct {
+	SpecID int           `json:"spec-id"`
+	Fields []interface{} `json:"fields"`
+}
+
+type icebergSortOrder struct {
+	OrderID int           `json:"order-id"`
+	Fields  []interface{} `json:"fields"`
+}
+
+type icebergSnapshot struct {
+	SnapshotID       int64             `json:"snapshot-id"`
+	ParentSnapshotID *int64            `json:"parent-snapshot-id,omitempty"`
+	SequenceNumber   int64             `json:"sequence-number"`
+	TimestampMs      int64             `json:"timestamp-ms"`
+	ManifestList     string            `json:"manifest-list"`
+	Summary          map[string]string `json:"summary"`
+	SchemaID         int               `json:"schema-id"`
+}
+
+type icebergSnapshotRef struct {
+	SnapshotID int64  `json:"snapshot-id"`
+	Type       string `json:"type"`
+}
+
+type icebergSnapshotLogEntry struct {
+	TimestampMs int64 `json:"timestamp-ms"`
+	SnapshotID  int64 `json:"snapshot-id"`
+}
+
+type icebergMetadataLogEntry struct {
+	TimestampMs  int64  `json:"timestamp-ms"`
+	MetadataFile string `json:"metadata-file"`
+}
+
+// icebergTableMetadata is the table metadata file described by
+// https://iceberg.apache.org/spec/#table-metadata.
+type icebergTableMetadata struct {
+	FormatVersion      int                           `json:"format-version"`
+	TableUUID          string                        `json:"table-uuid"`
+	Location           string                        `json:"location"`
+	LastSequenceNumber int64                         `json:"last-sequence-number"`
+	LastUpdatedMs      int64                         `json:"last-updated-ms"`
+	LastColumnID       int                           `json:"last-column-id"`
+	CurrentSchemaID    int                           `json:"current-schema-id"`
+	Schemas            []icebergSchema               `json:"schemas"`
+	DefaultSpecID      int                           `json:"default-spec-id"`
+	PartitionSpecs     []icebergPartitionSpec        `json:"partition-specs"`
+	LastPartitionID    int                           `json:"last-partition-id"`
+	DefaultSortOrderID int                           `json:"default-sort-order-id"`
+	SortOrders         []icebergSortOrder            `json:"sort-orders"`
+	Properties         map[string]string             `json:"properties"`
+	CurrentSnapshotID  *int64                        `json:"current-snapshot-id,omitempty"`
+	Refs               map[string]icebergSnapshotRef `json:"refs"`
+	Snapshots          []icebergSnapshot             `json:"snapshots"`
+	SnapshotLog        []icebergSnapshotLogEntry     `json:"snapshot-log"`
+	MetadataLog        []icebergMetadataLogEntry     `json:"metadata-log"`
+}
+
+func newIcebergTableMetadata(location string) *icebergTableMetadata {
+	return &icebergTableMetadata{
+		FormatVersion:   icebergFormatVersion,
+		TableUUID:       uuid.MakeV4().String(),
+		Location:        location,
+		Schemas:         []icebergSchema{{Type: `struct`, Fields: []icebergField{}}},
+		PartitionSpecs:  []icebergPartitionSpec{{Fields: []interface{}{}}},
+		LastPartitionID: 999,
+		SortOrders:      []icebergSortOrder{{Fields: []interface{}{}}},
+		Properties:      map[string]string{},
+		Refs:            map[string]icebergSnapshotRef{},
+		Snapshots:       []icebergSnapshot{},
+		SnapshotLog:     []icebergSnapshotLogEntry{},
+		MetadataLog:     []icebergMetadataLogEntry{},
+	}
+}
+
+func (md *icebergTableMetadata) currentSchema() icebergSchema {
+	for _, s := range md.Schemas {
+		if s.SchemaID == md.CurrentSchemaID {
+			return s
+		}
+	}
+	return icebergSchema{Type: `struct`, Fields: []icebergField{}}
+}
+
+func (md *icebergTableMetadata) currentSnapshot() *icebergSnapshot {
+	if md.CurrentSnapshotID == nil {
+		return nil
+	}
+	for i := range md.Snapshots {
+		if md.Snapshots[i].SnapshotID == *md.CurrentSnapshotID {
+			return &md.Snapshots[i]
+		}
+	}
+	return nil
+}
+
+// icebergPromotions are the type changes permitted by Iceberg schema
+// evolution.
+var icebergPromotions = map[[2]string]bool{
+	{`int`, `long`}:     true,
+	{`float`, `double`}: true,
+}
+
+// mergeIcebergFields returns fields extended with the columns in cols,
+// assigning new ids from lastID. Fields that already exist are matched by
+// name. The second return value indicates whether fields were changed.
+func mergeIcebergFields(
+	fields []icebergField, cols []icebergColumn, lastID *int,
+) ([]icebergField, bool, error) {
+	changed := false
+	for _, col := range cols {
+		idx := -1
+		for i := range fields {
+			if fields[i].Name == col.Name {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			fields = append(fields, makeIcebergField(col, lastID))
+			changed = true
+			continue
+		}
+		f := &fields[idx]
+		var fieldChanged bool
+		var err error
+		switch col.Type {
+		case `list`:
+			if f.Type.Primitive != "" || f.Type.Fields != nil {
+				return nil, false, errIcebergTypeChange(col, f.Type)
+			}
+			if f.Type.Element != col.Element {
+				if !icebergPromotions[[2]string{f.Type.Element, col.Element}] {
+					return nil, false, errIcebergTypeChange(col, f.Type)
+				}
+				f.Type.Element = col.Element
+				fieldChanged = true
+			}
+		case `struct`:
+			if f.Type.Fields == nil {
+				return nil, false, errIcebergTypeChange(col, f.Type)
+			}
+			// Copy the nested fields so that the previous schema is not modified.
+			nested := append([]icebergField(nil), f.Type.Fields...)
+			f.Type.Fields, fieldChanged, err = mergeIcebergFields(nested, col.Fields, lastID)
+			if err != nil {
+				return nil, false, err
+			}
+		default:
+			if f.Type.Primitive != col.Type {
+				if !icebergPromotions[[2]string{f.Type.Primitive, col.Type}] {
+					return nil, false, errIcebergTypeChange(col, f.Type)
+				}
+				f.Type.Primitive = col.Type
+				fieldChanged = true
+			}
+		}
+		changed = changed || fieldChanged
+	}
+	return fields, changed, nil
+}
+
+func errIcebergTypeChange(col icebergColumn, prev icebergType) error {
+	return pgerror.Newf(pgcode.FeatureNotSupported,
+		"column %s cannot change type from %s in an iceberg table", col.Name, prev)
+}
+
+func makeIcebergField(col icebergColumn, lastID *int) icebergField {
+	*lastID++
+	f := icebergField{ID: *lastID, Name: col.Name}
+	switch col.Type {
+	case `list`:
+		*lastID++
+		f.Type = icebergType{ElementID: *lastID, Element: col.Element}
+	case `struct`:
+		f.Type = icebergType{Fields: make([]icebergField, 0, len(col.Fields))}
+		for _, inner := range col.Fields {
+			f.Type.Fields = append(f.Type.Fields, makeIcebergField(inner, lastID))
+		}
+	default:
+		f.Type = icebergType{Primitive: col.Type}
+	}
+	return f
+}
+
+// icebergNameMapping is an entry in the default name mapping of a table. The
+// parquet files written by changefeeds do not carry field ids, so readers use
+// the name mapping to resolve the columns in data files to table fields.
+type icebergNameMapping struct {
+	FieldID int                  `json:"field-id"`
+	Names   []string             `json:"names"`
+	Fields  []icebergNameMapping `json:"fields,omitempty"`
+}
+
+func makeIcebergNameMapping(fields []icebergField) []icebergNameMapping {
+	mapping := make([]icebergNameMapping, 0, len(fields))
+	for _, f := range fields {
+		m := icebergNameMapping{FieldID: f.ID, Names: []string{f.Name}}
+		switch {
+		case f.Type.Fields != nil:
+			m.Fields = makeIcebergNameMapping(f.Type.Fields)
+		case f.Type.Primitive == "":
+			// The parquet writer names list elements `element`.
+			m.Fields = []icebergNameMapping{{FieldID: f.Type.ElementID, Names: []string{`element`}}}
+		}
+		mapping = append(mapping, m)
+	}
+	return mapping
+}
+
+// The avro schemas of manifests and manifest lists. Only the fields required
+// by the v2 spec are written; the field ids are what readers use to resolve
+// them.
+const icebergManifestEntrySchema = `{
+  "type": "record",
+  "name": "manifest_entry",
+  "fields": [
+    {"name": "status", "type": "int", "field-id": 0},
+    {"name": "snapshot_id", "type": ["null", "long"], "default": null, "field-id": 1},
+    {"name": "data_file", "field-id": 2, "type": {
+      "type": "record",
+      "name": "r2",
+      "fields": [
+        {"name": "content", "type": "int", "field-id": 134},
+        {"name": "file_path", "type": "string", "field-id": 100},
+        {"name": "file_format", "type": "string", "field-id": 101},
+        {"name": "partition", "field-id": 102, "type": {"type": "record", "name": "r102", "fields": []}},
+        {"name": "record_count", "type": "long", "field-id": 103},
+        {"name": "file_size_in_bytes", "type": "long", "field-id": 104}
+      ]
+    }}
+  ]
+}`
+
+const icebergManifestFileSchema = `{
+  "type": "record",
+  "name": "manifest_file",
+  "fields": [
+    {"name": "manifest_path", "type": "string", "field-id": 500},
+    {"name": "manifest_length", "type": "long", "field-id": 501},
+    {"name": "partition_spec_id", "type": "int", "field-id": 502},
+    {"name": "content", "type": "int", "field-id": 517},
+    {"name": "sequence_number", "type": "long", "field-id": 515},
+    {"name": "min_sequence_number", "type": "long", "field-id": 516},
+    {"name": "added_snapshot_id", "type": "long", "field-id": 503},
+    {"name": "added_files_count", "type": "int", "field-id": 504},
+    {"name": "existing_files_count", "type": "int", "field-id": 505},
+    {"name": "deleted_files_count", "type": "int", "field-id": 506},
+    {"name": "added_rows_count", "type": "long", "field-id": 512},
+    {"name": "existing_rows_count", "type": "long", "field-id": 513},
+    {"name": "deleted_rows_count", "type": "long", "field-id": 514}
+  ]
+}`
+
+const (
+	icebergManifestEntryStatusAdded = 1
+	icebergContentData              = 0
+)
+
+// icebergCatalog commits the data files written by a cloud storage changefeed
+// to the Iceberg tables under the sink's location.
+type icebergCatalog struct {
+	es cloud.ExternalStorage
+	// location is the URI of the sink with any query parameters, which may
+	// contain credentials, removed.
+	location string
+	// knobs may be nil if no knobs are set.
+	knobs *TestingKnobs
+
+	// committed holds the paths of the pending files whose data files are
+	// known to be committed to their tables, but which may not have been
+	// deleted yet.
+	committed map[string]struct{}
+	// scanned holds the topics of the tables whose manifests have been scanned
+	// for data files of pending files. Each table is only scanned by the first
+	// commit to it; later commits rely on committed.
+	scanned map[string]struct{}
+}
+
+func makeIcebergCatalog(
+	es cloud.ExternalStorage, sinkURI *url.URL, knobs *TestingKnobs,
+) *icebergCatalog {
+	location := *sinkURI
+	location.RawQuery = ""
+	location.User = nil
+	return &icebergCatalog{
+		es:        es,
+		location:  strings.TrimSuffix(location.String(), "/"),
+		knobs:     knobs,
+		committed: make(map[string]struct{}),
+		scanned:   make(map[string]struct{}),
+	}
+}
+
+// commit adds the data files that precede the resolved timestamp to their
+// tables as one new snapshot per table.
+func (c *icebergCatalog) commit(ctx context.Context, resolved hlc.Timestamp) error {
+	resolvedPrefix := cloudStorageFormatTime(resolved)
+	var names []string
+	allPending := make(map[string]struct{})
+	if err := c.es.List(ctx, icebergPendingDir+"/", "", func(name string) error {
+		name = strings.TrimPrefix(name, "/")
+		allPending[path.Join(icebergPendingDir, name)] = struct{}{}
+		// Pending files are named after their data files, which start with a
+		// fixed width timestamp.
+		if len(name) >= len(resolvedPrefix) && name[:len(resolvedPrefix)] <= resolvedPrefix {
+			names = append(names, name)
+		}
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "listing pending iceberg data files")
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	pending := make(map[string][]icebergPendingFile)
+	for _, name := range names {
+		f, err := c.readPendingFile(ctx, path.Join(icebergPendingDir, name))
+		if err != nil {
+			return err
+		}
+		pending[f.Topic] = append(pending[f.Topic], f)
+	}
+
+	topics := make([]string, 0, len(pending))
+	for topic := range pending {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	for _, topic := range topics {
+		if err := c.commitTable(ctx, topic, resolved, pending[topic], allPending); err != nil {
+			// The table may or may not have been committed to; scan it again
+			// the next time.
+			delete(c.scanned, topic)
+			return errors.Wrapf(err, "committing iceberg table %s", topic)
+		}
+	}
+
+	if c.knobs != nil && c.knobs.AfterIcebergCommit != nil {
+		if err := c.knobs.AfterIcebergCommit(); err != nil {
+			return err
+		}
+	}
+
+	// The files are committed; failing to clean up after them only means they
+	// will be skipped again by the next commit.
+	for _, name := range names {
+		name = path.Join(icebergPendingDir, name)
+		if err := c.es.Delete(ctx, name); err != nil {
+			log.Warningf(ctx, "failed to delete pending iceberg data file %s: %v", name, err)
+			continue
+		}
+		delete(c.committed, name)
+	}
+	return nil
+}
+
+func (c *icebergCatalog) readPendingFile(
+	ctx context.Context, name string,
+) (icebergPendingFile, error) {
+	var f icebergPendingFile
+	b, err := c.readFile(ctx, name)
+	if err != nil {
+		return f, err
+	}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return f, errors.Wrapf(err, "decoding %s", name)
+	}
+	return f, nil
+}
+
+func (c *icebergCatalog) readFile(ctx context.Context, name string) ([]byte, error) {
+	r, _, err := c.es.ReadFile(ctx, name, cloud.ReadOptions{NoFileSize: true})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close(ctx)
+	return ioctx.ReadAll(ctx, r)
+}
+
+// loadTable returns the current metadata of the table for the given topic
+// and its version, or nil if the table does not exist yet.
+func (c *icebergCatalog) loadTable(
+	ctx context.Context, topic string,
+) (*icebergTableMetadata, int, error) {
+	hint, err := c.readFile(ctx, path.Join(topic, icebergMetadataDir, icebergVersionHintFile))
+	if err != nil {
+		if errors.Is(err, cloud.ErrFileDoesNotExist) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(hint)))
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "parsing %s", icebergVersionHintFile)
+	}
+	b, err := c.readFile(ctx, icebergMetadataFilePath(topic, version))
+	if err != nil {
+		return nil, 0, err
+	}
+	md := &icebergTableMetadata{}
+	if err := json.Unmarshal(b, md); err != nil {
+		return nil, 0, errors.Wrapf(err, "decoding iceberg table metadata")
+	}
+	return md, version, nil
+}
+
+func icebergMetadataFilePath(topic string, version int) string {
+	return path.Join(topic, icebergMetadataDir, fmt.Sprintf("v%d.metadata.json", version))
+}
+
+// commitTable commits the given files to the table for the given topic. The
+// paths of all the files in the pending directory are passed in allPending.
+func (c *icebergCatalog) commitTable(
+	ctx context.Context,
+	topic string,
+	resolved hlc.Timestamp,
+	files []icebergPendingFile,
+	allPending map[string]struct{},
+) error {
+	md, version, err := c.loadTable(ctx, topic)
+	if err != nil {
+		return err
+	}
+	if md == nil {
+		md = newIcebergTableMetadata(c.location + "/" + topic)
+		c.scanned[topic] = struct{}{}
+	} else {
+		// A previous attempt may have committed some of these files and failed
+		// before it removed them from the pending directory. Committing them
+		// again would duplicate their rows.
+		if _, ok := c.scanned[topic]; !ok {
+			if err := c.scanCommittedDataFiles(ctx, md, allPending); err != nil {
+				return err
+			}
+			c.scanned[topic] = struct{}{}
+		}
+		uncommitted := files[:0:0]
+		for _, f := range files {
+			if _, ok := c.committed[icebergPendingFilePath(f.Path)]; !ok {
+				uncommitted = append(uncommitted, f)
+			}
+		}
+		if files = uncommitted; len(files) == 0 {
+			return nil
+		}
+		if prev, ok := md.Properties[icebergResolvedProperty]; ok {
+			prevResolved, err := hlc.ParseHLC(prev)
+			if err != nil {
+				return errors.Wrapf(err, "parsing %s", icebergResolvedProperty)
+			}
+			// The changefeed may have restarted from a checkpoint which precedes
+			// the last commit; the resolved timestamp of the table never
+			// regresses.
+			resolved.Forward(prevResolved)
+		}
+	}
+
+	// Evolve the schema to cover the columns of every new data file.
+	schema := md.currentSchema()
+	fields := append([]icebergField(nil), schema.Fields...)
+	schemaChanged := false
+	for _, f := range files {
+		var changed bool
+		if fields, changed, err = mergeIcebergFields(fields, f.Columns, &md.LastColumnID); err != nil {
+			return err
+		}
+		schemaChanged = schemaChanged || changed
+	}
+	if schemaChanged {
+		schemaID := 0
+		for _, s := range md.Schemas {
+			if s.SchemaID >= schemaID {
+				schemaID = s.SchemaID + 1
+			}
+		}
+		if md.CurrentSnapshotID == nil {
+			// Nothing refers to the initial empty schema; replace it.
+			md.Schemas, schemaID = nil, 0
+		}
+		schema = icebergSchema{Type: `struct`, SchemaID: schemaID, Fields: fields}
+		md.Schemas = append(md.Schemas, schema)
+		md.CurrentSchemaID = schemaID
+		nameMapping, err := json.Marshal(makeIcebergNameMapping(fields))
+		if err != nil {
+			return err
+		}
+		md.Properties[icebergNameMappingProperty] = string(nameMapping)
+	}
+
+	parent := md.currentSnapshot()
+	snapshotID := resolved.WallTime
+	if parent != nil && snapshotID <= parent.SnapshotID {
+		snapshotID = parent.SnapshotID + 1
+	}
+	seq := md.LastSequenceNumber + 1
+	timestampMs := resolved.GoTime().UnixMilli()
+	if timestampMs < md.LastUpdatedMs {
+		timestampMs = md.LastUpdatedMs
+	}
+
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	manifestName := path.Join(topic, icebergMetadataDir,
+		fmt.Sprintf("%s-m0.avro", uuid.MakeV4()))
+	var addedRows int64
+	entries := make([]interface{}, 0, len(files))
+	for _, f := range files {
+		addedRows += f.RecordCount
+		entries = append(entries, map[string]interface{}{
+			`status`:      icebergManifestEntryStatusAdded,
+			`snapshot_id`: goavro.Union(`long`, snapshotID),
+			`data_file`: map[string]interface{}{
+				`content`:            icebergContentData,
+				`file_path`:          c.location + "/" + f.Path,
+				`file_format`:        `PARQUET`,
+				`partition`:          map[string]interface{}{},
+				`record_count`:       f.RecordCount,
+				`file_size_in_bytes`: f.FileSizeInBytes,
+			},
+		})
+	}
+	manifestLength, err := c.writeAvro(ctx, manifestName, icebergManifestEntrySchema,
+		map[string][]byte{
+			`schema`:            schemaJSON,
+			`schema-id`:         []byte(strconv.Itoa(schema.SchemaID)),
+			`partition-spec`:    []byte(`[]`),
+			`partition-spec-id`: []byte(`0`),
+			`format-version`:    []byte(strconv.Itoa(icebergFormatVersion)),
+			`content`:           []byte(`data`),
+		}, entries)
+	if err != nil {
+		return err
+	}
+
+	// The manifest list of the new snapshot carries forward the manifests of
+	// its parent.
+	var manifests []interface{}
+	if parent != nil {
+		if manifests, err = c.readAvro(ctx, strings.TrimPrefix(parent.ManifestList, c.location+"/")); err != nil {
+			return err
+		}
+	}
+	manifests = append(manifests, map[string]interface{}{
+		`manifest_path`:        c.location + "/" + manifestName,
+		`manifest_length`:      manifestLength,
+		`partition_spec_id`:    0,
+		`content`:              icebergContentData,
+		`sequence_number`:      seq,
+		`min_sequence_number`:  seq,
+		`added_snapshot_id`:    snapshotID,
+		`added_files_count`:    len(files),
+		`existing_files_count`: 0,
+		`deleted_files_count`:  0,
+		`added_rows_count`:     addedRows,
+		`existing_rows_count`:  int64(0),
+		`deleted_rows_count`:   int64(0),
+	})
+	manifestListName := path.Join(topic, icebergMetadataDir,
+		fmt.Sprintf("snap-%d-1-%s.avro", snapshotID, uuid.MakeV4()))
+	manifestListMeta := map[string][]byte{
+		`snapshot-id`:     []byte(strconv.FormatInt(snapshotID, 10)),
+		`sequence-number`: []byte(strconv.FormatInt(seq, 10)),
+		`format-version`:  []byte(strconv.Itoa(icebergFormatVersion)),
+	}
+	if parent != nil {
+		manifestListMeta[`parent-snapshot-id`] = []byte(strconv.FormatInt(parent.SnapshotID, 10))
+	}
+	if _, err := c.writeAvro(ctx, manifestListName, icebergManifestFileSchema,
+		manifestListMeta, manifests); err != nil {
+		return err
+	}
+
+	snapshot := icebergSnapshot{
+		SnapshotID:     snapshotID,
+		SequenceNumber: seq,
+		TimestampMs:    timestampMs,
+		ManifestList:   c.location + "/" + manifestListName,
+		Summary: map[string]string{
+			`operation`:               `append`,
+			`added-data-files`:        strconv.Itoa(len(files)),
+			`added-records`:           strconv.FormatInt(addedRows, 10),
+			icebergResolvedProperty:   resolved.AsOfSystemTime(),
+			`changed-partition-count`: `0`,
+		},
+		SchemaID: schema.SchemaID,
+	}
+	if parent != nil {
+		parentID := parent.SnapshotID
+		snapshot.ParentSnapshotID = &parentID
+	}
+	if version > 0 {
+		md.MetadataLog = append(md.MetadataLog, icebergMetadataLogEntry{
+			TimestampMs:  md.LastUpdatedMs,
+			MetadataFile: c.location + "/" + icebergMetadataFilePath(topic, version),
+		})
+	}
+	md.Snapshots = append(md.Snapshots, snapshot)
+	md.SnapshotLog = append(md.SnapshotLog, icebergSnapshotLogEntry{
+		TimestampMs: timestampMs, SnapshotID: snapshotID,
+	})
+	md.CurrentSnapshotID = &snapshotID
+	md.Refs[`main`] = icebergSnapshotRef{SnapshotID: snapshotID, Type: `branch`}
+	md.LastSequenceNumber = seq
+	md.LastUpdatedMs = timestampMs
+	md.Properties[icebergResolvedProperty] = resolved.AsOfSystemTime()
+
+	// Writing the new metadata file commits the snapshot. The version hint is
+	// only an optimization for readers, which fall back to listing the
+	// metadata directory.
+	b, err := json.Marshal(md)
+	if err != nil {
+		return err
+	}
+	if err := cloud.WriteFile(ctx, c.es, icebergMetadataFilePath(topic, version+1),
+		bytes.NewReader(b)); err != nil {
+		return err
+	}
+	for _, f := range files {
+		c.committed[icebergPendingFilePath(f.Path)] = struct{}{}
+	}
+	return cloud.WriteFile(ctx, c.es, path.Join(topic, icebergMetadataDir, icebergVersionHintFile),
+		strings.NewReader(strconv.Itoa(version+1)))
+}
+
+// scanCommittedDataFiles adds the pending files whose data files are in the
+// manifests of the current snapshot of the table to c.committed. Since the
+// changeFrontier is the only writer of table metadata, this only needs to read
+// every manifest of the table once, when the catalog first commits to it.
+func (c *icebergCatalog) scanCommittedDataFiles(
+	ctx context.Context, md *icebergTableMetadata, allPending map[string]struct{},
+) error {
+	snapshot := md.currentSnapshot()
+	if snapshot == nil {
+		return nil
+	}
+	manifests, err := c.readAvro(ctx, strings.TrimPrefix(snapshot.ManifestList, c.location+"/"))
+	if err != nil {
+		return err
+	}
+	for _, m := range manifests {
+		manifestPath, _ := m.(map[string]interface{})[`manifest_path`].(string)
+		entries, err := c.readAvro(ctx, strings.TrimPrefix(manifestPath, c.location+"/"))
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			dataFile, _ := e.(map[string]interface{})[`data_file`].(map[string]interface{})
+			if filePath, ok := dataFile[`file_path`].(string); ok {
+				name := icebergPendingFilePath(filePath)
+				if _, ok := allPending[name]; ok {
+					c.committed[name] = struct{}{}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeAvro writes the records to an avro object container file and returns
+// its length.
+func (c *icebergCatalog) writeAvro(
+	ctx context.Context,
+	name string,
+	schema string,
+	metadata map[string][]byte,
+	records []interface{},
+) (int64, error) {
+	var buf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:        &buf,
+		Schema:   schema,
+		MetaData: metadata,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := w.Append(records); err != nil {
+		return 0, errors.Wrapf(err, "encoding %s", name)
+	}
+	length := int64(buf.Len())
+	if err := cloud.WriteFile(ctx, c.es, name, &buf); err != nil {
+		return 0, err
+	}
+	return length, nil
+}
+
+// readAvro returns the records in an avro object container file.
+func (c *icebergCatalog) readAvro(ctx context.Context, name string) ([]interface{}, error) {
+	b, err := c.readFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	r, err := goavro.NewOCFReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding %s", name)
+	}
+	var records []interface{}
+	for r.Scan() {
+		record, err := r.Read()
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding %s", name)
+		}
+		records = append(records, record)
+	}
+	return records, r.Err()
+}
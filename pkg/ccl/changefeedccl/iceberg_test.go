@@ -0,0 +1,267 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/blobs"
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/cdctest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIcebergColumnTypes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	for _, tc := range []struct {
+		typ      *types.T
+		expected icebergColumn
+	}{
+		{typ: types.Int, expected: icebergColumn{Name: "c", Type: "long"}},
+		{typ: types.Int4, expected: icebergColumn{Name: "c", Type: "int"}},
+		{typ: types.Float4, expected: icebergColumn{Name: "c", Type: "float"}},
+		{typ: types.Decimal, expected: icebergColumn{Name: "c", Type: "string"}},
+		{typ: types.Uuid, expected: icebergColumn{Name: "c", Type: "uuid"}},
+		{typ: types.Bytes, expected: icebergColumn{Name: "c", Type: "binary"}},
+		{typ: types.IntArray, expected: icebergColumn{Name: "c", Type: "list", Element: "long"}},
+		{
+			typ: types.MakeLabeledTuple([]*types.T{types.Bool, types.String}, []string{"a", "b"}),
+			expected: icebergColumn{Name: "c", Type: "struct", Fields: []icebergColumn{
+				{Name: "a", Type: "boolean"}, {Name: "b", Type: "string"},
+			}},
+		},
+	} {
+		t.Run(tc.typ.SQLString(), func(t *testing.T) {
+			col, err := makeIcebergColumn("c", tc.typ)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, col)
+		})
+	}
+}
+
+func TestIcebergCatalogCommit(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	externalIODir, dirCleanupFn := testutils.TempDir(t)
+	defer dirCleanupFn()
+	settings := cluster.MakeTestingClusterSettings()
+	settings.ExternalIODir = externalIODir
+
+	es, err := cloud.ExternalStorageFromURI(ctx, "nodelocal://1/iceberg", base.ExternalIODirConfig{}, settings,
+		blobs.TestBlobServiceClient(settings.ExternalIODir), username.RootUserName(),
+		nil /* db */, nil /* limiters */, cloud.NilMetrics)
+	require.NoError(t, err)
+	defer es.Close()
+
+	// Query parameters of the sink URI may contain credentials; they are not
+	// part of the table location.
+	u, err := url.Parse("nodelocal://1/iceberg?AUTH=specified")
+	require.NoError(t, err)
+	c := makeIcebergCatalog(es, u, nil /* knobs */)
+	require.Equal(t, "nodelocal://1/iceberg", c.location)
+
+	ts := func(i int64) hlc.Timestamp { return hlc.Timestamp{WallTime: i} }
+	fileID := 0
+	writeDataFile := func(t *testing.T, at hlc.Timestamp, cols ...icebergColumn) {
+		fileID++
+		f := &icebergPendingFile{
+			Topic: "t",
+			Path: path.Join("t", icebergDataDir,
+				fmt.Sprintf("%s-%d-t-1.parquet", cloudStorageFormatTime(at), fileID)),
+			RecordCount:     10,
+			FileSizeInBytes: 100,
+			Columns:         cols,
+		}
+		require.NoError(t, cloud.WriteFile(ctx, es, f.Path, strings.NewReader("data")))
+		require.NoError(t, writeIcebergPendingFile(ctx, es, f))
+	}
+	manifests := func(t *testing.T, md *icebergTableMetadata) []interface{} {
+		list := strings.TrimPrefix(md.currentSnapshot().ManifestList, c.location+"/")
+		records, err := c.readAvro(ctx, list)
+		require.NoError(t, err)
+		return records
+	}
+
+	a := icebergColumn{Name: "a", Type: "int"}
+	writeDataFile(t, ts(1), a)
+	writeDataFile(t, ts(3), a)
+
+	// Only the file that precedes the resolved timestamp is committed.
+	require.NoError(t, c.commit(ctx, ts(2)))
+	md, version, err := c.loadTable(ctx, "t")
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+	require.Equal(t, "nodelocal://1/iceberg/t", md.Location)
+	require.Len(t, md.Snapshots, 1)
+	require.Equal(t, "10", md.currentSnapshot().Summary["added-records"])
+	require.Equal(t, ts(2).AsOfSystemTime(), md.Properties[icebergResolvedProperty])
+	require.Equal(t, []icebergField{{ID: 1, Name: "a", Type: icebergType{Primitive: "int"}}},
+		md.currentSchema().Fields)
+	require.Len(t, manifests(t, md), 1)
+
+	// A new column and a type promotion evolve the schema.
+	writeDataFile(t, ts(4), icebergColumn{Name: "a", Type: "long"},
+		icebergColumn{Name: "b", Type: "list", Element: "string"})
+	require.NoError(t, c.commit(ctx, ts(5)))
+	md, version, err = c.loadTable(ctx, "t")
+	require.NoError(t, err)
+	require.Equal(t, 2, version)
+	require.Len(t, md.Snapshots, 2)
+	require.Equal(t, md.Snapshots[0].SnapshotID, *md.currentSnapshot().ParentSnapshotID)
+	require.Equal(t, "20", md.currentSnapshot().Summary["added-records"])
+	require.Equal(t, 1, md.CurrentSchemaID)
+	require.Equal(t, []icebergField{
+		{ID: 1, Name: "a", Type: icebergType{Primitive: "long"}},
+		{ID: 2, Name: "b", Type: icebergType{ElementID: 3, Element: "string"}},
+	}, md.currentSchema().Fields)
+	require.Equal(t,
+		`[{"field-id":1,"names":["a"]},{"field-id":2,"names":["b"],"fields":[{"field-id":3,"names":["element"]}]}]`,
+		md.Properties[icebergNameMappingProperty])
+	require.Len(t, manifests(t, md), 2)
+
+	// Nothing is pending, so there is nothing to commit.
+	require.NoError(t, c.commit(ctx, ts(6)))
+	_, version, err = c.loadTable(ctx, "t")
+	require.NoError(t, err)
+	require.Equal(t, 2, version)
+
+	// Only the first commit to a table reads all of its manifests, to find the
+	// pending files which are already committed; later commits don't read the
+	// manifests of earlier snapshots.
+	for _, m := range manifests(t, md) {
+		manifestPath := strings.TrimPrefix(m.(map[string]interface{})[`manifest_path`].(string), c.location+"/")
+		require.NoError(t, cloud.WriteFile(ctx, es, manifestPath, strings.NewReader("corrupt")))
+	}
+	writeDataFile(t, ts(7), icebergColumn{Name: "a", Type: "long"})
+	require.NoError(t, c.commit(ctx, ts(8)))
+	_, version, err = c.loadTable(ctx, "t")
+	require.NoError(t, err)
+	require.Equal(t, 3, version)
+	writeDataFile(t, ts(9), icebergColumn{Name: "a", Type: "long"})
+	require.Regexp(t, "decoding", makeIcebergCatalog(es, u, nil /* knobs */).commit(ctx, ts(10)))
+
+	// Iceberg does not allow arbitrary type changes.
+	writeDataFile(t, ts(11), icebergColumn{Name: "a", Type: "string"})
+	require.Regexp(t, "column a cannot change type", c.commit(ctx, ts(12)))
+}
+
+// TestIcebergChangefeedCrashBeforeCleanup verifies that the data files which
+// a changefeed committed to an Iceberg table before it failed, but had not yet
+// removed from the pending directory, are not committed again once it resumes.
+func TestIcebergChangefeedCrashBeforeCleanup(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	testFn := func(t *testing.T, s TestServer, f cdctest.TestFeedFactory) {
+		ctx := context.Background()
+		sqlDB := sqlutils.MakeSQLRunner(s.DB)
+		sqlDB.Exec(t, `CREATE TABLE foo (a INT PRIMARY KEY)`)
+		sqlDB.Exec(t, `INSERT INTO foo VALUES (1), (2)`)
+
+		// Fail the first commit after it committed the data files to the table,
+		// which leaves them in the pending directory.
+		var crashed atomic.Bool
+		knobs := s.TestingKnobs.DistSQL.(*execinfra.TestingKnobs).Changefeed.(*TestingKnobs)
+		knobs.AfterIcebergCommit = func() error {
+			if crashed.CompareAndSwap(false, true) {
+				return changefeedbase.MarkRetryableError(errors.New("crashed before cleanup"))
+			}
+			return nil
+		}
+
+		settings := cluster.MakeTestingClusterSettings()
+		settings.ExternalIODir = f.(*cloudFeedFactory).dir
+		es, err := cloud.ExternalStorageFromURI(ctx, "nodelocal://1/iceberg", base.ExternalIODirConfig{},
+			settings, blobs.TestBlobServiceClient(settings.ExternalIODir), username.RootUserName(),
+			nil /* db */, nil /* limiters */, cloud.NilMetrics)
+		require.NoError(t, err)
+		defer es.Close()
+		u, err := url.Parse("nodelocal://1/iceberg")
+		require.NoError(t, err)
+		c := makeIcebergCatalog(es, u, nil /* knobs */)
+
+		var jobID int
+		sqlDB.QueryRow(t, `CREATE CHANGEFEED FOR foo INTO 'nodelocal://1/iceberg?table_format=iceberg'
+WITH format=parquet, resolved='10ms', min_checkpoint_frequency='10ms'`).Scan(&jobID)
+		defer sqlDB.Exec(t, `CANCEL JOB $1`, jobID)
+
+		testutils.SucceedsSoon(t, func() error {
+			if !crashed.Load() {
+				return errors.New("changefeed has not committed yet")
+			}
+			return nil
+		})
+		sqlDB.Exec(t, `INSERT INTO foo VALUES (3)`)
+
+		// Once the changefeed committed again, it also cleaned up after the
+		// first commit.
+		testutils.SucceedsSoon(t, func() error {
+			md, _, err := c.loadTable(ctx, "foo")
+			if err != nil {
+				return err
+			}
+			if md == nil || len(md.Snapshots) < 2 {
+				return errors.New("waiting for the changefeed to commit again")
+			}
+			var pending []string
+			if err := es.List(ctx, icebergPendingDir+"/", "", func(name string) error {
+				pending = append(pending, name)
+				return nil
+			}); err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return errors.Newf("pending data files: %v", pending)
+			}
+			return nil
+		})
+
+		// Every data file was committed exactly once.
+		md, _, err := c.loadTable(ctx, "foo")
+		require.NoError(t, err)
+		seen := make(map[string]bool)
+		manifests, err := c.readAvro(ctx, strings.TrimPrefix(md.currentSnapshot().ManifestList, c.location+"/"))
+		require.NoError(t, err)
+		for _, m := range manifests {
+			manifestPath := m.(map[string]interface{})[`manifest_path`].(string)
+			entries, err := c.readAvro(ctx, strings.TrimPrefix(manifestPath, c.location+"/"))
+			require.NoError(t, err)
+			for _, e := range entries {
+				filePath := e.(map[string]interface{})[`data_file`].(map[string]interface{})[`file_path`].(string)
+				require.False(t, seen[filePath], "data file %s committed more than once", filePath)
+				seen[filePath] = true
+			}
+		}
+		require.NotEmpty(t, seen)
+	}
+
+	cdcTest(t, testFn, feedTestForceSink("cloudstorage"))
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvclient/kvcoord"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
@@ -77,6 +78,11 @@ type Config struct {
 
 	// UseMux enables MuxRangeFeed rpc
 	UseMux bool
+
+	// RangeFeedFilter, if set, is evaluated by the servers of the rangefeeds
+	// to discard events before they are sent to the kvfeed. It is best-effort:
+	// the events still need to be filtered once received.
+	RangeFeedFilter *kvpb.RangeFeedFilter
 }
 
 // Run will run the kvfeed. The feed runs synchronously and returns an
@@ -111,6 +117,7 @@ func Run(ctx context.Context, cfg Config) error {
 		cfg.SchemaFeed,
 		sc, pff, bf, cfg.UseMux, cfg.Targets, cfg.Knobs)
 	f.onBackfillCallback = cfg.OnBackfillCallback
+	f.rangefeedFilter = cfg.RangeFeedFilter
 
 	g := ctxgroup.WithContext(ctx)
 	g.GoCtx(cfg.SchemaFeed.Run)
@@ -182,6 +189,7 @@ type kvFeed struct {
 	codec               keys.SQLCodec
 
 	onBackfillCallback func() func()
+	rangefeedFilter    *kvpb.RangeFeedFilter
 	schemaChangeEvents changefeedbase.SchemaChangeEventClass
 	schemaChangePolicy changefeedbase.SchemaChangePolicy
 
@@ -497,6 +505,7 @@ func (f *kvFeed) runUntilTableEvent(
 		WithDiff: f.withDiff,
 		Knobs:    f.knobs,
 		UseMux:   f.useMux,
+		Filter:   f.rangefeedFilter,
 	}
 
 	// The following two synchronous calls works as follows:
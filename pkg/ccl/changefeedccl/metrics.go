@@ -49,6 +49,7 @@ const defaultSLIScope = "default"
 type AggMetrics struct {
 	EmittedMessages           *aggmetric.AggCounter
 	FilteredMessages          *aggmetric.AggCounter
+	DeadLetterMessages        *aggmetric.AggCounter
 	MessageSize               *aggmetric.AggHistogram
 	EmittedBytes              *aggmetric.AggCounter
 	FlushedBytes              *aggmetric.AggCounter
@@ -97,6 +98,7 @@ type metricsRecorder interface {
 	recordSizeBasedFlush()
 	recordParallelIOQueueLatency(time.Duration)
 	recordSinkIOInflightChange(int64)
+	recordDeadLetters(numMessages int)
 }
 
 var _ metricsRecorder = (*sliMetrics)(nil)
@@ -109,6 +111,7 @@ func (a *AggMetrics) MetricStruct() {}
 type sliMetrics struct {
 	EmittedMessages           *aggmetric.Counter
 	FilteredMessages          *aggmetric.Counter
+	DeadLetterMessages        *aggmetric.Counter
 	MessageSize               *aggmetric.Histogram
 	EmittedBytes              *aggmetric.Counter
 	FlushedBytes              *aggmetric.Counter
@@ -265,6 +268,14 @@ func (m *sliMetrics) recordSinkIOInflightChange(delta int64) {
 	m.SinkIOInflight.Inc(delta)
 }
 
+func (m *sliMetrics) recordDeadLetters(numMessages int) {
+	if m == nil {
+		return
+	}
+
+	m.DeadLetterMessages.Inc(int64(numMessages))
+}
+
 type wrappingCostController struct {
 	ctx      context.Context
 	inner    metricsRecorder
@@ -343,6 +354,10 @@ func (w *wrappingCostController) recordSinkIOInflightChange(delta int64) {
 	w.inner.recordSinkIOInflightChange(delta)
 }
 
+func (w *wrappingCostController) recordDeadLetters(numMessages int) {
+	w.inner.recordDeadLetters(numMessages)
+}
+
 var (
 	metaChangefeedForwardedResolvedMessages = metric.Metadata{
 		Name:        "changefeed.forwarded_resolved_messages",
@@ -429,6 +444,13 @@ func newAggregateMetrics(histogramWindow time.Duration) *AggMetrics {
 		Measurement: "Messages",
 		Unit:        metric.Unit_COUNT,
 	}
+	metaChangefeedDeadLetterMessages := metric.Metadata{
+		Name: "changefeed.dead_letter_messages",
+		Help: "Messages routed to the dead letter queue because they could not " +
+			"be encoded or were permanently rejected by the sink",
+		Measurement: "Messages",
+		Unit:        metric.Unit_COUNT,
+	}
 	metaChangefeedEmittedBytes := metric.Metadata{
 		Name:        "changefeed.emitted_bytes",
 		Help:        "Bytes emitted by all feeds",
@@ -548,9 +570,10 @@ func newAggregateMetrics(histogramWindow time.Duration) *AggMetrics {
 	// retain significant figures of 2.
 	b := aggmetric.MakeBuilder("scope")
 	a := &AggMetrics{
-		ErrorRetries:     b.Counter(metaChangefeedErrorRetries),
-		EmittedMessages:  b.Counter(metaChangefeedEmittedMessages),
-		FilteredMessages: b.Counter(metaChangefeedFilteredMessages),
+		ErrorRetries:       b.Counter(metaChangefeedErrorRetries),
+		EmittedMessages:    b.Counter(metaChangefeedEmittedMessages),
+		FilteredMessages:   b.Counter(metaChangefeedFilteredMessages),
+		DeadLetterMessages: b.Counter(metaChangefeedDeadLetterMessages),
 		MessageSize: b.Histogram(metric.HistogramOptions{
 			Metadata:     metaMessageSize,
 			Duration:     histogramWindow,
@@ -646,6 +669,7 @@ func (a *AggMetrics) getOrCreateScope(scope string) (*sliMetrics, error) {
 	sm := &sliMetrics{
 		EmittedMessages:           a.EmittedMessages.AddChild(scope),
 		FilteredMessages:          a.FilteredMessages.AddChild(scope),
+		DeadLetterMessages:        a.DeadLetterMessages.AddChild(scope),
 		MessageSize:               a.MessageSize.AddChild(scope),
 		EmittedBytes:              a.EmittedBytes.AddChild(scope),
 		FlushedBytes:              a.FlushedBytes.AddChild(scope),
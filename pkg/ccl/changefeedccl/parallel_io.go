@@ -35,6 +35,10 @@ type parallelIO struct {
 	doneCh    chan struct{}
 
 	ioHandler IOHandler
+	// isPermanent, if non-nil, returns whether an error returned by the
+	// ioHandler won't go away on retries, in which case the request fails
+	// immediately.
+	isPermanent func(error) bool
 
 	requestCh chan IORequest
 	resultCh  chan *ioResult // readers should freeIOResult after handling result events
@@ -84,16 +88,18 @@ func newParallelIO(
 	numWorkers int,
 	handler IOHandler,
 	metrics metricsRecorder,
+	isPermanent func(error) bool,
 ) *parallelIO {
 	wg := ctxgroup.WithContext(ctx)
 	io := &parallelIO{
-		retryOpts: retryOpts,
-		wg:        wg,
-		metrics:   metrics,
-		ioHandler: handler,
-		requestCh: make(chan IORequest, numWorkers),
-		resultCh:  make(chan *ioResult, numWorkers),
-		doneCh:    make(chan struct{}),
+		retryOpts:   retryOpts,
+		wg:          wg,
+		metrics:     metrics,
+		ioHandler:   handler,
+		isPermanent: isPermanent,
+		requestCh:   make(chan IORequest, numWorkers),
+		resultCh:    make(chan *ioResult, numWorkers),
+		doneCh:      make(chan struct{}),
 	}
 
 	wg.GoCtx(func(ctx context.Context) error {
@@ -137,13 +143,24 @@ func (p *parallelIO) Close() {
 func (p *parallelIO) processIO(ctx context.Context, numEmitWorkers int) error {
 	emitWithRetries := func(ctx context.Context, payload IORequest) error {
 		initialSend := true
-		return retry.WithMaxAttempts(ctx, p.retryOpts, p.retryOpts.MaxRetries+1, func() error {
+		var permanentErr error
+		if err := retry.WithMaxAttempts(ctx, p.retryOpts, p.retryOpts.MaxRetries+1, func() error {
 			if !initialSend {
 				p.metrics.recordInternalRetry(int64(payload.Keys().Len()), false)
 			}
 			initialSend = false
-			return p.ioHandler(ctx, payload)
-		})
+			err := p.ioHandler(ctx, payload)
+			if err != nil && p.isPermanent != nil && p.isPermanent(err) {
+				// The payload will fail no matter how many times it is retried, so
+				// stop retrying and surface the error.
+				permanentErr = err
+				return nil
+			}
+			return err
+		}); err != nil {
+			return err
+		}
+		return permanentErr
 	}
 
 	// Multiple worker routines handle the IO operations, retrying when necessary.
@@ -50,30 +50,36 @@ type parquetWriter struct {
 func newParquetSchemaDefintion(
 	row cdcevent.Row, encodingOpts changefeedbase.EncodingOptions,
 ) (*parquet.SchemaDefinition, error) {
-	var columnNames []string
-	var columnTypes []*types.T
+	columnNames, columnTypes, err := parquetColumnsFromRow(row, encodingOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaDef, err := parquet.NewSchema(columnNames, columnTypes)
+	if err != nil {
+		return nil, err
+	}
+	return schemaDef, nil
+}
 
-	numCols := 0
+// parquetColumnsFromRow returns the names and types of the columns written to
+// parquet files for the given cdcevent.Row.
+func parquetColumnsFromRow(
+	row cdcevent.Row, encodingOpts changefeedbase.EncodingOptions,
+) (columnNames []string, columnTypes []*types.T, _ error) {
 	if err := row.ForAllColumns().Col(func(col cdcevent.ResultColumn) error {
 		columnNames = append(columnNames, col.Name)
 		columnTypes = append(columnTypes, col.Typ)
-		numCols += 1
 		return nil
 	}); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	columnNames = append(columnNames, parquetCrdbEventTypeColName)
 	columnTypes = append(columnTypes, types.String)
-	numCols += 1
 
 	columnNames, columnTypes = appendMetadataColsToSchema(columnNames, columnTypes, encodingOpts)
-
-	schemaDef, err := parquet.NewSchema(columnNames, columnTypes)
-	if err != nil {
-		return nil, err
-	}
-	return schemaDef, nil
+	return columnNames, columnTypes, nil
 }
 
 const parquetOptUpdatedTimestampColName = metaSentinel + changefeedbase.OptUpdatedTimestamps
@@ -122,8 +122,9 @@ func (parquetSink *parquetCloudStorageSink) Dial() error {
 	return parquetSink.wrapped.Dial()
 }
 
-// EmitResolvedTimestamp does not do anything as of now. It is there to
-// implement Sink interface.
+// EmitResolvedTimestamp implements the Sink interface. It writes a parquet
+// resolved timestamp file or, for Iceberg tables, commits the data files which
+// precede the resolved timestamp.
 func (parquetSink *parquetCloudStorageSink) EmitResolvedTimestamp(
 	ctx context.Context, _ Encoder, resolved hlc.Timestamp,
 ) (err error) {
@@ -140,6 +141,10 @@ func (parquetSink *parquetCloudStorageSink) EmitResolvedTimestamp(
 		return errors.Wrapf(err, "while emitting resolved timestamp")
 	}
 
+	if parquetSink.wrapped.iceberg != nil {
+		return parquetSink.wrapped.iceberg.commit(ctx, resolved)
+	}
+
 	var buf bytes.Buffer
 	sch, err := parquet.NewSchema([]string{metaSentinel + "resolved"}, []*types.T{types.Decimal})
 	if err != nil {
@@ -202,11 +207,19 @@ func (parquetSink *parquetCloudStorageSink) EncodeAndEmitRow(
 		if err != nil {
 			return err
 		}
+		if s.iceberg != nil {
+			cols, err := makeIcebergColumnsFromRow(updatedRow, encodingOpts)
+			if err != nil {
+				return err
+			}
+			file.iceberg = &icebergPendingFile{Topic: file.topic, Columns: cols}
+		}
 	}
 
 	if err := file.parquetCodec.addData(updatedRow, prevRow, updated, mvcc); err != nil {
 		return err
 	}
+	file.numMessages++
 
 	if int64(file.buf.Len()) > s.targetMaxFileSize {
 		s.metrics.recordSizeBasedFlush()
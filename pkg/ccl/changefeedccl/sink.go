@@ -59,6 +59,9 @@ const (
 	sinkTypePubsub
 	sinkTypeCloudstorage
 	sinkTypeSQL
+	sinkTypePostgres
+	sinkTypeNATS
+	sinkTypeAMQP
 )
 
 // externalResource is the interface common to both EventSink and
@@ -282,6 +285,20 @@ func getSink(
 			return validateOptionsAndMakeSink(changefeedbase.SQLValidOptions, func() (Sink, error) {
 				return makeSQLSink(sinkURL{URL: u}, sqlSinkTableName, AllTargets(feedCfg), metricsBuilder)
 			})
+		case u.Scheme == changefeedbase.SinkSchemePostgres || u.Scheme == changefeedbase.SinkSchemePostgresql:
+			return validateOptionsAndMakeSink(changefeedbase.PostgresValidOptions, func() (Sink, error) {
+				return makePostgresSink(sinkURL{URL: u}, opts.GetPostgresConfigJSON(), encodingOpts, jobID, metricsBuilder)
+			})
+		case u.Scheme == changefeedbase.SinkSchemeNATS:
+			return validateOptionsAndMakeSink(changefeedbase.NATSValidOptions, func() (Sink, error) {
+				return makeNATSSink(ctx, sinkURL{URL: u}, encodingOpts, opts.GetNATSConfigJSON(), AllTargets(feedCfg),
+					numSinkIOWorkers(serverCfg), newCPUPacerFactory(ctx, serverCfg), timeutil.DefaultTimeSource{}, metricsBuilder)
+			})
+		case u.Scheme == changefeedbase.SinkSchemeAMQP || u.Scheme == changefeedbase.SinkSchemeAMQPS:
+			return validateOptionsAndMakeSink(changefeedbase.AMQPValidOptions, func() (Sink, error) {
+				return makeAMQPSink(ctx, sinkURL{URL: u}, encodingOpts, opts.GetAMQPConfigJSON(), AllTargets(feedCfg),
+					numSinkIOWorkers(serverCfg), newCPUPacerFactory(ctx, serverCfg), timeutil.DefaultTimeSource{}, metricsBuilder)
+			})
 		case u.Scheme == changefeedbase.SinkSchemeExternalConnection:
 			return validateOptionsAndMakeSink(changefeedbase.ExternalConnectionValidOptions, func() (Sink, error) {
 				return makeExternalConnectionSink(
@@ -726,6 +743,13 @@ type SinkWithEncoder interface {
 	Flush(ctx context.Context) error
 }
 
+// sinkEncodesRows returns true if the changefeed must pass rows to the sink's
+// EncodeAndEmitRow method instead of encoding them itself.
+func sinkEncodesRows(sink EventSink, encodingOpts changefeedbase.EncodingOptions) bool {
+	return encodingOpts.Format == changefeedbase.OptFormatParquet ||
+		sink.getConcreteType() == sinkTypePostgres
+}
+
 // proper JSON schema for sink config:
 //
 //	{
@@ -0,0 +1,407 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/util/admission"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	amqpDefaultPort    = "5672"
+	amqpDefaultTLSPort = "5671"
+
+	// amqpKeyHeader is the message header which carries the encoded key of the
+	// row, since the message body only contains the encoded value.
+	amqpKeyHeader = "crdb-key"
+
+	// amqpTimeout bounds how long dialing the broker and waiting for the
+	// confirmations of a batch of messages may take.
+	amqpTimeout = 30 * time.Second
+
+	// amqpHeartbeat is the heartbeat interval requested from the broker, so
+	// that connections to brokers which became unreachable are detected.
+	amqpHeartbeat = 10 * time.Second
+)
+
+// amqpSinkClient is a SinkClient which publishes messages to an AMQP 0-9-1
+// broker such as RabbitMQ. Channels are put in confirm mode so that a batch
+// is only considered flushed once the broker has confirmed every message in
+// it.
+//
+// Each topic is published to the exchange given by the exchange parameter
+// (the default exchange if unset) with the topic as the routing key. The URI
+// is of the form amqp[s]://[user:password@]host[:port][/vhost].
+type amqpSinkClient struct {
+	address     string
+	tlsConfig   *tls.Config
+	user        string
+	password    string
+	vhost       string
+	exchange    string
+	contentType string
+	batchCfg    sinkBatchConfig
+
+	mu struct {
+		syncutil.Mutex
+		// idle contains connections which are not being used by a Flush.
+		// Connections which encounter an error are closed rather than being
+		// returned here.
+		idle []*amqpConn
+	}
+}
+
+var _ SinkClient = (*amqpSinkClient)(nil)
+
+type amqpMessage struct {
+	key   []byte
+	value []byte
+}
+
+type amqpPayload struct {
+	routingKey string
+	messages   []amqpMessage
+}
+
+func makeAMQPSinkClient(
+	u *sinkURL, encodingOpts changefeedbase.EncodingOptions, batchCfg sinkBatchConfig,
+) (*amqpSinkClient, error) {
+	defaultPort := amqpDefaultPort
+	switch u.Scheme {
+	case changefeedbase.SinkSchemeAMQP:
+	case changefeedbase.SinkSchemeAMQPS:
+		defaultPort = amqpDefaultTLSPort
+	default:
+		return nil, errors.Errorf("unknown scheme: %s", u.Scheme)
+	}
+
+	sc := &amqpSinkClient{
+		address:  u.Host,
+		user:     "guest",
+		password: "guest",
+		vhost:    "/",
+		batchCfg: batchCfg,
+	}
+
+	switch encodingOpts.Format {
+	case changefeedbase.OptFormatJSON:
+		sc.contentType = "application/json"
+	case changefeedbase.OptFormatCSV:
+		sc.contentType = "text/csv"
+	default:
+		return nil, errors.Errorf(`this sink is incompatible with %s=%s`,
+			changefeedbase.OptFormat, encodingOpts.Format)
+	}
+
+	if u.Host == "" {
+		return nil, errors.New("missing AMQP broker address")
+	}
+	if u.Port() == "" {
+		sc.address = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	if u.User != nil {
+		sc.user = u.User.Username()
+		sc.password, _ = u.User.Password()
+	}
+	// As in the AMQP URI specification, the path names the virtual host
+	// without its leading slash, and an empty path refers to the default
+	// virtual host.
+	if len(u.Path) > 1 {
+		vhost, err := url.PathUnescape(u.EscapedPath()[1:])
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing AMQP virtual host")
+		}
+		sc.vhost = vhost
+	}
+
+	sc.exchange = u.consumeParam(changefeedbase.SinkParamExchange)
+	if len(sc.exchange) > 255 {
+		return nil, errors.Errorf("%s must be at most 255 bytes", changefeedbase.SinkParamExchange)
+	}
+
+	tlsConfig, err := makeSinkTLSConfig(u, u.Scheme == changefeedbase.SinkSchemeAMQPS)
+	if err != nil {
+		return nil, err
+	}
+	sc.tlsConfig = tlsConfig
+	return sc, nil
+}
+
+// MakeResolvedPayload implements the SinkClient interface
+func (sc *amqpSinkClient) MakeResolvedPayload(body []byte, topic string) (SinkPayload, error) {
+	return &amqpPayload{
+		routingKey: topic,
+		messages:   []amqpMessage{{value: body}},
+	}, nil
+}
+
+// Flush implements the SinkClient interface
+func (sc *amqpSinkClient) Flush(ctx context.Context, payload SinkPayload) error {
+	p := payload.(*amqpPayload)
+	if len(p.routingKey) > 255 {
+		return markSinkRejectedMessage(errors.Errorf(
+			"routing key %q is longer than 255 bytes", p.routingKey))
+	}
+
+	conn, err := sc.getConn(ctx)
+	if err != nil {
+		return err
+	}
+	if err := conn.publish(ctx, sc, p); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	sc.releaseConn(conn)
+	return nil
+}
+
+func (sc *amqpSinkClient) getConn(ctx context.Context) (*amqpConn, error) {
+	sc.mu.Lock()
+	if n := len(sc.mu.idle); n > 0 {
+		conn := sc.mu.idle[n-1]
+		sc.mu.idle = sc.mu.idle[:n-1]
+		sc.mu.Unlock()
+		return conn, nil
+	}
+	sc.mu.Unlock()
+	return dialAMQP(ctx, sc)
+}
+
+func (sc *amqpSinkClient) releaseConn(conn *amqpConn) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.mu.idle = append(sc.mu.idle, conn)
+}
+
+// Close implements the SinkClient interface
+func (sc *amqpSinkClient) Close() error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	var err error
+	for _, conn := range sc.mu.idle {
+		err = errors.CombineErrors(err, conn.Close())
+	}
+	sc.mu.idle = nil
+	return err
+}
+
+type amqpBuffer struct {
+	sc         *amqpSinkClient
+	routingKey string
+	messages   []amqpMessage
+	numBytes   int
+}
+
+var _ BatchBuffer = (*amqpBuffer)(nil)
+
+// Append implements the BatchBuffer interface
+func (ab *amqpBuffer) Append(key []byte, value []byte) {
+	ab.messages = append(ab.messages, amqpMessage{key: key, value: value})
+	ab.numBytes += len(key) + len(value)
+}
+
+// ShouldFlush implements the BatchBuffer interface
+func (ab *amqpBuffer) ShouldFlush() bool {
+	return shouldFlushBatch(ab.numBytes, len(ab.messages), ab.sc.batchCfg)
+}
+
+// Close implements the BatchBuffer interface
+func (ab *amqpBuffer) Close() (SinkPayload, error) {
+	return &amqpPayload{routingKey: ab.routingKey, messages: ab.messages}, nil
+}
+
+// MakeBatchBuffer implements the SinkClient interface
+func (sc *amqpSinkClient) MakeBatchBuffer(topic string) BatchBuffer {
+	return &amqpBuffer{
+		sc:         sc,
+		routingKey: topic,
+		messages:   make([]amqpMessage, 0, sc.batchCfg.Messages),
+	}
+}
+
+// amqpConn is a connection to an AMQP 0-9-1 broker with a single channel in
+// confirm mode.
+type amqpConn struct {
+	conn *amqp.Connection
+	ch   *amqp.Channel
+	// closed receives the error with which the broker closed the channel, or
+	// the connection it belongs to.
+	closed chan *amqp.Error
+}
+
+func dialAMQP(ctx context.Context, sc *amqpSinkClient) (_ *amqpConn, retErr error) {
+	scheme := changefeedbase.SinkSchemeAMQP
+	var tlsConfig *tls.Config
+	if sc.tlsConfig != nil {
+		scheme = changefeedbase.SinkSchemeAMQPS
+		// The client sets the server name of the configuration it is given.
+		tlsConfig = sc.tlsConfig.Clone()
+	}
+	conn, err := amqp.DialConfig(scheme+"://"+sc.address, amqp.Config{
+		SASL:            []amqp.Authentication{&amqp.PlainAuth{Username: sc.user, Password: sc.password}},
+		Vhost:           sc.vhost,
+		Heartbeat:       amqpHeartbeat,
+		TLSClientConfig: tlsConfig,
+		Properties:      amqp.Table{"product": "CockroachDB"},
+		Locale:          "en_US",
+		Dial: func(network, addr string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: amqpTimeout}
+			netConn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			// The deadline covers the TLS and AMQP handshakes; it is cleared
+			// once the connection is established, after which heartbeats
+			// detect unresponsive brokers.
+			if err := netConn.SetDeadline(timeutil.Now().Add(amqpTimeout)); err != nil {
+				_ = netConn.Close()
+				return nil, err
+			}
+			return netConn, nil
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "connecting to AMQP broker %s", sc.address)
+	}
+	c := &amqpConn{conn: conn}
+	defer func() {
+		if retErr != nil {
+			_ = c.Close()
+		}
+	}()
+	if c.ch, err = conn.Channel(); err != nil {
+		return nil, errors.Wrap(err, "opening AMQP channel")
+	}
+	c.closed = c.ch.NotifyClose(make(chan *amqp.Error, 1))
+	if err := c.ch.Confirm(false /* noWait */); err != nil {
+		return nil, errors.Wrap(err, "enabling AMQP publisher confirms")
+	}
+	return c, nil
+}
+
+// publish publishes every message in the payload and waits for the broker to
+// confirm each of them.
+func (c *amqpConn) publish(ctx context.Context, sc *amqpSinkClient, p *amqpPayload) error {
+	ctx, cancel := context.WithTimeout(ctx, amqpTimeout)
+	defer cancel()
+
+	confirms := make([]*amqp.DeferredConfirmation, 0, len(p.messages))
+	for _, m := range p.messages {
+		msg := amqp.Publishing{
+			ContentType:  sc.contentType,
+			DeliveryMode: amqp.Persistent,
+			Body:         m.value,
+		}
+		if m.key != nil {
+			msg.Headers = amqp.Table{amqpKeyHeader: string(m.key)}
+		}
+		confirm, err := c.ch.PublishWithDeferredConfirmWithContext(
+			ctx, sc.exchange, p.routingKey, false /* mandatory */, false /* immediate */, msg)
+		if err != nil {
+			return c.closeErr(errors.Wrap(err, "publishing to AMQP broker"))
+		}
+		confirms = append(confirms, confirm)
+	}
+
+	for _, confirm := range confirms {
+		if confirm.Wait() {
+			continue
+		}
+		// A confirmation is negative if the broker rejected the message, if
+		// the channel was closed, or if the context is done.
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "waiting for AMQP broker to confirm messages")
+		}
+		return c.closeErr(errors.Errorf(
+			"AMQP broker did not accept message published to %q", p.routingKey))
+	}
+	return nil
+}
+
+// closeErr returns the error with which the broker closed the channel, if it
+// did, and err otherwise.
+func (c *amqpConn) closeErr(err error) error {
+	if !c.ch.IsClosed() {
+		return err
+	}
+	// Once the channel is marked as closed, the error it was closed with, if
+	// any, is delivered before closed is closed.
+	if amqpErr, ok := <-c.closed; ok && amqpErr != nil {
+		return errors.Wrap(amqpErr, "AMQP broker closed the channel")
+	}
+	return err
+}
+
+// Close closes the connection, along with its channel.
+func (c *amqpConn) Close() error {
+	return c.conn.Close()
+}
+
+func makeAMQPSink(
+	ctx context.Context,
+	u sinkURL,
+	encodingOpts changefeedbase.EncodingOptions,
+	jsonConfig changefeedbase.SinkSpecificJSONConfig,
+	targets changefeedbase.Targets,
+	parallelism int,
+	pacerFactory func() *admission.Pacer,
+	source timeutil.TimeSource,
+	mb metricsRecorderBuilder,
+) (Sink, error) {
+	batchCfg, retryOpts, err := getSinkConfigFromJson(jsonConfig, sinkJSONConfig{
+		Flush: sinkBatchConfig{
+			Frequency: jsonDuration(10 * time.Millisecond),
+			Messages:  100,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	topicPrefix := u.consumeParam(changefeedbase.SinkParamTopicPrefix)
+	topicName := u.consumeParam(changefeedbase.SinkParamTopicName)
+	topicNamer, err := MakeTopicNamer(targets, WithPrefix(topicPrefix), WithSingleName(topicName))
+	if err != nil {
+		return nil, err
+	}
+
+	sinkClient, err := makeAMQPSinkClient(&u, encodingOpts, batchCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if unknownParams := u.remainingQueryParams(); len(unknownParams) > 0 {
+		return nil, errors.Errorf(
+			`unknown AMQP sink query parameters: %s`, strings.Join(unknownParams, ", "))
+	}
+
+	return makeBatchingSink(
+		ctx,
+		sinkTypeAMQP,
+		sinkClient,
+		time.Duration(batchCfg.Frequency),
+		retryOpts,
+		parallelism,
+		topicNamer,
+		pacerFactory,
+		source,
+		mb(requiresResourceAccounting),
+	), nil
+}
@@ -0,0 +1,489 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+type amqpTestMessage struct {
+	exchange    string
+	routingKey  string
+	contentType string
+	key         string
+	body        string
+}
+
+// AMQP 0-9-1 frame types and methods used by fakeAMQPBroker.
+const (
+	amqpTestFrameMethod    = 1
+	amqpTestFrameHeader    = 2
+	amqpTestFrameBody      = 3
+	amqpTestFrameHeartbeat = 8
+	amqpTestFrameEnd       = 0xCE
+)
+
+type amqpTestMethod struct {
+	class, method uint16
+}
+
+var (
+	amqpTestConnectionStart   = amqpTestMethod{10, 10}
+	amqpTestConnectionStartOk = amqpTestMethod{10, 11}
+	amqpTestConnectionTune    = amqpTestMethod{10, 30}
+	amqpTestConnectionTuneOk  = amqpTestMethod{10, 31}
+	amqpTestConnectionOpen    = amqpTestMethod{10, 40}
+	amqpTestConnectionOpenOk  = amqpTestMethod{10, 41}
+	amqpTestConnectionClose   = amqpTestMethod{10, 50}
+	amqpTestConnectionCloseOk = amqpTestMethod{10, 51}
+	amqpTestChannelOpen       = amqpTestMethod{20, 10}
+	amqpTestChannelOpenOk     = amqpTestMethod{20, 11}
+	amqpTestChannelClose      = amqpTestMethod{20, 40}
+	amqpTestChannelCloseOk    = amqpTestMethod{20, 41}
+	amqpTestBasicPublish      = amqpTestMethod{60, 40}
+	amqpTestBasicAck          = amqpTestMethod{60, 80}
+	amqpTestConfirmSelect     = amqpTestMethod{85, 10}
+	amqpTestConfirmSelectOk   = amqpTestMethod{85, 11}
+)
+
+// amqpTestWriter encodes the arguments of an AMQP method.
+type amqpTestWriter struct {
+	bytes.Buffer
+}
+
+func (w *amqpTestWriter) uint16(v uint16) { _ = binary.Write(w, binary.BigEndian, v) }
+func (w *amqpTestWriter) uint32(v uint32) { _ = binary.Write(w, binary.BigEndian, v) }
+func (w *amqpTestWriter) uint64(v uint64) { _ = binary.Write(w, binary.BigEndian, v) }
+
+func (w *amqpTestWriter) shortstr(s string) {
+	w.WriteByte(uint8(len(s)))
+	w.WriteString(s)
+}
+
+func (w *amqpTestWriter) longstr(s string) {
+	w.uint32(uint32(len(s)))
+	w.WriteString(s)
+}
+
+// amqpTestReader decodes the arguments of an AMQP method.
+type amqpTestReader struct {
+	buf []byte
+	err error
+}
+
+func (r *amqpTestReader) next(n int) []byte {
+	if r.err != nil || n > len(r.buf) {
+		r.err = errors.New("malformed AMQP frame")
+		return make([]byte, n)
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *amqpTestReader) uint8() uint8   { return r.next(1)[0] }
+func (r *amqpTestReader) uint16() uint16 { return binary.BigEndian.Uint16(r.next(2)) }
+func (r *amqpTestReader) uint32() uint32 { return binary.BigEndian.Uint32(r.next(4)) }
+func (r *amqpTestReader) uint64() uint64 { return binary.BigEndian.Uint64(r.next(8)) }
+
+func (r *amqpTestReader) shortstr() string { return string(r.next(int(r.uint8()))) }
+func (r *amqpTestReader) longstr() string  { return string(r.next(int(r.uint32()))) }
+
+// table returns the string fields of a field table.
+func (r *amqpTestReader) table() map[string]string {
+	fields := &amqpTestReader{buf: r.next(int(r.uint32()))}
+	strs := make(map[string]string)
+	for len(fields.buf) > 0 && fields.err == nil {
+		name, typ := fields.shortstr(), fields.uint8()
+		if typ != 'S' {
+			// Other field types are not used by the sink.
+			break
+		}
+		strs[name] = fields.longstr()
+	}
+	return strs
+}
+
+// fakeAMQPConn is the broker side of a connection to fakeAMQPBroker.
+type fakeAMQPConn struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// readFrame reads the next frame, skipping heartbeats.
+func (c *fakeAMQPConn) readFrame() (frameType uint8, channel uint16, payload []byte, _ error) {
+	for {
+		var header [7]byte
+		if _, err := io.ReadFull(c.r, header[:]); err != nil {
+			return 0, 0, nil, err
+		}
+		payload = make([]byte, binary.BigEndian.Uint32(header[3:])+1)
+		if _, err := io.ReadFull(c.r, payload); err != nil {
+			return 0, 0, nil, err
+		}
+		if payload[len(payload)-1] != amqpTestFrameEnd {
+			return 0, 0, nil, errors.New("malformed AMQP frame")
+		}
+		if header[0] != amqpTestFrameHeartbeat {
+			return header[0], binary.BigEndian.Uint16(header[1:]), payload[:len(payload)-1], nil
+		}
+	}
+}
+
+func (c *fakeAMQPConn) readMethod() (uint16, amqpTestMethod, *amqpTestReader, error) {
+	frameType, channel, payload, err := c.readFrame()
+	if err != nil {
+		return 0, amqpTestMethod{}, nil, err
+	}
+	if frameType != amqpTestFrameMethod {
+		return 0, amqpTestMethod{}, nil, errors.Errorf("unexpected frame type %d", frameType)
+	}
+	r := &amqpTestReader{buf: payload}
+	m := amqpTestMethod{class: r.uint16(), method: r.uint16()}
+	return channel, m, r, r.err
+}
+
+func (c *fakeAMQPConn) expect(expected amqpTestMethod) (uint16, *amqpTestReader, error) {
+	channel, method, args, err := c.readMethod()
+	if err != nil {
+		return 0, nil, err
+	}
+	if method != expected {
+		return 0, nil, errors.Errorf("unexpected method %v", method)
+	}
+	return channel, args, nil
+}
+
+func (c *fakeAMQPConn) writeMethod(channel uint16, method amqpTestMethod, args []byte) error {
+	var payload amqpTestWriter
+	payload.uint16(method.class)
+	payload.uint16(method.method)
+	payload.Write(args)
+	var frame amqpTestWriter
+	frame.WriteByte(amqpTestFrameMethod)
+	frame.uint16(channel)
+	frame.uint32(uint32(payload.Len()))
+	frame.Write(payload.Bytes())
+	frame.WriteByte(amqpTestFrameEnd)
+	_, err := c.w.Write(frame.Bytes())
+	return err
+}
+
+// fakeAMQPBroker implements enough of AMQP 0-9-1 to accept messages published
+// on a channel in confirm mode. It uses a small frame size so that message
+// bodies are split across frames.
+type fakeAMQPBroker struct {
+	ln        net.Listener
+	exchanges map[string]bool
+	mu        struct {
+		syncutil.Mutex
+		vhost     string
+		heartbeat uint16
+		published []amqpTestMessage
+	}
+}
+
+const fakeAMQPBrokerFrameMax = 256
+
+func startFakeAMQPBroker(t *testing.T, exchanges ...string) *fakeAMQPBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := &fakeAMQPBroker{ln: ln, exchanges: map[string]bool{"": true}}
+	for _, exchange := range exchanges {
+		b.exchanges[exchange] = true
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = b.serve(&fakeAMQPConn{r: bufio.NewReader(conn), w: conn})
+			}()
+		}
+	}()
+	return b
+}
+
+func (b *fakeAMQPBroker) serve(c *fakeAMQPConn) error {
+	var protocolHeader [8]byte
+	if _, err := io.ReadFull(c.r, protocolHeader[:]); err != nil {
+		return err
+	}
+
+	var start amqpTestWriter
+	start.WriteByte(0)
+	start.WriteByte(9)
+	start.uint32(0) // server properties
+	start.longstr("PLAIN")
+	start.longstr("en_US")
+	if err := c.writeMethod(0, amqpTestConnectionStart, start.Bytes()); err != nil {
+		return err
+	}
+	_, args, err := c.expect(amqpTestConnectionStartOk)
+	if err != nil {
+		return err
+	}
+	args.table()
+	args.shortstr()
+	if auth := args.longstr(); auth != "\x00user\x00pass" {
+		var closeArgs amqpTestWriter
+		closeArgs.uint16(403)
+		closeArgs.shortstr("ACCESS_REFUSED - Login was refused")
+		closeArgs.uint16(0)
+		closeArgs.uint16(0)
+		return c.writeMethod(0, amqpTestConnectionClose, closeArgs.Bytes())
+	}
+
+	var tune amqpTestWriter
+	tune.uint16(0)
+	tune.uint32(fakeAMQPBrokerFrameMax)
+	tune.uint16(60)
+	if err := c.writeMethod(0, amqpTestConnectionTune, tune.Bytes()); err != nil {
+		return err
+	}
+	if _, args, err = c.expect(amqpTestConnectionTuneOk); err != nil {
+		return err
+	}
+	args.uint16()
+	args.uint32()
+	heartbeat := args.uint16()
+	if _, args, err = c.expect(amqpTestConnectionOpen); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.mu.heartbeat = heartbeat
+	b.mu.vhost = args.shortstr()
+	b.mu.Unlock()
+
+	var openOk amqpTestWriter
+	openOk.shortstr("")
+	if err := c.writeMethod(0, amqpTestConnectionOpenOk, openOk.Bytes()); err != nil {
+		return err
+	}
+	channel, _, err := c.expect(amqpTestChannelOpen)
+	if err != nil {
+		return err
+	}
+	var channelOpenOk amqpTestWriter
+	channelOpenOk.longstr("")
+	if err := c.writeMethod(channel, amqpTestChannelOpenOk, channelOpenOk.Bytes()); err != nil {
+		return err
+	}
+	if _, _, err := c.expect(amqpTestConfirmSelect); err != nil {
+		return err
+	}
+	if err := c.writeMethod(channel, amqpTestConfirmSelectOk, nil); err != nil {
+		return err
+	}
+
+	// closing is set once the broker closed the channel, after which frames
+	// are discarded until the client confirms that the channel is closed.
+	closing := false
+	for tag := uint64(1); ; {
+		frameType, _, payload, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+		if frameType != amqpTestFrameMethod {
+			if closing {
+				continue
+			}
+			return errors.Errorf("unexpected frame type %d", frameType)
+		}
+		args := &amqpTestReader{buf: payload}
+		method := amqpTestMethod{class: args.uint16(), method: args.uint16()}
+		switch {
+		case method == amqpTestConnectionClose:
+			return c.writeMethod(0, amqpTestConnectionCloseOk, nil)
+		case method == amqpTestChannelCloseOk:
+			closing = false
+			continue
+		case closing:
+			continue
+		case method != amqpTestBasicPublish:
+			return errors.Errorf("unexpected method %v", method)
+		}
+		args.uint16()
+		m := amqpTestMessage{exchange: args.shortstr(), routingKey: args.shortstr()}
+
+		_, _, header, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+		props := &amqpTestReader{buf: header}
+		props.uint16()
+		props.uint16()
+		size := props.uint64()
+		flags := props.uint16()
+		if flags != 1<<15|1<<13|1<<12 && flags != 1<<15|1<<12 {
+			return errors.Errorf("unexpected properties %b", flags)
+		}
+		m.contentType = props.shortstr()
+		if flags&(1<<13) != 0 {
+			m.key = props.table()[amqpKeyHeader]
+		}
+		if props.uint8() != amqp.Persistent || props.err != nil {
+			return errors.New("message is not persistent")
+		}
+
+		var body strings.Builder
+		for uint64(body.Len()) < size {
+			frameType, _, frame, err := c.readFrame()
+			if err != nil {
+				return err
+			}
+			if frameType != amqpTestFrameBody || len(frame) > fakeAMQPBrokerFrameMax-8 {
+				return errors.New("unexpected body frame")
+			}
+			body.WriteString(string(frame))
+		}
+		m.body = body.String()
+
+		if !b.exchanges[m.exchange] {
+			var closeArgs amqpTestWriter
+			closeArgs.uint16(404)
+			closeArgs.shortstr(fmt.Sprintf("NOT_FOUND - no exchange '%s'", m.exchange))
+			closeArgs.uint16(amqpTestBasicPublish.class)
+			closeArgs.uint16(amqpTestBasicPublish.method)
+			if err := c.writeMethod(channel, amqpTestChannelClose, closeArgs.Bytes()); err != nil {
+				return err
+			}
+			closing = true
+			continue
+		}
+		b.mu.Lock()
+		b.mu.published = append(b.mu.published, m)
+		b.mu.Unlock()
+
+		// Confirm every other message, along with all the messages before it.
+		if tag%2 == 0 || m.routingKey == "resolved" {
+			var ack amqpTestWriter
+			ack.uint64(tag)
+			ack.WriteByte(1)
+			if err := c.writeMethod(channel, amqpTestBasicAck, ack.Bytes()); err != nil {
+				return err
+			}
+		}
+		tag++
+	}
+}
+
+func (b *fakeAMQPBroker) published() []amqpTestMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]amqpTestMessage(nil), b.mu.published...)
+}
+
+func TestAMQPSinkClient(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	broker := startFakeAMQPBroker(t, "changes")
+	defer broker.ln.Close()
+
+	makeClient := func(t *testing.T, uri string) *amqpSinkClient {
+		u, err := url.Parse(fmt.Sprintf(uri, broker.ln.Addr()))
+		require.NoError(t, err)
+		encodingOpts := changefeedbase.EncodingOptions{Format: changefeedbase.OptFormatJSON}
+		sc, err := makeAMQPSinkClient(&sinkURL{URL: u}, encodingOpts, sinkBatchConfig{Messages: 10})
+		require.NoError(t, err)
+		return sc
+	}
+	publish := func(sc *amqpSinkClient, topic string, values ...string) error {
+		buf := sc.MakeBatchBuffer(topic)
+		for i, v := range values {
+			buf.Append([]byte(fmt.Sprintf("[%d]", i)), []byte(v))
+		}
+		payload, err := buf.Close()
+		require.NoError(t, err)
+		return sc.Flush(ctx, payload)
+	}
+
+	sc := makeClient(t, "amqp://user:pass@%s/my%%2Fvhost?exchange=changes")
+	defer func() { require.NoError(t, sc.Close()) }()
+
+	large := strings.Repeat("x", 3*fakeAMQPBrokerFrameMax)
+	require.NoError(t, publish(sc, "foo", `{"a":1}`, large))
+	resolved, err := sc.MakeResolvedPayload([]byte(`{"resolved":"1.0"}`), "resolved")
+	require.NoError(t, err)
+	require.NoError(t, sc.Flush(ctx, resolved))
+
+	require.Equal(t, []amqpTestMessage{
+		{exchange: "changes", routingKey: "foo", contentType: "application/json", key: "[0]", body: `{"a":1}`},
+		{exchange: "changes", routingKey: "foo", contentType: "application/json", key: "[1]", body: large},
+		{exchange: "changes", routingKey: "resolved", contentType: "application/json", body: `{"resolved":"1.0"}`},
+	}, broker.published())
+	broker.mu.Lock()
+	require.Equal(t, "my/vhost", broker.mu.vhost)
+	// Heartbeats are enabled so that unreachable brokers are detected.
+	require.Equal(t, uint16(amqpHeartbeat/time.Second), broker.mu.heartbeat)
+	broker.mu.Unlock()
+
+	// Publishing to an exchange which does not exist closes the channel.
+	missing := makeClient(t, "amqp://user:pass@%s?exchange=missing")
+	defer func() { require.NoError(t, missing.Close()) }()
+	require.Regexp(t, `AMQP broker closed the channel: Exception \(404\) Reason: "NOT_FOUND - no exchange 'missing'"`,
+		publish(missing, "foo", `{}`, `{}`))
+
+	// The broker closes the connection if authentication fails.
+	unauthenticated := makeClient(t, "amqp://user:wrong@%s")
+	defer func() { require.NoError(t, unauthenticated.Close()) }()
+	require.ErrorIs(t, publish(unauthenticated, "foo", `{}`), amqp.ErrCredentials)
+}
+
+func TestAMQPSinkClientURI(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	encodingOpts := changefeedbase.EncodingOptions{Format: changefeedbase.OptFormatCSV}
+	makeClient := func(uri string) (*amqpSinkClient, error) {
+		u, err := url.Parse(uri)
+		require.NoError(t, err)
+		return makeAMQPSinkClient(&sinkURL{URL: u}, encodingOpts, sinkBatchConfig{})
+	}
+
+	sc, err := makeClient("amqp://localhost")
+	require.NoError(t, err)
+	require.Equal(t, "localhost:5672", sc.address)
+	require.Equal(t, "/", sc.vhost)
+	require.Equal(t, "guest", sc.user)
+	require.Equal(t, "text/csv", sc.contentType)
+	require.Nil(t, sc.tlsConfig)
+
+	sc, err = makeClient("amqps://localhost/prod")
+	require.NoError(t, err)
+	require.Equal(t, "localhost:5671", sc.address)
+	require.Equal(t, "prod", sc.vhost)
+	require.NotNil(t, sc.tlsConfig)
+
+	_, err = makeClient("amqp://localhost?exchange=" + strings.Repeat("x", 256))
+	require.Regexp(t, "exchange must be at most 255 bytes", err)
+}
@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync/atomic"
@@ -47,7 +48,8 @@ func isCloudStorageSink(u *url.URL) bool {
 	switch u.Scheme {
 	case changefeedbase.SinkSchemeCloudStorageS3, changefeedbase.SinkSchemeCloudStorageGCS,
 		changefeedbase.SinkSchemeCloudStorageNodelocal, changefeedbase.SinkSchemeCloudStorageHTTP,
-		changefeedbase.SinkSchemeCloudStorageHTTPS, changefeedbase.SinkSchemeCloudStorageAzure:
+		changefeedbase.SinkSchemeCloudStorageHTTPS, changefeedbase.SinkSchemeCloudStorageAzure,
+		changefeedbase.SinkSchemeCloudStorageSFTP:
 		return true
 	// During the deprecation period, we need to keep parsing these as cloudstorage for backwards
 	// compatibility. Afterwards we'll either remove them or move them to webhook.
@@ -78,6 +80,8 @@ type cloudStorageSinkFile struct {
 	alloc        kvevent.Alloc
 	oldestMVCC   hlc.Timestamp
 	parquetCodec *parquetWriter
+	// iceberg is set when the file is a data file of an Iceberg table.
+	iceberg *icebergPendingFile
 }
 
 var _ io.Writer = &cloudStorageSinkFile{}
@@ -300,6 +304,9 @@ type cloudStorageSink struct {
 	compression compressionAlgo
 
 	es cloud.ExternalStorage
+	// iceberg is set if the output is written as Iceberg tables; see
+	// icebergCatalog.
+	iceberg *icebergCatalog
 
 	// These are fields to track information needed to output files based on the naming
 	// convention described above. See comment on cloudStorageSink above for more details.
@@ -424,6 +431,19 @@ func makeCloudStorageSink(
 		s.partitionFormat = dateFormat
 	}
 
+	tableFormat := u.consumeParam(changefeedbase.SinkParamTableFormat)
+	switch tableFormat {
+	case ``:
+	case changefeedbase.SinkTableFormatIceberg:
+		if encodingOpts.Format != changefeedbase.OptFormatParquet {
+			return nil, errors.Errorf(`%s=%s requires %s=%s`,
+				changefeedbase.SinkParamTableFormat, tableFormat,
+				changefeedbase.OptFormat, changefeedbase.OptFormatParquet)
+		}
+	default:
+		return nil, errors.Errorf("invalid %s of %s", changefeedbase.SinkParamTableFormat, tableFormat)
+	}
+
 	if s.timestampOracle != nil {
 		s.setDataFileTimestamp()
 	}
@@ -474,6 +494,9 @@ func makeCloudStorageSink(
 	if s.es, err = makeExternalStorageFromURI(ctx, u.String(), user, cloud.WithIOAccountingInterceptor(nil)); err != nil {
 		return nil, err
 	}
+	if tableFormat == changefeedbase.SinkTableFormatIceberg {
+		s.iceberg = makeIcebergCatalog(s.es, u.URL, testingKnobs)
+	}
 	if mb != nil && s.es != nil {
 		s.metrics = mb(s.es.RequiresExternalIOAccounting())
 	} else {
@@ -746,6 +769,10 @@ func (s *cloudStorageSink) flushFile(ctx context.Context, file *cloudStorageSink
 	}
 	s.prevFilename = filename
 	dest := filepath.Join(s.dataFilePartition, filename)
+	if file.iceberg != nil {
+		dest = path.Join(file.topic, icebergDataDir, filename)
+		file.iceberg.Path = dest
+	}
 
 	if !asyncFlushEnabled {
 		return file.flushToStorage(ctx, s.es, dest, s.metrics)
@@ -832,6 +859,13 @@ func (f *cloudStorageSinkFile) flushToStorage(
 	if err := cloud.WriteFile(ctx, es, dest, bytes.NewReader(f.buf.Bytes())); err != nil {
 		return err
 	}
+	if f.iceberg != nil {
+		f.iceberg.RecordCount = int64(f.numMessages)
+		f.iceberg.FileSizeInBytes = int64(compressedBytes)
+		if err := writeIcebergPendingFile(ctx, es, f.iceberg); err != nil {
+			return err
+		}
+	}
 	m.recordEmittedBatch(f.created, f.numMessages, f.oldestMVCC, f.rawSize, compressedBytes)
 
 	return nil
@@ -0,0 +1,454 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/util/admission"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+const (
+	natsDefaultPort = "4222"
+
+	// natsKeyHeader is the message header which carries the encoded key of the
+	// row, since the message body only contains the encoded value.
+	natsKeyHeader = "Crdb-Key"
+
+	// natsTimeout bounds how long dialing the server and waiting for the
+	// acknowledgements of a batch of messages may take.
+	natsTimeout = 30 * time.Second
+
+	// natsPingInterval is the interval at which the server is pinged, so that
+	// connections to servers which became unreachable are detected.
+	natsPingInterval = 10 * time.Second
+)
+
+// natsSinkClient is a SinkClient which publishes messages to NATS JetStream.
+// Every message is published with a reply subject so that the stream which
+// captures it responds with a publish acknowledgement once it has persisted
+// the message; a batch is only considered flushed once every message in it was
+// acknowledged.
+//
+// Each topic is published to the subject of the same name. The URI is of the
+// form nats://[user:password@]host[:port], and a URI with only a user is
+// interpreted as nats://token@host[:port]. Alternatively, the client
+// authenticates with the base64-encoded contents of a credentials file passed
+// in the creds parameter, or of an nkey seed file passed in the nkey_seed
+// parameter.
+type natsSinkClient struct {
+	address   string
+	tlsConfig *tls.Config
+	user      string
+	password  string
+	token     string
+	// userJWT and nkey are set if the client authenticates with a credentials
+	// file or an nkey seed. userJWT is empty for nkey authentication.
+	userJWT  string
+	nkey     nkeys.KeyPair
+	batchCfg sinkBatchConfig
+
+	mu struct {
+		syncutil.Mutex
+		// idle contains connections which are not being used by a Flush.
+		// Connections which encounter an error are closed rather than being
+		// returned here.
+		idle []*natsConn
+	}
+}
+
+var _ SinkClient = (*natsSinkClient)(nil)
+
+type natsMessage struct {
+	key   []byte
+	value []byte
+}
+
+type natsPayload struct {
+	subject  string
+	messages []natsMessage
+}
+
+func makeNATSSinkClient(
+	u *sinkURL, encodingOpts changefeedbase.EncodingOptions, batchCfg sinkBatchConfig,
+) (*natsSinkClient, error) {
+	if u.Scheme != changefeedbase.SinkSchemeNATS {
+		return nil, errors.Errorf("unknown scheme: %s", u.Scheme)
+	}
+
+	switch encodingOpts.Format {
+	case changefeedbase.OptFormatJSON, changefeedbase.OptFormatCSV:
+	default:
+		return nil, errors.Errorf(`this sink is incompatible with %s=%s`,
+			changefeedbase.OptFormat, encodingOpts.Format)
+	}
+
+	if u.Host == "" {
+		return nil, errors.New("missing NATS server address")
+	}
+
+	tlsConfig, err := makeSinkTLSConfig(u, false /* tlsRequired */)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &natsSinkClient{
+		address:   u.Host,
+		tlsConfig: tlsConfig,
+		batchCfg:  batchCfg,
+	}
+	if u.Port() == "" {
+		sc.address = net.JoinHostPort(u.Hostname(), natsDefaultPort)
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			sc.user, sc.password = u.User.Username(), password
+		} else {
+			sc.token = u.User.Username()
+		}
+	}
+
+	var creds, seed []byte
+	if err := u.decodeBase64(changefeedbase.SinkParamCreds, &creds); err != nil {
+		return nil, err
+	}
+	if err := u.decodeBase64(changefeedbase.SinkParamNKeySeed, &seed); err != nil {
+		return nil, err
+	}
+	if creds != nil && seed != nil {
+		return nil, errors.Errorf("only one of %s and %s may be set",
+			changefeedbase.SinkParamCreds, changefeedbase.SinkParamNKeySeed)
+	}
+	if (creds != nil || seed != nil) && u.User != nil {
+		return nil, errors.Errorf("%s and %s may not be combined with a user in the sink URI",
+			changefeedbase.SinkParamCreds, changefeedbase.SinkParamNKeySeed)
+	}
+	if creds != nil {
+		userJWT, err := nkeys.ParseDecoratedJWT(creds)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", changefeedbase.SinkParamCreds)
+		}
+		nkey, err := nkeys.ParseDecoratedNKey(creds)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", changefeedbase.SinkParamCreds)
+		}
+		sc.userJWT, sc.nkey = userJWT, nkey
+	}
+	if seed != nil {
+		nkey, err := nkeys.ParseDecoratedNKey(seed)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", changefeedbase.SinkParamNKeySeed)
+		}
+		sc.nkey = nkey
+	}
+	return sc, nil
+}
+
+// MakeResolvedPayload implements the SinkClient interface
+func (sc *natsSinkClient) MakeResolvedPayload(body []byte, topic string) (SinkPayload, error) {
+	return &natsPayload{
+		subject:  topic,
+		messages: []natsMessage{{value: body}},
+	}, nil
+}
+
+// Flush implements the SinkClient interface
+func (sc *natsSinkClient) Flush(ctx context.Context, payload SinkPayload) error {
+	p := payload.(*natsPayload)
+
+	conn, err := sc.getConn(ctx)
+	if err != nil {
+		return err
+	}
+	if err := conn.publish(ctx, p); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	sc.releaseConn(conn)
+	return nil
+}
+
+func (sc *natsSinkClient) getConn(ctx context.Context) (*natsConn, error) {
+	sc.mu.Lock()
+	if n := len(sc.mu.idle); n > 0 {
+		conn := sc.mu.idle[n-1]
+		sc.mu.idle = sc.mu.idle[:n-1]
+		sc.mu.Unlock()
+		return conn, nil
+	}
+	sc.mu.Unlock()
+	return dialNATS(ctx, sc)
+}
+
+func (sc *natsSinkClient) releaseConn(conn *natsConn) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.mu.idle = append(sc.mu.idle, conn)
+}
+
+// Close implements the SinkClient interface
+func (sc *natsSinkClient) Close() error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	var err error
+	for _, conn := range sc.mu.idle {
+		err = errors.CombineErrors(err, conn.Close())
+	}
+	sc.mu.idle = nil
+	return err
+}
+
+type natsBuffer struct {
+	sc       *natsSinkClient
+	subject  string
+	messages []natsMessage
+	numBytes int
+}
+
+var _ BatchBuffer = (*natsBuffer)(nil)
+
+// Append implements the BatchBuffer interface
+func (nb *natsBuffer) Append(key []byte, value []byte) {
+	nb.messages = append(nb.messages, natsMessage{key: key, value: value})
+	nb.numBytes += len(key) + len(value)
+}
+
+// ShouldFlush implements the BatchBuffer interface
+func (nb *natsBuffer) ShouldFlush() bool {
+	return shouldFlushBatch(nb.numBytes, len(nb.messages), nb.sc.batchCfg)
+}
+
+// Close implements the BatchBuffer interface
+func (nb *natsBuffer) Close() (SinkPayload, error) {
+	return &natsPayload{subject: nb.subject, messages: nb.messages}, nil
+}
+
+// MakeBatchBuffer implements the SinkClient interface
+func (sc *natsSinkClient) MakeBatchBuffer(topic string) BatchBuffer {
+	return &natsBuffer{
+		sc:       sc,
+		subject:  topic,
+		messages: make([]natsMessage, 0, sc.batchCfg.Messages),
+	}
+}
+
+// natsConn is a connection to a NATS server with JetStream enabled.
+type natsConn struct {
+	nc *nats.Conn
+	js nats.JetStreamContext
+	// closed is closed once the connection is closed, e.g. because the server
+	// stopped responding to pings.
+	closed chan struct{}
+}
+
+func dialNATS(ctx context.Context, sc *natsSinkClient) (_ *natsConn, retErr error) {
+	c := &natsConn{closed: make(chan struct{})}
+	opts := []nats.Option{
+		nats.Name("cockroachdb-changefeed"),
+		nats.Timeout(natsTimeout),
+		nats.PingInterval(natsPingInterval),
+		// Connections are re-dialed by the sink when a Flush fails, so the
+		// client must not transparently reconnect and drop acknowledgements.
+		nats.NoReconnect(),
+		nats.ClosedHandler(func(*nats.Conn) { close(c.closed) }),
+		nats.SetCustomDialer(natsDialer{ctx: ctx}),
+	}
+	if sc.tlsConfig != nil {
+		opts = append(opts, nats.Secure(sc.tlsConfig.Clone()))
+	}
+	switch {
+	case sc.userJWT != "":
+		opts = append(opts, nats.UserJWT(
+			func() (string, error) { return sc.userJWT, nil }, sc.nkey.Sign))
+	case sc.nkey != nil:
+		pub, err := sc.nkey.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, nats.Nkey(pub, sc.nkey.Sign))
+	case sc.token != "":
+		opts = append(opts, nats.Token(sc.token))
+	case sc.user != "":
+		opts = append(opts, nats.UserInfo(sc.user, sc.password))
+	}
+
+	nc, err := nats.Connect("nats://"+sc.address, opts...)
+	if err != nil {
+		if errors.Is(err, nats.ErrSecureConnRequired) {
+			return nil, errors.Errorf("NATS server requires TLS, set %s=true",
+				changefeedbase.SinkParamTLSEnabled)
+		}
+		return nil, errors.Wrapf(err, "connecting to NATS server %s", sc.address)
+	}
+	c.nc = nc
+	defer func() {
+		if retErr != nil {
+			_ = c.Close()
+		}
+	}()
+	if !nc.HeadersSupported() {
+		return nil, errors.New("NATS server does not support message headers")
+	}
+	if c.js, err = nc.JetStream(); err != nil {
+		return nil, errors.Wrap(err, "connecting to JetStream")
+	}
+	return c, nil
+}
+
+// natsDialer dials the NATS server, giving up once its context is done.
+type natsDialer struct {
+	ctx context.Context
+}
+
+// Dial implements the nats.CustomDialer interface.
+func (d natsDialer) Dial(network, address string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: natsTimeout}
+	return dialer.DialContext(d.ctx, network, address)
+}
+
+// publish publishes every message in the payload and waits for JetStream to
+// acknowledge each of them.
+func (c *natsConn) publish(ctx context.Context, p *natsPayload) error {
+	// Reject oversized messages before anything in the batch is published.
+	maxPayload := c.nc.MaxPayload()
+	msgs := make([]*nats.Msg, len(p.messages))
+	for i, m := range p.messages {
+		msgs[i] = &nats.Msg{Subject: p.subject, Data: m.value}
+		if m.key != nil {
+			msgs[i].Header = nats.Header{}
+			msgs[i].Header.Set(natsKeyHeader, string(m.key))
+		}
+		if size := natsHeaderSize(m.key) + len(m.value); maxPayload > 0 && int64(size) > maxPayload {
+			return markSinkRejectedMessage(errors.Errorf(
+				"message of %d bytes exceeds the maximum NATS payload size of %d bytes",
+				size, maxPayload))
+		}
+	}
+
+	acks := make([]nats.PubAckFuture, len(msgs))
+	for i, msg := range msgs {
+		ack, err := c.js.PublishMsgAsync(msg)
+		if err != nil {
+			return errors.Wrapf(err, "publishing to subject %s", p.subject)
+		}
+		acks[i] = ack
+	}
+
+	timer := time.NewTimer(natsTimeout)
+	defer timer.Stop()
+	for _, ack := range acks {
+		select {
+		case <-ack.Ok():
+		case err := <-ack.Err():
+			if errors.Is(err, nats.ErrNoResponders) {
+				return errors.Errorf("no JetStream stream is configured for subject %s", p.subject)
+			}
+			return errors.Wrapf(err, "JetStream did not accept message published to subject %s", p.subject)
+		case <-c.closed:
+			return errors.Newf("connection to NATS server closed: %v", c.nc.LastError())
+		case <-timer.C:
+			return errors.Errorf("timed out waiting for JetStream to acknowledge messages published to subject %s",
+				p.subject)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// natsHeaderSize returns the size of the header block of a message with the
+// given key, which counts towards the maximum payload size of the server.
+// Messages without a key are published without headers.
+func natsHeaderSize(key []byte) int {
+	if key == nil {
+		return 0
+	}
+	return len("NATS/1.0\r\n") + len(natsKeyHeader) + len(": ") + len(key) + len("\r\n\r\n")
+}
+
+// Close closes the connection.
+func (c *natsConn) Close() error {
+	c.nc.Close()
+	return nil
+}
+
+// sanitizeNATSSubject replaces characters which may not appear in a NATS
+// subject. Dots are preserved since they separate the tokens of a subject.
+func sanitizeNATSSubject(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n', '*', '>':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func makeNATSSink(
+	ctx context.Context,
+	u sinkURL,
+	encodingOpts changefeedbase.EncodingOptions,
+	jsonConfig changefeedbase.SinkSpecificJSONConfig,
+	targets changefeedbase.Targets,
+	parallelism int,
+	pacerFactory func() *admission.Pacer,
+	source timeutil.TimeSource,
+	mb metricsRecorderBuilder,
+) (Sink, error) {
+	batchCfg, retryOpts, err := getSinkConfigFromJson(jsonConfig, sinkJSONConfig{
+		Flush: sinkBatchConfig{
+			Frequency: jsonDuration(10 * time.Millisecond),
+			Messages:  100,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	topicPrefix := u.consumeParam(changefeedbase.SinkParamTopicPrefix)
+	topicName := u.consumeParam(changefeedbase.SinkParamTopicName)
+	topicNamer, err := MakeTopicNamer(targets,
+		WithPrefix(topicPrefix), WithSingleName(topicName), WithSanitizeFn(sanitizeNATSSubject))
+	if err != nil {
+		return nil, err
+	}
+
+	sinkClient, err := makeNATSSinkClient(&u, encodingOpts, batchCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if unknownParams := u.remainingQueryParams(); len(unknownParams) > 0 {
+		return nil, errors.Errorf(
+			`unknown NATS sink query parameters: %s`, strings.Join(unknownParams, ", "))
+	}
+
+	return makeBatchingSink(
+		ctx,
+		sinkTypeNATS,
+		sinkClient,
+		time.Duration(batchCfg.Frequency),
+		retryOpts,
+		parallelism,
+		topicNamer,
+		pacerFactory,
+		source,
+		mb(requiresResourceAccounting),
+	), nil
+}
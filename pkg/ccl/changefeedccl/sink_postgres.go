@@ -0,0 +1,902 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"context"
+	gosql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/cdcevent"
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/kvevent"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+	"github.com/lib/pq"
+)
+
+const (
+	// postgresSinkDefaultSchema is the downstream schema that changes are
+	// applied to, unless overridden with the schema sink parameter.
+	postgresSinkDefaultSchema = `public`
+
+	// postgresSinkProgressTable records the resolved timestamp of each
+	// changefeed writing into the downstream database.
+	postgresSinkProgressTable      = `crdb_changefeed_progress`
+	postgresSinkCreateProgressStmt = `CREATE TABLE IF NOT EXISTS %s (
+		job_id INT8 PRIMARY KEY,
+		resolved TEXT NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`
+	postgresSinkSelectProgressStmt = `SELECT resolved FROM %s WHERE job_id = $1 FOR UPDATE`
+	postgresSinkUpdateProgressStmt = `INSERT INTO %s (job_id, resolved) VALUES ($1, $2)
+		ON CONFLICT (job_id) DO UPDATE SET resolved = excluded.resolved, updated_at = now()`
+
+	// postgresSinkStagingTable holds the changes emitted by each changefeed
+	// writing into the downstream database until its resolved timestamp covers
+	// them. row_key identifies the row (and column family) that a change
+	// applies to.
+	postgresSinkStagingTable      = `crdb_changefeed_staging`
+	postgresSinkCreateStagingStmt = `CREATE TABLE IF NOT EXISTS %s (
+		job_id INT8 NOT NULL,
+		mvcc_wall INT8 NOT NULL,
+		mvcc_logical INT4 NOT NULL,
+		row_key BYTEA NOT NULL,
+		table_name TEXT NOT NULL,
+		version INT8 NOT NULL,
+		deleted BOOL NOT NULL,
+		num_key_cols INT4 NOT NULL,
+		cols TEXT[] NOT NULL,
+		vals TEXT[] NOT NULL,
+		PRIMARY KEY (job_id, mvcc_wall, mvcc_logical, row_key)
+	)`
+	postgresSinkStageStmt = `INSERT INTO %s
+		(job_id, mvcc_wall, mvcc_logical, row_key, table_name, version, deleted, num_key_cols, cols, vals)
+		VALUES %s ON CONFLICT (job_id, mvcc_wall, mvcc_logical, row_key) DO NOTHING`
+	postgresSinkStagingColumns    = 10
+	postgresSinkSelectStagedQuery = `SELECT
+		mvcc_wall, mvcc_logical, row_key, table_name, version, deleted, num_key_cols, cols, vals
+		FROM %s
+		WHERE job_id = $1 AND (mvcc_wall, mvcc_logical, row_key) > ($2::INT8, $3::INT4, $4::BYTEA)
+			AND (mvcc_wall, mvcc_logical) <= ($5::INT8, $6::INT4)
+		ORDER BY mvcc_wall, mvcc_logical, row_key
+		LIMIT $7`
+	postgresSinkDeleteStagedStmt = `DELETE FROM %s
+		WHERE job_id = $1 AND (mvcc_wall, mvcc_logical) <= ($2::INT8, $3::INT4)`
+
+	// postgresSinkMVCCColumn is the name of the optional DECIMAL column of a
+	// downstream table in which the sink records the MVCC timestamp of the
+	// change which last wrote each row.
+	postgresSinkMVCCColumn = `mvcc_ts`
+	// postgresSinkTargetAlias is the alias of the downstream table in the
+	// statements which apply changes to it.
+	postgresSinkTargetAlias = `existing`
+
+	postgresSinkPrimaryKeyQuery = `SELECT kcu.column_name
+		FROM information_schema.table_constraints AS tc
+		JOIN information_schema.key_column_usage AS kcu
+			ON tc.constraint_schema = kcu.constraint_schema
+			AND tc.constraint_name = kcu.constraint_name
+			AND tc.table_name = kcu.table_name
+		WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = $1 AND tc.table_name = $2
+		ORDER BY kcu.ordinal_position`
+	postgresSinkColumnsQuery = `SELECT column_name FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2`
+
+	// postgresSinkDefaultBatchRows is the default number of rows written by
+	// a single statement.
+	postgresSinkDefaultBatchRows = 100
+	// postgresSinkApplyPageRows is the number of staged changes which are read
+	// and applied at a time.
+	postgresSinkApplyPageRows = 10000
+	// postgresMaxPlaceholders is the number of placeholders a single
+	// statement may use in the PostgreSQL wire protocol.
+	postgresMaxPlaceholders = 65535
+)
+
+// postgresSink applies the changes emitted by a changefeed to tables of the
+// same name in a downstream database which speaks the PostgreSQL wire
+// protocol, such as PostgreSQL or another CockroachDB cluster. The downstream
+// tables must already exist and their primary keys must consist of columns
+// with the same names as the primary keys of the watched tables.
+//
+// Rows are passed to the sink unencoded (see SinkWithEncoder); datums are sent
+// in their text representation, leaving the conversion to the downstream
+// column types to the downstream database. Changes are buffered until Flush,
+// which the changeAggregator calls before it reports progress, and every
+// flush writes them to crdb_changefeed_staging. Changes are only applied to
+// the downstream tables by EmitResolvedTimestamp, which is only called by the
+// changeFrontier: all staged changes up to the resolved timestamp are applied
+// in one downstream transaction, which also removes them from the staging
+// table and records the resolved timestamp in crdb_changefeed_progress. The
+// downstream tables therefore only ever reflect the state of the watched
+// tables as of a resolved timestamp. Note that a resolved timestamp which
+// covers many changes, such as the end of an initial scan, is applied in one
+// large transaction.
+//
+// Inserts and updates are applied as INSERT ... ON CONFLICT DO UPDATE
+// statements and deletions as DELETE statements. Only the most recent change
+// to each row is applied. Changes at or below the recorded resolved timestamp,
+// which are replayed when the changefeed restarts from an earlier checkpoint,
+// are not applied again. If a downstream table has an mvcc_ts column, the
+// sink records the MVCC timestamp of each change in it and does not apply
+// changes which are older than the row, e.g. because the row was written by
+// another changefeed.
+//
+// Columns which do not exist downstream are not written, so that columns may
+// be added to the downstream tables after they have been added upstream. The
+// sink reloads the schema of a downstream table whenever the version of the
+// watched table changes.
+type postgresSink struct {
+	uri       string
+	schema    string
+	jobID     jobspb.JobID
+	batchRows int
+	retryOpts retry.Options
+	metrics   metricsRecorder
+
+	db *gosql.DB
+	// progressTable and stagingTable are the qualified names of the tables
+	// maintained by the sink, which are created on first use.
+	progressTable, stagingTable string
+	tablesCreated               bool
+
+	// tables caches the schema of the downstream tables, by table name.
+	tables map[string]*postgresTable
+
+	// buffered are the changes buffered since the last flush.
+	buffered   postgresChanges
+	alloc      kvevent.Alloc
+	bufferTime time.Time
+	oldestMVCC hlc.Timestamp
+	numBytes   int
+}
+
+var _ SinkWithEncoder = (*postgresSink)(nil)
+
+// postgresTable is the schema of a downstream table.
+type postgresTable struct {
+	// version is the version of the watched table for which the schema was
+	// loaded.
+	version descpb.DescriptorVersion
+	name    string
+	keyCols []string
+	cols    map[string]struct{}
+	// hasMVCCCol is set if the table has a postgresSinkMVCCColumn column.
+	hasMVCCCol bool
+}
+
+// postgresChange is a change to a row.
+type postgresChange struct {
+	// key identifies the row, and the column family of the row, which the
+	// change applies to.
+	key     string
+	mvcc    hlc.Timestamp
+	table   string
+	version descpb.DescriptorVersion
+	deleted bool
+	// cols are the names of the columns in vals; the primary key columns come
+	// first.
+	cols       []string
+	numKeyCols int
+	vals       []gosql.NullString
+	superseded bool
+}
+
+// postgresChanges is a list of changes in which only the most recent change
+// to each row is live.
+type postgresChanges struct {
+	changes []postgresChange
+	// byKey maps each row to the index of its most recent change.
+	byKey map[string]int
+}
+
+// add adds a change to the list, superseding any older change to the same row.
+func (cs *postgresChanges) add(c postgresChange) {
+	if cs.byKey == nil {
+		cs.byKey = make(map[string]int)
+	}
+	if prev, ok := cs.byKey[c.key]; ok {
+		if c.mvcc.Less(cs.changes[prev].mvcc) {
+			return
+		}
+		cs.changes[prev].superseded = true
+	}
+	cs.byKey[c.key] = len(cs.changes)
+	cs.changes = append(cs.changes, c)
+}
+
+// live returns the changes which are not superseded.
+func (cs *postgresChanges) live() []*postgresChange {
+	live := make([]*postgresChange, 0, len(cs.byKey))
+	for i := range cs.changes {
+		if !cs.changes[i].superseded {
+			live = append(live, &cs.changes[i])
+		}
+	}
+	return live
+}
+
+func (cs *postgresChanges) reset() {
+	cs.changes = cs.changes[:0]
+	cs.byKey = nil
+}
+
+func makePostgresSink(
+	u sinkURL,
+	jsonConfig changefeedbase.SinkSpecificJSONConfig,
+	encodingOpts changefeedbase.EncodingOptions,
+	jobID jobspb.JobID,
+	mb metricsRecorderBuilder,
+) (Sink, error) {
+	if u.Path == `` || u.Path == `/` {
+		return nil, errors.Errorf(`must specify database`)
+	}
+	switch encodingOpts.Format {
+	case changefeedbase.OptFormatJSON:
+	default:
+		return nil, errors.Errorf(`this sink is incompatible with %s=%s`,
+			changefeedbase.OptFormat, encodingOpts.Format)
+	}
+
+	batchCfg, retryOpts, err := getSinkConfigFromJson(jsonConfig, sinkJSONConfig{})
+	if err != nil {
+		return nil, err
+	}
+	if batchCfg.Bytes != 0 || batchCfg.Frequency != 0 {
+		return nil, errors.Errorf(`only Flush.Messages may be configured for this sink`)
+	}
+	batchRows := batchCfg.Messages
+	if batchRows == 0 {
+		batchRows = postgresSinkDefaultBatchRows
+	}
+
+	schema := u.consumeParam(changefeedbase.SinkParamSchema)
+	if schema == `` {
+		schema = postgresSinkDefaultSchema
+	}
+
+	return &postgresSink{
+		uri:           u.String(),
+		schema:        schema,
+		jobID:         jobID,
+		batchRows:     batchRows,
+		retryOpts:     retryOpts,
+		metrics:       mb(noResourceAccounting),
+		progressTable: tree.NameString(schema) + "." + tree.NameString(postgresSinkProgressTable),
+		stagingTable:  tree.NameString(schema) + "." + tree.NameString(postgresSinkStagingTable),
+		tables:        make(map[string]*postgresTable),
+	}, nil
+}
+
+func (s *postgresSink) getConcreteType() sinkType {
+	return sinkTypePostgres
+}
+
+// Dial implements the Sink interface.
+func (s *postgresSink) Dial() error {
+	db, err := gosql.Open(`postgres`, s.uri)
+	if err != nil {
+		return err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+// EmitRow does not do anything. It must not be called; rows are passed to
+// EncodeAndEmitRow instead.
+func (s *postgresSink) EmitRow(
+	ctx context.Context,
+	topic TopicDescriptor,
+	key, value []byte,
+	updated, mvcc hlc.Timestamp,
+	alloc kvevent.Alloc,
+) error {
+	return errors.AssertionFailedf("EmitRow unimplemented by the postgres sink")
+}
+
+// EncodeAndEmitRow implements the SinkWithEncoder interface.
+func (s *postgresSink) EncodeAndEmitRow(
+	ctx context.Context,
+	updatedRow cdcevent.Row,
+	prevRow cdcevent.Row,
+	topic TopicDescriptor,
+	updated, mvcc hlc.Timestamp,
+	encodingOpts changefeedbase.EncodingOptions,
+	alloc kvevent.Alloc,
+) error {
+	if s.db == nil {
+		return errors.New(`cannot EmitRow on a closed sink`)
+	}
+
+	change := postgresChange{
+		mvcc:    mvcc,
+		table:   updatedRow.TableName,
+		version: updatedRow.Version,
+		deleted: updatedRow.IsDeleted(),
+	}
+	size := 0
+	appendDatum := func(d tree.Datum, col cdcevent.ResultColumn) error {
+		change.cols = append(change.cols, col.Name)
+		if d == tree.DNull {
+			change.vals = append(change.vals, gosql.NullString{})
+			return nil
+		}
+		v := tree.AsStringWithFlags(d, tree.FmtPgwireText)
+		size += len(v)
+		change.vals = append(change.vals, gosql.NullString{String: v, Valid: true})
+		return nil
+	}
+	if err := updatedRow.ForEachKeyColumn().Datum(appendDatum); err != nil {
+		return err
+	}
+	change.numKeyCols = len(change.cols)
+
+	// With multiple column families, each family of a row is tracked
+	// separately.
+	var key strings.Builder
+	fmt.Fprintf(&key, "%s\x00%d", change.table, updatedRow.FamilyID)
+	for _, v := range change.vals {
+		if !v.Valid {
+			key.WriteString("\x00\x01")
+		} else {
+			key.WriteString("\x00\x02")
+			key.WriteString(v.String)
+		}
+	}
+	change.key = key.String()
+
+	if !change.deleted {
+		if err := updatedRow.ForEachColumn().Datum(func(d tree.Datum, col cdcevent.ResultColumn) error {
+			for _, name := range change.cols[:change.numKeyCols] {
+				if name == col.Name {
+					return nil
+				}
+			}
+			return appendDatum(d, col)
+		}); err != nil {
+			return err
+		}
+	}
+
+	s.metrics.recordMessageSize(int64(size))
+	if len(s.buffered.changes) == 0 {
+		s.bufferTime = timeutil.Now()
+		s.oldestMVCC = mvcc
+	} else if mvcc.Less(s.oldestMVCC) {
+		s.oldestMVCC = mvcc
+	}
+	s.buffered.add(change)
+	s.numBytes += size
+	s.alloc.Merge(&alloc)
+	return nil
+}
+
+// Flush implements the Sink interface.
+func (s *postgresSink) Flush(ctx context.Context) error {
+	defer s.metrics.recordFlushRequestCallback()()
+
+	if len(s.buffered.changes) == 0 {
+		return nil
+	}
+
+	live := s.buffered.live()
+	if err := s.withRetry(ctx, func() error {
+		return s.stageChanges(ctx, live)
+	}); err != nil {
+		return errors.Wrap(err, "staging changes in downstream database")
+	}
+	s.metrics.recordEmittedBatch(s.bufferTime, len(live), s.oldestMVCC, s.numBytes, sinkDoesNotCompress)
+
+	s.alloc.Release(ctx)
+	s.alloc = kvevent.Alloc{}
+	s.buffered.reset()
+	s.numBytes = 0
+	return nil
+}
+
+// withRetry runs fn until it succeeds, fails with an error which retrying does
+// not help with, or the retry options are exhausted.
+func (s *postgresSink) withRetry(ctx context.Context, fn func() error) error {
+	var permanentErr error
+	if err := retry.WithMaxAttempts(ctx, s.retryOpts, s.retryOpts.MaxRetries+1, func() error {
+		err := fn()
+		if err != nil && !isRetryablePostgresError(err) {
+			// Retrying will not help, so stop retrying and surface the error.
+			permanentErr = err
+			return nil
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	return permanentErr
+}
+
+// maybeCreateTables creates the progress and staging tables if they do not
+// exist yet.
+func (s *postgresSink) maybeCreateTables(ctx context.Context) error {
+	if s.tablesCreated {
+		return nil
+	}
+	for _, stmt := range []string{
+		fmt.Sprintf(postgresSinkCreateProgressStmt, s.progressTable),
+		fmt.Sprintf(postgresSinkCreateStagingStmt, s.stagingTable),
+	} {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	s.tablesCreated = true
+	return nil
+}
+
+// stageChanges writes changes to the staging table. Staging the same change
+// again, e.g. after a retry or a restart of the changefeed, is harmless.
+func (s *postgresSink) stageChanges(
+	ctx context.Context, changes []*postgresChange,
+) (retErr error) {
+	if err := s.maybeCreateTables(ctx); err != nil {
+		return err
+	}
+	txn, err := s.db.BeginTx(ctx, nil /* opts */)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			_ = txn.Rollback()
+		}
+	}()
+
+	if err := s.execBatches(ctx, txn, changes, postgresSinkStagingColumns, func(batch []*postgresChange) (string, []interface{}) {
+		var values strings.Builder
+		args := make([]interface{}, 0, len(batch)*postgresSinkStagingColumns)
+		for r, c := range batch {
+			if r > 0 {
+				values.WriteString(`, `)
+			}
+			values.WriteString(`(`)
+			for i := 1; i <= postgresSinkStagingColumns; i++ {
+				if i > 1 {
+					values.WriteString(`, `)
+				}
+				fmt.Fprintf(&values, `$%d`, len(args)+i)
+			}
+			values.WriteString(`)`)
+			args = append(args, int64(s.jobID), c.mvcc.WallTime, c.mvcc.Logical, []byte(c.key),
+				c.table, int64(c.version), c.deleted, c.numKeyCols, pq.Array(c.cols), pq.Array(c.vals))
+		}
+		return fmt.Sprintf(postgresSinkStageStmt, s.stagingTable, values.String()), args
+	}); err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+// applyStagedChanges applies the staged changes up to the resolved timestamp
+// to the downstream tables and records the resolved timestamp, in one
+// downstream transaction.
+func (s *postgresSink) applyStagedChanges(
+	ctx context.Context, resolved hlc.Timestamp,
+) (retErr error) {
+	if err := s.maybeCreateTables(ctx); err != nil {
+		return err
+	}
+	txn, err := s.db.BeginTx(ctx, nil /* opts */)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			_ = txn.Rollback()
+		}
+	}()
+
+	// Locking the progress row serializes concurrent attempts to apply the
+	// changes of the changefeed, e.g. by a changeFrontier which is about to
+	// be replaced.
+	var applied hlc.Timestamp
+	var prev string
+	if err := txn.QueryRowContext(ctx, fmt.Sprintf(postgresSinkSelectProgressStmt, s.progressTable),
+		int64(s.jobID)).Scan(&prev); err == nil {
+		if applied, err = hlc.ParseHLC(prev); err != nil {
+			return errors.Wrapf(err, "parsing resolved timestamp %q", prev)
+		}
+	} else if !errors.Is(err, gosql.ErrNoRows) {
+		return err
+	}
+
+	// Changes at or below the previous resolved timestamp were applied along
+	// with it; they are staged again when the changefeed restarts from an
+	// earlier checkpoint. Changes are applied a page at a time in MVCC order,
+	// so a row changed on several pages ends up in its most recent state.
+	afterWall, afterLogical, afterKey := applied.WallTime, applied.Logical, []byte{}
+	for {
+		rows, err := txn.QueryContext(ctx, fmt.Sprintf(postgresSinkSelectStagedQuery, s.stagingTable),
+			int64(s.jobID), afterWall, afterLogical, afterKey,
+			resolved.WallTime, resolved.Logical, postgresSinkApplyPageRows)
+		if err != nil {
+			return err
+		}
+		var page postgresChanges
+		n := 0
+		for rows.Next() {
+			var c postgresChange
+			var key []byte
+			var version int64
+			if err := rows.Scan(&c.mvcc.WallTime, &c.mvcc.Logical, &key, &c.table, &version,
+				&c.deleted, &c.numKeyCols, pq.Array(&c.cols), pq.Array(&c.vals)); err != nil {
+				rows.Close()
+				return err
+			}
+			c.key, c.version = string(key), descpb.DescriptorVersion(version)
+			afterWall, afterLogical, afterKey = c.mvcc.WallTime, c.mvcc.Logical, key
+			n++
+			if c.mvcc.LessEq(applied) {
+				continue
+			}
+			page.add(c)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		if err := s.applyChanges(ctx, txn, page.live()); err != nil {
+			return err
+		}
+		if n < postgresSinkApplyPageRows {
+			break
+		}
+	}
+
+	if _, err := txn.ExecContext(ctx, fmt.Sprintf(postgresSinkDeleteStagedStmt, s.stagingTable),
+		int64(s.jobID), resolved.WallTime, resolved.Logical); err != nil {
+		return err
+	}
+	resolved.Forward(applied)
+	if _, err := txn.ExecContext(ctx, fmt.Sprintf(postgresSinkUpdateProgressStmt, s.progressTable),
+		int64(s.jobID), resolved.AsOfSystemTime()); err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+// applyChanges applies changes to the downstream tables.
+func (s *postgresSink) applyChanges(
+	ctx context.Context, txn *gosql.Tx, changes []*postgresChange,
+) error {
+	for _, c := range changes {
+		if t, ok := s.tables[c.table]; !ok || t.version != c.version {
+			if err := s.loadTable(ctx, c.table, c.version); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Consecutive changes of the same kind to the same columns of a table are
+	// applied by the same statements.
+	var run []*postgresChange
+	flushRun := func() error {
+		if len(run) == 0 {
+			return nil
+		}
+		defer func() { run = run[:0] }()
+		if run[0].deleted {
+			return s.deleteRows(ctx, txn, run)
+		}
+		return s.upsertRows(ctx, txn, run)
+	}
+	for _, c := range changes {
+		if len(run) > 0 && !run[0].sameShape(c) {
+			if err := flushRun(); err != nil {
+				return err
+			}
+		}
+		run = append(run, c)
+	}
+	return flushRun()
+}
+
+func (c *postgresChange) sameShape(other *postgresChange) bool {
+	if c.table != other.table || c.version != other.version || c.deleted != other.deleted ||
+		len(c.cols) != len(other.cols) || c.numKeyCols != other.numKeyCols {
+		return false
+	}
+	for i := range c.cols {
+		if c.cols[i] != other.cols[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// loadTable loads the schema of the downstream table with the given name.
+func (s *postgresSink) loadTable(
+	ctx context.Context, name string, version descpb.DescriptorVersion,
+) error {
+	t := &postgresTable{
+		version: version,
+		name:    tree.NameString(s.schema) + "." + tree.NameString(name),
+		cols:    make(map[string]struct{}),
+	}
+	rows, err := s.db.QueryContext(ctx, postgresSinkColumnsQuery, s.schema, name)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			rows.Close()
+			return err
+		}
+		t.cols[col] = struct{}{}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if len(t.cols) == 0 {
+		return errors.Errorf(`table %s does not exist in the downstream database`, t.name)
+	}
+	_, t.hasMVCCCol = t.cols[postgresSinkMVCCColumn]
+
+	rows, err = s.db.QueryContext(ctx, postgresSinkPrimaryKeyQuery, s.schema, name)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			rows.Close()
+			return err
+		}
+		t.keyCols = append(t.keyCols, col)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if len(t.keyCols) == 0 {
+		return errors.Errorf(`downstream table %s does not have a primary key`, t.name)
+	}
+	s.tables[name] = t
+	return nil
+}
+
+// keyColumnIdxs returns, for each primary key column of the downstream table,
+// the index of the column of the same name in the columns of the change.
+func (t *postgresTable) keyColumnIdxs(c *postgresChange) ([]int, error) {
+	if len(t.keyCols) != c.numKeyCols {
+		return nil, errors.Errorf(`downstream table %s has %d primary key columns; expected %d`,
+			t.name, len(t.keyCols), c.numKeyCols)
+	}
+	idxs := make([]int, len(t.keyCols))
+	for i, k := range t.keyCols {
+		idxs[i] = -1
+		for j, col := range c.cols[:c.numKeyCols] {
+			if col == k {
+				idxs[i] = j
+				break
+			}
+		}
+		if idxs[i] == -1 {
+			return nil, errors.Errorf(`primary key column %s of downstream table %s is not written by the changefeed`,
+				k, t.name)
+		}
+	}
+	return idxs, nil
+}
+
+// upsertRows writes rows which all set the same columns of the same table.
+func (s *postgresSink) upsertRows(
+	ctx context.Context, txn *gosql.Tx, rows []*postgresChange,
+) error {
+	t := s.tables[rows[0].table]
+	if _, err := t.keyColumnIdxs(rows[0]); err != nil {
+		return err
+	}
+
+	// Only write the columns which exist downstream. The MVCC column is
+	// written by the sink itself.
+	var colIdxs []int
+	var missing []string
+	for i, col := range rows[0].cols {
+		if _, ok := t.cols[col]; !ok {
+			missing = append(missing, col)
+		} else if !t.hasMVCCCol || col != postgresSinkMVCCColumn {
+			colIdxs = append(colIdxs, i)
+		}
+	}
+	if len(missing) > 0 && log.V(1) {
+		log.Infof(ctx, "not writing columns %s which do not exist in %s", missing, t.name)
+	}
+	var cols, updates []string
+	for _, i := range colIdxs {
+		col := rows[0].cols[i]
+		cols = append(cols, tree.NameString(col))
+		if i >= rows[0].numKeyCols {
+			updates = append(updates, fmt.Sprintf("%[1]s = excluded.%[1]s", tree.NameString(col)))
+		}
+	}
+	mvccCol := tree.NameString(postgresSinkMVCCColumn)
+	if t.hasMVCCCol {
+		cols = append(cols, mvccCol)
+		updates = append(updates, fmt.Sprintf("%[1]s = excluded.%[1]s", mvccCol))
+	}
+	keyCols := make([]string, len(t.keyCols))
+	for i, k := range t.keyCols {
+		keyCols[i] = tree.NameString(k)
+	}
+	onConflict := fmt.Sprintf(` ON CONFLICT (%s) DO NOTHING`, strings.Join(keyCols, ", "))
+	if len(updates) > 0 {
+		onConflict = fmt.Sprintf(` ON CONFLICT (%s) DO UPDATE SET %s`,
+			strings.Join(keyCols, ", "), strings.Join(updates, ", "))
+	}
+	if t.hasMVCCCol {
+		// Changes which are older than the current state of the row are not
+		// applied.
+		onConflict += fmt.Sprintf(` WHERE %[1]s.%[2]s IS NULL OR %[1]s.%[2]s < excluded.%[2]s`,
+			postgresSinkTargetAlias, mvccCol)
+	}
+
+	return s.execBatches(ctx, txn, rows, len(cols), func(batch []*postgresChange) (string, []interface{}) {
+		var stmt strings.Builder
+		args := make([]interface{}, 0, len(batch)*len(cols))
+		fmt.Fprintf(&stmt, `INSERT INTO %s AS %s (%s) VALUES `,
+			t.name, postgresSinkTargetAlias, strings.Join(cols, ", "))
+		for r, row := range batch {
+			if r > 0 {
+				stmt.WriteString(`, `)
+			}
+			first := len(args)
+			for _, i := range colIdxs {
+				args = append(args, row.vals[i])
+			}
+			if t.hasMVCCCol {
+				args = append(args, row.mvcc.AsOfSystemTime())
+			}
+			stmt.WriteString(`(`)
+			for j := first; j < len(args); j++ {
+				if j > first {
+					stmt.WriteString(`, `)
+				}
+				fmt.Fprintf(&stmt, `$%d`, j+1)
+			}
+			stmt.WriteString(`)`)
+		}
+		stmt.WriteString(onConflict)
+		return stmt.String(), args
+	})
+}
+
+// deleteRows deletes rows of the same table.
+func (s *postgresSink) deleteRows(
+	ctx context.Context, txn *gosql.Tx, rows []*postgresChange,
+) error {
+	t := s.tables[rows[0].table]
+	keyIdxs, err := t.keyColumnIdxs(rows[0])
+	if err != nil {
+		return err
+	}
+	placeholdersPerRow := len(keyIdxs)
+	if t.hasMVCCCol {
+		placeholdersPerRow++
+	}
+	return s.execBatches(ctx, txn, rows, placeholdersPerRow, func(batch []*postgresChange) (string, []interface{}) {
+		var stmt strings.Builder
+		args := make([]interface{}, 0, len(batch)*placeholdersPerRow)
+		fmt.Fprintf(&stmt, `DELETE FROM %s WHERE `, t.name)
+		for r, row := range batch {
+			if r > 0 {
+				stmt.WriteString(` OR `)
+			}
+			stmt.WriteString(`(`)
+			for i, k := range t.keyCols {
+				if i > 0 {
+					stmt.WriteString(` AND `)
+				}
+				args = append(args, row.vals[keyIdxs[i]])
+				fmt.Fprintf(&stmt, `%s = $%d`, tree.NameString(k), len(args))
+			}
+			if t.hasMVCCCol {
+				args = append(args, row.mvcc.AsOfSystemTime())
+				fmt.Fprintf(&stmt, ` AND (%[1]s IS NULL OR %[1]s < $%[2]d)`,
+					tree.NameString(postgresSinkMVCCColumn), len(args))
+			}
+			stmt.WriteString(`)`)
+		}
+		return stmt.String(), args
+	})
+}
+
+// execBatches executes the statements returned by makeStmt for batches of
+// rows, where each row uses the given number of placeholders.
+func (s *postgresSink) execBatches(
+	ctx context.Context,
+	txn *gosql.Tx,
+	rows []*postgresChange,
+	placeholdersPerRow int,
+	makeStmt func([]*postgresChange) (string, []interface{}),
+) error {
+	batchRows := s.batchRows
+	if placeholdersPerRow > 0 && batchRows*placeholdersPerRow > postgresMaxPlaceholders {
+		batchRows = postgresMaxPlaceholders / placeholdersPerRow
+	}
+	for len(rows) > 0 {
+		n := batchRows
+		if n > len(rows) {
+			n = len(rows)
+		}
+		stmt, args := makeStmt(rows[:n])
+		if _, err := txn.ExecContext(ctx, stmt, args...); err != nil {
+			return err
+		}
+		rows = rows[n:]
+	}
+	return nil
+}
+
+// isRetryablePostgresError returns true if applying changes should be retried
+// after the given error.
+func isRetryablePostgresError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case `08`, // connection exception
+			`40`, // transaction rollback, e.g. serialization failures
+			`57`: // operator intervention, e.g. the server shutting down
+			return true
+		}
+	}
+	return false
+}
+
+// EmitResolvedTimestamp implements the Sink interface.
+func (s *postgresSink) EmitResolvedTimestamp(
+	ctx context.Context, _ Encoder, resolved hlc.Timestamp,
+) error {
+	defer s.metrics.recordResolvedCallback()()
+
+	if err := s.withRetry(ctx, func() error {
+		return s.applyStagedChanges(ctx, resolved)
+	}); err != nil {
+		return errors.Wrap(err, "applying changes to downstream database")
+	}
+	return nil
+}
+
+// Close implements the Sink interface.
+func (s *postgresSink) Close() error {
+	s.alloc.Release(context.Background())
+	s.buffered.reset()
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
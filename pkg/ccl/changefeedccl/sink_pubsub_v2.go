@@ -189,6 +189,11 @@ func (sc *pubsubSinkClient) Flush(ctx context.Context, payload SinkPayload) erro
 				"Create topics in advance or grant this service account the pubsub.editor role on your project.")
 		}
 	}
+	if status.Code(err) == codes.InvalidArgument {
+		// The publish request itself is malformed (e.g. a message exceeds the
+		// maximum size), so retrying it cannot succeed.
+		return markSinkRejectedMessage(err)
+	}
 	return err
 }
 
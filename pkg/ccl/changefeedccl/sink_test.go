@@ -585,6 +585,136 @@ func TestSQLSink(t *testing.T) {
 	)
 }
 
+func TestPostgresSink(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	s, sqlDBRaw, _ := serverutils.StartServer(t, base.TestServerArgs{UseDatabase: "d"})
+	defer s.Stopper().Stop(ctx)
+	sqlDB := sqlutils.MakeSQLRunner(sqlDBRaw)
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	// The downstream table does not have column c yet. It records the MVCC
+	// timestamp of every row.
+	sqlDB.Exec(t, `CREATE TABLE foo (a INT PRIMARY KEY, b STRING, mvcc_ts DECIMAL)`)
+	// The primary key columns of the downstream table are in a different order.
+	sqlDB.Exec(t, `CREATE TABLE baz (a INT, b INT, c STRING, PRIMARY KEY (b, a))`)
+
+	pgURL, cleanup := sqlutils.PGUrl(t, s.ApplicationLayer().AdvSQLAddr(), t.Name(), url.User(username.RootUser))
+	defer cleanup()
+	pgURL.Path = `d`
+
+	tableDesc, err := parseTableDesc(`CREATE TABLE foo (a INT PRIMARY KEY, b STRING, c INT)`)
+	require.NoError(t, err)
+	rows, err := parseValues(tableDesc, `VALUES (1, 'one', 10), (2, 'two', 20), (1, 'uno', 11)`)
+	require.NoError(t, err)
+	topic := makeTopic(`foo`)
+
+	const jobID = 42
+	sink, err := makePostgresSink(sinkURL{URL: &pgURL}, ``,
+		changefeedbase.EncodingOptions{Format: changefeedbase.OptFormatJSON}, jobID, nilMetricsRecorderBuilder)
+	require.NoError(t, err)
+	require.NoError(t, sink.Dial())
+	defer func() { require.NoError(t, sink.Close()) }()
+	pgSink := sink.(*postgresSink)
+
+	emit := func(row cdcevent.Row, mvcc int64, alloc kvevent.Alloc) {
+		require.NoError(t, pgSink.EncodeAndEmitRow(ctx, row, cdcevent.Row{}, topic,
+			zeroTS, hlc.Timestamp{WallTime: mvcc}, changefeedbase.EncodingOptions{}, alloc))
+	}
+	var e testEncoder
+	resolve := func(resolved int64) error {
+		return sink.EmitResolvedTimestamp(ctx, e, hlc.Timestamp{WallTime: resolved})
+	}
+	countStaged := func() int {
+		var n int
+		sqlDB.QueryRow(t, `SELECT count(*) FROM crdb_changefeed_staging`).Scan(&n)
+		return n
+	}
+
+	// Changes are staged by Flush, and only applied once they are resolved.
+	var pool testAllocPool
+	emit(cdcevent.TestingMakeEventRow(tableDesc, 0, rows[0], false), 1, pool.alloc())
+	emit(cdcevent.TestingMakeEventRow(tableDesc, 0, rows[1], false), 2, pool.alloc())
+	require.NoError(t, sink.Flush(ctx))
+	require.EqualValues(t, 0, pool.used())
+	require.Equal(t, 2, countStaged())
+	sqlDB.CheckQueryResults(t, `SELECT count(*) FROM foo`, [][]string{{`0`}})
+	require.NoError(t, resolve(1))
+	sqlDB.CheckQueryResults(t, `SELECT a, b, mvcc_ts FROM foo ORDER BY a`,
+		[][]string{{`1`, `one`, `1.0000000000`}},
+	)
+	require.Equal(t, 1, countStaged())
+	require.NoError(t, resolve(2))
+	sqlDB.CheckQueryResults(t, `SELECT a, b FROM foo ORDER BY a`,
+		[][]string{{`1`, `one`}, {`2`, `two`}},
+	)
+	sqlDB.CheckQueryResults(t, `SELECT job_id, resolved FROM crdb_changefeed_progress`,
+		[][]string{{`42`, `2.0000000000`}},
+	)
+
+	// Only the latest change to each row is applied, regardless of the order in
+	// which the changes are flushed. Changes at or below the last resolved
+	// timestamp, which are replayed after a restart, are not applied again.
+	emit(cdcevent.TestingMakeEventRow(tableDesc, 0, rows[2], false), 4, pool.alloc())
+	emit(cdcevent.TestingMakeEventRow(tableDesc, 0, rows[1], true), 5, pool.alloc())
+	require.NoError(t, sink.Flush(ctx))
+	emit(cdcevent.TestingMakeEventRow(tableDesc, 0, rows[1], false), 3, pool.alloc())
+	emit(cdcevent.TestingMakeEventRow(tableDesc, 0, rows[0], false), 1, pool.alloc())
+	require.NoError(t, sink.Flush(ctx))
+	require.EqualValues(t, 0, pool.used())
+	require.NoError(t, resolve(5))
+	sqlDB.CheckQueryResults(t, `SELECT a, b FROM foo ORDER BY a`, [][]string{{`1`, `uno`}})
+	require.Equal(t, 0, countStaged())
+
+	// Changes which are older than a row are not applied to it.
+	sqlDB.Exec(t, `UPDATE foo SET mvcc_ts = 100 WHERE a = 1`)
+	emit(cdcevent.TestingMakeEventRow(tableDesc, 0, rows[0], false), 6, pool.alloc())
+	require.NoError(t, sink.Flush(ctx))
+	require.NoError(t, resolve(6))
+	sqlDB.CheckQueryResults(t, `SELECT a, b FROM foo ORDER BY a`, [][]string{{`1`, `uno`}})
+
+	// Columns added downstream are written once the upstream table changes.
+	sqlDB.Exec(t, `ALTER TABLE foo ADD COLUMN c INT`)
+	newVersion := *tableDesc.TableDesc()
+	newVersion.Version++
+	tableDesc = tabledesc.NewBuilder(&newVersion).BuildImmutableTable()
+	emit(cdcevent.TestingMakeEventRow(tableDesc, 0, rows[1], false), 7, pool.alloc())
+	require.NoError(t, sink.Flush(ctx))
+	require.NoError(t, resolve(7))
+	sqlDB.CheckQueryResults(t, `SELECT a, b, c FROM foo ORDER BY a`,
+		[][]string{{`1`, `uno`, `NULL`}, {`2`, `two`, `20`}},
+	)
+
+	// Primary key columns are matched by name.
+	bazDesc, err := parseTableDesc(`CREATE TABLE baz (a INT, b INT, c STRING, PRIMARY KEY (a, b))`)
+	require.NoError(t, err)
+	bazRows, err := parseValues(bazDesc, `VALUES (1, 2, 'x'), (3, 4, 'y')`)
+	require.NoError(t, err)
+	emit(cdcevent.TestingMakeEventRow(bazDesc, 0, bazRows[0], false), 8, zeroAlloc)
+	emit(cdcevent.TestingMakeEventRow(bazDesc, 0, bazRows[1], false), 8, zeroAlloc)
+	require.NoError(t, sink.Flush(ctx))
+	require.NoError(t, resolve(8))
+	emit(cdcevent.TestingMakeEventRow(bazDesc, 0, bazRows[0], true), 9, zeroAlloc)
+	require.NoError(t, sink.Flush(ctx))
+	require.NoError(t, resolve(9))
+	sqlDB.CheckQueryResults(t, `SELECT a, b, c FROM baz`, [][]string{{`3`, `4`, `y`}})
+
+	// Changes to tables which do not exist downstream fail once they are
+	// applied, and are not lost.
+	barDesc, err := parseTableDesc(`CREATE TABLE bar (a INT PRIMARY KEY)`)
+	require.NoError(t, err)
+	barRows, err := parseValues(barDesc, `VALUES (1)`)
+	require.NoError(t, err)
+	emit(cdcevent.TestingMakeEventRow(barDesc, 0, barRows[0], false), 10, zeroAlloc)
+	require.NoError(t, sink.Flush(ctx))
+	require.Regexp(t, `table public.bar does not exist`, resolve(10))
+	require.Equal(t, 1, countStaged())
+	sqlDB.CheckQueryResults(t, `SELECT job_id, resolved FROM crdb_changefeed_progress`,
+		[][]string{{`42`, `9.0000000000`}},
+	)
+}
+
 func TestSaramaConfigOptionParsing(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -26,6 +26,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/testutils/skip"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/require"
@@ -695,6 +696,119 @@ func TestWebhookSinkShutsDownOnError(t *testing.T) {
 	}
 }
 
+// testDeadLetterQueue is a deadLetterQueue which records dead letters in
+// memory.
+type testDeadLetterQueue struct {
+	syncutil.Mutex
+	letters []deadLetter
+}
+
+var _ deadLetterQueue = (*testDeadLetterQueue)(nil)
+
+func (q *testDeadLetterQueue) Record(_ context.Context, dl deadLetter) error {
+	q.Lock()
+	defer q.Unlock()
+	q.letters = append(q.letters, dl)
+	return nil
+}
+
+func (q *testDeadLetterQueue) Flush(context.Context) error { return nil }
+
+func (q *testDeadLetterQueue) Close() error { return nil }
+
+func (q *testDeadLetterQueue) get() []deadLetter {
+	q.Lock()
+	defer q.Unlock()
+	return append([]deadLetter(nil), q.letters...)
+}
+
+func TestWebhookSinkDeadLettersRejectedMessages(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	cert, certEncoded, err := cdctest.NewCACertBase64Encoded()
+	require.NoError(t, err)
+	sinkDest, err := cdctest.StartMockWebhookSink(cert)
+	require.NoError(t, err)
+	defer sinkDest.Close()
+
+	sinkDestHost, err := url.Parse(sinkDest.URL())
+	require.NoError(t, err)
+	params := sinkDestHost.Query()
+	params.Set(changefeedbase.SinkParamCACert, certEncoded)
+	sinkDestHost.RawQuery = params.Encode()
+
+	details := jobspb.ChangefeedDetails{
+		SinkURI: fmt.Sprintf("webhook-%s", sinkDestHost.String()),
+		Opts:    getGenericWebhookSinkOptions().AsMap(),
+	}
+	sinkSrc, err := setupWebhookSinkWithDetails(ctx, details, 1 /* parallelism */, timeutil.DefaultTimeSource{})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, sinkSrc.Close()) }()
+
+	dlq := &testDeadLetterQueue{}
+	sinkSrc.(sinkWithDeadLetterQueue).setDeadLetterQueue(dlq)
+
+	// A 400 is a permanent rejection of the message: it must not be retried,
+	// and the message must be dead lettered rather than failing the sink.
+	sinkDest.SetStatusCodes([]int{http.StatusBadRequest})
+	rejected := []byte("{\"after\":{\"col1\":\"bad\",\"rowid\":1000},\"key\":[1001],\"topic:\":\"foo\"}")
+	require.NoError(t, sinkSrc.EmitRow(ctx, nil, []byte("[1001]"), rejected, zeroTS, zeroTS, zeroAlloc))
+	require.NoError(t, sinkSrc.Flush(ctx))
+
+	letters := dlq.get()
+	require.Len(t, letters, 1)
+	require.Equal(t, deadLetterReasonSinkRejected, letters[0].reason)
+	require.Equal(t, []byte("[1001]"), letters[0].key)
+	require.Equal(t, rejected, letters[0].value)
+	require.Contains(t, letters[0].err.Error(), "400 Bad Request")
+
+	// The sink keeps delivering subsequent messages.
+	sinkDest.SetStatusCodes([]int{http.StatusOK})
+	testSendAndReceiveRows(t, sinkSrc, sinkDest)
+	require.Len(t, dlq.get(), 1)
+
+	// Transient errors are still retried and eventually fail the sink.
+	sinkDest.SetStatusCodes(repeatStatusCode(http.StatusInternalServerError,
+		defaultRetryConfig().MaxRetries+1))
+	require.NoError(t, sinkSrc.EmitRow(ctx, nil, []byte("[1002]"), []byte("{}"), zeroTS, zeroTS, zeroAlloc))
+	require.EqualError(t, sinkSrc.Flush(ctx), "500 Internal Server Error: ")
+	require.Len(t, dlq.get(), 1)
+}
+
+// TestWebhookSinkRetriesRejectedMessagesWithoutDeadLetterQueue checks that
+// messages rejected by the sink are retried like any other error unless they
+// can be dead lettered.
+func TestWebhookSinkRetriesRejectedMessagesWithoutDeadLetterQueue(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	cert, certEncoded, err := cdctest.NewCACertBase64Encoded()
+	require.NoError(t, err)
+	sinkDest, err := cdctest.StartMockWebhookSink(cert)
+	require.NoError(t, err)
+	defer sinkDest.Close()
+
+	sinkDestHost, err := url.Parse(sinkDest.URL())
+	require.NoError(t, err)
+	params := sinkDestHost.Query()
+	params.Set(changefeedbase.SinkParamCACert, certEncoded)
+	sinkDestHost.RawQuery = params.Encode()
+
+	details := jobspb.ChangefeedDetails{
+		SinkURI: fmt.Sprintf("webhook-%s", sinkDestHost.String()),
+		Opts:    getGenericWebhookSinkOptions().AsMap(),
+	}
+	sinkSrc, err := setupWebhookSinkWithDetails(ctx, details, 1 /* parallelism */, timeutil.DefaultTimeSource{})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, sinkSrc.Close()) }()
+
+	sinkDest.SetStatusCodes([]int{http.StatusBadRequest, http.StatusOK})
+	require.NoError(t, sinkSrc.EmitRow(ctx, nil, []byte("[1001]"), []byte("{}"), zeroTS, zeroTS, zeroAlloc))
+	require.NoError(t, sinkSrc.Flush(ctx))
+	require.Equal(t, "{\"payload\":[{}],\"length\":1}", sinkDest.Latest())
+}
+
 // Regression test for https://github.com/cockroachdb/cockroach/issues/102467.
 // Ensure that we do not use the default retry config which is capped at
 // 4000ms.
@@ -214,11 +214,29 @@ func (sc *webhookSinkClient) Flush(ctx context.Context, batch SinkPayload) error
 		if err != nil {
 			return errors.Wrapf(err, "failed to read body for HTTP response with status: %d", res.StatusCode)
 		}
-		return fmt.Errorf("%s: %s", res.Status, string(resBody))
+		err = fmt.Errorf("%s: %s", res.Status, string(resBody))
+		if isPermanentWebhookStatus(res.StatusCode) {
+			return markSinkRejectedMessage(err)
+		}
+		return err
 	}
 	return nil
 }
 
+// isPermanentWebhookStatus returns true if the HTTP status code returned by the
+// webhook endpoint indicates that the request itself was rejected, such that
+// retrying it will not succeed.
+func isPermanentWebhookStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusUnauthorized, http.StatusForbidden:
+		// Timeouts and rate limiting are transient, and authentication failures
+		// are not specific to the message being delivered.
+		return false
+	}
+	return code >= http.StatusBadRequest && code < http.StatusInternalServerError
+}
+
 // Close implements the SinkClient interface
 func (sc *webhookSinkClient) Close() error {
 	sc.client.CloseIdleConnections()
@@ -194,6 +194,10 @@ func (r *telemetryMetricsRecorder) recordSinkIOInflightChange(delta int64) {
 	r.inner.recordSinkIOInflightChange(delta)
 }
 
+func (r *telemetryMetricsRecorder) recordDeadLetters(numMessages int) {
+	r.inner.recordDeadLetters(numMessages)
+}
+
 // ContinuousTelemetryInterval determines the interval at which each node emits telemetry events
 // during the lifespan of each enterprise changefeed.
 var ContinuousTelemetryInterval = settings.RegisterDurationSetting(
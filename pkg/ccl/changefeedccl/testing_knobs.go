@@ -77,6 +77,12 @@ type TestingKnobs struct {
 
 	// OnDrain returns the channel to select on to detect node drain
 	OnDrain func() <-chan struct{}
+
+	// AfterIcebergCommit is called after the data files which precede a
+	// resolved timestamp were committed to their Iceberg tables, before they are
+	// removed from the pending directory. Returning an error aborts the commit
+	// at that point.
+	AfterIcebergCommit func() error
 }
 
 // ModuleTestingKnobs is part of the base.ModuleTestingKnobs interface.
@@ -81,3 +81,82 @@ func newClientFromTLSKeyPair(caCert, clientCert, clientKey []byte) (*httputil.Cl
 
 	return client, nil
 }
+
+// makeSinkTLSConfig consumes the TLS related query parameters of a sink URI
+// and returns the configuration to use when dialing the sink. It returns nil
+// if TLS was neither required by the caller nor enabled via the tls_enabled
+// parameter.
+func makeSinkTLSConfig(u *sinkURL, tlsRequired bool) (*tls.Config, error) {
+	dialConfig := struct {
+		tlsEnabled    bool
+		tlsSkipVerify bool
+		caCert        []byte
+		clientCert    []byte
+		clientKey     []byte
+	}{tlsEnabled: tlsRequired}
+
+	if _, err := u.consumeBool(changefeedbase.SinkParamTLSEnabled, &dialConfig.tlsEnabled); err != nil {
+		return nil, err
+	}
+	if _, err := u.consumeBool(changefeedbase.SinkParamSkipTLSVerify, &dialConfig.tlsSkipVerify); err != nil {
+		return nil, err
+	}
+	if err := u.decodeBase64(changefeedbase.SinkParamCACert, &dialConfig.caCert); err != nil {
+		return nil, err
+	}
+	if err := u.decodeBase64(changefeedbase.SinkParamClientCert, &dialConfig.clientCert); err != nil {
+		return nil, err
+	}
+	if err := u.decodeBase64(changefeedbase.SinkParamClientKey, &dialConfig.clientKey); err != nil {
+		return nil, err
+	}
+
+	if !dialConfig.tlsEnabled {
+		for param, set := range map[string]bool{
+			changefeedbase.SinkParamSkipTLSVerify: dialConfig.tlsSkipVerify,
+			changefeedbase.SinkParamCACert:        dialConfig.caCert != nil,
+			changefeedbase.SinkParamClientCert:    dialConfig.clientCert != nil,
+			changefeedbase.SinkParamClientKey:     dialConfig.clientKey != nil,
+		} {
+			if set {
+				return nil, errors.Errorf(`%s requires %s=true`, param, changefeedbase.SinkParamTLSEnabled)
+			}
+		}
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: dialConfig.tlsSkipVerify,
+		ServerName:         u.Hostname(),
+	}
+
+	if dialConfig.caCert != nil {
+		caCertPool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not load system root CA pool")
+		}
+		if caCertPool == nil {
+			caCertPool = x509.NewCertPool()
+		}
+		if !caCertPool.AppendCertsFromPEM(dialConfig.caCert) {
+			return nil, errors.Errorf("failed to parse certificate data:%s", string(dialConfig.caCert))
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	if dialConfig.clientCert != nil && dialConfig.clientKey == nil {
+		return nil, errors.Errorf(`%s requires %s to be set`, changefeedbase.SinkParamClientCert, changefeedbase.SinkParamClientKey)
+	} else if dialConfig.clientKey != nil && dialConfig.clientCert == nil {
+		return nil, errors.Errorf(`%s requires %s to be set`, changefeedbase.SinkParamClientKey, changefeedbase.SinkParamClientCert)
+	}
+
+	if dialConfig.clientCert != nil {
+		cert, err := tls.X509KeyPair(dialConfig.clientCert, dialConfig.clientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, `invalid client certificate data provided`)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
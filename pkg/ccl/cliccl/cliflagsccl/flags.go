@@ -33,12 +33,18 @@ Valid fields:
 * path    (required): must match the path of one of the stores
 * key     (required): path to the current key file, or "plain"
 * old-key (required): path to the previous key file, or "plain"
+* kms               : URI of a KMS wrapping store keys generated by CockroachDB,
+                      used instead of key; old-key is then optional
 * rotation-period   : amount of time after which data keys should be rotated
 
 </PRE>
-example:
+Store keys wrapped by a KMS are saved in the store directory and can be
+rotated online with crdb_internal.kv_rotate_store_encryption_key().
+
+examples:
 <PRE>
-  --enterprise-encryption=path=cockroach-data,key=/keys/aes-128.key,old-key=plain</PRE>
+  --enterprise-encryption=path=cockroach-data,key=/keys/aes-128.key,old-key=plain
+  --enterprise-encryption=path=cockroach-data,kms=aws-kms:///alias/crdb?REGION=us-east-1</PRE>
 `,
 	}
 )
@@ -11,13 +11,17 @@ package engineccl
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/baseccl"
 	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl/engineccl/enginepbccl"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/vfs"
 )
 
@@ -41,6 +45,8 @@ import (
 // - The StoreKeyManager uses the base-FS to read the user-specified store keys at startup.
 //   These are in two key files: the active key file and the old key file, which contain the
 //   key id and the key.
+// - Alternatively, the KMSStoreKeyManager generates the store keys and uses the base-FS to
+//   store them wrapped by a KMS. Its store key can be rotated while the store is running.
 // - The store-FS is used only for storing the key file for the generated keys. It is used by
 //   the DataKeyManager. These keys are rotated periodically in a simple manner -- a new
 //   active key is generated for future file writes. Existing files are not affected.
@@ -240,14 +246,19 @@ func (fs *encryptedFS) ReuseForWrite(oldname, newname string) (vfs.File, error)
 }
 
 type encryptionStatsHandler struct {
-	storeKM *StoreKeyManager
+	storeKM PebbleKeyManager
 	dataKM  *DataKeyManager
+	fr      *storage.PebbleFileRegistry
 }
 
 func (e *encryptionStatsHandler) GetEncryptionStatus() ([]byte, error) {
 	var s enginepbccl.EncryptionStatus
-	if e.storeKM.activeKey != nil {
-		s.ActiveStoreKey = e.storeKM.activeKey.Info
+	storeKey, err := e.storeKM.ActiveKey(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	if storeKey != nil {
+		s.ActiveStoreKey = storeKey.Info
 	}
 	k, err := e.dataKM.ActiveKey(context.TODO())
 	if err != nil {
@@ -256,9 +267,41 @@ func (e *encryptionStatsHandler) GetEncryptionStatus() ([]byte, error) {
 	if k != nil {
 		s.ActiveDataKey = k.Info
 	}
+
+	// Count the files encrypted with each key known to the data keys registry.
+	files := make(map[string]uint64)
+	for _, entry := range e.fr.List() {
+		if len(entry.EncryptionSettings) == 0 {
+			continue
+		}
+		keyID, err := e.GetKeyIDFromSettings(entry.EncryptionSettings)
+		if err != nil {
+			return nil, err
+		}
+		files[keyID]++
+	}
+	r := e.dataKM.getScrubbedRegistry()
+	for id, info := range r.StoreKeys {
+		s.StoreKeys = append(s.StoreKeys, &enginepbccl.KeyUsage{Key: info, Files: files[id]})
+	}
+	for id, key := range r.DataKeys {
+		s.DataKeys = append(s.DataKeys, &enginepbccl.KeyUsage{Key: key.Info, Files: files[id]})
+	}
+	sortKeyUsage(s.StoreKeys)
+	sortKeyUsage(s.DataKeys)
 	return protoutil.Marshal(&s)
 }
 
+// sortKeyUsage sorts the keys by creation time and ID.
+func sortKeyUsage(keys []*enginepbccl.KeyUsage) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Key.CreationTime != keys[j].Key.CreationTime {
+			return keys[i].Key.CreationTime < keys[j].Key.CreationTime
+		}
+		return keys[i].Key.KeyId < keys[j].Key.KeyId
+	})
+}
+
 func (e *encryptionStatsHandler) GetDataKeysRegistry() ([]byte, error) {
 	r := e.dataKM.getScrubbedRegistry()
 	return protoutil.Marshal(r)
@@ -276,8 +319,8 @@ func (e *encryptionStatsHandler) GetActiveDataKeyID() (string, error) {
 }
 
 func (e *encryptionStatsHandler) GetActiveStoreKeyType() int32 {
-	if e.storeKM.activeKey != nil {
-		return int32(e.storeKM.activeKey.Info.EncryptionType)
+	if k, err := e.storeKM.ActiveKey(context.TODO()); err == nil && k != nil {
+		return int32(k.Info.EncryptionType)
 	}
 	return int32(enginepbccl.EncryptionType_Plaintext)
 }
@@ -290,6 +333,38 @@ func (e *encryptionStatsHandler) GetKeyIDFromSettings(settings []byte) (string,
 	return s.KeyId, nil
 }
 
+// kmsStoreKeyRotator rotates the store key of an environment whose store keys
+// are wrapped by a KMS. Implements storage.EncryptionKeyRotator.
+type kmsStoreKeyRotator struct {
+	// mu serializes rotations, so that the active store key recorded in the
+	// data keys registry is always the one that encrypts it.
+	mu      syncutil.Mutex
+	storeKM *KMSStoreKeyManager
+	dataKM  *DataKeyManager
+}
+
+// RotateStoreKey implements storage.EncryptionKeyRotator.
+func (r *kmsStoreKeyRotator) RotateStoreKey(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// If the data keys aren't re-encrypted because of a failure or crash, the
+	// new store key is still recorded as the active one, and the data keys
+	// will be re-encrypted on the next attempt or restart.
+	info, err := r.storeKM.Rotate(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := r.dataKM.SetActiveStoreKeyInfo(ctx, info); err != nil {
+		return "", err
+	}
+	return info.KeyId, nil
+}
+
+// closerFunc implements io.Closer.
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
 // init initializes function hooks used in non-CCL code.
 func init() {
 	storage.NewEncryptedEnvFunc = newEncryptedEnv
@@ -302,23 +377,57 @@ func init() {
 //
 // See the comment at the top of this file for the structure of this environment.
 func newEncryptedEnv(
-	fs vfs.FS, fr *storage.PebbleFileRegistry, dbDir string, readOnly bool, optionBytes []byte,
-) (*storage.EncryptionEnv, error) {
+	fs vfs.FS,
+	fr *storage.PebbleFileRegistry,
+	dbDir string,
+	readOnly bool,
+	optionBytes []byte,
+	st *cluster.Settings,
+) (_ *storage.EncryptionEnv, retErr error) {
 	options := &baseccl.EncryptionOptions{}
 	if err := protoutil.Unmarshal(optionBytes, options); err != nil {
 		return nil, err
 	}
-	if options.KeySource != baseccl.EncryptionKeySource_KeyFiles {
+	var storeKeyManager PebbleKeyManager
+	var kmsStoreKeyManager *KMSStoreKeyManager
+	switch options.KeySource {
+	case baseccl.EncryptionKeySource_KeyFiles:
+		km := &StoreKeyManager{
+			fs:                fs,
+			activeKeyFilename: options.KeyFiles.CurrentKey,
+			oldKeyFilename:    options.KeyFiles.OldKey,
+		}
+		if err := km.Load(context.TODO()); err != nil {
+			return nil, err
+		}
+		storeKeyManager = km
+	case baseccl.EncryptionKeySource_KMS:
+		if st == nil {
+			st = cluster.MakeClusterSettings()
+		}
+		kms, err := cloud.KMSFromURI(context.TODO(), options.Kms.Uri, &storeKMSEnv{settings: st})
+		if err != nil {
+			return nil, errors.Wrap(err, "opening store key KMS")
+		}
+		kmsStoreKeyManager = &KMSStoreKeyManager{
+			fs:             fs,
+			dbDir:          dbDir,
+			kms:            kms,
+			oldKeyFilename: options.Kms.OldKey,
+			readOnly:       readOnly,
+		}
+		if err := kmsStoreKeyManager.Load(context.TODO()); err != nil {
+			return nil, errors.CombineErrors(err, kms.Close())
+		}
+		defer func() {
+			if retErr != nil {
+				_ = kmsStoreKeyManager.Close()
+			}
+		}()
+		storeKeyManager = kmsStoreKeyManager
+	default:
 		return nil, fmt.Errorf("unknown encryption key source: %d", options.KeySource)
 	}
-	storeKeyManager := &StoreKeyManager{
-		fs:                fs,
-		activeKeyFilename: options.KeyFiles.CurrentKey,
-		oldKeyFilename:    options.KeyFiles.OldKey,
-	}
-	if err := storeKeyManager.Load(context.TODO()); err != nil {
-		return nil, err
-	}
 	storeFS := &encryptedFS{
 		FS:           fs,
 		fileRegistry: fr,
@@ -355,14 +464,27 @@ func newEncryptedEnv(
 		}
 	}
 
-	return &storage.EncryptionEnv{
+	env := &storage.EncryptionEnv{
 		Closer: dataKeyManager,
 		FS:     dataFS,
 		StatsHandler: &encryptionStatsHandler{
 			storeKM: storeKeyManager,
 			dataKM:  dataKeyManager,
+			fr:      fr,
 		},
-	}, nil
+	}
+	if kmsStoreKeyManager != nil {
+		env.Closer = closerFunc(func() error {
+			return errors.CombineErrors(dataKeyManager.Close(), kmsStoreKeyManager.Close())
+		})
+		if !readOnly {
+			env.KeyRotator = &kmsStoreKeyRotator{
+				storeKM: kmsStoreKeyManager,
+				dataKM:  dataKeyManager,
+			}
+		}
+	}
+	return env, nil
 }
 
 func canRegistryElide(entry *enginepb.FileEntry) bool {
@@ -533,7 +533,7 @@ func (etfs *encryptedTestFS) restart() error {
 		return err
 	}
 	encEnv, err := newEncryptedEnv(
-		fsMeta, fileRegistry, "", false, etfs.encOptionsBytes)
+		fsMeta, fileRegistry, "", false, etfs.encOptionsBytes, nil /* st */)
 	if err != nil {
 		return err
 	}
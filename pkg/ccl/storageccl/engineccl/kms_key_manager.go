@@ -0,0 +1,296 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package engineccl
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl/engineccl/enginepbccl"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/isql"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/cockroachdb/pebble/vfs/atomicfs"
+	"github.com/gogo/protobuf/proto"
+)
+
+const (
+	// The filename used for writing the wrapped store keys by the
+	// KMSStoreKeyManager.
+	storeKeysRegistryFilename = "COCKROACHDB_STORE_KEYS"
+	// The name of the marker used to record the active store keys registry.
+	storeKeysRegistryMarkerName = "storekeys"
+	// The source recorded in the KeyInfo of generated store keys.
+	kmsStoreKeySource = "kms store key manager"
+	// The encryption type of generated store keys.
+	kmsStoreKeyEncryptionType = enginepbccl.EncryptionType_AES256_CTR
+)
+
+// KMSStoreKeyManager manages store keys which are generated by CockroachDB
+// and wrapped by a KMS. Implements PebbleKeyManager.
+//
+// The wrapped keys are persisted in a StoreKeysRegistry in the base-FS. Since
+// a store key only ever encrypts the data keys registry, which is rewritten
+// under the new store key when the store key changes, the store key can be
+// rotated online by generating a new key with Rotate and passing it to
+// DataKeyManager.SetActiveStoreKeyInfo. Previous store keys are retained so
+// that a crash between the two steps doesn't render the store unreadable.
+type KMSStoreKeyManager struct {
+	// Initialize the following before calling Load().
+	fs    vfs.FS
+	dbDir string
+	kms   cloud.KMS
+	// oldKeyFilename is the key file of the store key which was in use before
+	// the store switched to KMS-wrapped keys, if any.
+	oldKeyFilename string
+	readOnly       bool
+
+	mu struct {
+		syncutil.Mutex
+		// Non-nil after Load().
+		registry *enginepbccl.StoreKeysRegistry
+		// Non-nil after a successful call to Load().
+		activeKey *enginepbccl.SecretKey
+		// oldKey is loaded from oldKeyFilename, if set.
+		oldKey *enginepbccl.SecretKey
+		// unwrapped caches the store keys which have been unwrapped, by ID.
+		unwrapped map[string]*enginepbccl.SecretKey
+		// marker is an atomic file marker used to denote which of the store
+		// keys registry files is the current one.
+		marker *atomicfs.Marker
+		// filename is the filename of the currently active registry.
+		filename string
+	}
+}
+
+// Load must be called before calling other methods. On the first run, it
+// generates the first store key.
+func (m *KMSStoreKeyManager) Load(ctx context.Context) error {
+	marker, filename, err := atomicfs.LocateMarker(m.fs, m.dbDir, storeKeysRegistryMarkerName)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mu.marker = marker
+	m.mu.filename = filename
+	m.mu.registry = &enginepbccl.StoreKeysRegistry{
+		StoreKeys: make(map[string]*enginepbccl.WrappedStoreKey),
+	}
+	m.mu.unwrapped = make(map[string]*enginepbccl.SecretKey)
+	if filename != "" {
+		f, err := m.fs.Open(m.fs.PathJoin(m.dbDir, filename))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		if err := protoutil.Unmarshal(b, m.mu.registry); err != nil {
+			return err
+		}
+	}
+	if m.oldKeyFilename != "" {
+		if m.mu.oldKey, err = loadKeyFromFile(m.fs, m.oldKeyFilename); err != nil {
+			return err
+		}
+	}
+
+	if m.mu.registry.ActiveStoreKeyId == "" {
+		if m.readOnly {
+			return errors.New("no store key has been generated for this store")
+		}
+		if _, err := m.rotateLocked(ctx); err != nil {
+			return err
+		}
+	} else {
+		if m.mu.activeKey, err = m.getKeyLocked(ctx, m.mu.registry.ActiveStoreKeyId); err != nil {
+			return err
+		}
+	}
+	log.Infof(ctx, "loaded active store key: %s", proto.CompactTextString(m.mu.activeKey.Info))
+	return nil
+}
+
+// Close releases all of the manager's held resources.
+func (m *KMSStoreKeyManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return errors.CombineErrors(m.mu.marker.Close(), m.kms.Close())
+}
+
+// ActiveKey implements PebbleKeyManager.ActiveKey.
+func (m *KMSStoreKeyManager) ActiveKey(ctx context.Context) (*enginepbccl.SecretKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mu.activeKey, nil
+}
+
+// GetKey implements PebbleKeyManager.GetKey.
+func (m *KMSStoreKeyManager) GetKey(id string) (*enginepbccl.SecretKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.getKeyLocked(context.TODO(), id)
+}
+
+// Rotate generates a new store key, wraps it with the KMS, and makes it the
+// active store key. It returns the information of the new key, which must
+// then be passed to DataKeyManager.SetActiveStoreKeyInfo to re-encrypt the
+// data keys.
+func (m *KMSStoreKeyManager) Rotate(ctx context.Context) (*enginepbccl.KeyInfo, error) {
+	if m.readOnly {
+		return nil, errors.New("read only")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rotateLocked(ctx)
+}
+
+// REQUIRES: m.mu is held.
+func (m *KMSStoreKeyManager) getKeyLocked(
+	ctx context.Context, id string,
+) (*enginepbccl.SecretKey, error) {
+	if key, ok := m.mu.unwrapped[id]; ok {
+		return key, nil
+	}
+	if m.mu.oldKey != nil && m.mu.oldKey.Info.KeyId == id {
+		return m.mu.oldKey, nil
+	}
+	wrapped, ok := m.mu.registry.StoreKeys[id]
+	if !ok {
+		return nil, fmt.Errorf("store key ID %s was not found", id)
+	}
+	raw, err := m.kms.Decrypt(ctx, wrapped.WrappedKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unwrapping store key ID %s with KMS master key %s",
+			id, wrapped.KmsMasterKeyId)
+	}
+	key := &enginepbccl.SecretKey{Info: wrapped.Info, Key: raw}
+	m.mu.unwrapped[id] = key
+	return key, nil
+}
+
+// REQUIRES: m.mu is held.
+func (m *KMSStoreKeyManager) rotateLocked(ctx context.Context) (*enginepbccl.KeyInfo, error) {
+	key := &enginepbccl.SecretKey{
+		Info: &enginepbccl.KeyInfo{
+			EncryptionType: kmsStoreKeyEncryptionType,
+			CreationTime:   kmTimeNow().Unix(),
+			Source:         kmsStoreKeySource,
+		},
+	}
+	if err := generateRandomKey(ctx, key); err != nil {
+		return nil, err
+	}
+	masterKeyID, err := m.kms.MasterKeyID()
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := m.kms.Encrypt(ctx, key.Key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "wrapping store key with KMS master key %s", masterKeyID)
+	}
+
+	registry := &enginepbccl.StoreKeysRegistry{}
+	proto.Merge(registry, m.mu.registry)
+	if registry.StoreKeys == nil {
+		registry.StoreKeys = make(map[string]*enginepbccl.WrappedStoreKey)
+	}
+	registry.StoreKeys[key.Info.KeyId] = &enginepbccl.WrappedStoreKey{
+		Info:           key.Info,
+		WrappedKey:     wrappedKey,
+		KmsMasterKeyId: masterKeyID,
+	}
+	registry.ActiveStoreKeyId = key.Info.KeyId
+	if err := m.writeRegistryLocked(registry); err != nil {
+		return nil, err
+	}
+	m.mu.unwrapped[key.Info.KeyId] = key
+	m.mu.activeKey = key
+	log.Infof(ctx, "rotated to new active store key: %s", proto.CompactTextString(key.Info))
+	return key.Info, nil
+}
+
+// writeRegistryLocked persists the registry and makes it the current one.
+//
+// REQUIRES: m.mu is held.
+func (m *KMSStoreKeyManager) writeRegistryLocked(registry *enginepbccl.StoreKeysRegistry) error {
+	b, err := protoutil.Marshal(registry)
+	if err != nil {
+		return err
+	}
+	// See DataKeyManager.rotateDataKeyAndWrite.
+	filename := fmt.Sprintf("%s_%06d_%s", storeKeysRegistryFilename, m.mu.marker.NextIter(), registryFormatMonolith)
+	f, err := m.fs.Create(m.fs.PathJoin(m.dbDir, filename))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(b); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := m.mu.marker.Move(filename); err != nil {
+		return err
+	}
+
+	prevFilename := m.mu.filename
+	m.mu.filename = filename
+	m.mu.registry = registry
+	if prevFilename != "" {
+		path := m.fs.PathJoin(m.dbDir, prevFilename)
+		if err := m.fs.Remove(path); err != nil && !oserror.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeKMSEnv is the cloud.KMSEnv used to wrap store keys. Stores are opened
+// before the node can serve SQL, so the environment has no database handle,
+// and external connections (external://) cannot be used as KMS URIs.
+type storeKMSEnv struct {
+	settings *cluster.Settings
+}
+
+var _ cloud.KMSEnv = &storeKMSEnv{}
+
+// ClusterSettings implements the cloud.KMSEnv interface.
+func (e *storeKMSEnv) ClusterSettings() *cluster.Settings {
+	return e.settings
+}
+
+// KMSConfig implements the cloud.KMSEnv interface.
+func (e *storeKMSEnv) KMSConfig() *base.ExternalIODirConfig {
+	return &base.ExternalIODirConfig{}
+}
+
+// DBHandle implements the cloud.KMSEnv interface.
+func (e *storeKMSEnv) DBHandle() isql.DB {
+	return nil
+}
+
+// User implements the cloud.KMSEnv interface.
+func (e *storeKMSEnv) User() username.SQLUsername {
+	return username.NodeUserName()
+}
@@ -0,0 +1,228 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package engineccl
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/baseccl"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl/engineccl/enginepbccl"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+const testKMSScheme = "engineccl-test-kms"
+
+// testKMS is a cloud.KMS which "wraps" keys by reversing them and prefixing
+// them with its master key ID.
+type testKMS struct {
+	masterKeyID string
+}
+
+var _ cloud.KMS = &testKMS{}
+
+func init() {
+	cloud.RegisterKMSFromURIFactory(
+		func(ctx context.Context, uri string, env cloud.KMSEnv) (cloud.KMS, error) {
+			return &testKMS{masterKeyID: uri}, nil
+		}, testKMSScheme)
+}
+
+func (k *testKMS) MasterKeyID() (string, error) {
+	return k.masterKeyID, nil
+}
+
+func (k *testKMS) Encrypt(ctx context.Context, data []byte) ([]byte, error) {
+	return append([]byte(k.masterKeyID), reverse(data)...), nil
+}
+
+func (k *testKMS) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(k.masterKeyID)) {
+		return nil, errors.Newf("not wrapped by %s", k.masterKeyID)
+	}
+	return reverse(data[len(k.masterKeyID):]), nil
+}
+
+func (k *testKMS) Close() error {
+	return nil
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+func TestKMSStoreKeyManager(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	memFS := vfs.NewMem()
+	require.NoError(t, memFS.MkdirAll("/data", os.ModePerm))
+	kms := &testKMS{masterKeyID: "master-1"}
+
+	// A read-only store must already have a store key.
+	skm := &KMSStoreKeyManager{fs: memFS, dbDir: "/data", kms: kms, readOnly: true}
+	require.EqualError(t, skm.Load(ctx), "no store key has been generated for this store")
+
+	// The first store key is generated on the first run.
+	skm = &KMSStoreKeyManager{fs: memFS, dbDir: "/data", kms: kms}
+	require.NoError(t, skm.Load(ctx))
+	key1, err := skm.ActiveKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, enginepbccl.EncryptionType_AES256_CTR, key1.Info.EncryptionType)
+	require.Len(t, key1.Key, 32)
+	require.Len(t, key1.Info.KeyId, 2*keyIDLength)
+	require.NoError(t, skm.Close())
+
+	// The key is persisted wrapped, and unwrapped on the next run.
+	skm = &KMSStoreKeyManager{fs: memFS, dbDir: "/data", kms: kms}
+	require.NoError(t, skm.Load(ctx))
+	key, err := skm.ActiveKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, key1.String(), key.String())
+	require.Equal(t, key1.Info.KeyId, skm.mu.registry.ActiveStoreKeyId)
+	wrapped := skm.mu.registry.StoreKeys[key1.Info.KeyId]
+	require.Equal(t, "master-1", wrapped.KmsMasterKeyId)
+	require.NotContains(t, string(wrapped.WrappedKey), string(key1.Key))
+
+	// Rotation generates a new active key; the previous one remains available.
+	info, err := skm.Rotate(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, key1.Info.KeyId, info.KeyId)
+	key2, err := skm.ActiveKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, info.KeyId, key2.Info.KeyId)
+	key, err = skm.GetKey(key1.Info.KeyId)
+	require.NoError(t, err)
+	require.Equal(t, key1.String(), key.String())
+	_, err = skm.GetKey("x")
+	require.EqualError(t, err, "store key ID x was not found")
+	require.NoError(t, skm.Close())
+
+	// Only the latest registry file is kept.
+	files, err := memFS.List("/data")
+	require.NoError(t, err)
+	var registryFiles int
+	for _, f := range files {
+		if bytes.HasPrefix([]byte(f), []byte(storeKeysRegistryFilename)) {
+			registryFiles++
+		}
+	}
+	require.Equal(t, 1, registryFiles)
+
+	// Previous keys are unwrapped on demand after a restart, and can't be
+	// unwrapped by a different KMS.
+	skm = &KMSStoreKeyManager{fs: memFS, dbDir: "/data", kms: kms, readOnly: true}
+	require.NoError(t, skm.Load(ctx))
+	key, err = skm.ActiveKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, key2.String(), key.String())
+	key, err = skm.GetKey(key1.Info.KeyId)
+	require.NoError(t, err)
+	require.Equal(t, key1.String(), key.String())
+	_, err = skm.Rotate(ctx)
+	require.EqualError(t, err, "read only")
+	require.NoError(t, skm.Close())
+
+	skm = &KMSStoreKeyManager{fs: memFS, dbDir: "/data", kms: &testKMS{masterKeyID: "master-2"}}
+	require.ErrorContains(t, skm.Load(ctx), "not wrapped by master-2")
+}
+
+func TestKMSEncryptedEnvRotation(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	memFS := vfs.NewMem()
+	require.NoError(t, memFS.MkdirAll("/data", os.ModePerm))
+	optionBytes, err := protoutil.Marshal(&baseccl.EncryptionOptions{
+		KeySource: baseccl.EncryptionKeySource_KMS,
+		Kms:       &baseccl.EncryptionKMS{Uri: testKMSScheme + ":///master"},
+		// Avoid rotating the data key other than when the store key changes.
+		DataKeyRotationPeriod: 3600,
+	})
+	require.NoError(t, err)
+
+	openEnv := func() (*storage.PebbleFileRegistry, *storage.EncryptionEnv) {
+		fr := &storage.PebbleFileRegistry{FS: memFS, DBDir: "/data"}
+		require.NoError(t, fr.Load(ctx))
+		env, err := newEncryptedEnv(memFS, fr, "/data", false, optionBytes, nil /* st */)
+		require.NoError(t, err)
+		require.NotNil(t, env.KeyRotator)
+		return fr, env
+	}
+	status := func(env *storage.EncryptionEnv) *enginepbccl.EncryptionStatus {
+		b, err := env.StatsHandler.GetEncryptionStatus()
+		require.NoError(t, err)
+		var s enginepbccl.EncryptionStatus
+		require.NoError(t, protoutil.Unmarshal(b, &s))
+		return &s
+	}
+
+	fr, env := openEnv()
+	f, err := env.FS.Create("/data/foo")
+	require.NoError(t, err)
+	_, err = f.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s := status(env)
+	require.Len(t, s.StoreKeys, 1)
+	require.Equal(t, s.ActiveStoreKey.KeyId, s.StoreKeys[0].Key.KeyId)
+	require.EqualValues(t, 1, s.StoreKeys[0].Files)
+	require.Len(t, s.DataKeys, 1)
+	require.EqualValues(t, 1, s.DataKeys[0].Files)
+	oldStoreKeyID := s.ActiveStoreKey.KeyId
+	oldDataKeyID := s.ActiveDataKey.KeyId
+
+	// After a rotation, the old store key is no longer in use, and the old data
+	// key is only in use by the file it encrypted.
+	newStoreKeyID, err := env.KeyRotator.RotateStoreKey(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, oldStoreKeyID, newStoreKeyID)
+	s = status(env)
+	require.Equal(t, newStoreKeyID, s.ActiveStoreKey.KeyId)
+	require.NotEqual(t, oldDataKeyID, s.ActiveDataKey.KeyId)
+	files := make(map[string]uint64)
+	for _, k := range append(s.StoreKeys, s.DataKeys...) {
+		files[k.Key.KeyId] = k.Files
+	}
+	require.Equal(t, map[string]uint64{
+		oldStoreKeyID:         0,
+		newStoreKeyID:         1,
+		oldDataKeyID:          1,
+		s.ActiveDataKey.KeyId: 0,
+	}, files)
+	require.NoError(t, env.Closer.Close())
+	require.NoError(t, fr.Close())
+
+	// The rotated keys are used after a restart, and existing files remain
+	// readable.
+	fr, env = openEnv()
+	require.Equal(t, newStoreKeyID, status(env).ActiveStoreKey.KeyId)
+	f, err = env.FS.Open("/data/foo")
+	require.NoError(t, err)
+	b := make([]byte, 5)
+	_, err = f.ReadAt(b, 0)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(b))
+	require.NoError(t, f.Close())
+	require.NoError(t, env.Closer.Close())
+	require.NoError(t, fr.Close())
+}
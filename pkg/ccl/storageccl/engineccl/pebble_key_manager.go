@@ -54,7 +54,7 @@ const (
 	registryFormatMonolith registryFormat = "monolith"
 )
 
-// PebbleKeyManager manages encryption keys. There are two implementations. See encrypted_fs.go for
+// PebbleKeyManager manages encryption keys. There are three implementations. See encrypted_fs.go for
 // high-level context.
 type PebbleKeyManager interface {
 	// ActiveKey returns the currently active key. If plaintext should be used it can return nil or
@@ -66,6 +66,7 @@ type PebbleKeyManager interface {
 }
 
 var _ PebbleKeyManager = &StoreKeyManager{}
+var _ PebbleKeyManager = &KMSStoreKeyManager{}
 var _ PebbleKeyManager = &DataKeyManager{}
 
 // Overridden for testing.
@@ -374,35 +375,9 @@ func generateAndSetNewDataKey(
 		key.Info.KeyId = plainKeyID
 		key.Info.WasExposed = true
 	} else {
-		var keyLength int
-		switch activeStoreKey.EncryptionType {
-		case enginepbccl.EncryptionType_AES128_CTR:
-			keyLength = 16
-		case enginepbccl.EncryptionType_AES192_CTR:
-			keyLength = 24
-		case enginepbccl.EncryptionType_AES256_CTR:
-			keyLength = 32
-		default:
-			return nil, fmt.Errorf("unknown encryption type %d for key ID %s",
-				activeStoreKey.EncryptionType, activeStoreKey.KeyId)
+		if err := generateRandomKey(ctx, key); err != nil {
+			return nil, errors.Wrapf(err, "generating data key for store key ID %s", activeStoreKey.KeyId)
 		}
-		key.Key = make([]byte, keyLength)
-		n, err := rand.Read(key.Key)
-		if err != nil {
-			return nil, err
-		}
-		if n != keyLength {
-			log.Fatalf(ctx, "rand.Read returned no error but fewer bytes %d than promised %d", n, keyLength)
-		}
-		keyID := make([]byte, keyIDLength)
-		if n, err = rand.Read(keyID); err != nil {
-			return nil, err
-		}
-		if n != keyIDLength {
-			log.Fatalf(ctx, "rand.Read returned no error but fewer bytes %d than promised %d", n, keyIDLength)
-		}
-		// Hex encoding to make it human readable.
-		key.Info.KeyId = hex.EncodeToString(keyID)
 		key.Info.WasExposed = false
 	}
 	keyRegistry.DataKeys[key.Info.KeyId] = key
@@ -410,6 +385,41 @@ func generateAndSetNewDataKey(
 	return key, nil
 }
 
+// generateRandomKey sets the raw key and the key ID of the given key to random
+// values. The key's encryption type must already be set to one of the AES
+// types, which determines the length of the raw key.
+func generateRandomKey(ctx context.Context, key *enginepbccl.SecretKey) error {
+	var keyLength int
+	switch key.Info.EncryptionType {
+	case enginepbccl.EncryptionType_AES128_CTR:
+		keyLength = 16
+	case enginepbccl.EncryptionType_AES192_CTR:
+		keyLength = 24
+	case enginepbccl.EncryptionType_AES256_CTR:
+		keyLength = 32
+	default:
+		return fmt.Errorf("unknown encryption type %d", key.Info.EncryptionType)
+	}
+	key.Key = make([]byte, keyLength)
+	n, err := rand.Read(key.Key)
+	if err != nil {
+		return err
+	}
+	if n != keyLength {
+		log.Fatalf(ctx, "rand.Read returned no error but fewer bytes %d than promised %d", n, keyLength)
+	}
+	keyID := make([]byte, keyIDLength)
+	if n, err = rand.Read(keyID); err != nil {
+		return err
+	}
+	if n != keyIDLength {
+		log.Fatalf(ctx, "rand.Read returned no error but fewer bytes %d than promised %d", n, keyIDLength)
+	}
+	// Hex encoding to make it human readable.
+	key.Info.KeyId = hex.EncodeToString(keyID)
+	return nil
+}
+
 // REQUIRES: m.mu is held.
 func (m *DataKeyManager) rotateDataKeyAndWrite(
 	ctx context.Context, keyRegistry *enginepbccl.DataKeysRegistry,
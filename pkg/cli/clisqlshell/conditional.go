@@ -0,0 +1,147 @@
+// Copyright 2026 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package clisqlshell
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/cli/clisqlclient"
+	"github.com/cockroachdb/errors"
+)
+
+// condState is the state of one level of \if ... \endif nesting.
+// This mirrors the ifState enum in psql's conditional.h.
+type condState int
+
+const (
+	// condTrue: the current branch is active.
+	condTrue condState = iota
+	// condFalse: the current branch is inactive, and no branch has
+	// been active so far. A subsequent \elif or \else may become active.
+	condFalse
+	// condIgnored: the current branch is inactive, and no subsequent
+	// branch can become active. This happens either after an active
+	// branch was completed, or when the entire \if block is nested
+	// inside an inactive branch.
+	condIgnored
+	// condElseTrue: the \else branch is active.
+	condElseTrue
+	// condElseFalse: the \else branch is inactive.
+	condElseFalse
+)
+
+// condStack tracks the nesting of \if blocks in one input source.
+// Like in psql, \if blocks cannot span multiple files.
+type condStack []condState
+
+// active returns true iff the input should currently be processed.
+func (s condStack) active() bool {
+	if len(s) == 0 {
+		return true
+	}
+	top := s[len(s)-1]
+	return top == condTrue || top == condElseTrue
+}
+
+// isConditionalCmd returns true iff cmd is one of the commands that
+// must be processed even in an inactive branch.
+func isConditionalCmd(cmd string) bool {
+	switch cmd {
+	case `\if`, `\elif`, `\else`, `\endif`:
+		return true
+	}
+	return false
+}
+
+// evalCondition evaluates the argument of \if or \elif.
+func evalCondition(cmd string, args []string) (bool, error) {
+	expr := strings.Join(args, " ")
+	b, err := clisqlclient.ParseBool(expr)
+	if err != nil {
+		return false, errors.WithHint(
+			errors.Newf("%s: unrecognized value %q: boolean expected", cmd, expr),
+			"Possible values: true, false, on, off, yes, no, 1, 0.")
+	}
+	return b, nil
+}
+
+// handleConditional supports the \if, \elif, \else and \endif
+// client-side commands.
+func (c *cliState) handleConditional(
+	cmd []string, nextState, errState cliStateEnum,
+) cliStateEnum {
+	if cmd[0] == `\if` || cmd[0] == `\elif` {
+		if len(cmd) < 2 {
+			return c.invalidSyntax(errState)
+		}
+	} else if len(cmd) > 1 {
+		return c.invalidSyntax(errState)
+	}
+
+	// eval evaluates the condition, and reports an evaluation error
+	// to the user. Like in psql, an expression that cannot be
+	// evaluated is considered to be false.
+	var evalErr error
+	eval := func() condState {
+		b, err := evalCondition(cmd[0], cmd[1:])
+		if err != nil {
+			evalErr = err
+			return condFalse
+		}
+		if b {
+			return condTrue
+		}
+		return condFalse
+	}
+
+	if cmd[0] == `\if` {
+		if c.conds.active() {
+			c.conds = append(c.conds, eval())
+		} else {
+			// Nested inside an inactive branch: the condition is not
+			// evaluated at all.
+			c.conds = append(c.conds, condIgnored)
+		}
+	} else {
+		if len(c.conds) == 0 {
+			return c.cliError(errState, errors.Newf(`%s: no matching \if`, cmd[0]))
+		}
+		top := &c.conds[len(c.conds)-1]
+		switch cmd[0] {
+		case `\elif`:
+			switch *top {
+			case condTrue:
+				*top = condIgnored
+			case condFalse:
+				*top = eval()
+			case condIgnored:
+			default:
+				return c.cliError(errState, errors.New(`\elif: cannot occur after \else`))
+			}
+		case `\else`:
+			switch *top {
+			case condTrue, condIgnored:
+				*top = condElseFalse
+			case condFalse:
+				*top = condElseTrue
+			default:
+				return c.cliError(errState, errors.New(`\else: cannot occur after \else`))
+			}
+		case `\endif`:
+			c.conds = c.conds[:len(c.conds)-1]
+		}
+	}
+
+	if evalErr != nil {
+		return c.cliError(errState, evalErr)
+	}
+	return nextState
+}
@@ -115,6 +115,14 @@ type internalContext struct {
 
 	statementWrappers []statementWrapper
 
+	// vars contains the client-side variables set via \set or \gset,
+	// for interpolation in SQL statements and client-side commands.
+	vars map[string]string
+
+	// lastQuery is the last query sent to the server. It is reused
+	// by \gset, \gexec and \watch when the query buffer is empty.
+	lastQuery string
+
 	// state about the current query.
 	mu struct {
 		syncutil.Mutex
@@ -0,0 +1,277 @@
+// Copyright 2026 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package clisqlshell
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/cli/clisqlclient"
+	"github.com/cockroachdb/cockroach/pkg/cli/clisqlexec"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+// defaultWatchInterval is the interval used by \watch when
+// none is specified. This is the same default as psql.
+const defaultWatchInterval = 2 * time.Second
+
+// handleQueryBufferCmd supports the client-side commands that run the
+// current query buffer: \gset, \gexec and \watch. If the query buffer
+// is empty, the last query sent to the server is used instead, like
+// in psql.
+func (c *cliState) handleQueryBufferCmd(
+	cmd []string, query string, nextState, errState cliStateEnum,
+) cliStateEnum {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \r\n\t\f")
+	if query == "" {
+		query = c.iCtx.lastQuery
+	} else {
+		query = interpolateVariables(query, c.iCtx.vars)
+	}
+	if query == "" {
+		return c.cliError(errState, errors.Newf(`%s: no query to run`, cmd[0]))
+	}
+	c.iCtx.lastQuery = query
+
+	switch cmd[0] {
+	case `\gset`:
+		return c.handleGset(cmd[1:], query, nextState, errState)
+	case `\gexec`:
+		return c.handleGexec(cmd[1:], query, nextState, errState)
+	case `\watch`:
+		return c.handleWatch(cmd[1:], query, nextState, errState)
+	}
+	return c.invalidSyntax(errState)
+}
+
+// handleGset supports the \gset client-side command. It runs the
+// query and stores the values of the single result row into
+// client-side variables named after the result columns, optionally
+// with a prefix.
+func (c *cliState) handleGset(
+	args []string, query string, nextState, errState cliStateEnum,
+) cliStateEnum {
+	if len(args) > 1 {
+		return c.invalidSyntax(errState)
+	}
+	var prefix string
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+
+	cols, rows, err := c.runQueryRaw(query)
+	if err != nil {
+		return c.cliError(errState, err)
+	}
+	switch {
+	case len(cols) == 0 || len(rows) == 0:
+		return c.cliError(errState, errors.New(`\gset: no rows returned`))
+	case len(rows) > 1:
+		return c.cliError(errState, errors.Newf(`\gset: more than one row returned (%d rows)`, len(rows)))
+	}
+
+	for i, col := range cols {
+		name := prefix + col
+		if !isValidVarName(name) {
+			return c.cliError(errState, errors.WithHint(
+				errors.Newf(`\gset: invalid variable name: %q`, name),
+				"Use a column alias to choose the variable name."))
+		}
+		if _, ok := options[name]; ok {
+			return c.cliError(errState, errors.Newf(
+				`\gset: cannot overwrite client-side option %q`, name))
+		}
+		if rows[0][i] == nil {
+			// Like in psql, a NULL value unsets the variable.
+			delete(c.iCtx.vars, name)
+			continue
+		}
+		c.iCtx.vars[name] = formatRawVal(rows[0][i])
+	}
+	return nextState
+}
+
+// handleGexec supports the \gexec client-side command. It runs the
+// query, then runs every non-NULL value in its result as a SQL
+// statement, in row order then column order.
+func (c *cliState) handleGexec(
+	args []string, query string, nextState, errState cliStateEnum,
+) cliStateEnum {
+	if len(args) > 0 {
+		return c.invalidSyntax(errState)
+	}
+
+	_, rows, err := c.runQueryRaw(query)
+	if err != nil {
+		return c.cliError(errState, err)
+	}
+
+	for _, row := range rows {
+		for _, val := range row {
+			if val == nil {
+				continue
+			}
+			c.concatLines = formatRawVal(val)
+			if state := c.doRunStatements(nextState); state == cliStop {
+				return cliStop
+			}
+		}
+	}
+	c.iCtx.lastQuery = query
+	return nextState
+}
+
+// handleWatch supports the \watch client-side command. It runs the
+// query repeatedly until interrupted, an error occurs, or the
+// requested number of iterations is reached.
+func (c *cliState) handleWatch(
+	args []string, query string, nextState, errState cliStateEnum,
+) cliStateEnum {
+	interval, count, err := parseWatchArgs(args)
+	if err != nil {
+		return c.cliError(errState, err)
+	}
+
+	for i := 0; count == 0 || i < count; i++ {
+		if i > 0 && c.sleepInterruptible(interval) {
+			break
+		}
+		if c.sqlExecCtx.TerminalOutput {
+			// Like psql, show when the query ran. We do not do this for
+			// non-terminal output, to keep the output of scripts
+			// deterministic.
+			fmt.Fprintf(c.iCtx.queryOutput, "%s (every %s)\n\n",
+				timeutil.Now().Format(time.RFC1123), interval)
+		}
+		if err := c.runWithInterruptableCtx(func(ctx context.Context) error {
+			defer c.maybeFlushOutput()
+			return c.sqlExecCtx.RunQueryAndFormatResults(
+				ctx,
+				c.conn,
+				c.iCtx.queryOutput, // query output.
+				c.iCtx.stdout,      // timings.
+				c.iCtx.stderr,
+				clisqlclient.MakeQuery(query),
+			)
+		}); err != nil {
+			return c.cliError(errState, err)
+		}
+	}
+	return nextState
+}
+
+// parseWatchArgs parses the arguments to \watch. Like in psql, the
+// arguments can be either "[interval]" or
+// "[i[nterval]=SECONDS] [c[ount]=TIMES]". A count of zero means to
+// repeat indefinitely.
+func parseWatchArgs(args []string) (interval time.Duration, count int, err error) {
+	interval = defaultWatchInterval
+	parseInterval := func(s string) error {
+		secs, err := strconv.ParseFloat(s, 64)
+		if err != nil || secs <= 0 {
+			return errors.Newf(`\watch: invalid interval: %q`, s)
+		}
+		interval = time.Duration(secs * float64(time.Second))
+		return nil
+	}
+	for _, arg := range args {
+		name, val, hasName := strings.Cut(arg, "=")
+		switch {
+		case !hasName:
+			err = parseInterval(arg)
+		case name == "i" || name == "interval":
+			err = parseInterval(val)
+		case name == "c" || name == "count":
+			count, err = strconv.Atoi(val)
+			if err != nil || count <= 0 {
+				err = errors.Newf(`\watch: invalid count: %q`, val)
+			}
+		default:
+			err = errors.Newf(`\watch: unrecognized parameter: %q`, arg)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return interval, count, nil
+}
+
+// sleepInterruptible waits for the specified duration. It returns
+// true if the wait was interrupted by the user with Ctrl+C.
+func (c *cliState) sleepInterruptible(d time.Duration) (interrupted bool) {
+	if !c.cliCtx.IsInteractive {
+		time.Sleep(d)
+		return false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan struct{})
+	defer func() { close(doneCh) }()
+
+	// Inform the Ctrl+C handler that it can interrupt the wait.
+	c.iCtx.mu.Lock()
+	c.iCtx.mu.cancelFn = func(context.Context) error { cancel(); return nil }
+	c.iCtx.mu.doneCh = doneCh
+	c.iCtx.mu.Unlock()
+	defer func() {
+		c.iCtx.mu.Lock()
+		cancel()
+		c.iCtx.mu.cancelFn = nil
+		c.iCtx.mu.doneCh = nil
+		c.iCtx.mu.Unlock()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+// runQueryRaw runs the given query and returns the values of its
+// first result set without formatting them, so that NULL values can
+// be distinguished from the string "NULL".
+func (c *cliState) runQueryRaw(query string) (cols []string, rows [][]driver.Value, err error) {
+	err = c.runWithInterruptableCtx(func(ctx context.Context) (resErr error) {
+		r, err := c.conn.Query(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer func() { resErr = errors.CombineErrors(resErr, r.Close()) }()
+		cols = r.Columns()
+		for {
+			vals := make([]driver.Value, len(cols))
+			if err := r.Next(vals); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+			rows = append(rows, vals)
+		}
+	})
+	return cols, rows, err
+}
+
+// formatRawVal formats a non-NULL value returned by runQueryRaw.
+func formatRawVal(val driver.Value) string {
+	if s, ok := val.(string); ok {
+		// Strings are used as-is, including any special characters.
+		return s
+	}
+	return clisqlexec.FormatVal(val, true /* showPrintableUnicode */, true /* showNewLinesAndTabs */)
+}
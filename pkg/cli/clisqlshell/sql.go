@@ -64,6 +64,16 @@ Query Buffer
   \p                during a multi-line statement, show the SQL entered so far.
   \r                during a multi-line statement, erase all the SQL entered so far.
   \| CMD            run an external command and run its output as SQL statements.
+  \gset [PREFIX]    run the query buffer and store its result columns in variables.
+  \gexec            run the query buffer, then run each value in its result as a statement.
+  \watch [[i=]SEC] [c=N]
+                    run the query buffer repeatedly, every SEC seconds (default 2).
+
+Conditionals
+  \if EXPR          begin a conditional block.
+  \elif EXPR        alternative within the current conditional block.
+  \else             final alternative within the current conditional block.
+  \endif            end the current conditional block.
 
 Connection
   \info             display server details including connection strings.
@@ -109,8 +119,11 @@ Operating System
   \! CMD            run an external command and print its results on standard output.
 
 Configuration
-  \set [NAME]       set a client-side flag or (without argument) print the current settings.
-  \unset NAME       unset a flag.
+  \set [NAME [VALUE]]
+                    set a client-side flag or variable, or (without argument) print the current settings.
+                    Variables are substituted in input using :NAME, :'NAME' (as a string literal),
+                    :"NAME" (as an identifier) and :{?NAME} (whether the variable is defined).
+  \unset NAME       unset a flag or variable.
 
 Statement diagnostics
   \statement-diag list                               list available bundles.
@@ -168,6 +181,9 @@ type cliState struct {
 	// includes (\ir) resolve the file name.
 	includeDir string
 
+	// conds tracks the \if blocks in the current input source.
+	conds condStack
+
 	// The prompt at the beginning of a multi-line entry.
 	fullPrompt string
 	// The prompt on a continuation line in a multi-line entry.
@@ -608,6 +624,25 @@ func (c *cliState) handleSet(
 			panic(err)
 		}
 
+		if len(c.iCtx.vars) > 0 {
+			varNames := make([]string, 0, len(c.iCtx.vars))
+			for n := range c.iCtx.vars {
+				varNames = append(varNames, n)
+			}
+			sort.Strings(varNames)
+			varData := make([][]string, 0, len(varNames))
+			for _, n := range varNames {
+				varData = append(varData, []string{n, c.iCtx.vars[n]})
+			}
+			fmt.Fprintln(c.iCtx.stdout)
+			err := c.sqlExecCtx.PrintQueryOutput(c.iCtx.stdout, c.iCtx.stderr,
+				[]string{"Variable", "Value"},
+				clisqlexec.NewRowSliceIter(varData, "ll" /*align*/))
+			if err != nil {
+				panic(err)
+			}
+		}
+
 		return nextState
 	}
 
@@ -618,7 +653,14 @@ func (c *cliState) handleSet(
 
 	opt, ok := options[optName]
 	if !ok {
-		return c.cliError(errState, errors.Newf("unknown variable name: %q", optName))
+		// Not a client-side option: this defines a variable. Like in
+		// psql, the value is used as-is and defaults to the empty
+		// string.
+		if !isValidVarName(optName) {
+			return c.cliError(errState, errors.Newf("invalid variable name: %q", optName))
+		}
+		c.iCtx.vars[optName] = val
+		return nextState
 	}
 	if len(c.partialLines) > 0 && !opt.validDuringMultilineEntry {
 		return c.invalidOptionChange(errState, optName)
@@ -661,7 +703,11 @@ func (c *cliState) handleUnset(args []string, nextState, errState cliStateEnum)
 	}
 	opt, ok := options[args[0]]
 	if !ok {
-		return c.cliError(errState, errors.Newf("unknown variable name: %q", args[0]))
+		if !isValidVarName(args[0]) {
+			return c.cliError(errState, errors.Newf("invalid variable name: %q", args[0]))
+		}
+		delete(c.iCtx.vars, args[0])
+		return nextState
 	}
 	if len(c.partialLines) > 0 && !opt.validDuringMultilineEntry {
 		return c.invalidOptionChange(errState, args[0])
@@ -1392,6 +1438,10 @@ func (c *cliState) handleDescribe(cmd []string, loopState, errState cliStateEnum
 
 func (c *cliState) doHandleCliCmd(loopState, nextState cliStateEnum) cliStateEnum {
 	if len(c.lastInputLine) == 0 || c.lastInputLine[0] != '\\' {
+		if !c.conds.active() {
+			// Inside an inactive \if branch: skip the input.
+			return loopState
+		}
 		return nextState
 	}
 
@@ -1410,6 +1460,17 @@ func (c *cliState) doHandleCliCmd(loopState, nextState cliStateEnum) cliStateEnu
 	// any, in all cases.
 	line := strings.TrimRight(c.lastInputLine, "; ")
 
+	if !c.conds.active() {
+		// Inside an inactive \if branch, only the commands that
+		// delimit conditional blocks are processed.
+		cmd, err := scanLocalCmdArgs(line)
+		if err != nil || !isConditionalCmd(cmd[0]) {
+			return loopState
+		}
+		return c.handleConditional(cmd, loopState, errState)
+	}
+
+	line = interpolateVariables(line, c.iCtx.vars)
 	cmd, err := scanLocalCmdArgs(line)
 	if err != nil {
 		return c.cliError(cliStartLine, err)
@@ -1442,6 +1503,13 @@ func (c *cliState) doHandleCliCmd(loopState, nextState cliStateEnum) cliStateEnu
 	case `\set`:
 		return c.handleSet(line, cmd[1:], loopState, errState)
 
+	case `\if`, `\elif`, `\else`, `\endif`:
+		return c.handleConditional(cmd, loopState, errState)
+
+	case `\gset`, `\gexec`, `\watch`:
+		// These commands consume the query buffer, if any.
+		return c.handleQueryBufferCmd(cmd, strings.Join(c.partialLines, "\n"), cliStartLine, cliStartLine)
+
 	case `\unset`:
 		return c.handleUnset(cmd[1:], loopState, errState)
 
@@ -1944,6 +2012,25 @@ func (c *cliState) runIncludeInternal(
 func (c *cliState) doPrepareStatementLine(
 	startState, contState, checkState, execState cliStateEnum,
 ) cliStateEnum {
+	if !c.inCopy() {
+		// Is the input terminated by a client-side command that runs
+		// the query buffer, as in "SELECT 1 AS x \gset"?
+		text, start := c.lastInputLine, 0
+		if len(c.partialLines) > 0 {
+			prefix := strings.Join(c.partialLines, "\n")
+			text, start = prefix+"\n"+c.lastInputLine, len(prefix)+1
+		}
+		if pos, ok := findQueryBufferCmd(text, start); ok {
+			c.addHistory(text)
+			cmdLine := interpolateVariables(strings.TrimRight(text[pos:], "; "), c.iCtx.vars)
+			cmd, err := scanLocalCmdArgs(cmdLine)
+			if err != nil {
+				return c.cliError(startState, err)
+			}
+			return c.handleQueryBufferCmd(cmd, text[:pos], startState, startState)
+		}
+	}
+
 	c.partialLines = append(c.partialLines, c.lastInputLine)
 
 	// We join the statements back together with newlines in case
@@ -1989,9 +2076,11 @@ func (c *cliState) doPrepareStatementLine(
 		return contState
 	}
 
-	// Complete input. Remember it in the history.
+	// Complete input. Remember it in the history, then substitute
+	// client-side variables.
 	if !c.inCopy() {
 		c.addHistory(c.concatLines)
+		c.concatLines = interpolateVariables(c.concatLines, c.iCtx.vars)
 	}
 
 	if !c.iCtx.checkSyntax {
@@ -2103,6 +2192,11 @@ func (c *cliState) doRunStatements(nextState cliStateEnum) cliStateEnum {
 	// status.
 	c.exitErr = nil
 
+	// Remember the statement for \gset, \gexec and \watch.
+	if !c.inCopy() {
+		c.iCtx.lastQuery = c.concatLines
+	}
+
 	// Once we send something to the server, the txn status may change arbitrarily.
 	// Clear the known state so that further entries do not assume anything.
 	c.lastKnownTxnStatus = " ?"
@@ -2223,6 +2317,12 @@ func (c *cliState) beginCopyFrom(ctx context.Context, sql string) error {
 }
 
 func (c *cliState) doDecidePath() cliStateEnum {
+	if !c.conds.active() {
+		// Inside an inactive \if branch, all the input goes through
+		// doHandleCliCmd, which skips everything but the conditional
+		// commands.
+		return cliHandleCliCmd
+	}
 	if len(c.partialLines) == 0 {
 		return cliProcessFirstLine
 	} else if c.cliCtx.IsInteractive {
@@ -2254,6 +2354,7 @@ func NewShell(
 		sqlCtx:     sqlCtx,
 		iCtx: &internalContext{
 			customPromptPattern: defaultPromptPattern,
+			vars:                make(map[string]string),
 		},
 		conn:       conn,
 		includeDir: ".",
@@ -2334,6 +2435,10 @@ func (c *cliState) doRunShell(state cliStateEnum, cmdIn, cmdOut, cmdErr *os.File
 		}
 	}
 
+	if c.atEOF && len(c.conds) > 0 {
+		_ = c.cliError(cliStop, errors.New(`reached end of input without finding closing \endif`))
+	}
+
 	return c.exitErr
 }
 
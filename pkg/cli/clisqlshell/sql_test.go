@@ -131,8 +131,10 @@ func Example_sql_config() {
 	c.RunWithArgs([]string{`sql`, `-e`, `select 123`, `--set`, `display_format=raw`})
 	// Possible to run client-side commands with -e.
 	c.RunWithArgs([]string{`sql`, `-e`, `\set display_format=raw`, `-e`, `select 123 as "123"`})
+	// --set can also define client-side variables, for use in statements.
+	c.RunWithArgs([]string{`sql`, `--set`, `val=123`, `-e`, `select :val as "123"`})
 	// A failure in a client-side command prevents subsequent statements from executing.
-	c.RunWithArgs([]string{`sql`, `--set`, `unknownoption`, `-e`, `select 123 as "123"`})
+	c.RunWithArgs([]string{`sql`, `--set`, `invalid-name`, `-e`, `select 123 as "123"`})
 	c.RunWithArgs([]string{`sql`, `--set`, `display_format=invalidvalue`, `-e`, `select 123 as "123"`})
 	c.RunWithArgs([]string{`sql`, `-e`, `\set display_format=invalidvalue`, `-e`, `select 123 as "123"`})
 
@@ -158,8 +160,11 @@ func Example_sql_config() {
 	// ## 3
 	// 123
 	// # 1 row
-	// sql --set unknownoption -e select 123 as "123"
-	// ERROR: -e: unknown variable name: "unknownoption"
+	// sql --set val=123 -e select :val as "123"
+	// 123
+	// 123
+	// sql --set invalid-name -e select 123 as "123"
+	// ERROR: -e: invalid variable name: "invalid-name"
 	// sql --set display_format=invalidvalue -e select 123 as "123"
 	// ERROR: -e: \set display_format=invalidvalue: invalid table display format: invalidvalue
 	// HINT: Possible values: tsv, csv, table, records, ndjson, json, sql, html, raw.
@@ -351,6 +356,38 @@ func Example_includes() {
 	// ERROR: testdata/i_maxrecursion.sql: testdata/i_maxrecursion.sql: testdata/i_maxrecursion.sql: testdata/i_maxrecursion.sql: testdata/i_maxrecursion.sql: testdata/i_maxrecursion.sql: testdata/i_maxrecursion.sql: testdata/i_maxrecursion.sql: testdata/i_maxrecursion.sql: testdata/i_maxrecursion.sql: \i: too many recursion levels (max 10)
 }
 
+// Example_psql_scripting tests client-side variables, conditionals and
+// the commands that run the query buffer.
+func Example_psql_scripting() {
+	c := cli.NewCLITest(cli.TestCLIParams{})
+	defer c.Cleanup()
+
+	c.RunWithArgs([]string{"sql", "-f", "testdata/psql_scripting.sql"})
+
+	// Output:
+	// sql -f testdata/psql_scripting.sql
+	// CREATE TABLE
+	// INSERT 0 3
+	// rows: 3, max: 3
+	// n is defined
+	// result
+	// many rows
+	// result
+	// scripting_test
+	// generated
+	// 1
+	// generated
+	// 2
+	// generated
+	// 3
+	// tbl
+	// scripting_test
+	// tbl
+	// scripting_test
+	// ERROR: reached end of input without finding closing \endif
+	// ERROR: reached end of input without finding closing \endif
+}
+
 // Example_sql_lex tests the usage of the lexer in the sql subcommand.
 func Example_sql_lex() {
 	c := cli.NewCLITest(cli.TestCLIParams{Insecure: true})
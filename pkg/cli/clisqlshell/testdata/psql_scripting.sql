@@ -0,0 +1,46 @@
+--- input file for Example_psql_scripting.
+
+--- don't report timestamps: it makes the output non-deterministic.
+\unset show_times
+
+\set tbl scripting_test
+CREATE TABLE defaultdb.:"tbl" (x INT);
+INSERT INTO defaultdb.:"tbl" VALUES (1), (2), (3);
+
+-- store query results in variables.
+SELECT count(*) AS n, max(x) AS hi FROM defaultdb.:"tbl" \gset
+\echo rows: :n, max: :hi
+
+-- conditionals.
+\if :{?n}
+\echo n is defined
+\else
+\echo n is not defined
+\endif
+
+SELECT :n > 2 AS many \gset
+\if :many
+SELECT 'many rows' AS result;
+\elif true
+SELECT 'unreachable' AS result;
+\else
+SELECT 'few rows' AS result;
+\endif
+
+\if false
+\if true
+SELECT 'nested, unreachable' AS result;
+\endif
+\else
+SELECT :'tbl' AS result;
+\endif
+
+-- run generated statements.
+SELECT format('SELECT %s AS generated', x) FROM defaultdb.:"tbl" ORDER BY x \gexec
+
+-- re-run a query.
+SELECT :'tbl' AS tbl
+\watch c=2 i=0.01
+
+-- an unterminated conditional block is an error.
+\if true
@@ -0,0 +1,273 @@
+// Copyright 2026 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package clisqlshell
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/lexbase"
+)
+
+// isValidVarName returns true iff name can be used as the name of a
+// client-side variable. Like in psql, variable names are restricted
+// to ASCII letters, digits and underscores.
+func isValidVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isVarNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isVarNameChar(ch byte) bool {
+	return ch == '_' ||
+		(ch >= 'a' && ch <= 'z') ||
+		(ch >= 'A' && ch <= 'Z') ||
+		(ch >= '0' && ch <= '9')
+}
+
+// skipQuotedSQL determines whether a string literal, a quoted
+// identifier or a comment starts at position i in s. If so, it
+// returns the position just after the end of that construct, or
+// len(s) if the construct is not terminated. Otherwise, it returns i.
+//
+// This is a lightweight approximation of the SQL lexer which is
+// sufficient to avoid interpreting the contents of strings, quoted
+// identifiers and comments.
+func skipQuotedSQL(s string, i int) int {
+	switch s[i] {
+	case '\'':
+		// A string literal. If it is preceded by E, backslash escapes
+		// are recognized.
+		escapes := i > 0 && (s[i-1] == 'e' || s[i-1] == 'E') &&
+			(i == 1 || !isVarNameChar(s[i-2]))
+		for j := i + 1; j < len(s); j++ {
+			switch s[j] {
+			case '\\':
+				if escapes {
+					j++
+				}
+			case '\'':
+				if j+1 < len(s) && s[j+1] == '\'' {
+					j++
+					continue
+				}
+				return j + 1
+			}
+		}
+		return len(s)
+
+	case '"':
+		// A quoted identifier.
+		for j := i + 1; j < len(s); j++ {
+			if s[j] == '"' {
+				if j+1 < len(s) && s[j+1] == '"' {
+					j++
+					continue
+				}
+				return j + 1
+			}
+		}
+		return len(s)
+
+	case '-':
+		// A line comment.
+		if i+1 < len(s) && s[i+1] == '-' {
+			if j := strings.IndexByte(s[i:], '\n'); j >= 0 {
+				return i + j + 1
+			}
+			return len(s)
+		}
+
+	case '/':
+		// A block comment. These can be nested.
+		if i+1 < len(s) && s[i+1] == '*' {
+			depth := 0
+			for j := i; j+1 < len(s); j++ {
+				if s[j] == '/' && s[j+1] == '*' {
+					depth++
+					j++
+				} else if s[j] == '*' && s[j+1] == '/' {
+					depth--
+					j++
+					if depth == 0 {
+						return j + 1
+					}
+				}
+			}
+			return len(s)
+		}
+
+	case '$':
+		// A dollar-quoted string: $tag$...$tag$. The tag may be empty
+		// but cannot start with a digit, so that placeholders ($1) are
+		// not confused with dollar quotes.
+		if i > 0 && isVarNameChar(s[i-1]) {
+			return i
+		}
+		j := i + 1
+		for j < len(s) && isVarNameChar(s[j]) {
+			j++
+		}
+		if j >= len(s) || s[j] != '$' || (j > i+1 && s[i+1] >= '0' && s[i+1] <= '9') {
+			return i
+		}
+		delim := s[i : j+1]
+		if k := strings.Index(s[j+1:], delim); k >= 0 {
+			return j + 1 + k + len(delim)
+		}
+		return len(s)
+	}
+	return i
+}
+
+// interpolateVariables substitutes references to client-side
+// variables in the given input. The following syntaxes are
+// recognized, like in psql:
+//
+//	:name    the value of the variable, as-is.
+//	:'name'  the value of the variable, as a SQL string literal.
+//	:"name"  the value of the variable, as a SQL identifier.
+//	:{?name} TRUE if the variable is defined, FALSE otherwise.
+//
+// References to undefined variables are left unchanged, as are
+// references inside string literals, quoted identifiers and comments.
+// The "::" cast operator is also left unchanged.
+func interpolateVariables(s string, vars map[string]string) string {
+	if len(vars) == 0 && !strings.Contains(s, ":{?") {
+		// Fast path: nothing to substitute.
+		return s
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); {
+		if j := skipQuotedSQL(s, i); j > i {
+			buf.WriteString(s[i:j])
+			i = j
+			continue
+		}
+		if s[i] != ':' {
+			buf.WriteByte(s[i])
+			i++
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == ':' {
+			// Cast operator.
+			buf.WriteString("::")
+			i += 2
+			continue
+		}
+		repl, n := interpolateOne(s[i:], vars)
+		if n == 0 {
+			buf.WriteByte(':')
+			i++
+			continue
+		}
+		buf.WriteString(repl)
+		i += n
+	}
+	return buf.String()
+}
+
+// interpolateOne substitutes the variable reference at the start of
+// s, which starts with a colon. It returns the replacement string and
+// the number of bytes consumed in s, or zero if there is no
+// substitution to perform.
+func interpolateOne(s string, vars map[string]string) (repl string, n int) {
+	if len(s) < 2 {
+		return "", 0
+	}
+	switch s[1] {
+	case '\'', '"':
+		end := strings.IndexByte(s[2:], s[1])
+		if end < 0 {
+			return "", 0
+		}
+		name := s[2 : 2+end]
+		val, ok := vars[name]
+		if !isValidVarName(name) || !ok {
+			return "", 0
+		}
+		if s[1] == '\'' {
+			return lexbase.EscapeSQLString(val), end + 3
+		}
+		return lexbase.EscapeSQLIdent(val), end + 3
+
+	case '{':
+		if !strings.HasPrefix(s, ":{?") {
+			return "", 0
+		}
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", 0
+		}
+		name := s[3:end]
+		if !isValidVarName(name) {
+			return "", 0
+		}
+		if _, ok := vars[name]; ok {
+			return "TRUE", end + 1
+		}
+		return "FALSE", end + 1
+
+	default:
+		end := 1
+		for end < len(s) && isVarNameChar(s[end]) {
+			end++
+		}
+		val, ok := vars[s[1:end]]
+		if end == 1 || !ok {
+			return "", 0
+		}
+		return val, end
+	}
+}
+
+// queryBufferCmds are the client-side commands that terminate and
+// execute the current query buffer. They can appear at the end of a
+// line of SQL input, e.g. "SELECT 1 AS x \gset".
+var queryBufferCmds = map[string]struct{}{
+	`\gset`:  {},
+	`\gexec`: {},
+	`\watch`: {},
+}
+
+// findQueryBufferCmd looks for a client-side command that terminates
+// the query buffer in the input text s, at or after position start.
+// If found, it returns the position of the backslash that starts the
+// command. Backslashes inside string literals, quoted identifiers
+// and comments are ignored.
+func findQueryBufferCmd(s string, start int) (pos int, ok bool) {
+	for i := 0; i < len(s); {
+		if j := skipQuotedSQL(s, i); j > i {
+			i = j
+			continue
+		}
+		if s[i] == '\\' && i >= start {
+			end := i + 1
+			for end < len(s) && isVarNameChar(s[end]) {
+				end++
+			}
+			if _, ok := queryBufferCmds[s[i:end]]; ok &&
+				(end == len(s) || s[end] == ' ' || s[end] == '\t' || s[end] == ';') {
+				return i, true
+			}
+			// Some other backslash. We do not split on those, for
+			// compatibility with previous versions.
+			return 0, false
+		}
+		i++
+	}
+	return 0, false
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package clisqlshell
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolateVariables(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	vars := map[string]string{
+		"tbl":   "my table",
+		"n":     "42",
+		"quote": "it's",
+	}
+
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{`SELECT :n`, `SELECT 42`},
+		{`SELECT :'quote'`, `SELECT e'it\'s'`},
+		{`SELECT * FROM :"tbl"`, `SELECT * FROM "my table"`},
+		{`SELECT :{?n}, :{?undefined}`, `SELECT TRUE, FALSE`},
+		// Undefined variables are left unchanged.
+		{`SELECT :undefined, :'undefined', :"undefined"`, `SELECT :undefined, :'undefined', :"undefined"`},
+		// Casts are not variable references.
+		{`SELECT 1::n`, `SELECT 1::n`},
+		// Nor is anything inside strings, identifiers or comments.
+		{`SELECT ':n', ":n" -- :n`, `SELECT ':n', ":n" -- :n`},
+		{`SELECT /* :n /* :n */ :n */ :n`, `SELECT /* :n /* :n */ :n */ 42`},
+		{`SELECT e'\' :n', :n`, `SELECT e'\' :n', 42`},
+		{`SELECT $$ :n $$, $x$ :n $x$, :n`, `SELECT $$ :n $$, $x$ :n $x$, 42`},
+		{`SELECT $1, :n`, `SELECT $1, 42`},
+		{`SELECT 'unterminated :n`, `SELECT 'unterminated :n`},
+		{`SELECT arr[1:n]`, `SELECT arr[142]`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			assert.Equal(t, tc.out, interpolateVariables(tc.in, vars))
+		})
+	}
+}
+
+func TestFindQueryBufferCmd(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tests := []struct {
+		in    string
+		start int
+		pos   int
+		found bool
+	}{
+		{`SELECT 1 AS x \gset`, 0, 14, true},
+		{`SELECT 1 AS x \gset pre_`, 0, 14, true},
+		{`\watch 1`, 0, 0, true},
+		{`SELECT 'a' \gexec;`, 0, 11, true},
+		{`SELECT '\gset'`, 0, 0, false},
+		{`SELECT 1 -- \gset`, 0, 0, false},
+		{`SELECT 1 \gsetx`, 0, 0, false},
+		{`SELECT 1 \i foo`, 0, 0, false},
+		{"SELECT '\n\\gset'", 7, 0, false},
+		{"SELECT 1\n\\gset", 9, 9, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			pos, found := findQueryBufferCmd(tc.in, tc.start)
+			assert.Equal(t, tc.found, found)
+			if found {
+				assert.Equal(t, tc.pos, pos)
+			}
+		})
+	}
+}
+
+func TestParseWatchArgs(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tests := []struct {
+		args     []string
+		interval time.Duration
+		count    int
+		err      string
+	}{
+		{nil, defaultWatchInterval, 0, ""},
+		{[]string{"5"}, 5 * time.Second, 0, ""},
+		{[]string{"0.5"}, 500 * time.Millisecond, 0, ""},
+		{[]string{"i=3", "c=2"}, 3 * time.Second, 2, ""},
+		{[]string{"interval=1", "count=10"}, time.Second, 10, ""},
+		{[]string{"0"}, 0, 0, `\watch: invalid interval: "0"`},
+		{[]string{"c=-1"}, 0, 0, `\watch: invalid count: "-1"`},
+		{[]string{"x=1"}, 0, 0, `\watch: unrecognized parameter: "x=1"`},
+	}
+
+	for _, tc := range tests {
+		interval, count, err := parseWatchArgs(tc.args)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tc.interval, interval)
+		assert.Equal(t, tc.count, count)
+	}
+}
@@ -68,6 +68,7 @@ func initCLIDefaults() {
 	setCertContextDefaults()
 	setDebugRecoverContextDefaults()
 	setDebugSendKVBatchContextDefaults()
+	setDebugAllocatorSimulateContextDefaults()
 
 	initPreFlagsDefaults()
 
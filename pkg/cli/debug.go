@@ -39,6 +39,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/gc"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/liveness/livenesspb"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/rditer"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
@@ -1089,6 +1090,12 @@ func parseGossipValues(gossipInfo *gossip.InfoStatus) (string, error) {
 				return "", errors.Wrapf(err, "failed to parse value for key %q", key)
 			}
 			output = append(output, fmt.Sprintf("%q: %+v", key, drainingInfo))
+		} else if strings.HasPrefix(key, gossip.KeyTableWriteDemandPrefix) {
+			var demand kvserverpb.TableWriteDemand
+			if err := protoutil.Unmarshal(bytes, &demand); err != nil {
+				return "", errors.Wrapf(err, "failed to parse value for key %q", key)
+			}
+			output = append(output, fmt.Sprintf("%q: %+v", key, demand))
 		}
 	}
 
@@ -1344,6 +1351,7 @@ var debugCmds = []*cobra.Command{
 	debugResetQuorumCmd,
 	debugSendKVBatchCmd,
 	debugRecoverCmd,
+	debugAllocatorSimulateCmd,
 }
 
 // DebugCmd is the root of all debug commands. Exported to allow modification by CCL code.
@@ -1532,6 +1540,22 @@ func init() {
 		"whether to keep the CollectedSpans field on the response, to learn about how traces work")
 	f.StringVar(&debugSendKVBatchContext.traceFile, "trace-output", debugSendKVBatchContext.traceFile,
 		"the output file to use for the trace. If left empty, output to stderr.")
+
+	f = debugAllocatorSimulateCmd.Flags()
+	f.StringVar(&debugAllocatorSimulateOpts.tsdump, "tsdump", debugAllocatorSimulateOpts.tsdump,
+		"seed the simulation from a time series dump in CSV format")
+	f.StringVar(&debugAllocatorSimulateOpts.rangeReport, "range-report", debugAllocatorSimulateOpts.rangeReport,
+		"seed the simulation from a range report in CSV format")
+	f.StringVar(&debugAllocatorSimulateOpts.format, "format", debugAllocatorSimulateOpts.format,
+		"output format (text, csv)")
+	f.StringSliceVar(&debugAllocatorSimulateOpts.stats, "stats", debugAllocatorSimulateOpts.stats,
+		"per-store statistics to output")
+	f.DurationVar(&debugAllocatorSimulateOpts.interval, "interval", debugAllocatorSimulateOpts.interval,
+		"simulated time between output rows (default: a tenth of the duration)")
+	f.DurationVar(&debugAllocatorSimulateOpts.duration, "duration", debugAllocatorSimulateOpts.duration,
+		"simulated duration, overriding the spec")
+	f.Int64Var(&debugAllocatorSimulateOpts.seed, "seed", debugAllocatorSimulateOpts.seed,
+		"random seed, overriding the spec")
 }
 
 func initPebbleCmds(cmd *cobra.Command, pebbleTool *tool.T) {
@@ -0,0 +1,156 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package cli
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/cli/clierrorplus"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/asim/scenario"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+var debugAllocatorSimulateOpts = struct {
+	// tsdump and rangeReport are the files the simulation is seeded from.
+	tsdump      string
+	rangeReport string
+	format      string
+	stats       []string
+	// interval is the simulated time between reported rows. If zero, a tenth
+	// of the simulated duration is used.
+	interval time.Duration
+	// duration and seed override the spec.
+	duration time.Duration
+	seed     int64
+}{}
+
+func setDebugAllocatorSimulateContextDefaults() {
+	debugAllocatorSimulateOpts.tsdump = ""
+	debugAllocatorSimulateOpts.rangeReport = ""
+	debugAllocatorSimulateOpts.format = string(scenario.ReportText)
+	debugAllocatorSimulateOpts.stats = scenario.DefaultStats
+	debugAllocatorSimulateOpts.interval = 0
+	debugAllocatorSimulateOpts.duration = 0
+	debugAllocatorSimulateOpts.seed = 0
+}
+
+var debugAllocatorSimulateCmd = &cobra.Command{
+	Use:   "allocator-simulate [<spec.yaml>]",
+	Short: "simulate the allocator on a described cluster",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  clierrorplus.MaybeDecorateError(runDebugAllocatorSimulate),
+	Long: `
+Simulates the allocator, replicate queue and store rebalancer of every store
+of a cluster described by a YAML spec, and outputs the predicted distribution
+of replicas, leases and QPS over the stores as simulated time passes. The
+spec describes the cluster's regions, nodes and stores, its ranges, zone
+configs and workload, and nodes which join the cluster during the simulation.
+For example:
+
+  duration: 1h
+  cluster:
+    regions:
+    - name: us-east1
+      zones:
+      - {name: us-east1-a, nodes: 3}
+      - {name: us-east1-b, nodes: 3}
+      - {name: us-east1-c, nodes: 3}
+  ranges: {count: 3000, bytes: 268435456, placement: skewed}
+  zone_configs:
+  - {start_key: 0, end_key: 100000, config: {num_replicas: 5}}
+  workload:
+  - {rate: 5000, rw_ratio: 0.95}
+  add_nodes:
+  - {at: 10m, nodes: 3, locality: "region=us-east1,zone=us-east1-a"}
+
+Instead of the spec's ranges, the simulation can be seeded with the replica,
+lease and load distribution of a live cluster, from either a time series dump
+in CSV format (--tsdump), produced by:
+
+  cockroach debug tsdump --format=csv
+
+or a range report in CSV format (--range-report), produced by:
+
+  cockroach sql --format=csv -e \
+    "SELECT range_id, replicas, lease_holder, range_size FROM crdb_internal.ranges"
+
+The simulated stores 1..n stand in for the seeded stores in ascending order of
+store ID. If the spec has no cluster topology, the seeded cluster has a node
+per store; otherwise stores beyond the seeded ones start out empty. If the spec
+has no workload, a tsdump seed's QPS is applied uniformly over the keyspace.
+
+The simulation is an approximation: it models the allocation decisions of a
+cluster, not its performance.
+`,
+}
+
+func runDebugAllocatorSimulate(cmd *cobra.Command, args []string) error {
+	var specBytes []byte
+	if len(args) > 0 {
+		var err error
+		if specBytes, err = os.ReadFile(args[0]); err != nil {
+			return err
+		}
+	}
+	spec, err := scenario.ParseSpec(specBytes)
+	if err != nil {
+		return err
+	}
+	if debugAllocatorSimulateOpts.duration != 0 {
+		spec.Duration = debugAllocatorSimulateOpts.duration
+	}
+	if debugAllocatorSimulateOpts.seed != 0 {
+		spec.Seed = debugAllocatorSimulateOpts.seed
+	}
+
+	var seed *scenario.Seed
+	switch {
+	case debugAllocatorSimulateOpts.tsdump != "" && debugAllocatorSimulateOpts.rangeReport != "":
+		return errors.New("--tsdump and --range-report cannot both be specified")
+	case debugAllocatorSimulateOpts.tsdump != "":
+		f, err := os.Open(debugAllocatorSimulateOpts.tsdump)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if seed, err = scenario.SeedFromTSDump(f); err != nil {
+			return err
+		}
+	case debugAllocatorSimulateOpts.rangeReport != "":
+		f, err := os.Open(debugAllocatorSimulateOpts.rangeReport)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if seed, err = scenario.SeedFromRangeReport(f); err != nil {
+			return err
+		}
+	case len(args) == 0:
+		return errors.New("a spec, --tsdump or --range-report must be specified")
+	}
+
+	interval := debugAllocatorSimulateOpts.interval
+	if interval == 0 {
+		interval = spec.Duration / 10
+	}
+	history, err := scenario.Run(context.Background(), spec, seed)
+	if err != nil {
+		return err
+	}
+	return scenario.WriteReport(os.Stdout, history.Recorded, scenario.ReportOptions{
+		Format:   scenario.ReportFormat(debugAllocatorSimulateOpts.format),
+		Stats:    debugAllocatorSimulateOpts.stats,
+		Interval: interval,
+	})
+}
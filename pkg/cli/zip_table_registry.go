@@ -350,6 +350,25 @@ var zipInternalTablesPerCluster = DebugZipTableRegistry{
 			"is_grantable",
 		},
 	},
+	"crdb_internal.hot_keys": {
+		// `key` column contains the hot key, which may contain sensitive
+		// row-level data.
+		// `pretty_key` and `index_key` columns contain the decoded hot key,
+		// which may contain sensitive row-level data.
+		nonSensitiveCols: NonSensitiveColumns{
+			"range_id",
+			"node_id",
+			"store_id",
+			"database_name",
+			"schema_name",
+			"table_name",
+			"index_name",
+			"frequency",
+			"reads_per_second",
+			"writes_per_second",
+			"contention_per_second",
+		},
+	},
 	"crdb_internal.index_usage_statistics": {
 		nonSensitiveCols: NonSensitiveColumns{
 			"table_id",
@@ -563,6 +582,34 @@ var zipInternalTablesPerCluster = DebugZipTableRegistry{
 			"waiting_txn_fingerprint_id",
 			"contention_duration",
 			"IF(crdb_internal.is_system_table_key(contending_key), crdb_internal.pretty_key(contending_key, 0) ,'redacted') as contending_pretty_key",
+			"contention_type",
+		},
+	},
+	"crdb_internal.transaction_wait_graph": {
+		// `key` column contains the contended key, which may contain sensitive
+		// row-level data.
+		// `key_pretty` column contains the pretty-printed contended key, which
+		// may contain sensitive row-level data.
+		nonSensitiveCols: NonSensitiveColumns{
+			"waiting_txn_id",
+			"blocking_txn_id",
+			"wait_type",
+			"range_id",
+			"lock_strength",
+			"wait_duration",
+			"cycle_id",
+			"blocking_txn_is_root_blocker",
+			"blocking_txn_num_blocked",
+			"waiting_session_id",
+			"waiting_node_id",
+			"waiting_user_name",
+			"waiting_app_name",
+			"waiting_stmt_fingerprint",
+			"blocking_session_id",
+			"blocking_node_id",
+			"blocking_user_name",
+			"blocking_app_name",
+			"blocking_stmt_fingerprint",
 		},
 	},
 	"crdb_internal.zones": {
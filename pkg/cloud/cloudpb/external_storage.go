@@ -55,6 +55,9 @@ func (m *ExternalStorage) AccessIsWithExplicitAuth() bool {
 	case ExternalStorageProvider_nodelocal:
 		// The node's local filesystem is obviously accessed implicitly as the node.
 		return false
+	case ExternalStorageProvider_sftp:
+		// Like http, the server may be reachable only via the node's network.
+		return false
 	case ExternalStorageProvider_external:
 		// External Connections have a `USAGE` privilege that determines if a user
 		// has the appropriate privileges to use the underlying resource.
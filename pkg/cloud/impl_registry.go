@@ -126,6 +126,15 @@ func RegisterExternalStorageProvider(
 	}
 }
 
+// RegisterRedactedParams registers query parameters which hold secrets in the
+// URIs of providers that are not external storage providers, such as KMS, so
+// that they are redacted by SanitizeExternalStorageURI.
+func RegisterRedactedParams(redactedParams map[string]struct{}) {
+	for param := range redactedParams {
+		redactedQueryParams[param] = struct{}{}
+	}
+}
+
 // ExternalStorageConfFromURI generates an ExternalStorage config from a URI string.
 func ExternalStorageConfFromURI(
 	path string, user username.SQLUsername,
@@ -0,0 +1,128 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sftp
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is an in-process SSH server that serves the SFTP subsystem out
+// of an in-memory file system shared by all of its connections.
+type testServer struct {
+	addr     string
+	hostKey  ssh.PublicKey
+	handlers sftp.Handlers
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// startTestServer starts a server that accepts the given user with either the
+// given password or the given public key.
+func startTestServer(
+	t *testing.T, user, password string, authorizedKey ssh.PublicKey,
+) *testServer {
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if conn.User() == user && string(pass) == password {
+				return nil, nil
+			}
+			return nil, errors.New("invalid password")
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == user && authorizedKey != nil &&
+				bytes.Equal(key.Marshal(), authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("invalid key")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &testServer{
+		addr:     listener.Addr().String(),
+		hostKey:  hostSigner.PublicKey(),
+		handlers: sftp.InMemHandler(),
+		listener: listener,
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serveConn(conn, config)
+			}()
+		}
+	}()
+	return s
+}
+
+func (s *testServer) close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *testServer) serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	defer sshConn.Close()
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			_ = newChan.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		ch, chReqs, err := newChan.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range chReqs {
+				// The payload of a subsystem request is the name of the subsystem.
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 &&
+					string(req.Payload[4:]) == "sftp"
+				_ = req.Reply(ok, nil)
+				if ok {
+					go func() {
+						server := sftp.NewRequestServer(ch, s.handlers)
+						defer server.Close()
+						_ = server.Serve()
+					}()
+				}
+			}
+		}()
+	}
+}
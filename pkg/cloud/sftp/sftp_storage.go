@@ -0,0 +1,519 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sftp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudpb"
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	scheme      = "sftp"
+	defaultPort = "22"
+
+	// PasswordParam is the query parameter for the password of the user.
+	PasswordParam = "SFTP_PASSWORD"
+	// PrivateKeyParam is the query parameter for the base64 encoded PEM private
+	// key used to authenticate the user.
+	PrivateKeyParam = "SFTP_PRIVATE_KEY"
+	// PrivateKeyPassphraseParam is the query parameter for the passphrase of
+	// the private key.
+	PrivateKeyPassphraseParam = "SFTP_PRIVATE_KEY_PASSPHRASE"
+	// HostKeyParam is the query parameter for the expected public key of the
+	// server, either as a line of an OpenSSH authorized_keys file or as a
+	// SHA256 fingerprint such as those printed by `ssh-keygen -l`.
+	HostKeyParam = "SFTP_HOST_KEY"
+
+	// tmpSuffix is the suffix of the temporary files written by the storage
+	// before they are renamed into place.
+	tmpSuffix = ".sftp-tmp"
+
+	// posixRenameExtension is the OpenSSH extension that renames a file,
+	// replacing the target if it exists.
+	posixRenameExtension = "posix-rename@openssh.com"
+)
+
+func parseSFTPURL(
+	_ cloud.ExternalStorageURIContext, uri *url.URL,
+) (cloudpb.ExternalStorage, error) {
+	sftpURL := cloud.ConsumeURL{URL: uri}
+	conf := cloudpb.ExternalStorage{}
+	conf.Provider = cloudpb.ExternalStorageProvider_sftp
+	conf.SFTPConfig = &cloudpb.ExternalStorage_SFTP{
+		Host:                 uri.Host,
+		Path:                 uri.Path,
+		User:                 uri.User.Username(),
+		Password:             sftpURL.ConsumeParam(PasswordParam),
+		PrivateKey:           sftpURL.ConsumeParam(PrivateKeyParam),
+		PrivateKeyPassphrase: sftpURL.ConsumeParam(PrivateKeyPassphraseParam),
+		HostKey:              sftpURL.ConsumeParam(HostKeyParam),
+	}
+
+	// Validate that all the passed in parameters are supported.
+	if unknownParams := sftpURL.RemainingQueryParams(); len(unknownParams) > 0 {
+		return cloudpb.ExternalStorage{}, errors.Errorf(
+			`unknown sftp query parameters: %s`, strings.Join(unknownParams, ", "))
+	}
+
+	// Passwords in the user info of the URI would not be redacted when the URI
+	// is displayed, unlike the query parameters.
+	if _, ok := uri.User.Password(); ok {
+		return conf, errors.Errorf("sftp uri must not contain a password, use the %q parameter instead",
+			PasswordParam)
+	}
+	if conf.SFTPConfig.Host == "" {
+		return conf, errors.New("sftp uri missing host")
+	}
+	if conf.SFTPConfig.User == "" {
+		return conf, errors.New("sftp uri missing user")
+	}
+	if conf.SFTPConfig.Path == "" {
+		return conf, errors.New("sftp uri missing path")
+	}
+	if conf.SFTPConfig.Password == "" && conf.SFTPConfig.PrivateKey == "" {
+		return conf, errors.Errorf("sftp uri requires the %q or %q parameter",
+			PasswordParam, PrivateKeyParam)
+	}
+	if conf.SFTPConfig.HostKey == "" {
+		return conf, errors.Errorf("sftp uri missing %q parameter", HostKeyParam)
+	}
+	return conf, nil
+}
+
+type sftpStorage struct {
+	conf      *cloudpb.ExternalStorage_SFTP
+	ioConf    base.ExternalIODirConfig
+	settings  *cluster.Settings
+	addr      string
+	sshConfig *ssh.ClientConfig
+
+	mu struct {
+		syncutil.Mutex
+		client *client
+	}
+}
+
+var _ cloud.ExternalStorage = &sftpStorage{}
+
+// MakeSFTPStorage returns an instance of an SFTP ExternalStorage. The
+// connection to the server is established when it is first used.
+func MakeSFTPStorage(
+	ctx context.Context, args cloud.ExternalStorageContext, dest cloudpb.ExternalStorage,
+) (cloud.ExternalStorage, error) {
+	telemetry.Count("external-io.sftp")
+	conf := dest.SFTPConfig
+	if conf == nil {
+		return nil, errors.Errorf("sftp storage requested but info missing")
+	}
+
+	var auth []ssh.AuthMethod
+	if conf.PrivateKey != "" {
+		signer, err := parsePrivateKey(conf.PrivateKey, conf.PrivateKeyPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if conf.Password != "" {
+		auth = append(auth, ssh.Password(conf.Password))
+	}
+	hostKeyCallback, err := makeHostKeyCallback(conf.HostKey)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := conf.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, defaultPort)
+	}
+	return &sftpStorage{
+		conf:     conf,
+		ioConf:   args.IOConf,
+		settings: args.Settings,
+		addr:     addr,
+		sshConfig: &ssh.ClientConfig{
+			User:            conf.User,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+		},
+	}, nil
+}
+
+// parsePrivateKey parses a base64 encoded PEM private key.
+func parsePrivateKey(encoded, passphrase string) (ssh.Signer, error) {
+	pemBytes, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding value of %s", PrivateKeyParam)
+	}
+	var signer ssh.Signer
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(pemBytes)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing value of %s", PrivateKeyParam)
+	}
+	return signer, nil
+}
+
+// makeHostKeyCallback returns a callback that only accepts the given host key,
+// which is either an authorized_keys line or a SHA256 fingerprint.
+func makeHostKeyCallback(hostKey string) (ssh.HostKeyCallback, error) {
+	if strings.HasPrefix(hostKey, "SHA256:") {
+		return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			if fingerprint := ssh.FingerprintSHA256(key); fingerprint != hostKey {
+				return errors.Errorf("sftp: host key fingerprint %s does not match %s", fingerprint, hostKey)
+			}
+			return nil
+		}, nil
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing value of %s", HostKeyParam)
+	}
+	return ssh.FixedHostKey(key), nil
+}
+
+func (s *sftpStorage) Conf() cloudpb.ExternalStorage {
+	return cloudpb.ExternalStorage{
+		Provider:   cloudpb.ExternalStorageProvider_sftp,
+		SFTPConfig: s.conf,
+	}
+}
+
+func (s *sftpStorage) ExternalIOConf() base.ExternalIODirConfig {
+	return s.ioConf
+}
+
+func (s *sftpStorage) RequiresExternalIOAccounting() bool { return true }
+
+func (s *sftpStorage) Settings() *cluster.Settings {
+	return s.settings
+}
+
+// client is a connection to an SFTP server.
+type client struct {
+	*sftp.Client
+	sshClient *ssh.Client
+	// done is closed once the connection to the server is lost or closed.
+	done chan struct{}
+}
+
+func (c *client) broken() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *client) Close() error {
+	return errors.CombineErrors(c.Client.Close(), c.sshClient.Close())
+}
+
+// rename renames a file, replacing the target if it exists.
+func (c *client) rename(from, to string) error {
+	if _, ok := c.HasExtension(posixRenameExtension); ok {
+		return c.PosixRename(from, to)
+	}
+	if err := c.Remove(to); err != nil && !isNotExist(err) {
+		return err
+	}
+	return c.Rename(from, to)
+}
+
+// getClient returns the client of the storage, connecting to the server if
+// the storage is not connected yet or if its previous connection failed.
+func (s *sftpStorage) getClient(ctx context.Context) (*client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mu.client != nil {
+		if !s.mu.client.broken() {
+			return s.mu.client, nil
+		}
+		_ = s.mu.client.Close()
+		s.mu.client = nil
+	}
+	c, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.client = c
+	return c, nil
+}
+
+func (s *sftpStorage) dial(ctx context.Context) (*client, error) {
+	timeout := cloud.Timeout.Get(&s.settings.SV)
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "sftp: connecting to %s", s.addr)
+	}
+	// The SSH handshake does not observe the context, so bound it by the
+	// timeout instead.
+	if err := conn.SetDeadline(timeutil.Now().Add(timeout)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, s.addr, s.sshConfig)
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrapf(err, "sftp: ssh handshake with %s", s.addr)
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		_ = sshConn.Close()
+		return nil, err
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		_ = sshClient.Close()
+		return nil, errors.Wrap(err, "sftp: starting sftp session")
+	}
+	c := &client{Client: sftpClient, sshClient: sshClient, done: make(chan struct{})}
+	go func() {
+		_ = sftpClient.Wait()
+		close(c.done)
+	}()
+	return c, nil
+}
+
+func (s *sftpStorage) fullPath(basename string) string {
+	return path.Join(s.conf.Path, basename)
+}
+
+func isNotExist(err error) bool {
+	return errors.Is(err, os.ErrNotExist)
+}
+
+func wrapNotExist(err error, p string) error {
+	if isNotExist(err) {
+		// nolint:errwrap
+		return errors.Wrapf(
+			errors.Wrap(cloud.ErrFileDoesNotExist, "sftp storage file does not exist"),
+			"%s: %v", p, err.Error(),
+		)
+	}
+	return errors.Wrapf(err, "sftp: %s", p)
+}
+
+func (s *sftpStorage) ReadFile(
+	ctx context.Context, basename string, opts cloud.ReadOptions,
+) (_ ioctx.ReadCloserCtx, fileSize int64, _ error) {
+	c, err := s.getClient(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	p := s.fullPath(basename)
+	f, err := c.Open(p)
+	if err != nil {
+		return nil, 0, wrapNotExist(err, p)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, 0, wrapNotExist(err, p)
+	}
+	if opts.Offset > fi.Size() {
+		_ = f.Close()
+		return nil, 0, errors.Errorf("sftp: offset %d is past the end of %s (size %d)",
+			opts.Offset, p, fi.Size())
+	}
+	if opts.Offset > 0 {
+		if _, err := f.Seek(opts.Offset, io.SeekStart); err != nil {
+			_ = f.Close()
+			return nil, 0, errors.Wrapf(err, "sftp: %s", p)
+		}
+	}
+	return ioctx.ReadCloserAdapter(f), fi.Size(), nil
+}
+
+// Writer returns a writer to the given file. The data is written to a
+// temporary file which is renamed to the file once the writer is closed, so
+// that readers never observe partially written files.
+func (s *sftpStorage) Writer(ctx context.Context, basename string) (io.WriteCloser, error) {
+	return cloud.BackgroundPipe(ctx, func(ctx context.Context, r io.Reader) error {
+		c, err := s.getClient(ctx)
+		if err != nil {
+			return err
+		}
+		p := s.fullPath(basename)
+		dir, file := path.Split(p)
+		if err := mkdirAll(c, dir); err != nil {
+			return err
+		}
+		tmp := path.Join(dir, fmt.Sprintf(".%s.%s%s", file, uuid.MakeV4(), tmpSuffix))
+		f, err := c.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return errors.Wrapf(err, "sftp: creating %s", tmp)
+		}
+		_, err = io.Copy(f, r)
+		err = errors.CombineErrors(err, f.Close())
+		if err == nil {
+			err = c.rename(tmp, p)
+		}
+		if err != nil {
+			_ = c.Remove(tmp)
+			return errors.Wrapf(err, "sftp: writing %s", p)
+		}
+		return nil
+	}), nil
+}
+
+// mkdirAll creates the given directory and any missing parents.
+func mkdirAll(c *client, dir string) error {
+	dir = path.Clean(dir)
+	if err := c.MkdirAll(dir); err != nil {
+		// The directory may have been created concurrently.
+		if fi, statErr := c.Stat(dir); statErr == nil && fi.IsDir() {
+			return nil
+		}
+		return errors.Wrapf(err, "sftp: creating %s", dir)
+	}
+	return nil
+}
+
+func (s *sftpStorage) List(
+	ctx context.Context, prefix, delim string, fn cloud.ListingFn,
+) error {
+	c, err := s.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	dest := cloud.JoinPathPreservingTrailingSlash(s.conf.Path, prefix)
+	root := dest
+	if !strings.HasSuffix(root, "/") {
+		root = path.Dir(root)
+	}
+
+	var res []string
+	walker := c.Walk(root)
+	for walker.Step() {
+		p := walker.Path()
+		if err := walker.Err(); err != nil {
+			if isNotExist(err) {
+				continue
+			}
+			return errors.Wrapf(err, "sftp: listing %s", p)
+		}
+		if walker.Stat().IsDir() {
+			// Only descend into directories that may contain matching files.
+			if p != root && !strings.HasPrefix(p+"/", dest) && !strings.HasPrefix(dest, p+"/") {
+				walker.SkipDir()
+			}
+			continue
+		}
+		if strings.HasSuffix(p, tmpSuffix) {
+			continue
+		}
+		if strings.HasPrefix(p, dest) {
+			res = append(res, p)
+		}
+	}
+
+	// Sort results so that we can group as we go.
+	sort.Strings(res)
+	var prevPrefix string
+	for _, f := range res {
+		f = strings.TrimPrefix(f, dest)
+		if delim != "" {
+			if i := strings.Index(f, delim); i >= 0 {
+				f = f[:i+len(delim)]
+			}
+			if f == prevPrefix {
+				continue
+			}
+			prevPrefix = f
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sftpStorage) Delete(ctx context.Context, basename string) error {
+	p := s.fullPath(basename)
+	return timeutil.RunWithTimeout(ctx, fmt.Sprintf("delete %s", p),
+		cloud.Timeout.Get(&s.settings.SV), func(ctx context.Context) error {
+			c, err := s.getClient(ctx)
+			if err != nil {
+				return err
+			}
+			if err := c.Remove(p); err != nil && !isNotExist(err) {
+				return errors.Wrapf(err, "sftp: deleting %s", p)
+			}
+			return nil
+		})
+}
+
+func (s *sftpStorage) Size(ctx context.Context, basename string) (int64, error) {
+	p := s.fullPath(basename)
+	var size int64
+	if err := timeutil.RunWithTimeout(ctx, fmt.Sprintf("stat %s", p),
+		cloud.Timeout.Get(&s.settings.SV), func(ctx context.Context) error {
+			c, err := s.getClient(ctx)
+			if err != nil {
+				return err
+			}
+			fi, err := c.Stat(p)
+			if err != nil {
+				return wrapNotExist(err, p)
+			}
+			size = fi.Size()
+			return nil
+		}); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+func (s *sftpStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mu.client == nil {
+		return nil
+	}
+	err := s.mu.client.Close()
+	s.mu.client = nil
+	return err
+}
+
+func init() {
+	cloud.RegisterExternalStorageProvider(cloudpb.ExternalStorageProvider_sftp,
+		parseSFTPURL, MakeSFTPStorage,
+		cloud.RedactedParams(PasswordParam, PrivateKeyParam, PrivateKeyPassphraseParam),
+		scheme)
+}
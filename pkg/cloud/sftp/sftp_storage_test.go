@@ -0,0 +1,168 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/cloud/cloudtestutils"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	testUser     = "backup"
+	testPassword = "hunter2"
+)
+
+func TestPutSFTP(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	authorizedKey, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	privateKey := base64.StdEncoding.EncodeToString(
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	srv := startTestServer(t, testUser, testPassword, authorizedKey)
+	defer srv.close()
+	hostKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(srv.hostKey)))
+
+	testSettings := cluster.MakeTestingClusterSettings()
+	user := username.RootUserName()
+
+	makeURI := func(path string, params url.Values) string {
+		return fmt.Sprintf("%s://%s@%s%s?%s", scheme, testUser, srv.addr, path, params.Encode())
+	}
+
+	t.Run("password", func(t *testing.T) {
+		params := url.Values{PasswordParam: {testPassword}, HostKeyParam: {hostKey}}
+		cloudtestutils.CheckExportStore(
+			t, makeURI("/password/backup", params), false, user, nil /* db */, testSettings)
+		cloudtestutils.CheckListFiles(
+			t, makeURI("/password/listing-test/basepath", params), user, nil /* db */, testSettings)
+	})
+
+	t.Run("private-key", func(t *testing.T) {
+		params := url.Values{
+			PrivateKeyParam: {privateKey},
+			HostKeyParam:    {ssh.FingerprintSHA256(srv.hostKey)},
+		}
+		cloudtestutils.CheckExportStore(
+			t, makeURI("/key/backup", params), false, user, nil /* db */, testSettings)
+		cloudtestutils.CheckListFiles(
+			t, makeURI("/key/listing-test/basepath", params), user, nil /* db */, testSettings)
+	})
+
+	// connect makes a storage for the given URI and uses it, returning the
+	// error returned when connecting to the server.
+	connect := func(t *testing.T, uri string) error {
+		ctx := context.Background()
+		conf, err := cloud.ExternalStorageConfFromURI(uri, user)
+		require.NoError(t, err)
+		s, err := cloud.MakeExternalStorage(ctx, conf, base.ExternalIODirConfig{}, testSettings,
+			nil /* blobClientFactory */, nil /* db */, nil /* limiters */, cloud.NilMetrics)
+		require.NoError(t, err)
+		defer s.Close()
+		return cloud.WriteFile(ctx, s, "file", bytes.NewReader([]byte("data")))
+	}
+
+	t.Run("wrong-host-key", func(t *testing.T) {
+		otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		otherKey, err := ssh.NewPublicKey(otherPub)
+		require.NoError(t, err)
+		for _, key := range []string{
+			strings.TrimSpace(string(ssh.MarshalAuthorizedKey(otherKey))),
+			ssh.FingerprintSHA256(otherKey),
+		} {
+			params := url.Values{PasswordParam: {testPassword}, HostKeyParam: {key}}
+			err := connect(t, makeURI("/wrong-host-key", params))
+			require.ErrorContains(t, err, "ssh handshake")
+		}
+	})
+
+	t.Run("wrong-password", func(t *testing.T) {
+		params := url.Values{PasswordParam: {"wrong"}, HostKeyParam: {hostKey}}
+		err := connect(t, makeURI("/wrong-password", params))
+		require.ErrorContains(t, err, "unable to authenticate")
+	})
+}
+
+func TestParseSFTPURL(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const hostKey = "SHA256:c2hhMjU2IGZpbmdlcnByaW50IG9mIHRoZSBob3N0IGtleQ"
+	for _, tc := range []struct {
+		uri string
+		err string
+	}{
+		{
+			uri: "sftp://user@host:2222/backups?SFTP_PASSWORD=pw&SFTP_HOST_KEY=" + hostKey,
+		},
+		{
+			uri: "sftp://user:pw@host/backups?SFTP_HOST_KEY=" + hostKey,
+			err: `sftp uri must not contain a password, use the "SFTP_PASSWORD" parameter instead`,
+		},
+		{
+			uri: "sftp://host/backups?SFTP_PASSWORD=pw&SFTP_HOST_KEY=" + hostKey,
+			err: "sftp uri missing user",
+		},
+		{
+			uri: "sftp://user@host?SFTP_PASSWORD=pw&SFTP_HOST_KEY=" + hostKey,
+			err: "sftp uri missing path",
+		},
+		{
+			uri: "sftp://user@host/backups?SFTP_HOST_KEY=" + hostKey,
+			err: `sftp uri requires the "SFTP_PASSWORD" or "SFTP_PRIVATE_KEY" parameter`,
+		},
+		{
+			uri: "sftp://user@host/backups?SFTP_PASSWORD=pw",
+			err: `sftp uri missing "SFTP_HOST_KEY" parameter`,
+		},
+		{
+			uri: "sftp://user@host/backups?SFTP_PASSWORD=pw&SFTP_HOST_KEY=" + hostKey + "&foo=bar",
+			err: "unknown sftp query parameters: foo",
+		},
+	} {
+		t.Run(tc.uri, func(t *testing.T) {
+			conf, err := cloud.ExternalStorageConfFromURI(tc.uri, username.RootUserName())
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "host:2222", conf.SFTPConfig.Host)
+			require.Equal(t, "/backups", conf.SFTPConfig.Path)
+			require.Equal(t, "user", conf.SFTPConfig.User)
+			require.Equal(t, "pw", conf.SFTPConfig.Password)
+			require.Equal(t, hostKey, conf.SFTPConfig.HostKey)
+		})
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/util/envutil"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	scheme = "vault-transit"
+
+	// TokenParam is the query parameter for the Vault token used to
+	// authenticate when AUTH=specified.
+	TokenParam = "VAULT_TOKEN"
+	// RoleIDParam is the query parameter for the role ID used to authenticate
+	// with the AppRole auth method.
+	RoleIDParam = "VAULT_ROLE_ID"
+	// SecretIDParam is the query parameter for the secret ID used to
+	// authenticate with the AppRole auth method.
+	SecretIDParam = "VAULT_SECRET_ID"
+	// AppRolePathParam is the query parameter for the path at which the
+	// AppRole auth method is mounted. It defaults to "approle".
+	AppRolePathParam = "VAULT_APPROLE_PATH"
+	// NamespaceParam is the query parameter for the Vault Enterprise namespace
+	// that contains the transit engine.
+	NamespaceParam = "VAULT_NAMESPACE"
+	// DisableTLSParam is the query parameter which, if true, makes requests to
+	// Vault over plain HTTP. This is meant for Vault dev servers.
+	DisableTLSParam = "VAULT_DISABLE_TLS"
+
+	// defaultTransitMount is the mount path of the transit engine used when the
+	// URI path only names the key.
+	defaultTransitMount = "transit"
+	defaultAppRolePath  = "approle"
+
+	// tokenEnvVar is read for the token to use when AUTH=implicit, as with the
+	// Vault CLI.
+	tokenEnvVar = "VAULT_TOKEN"
+)
+
+// vaultKMS is a KMS backed by the transit secrets engine of HashiCorp Vault.
+// Data keys are encrypted and decrypted by Vault with a named transit key that
+// never leaves Vault; the ciphertexts are of the form vault:v<N>:<data> where
+// N is the version of the transit key that encrypted them, which allows the
+// transit key to be rotated without losing access to older backups.
+//
+// The URI is of the form
+// vault-transit://<host>[:port]/[<mount path>/]<key name>?<params>.
+type vaultKMS struct {
+	client    *http.Client
+	address   url.URL
+	namespace string
+	mount     string
+	keyName   string
+
+	// If roleID is set, tokens are obtained by logging in with AppRole and are
+	// renewed by logging in again when Vault rejects them.
+	roleID      string
+	secretID    string
+	appRolePath string
+
+	mu struct {
+		syncutil.Mutex
+		token string
+	}
+}
+
+var _ cloud.KMS = &vaultKMS{}
+
+func init() {
+	cloud.RegisterKMSFromURIFactory(MakeVaultKMS, scheme)
+	cloud.RegisterRedactedParams(cloud.RedactedParams(TokenParam, SecretIDParam))
+}
+
+type kmsURIParams struct {
+	auth        string
+	token       string
+	roleID      string
+	secretID    string
+	appRolePath string
+	namespace   string
+	disableTLS  bool
+}
+
+// resolveKMSURIParams parses the `kmsURI` for all the supported KMS parameters.
+func resolveKMSURIParams(kmsURI *url.URL) (kmsURIParams, error) {
+	kmsConsumeURL := cloud.ConsumeURL{URL: kmsURI}
+	params := kmsURIParams{
+		auth:        kmsConsumeURL.ConsumeParam(cloud.AuthParam),
+		token:       kmsConsumeURL.ConsumeParam(TokenParam),
+		roleID:      kmsConsumeURL.ConsumeParam(RoleIDParam),
+		secretID:    kmsConsumeURL.ConsumeParam(SecretIDParam),
+		appRolePath: kmsConsumeURL.ConsumeParam(AppRolePathParam),
+		namespace:   kmsConsumeURL.ConsumeParam(NamespaceParam),
+	}
+	if disableTLS := kmsConsumeURL.ConsumeParam(DisableTLSParam); disableTLS != "" {
+		var err error
+		if params.disableTLS, err = strconv.ParseBool(disableTLS); err != nil {
+			return kmsURIParams{}, errors.Wrapf(err, "parsing %s", DisableTLSParam)
+		}
+	}
+
+	// Validate that all the passed in parameters are supported.
+	if unknownParams := kmsConsumeURL.RemainingQueryParams(); len(unknownParams) > 0 {
+		return kmsURIParams{}, errors.Errorf(
+			`unknown KMS query parameters: %s`, strings.Join(unknownParams, ", "))
+	}
+
+	return params, nil
+}
+
+// MakeVaultKMS is the factory method which returns a configured, ready-to-use
+// Vault transit KMS object.
+func MakeVaultKMS(ctx context.Context, uri string, env cloud.KMSEnv) (cloud.KMS, error) {
+	if env.KMSConfig().DisableOutbound {
+		return nil, errors.New("external IO must be enabled to use KMS")
+	}
+	kmsURI, err := url.ParseRequestURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if kmsURI.Host == "" {
+		return nil, errors.New("host component of the KMS URI must be the address of the Vault server")
+	}
+
+	keyPath := strings.Trim(kmsURI.Path, "/")
+	if keyPath == "" {
+		return nil, errors.New("path component of the KMS cannot be empty; must contain the transit key name")
+	}
+	mount, keyName := path.Split(keyPath)
+	mount = strings.TrimSuffix(mount, "/")
+	if mount == "" {
+		mount = defaultTransitMount
+	}
+
+	kmsURIParams, err := resolveKMSURIParams(kmsURI)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &vaultKMS{
+		address:     url.URL{Scheme: "https", Host: kmsURI.Host},
+		namespace:   kmsURIParams.namespace,
+		mount:       mount,
+		keyName:     keyName,
+		appRolePath: kmsURIParams.appRolePath,
+	}
+	if kmsURIParams.disableTLS {
+		k.address.Scheme = "http"
+	}
+	if k.appRolePath == "" {
+		k.appRolePath = defaultAppRolePath
+	}
+
+	switch kmsURIParams.auth {
+	case "", cloud.AuthParamSpecified:
+		switch {
+		case kmsURIParams.token != "" && (kmsURIParams.roleID != "" || kmsURIParams.secretID != ""):
+			return nil, errors.Errorf("%s cannot be combined with %s or %s",
+				TokenParam, RoleIDParam, SecretIDParam)
+		case kmsURIParams.token != "":
+			k.mu.token = kmsURIParams.token
+		case kmsURIParams.roleID != "" && kmsURIParams.secretID != "":
+			k.roleID, k.secretID = kmsURIParams.roleID, kmsURIParams.secretID
+		case kmsURIParams.roleID != "" || kmsURIParams.secretID != "":
+			return nil, errors.Errorf("%s and %s must be set together", RoleIDParam, SecretIDParam)
+		default:
+			return nil, errors.Errorf("%s or %s and %s must be set if %q is %q",
+				TokenParam, RoleIDParam, SecretIDParam, cloud.AuthParam, cloud.AuthParamSpecified)
+		}
+	case cloud.AuthParamImplicit:
+		if env.KMSConfig().DisableImplicitCredentials {
+			return nil, errors.New(
+				"implicit credentials disallowed for vault due to --external-io-disable-implicit-credentials flag")
+		}
+		if kmsURIParams.token != "" || kmsURIParams.roleID != "" || kmsURIParams.secretID != "" {
+			return nil, errors.Errorf("%s, %s and %s cannot be set if %q is %q",
+				TokenParam, RoleIDParam, SecretIDParam, cloud.AuthParam, cloud.AuthParamImplicit)
+		}
+		token, _ := envutil.ExternalEnvString(tokenEnvVar, 1)
+		if token == "" {
+			return nil, errors.Errorf("the %s environment variable must be set if %q is %q",
+				tokenEnvVar, cloud.AuthParam, cloud.AuthParamImplicit)
+		}
+		k.mu.token = token
+	default:
+		return nil, errors.Errorf("unsupported value %s for %s", kmsURIParams.auth, cloud.AuthParam)
+	}
+
+	if k.client, err = cloud.MakeHTTPClient(env.ClusterSettings()); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// MasterKeyID implements the KMS interface.
+func (k *vaultKMS) MasterKeyID() (string, error) {
+	return path.Join(k.mount, k.keyName), nil
+}
+
+// Encrypt implements the KMS interface.
+func (k *vaultKMS) Encrypt(ctx context.Context, data []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	if err := k.transit(ctx, "encrypt", map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(data),
+	}, &resp); err != nil {
+		return nil, errors.Wrap(err, "vault transit encrypt")
+	}
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+// Decrypt implements the KMS interface.
+func (k *vaultKMS) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := k.transit(ctx, "decrypt", map[string]string{
+		"ciphertext": string(data),
+	}, &resp); err != nil {
+		return nil, errors.Wrap(err, "vault transit decrypt")
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, errors.Wrap(err, "vault transit decrypt")
+	}
+	return plaintext, nil
+}
+
+// Close implements the KMS interface.
+func (k *vaultKMS) Close() error {
+	k.client.CloseIdleConnections()
+	return nil
+}
+
+// transit calls the given operation of the transit engine with the key of the
+// KMS. If the request is rejected because the AppRole token expired, the KMS
+// logs in again and retries it once.
+func (k *vaultKMS) transit(
+	ctx context.Context, op string, req map[string]string, resp interface{},
+) error {
+	apiPath := path.Join(k.mount, op, k.keyName)
+	for attempt := 0; ; attempt++ {
+		token, err := k.getToken(ctx)
+		if err != nil {
+			return err
+		}
+		err = k.do(ctx, apiPath, token, req, resp)
+		var respErr *responseError
+		if attempt == 0 && k.roleID != "" && errors.As(err, &respErr) && respErr.code == http.StatusForbidden {
+			k.mu.Lock()
+			if k.mu.token == token {
+				k.mu.token = ""
+			}
+			k.mu.Unlock()
+			continue
+		}
+		return err
+	}
+}
+
+// getToken returns the token with which to authenticate requests, logging in
+// with AppRole if there is none.
+func (k *vaultKMS) getToken(ctx context.Context) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.mu.token != "" || k.roleID == "" {
+		return k.mu.token, nil
+	}
+
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := k.do(ctx, path.Join("auth", k.appRolePath, "login"), "", map[string]string{
+		"role_id":   k.roleID,
+		"secret_id": k.secretID,
+	}, &resp); err != nil {
+		return "", errors.Wrap(err, "vault approle login")
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", errors.New("vault approle login did not return a token")
+	}
+	k.mu.token = resp.Auth.ClientToken
+	return k.mu.token, nil
+}
+
+// responseError is an error returned by the Vault HTTP API.
+type responseError struct {
+	code   int
+	errors []string
+}
+
+func (e *responseError) Error() string {
+	if len(e.errors) == 0 {
+		return fmt.Sprintf("unexpected status %d", e.code)
+	}
+	return fmt.Sprintf("%s (status %d)", strings.Join(e.errors, "; "), e.code)
+}
+
+// do sends a request with the given JSON body to the Vault HTTP API and
+// decodes the response into resp.
+func (k *vaultKMS) do(
+	ctx context.Context, apiPath, token string, req interface{}, resp interface{},
+) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	u := k.address
+	u.Path = "/v1/" + apiPath
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		httpReq.Header.Set("X-Vault-Token", token)
+	}
+	if k.namespace != "" {
+		httpReq.Header.Set("X-Vault-Namespace", k.namespace)
+	}
+
+	httpResp, err := k.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		respErr := &responseError{code: httpResp.StatusCode}
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &errResp) == nil {
+			respErr.errors = errResp.Errors
+		}
+		return respErr
+	}
+	return json.Unmarshal(respBody, resp)
+}
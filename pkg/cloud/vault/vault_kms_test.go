@@ -0,0 +1,233 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/testutils/skip"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testKMSEnv() *cloud.TestKMSEnv {
+	return &cloud.TestKMSEnv{
+		Settings:         cluster.MakeTestingClusterSettings(),
+		ExternalIOConfig: &base.ExternalIODirConfig{},
+	}
+}
+
+// fakeTransitServer implements the subset of the Vault HTTP API used by the
+// KMS: the encrypt and decrypt endpoints of a transit engine mounted at
+// "transit" and AppRole login. Its "ciphertexts" are just base64 encoded
+// plaintexts.
+type fakeTransitServer struct {
+	*httptest.Server
+	mu struct {
+		syncutil.Mutex
+		tokens     map[string]bool
+		logins     int
+		namespaces []string
+	}
+}
+
+func startFakeTransitServer(t *testing.T) *fakeTransitServer {
+	s := &fakeTransitServer{}
+	s.mu.tokens = map[string]bool{"root": true}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		respond := func(code int, resp interface{}) {
+			w.WriteHeader(code)
+			require.NoError(t, json.NewEncoder(w).Encode(resp))
+		}
+		denied := map[string][]string{"errors": {"permission denied"}}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.mu.namespaces = append(s.mu.namespaces, r.Header.Get("X-Vault-Namespace"))
+		if r.URL.Path == "/v1/auth/approle/login" {
+			if req["role_id"] != "role" || req["secret_id"] != "secret" {
+				respond(http.StatusBadRequest, map[string][]string{"errors": {"invalid role or secret ID"}})
+				return
+			}
+			s.mu.logins++
+			token := fmt.Sprintf("approle-%d", s.mu.logins)
+			s.mu.tokens[token] = true
+			respond(http.StatusOK, map[string]interface{}{"auth": map[string]string{"client_token": token}})
+			return
+		}
+		if !s.mu.tokens[r.Header.Get("X-Vault-Token")] {
+			respond(http.StatusForbidden, denied)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/backup-key":
+			respond(http.StatusOK, map[string]interface{}{
+				"data": map[string]string{"ciphertext": "vault:v1:" + req["plaintext"]},
+			})
+		case "/v1/transit/decrypt/backup-key":
+			if !strings.HasPrefix(req["ciphertext"], "vault:v1:") {
+				respond(http.StatusBadRequest, map[string][]string{"errors": {"invalid ciphertext"}})
+				return
+			}
+			respond(http.StatusOK, map[string]interface{}{
+				"data": map[string]string{"plaintext": strings.TrimPrefix(req["ciphertext"], "vault:v1:")},
+			})
+		default:
+			respond(http.StatusNotFound, map[string][]string{"errors": {}})
+		}
+	}))
+	return s
+}
+
+func TestVaultKMSFakeServer(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+
+	s := startFakeTransitServer(t)
+	defer s.Close()
+	host := strings.TrimPrefix(s.URL, "http://")
+
+	t.Run("token", func(t *testing.T) {
+		uri := fmt.Sprintf("vault-transit://%s/transit/backup-key?%s=root&%s=true&%s=ns1",
+			host, TokenParam, DisableTLSParam, NamespaceParam)
+		cloud.KMSEncryptDecrypt(t, uri, testKMSEnv())
+
+		k, err := cloud.KMSFromURI(ctx, uri, testKMSEnv())
+		require.NoError(t, err)
+		defer func() { require.NoError(t, k.Close()) }()
+		id, err := k.MasterKeyID()
+		require.NoError(t, err)
+		require.Equal(t, "transit/backup-key", id)
+
+		ciphertext, err := k.Encrypt(ctx, []byte("data key"))
+		require.NoError(t, err)
+		require.Equal(t, "vault:v1:"+base64.StdEncoding.EncodeToString([]byte("data key")), string(ciphertext))
+
+		s.mu.Lock()
+		require.Equal(t, "ns1", s.mu.namespaces[len(s.mu.namespaces)-1])
+		s.mu.Unlock()
+	})
+
+	t.Run("approle", func(t *testing.T) {
+		// The transit mount defaults to "transit" if only the key is named.
+		uri := fmt.Sprintf("vault-transit://%s/backup-key?%s=role&%s=secret&%s=true",
+			host, RoleIDParam, SecretIDParam, DisableTLSParam)
+		k, err := cloud.KMSFromURI(ctx, uri, testKMSEnv())
+		require.NoError(t, err)
+		defer func() { require.NoError(t, k.Close()) }()
+
+		ciphertext, err := k.Encrypt(ctx, []byte("data key"))
+		require.NoError(t, err)
+
+		// Revoke the token obtained by logging in; the KMS logs in again.
+		s.mu.Lock()
+		require.Equal(t, 1, s.mu.logins)
+		s.mu.tokens = map[string]bool{}
+		s.mu.Unlock()
+		plaintext, err := k.Decrypt(ctx, ciphertext)
+		require.NoError(t, err)
+		require.Equal(t, "data key", string(plaintext))
+		s.mu.Lock()
+		require.Equal(t, 2, s.mu.logins)
+		s.mu.Unlock()
+
+		_, err = k.Decrypt(ctx, []byte("not a ciphertext"))
+		require.EqualError(t, err, "vault transit decrypt: invalid ciphertext (status 400)")
+	})
+
+	t.Run("bad-token", func(t *testing.T) {
+		uri := fmt.Sprintf("vault-transit://%s/transit/backup-key?%s=wrong&%s=true",
+			host, TokenParam, DisableTLSParam)
+		k, err := cloud.KMSFromURI(ctx, uri, testKMSEnv())
+		require.NoError(t, err)
+		defer func() { require.NoError(t, k.Close()) }()
+		_, err = k.Encrypt(ctx, []byte("data key"))
+		require.EqualError(t, err, "vault transit encrypt: permission denied (status 403)")
+	})
+}
+
+func TestVaultKMSURIParams(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		uri string
+		err string
+	}{
+		{uri: "vault-transit://vault:8200/?VAULT_TOKEN=t", err: "path component of the KMS cannot be empty"},
+		{uri: "vault-transit:///transit/key?VAULT_TOKEN=t", err: "host component of the KMS URI"},
+		{uri: "vault-transit://vault:8200/transit/key", err: "VAULT_TOKEN or VAULT_ROLE_ID and VAULT_SECRET_ID must be set"},
+		{uri: "vault-transit://vault:8200/transit/key?VAULT_ROLE_ID=r", err: "VAULT_ROLE_ID and VAULT_SECRET_ID must be set together"},
+		{uri: "vault-transit://vault:8200/transit/key?VAULT_TOKEN=t&VAULT_SECRET_ID=s", err: "VAULT_TOKEN cannot be combined"},
+		{uri: "vault-transit://vault:8200/transit/key?VAULT_TOKEN=t&FOO=bar", err: "unknown KMS query parameters: FOO"},
+		{uri: "vault-transit://vault:8200/transit/key?AUTH=implicit&VAULT_TOKEN=t", err: "cannot be set if \"AUTH\" is \"implicit\""},
+		{uri: "vault-transit://vault:8200/transit/key?AUTH=other", err: "unsupported value other for AUTH"},
+	} {
+		t.Run(tc.uri, func(t *testing.T) {
+			_, err := cloud.KMSFromURI(ctx, tc.uri, testKMSEnv())
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+
+	// Secrets are redacted from the URI when it is displayed.
+	redacted, err := cloud.RedactKMSURI(
+		"vault-transit://vault:8200/transit/key?VAULT_ROLE_ID=r&VAULT_SECRET_ID=s")
+	require.NoError(t, err)
+	require.Equal(t, "vault-transit://vault:8200/redacted?VAULT_ROLE_ID=r&VAULT_SECRET_ID=redacted", redacted)
+}
+
+// TestEncryptDecryptVault runs against a real Vault server, such as one
+// started with `vault server -dev` followed by `vault secrets enable transit`
+// and `vault write -f transit/keys/<key>`.
+func TestEncryptDecryptVault(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		skip.IgnoreLint(t, "VAULT_ADDR env var must be set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		skip.IgnoreLint(t, "VAULT_TOKEN env var must be set")
+	}
+	key := os.Getenv("VAULT_TRANSIT_KEY")
+	if key == "" {
+		skip.IgnoreLint(t, "VAULT_TRANSIT_KEY env var must be set")
+	}
+	u, err := url.Parse(addr)
+	require.NoError(t, err)
+
+	q := make(url.Values)
+	q.Set(TokenParam, token)
+	if u.Scheme == "http" {
+		q.Set(DisableTLSParam, "true")
+	}
+	uri := fmt.Sprintf("vault-transit://%s/transit/%s?%s", u.Host, key, q.Encode())
+	cloud.KMSEncryptDecrypt(t, uri, testKMSEnv())
+}
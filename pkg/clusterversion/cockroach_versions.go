@@ -561,6 +561,14 @@ const (
 	// and its ready for use.
 	V23_2_RegionaLivenessTable
 
+	// V23_2_RaftCommandCompression gates the compression of the payload of
+	// raft commands, see kv.raft.command.compression.
+	V23_2_RaftCommandCompression
+
+	// V23_2_WitnessReplicas gates the WITNESS replica type, which nodes running
+	// older versions can't handle in range descriptors or snapshots.
+	V23_2_WitnessReplicas
+
 	// *************************************************
 	// Step (1) Add new versions here.
 	// Do not add new versions to a patch release.
@@ -979,6 +987,14 @@ var rawVersionsSingleton = keyedVersions{
 		Key:     V23_2_RegionaLivenessTable,
 		Version: roachpb.Version{Major: 23, Minor: 1, Internal: 20},
 	},
+	{
+		Key:     V23_2_RaftCommandCompression,
+		Version: roachpb.Version{Major: 23, Minor: 1, Internal: 22},
+	},
+	{
+		Key:     V23_2_WitnessReplicas,
+		Version: roachpb.Version{Major: 23, Minor: 1, Internal: 24},
+	},
 
 	// *************************************************
 	// Step (2): Add new versions here.
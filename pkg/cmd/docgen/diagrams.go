@@ -368,7 +368,7 @@ var specs = []stmtSpec{
 		replace: map[string]string{
 			"'ALTER' 'BACKUP' string_or_placeholder":                   "'ALTER' 'BACKUP' ( 'LATEST' | subdirectory ) 'IN' collectionURI",
 			"'IN' string_or_placeholder":                               "",
-			"alter_backup_cmds":                                        "'ADD' 'NEW_KMS' kmsURI 'WITH' 'OLD_KMS' kmsURI",
+			"alter_backup_cmds":                                        "( 'ADD' 'NEW_KMS' kmsURI 'WITH' 'OLD_KMS' kmsURI | 'COMPACT' opt_with_backup_options )",
 			"'ALTER' 'BACKUP' string_or_placeholder alter_backup_cmds": "",
 		},
 		unlink: []string{"subdirectory", "collectionURI", "kmsURI"},
@@ -602,6 +602,14 @@ var specs = []stmtSpec{
 	{
 		name: "analyze_stmt",
 	},
+	{
+		name: "attach_backup",
+		stmt: "attach_backup_stmt",
+		replace: map[string]string{
+			"'FROM' string_or_placeholder 'IN' string_or_placeholder_opt_list": "'FROM' ( 'LATEST' | subdirectory ) 'IN' collectionURI",
+		},
+		unlink: []string{"subdirectory", "collectionURI"},
+	},
 	{
 		name:   "backup",
 		stmt:   "backup_stmt",
@@ -17,6 +17,9 @@ func _() {
 	_ = x[Constraints-7]
 	_ = x[VoterConstraints-8]
 	_ = x[LeasePreferences-9]
+	_ = x[NumWitnesses-10]
+	_ = x[MaxWriteBytesPerSecond-11]
+	_ = x[MaxWriteRequestsPerSecond-12]
 }
 
 func (i Field) String() string {
@@ -39,6 +42,12 @@ func (i Field) String() string {
 		return "voter_constraints"
 	case LeasePreferences:
 		return "lease_preferences"
+	case NumWitnesses:
+		return "num_witnesses"
+	case MaxWriteBytesPerSecond:
+		return "max_write_bytes_per_second"
+	case MaxWriteRequestsPerSecond:
+		return "max_write_requests_per_second"
 	default:
 		return "Field(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
@@ -355,6 +355,14 @@ func (z *ZoneConfig) Validate() error {
 		}
 	}
 
+	var numWitnesses int32
+	if z.NumWitnesses != nil {
+		numWitnesses = *z.NumWitnesses
+		if numWitnesses < 0 {
+			return fmt.Errorf("num_witnesses cannot be negative")
+		}
+	}
+
 	if z.NumReplicas != nil {
 		switch {
 		case *z.NumReplicas < 0:
@@ -367,7 +375,7 @@ func (z *ZoneConfig) Validate() error {
 			}
 			return fmt.Errorf("at least one replica is required")
 		case *z.NumReplicas == 2:
-			if !(z.NumVoters != nil && *z.NumVoters > 0) {
+			if !(z.NumVoters != nil && *z.NumVoters > 0) && numWitnesses == 0 {
 				return fmt.Errorf("at least 3 replicas are required for multi-replica configurations")
 			}
 		}
@@ -379,7 +387,7 @@ func (z *ZoneConfig) Validate() error {
 		switch {
 		case *z.NumVoters <= 0:
 			return fmt.Errorf("at least one voting replica is required")
-		case *z.NumVoters == 2:
+		case *z.NumVoters == 2 && numWitnesses == 0:
 			return fmt.Errorf("at least 3 voting replicas are required for multi-replica configurations")
 		}
 		if z.NumReplicas != nil && *z.NumVoters > *z.NumReplicas {
@@ -387,6 +395,20 @@ func (z *ZoneConfig) Validate() error {
 		}
 	}
 
+	// Witnesses don't store any data, so we require a majority of the raft
+	// group to be made up of voters that do.
+	if numWitnesses > 0 {
+		numVoters := int32(0)
+		if z.NumVoters != nil && *z.NumVoters > 0 {
+			numVoters = *z.NumVoters
+		} else if z.NumReplicas != nil {
+			numVoters = *z.NumReplicas
+		}
+		if numVoters > 0 && numWitnesses >= numVoters {
+			return fmt.Errorf("num_witnesses must be less than the number of voting replicas")
+		}
+	}
+
 	if z.RangeMaxBytes != nil && *z.RangeMaxBytes < minRangeMaxBytes {
 		return fmt.Errorf("RangeMaxBytes %d less than minimum allowed %d",
 			*z.RangeMaxBytes, minRangeMaxBytes)
@@ -400,6 +422,13 @@ func (z *ZoneConfig) Validate() error {
 			*z.RangeMinBytes, *z.RangeMaxBytes)
 	}
 
+	if z.MaxWriteBytesPerSecond != nil && *z.MaxWriteBytesPerSecond < 0 {
+		return fmt.Errorf("max_write_bytes_per_second cannot be negative")
+	}
+	if z.MaxWriteRequestsPerSecond != nil && *z.MaxWriteRequestsPerSecond < 0 {
+		return fmt.Errorf("max_write_requests_per_second cannot be negative")
+	}
+
 	// Reserve the value 0 to potentially have some special meaning in the future,
 	// such as to disable GC.
 	if z.GC != nil && z.GC.TTLSeconds < 1 {
@@ -553,11 +582,26 @@ func (z *ZoneConfig) InheritFromParent(parent *ZoneConfig) {
 			z.NumVoters = proto.Int32(*parent.NumVoters)
 		}
 	}
+	if z.NumWitnesses == nil {
+		if parent.NumWitnesses != nil {
+			z.NumWitnesses = proto.Int32(*parent.NumWitnesses)
+		}
+	}
 	if z.GlobalReads == nil {
 		if parent.GlobalReads != nil {
 			z.GlobalReads = proto.Bool(*parent.GlobalReads)
 		}
 	}
+	if z.MaxWriteBytesPerSecond == nil {
+		if parent.MaxWriteBytesPerSecond != nil {
+			z.MaxWriteBytesPerSecond = proto.Int64(*parent.MaxWriteBytesPerSecond)
+		}
+	}
+	if z.MaxWriteRequestsPerSecond == nil {
+		if parent.MaxWriteRequestsPerSecond != nil {
+			z.MaxWriteRequestsPerSecond = proto.Int64(*parent.MaxWriteRequestsPerSecond)
+		}
+	}
 	if z.RangeMinBytes == nil {
 		if parent.RangeMinBytes != nil {
 			z.RangeMinBytes = proto.Int64(*parent.RangeMinBytes)
@@ -602,6 +646,11 @@ func (z *ZoneConfig) CopyFromZone(other ZoneConfig, fieldList []tree.Name) {
 			if other.NumVoters != nil {
 				z.NumVoters = proto.Int32(*other.NumVoters)
 			}
+		case "num_witnesses":
+			z.NumWitnesses = nil
+			if other.NumWitnesses != nil {
+				z.NumWitnesses = proto.Int32(*other.NumWitnesses)
+			}
 		case "range_min_bytes":
 			z.RangeMinBytes = nil
 			if other.RangeMinBytes != nil {
@@ -617,6 +666,16 @@ func (z *ZoneConfig) CopyFromZone(other ZoneConfig, fieldList []tree.Name) {
 			if other.GlobalReads != nil {
 				z.GlobalReads = proto.Bool(*other.GlobalReads)
 			}
+		case "max_write_bytes_per_second":
+			z.MaxWriteBytesPerSecond = nil
+			if other.MaxWriteBytesPerSecond != nil {
+				z.MaxWriteBytesPerSecond = proto.Int64(*other.MaxWriteBytesPerSecond)
+			}
+		case "max_write_requests_per_second":
+			z.MaxWriteRequestsPerSecond = nil
+			if other.MaxWriteRequestsPerSecond != nil {
+				z.MaxWriteRequestsPerSecond = proto.Int64(*other.MaxWriteRequestsPerSecond)
+			}
 		case "gc.ttlseconds":
 			z.GC = nil
 			if other.GC != nil {
@@ -691,6 +750,16 @@ func (z *ZoneConfig) DiffWithZone(
 					Field: "num_voters",
 				}, nil
 			}
+		case "num_witnesses":
+			if other.NumWitnesses == nil && z.NumWitnesses == nil {
+				continue
+			}
+			if z.NumWitnesses == nil || other.NumWitnesses == nil ||
+				*z.NumWitnesses != *other.NumWitnesses {
+				return false, DiffWithZoneMismatch{
+					Field: "num_witnesses",
+				}, nil
+			}
 		case "range_min_bytes":
 			if other.RangeMinBytes == nil && z.RangeMinBytes == nil {
 				continue
@@ -721,6 +790,26 @@ func (z *ZoneConfig) DiffWithZone(
 					Field: "global_reads",
 				}, nil
 			}
+		case "max_write_bytes_per_second":
+			if other.MaxWriteBytesPerSecond == nil && z.MaxWriteBytesPerSecond == nil {
+				continue
+			}
+			if z.MaxWriteBytesPerSecond == nil || other.MaxWriteBytesPerSecond == nil ||
+				*z.MaxWriteBytesPerSecond != *other.MaxWriteBytesPerSecond {
+				return false, DiffWithZoneMismatch{
+					Field: "max_write_bytes_per_second",
+				}, nil
+			}
+		case "max_write_requests_per_second":
+			if other.MaxWriteRequestsPerSecond == nil && z.MaxWriteRequestsPerSecond == nil {
+				continue
+			}
+			if z.MaxWriteRequestsPerSecond == nil || other.MaxWriteRequestsPerSecond == nil ||
+				*z.MaxWriteRequestsPerSecond != *other.MaxWriteRequestsPerSecond {
+				return false, DiffWithZoneMismatch{
+					Field: "max_write_requests_per_second",
+				}, nil
+			}
 		case "gc.ttlseconds":
 			if other.GC == nil && z.GC == nil {
 				continue
@@ -1132,6 +1221,16 @@ func (z *ZoneConfig) toSpanConfig() (roachpb.SpanConfig, error) {
 	if z.NumVoters != nil {
 		sc.NumVoters = *z.NumVoters
 	}
+	if z.NumWitnesses != nil {
+		sc.NumWitnesses = *z.NumWitnesses
+	}
+	// Writes are unlimited by default.
+	if z.MaxWriteBytesPerSecond != nil {
+		sc.MaxWriteBytesPerSecond = *z.MaxWriteBytesPerSecond
+	}
+	if z.MaxWriteRequestsPerSecond != nil {
+		sc.MaxWriteRequestsPerSecond = *z.MaxWriteRequestsPerSecond
+	}
 
 	toSpanConfigConstraints := func(src []Constraint) ([]roachpb.Constraint, error) {
 		spanConfigConstraints := make([]roachpb.Constraint, len(src))
@@ -245,6 +245,34 @@ func TestZoneConfigValidate(t *testing.T) {
 			},
 			"",
 		},
+		{
+			ZoneConfig{
+				NumReplicas:            proto.Int32(1),
+				RangeMaxBytes:          DefaultZoneConfig().RangeMaxBytes,
+				GC:                     &GCPolicy{TTLSeconds: 1},
+				MaxWriteBytesPerSecond: proto.Int64(-1),
+			},
+			"max_write_bytes_per_second cannot be negative",
+		},
+		{
+			ZoneConfig{
+				NumReplicas:               proto.Int32(1),
+				RangeMaxBytes:             DefaultZoneConfig().RangeMaxBytes,
+				GC:                        &GCPolicy{TTLSeconds: 1},
+				MaxWriteRequestsPerSecond: proto.Int64(-1),
+			},
+			"max_write_requests_per_second cannot be negative",
+		},
+		{
+			ZoneConfig{
+				NumReplicas:               proto.Int32(1),
+				RangeMaxBytes:             DefaultZoneConfig().RangeMaxBytes,
+				GC:                        &GCPolicy{TTLSeconds: 1},
+				MaxWriteBytesPerSecond:    proto.Int64(1 << 20),
+				MaxWriteRequestsPerSecond: proto.Int64(0),
+			},
+			"",
+		},
 	}
 
 	for i, c := range testCases {
@@ -285,6 +313,57 @@ func TestZoneConfigValidateNonVoterSpecific(t *testing.T) {
 	}
 }
 
+func TestZoneConfigValidateWitnessSpecific(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		cfg      ZoneConfig
+		expected string
+	}{
+		{
+			cfg: ZoneConfig{
+				NumReplicas:  proto.Int32(2),
+				NumWitnesses: proto.Int32(1),
+			},
+		},
+		{
+			cfg: ZoneConfig{
+				NumReplicas:  proto.Int32(4),
+				NumVoters:    proto.Int32(2),
+				NumWitnesses: proto.Int32(1),
+			},
+		},
+		{
+			cfg: ZoneConfig{
+				NumReplicas:  proto.Int32(2),
+				NumWitnesses: proto.Int32(0),
+			},
+			expected: "at least 3 replicas are required for multi-replica configurations",
+		},
+		{
+			cfg: ZoneConfig{
+				NumReplicas:  proto.Int32(2),
+				NumWitnesses: proto.Int32(2),
+			},
+			expected: "num_witnesses must be less than the number of voting replicas",
+		},
+		{
+			cfg: ZoneConfig{
+				NumReplicas:  proto.Int32(3),
+				NumWitnesses: proto.Int32(-1),
+			},
+			expected: "num_witnesses cannot be negative",
+		},
+	}
+
+	for i, c := range testCases {
+		err := c.cfg.Validate()
+		if !testutils.IsError(err, c.expected) {
+			t.Errorf("%d: expected %q, got %v", i, c.expected, err)
+		}
+	}
+}
+
 func TestZoneConfigValidateTandemFields(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
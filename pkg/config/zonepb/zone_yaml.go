@@ -205,6 +205,9 @@ type marshalableZoneConfig struct {
 	GlobalReads                  *bool             `json:"global_reads" yaml:"global_reads"`
 	NumReplicas                  *int32            `json:"num_replicas" yaml:"num_replicas"`
 	NumVoters                    *int32            `json:"num_voters" yaml:"num_voters"`
+	NumWitnesses                 *int32            `json:"num_witnesses,omitempty" yaml:"num_witnesses,omitempty"`
+	MaxWriteBytesPerSecond       *int64            `json:"max_write_bytes_per_second,omitempty" yaml:"max_write_bytes_per_second,omitempty"`
+	MaxWriteRequestsPerSecond    *int64            `json:"max_write_requests_per_second,omitempty" yaml:"max_write_requests_per_second,omitempty"`
 	Constraints                  ConstraintsList   `json:"constraints" yaml:"constraints,flow"`
 	VoterConstraints             ConstraintsList   `json:"voter_constraints" yaml:"voter_constraints,flow"`
 	LeasePreferences             []LeasePreference `json:"lease_preferences" yaml:"lease_preferences,flow"`
@@ -235,6 +238,15 @@ func zoneConfigToMarshalable(c ZoneConfig) marshalableZoneConfig {
 	if c.NumVoters != nil && *c.NumVoters != 0 {
 		m.NumVoters = proto.Int32(*c.NumVoters)
 	}
+	if c.NumWitnesses != nil && *c.NumWitnesses != 0 {
+		m.NumWitnesses = proto.Int32(*c.NumWitnesses)
+	}
+	if c.MaxWriteBytesPerSecond != nil {
+		m.MaxWriteBytesPerSecond = proto.Int64(*c.MaxWriteBytesPerSecond)
+	}
+	if c.MaxWriteRequestsPerSecond != nil {
+		m.MaxWriteRequestsPerSecond = proto.Int64(*c.MaxWriteRequestsPerSecond)
+	}
 	// NB: In order to preserve round-trippability, we're directly using
 	// `NullVoterConstraintsIsEmpty` as opposed to calling
 	// `c.InheritedVoterConstraints()`. This is copacetic as long as the value is
@@ -275,6 +287,15 @@ func zoneConfigFromMarshalable(m marshalableZoneConfig, c ZoneConfig) ZoneConfig
 	if m.NumVoters != nil {
 		c.NumVoters = proto.Int32(*m.NumVoters)
 	}
+	if m.NumWitnesses != nil {
+		c.NumWitnesses = proto.Int32(*m.NumWitnesses)
+	}
+	if m.MaxWriteBytesPerSecond != nil {
+		c.MaxWriteBytesPerSecond = proto.Int64(*m.MaxWriteBytesPerSecond)
+	}
+	if m.MaxWriteRequestsPerSecond != nil {
+		c.MaxWriteRequestsPerSecond = proto.Int64(*m.MaxWriteRequestsPerSecond)
+	}
 	c.VoterConstraints = m.VoterConstraints.Constraints
 	c.NullVoterConstraintsIsEmpty = !m.VoterConstraints.Inherited
 	if m.LeasePreferences != nil {
@@ -66,6 +66,7 @@ DIAGRAMS_SRCS = [
     "//docs/generated/sql/bnf:alter_zone_range.html",
     "//docs/generated/sql/bnf:alter_zone_table.html",
     "//docs/generated/sql/bnf:analyze.html",
+    "//docs/generated/sql/bnf:attach_backup.html",
     "//docs/generated/sql/bnf:backup.html",
     "//docs/generated/sql/bnf:backup_options.html",
     "//docs/generated/sql/bnf:begin.html",
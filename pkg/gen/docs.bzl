@@ -78,6 +78,7 @@ DOCS_SRCS = [
     "//docs/generated/sql/bnf:alter_zone_range_stmt.bnf",
     "//docs/generated/sql/bnf:alter_zone_table_stmt.bnf",
     "//docs/generated/sql/bnf:analyze_stmt.bnf",
+    "//docs/generated/sql/bnf:attach_backup.bnf",
     "//docs/generated/sql/bnf:backup.bnf",
     "//docs/generated/sql/bnf:backup_options.bnf",
     "//docs/generated/sql/bnf:begin_stmt.bnf",
@@ -83,6 +83,16 @@ const (
 	// KeyDistSQLDrainingPrefix is the key prefix for each node's DistSQL
 	// draining state.
 	KeyDistSQLDrainingPrefix = "distsql-draining"
+
+	// KeyTableWriteDemandPrefix is the key prefix for gossiping the write
+	// demand of tables with write rate limits on each store. The suffix is a
+	// store ID and the value is a kvserverpb.TableWriteDemand.
+	KeyTableWriteDemandPrefix = "table-write-demand"
+
+	// KeySnapshotSchedulerDemandPrefix is the key prefix for gossiping the
+	// snapshot demand of each node. The suffix is a node ID and the value is a
+	// kvserverpb.SnapshotSchedulerDemand.
+	KeySnapshotSchedulerDemandPrefix = "snapshot-scheduler-demand"
 )
 
 // MakeKey creates a canonical key under which to gossip a piece of
@@ -156,6 +166,27 @@ func DecodeStoreDescKey(storeKey string) (roachpb.StoreID, error) {
 	return roachpb.StoreID(storeID), nil
 }
 
+// MakeDemandKey returns the gossip key for the demand of the node or store
+// with the given ID, for a demand key prefix such as KeyTableWriteDemandPrefix.
+func MakeDemandKey(prefix string, id int64) string {
+	return MakeKey(prefix, strconv.FormatInt(id, 10 /* base */))
+}
+
+// DecodeDemandKey attempts to extract a node or store ID from the provided key
+// after stripping the given demand key prefix. Returns an error if the key is
+// not of the correct type or is not parsable.
+func DecodeDemandKey(key, prefix string) (int64, error) {
+	trimmedKey, err := removePrefixFromKey(key, prefix)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(trimmedKey, 10 /* base */, 64 /* bitSize */)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed parsing ID from key %q", key)
+	}
+	return id, nil
+}
+
 // MakeDistSQLNodeVersionKey returns the gossip key for the given store.
 func MakeDistSQLNodeVersionKey(instanceID base.SQLInstanceID) string {
 	return MakeKey(KeyDistSQLNodeVersionKeyPrefix, instanceID.String())
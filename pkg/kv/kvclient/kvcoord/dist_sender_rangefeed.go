@@ -93,6 +93,7 @@ type rangeFeedConfig struct {
 	useMuxRangeFeed bool
 	overSystemTable bool
 	withDiff        bool
+	filter          *kvpb.RangeFeedFilter
 
 	knobs struct {
 		// onRangefeedEvent invoked on each rangefeed event.
@@ -139,6 +140,16 @@ func WithDiff() RangeFeedOption {
 	})
 }
 
+// WithFilter configures the rangefeed to filter the RangeFeedValue events
+// emitted by each range on the server, before they are sent over the network.
+// The filter is best-effort: servers which do not support it emit all events,
+// so callers must still apply the equivalent filter to the events they receive.
+func WithFilter(filter *kvpb.RangeFeedFilter) RangeFeedOption {
+	return optionFunc(func(c *rangeFeedConfig) {
+		c.filter = filter
+	})
+}
+
 // A "kill switch" to disable multiplexing rangefeed if severe issues discovered with new implementation.
 var enableMuxRangeFeed = envutil.EnvOrDefaultBool("COCKROACH_ENABLE_MULTIPLEXING_RANGEFEED", true)
 
@@ -721,6 +732,7 @@ func (ds *DistSender) singleRangeFeed(
 	}()
 
 	args := makeRangeFeedRequest(span, desc.RangeID, cfg.overSystemTable, startAfter, cfg.withDiff)
+	args.Filter = cfg.filter
 	transport, err := newTransportForRange(ctx, desc, ds)
 	if err != nil {
 		return args.Timestamp, err
@@ -4339,6 +4339,7 @@ func TestRefreshFailureIncludesConflictingTxn(t *testing.T) {
 
 			tErr := (*kvpb.TransactionRetryWithProtoRefreshError)(nil)
 			require.ErrorAs(t, err, &tErr)
+			require.Equal(t, roachpb.Key(keyA), tErr.ConflictingKey)
 
 			if committed {
 				require.Nil(t, tErr.ConflictingTxn)
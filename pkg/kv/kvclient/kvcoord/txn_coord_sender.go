@@ -807,10 +807,15 @@ func (tc *TxnCoordSender) handleRetryableErrLocked(ctx context.Context, pErr *kv
 	// reflect the reason for the restart. More details about the
 	// different error types are documented above on the metaRestart
 	// variables.
+	//
+	// The conflict which caused the error, if known, is carried over to the
+	// TransactionRetryWithProtoRefreshError so that it can be reported to the
+	// client.
 	var conflictingTxn *enginepb.TxnMeta
+	var conflictingKey roachpb.Key
 	switch tErr := pErr.GetDetail().(type) {
 	case *kvpb.TransactionRetryError:
-		conflictingTxn = tErr.ConflictingTxn
+		conflictingTxn, conflictingKey = tErr.ConflictingTxn, tErr.ConflictingKey
 		switch tErr.Reason {
 		case kvpb.RETRY_WRITE_TOO_OLD:
 			tc.metrics.RestartsWriteTooOld.Inc()
@@ -825,9 +830,11 @@ func (tc *TxnCoordSender) handleRetryableErrLocked(ctx context.Context, pErr *kv
 		}
 
 	case *kvpb.WriteTooOldError:
+		conflictingKey = tErr.Key
 		tc.metrics.RestartsWriteTooOldMulti.Inc()
 
 	case *kvpb.ReadWithinUncertaintyIntervalError:
+		conflictingKey = tErr.Key
 		tc.metrics.RestartsReadWithinUncertainty.Inc()
 
 	case *kvpb.TransactionAbortedError:
@@ -854,6 +861,7 @@ func (tc *TxnCoordSender) handleRetryableErrLocked(ctx context.Context, pErr *kv
 		prevTxn.Epoch,       /* prevTxnEpoch */
 		nextTxn,             /* nextTxn */
 		kvpb.WithConflictingTxn(conflictingTxn),
+		kvpb.WithConflictingKey(conflictingKey),
 	)
 
 	// Update the TxnCoordSender's state.
@@ -316,8 +316,7 @@ func (sr *txnSpanRefresher) maybeRefreshAndRetrySend(
 	// Try refreshing the txn spans so we can retry.
 	if refreshErr := sr.tryRefreshTxnSpans(ctx, refreshFrom, refreshToTxn); refreshErr != nil {
 		log.Eventf(ctx, "refresh failed; propagating original retry error")
-		// TODO(lidor): we should add refreshErr info to the returned error. See issue #41057.
-		return nil, pErr
+		return nil, annotateRetryErrorWithRefreshFailure(pErr, refreshErr)
 	}
 
 	// We've refreshed all of the read spans successfully and bumped
@@ -524,24 +523,62 @@ func newRetryErrorOnFailedPreemptiveRefresh(
 		reason = kvpb.RETRY_WRITE_TOO_OLD
 	}
 	var conflictingTxn *enginepb.TxnMeta
+	var conflictingKey roachpb.Key
 	msg := redact.StringBuilder{}
 	msg.SafeString("failed preemptive refresh")
 	if refreshErr != nil {
 		if refreshErr, ok := refreshErr.GetDetail().(*kvpb.RefreshFailedError); ok {
-			if refreshErr.ConflictingTxn != nil {
-				conflictingTxn = refreshErr.ConflictingTxn
-				msg.Printf(" due to a conflict: %s on key %s with conflicting txn %s", refreshErr.FailureReason(), refreshErr.Key, refreshErr.ConflictingTxn.Short())
-			} else {
-				msg.Printf(" due to a conflict: %s on key %s", refreshErr.FailureReason(), refreshErr.Key)
-			}
+			conflictingTxn, conflictingKey = refreshErr.ConflictingTxn, refreshErr.Key
+			printRefreshConflict(&msg, refreshErr)
 		} else {
 			msg.Printf(" - unknown error: %s", refreshErr)
 		}
 	}
-	retryErr := kvpb.NewTransactionRetryError(reason, msg.RedactableString(), kvpb.WithConflictingTxn(conflictingTxn))
+	retryErr := kvpb.NewTransactionRetryError(reason, msg.RedactableString(),
+		kvpb.WithConflictingTxn(conflictingTxn), kvpb.WithConflictingKey(conflictingKey))
 	return kvpb.NewErrorWithTxn(retryErr, txn)
 }
 
+// annotateRetryErrorWithRefreshFailure attaches the conflict which caused a
+// refresh to fail to the TransactionRetryError that the refresh attempted to
+// avoid, so that the conflict is not lost when the error is returned to the
+// client. Other retry errors already carry the key they conflicted on, and are
+// returned unchanged.
+func annotateRetryErrorWithRefreshFailure(pErr, refreshErr *kvpb.Error) *kvpb.Error {
+	retryErr, ok := pErr.GetDetail().(*kvpb.TransactionRetryError)
+	if !ok || retryErr.ConflictingKey != nil {
+		return pErr
+	}
+	refreshFailedErr, ok := refreshErr.GetDetail().(*kvpb.RefreshFailedError)
+	if !ok {
+		return pErr
+	}
+	msg := redact.StringBuilder{}
+	if retryErr.ExtraMsgRedactable != "" {
+		msg.Print(retryErr.ExtraMsgRedactable)
+		msg.SafeString("; ")
+	}
+	msg.SafeString("failed refresh")
+	printRefreshConflict(&msg, refreshFailedErr)
+	annotated := kvpb.NewErrorWithTxn(kvpb.NewTransactionRetryError(
+		retryErr.Reason, msg.RedactableString(),
+		kvpb.WithConflictingTxn(refreshFailedErr.ConflictingTxn),
+		kvpb.WithConflictingKey(refreshFailedErr.Key)), pErr.GetTxn())
+	annotated.Index = pErr.Index
+	annotated.OriginNode = pErr.OriginNode
+	annotated.Now = pErr.Now
+	return annotated
+}
+
+// printRefreshConflict describes the conflict which caused a refresh to fail.
+func printRefreshConflict(msg *redact.StringBuilder, refreshErr *kvpb.RefreshFailedError) {
+	if refreshErr.ConflictingTxn != nil {
+		msg.Printf(" due to a conflict: %s on key %s with conflicting txn %s", refreshErr.FailureReason(), refreshErr.Key, refreshErr.ConflictingTxn.Short())
+	} else {
+		msg.Printf(" due to a conflict: %s on key %s", refreshErr.FailureReason(), refreshErr.Key)
+	}
+}
+
 // tryRefreshTxnSpans sends Refresh and RefreshRange commands to all spans read
 // during the transaction to ensure that no writes were written more recently
 // than refreshFrom. All implicated timestamp caches are updated with the final
@@ -350,6 +350,65 @@ func TestTxnSpanRefresherRefreshesTransactions(t *testing.T) {
 	}
 }
 
+// TestTxnSpanRefresherAnnotatesRetryErrorOnFailedRefresh tests that when the
+// txnSpanRefresher fails to refresh away a TransactionRetryError, the conflict
+// which caused the refresh to fail is attached to the returned error.
+func TestTxnSpanRefresherAnnotatesRetryErrorOnFailedRefresh(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+	tsr, mockSender := makeMockTxnSpanRefresher()
+
+	txn := makeTxnProto()
+	conflictingTxn := makeTxnProto()
+	keyA, keyB := roachpb.Key("a"), roachpb.Key("b")
+
+	// Collect some refresh spans.
+	ba := &kvpb.BatchRequest{}
+	ba.Header = kvpb.Header{Txn: txn.Clone()}
+	scanArgs := kvpb.ScanRequest{RequestHeader: kvpb.RequestHeader{Key: keyA, EndKey: keyB}}
+	ba.Add(&scanArgs)
+
+	br, pErr := tsr.SendLocked(ctx, ba)
+	require.Nil(t, pErr)
+	require.NotNil(t, br)
+
+	// Send a request that hits a RETRY_SERIALIZABLE error, and fail the
+	// refresh on an intent of the conflicting transaction.
+	onPut := func(ba *kvpb.BatchRequest) (*kvpb.BatchResponse, *kvpb.Error) {
+		pErr := kvpb.NewError(&kvpb.TransactionRetryError{Reason: kvpb.RETRY_SERIALIZABLE})
+		pErr.SetTxn(ba.Txn)
+		return nil, pErr
+	}
+	onRefresh := func(ba *kvpb.BatchRequest) (*kvpb.BatchResponse, *kvpb.Error) {
+		require.Len(t, ba.Requests, 1)
+		require.IsType(t, &kvpb.RefreshRangeRequest{}, ba.Requests[0].GetInner())
+		return nil, kvpb.NewError(kvpb.NewRefreshFailedError(ctx,
+			kvpb.RefreshFailedError_REASON_INTENT, keyA, hlc.Timestamp{WallTime: 1},
+			kvpb.WithConflictingTxn(&conflictingTxn.TxnMeta)))
+	}
+	mockSender.ChainMockSend(onPut, onRefresh)
+
+	ba.Requests = nil
+	putArgs := kvpb.PutRequest{RequestHeader: kvpb.RequestHeader{Key: keyB}}
+	ba.Add(&putArgs)
+
+	br, pErr = tsr.SendLocked(ctx, ba)
+	require.Nil(t, br)
+	require.NotNil(t, pErr)
+	require.NotNil(t, pErr.GetTxn())
+	require.Regexp(t,
+		"TransactionRetryError: retry txn \\(RETRY_SERIALIZABLE - failed refresh "+
+			"due to a conflict: intent on key \"a\" with conflicting txn", pErr)
+	retryErr, ok := pErr.GetDetail().(*kvpb.TransactionRetryError)
+	require.True(t, ok)
+	require.Equal(t, kvpb.RETRY_SERIALIZABLE, retryErr.Reason)
+	require.Equal(t, keyA, retryErr.ConflictingKey)
+	require.NotNil(t, retryErr.ConflictingTxn)
+	require.Equal(t, conflictingTxn.ID, retryErr.ConflictingTxn.ID)
+	require.Equal(t, int64(1), tsr.metrics.ClientRefreshFail.Count())
+}
+
 // TestTxnSpanRefresherDowngradesStagingTxnStatus tests that the txnSpanRefresher
 // tolerates retry errors with a STAGING transaction status. In such cases, it
 // will downgrade the status to PENDING before refreshing and retrying, because
@@ -717,6 +717,7 @@ func (e *TransactionAbortedError) SafeFormatError(p errors.Printer) (next error)
 
 type retryErrOptions struct {
 	conflictingTxn *enginepb.TxnMeta
+	conflictingKey roachpb.Key
 }
 
 // RetryErrOption is used to annotate optional fields in retry related errors.
@@ -739,6 +740,14 @@ func WithConflictingTxn(txn *enginepb.TxnMeta) RetryErrOption {
 	})
 }
 
+// WithConflictingKey is used to annotate a retry error with the key on which
+// the transaction conflicted (optional).
+func WithConflictingKey(key roachpb.Key) RetryErrOption {
+	return retryErrOptionFunc(func(o *retryErrOptions) {
+		o.conflictingKey = key
+	})
+}
+
 // NewTransactionRetryWithProtoRefreshError initializes a new
 // TransactionRetryWithProtoRefreshError.
 //
@@ -768,6 +777,7 @@ func NewTransactionRetryWithProtoRefreshError(
 		PrevTxnEpoch:    prevTxnEpoch,
 		NextTransaction: nextTxn,
 		ConflictingTxn:  options.conflictingTxn,
+		ConflictingKey:  options.conflictingKey,
 	}
 }
 
@@ -850,6 +860,7 @@ func NewTransactionRetryError(
 		ExtraMsg:           extraMsg.StripMarkers(),
 		ExtraMsgRedactable: extraMsg,
 		ConflictingTxn:     options.conflictingTxn,
+		ConflictingKey:     options.conflictingKey,
 	}
 }
 
@@ -19,6 +19,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/allocator"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/allocator/load"
@@ -136,6 +137,10 @@ const (
 	AllocatorReplaceDecommissioningNonVoter
 	AllocatorRemoveDecommissioningVoter
 	AllocatorRemoveDecommissioningNonVoter
+	AllocatorAddWitness
+	AllocatorRemoveWitness
+	AllocatorRemoveDeadWitness
+	AllocatorRemoveDecommissioningWitness
 	AllocatorRemoveLearner
 	AllocatorConsiderRebalance
 	AllocatorRangeUnavailable
@@ -144,7 +149,7 @@ const (
 
 // Add indicates an action adding a replica.
 func (a AllocatorAction) Add() bool {
-	return a == AllocatorAddVoter || a == AllocatorAddNonVoter
+	return a == AllocatorAddVoter || a == AllocatorAddNonVoter || a == AllocatorAddWitness
 }
 
 // Replace indicates an action replacing a dead or decommissioning replica.
@@ -162,10 +167,14 @@ func (a AllocatorAction) Remove() bool {
 		a == AllocatorRemoveDeadVoter ||
 		a == AllocatorRemoveDeadNonVoter ||
 		a == AllocatorRemoveDecommissioningVoter ||
-		a == AllocatorRemoveDecommissioningNonVoter
+		a == AllocatorRemoveDecommissioningNonVoter ||
+		a == AllocatorRemoveWitness ||
+		a == AllocatorRemoveDeadWitness ||
+		a == AllocatorRemoveDecommissioningWitness
 }
 
-// TargetReplicaType returns that the action is for a voter or non-voter replica.
+// TargetReplicaType returns that the action is for a voter, non-voter or
+// witness replica.
 func (a AllocatorAction) TargetReplicaType() TargetReplicaType {
 	var t TargetReplicaType
 	if a == AllocatorRemoveVoter ||
@@ -182,6 +191,11 @@ func (a AllocatorAction) TargetReplicaType() TargetReplicaType {
 		a == AllocatorReplaceDecommissioningNonVoter ||
 		a == AllocatorRemoveDecommissioningNonVoter {
 		t = NonVoterTarget
+	} else if a == AllocatorAddWitness ||
+		a == AllocatorRemoveWitness ||
+		a == AllocatorRemoveDeadWitness ||
+		a == AllocatorRemoveDecommissioningWitness {
+		t = WitnessTarget
 	}
 	return t
 }
@@ -193,17 +207,21 @@ func (a AllocatorAction) ReplicaStatus() ReplicaStatus {
 	if a == AllocatorRemoveVoter ||
 		a == AllocatorRemoveNonVoter ||
 		a == AllocatorAddVoter ||
-		a == AllocatorAddNonVoter {
+		a == AllocatorAddNonVoter ||
+		a == AllocatorAddWitness ||
+		a == AllocatorRemoveWitness {
 		s = Alive
 	} else if a == AllocatorReplaceDeadVoter ||
 		a == AllocatorReplaceDeadNonVoter ||
 		a == AllocatorRemoveDeadVoter ||
-		a == AllocatorRemoveDeadNonVoter {
+		a == AllocatorRemoveDeadNonVoter ||
+		a == AllocatorRemoveDeadWitness {
 		s = Dead
 	} else if a == AllocatorReplaceDecommissioningVoter ||
 		a == AllocatorReplaceDecommissioningNonVoter ||
 		a == AllocatorRemoveDecommissioningVoter ||
-		a == AllocatorRemoveDecommissioningNonVoter {
+		a == AllocatorRemoveDecommissioningNonVoter ||
+		a == AllocatorRemoveDecommissioningWitness {
 		s = Decommissioning
 	}
 	return s
@@ -223,6 +241,10 @@ var allocatorActionNames = map[AllocatorAction]string{
 	AllocatorReplaceDecommissioningNonVoter:  "replace decommissioning non-voter",
 	AllocatorRemoveDecommissioningVoter:      "remove decommissioning voter",
 	AllocatorRemoveDecommissioningNonVoter:   "remove decommissioning non-voter",
+	AllocatorAddWitness:                      "add witness",
+	AllocatorRemoveWitness:                   "remove witness",
+	AllocatorRemoveDeadWitness:               "remove dead witness",
+	AllocatorRemoveDecommissioningWitness:    "remove decommissioning witness",
 	AllocatorRemoveLearner:                   "remove learner",
 	AllocatorConsiderRebalance:               "consider rebalance",
 	AllocatorRangeUnavailable:                "range unavailable",
@@ -259,6 +281,14 @@ func (a AllocatorAction) Priority() float64 {
 		return 900
 	case AllocatorRemoveVoter:
 		return 800
+	case AllocatorAddWitness:
+		return 760
+	case AllocatorRemoveDeadWitness:
+		return 750
+	case AllocatorRemoveDecommissioningWitness:
+		return 740
+	case AllocatorRemoveWitness:
+		return 730
 	case AllocatorReplaceDeadNonVoter:
 		return 700
 	case AllocatorAddNonVoter:
@@ -278,8 +308,8 @@ func (a AllocatorAction) Priority() float64 {
 	}
 }
 
-// TargetReplicaType indicates whether the target replica is a voter or
-// non-voter.
+// TargetReplicaType indicates whether the target replica is a voter,
+// non-voter or witness.
 type TargetReplicaType int
 
 const (
@@ -288,6 +318,8 @@ const (
 	VoterTarget
 	// NonVoterTarget represents a non-voting target replica.
 	NonVoterTarget
+	// WitnessTarget represents a witness target replica.
+	WitnessTarget
 )
 
 // ReplicaStatus represents whether a replica is currently alive,
@@ -315,6 +347,8 @@ func (t TargetReplicaType) AddChangeType() roachpb.ReplicaChangeType {
 		return roachpb.ADD_VOTER
 	case NonVoterTarget:
 		return roachpb.ADD_NON_VOTER
+	case WitnessTarget:
+		return roachpb.ADD_WITNESS
 	default:
 		panic(fmt.Sprintf("unknown targetReplicaType %d", t))
 	}
@@ -328,6 +362,8 @@ func (t TargetReplicaType) RemoveChangeType() roachpb.ReplicaChangeType {
 		return roachpb.REMOVE_VOTER
 	case NonVoterTarget:
 		return roachpb.REMOVE_NON_VOTER
+	case WitnessTarget:
+		return roachpb.REMOVE_WITNESS
 	default:
 		panic(fmt.Sprintf("unknown targetReplicaType %d", t))
 	}
@@ -339,6 +375,8 @@ func (t TargetReplicaType) String() string {
 		return "voter"
 	case NonVoterTarget:
 		return "non-voter"
+	case WitnessTarget:
+		return "witness"
 	default:
 		panic(fmt.Sprintf("unknown targetReplicaType %d", t))
 	}
@@ -685,6 +723,32 @@ func GetNeededNonVoters(numVoters, zoneConfigNonVoterCount, clusterNodes int) in
 	return need
 }
 
+// WitnessesEnabled returns whether witnesses may be added to ranges, which is
+// only the case once no node runs a version that can't handle them.
+func (a *Allocator) WitnessesEnabled(ctx context.Context) bool {
+	return a.st.Version.IsActive(ctx, clusterversion.V23_2_WitnessReplicas)
+}
+
+// GetNeededWitnesses calculates the number of witnesses a range should have
+// given the number of voting replicas the range has and the number of nodes
+// available for up-replication. Like GetNeededNonVoters, it assumes that the
+// range has exactly as many voters as it needs.
+func GetNeededWitnesses(numVoters, zoneConfigWitnessCount, clusterNodes int) int {
+	need := zoneConfigWitnessCount
+	if clusterNodes-numVoters < need {
+		// Witnesses can only be placed on nodes that do not have a voter.
+		need = clusterNodes - numVoters
+	}
+	if need >= numVoters {
+		// A majority of the raft group must store the range's data.
+		need = numVoters - 1
+	}
+	if need < 0 {
+		need = 0 // Must be non-negative.
+	}
+	return need
+}
+
 // WillHaveFragileQuorum determines, based on the number of existing voters,
 // incoming voters, and needed voters, if we will be upreplicating to a state
 // in which we don't have enough needed voters and yet will have a fragile quorum
@@ -762,6 +826,13 @@ func DetermineReplicaToReplaceAndFilter(
 	case NonVoterTarget:
 		existing = nonVoters
 		deadReplicas = deadNonVoterReplicas
+	case WitnessTarget:
+		// Witnesses are only ever added, never replaced, and their allocation
+		// does not depend on the voters and non-voters being filtered.
+		if replicaStatus != Alive {
+			err = errors.AssertionFailedf("unexpected attempt to replace a witness with action %s", action)
+		}
+		return
 	default:
 		panic(fmt.Sprintf("unknown targetReplicaType: %s", replicaType))
 	}
@@ -925,7 +996,7 @@ func (a *Allocator) ComputeAction(
 	}
 
 	return a.computeAction(ctx, storePool, conf, desc.Replicas().VoterDescriptors(),
-		desc.Replicas().NonVoterDescriptors())
+		desc.Replicas().NonVoterDescriptors(), desc.Replicas().WitnessDescriptors())
 }
 
 func (a *Allocator) computeAction(
@@ -934,6 +1005,7 @@ func (a *Allocator) computeAction(
 	conf roachpb.SpanConfig,
 	voterReplicas []roachpb.ReplicaDescriptor,
 	nonVoterReplicas []roachpb.ReplicaDescriptor,
+	witnessReplicas []roachpb.ReplicaDescriptor,
 ) (action AllocatorAction, adjustedPriority float64) {
 	// NB: The ordering of the checks in this method is intentional. The order in
 	// which these actions are returned by this method determines the relative
@@ -942,8 +1014,9 @@ func (a *Allocator) computeAction(
 	// (which influence the replicateQueue's decision of which range it'll pick to
 	// repair/rebalance before the others).
 	//
-	// In broad strokes, we first handle all voting replica-based actions and then
-	// the actions pertaining to non-voting replicas. Within each replica set, we
+	// In broad strokes, we first handle all voting replica-based actions, then
+	// the actions pertaining to witnesses and then the actions pertaining to
+	// non-voting replicas. Within each replica set, we
 	// first handle operations that correspond to repairing/recovering the range.
 	// After that we handle rebalancing related actions, followed by removal
 	// actions.
@@ -955,7 +1028,9 @@ func (a *Allocator) computeAction(
 	clusterNodes := storePool.ClusterNodeCount()
 	neededVoters := GetNeededVoters(conf.GetNumVoters(), clusterNodes)
 	desiredQuorum := computeQuorum(neededVoters)
-	quorum := computeQuorum(haveVoters)
+	// Witnesses are part of the raft group, so they count towards its quorum.
+	haveWitnesses := len(witnessReplicas)
+	quorum := computeQuorum(haveVoters + haveWitnesses)
 
 	// TODO(aayush): When haveVoters < neededVoters but we don't have quorum to
 	// actually execute the addition of a new replica, we should be returning a
@@ -979,15 +1054,16 @@ func (a *Allocator) computeAction(
 	// elsewhere (for a regular rebalance or for decommissioning).
 	const includeSuspectAndDrainingStores = true
 	liveVoters, deadVoters := storePool.LiveAndDeadReplicas(voterReplicas, includeSuspectAndDrainingStores)
+	liveWitnesses, deadWitnesses := storePool.LiveAndDeadReplicas(witnessReplicas, includeSuspectAndDrainingStores)
 
-	if len(liveVoters) < quorum {
+	if len(liveVoters)+len(liveWitnesses) < quorum {
 		// Do not take any replacement/removal action if we do not have a quorum of
 		// live voters. If we're correctly assessing the unavailable state of the
 		// range, we also won't be able to add replicas as we try above, but hope
 		// springs eternal.
 		action = AllocatorRangeUnavailable
-		log.KvDistribution.VEventf(ctx, 1, "unable to take action - live voters %v don't meet quorum of %d",
-			liveVoters, quorum)
+		log.KvDistribution.VEventf(ctx, 1, "unable to take action - live voters %v and witnesses %v don't meet quorum of %d",
+			liveVoters, liveWitnesses, quorum)
 		return action, action.Priority()
 	}
 
@@ -1018,7 +1094,7 @@ func (a *Allocator) computeAction(
 	if len(deadVoters) > 0 {
 		// The range has dead replicas, which should be removed immediately.
 		action = AllocatorRemoveDeadVoter
-		adjustedPriority = action.Priority() + float64(quorum-len(liveVoters))
+		adjustedPriority = action.Priority() + float64(quorum-len(liveVoters)-len(liveWitnesses))
 		log.KvDistribution.VEventf(ctx, 3, "%s - dead=%d, live=%d, quorum=%d, priority=%.2f",
 			action, len(deadVoters), len(liveVoters), quorum, adjustedPriority)
 		return action, adjustedPriority
@@ -1045,11 +1121,55 @@ func (a *Allocator) computeAction(
 		return action, adjustedPriority
 	}
 
+	// Witness actions follow. Witnesses are not replaced atomically; instead, a
+	// new witness is added first and the dead or decommissioning one is removed
+	// once the range is over-replicated.
+	decommissioningWitnesses := storePool.DecommissioningReplicas(witnessReplicas)
+	healthyWitnesses := len(liveWitnesses)
+	for _, w := range liveWitnesses {
+		if getRemoveIdx(decommissioningWitnesses, w) >= 0 {
+			healthyWitnesses--
+		}
+	}
+	neededWitnesses := GetNeededWitnesses(haveVoters, int(conf.NumWitnesses), clusterNodes)
+	if healthyWitnesses < neededWitnesses && !a.WitnessesEnabled(ctx) {
+		log.KvDistribution.VEventf(ctx, 3,
+			"not adding witnesses until the cluster version is at least %s - need=%d, have=%d",
+			clusterversion.ByKey(clusterversion.V23_2_WitnessReplicas), neededWitnesses, healthyWitnesses)
+	} else if healthyWitnesses < neededWitnesses {
+		action = AllocatorAddWitness
+		log.KvDistribution.VEventf(ctx, 3, "%s - missing witness need=%d, have=%d, priority=%.2f",
+			action, neededWitnesses, healthyWitnesses, action.Priority())
+		return action, action.Priority()
+	}
+
+	if len(deadWitnesses) > 0 {
+		action = AllocatorRemoveDeadWitness
+		log.KvDistribution.VEventf(ctx, 3, "%s - dead=%d, live=%d, priority=%.2f",
+			action, len(deadWitnesses), len(liveWitnesses), action.Priority())
+		return action, action.Priority()
+	}
+
+	if len(decommissioningWitnesses) > 0 {
+		action = AllocatorRemoveDecommissioningWitness
+		log.KvDistribution.VEventf(ctx, 3,
+			"%s - need=%d, have=%d, num_decommissioning=%d, priority=%.2f",
+			action, neededWitnesses, haveWitnesses, len(decommissioningWitnesses), action.Priority())
+		return action, action.Priority()
+	}
+
+	if haveWitnesses > neededWitnesses {
+		action = AllocatorRemoveWitness
+		log.KvDistribution.VEventf(ctx, 3, "%s - need=%d, have=%d, priority=%.2f", action,
+			neededWitnesses, haveWitnesses, action.Priority())
+		return action, action.Priority()
+	}
+
 	// Non-voting replica actions follow.
 	//
 	// Non-voting replica addition / replacement.
 	haveNonVoters := len(nonVoterReplicas)
-	neededNonVoters := GetNeededNonVoters(haveVoters, int(conf.GetNumNonVoters()), clusterNodes)
+	neededNonVoters := GetNeededNonVoters(haveVoters+haveWitnesses, int(conf.GetNumNonVoters()), clusterNodes)
 	if haveNonVoters < neededNonVoters {
 		action = AllocatorAddNonVoter
 		log.KvDistribution.VEventf(ctx, 3, "%s - missing non-voter need=%d, have=%d, priority=%.2f",
@@ -1141,7 +1261,9 @@ func getReplicasForDiversityCalc(
 		// off of all `existingReplicas`), regions A, B, and C would all be equally
 		// likely to get a new voting replica.
 		return existingVoters
-	case NonVoterTarget:
+	case NonVoterTarget, WitnessTarget:
+		// Witnesses exist to provide an additional failure domain to the raft
+		// group, so we prefer localities that hold no other replica of the range.
 		return allExistingReplicas
 	default:
 		panic(fmt.Sprintf("unsupported targetReplicaType: %v", t))
@@ -1339,6 +1461,21 @@ func (a *Allocator) AllocateNonVoter(
 	return a.AllocateTarget(ctx, storePool, conf, existingVoters, existingNonVoters, replacing, replicaStatus, NonVoterTarget)
 }
 
+// AllocateWitness returns a suitable store for a new allocation of a witness
+// replica. Nodes already accommodating _any_ existing replicas are ruled out
+// as targets.
+func (a *Allocator) AllocateWitness(
+	ctx context.Context,
+	storePool storepool.AllocatorStorePool,
+	conf roachpb.SpanConfig,
+	existingVoters, existingNonVoters, existingWitnesses []roachpb.ReplicaDescriptor,
+) (roachpb.ReplicationTarget, string, error) {
+	// Witnesses are only checked against the constraints, which they don't count
+	// towards, so they can be passed in along with the non-voters.
+	existingOthers := append(append([]roachpb.ReplicaDescriptor(nil), existingNonVoters...), existingWitnesses...)
+	return a.AllocateTarget(ctx, storePool, conf, existingVoters, existingOthers, nil /* replacing */, Alive, WitnessTarget)
+}
+
 // AllocateTargetFromList returns a suitable store for a new allocation of a
 // replica of the given type from the set of candidate stores, with the given
 // existing set of voters and non-voters..
@@ -1420,6 +1557,8 @@ func (a *Allocator) allocateTargetFromList(
 		} else {
 			constraintsChecker = nonVoterConstraintsCheckerForAllocation(analyzedOverallConstraints)
 		}
+	case WitnessTarget:
+		constraintsChecker = witnessConstraintsChecker(analyzedOverallConstraints)
 	default:
 		log.KvDistribution.Fatalf(ctx, "unsupported targetReplicaType: %v", t)
 	}
@@ -1561,6 +1700,8 @@ func (a Allocator) RemoveTarget(
 		)
 	case NonVoterTarget:
 		constraintsChecker = nonVoterConstraintsCheckerForRemoval(analyzedOverallConstraints)
+	case WitnessTarget:
+		constraintsChecker = witnessConstraintsChecker(analyzedOverallConstraints)
 	default:
 		log.KvDistribution.Fatalf(ctx, "unsupported targetReplicaType: %v", t)
 	}
@@ -1659,6 +1800,37 @@ func (a Allocator) RemoveNonVoter(
 	)
 }
 
+// RemoveWitness returns a suitable witness to remove from the provided set.
+func (a Allocator) RemoveWitness(
+	ctx context.Context,
+	storePool storepool.AllocatorStorePool,
+	conf roachpb.SpanConfig,
+	witnessCandidates []roachpb.ReplicaDescriptor,
+	existingVoters []roachpb.ReplicaDescriptor,
+	existingNonVoters []roachpb.ReplicaDescriptor,
+	existingWitnesses []roachpb.ReplicaDescriptor,
+	options ScorerOptions,
+) (roachpb.ReplicationTarget, string, error) {
+	// Retrieve store descriptors for the provided candidates from the StorePool.
+	candidateStoreIDs := make(roachpb.StoreIDSlice, len(witnessCandidates))
+	for i, exist := range witnessCandidates {
+		candidateStoreIDs[i] = exist.StoreID
+	}
+	candidateStoreList, _, _ := storePool.GetStoreListFromIDs(candidateStoreIDs, storepool.StoreFilterNone)
+
+	existingOthers := append(append([]roachpb.ReplicaDescriptor(nil), existingNonVoters...), existingWitnesses...)
+	return a.RemoveTarget(
+		ctx,
+		storePool,
+		conf,
+		candidateStoreList,
+		existingVoters,
+		existingOthers,
+		WitnessTarget,
+		options,
+	)
+}
+
 // RebalanceTarget returns a suitable store for a rebalance target (of the given
 // type) with required attributes.
 func (a Allocator) RebalanceTarget(
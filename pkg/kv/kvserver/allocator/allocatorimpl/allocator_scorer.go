@@ -1879,6 +1879,20 @@ func nonVoterConstraintsCheckerForAllocation(
 	}
 }
 
+// witnessConstraintsChecker returns a constraintsCheckFn that determines
+// whether a store is a valid location for a witness with respect to the
+// `constraints` on the range.
+//
+// NB: Witnesses don't count towards the number of replicas required by the
+// constraints, so they are never necessary. They are valid on the stores where
+// a replica that stores data would be.
+func witnessConstraintsChecker(overallConstraints constraint.AnalyzedConstraints) constraintsCheckFn {
+	return func(s roachpb.StoreDescriptor) (valid, necessary bool) {
+		valid, _ = allocateConstraintsCheck(s, overallConstraints)
+		return valid, false
+	}
+}
+
 // voterConstraintsCheckerForRemoval returns a constraintsCheckFn that
 // determines whether an existing voting replica is valid and/or necessary with
 // respect to the `constraints` and `voter_constraints` on the range.
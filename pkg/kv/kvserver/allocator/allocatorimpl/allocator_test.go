@@ -7821,6 +7821,34 @@ func TestAllocatorGetNeededReplicas(t *testing.T) {
 	}
 }
 
+func TestAllocatorGetNeededWitnesses(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	testCases := []struct {
+		numVoters, numWitnesses, availNodes int
+		expected                            int
+	}{
+		{numVoters: 2, numWitnesses: 0, availNodes: 5, expected: 0},
+		{numVoters: 2, numWitnesses: 1, availNodes: 5, expected: 1},
+		// Witnesses can't be placed on nodes that already have a voter.
+		{numVoters: 2, numWitnesses: 1, availNodes: 2, expected: 0},
+		{numVoters: 3, numWitnesses: 2, availNodes: 4, expected: 1},
+		// Witnesses must be a minority of the raft group.
+		{numVoters: 2, numWitnesses: 2, availNodes: 5, expected: 1},
+		{numVoters: 1, numWitnesses: 1, availNodes: 5, expected: 0},
+		{numVoters: 4, numWitnesses: 3, availNodes: 7, expected: 3},
+	}
+
+	for _, tc := range testCases {
+		if e, a := tc.expected, GetNeededWitnesses(tc.numVoters, tc.numWitnesses, tc.availNodes); e != a {
+			t.Errorf(
+				"GetNeededWitnesses(numVoters=%d, numWitnesses=%d, availNodes=%d) got %d; want %d",
+				tc.numVoters, tc.numWitnesses, tc.availNodes, a, e)
+		}
+	}
+}
+
 func makeDescriptor(storeList []roachpb.StoreID) roachpb.RangeDescriptor {
 	desc := roachpb.RangeDescriptor{
 		EndKey: roachpb.RKey(keys.SystemPrefix),
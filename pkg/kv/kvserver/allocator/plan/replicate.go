@@ -15,6 +15,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/allocator"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/allocator/allocatorimpl"
@@ -335,6 +336,20 @@ func (rp ReplicaPlanner) PlanOneChange(
 		op, stats, err = rp.removeDead(ctx, repl, deadVoterReplicas, allocatorimpl.VoterTarget)
 	case allocatorimpl.AllocatorRemoveDeadNonVoter:
 		op, stats, err = rp.removeDead(ctx, repl, deadNonVoterReplicas, allocatorimpl.NonVoterTarget)
+
+	// Add or remove witnesses. Witnesses are never replaced atomically, see
+	// Allocator.computeAction.
+	case allocatorimpl.AllocatorAddWitness:
+		op, stats, err = rp.addWitness(ctx, repl, desc, conf, allocatorPrio)
+	case allocatorimpl.AllocatorRemoveWitness:
+		op, stats, err = rp.removeWitness(ctx, repl, desc, conf)
+	case allocatorimpl.AllocatorRemoveDecommissioningWitness:
+		op, stats, err = rp.removeDecommissioning(ctx, repl, desc, conf, allocatorimpl.WitnessTarget)
+	case allocatorimpl.AllocatorRemoveDeadWitness:
+		_, deadWitnessReplicas := rp.storePool.LiveAndDeadReplicas(
+			desc.Replicas().WitnessDescriptors(), true, /* includeSuspectAndDrainingStores */
+		)
+		op, stats, err = rp.removeDead(ctx, repl, deadWitnessReplicas, allocatorimpl.WitnessTarget)
 	// Rebalance replicas.
 	//
 	// NB: Rebalacing attempts to balance replica counts among stores of
@@ -530,6 +545,79 @@ func (rp ReplicaPlanner) addOrReplaceNonVoters(
 	return op, stats, nil
 }
 
+// addWitness adds a witness to `repl`s range.
+func (rp ReplicaPlanner) addWitness(
+	ctx context.Context,
+	repl AllocatorReplica,
+	desc *roachpb.RangeDescriptor,
+	conf roachpb.SpanConfig,
+	allocatorPrio float64,
+) (op AllocationOp, stats ReplicateStats, _ error) {
+	// The allocator doesn't ask for witnesses before they're enabled, but the
+	// cluster version may not have been observed yet when it did.
+	if !rp.allocator.WitnessesEnabled(ctx) {
+		return nil, stats, errors.Errorf(
+			"witnesses cannot be added until the cluster version is at least %s",
+			clusterversion.ByKey(clusterversion.V23_2_WitnessReplicas))
+	}
+	existingWitnesses := desc.Replicas().WitnessDescriptors()
+	newWitness, details, err := rp.allocator.AllocateWitness(ctx, rp.storePool, conf,
+		desc.Replicas().VoterDescriptors(), desc.Replicas().NonVoterDescriptors(), existingWitnesses)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	stats = stats.trackAddReplicaCount(allocatorimpl.WitnessTarget)
+	log.KvDistribution.Infof(ctx, "adding witness %+v: %s",
+		newWitness, rangeRaftProgress(repl.RaftStatus(), existingWitnesses))
+
+	op = AllocationChangeReplicasOp{
+		lhStore:           repl.StoreID(),
+		Usage:             repl.RangeUsageInfo(),
+		Chgs:              kvpb.MakeReplicationChanges(roachpb.ADD_WITNESS, newWitness),
+		Priority:          kvserverpb.SnapshotRequest_RECOVERY,
+		AllocatorPriority: allocatorPrio,
+		Reason:            kvserverpb.ReasonRangeUnderReplicated,
+		Details:           details,
+	}
+	return op, stats, nil
+}
+
+// removeWitness removes a witness from `repl`s range due to
+// over-replication.
+func (rp ReplicaPlanner) removeWitness(
+	ctx context.Context, repl AllocatorReplica, desc *roachpb.RangeDescriptor, conf roachpb.SpanConfig,
+) (op AllocationOp, stats ReplicateStats, _ error) {
+	existingWitnesses := desc.Replicas().WitnessDescriptors()
+	removeWitness, details, err := rp.allocator.RemoveWitness(
+		ctx,
+		rp.storePool,
+		conf,
+		existingWitnesses,
+		desc.Replicas().VoterDescriptors(),
+		desc.Replicas().NonVoterDescriptors(),
+		existingWitnesses,
+		rp.allocator.ScorerOptions(ctx),
+	)
+	if err != nil {
+		return nil, stats, err
+	}
+	stats = stats.trackRemoveMetric(allocatorimpl.WitnessTarget, allocatorimpl.Alive)
+
+	log.KvDistribution.Infof(ctx, "removing witness %+v due to over-replication: %s",
+		removeWitness, rangeRaftProgress(repl.RaftStatus(), existingWitnesses))
+	op = AllocationChangeReplicasOp{
+		lhStore:           repl.StoreID(),
+		Usage:             repl.RangeUsageInfo(),
+		Chgs:              kvpb.MakeReplicationChanges(roachpb.REMOVE_WITNESS, removeWitness),
+		Priority:          kvserverpb.SnapshotRequest_UNKNOWN, // unused
+		AllocatorPriority: 0.0,                                // unused
+		Reason:            kvserverpb.ReasonRangeOverReplicated,
+		Details:           details,
+	}
+	return op, stats, nil
+}
+
 // findRemoveVoter takes a list of voting replicas and picks one to remove,
 // making sure to not remove a newly added voter or to violate the zone configs
 // in the process.
@@ -722,6 +810,10 @@ func (rp ReplicaPlanner) removeDecommissioning(
 		decommissioningReplicas = rp.storePool.DecommissioningReplicas(
 			desc.Replicas().NonVoterDescriptors(),
 		)
+	case allocatorimpl.WitnessTarget:
+		decommissioningReplicas = rp.storePool.DecommissioningReplicas(
+			desc.Replicas().WitnessDescriptors(),
+		)
 	default:
 		panic(fmt.Sprintf("unknown targetReplicaType: %s", targetType))
 	}
@@ -485,6 +485,9 @@ func (sp *StorePool) UpdateLocalStoreAfterRebalance(
 	// Only apply the raft cpu delta on rebalance. This estimate assumes that
 	// the raft cpu usage is approximately equal across replicas for a range.
 	switch changeType {
+	case roachpb.ADD_WITNESS, roachpb.REMOVE_WITNESS:
+		// Witnesses store no data and serve no traffic, so they don't affect the
+		// capacity of the store in a meaningful way.
 	case roachpb.ADD_VOTER, roachpb.ADD_NON_VOTER:
 		detail.Desc.Capacity.RangeCount++
 		detail.Desc.Capacity.LogicalBytes += rangeUsageInfo.LogicalBytes
@@ -74,6 +74,10 @@ func (s *appBatchStats) merge(ss appBatchStats) {
 // [^1]: https://github.com/cockroachdb/cockroach/issues/75729
 type appBatch struct {
 	appBatchStats
+	// witness is set if the batch is applied to a witness replica, in which
+	// case only the local portion of each command's writes is applied and
+	// AddSSTable ingestions are skipped entirely. See witnessWriteBatchRepr.
+	witness bool
 	// TODO(tbg): this will absorb the following fields from replicaAppBatch:
 	//
 	// - batch
@@ -143,7 +147,14 @@ func (b *appBatch) addWriteBatch(
 	} else {
 		b.numMutations += mutations
 	}
-	if err := batch.ApplyBatchRepr(wb.Data, false); err != nil {
+	repr := wb.Data
+	if b.witness {
+		var err error
+		if repr, err = witnessWriteBatchRepr(repr); err != nil {
+			return errors.Wrapf(err, "unable to filter WriteBatch for witness")
+		}
+	}
+	if err := batch.ApplyBatchRepr(repr, false); err != nil {
 		return errors.Wrapf(err, "unable to apply WriteBatch")
 	}
 	return nil
@@ -174,7 +185,7 @@ func (b *appBatch) runPostAddTriggers(
 	// NB: any command which has an AddSSTable is non-trivial and will be
 	// applied in its own batch so it's not possible that any other commands
 	// which precede this command can shadow writes from this SSTable.
-	if res.AddSSTable != nil {
+	if res.AddSSTable != nil && !b.witness {
 		copied := addSSTablePreApply(
 			ctx,
 			env,
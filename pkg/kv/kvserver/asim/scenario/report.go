@@ -0,0 +1,221 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package scenario
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/asim/metrics"
+	"github.com/cockroachdb/errors"
+)
+
+// Stats maps the names of the per-store statistics which can be reported to
+// their value. The names match those of metrics.MakeTS.
+var Stats = map[string]func(metrics.StoreMetrics) float64{
+	"qps":                func(sm metrics.StoreMetrics) float64 { return float64(sm.QPS) },
+	"write":              func(sm metrics.StoreMetrics) float64 { return float64(sm.WriteKeys) },
+	"write_b":            func(sm metrics.StoreMetrics) float64 { return float64(sm.WriteBytes) },
+	"read":               func(sm metrics.StoreMetrics) float64 { return float64(sm.ReadKeys) },
+	"read_b":             func(sm metrics.StoreMetrics) float64 { return float64(sm.ReadBytes) },
+	"replicas":           func(sm metrics.StoreMetrics) float64 { return float64(sm.Replicas) },
+	"leases":             func(sm metrics.StoreMetrics) float64 { return float64(sm.Leases) },
+	"lease_moves":        func(sm metrics.StoreMetrics) float64 { return float64(sm.LeaseTransfers) },
+	"replica_moves":      func(sm metrics.StoreMetrics) float64 { return float64(sm.Rebalances) },
+	"replica_b_rcvd":     func(sm metrics.StoreMetrics) float64 { return float64(sm.RebalanceRcvdBytes) },
+	"replica_b_sent":     func(sm metrics.StoreMetrics) float64 { return float64(sm.RebalanceSentBytes) },
+	"range_splits":       func(sm metrics.StoreMetrics) float64 { return float64(sm.RangeSplits) },
+	"disk_fraction_used": func(sm metrics.StoreMetrics) float64 { return sm.DiskFractionUsed },
+}
+
+// DefaultStats are the statistics reported by default.
+var DefaultStats = []string{"replicas", "leases", "qps"}
+
+// ReportFormat is the output format of a report.
+type ReportFormat string
+
+const (
+	// ReportText reports a table per statistic, with a row per interval and a
+	// column per store, along with the statistic's distribution over the
+	// stores.
+	ReportText ReportFormat = "text"
+	// ReportCSV reports a row per interval and store, with a column per
+	// statistic.
+	ReportCSV ReportFormat = "csv"
+)
+
+// ReportOptions configures WriteReport.
+type ReportOptions struct {
+	Format ReportFormat
+	// Stats are the names of the statistics to report, see Stats.
+	Stats []string
+	// Interval is the simulated time between the reported rows. The last
+	// recorded tick is always reported.
+	Interval time.Duration
+}
+
+// WriteReport writes the per-store statistics recorded in a simulation run.
+func WriteReport(w io.Writer, recorded [][]metrics.StoreMetrics, opts ReportOptions) error {
+	for _, stat := range opts.Stats {
+		if _, ok := Stats[stat]; !ok {
+			names := make([]string, 0, len(Stats))
+			for name := range Stats {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return errors.Newf("unknown stat %q, expected one of: %s", stat, strings.Join(names, ", "))
+		}
+	}
+	rows := sampleTicks(recorded, opts.Interval)
+	switch opts.Format {
+	case ReportText:
+		return writeTextReport(w, rows, opts.Stats)
+	case ReportCSV:
+		return writeCSVReport(w, rows, opts.Stats)
+	default:
+		return errors.Newf("unknown report format %q", opts.Format)
+	}
+}
+
+// reportRow is the store metrics recorded at a tick, along with the simulated
+// time elapsed since the start of the simulation.
+type reportRow struct {
+	elapsed time.Duration
+	stores  []metrics.StoreMetrics
+}
+
+// sampleTicks returns the first recorded tick at or after each multiple of the
+// interval, and the last recorded tick.
+func sampleTicks(recorded [][]metrics.StoreMetrics, interval time.Duration) []reportRow {
+	var rows []reportRow
+	if len(recorded) == 0 || len(recorded[0]) == 0 {
+		return rows
+	}
+	start := recorded[0][0].Tick
+	var next time.Duration
+	for i, sms := range recorded {
+		if len(sms) == 0 {
+			continue
+		}
+		elapsed := sms[0].Tick.Sub(start)
+		if elapsed >= next || i == len(recorded)-1 {
+			rows = append(rows, reportRow{elapsed: elapsed, stores: sms})
+			for next <= elapsed {
+				if interval <= 0 {
+					break
+				}
+				next += interval
+			}
+		}
+	}
+	return rows
+}
+
+func writeTextReport(w io.Writer, rows []reportRow, stats []string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	// Stores may join the cluster during the simulation, so the last row has
+	// the most stores.
+	lastStores := rows[len(rows)-1].stores
+	for i, stat := range stats {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\n", stat)
+		tw := tabwriter.NewWriter(w, 2, 1, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprint(tw, "elapsed\tmin\tmean\tmax\tstddev\t")
+		for _, sm := range lastStores {
+			fmt.Fprintf(tw, "s%d\t", sm.StoreID)
+		}
+		fmt.Fprintln(tw)
+		for _, row := range rows {
+			values := make([]float64, len(row.stores))
+			for j, sm := range row.stores {
+				values[j] = Stats[stat](sm)
+			}
+			min, mean, max, stddev := summarize(values)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t",
+				row.elapsed, formatValue(min), formatValue(mean), formatValue(max), formatValue(stddev))
+			for j := range lastStores {
+				if j < len(values) {
+					fmt.Fprintf(tw, "%s\t", formatValue(values[j]))
+				} else {
+					fmt.Fprint(tw, "-\t")
+				}
+			}
+			fmt.Fprintln(tw)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSVReport(w io.Writer, rows []reportRow, stats []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"elapsed_seconds", "store"}, stats...)); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for _, sm := range row.stores {
+			record := []string{
+				strconv.FormatFloat(row.elapsed.Seconds(), 'f', -1, 64),
+				strconv.FormatInt(sm.StoreID, 10),
+			}
+			for _, stat := range stats {
+				record = append(record, strconv.FormatFloat(Stats[stat](sm), 'f', -1, 64))
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// summarize returns the minimum, mean, maximum and standard deviation of the
+// values.
+func summarize(values []float64) (min, mean, max, stddev float64) {
+	if len(values) == 0 {
+		return 0, 0, 0, 0
+	}
+	min, max = math.Inf(1), math.Inf(-1)
+	var sum float64
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+		sum += v
+	}
+	mean = sum / float64(len(values))
+	var sqDiff float64
+	for _, v := range values {
+		sqDiff += (v - mean) * (v - mean)
+	}
+	stddev = math.Sqrt(sqDiff / float64(len(values)))
+	return min, mean, max, stddev
+}
+
+func formatValue(v float64) string {
+	if v == math.Trunc(v) {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,344 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/asim"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/asim/config"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/asim/event"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/asim/gen"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/asim/state"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/asim/workload"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/errors"
+)
+
+// seedRegion and seedZone are the locality of the nodes of a seeded cluster,
+// when the spec doesn't describe the cluster's topology.
+const (
+	seedRegion = "region"
+	seedZone   = "zone"
+)
+
+// NewSimulator returns a simulator for the given spec. If the seed is non-nil,
+// the initial ranges and, unless the spec has workload, the load of the
+// simulation are taken from it.
+func NewSimulator(spec *Spec, seed *Seed) (*asim.Simulator, error) {
+	settings, err := spec.simulationSettings()
+	if err != nil {
+		return nil, err
+	}
+	clusterInfo, err := spec.clusterInfo(seed)
+	if err != nil {
+		return nil, err
+	}
+	rangesInfo, err := spec.rangesInfo(seed, clusterStores(clusterInfo))
+	if err != nil {
+		return nil, err
+	}
+	return gen.GenerateSimulation(
+		spec.Duration,
+		gen.LoadedCluster{Info: clusterInfo},
+		gen.LoadedRanges{Info: rangesInfo},
+		spec.loadGen(seed),
+		gen.StaticSettings{Settings: settings},
+		gen.StaticEvents{DelayedEvents: spec.events(settings.StartTime)},
+		spec.Seed,
+	), nil
+}
+
+// Run runs the simulation for the given spec and seed, and returns its
+// history.
+func Run(ctx context.Context, spec *Spec, seed *Seed) (asim.History, error) {
+	sim, err := NewSimulator(spec, seed)
+	if err != nil {
+		return asim.History{}, err
+	}
+	sim.RunSim(ctx)
+	return sim.History(), nil
+}
+
+func (s *Spec) simulationSettings() (*config.SimulationSettings, error) {
+	settings := config.DefaultSimulationSettings()
+	settings.Seed = s.Seed
+	if s.Settings.TickInterval != 0 {
+		settings.TickInterval = s.Settings.TickInterval
+	}
+	if s.Settings.MetricsInterval != 0 {
+		settings.MetricsInterval = s.Settings.MetricsInterval
+	}
+	if s.Settings.LoadBasedRebalancing != "" {
+		settings.LBRebalancingMode = loadBasedRebalancingModes[s.Settings.LoadBasedRebalancing]
+	}
+	if s.Settings.RangeRebalanceThreshold != 0 {
+		settings.RangeRebalanceThreshold = s.Settings.RangeRebalanceThreshold
+	}
+	if s.Settings.QPSRebalanceThreshold != 0 {
+		settings.LBRebalanceQPSThreshold = s.Settings.QPSRebalanceThreshold
+	}
+	if s.Settings.SplitQPSThreshold != 0 {
+		settings.SplitQPSThreshold = s.Settings.SplitQPSThreshold
+	}
+	if settings.TickInterval <= 0 || settings.MetricsInterval < settings.TickInterval {
+		return nil, errors.Newf("metrics interval %s must be at least the tick interval %s",
+			settings.MetricsInterval, settings.TickInterval)
+	}
+	return settings, nil
+}
+
+// clusterInfo returns the initial topology of the cluster. A seeded cluster
+// without a topology in the spec gets one node per seeded store.
+func (s *Spec) clusterInfo(seed *Seed) (state.ClusterInfo, error) {
+	info := state.ClusterInfo{DiskCapacityGB: s.Cluster.DiskCapacityGB}
+	for _, r := range s.Cluster.Regions {
+		region := state.Region{Name: r.Name}
+		for _, z := range r.Zones {
+			region.Zones = append(region.Zones, state.Zone{
+				Name:          z.Name,
+				NodeCount:     z.Nodes,
+				StoresPerNode: z.StoresPerNode,
+			})
+		}
+		info.Regions = append(info.Regions, region)
+	}
+	if len(info.Regions) == 0 {
+		if seed == nil {
+			return state.ClusterInfo{}, errors.New("cluster must have at least one region")
+		}
+		info.Regions = []state.Region{{
+			Name:  seedRegion,
+			Zones: []state.Zone{{Name: seedZone, NodeCount: len(seed.Stores), StoresPerNode: 1}},
+		}}
+	}
+	if seed != nil {
+		if stores := clusterStores(info); stores < len(seed.Stores) {
+			return state.ClusterInfo{}, errors.Newf(
+				"the seed has %d stores, but the cluster only has %d", len(seed.Stores), stores)
+		}
+	}
+	return info, nil
+}
+
+// clusterStores returns the number of stores of the cluster.
+func clusterStores(info state.ClusterInfo) int {
+	var stores int
+	for _, r := range info.Regions {
+		for _, z := range r.Zones {
+			storesPerNode := z.StoresPerNode
+			if storesPerNode < 1 {
+				storesPerNode = 1
+			}
+			stores += z.NodeCount * storesPerNode
+		}
+	}
+	return stores
+}
+
+// rangesInfo returns the initial ranges of the cluster. Stores beyond the
+// seeded ones start out empty.
+func (s *Spec) rangesInfo(seed *Seed, stores int) (state.RangesInfo, error) {
+	spanConfig := roachpb.SpanConfig{
+		RangeMinBytes: 128 << 20, // 128 MB
+		RangeMaxBytes: 512 << 20, // 512 MB
+		NumReplicas:   int32(s.Ranges.ReplicationFactor),
+		NumVoters:     int32(s.Ranges.ReplicationFactor),
+	}
+
+	if seed == nil {
+		ranges := s.Ranges.Count
+		if ranges == 0 {
+			ranges = defaultRanges
+		}
+		base := gen.BaseRanges{
+			Ranges:            ranges,
+			KeySpace:          s.Ranges.Keyspace,
+			ReplicationFactor: s.Ranges.ReplicationFactor,
+			Bytes:             s.Ranges.Bytes,
+		}
+		info := base.GetRangesInfo(
+			gen.GetRangePlacementType(s.Ranges.Placement), stores, nil /* randSource */, nil, /* weightedRandom */
+		)
+		for i := range info {
+			info[i].Size = s.Ranges.Bytes
+		}
+		return info, nil
+	}
+
+	if len(seed.Ranges) > 0 {
+		if len(seed.Ranges) > s.Ranges.Keyspace {
+			return nil, errors.Newf(
+				"the seed has %d ranges, which exceeds the keyspace %d", len(seed.Ranges), s.Ranges.Keyspace)
+		}
+		info := make(state.RangesInfo, len(seed.Ranges))
+		interval := s.Ranges.Keyspace / len(seed.Ranges)
+		for i, r := range seed.Ranges {
+			voters := make([]state.StoreID, len(r.Replicas))
+			leaseholder := state.StoreID(seed.storeIndex(r.Replicas[0]) + 1)
+			for j, storeID := range r.Replicas {
+				voters[j] = state.StoreID(seed.storeIndex(storeID) + 1)
+				if storeID == r.Leaseholder {
+					leaseholder = voters[j]
+				}
+			}
+			conf := spanConfig
+			conf.NumReplicas = int32(len(voters))
+			conf.NumVoters = int32(len(voters))
+			info[i] = state.RangeInfoWithReplicas(
+				state.Key(i*interval), voters, nil /* nonVoters */, leaseholder, &conf)
+			info[i].Size = r.Size
+			if s.Ranges.Bytes != 0 {
+				info[i].Size = s.Ranges.Bytes
+			}
+		}
+		return info, nil
+	}
+
+	var totalReplicas, totalLeases int64
+	for i := range seed.Stores {
+		totalReplicas += seed.Replicas[i]
+		totalLeases += seed.Leases[i]
+	}
+	if totalReplicas == 0 {
+		return nil, errors.New("the seeded stores have no replicas")
+	}
+	ranges := s.Ranges.Count
+	if ranges == 0 {
+		ranges = int(totalReplicas) / s.Ranges.ReplicationFactor
+		if ranges == 0 {
+			ranges = defaultRanges
+		}
+	}
+	if ranges > s.Ranges.Keyspace {
+		return nil, errors.Newf(
+			"the seed has %d ranges, which exceeds the keyspace %d", ranges, s.Ranges.Keyspace)
+	}
+	rangeSize := s.Ranges.Bytes
+	if rangeSize == 0 {
+		rangeSize = seed.LiveBytes / totalReplicas
+	}
+	storeIDs := make([]state.StoreID, len(seed.Stores))
+	replicaWeights := make([]float64, len(seed.Stores))
+	leaseWeights := make([]float64, len(seed.Stores))
+	for i := range seed.Stores {
+		storeIDs[i] = state.StoreID(i + 1)
+		replicaWeights[i] = float64(seed.Replicas[i]) / float64(totalReplicas)
+		if totalLeases > 0 {
+			leaseWeights[i] = float64(seed.Leases[i]) / float64(totalLeases)
+		} else {
+			leaseWeights[i] = replicaWeights[i]
+		}
+	}
+	return state.RangesInfoWithDistribution(
+		storeIDs, replicaWeights, leaseWeights, ranges, spanConfig,
+		int64(state.MinKey), int64(s.Ranges.Keyspace), rangeSize,
+	), nil
+}
+
+// loadGen returns the workload of the simulation. A seeded simulation without
+// workload in the spec gets a uniform workload with the seed's load.
+func (s *Spec) loadGen(seed *Seed) gen.LoadGen {
+	var loads multiLoad
+	for _, w := range s.Workload {
+		loads = append(loads, gen.BasicLoad{
+			RWRatio:      w.RWRatio,
+			Rate:         w.Rate,
+			SkewedAccess: w.SkewedAccess,
+			MinBlockSize: w.MinBlock,
+			MaxBlockSize: w.MaxBlock,
+			MinKey:       w.MinKey,
+			MaxKey:       w.MaxKey,
+		})
+	}
+	if len(loads) == 0 && seed != nil && seed.QPS > 0 {
+		var rwRatio float64
+		if total := seed.ReadsPerSecond + seed.WritesPerSecond; total > 0 {
+			rwRatio = seed.ReadsPerSecond / total
+		}
+		loads = append(loads, gen.BasicLoad{
+			RWRatio:      rwRatio,
+			Rate:         seed.QPS,
+			MinBlockSize: defaultMinBlock,
+			MaxBlockSize: defaultMaxBlock,
+			MinKey:       int64(state.MinKey),
+			MaxKey:       int64(s.Ranges.Keyspace),
+		})
+	}
+	return loads
+}
+
+// events returns the zone config changes and node additions of the
+// simulation.
+func (s *Spec) events(start time.Time) event.DelayedEventList {
+	var events event.DelayedEventList
+	for _, z := range s.ZoneConfigs {
+		span := roachpb.Span{
+			Key:    state.Key(z.StartKey).ToRKey().AsRawKey(),
+			EndKey: state.Key(z.EndKey).ToRKey().AsRawKey(),
+		}
+		conf := z.Config.AsSpanConfig()
+		events = append(events, event.DelayedEvent{
+			At: start.Add(z.At),
+			EventFn: func(ctx context.Context, tick time.Time, s state.State) {
+				s.SetSpanConfig(span, conf)
+			},
+		})
+	}
+	for _, n := range s.AddNodes {
+		n := n
+		storesPerNode := n.StoresPerNode
+		if storesPerNode < 1 {
+			storesPerNode = 1
+		}
+		events = append(events, event.DelayedEvent{
+			At: start.Add(n.At),
+			EventFn: func(ctx context.Context, tick time.Time, s state.State) {
+				var locality roachpb.Locality
+				if n.Locality != "" {
+					if err := locality.Set(n.Locality); err != nil {
+						panic(fmt.Sprintf("unable to set node locality %s", err.Error()))
+					}
+				}
+				for i := 0; i < n.Nodes; i++ {
+					node := s.AddNode()
+					s.SetNodeLocality(node.NodeID(), locality)
+					for j := 0; j < storesPerNode; j++ {
+						if store, ok := s.AddStore(node.NodeID()); !ok {
+							panic(fmt.Sprintf("adding store to node=%d failed", node.NodeID()))
+						} else {
+							s.SetStoreCapacity(store.StoreID(), int64(s.ClusterInfo().DiskCapacityGB)<<30)
+						}
+					}
+				}
+			},
+		})
+	}
+	return events
+}
+
+// multiLoad implements the gen.LoadGen interface, running a workload generator
+// for each of the loads.
+type multiLoad []gen.BasicLoad
+
+// Generate returns the concatenation of the workload generators of each of
+// the loads.
+func (ml multiLoad) Generate(
+	seed int64, settings *config.SimulationSettings,
+) []workload.Generator {
+	var generators []workload.Generator
+	for i, l := range ml {
+		generators = append(generators, l.Generate(seed+int64(i), settings)...)
+	}
+	return generators
+}
@@ -0,0 +1,241 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpec = `
+duration: 5m
+cluster:
+  regions:
+  - name: a
+    zones:
+    - {name: a1, nodes: 1}
+    - {name: a2, nodes: 1}
+    - {name: a3, nodes: 1}
+ranges:
+  count: 30
+  keyspace: 1000
+  placement: skewed
+zone_configs:
+- start_key: 0
+  end_key: 100
+  config:
+    num_replicas: 3
+    constraints: [+region=a]
+workload:
+- {rate: 500, rw_ratio: 0.9}
+add_nodes:
+- {at: 1m, nodes: 1, locality: "region=a,zone=a1"}
+`
+
+func TestParseSpec(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Minute, spec.Duration)
+	require.EqualValues(t, defaultSeed, spec.Seed)
+	require.Equal(t, defaultReplicationFactor, spec.Ranges.ReplicationFactor)
+	require.Len(t, spec.ZoneConfigs, 1)
+	conf := spec.ZoneConfigs[0].Config.AsSpanConfig()
+	require.EqualValues(t, 3, conf.NumReplicas)
+	require.Len(t, conf.Constraints, 1)
+	require.EqualValues(t, 1000, spec.Workload[0].MaxKey)
+	require.Equal(t, defaultMaxBlock, spec.Workload[0].MaxBlock)
+
+	for _, tc := range []struct {
+		spec, err string
+	}{
+		{spec: "unknown: 1", err: "field unknown not found"},
+		{spec: "ranges: {placement: random}", err: `unknown range placement "random"`},
+		{spec: "ranges: {count: 10, keyspace: 5}", err: "range count 10 exceeds keyspace 5"},
+		{spec: "cluster: {regions: [{name: a, zones: [{name: b}]}]}", err: "must have at least one node"},
+		{spec: "zone_configs: [{start_key: 10, end_key: 5}]", err: "invalid zone config span [10,5)"},
+		{spec: "zone_configs: [{config: {num_replicas: 0}}]", err: "at least one replica is required"},
+		{spec: "workload: [{rate: 1, rw_ratio: 2}]", err: "rw_ratio must be in [0,1]"},
+		{spec: "settings: {load_based_rebalancing: sometimes}", err: `unknown load_based_rebalancing mode "sometimes"`},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			_, err := ParseSpec([]byte(tc.spec))
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestSeedFromTSDump(t *testing.T) {
+	const tsdump = `cr.store.replicas,2023-01-01T00:00:00Z,1,10
+cr.store.replicas,2023-01-01T00:00:10Z,1,20
+cr.store.replicas,2023-01-01T00:00:10Z,4,40
+cr.store.replicas.leaseholders,2023-01-01T00:00:10Z,1,5
+cr.store.replicas.leaseholders,2023-01-01T00:00:10Z,4,15
+cr.store.livebytes,2023-01-01T00:00:10Z,1,1000
+cr.store.livebytes,2023-01-01T00:00:10Z,4,2000
+cr.store.rebalancing.queriespersecond,2023-01-01T00:00:10Z,1,100
+cr.store.rebalancing.queriespersecond,2023-01-01T00:00:10Z,4,300
+cr.store.rebalancing.readspersecond,2023-01-01T00:00:10Z,4,300
+cr.store.rebalancing.writespersecond,2023-01-01T00:00:10Z,4,100
+cr.node.sql.conns,2023-01-01T00:00:10Z,1,7
+`
+	seed, err := SeedFromTSDump(strings.NewReader(tsdump))
+	require.NoError(t, err)
+	require.Equal(t, &Seed{
+		Stores:          []roachpb.StoreID{1, 4},
+		Replicas:        []int64{20, 40},
+		Leases:          []int64{5, 15},
+		LiveBytes:       3000,
+		QPS:             400,
+		ReadsPerSecond:  300,
+		WritesPerSecond: 100,
+	}, seed)
+
+	_, err = SeedFromTSDump(strings.NewReader("cr.node.sql.conns,2023-01-01T00:00:10Z,1,7\n"))
+	require.EqualError(t, err, "tsdump contains no cr.store.replicas datapoints")
+}
+
+func TestSeedFromRangeReport(t *testing.T) {
+	const report = `range_id,replicas,lease_holder,range_size
+1,"{1,2,3}",2,100
+2,"{2,3,5}",NULL,200
+`
+	seed, err := SeedFromRangeReport(strings.NewReader(report))
+	require.NoError(t, err)
+	require.Equal(t, &Seed{
+		Stores:    []roachpb.StoreID{1, 2, 3, 5},
+		Replicas:  []int64{1, 2, 2, 1},
+		Leases:    []int64{0, 2, 0, 0},
+		LiveBytes: 900,
+		Ranges: []SeedRange{
+			{Replicas: []roachpb.StoreID{1, 2, 3}, Leaseholder: 2, Size: 100},
+			{Replicas: []roachpb.StoreID{2, 3, 5}, Leaseholder: 2, Size: 200},
+		},
+	}, seed)
+
+	_, err = SeedFromRangeReport(strings.NewReader("range_id\n1\n"))
+	require.EqualError(t, err, "range report has no replicas column")
+	_, err = SeedFromRangeReport(strings.NewReader("replicas\n1\n"))
+	require.ErrorContains(t, err, `line 2: malformed replicas "1"`)
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	spec, err := ParseSpec([]byte(testSpec))
+	require.NoError(t, err)
+
+	history, err := Run(ctx, spec, nil /* seed */)
+	require.NoError(t, err)
+	require.NotEmpty(t, history.Recorded)
+	// The node added after a minute shows up in the metrics.
+	require.Len(t, history.Recorded[0], 3)
+	require.Len(t, history.Recorded[len(history.Recorded)-1], 4)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteReport(&buf, history.Recorded, ReportOptions{
+		Format:   ReportCSV,
+		Stats:    []string{"replicas", "leases"},
+		Interval: time.Minute,
+	}))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal(t, "elapsed_seconds,store,replicas,leases", lines[0])
+	// The first row is recorded before the node is added, the following ones
+	// after.
+	require.Len(t, lines, 1+3+5*4)
+	require.True(t, strings.HasPrefix(lines[1], "0,1,"), lines[1])
+
+	buf.Reset()
+	require.NoError(t, WriteReport(&buf, history.Recorded, ReportOptions{
+		Format:   ReportText,
+		Stats:    DefaultStats,
+		Interval: time.Minute,
+	}))
+	lines = strings.Split(buf.String(), "\n")
+	require.Equal(t, "replicas", lines[0])
+	require.Equal(t,
+		[]string{"elapsed", "min", "mean", "max", "stddev", "s1", "s2", "s3", "s4"},
+		strings.Fields(lines[1]))
+	// The added store has no value before it joins the cluster.
+	require.Equal(t, "-", strings.Fields(lines[2])[8])
+
+	require.EqualError(t, WriteReport(&buf, history.Recorded, ReportOptions{
+		Format: ReportText, Stats: []string{"foo"},
+	}), "unknown stat \"foo\", expected one of: disk_fraction_used, lease_moves, leases, "+
+		"qps, range_splits, read, read_b, replica_b_rcvd, replica_b_sent, replica_moves, "+
+		"replicas, write, write_b")
+}
+
+func TestRunSeeded(t *testing.T) {
+	ctx := context.Background()
+	seed := &Seed{
+		Stores:    []roachpb.StoreID{2, 5, 7},
+		Replicas:  []int64{30, 30, 30},
+		Leases:    []int64{20, 10, 0},
+		LiveBytes: 90 << 20,
+		QPS:       100,
+	}
+
+	// Without a topology, the cluster has a node per seeded store.
+	spec, err := ParseSpec([]byte("duration: 1m"))
+	require.NoError(t, err)
+	sim, err := NewSimulator(spec, seed)
+	require.NoError(t, err)
+	sim.RunSim(ctx)
+	history := sim.History()
+	first := history.Recorded[0]
+	require.Len(t, first, 3)
+	require.EqualValues(t, 90, first[0].Replicas+first[1].Replicas+first[2].Replicas)
+	require.Greater(t, first[0].Leases, first[2].Leases)
+
+	// A topology with more stores than the seed starts the extra stores empty;
+	// one with fewer stores is rejected.
+	spec, err = ParseSpec([]byte(`
+duration: 1m
+cluster: {regions: [{name: a, zones: [{name: a1, nodes: 4}]}]}
+`))
+	require.NoError(t, err)
+	history, err = Run(ctx, spec, seed)
+	require.NoError(t, err)
+	require.Len(t, history.Recorded[0], 4)
+
+	spec, err = ParseSpec([]byte(`
+cluster: {regions: [{name: a, zones: [{name: a1, nodes: 2}]}]}
+`))
+	require.NoError(t, err)
+	_, err = NewSimulator(spec, seed)
+	require.EqualError(t, err, "the seed has 3 stores, but the cluster only has 2")
+
+	// The exact placement of a range report is used.
+	seed = &Seed{
+		Stores:   []roachpb.StoreID{1, 2, 3, 5},
+		Replicas: []int64{1, 2, 2, 1},
+		Leases:   []int64{0, 2, 0, 0},
+		Ranges: []SeedRange{
+			{Replicas: []roachpb.StoreID{1, 2, 3}, Leaseholder: 2},
+			{Replicas: []roachpb.StoreID{2, 3, 5}, Leaseholder: 2},
+		},
+	}
+	spec, err = ParseSpec([]byte(`
+duration: 10s
+settings: {load_based_rebalancing: "off"}
+`))
+	require.NoError(t, err)
+	history, err = Run(ctx, spec, seed)
+	require.NoError(t, err)
+	first = history.Recorded[0]
+	require.Len(t, first, 4)
+	require.EqualValues(t, 2, first[1].Leases)
+}
@@ -0,0 +1,237 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package scenario
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/errors"
+)
+
+// Seed is the replica, lease and load distribution of an existing cluster,
+// used to initialize a simulation in place of the range count and placement
+// of the Spec. The simulated stores 1..n stand in for the seed's stores, in
+// ascending order of store ID.
+type Seed struct {
+	// Stores are the IDs of the cluster's stores, in ascending order.
+	Stores []roachpb.StoreID
+	// Replicas and Leases are the number of replicas and leases of each store.
+	Replicas, Leases []int64
+	// LiveBytes is the total number of live bytes of the replicas of the
+	// cluster.
+	LiveBytes int64
+	// QPS, ReadsPerSecond and WritesPerSecond are the total load served by the
+	// leaseholders of the cluster.
+	QPS, ReadsPerSecond, WritesPerSecond float64
+	// Ranges is the placement of each of the cluster's ranges, if known. When
+	// set, it takes precedence over Replicas and Leases.
+	Ranges []SeedRange
+}
+
+// SeedRange is the placement of a range.
+type SeedRange struct {
+	Replicas    []roachpb.StoreID
+	Leaseholder roachpb.StoreID
+	Size        int64
+}
+
+// The store metrics read from a tsdump to seed a simulation.
+const (
+	replicasMetric        = "cr.store.replicas"
+	leasesMetric          = "cr.store.replicas.leaseholders"
+	liveBytesMetric       = "cr.store.livebytes"
+	qpsMetric             = "cr.store.rebalancing.queriespersecond"
+	readsPerSecondMetric  = "cr.store.rebalancing.readspersecond"
+	writesPerSecondMetric = "cr.store.rebalancing.writespersecond"
+)
+
+// SeedFromTSDump reads a seed from a time series dump in the CSV format of
+// `cockroach debug tsdump --format=csv`. The latest datapoint of each store's
+// replica, lease, live bytes and load metrics is used.
+func SeedFromTSDump(r io.Reader) (*Seed, error) {
+	type datapoint struct {
+		ts    time.Time
+		value float64
+	}
+	latest := make(map[string]map[roachpb.StoreID]datapoint)
+	for _, name := range []string{
+		replicasMetric, leasesMetric, liveBytesMetric,
+		qpsMetric, readsPerSecondMetric, writesPerSecondMetric,
+	} {
+		latest[name] = make(map[roachpb.StoreID]datapoint)
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 4
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading tsdump")
+		}
+		name, tsStr, source, valueStr := record[0], record[1], record[2], record[3]
+		points, ok := latest[name]
+		if !ok {
+			continue
+		}
+		storeID, err := strconv.Atoi(source)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing store ID of %s", name)
+		}
+		ts, err := time.Parse(time.RFC3339, tsStr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing timestamp of %s", name)
+		}
+		value, err := strconv.ParseFloat(valueStr, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing value of %s", name)
+		}
+		if prev, ok := points[roachpb.StoreID(storeID)]; !ok || !ts.Before(prev.ts) {
+			points[roachpb.StoreID(storeID)] = datapoint{ts: ts, value: value}
+		}
+	}
+
+	replicas := latest[replicasMetric]
+	if len(replicas) == 0 {
+		return nil, errors.Newf("tsdump contains no %s datapoints", replicasMetric)
+	}
+	seed := &Seed{}
+	for storeID := range replicas {
+		seed.Stores = append(seed.Stores, storeID)
+	}
+	sort.Slice(seed.Stores, func(i, j int) bool { return seed.Stores[i] < seed.Stores[j] })
+	for _, storeID := range seed.Stores {
+		seed.Replicas = append(seed.Replicas, int64(replicas[storeID].value))
+		seed.Leases = append(seed.Leases, int64(latest[leasesMetric][storeID].value))
+		seed.LiveBytes += int64(latest[liveBytesMetric][storeID].value)
+		seed.QPS += latest[qpsMetric][storeID].value
+		seed.ReadsPerSecond += latest[readsPerSecondMetric][storeID].value
+		seed.WritesPerSecond += latest[writesPerSecondMetric][storeID].value
+	}
+	return seed, nil
+}
+
+// SeedFromRangeReport reads a seed from a CSV range report with a header row,
+// in the format of
+//
+//	cockroach sql --format=csv -e \
+//	  "SELECT range_id, replicas, lease_holder, range_size FROM crdb_internal.ranges"
+//
+// The replicas column is required; lease_holder and range_size are optional.
+// Ranges without a leaseholder are assigned to their first replica.
+func SeedFromRangeReport(r io.Reader) (*Seed, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading range report header")
+	}
+	replicasCol, leaseholderCol, sizeCol := -1, -1, -1
+	for i, col := range header {
+		switch strings.TrimSpace(col) {
+		case "replicas":
+			replicasCol = i
+		case "lease_holder":
+			leaseholderCol = i
+		case "range_size":
+			sizeCol = i
+		}
+	}
+	if replicasCol < 0 {
+		return nil, errors.New("range report has no replicas column")
+	}
+
+	seed := &Seed{}
+	replicas := make(map[roachpb.StoreID]int64)
+	leases := make(map[roachpb.StoreID]int64)
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading range report")
+		}
+		var rng SeedRange
+		if rng.Replicas, err = parseStoreIDArray(record[replicasCol]); err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+		if len(rng.Replicas) == 0 {
+			return nil, errors.Newf("line %d: range has no replicas", line)
+		}
+		rng.Leaseholder = rng.Replicas[0]
+		if leaseholderCol >= 0 && record[leaseholderCol] != "" && record[leaseholderCol] != "NULL" {
+			lh, err := strconv.Atoi(record[leaseholderCol])
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d: parsing lease_holder", line)
+			}
+			rng.Leaseholder = roachpb.StoreID(lh)
+		}
+		if sizeCol >= 0 && record[sizeCol] != "" && record[sizeCol] != "NULL" {
+			if rng.Size, err = strconv.ParseInt(record[sizeCol], 10, 64); err != nil {
+				return nil, errors.Wrapf(err, "line %d: parsing range_size", line)
+			}
+		}
+		for _, storeID := range rng.Replicas {
+			replicas[storeID]++
+		}
+		leases[rng.Leaseholder]++
+		seed.LiveBytes += rng.Size * int64(len(rng.Replicas))
+		seed.Ranges = append(seed.Ranges, rng)
+	}
+	if len(seed.Ranges) == 0 {
+		return nil, errors.New("range report contains no ranges")
+	}
+
+	for storeID := range replicas {
+		seed.Stores = append(seed.Stores, storeID)
+	}
+	sort.Slice(seed.Stores, func(i, j int) bool { return seed.Stores[i] < seed.Stores[j] })
+	for _, storeID := range seed.Stores {
+		seed.Replicas = append(seed.Replicas, replicas[storeID])
+		seed.Leases = append(seed.Leases, leases[storeID])
+	}
+	return seed, nil
+}
+
+// parseStoreIDArray parses an array of store IDs, such as {1,2,3}.
+func parseStoreIDArray(s string) ([]roachpb.StoreID, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return nil, errors.Newf("malformed replicas %q", s)
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	if s == "" {
+		return nil, nil
+	}
+	var ids []roachpb.StoreID
+	for _, part := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, errors.Wrapf(err, "malformed replicas %q", s)
+		}
+		ids = append(ids, roachpb.StoreID(id))
+	}
+	return ids, nil
+}
+
+// storeIndex returns the 0-based index of the given store in the seed's
+// stores, i.e. the ID of the simulated store standing in for it minus 1.
+func (s *Seed) storeIndex(storeID roachpb.StoreID) int {
+	return sort.Search(len(s.Stores), func(i int) bool { return s.Stores[i] >= storeID })
+}
@@ -0,0 +1,285 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+// Package scenario builds allocation simulations from declarative, YAML
+// encoded, descriptions of a cluster and its workload. It backs the `cockroach
+// debug allocator-simulate` command.
+package scenario
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/config/zonepb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/asim/gen"
+	"github.com/cockroachdb/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	defaultDuration          = 30 * time.Minute
+	defaultSeed              = 42
+	defaultDiskCapacityGB    = 1024
+	defaultRanges            = 1
+	defaultReplicationFactor = 3
+	defaultKeyspace          = 200000
+	defaultMinBlock          = 1
+	defaultMaxBlock          = 1
+)
+
+// Spec is a declarative description of a simulated cluster, its ranges and
+// workload, and the changes made to it while the simulation runs. For
+// example:
+//
+//	duration: 1h
+//	cluster:
+//	  regions:
+//	  - name: us-east1
+//	    zones:
+//	    - {name: us-east1-a, nodes: 3}
+//	    - {name: us-east1-b, nodes: 3}
+//	    - {name: us-east1-c, nodes: 3}
+//	ranges:
+//	  count: 3000
+//	  bytes: 268435456
+//	  placement: skewed
+//	zone_configs:
+//	- start_key: 0
+//	  end_key: 100000
+//	  config:
+//	    num_replicas: 5
+//	workload:
+//	- {rate: 5000, rw_ratio: 0.95}
+//	add_nodes:
+//	- {at: 10m, nodes: 3, locality: "region=us-east1,zone=us-east1-a"}
+//
+// Keys in the simulator are integers in [0, keyspace).
+type Spec struct {
+	// Duration is the simulated duration of the run.
+	Duration time.Duration `yaml:"duration"`
+	// Seed is the seed used by the randomized simulator components.
+	Seed int64 `yaml:"seed"`
+	// Cluster is the initial topology of the cluster.
+	Cluster ClusterSpec `yaml:"cluster"`
+	// Ranges is the initial range count and placement.
+	Ranges RangesSpec `yaml:"ranges"`
+	// ZoneConfigs are applied to spans of the keyspace.
+	ZoneConfigs []ZoneConfigSpec `yaml:"zone_configs"`
+	// Workload is the load applied to the cluster. The generators run
+	// concurrently.
+	Workload []WorkloadSpec `yaml:"workload"`
+	// AddNodes are nodes which join the cluster while the simulation runs.
+	AddNodes []AddNodesSpec `yaml:"add_nodes"`
+	// Settings overrides the default simulation settings.
+	Settings SettingsSpec `yaml:"settings"`
+}
+
+// ClusterSpec describes the nodes and stores of a cluster.
+type ClusterSpec struct {
+	DiskCapacityGB int          `yaml:"disk_capacity_gb"`
+	Regions        []RegionSpec `yaml:"regions"`
+}
+
+// RegionSpec describes a region and its zones.
+type RegionSpec struct {
+	Name  string     `yaml:"name"`
+	Zones []ZoneSpec `yaml:"zones"`
+}
+
+// ZoneSpec describes the nodes of an availability zone.
+type ZoneSpec struct {
+	Name          string `yaml:"name"`
+	Nodes         int    `yaml:"nodes"`
+	StoresPerNode int    `yaml:"stores_per_node"`
+}
+
+// RangesSpec describes the initial ranges of the cluster and the placement of
+// their replicas and leases.
+type RangesSpec struct {
+	Count             int   `yaml:"count"`
+	ReplicationFactor int   `yaml:"replication_factor"`
+	Bytes             int64 `yaml:"bytes"`
+	Keyspace          int   `yaml:"keyspace"`
+	// Placement is one of "even" or "skewed".
+	Placement string `yaml:"placement"`
+}
+
+// ZoneConfigSpec applies a zone config to the span [StartKey, EndKey), once At
+// has elapsed since the start of the simulation. Fields which are not set in
+// Config are inherited from the default zone config.
+type ZoneConfigSpec struct {
+	StartKey int64             `yaml:"start_key"`
+	EndKey   int64             `yaml:"end_key"`
+	At       time.Duration     `yaml:"at"`
+	Config   zonepb.ZoneConfig `yaml:"config"`
+}
+
+// WorkloadSpec describes a workload generator.
+type WorkloadSpec struct {
+	// Rate is the number of requests per second.
+	Rate float64 `yaml:"rate"`
+	// RWRatio is the fraction of requests which are reads.
+	RWRatio      float64 `yaml:"rw_ratio"`
+	SkewedAccess bool    `yaml:"skewed_access"`
+	MinBlock     int     `yaml:"min_block"`
+	MaxBlock     int     `yaml:"max_block"`
+	MinKey       int64   `yaml:"min_key"`
+	MaxKey       int64   `yaml:"max_key"`
+}
+
+// AddNodesSpec adds nodes to the cluster once At has elapsed since the start of
+// the simulation.
+type AddNodesSpec struct {
+	At            time.Duration `yaml:"at"`
+	Nodes         int           `yaml:"nodes"`
+	StoresPerNode int           `yaml:"stores_per_node"`
+	// Locality is the locality of the nodes, e.g. "region=a,zone=b".
+	Locality string `yaml:"locality"`
+}
+
+// SettingsSpec overrides the default simulation settings. Unset fields keep
+// their default values.
+type SettingsSpec struct {
+	TickInterval    time.Duration `yaml:"tick_interval"`
+	MetricsInterval time.Duration `yaml:"metrics_interval"`
+	// LoadBasedRebalancing is one of "off", "leases" or "leases and
+	// replicas", as for the kv.allocator.load_based_rebalancing cluster
+	// setting.
+	LoadBasedRebalancing    string  `yaml:"load_based_rebalancing"`
+	RangeRebalanceThreshold float64 `yaml:"range_rebalance_threshold"`
+	QPSRebalanceThreshold   float64 `yaml:"qps_rebalance_threshold"`
+	SplitQPSThreshold       float64 `yaml:"split_qps_threshold"`
+}
+
+// loadBasedRebalancingModes maps the values of SettingsSpec.LoadBasedRebalancing
+// to the modes of the store rebalancer.
+var loadBasedRebalancingModes = map[string]int64{
+	"off":                 0,
+	"leases":              1,
+	"leases and replicas": 2,
+}
+
+// ParseSpec parses a YAML encoded Spec, fills in the defaults of unset fields
+// and validates it.
+func ParseSpec(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := yaml.UnmarshalStrict(data, &spec); err != nil {
+		return nil, errors.Wrap(err, "parsing simulation spec")
+	}
+	spec.setDefaults()
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+func (s *Spec) setDefaults() {
+	if s.Duration == 0 {
+		s.Duration = defaultDuration
+	}
+	if s.Seed == 0 {
+		s.Seed = defaultSeed
+	}
+	if s.Cluster.DiskCapacityGB == 0 {
+		s.Cluster.DiskCapacityGB = defaultDiskCapacityGB
+	}
+	if s.Ranges.ReplicationFactor == 0 {
+		s.Ranges.ReplicationFactor = defaultReplicationFactor
+	}
+	if s.Ranges.Keyspace == 0 {
+		s.Ranges.Keyspace = defaultKeyspace
+	}
+	if s.Ranges.Placement == "" {
+		s.Ranges.Placement = gen.Even.String()
+	}
+	for i := range s.Workload {
+		w := &s.Workload[i]
+		if w.MinBlock == 0 {
+			w.MinBlock = defaultMinBlock
+		}
+		if w.MaxBlock == 0 {
+			w.MaxBlock = defaultMaxBlock
+		}
+		if w.MaxKey == 0 {
+			w.MaxKey = int64(s.Ranges.Keyspace)
+		}
+	}
+	for i := range s.ZoneConfigs {
+		z := &s.ZoneConfigs[i]
+		if z.EndKey == 0 {
+			z.EndKey = int64(s.Ranges.Keyspace)
+		}
+		defaultZone := zonepb.DefaultZoneConfig()
+		z.Config.InheritFromParent(&defaultZone)
+	}
+}
+
+func (s *Spec) validate() error {
+	if s.Duration < 0 {
+		return errors.Newf("duration must be positive: %s", s.Duration)
+	}
+	for _, r := range s.Cluster.Regions {
+		for _, z := range r.Zones {
+			if z.Nodes <= 0 {
+				return errors.Newf("zone %q in region %q must have at least one node", z.Name, r.Name)
+			}
+		}
+	}
+	if s.Ranges.Count < 0 {
+		return errors.Newf("range count must be positive: %d", s.Ranges.Count)
+	}
+	if s.Ranges.Count > s.Ranges.Keyspace {
+		return errors.Newf("range count %d exceeds keyspace %d", s.Ranges.Count, s.Ranges.Keyspace)
+	}
+	if s.Ranges.ReplicationFactor < 0 {
+		return errors.Newf("replication factor must be positive: %d", s.Ranges.ReplicationFactor)
+	}
+	switch s.Ranges.Placement {
+	case gen.Even.String(), gen.Skewed.String():
+	default:
+		return errors.Newf("unknown range placement %q, expected %q or %q",
+			s.Ranges.Placement, gen.Even, gen.Skewed)
+	}
+	for _, z := range s.ZoneConfigs {
+		if z.StartKey < 0 || z.StartKey >= z.EndKey {
+			return errors.Newf("invalid zone config span [%d,%d)", z.StartKey, z.EndKey)
+		}
+		if err := z.Config.Validate(); err != nil {
+			return errors.Wrapf(err, "zone config for span [%d,%d)", z.StartKey, z.EndKey)
+		}
+		if err := z.Config.EnsureFullyHydrated(); err != nil {
+			return errors.Wrapf(err, "zone config for span [%d,%d)", z.StartKey, z.EndKey)
+		}
+	}
+	for _, w := range s.Workload {
+		if w.Rate < 0 {
+			return errors.Newf("workload rate must be positive: %f", w.Rate)
+		}
+		if w.RWRatio < 0 || w.RWRatio > 1 {
+			return errors.Newf("workload rw_ratio must be in [0,1]: %f", w.RWRatio)
+		}
+		if w.MinBlock > w.MaxBlock {
+			return errors.Newf("workload min_block %d exceeds max_block %d", w.MinBlock, w.MaxBlock)
+		}
+		if w.MinKey >= w.MaxKey {
+			return errors.Newf("invalid workload key range [%d,%d)", w.MinKey, w.MaxKey)
+		}
+	}
+	for _, n := range s.AddNodes {
+		if n.Nodes <= 0 {
+			return errors.Newf("add_nodes must add at least one node")
+		}
+	}
+	if mode := s.Settings.LoadBasedRebalancing; mode != "" {
+		if _, ok := loadBasedRebalancingModes[mode]; !ok {
+			return errors.Newf("unknown load_based_rebalancing mode %q", mode)
+		}
+	}
+	return nil
+}
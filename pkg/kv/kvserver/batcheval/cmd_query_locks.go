@@ -74,5 +74,15 @@ func QueryLocks(
 	reply.ResumeSpan = resumeState.ResumeSpan
 	reply.ResumeNextBytes = resumeState.ResumeNextBytes
 
+	if args.IncludeTxnWaitQueue {
+		// Only report the pushes on transactions anchored in the part of the span
+		// covered by this response, so that they aren't repeated on resumption.
+		span := args.Span()
+		if resumeState.ResumeSpan != nil {
+			span.EndKey = resumeState.ResumeSpan.Key
+		}
+		reply.TxnWaits = concurrencyManager.QueryTxnWaitQueueState(ctx, span)
+	}
+
 	return result.Result{}, nil
 }
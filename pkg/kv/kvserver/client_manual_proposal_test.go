@@ -193,7 +193,7 @@ LIMIT
 			}
 
 			idKey := raftlog.MakeCmdIDKey()
-			payload, err := raftlog.EncodeCommand(ctx, &raftCmd, idKey, nil)
+			payload, _, err := raftlog.EncodeCommand(ctx, &raftCmd, idKey, nil, raftlog.CompressionConfig{})
 			require.NoError(t, err)
 			ents = append(ents, raftpb.Entry{
 				Term:  lastTerm,
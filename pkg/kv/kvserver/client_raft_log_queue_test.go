@@ -20,6 +20,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/config/zonepb"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/server"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
@@ -123,3 +125,114 @@ func TestRaftLogQueue(t *testing.T) {
 			afterTruncationIndex, after2ndTruncationIndex)
 	}
 }
+
+// TestRaftLogQueueWitness verifies that a range with a witness doesn't truncate
+// its log past a full voter that fell behind, even once that voter is no
+// longer active and the log is too large. Otherwise, if the leaseholder then
+// failed, the lagging full voter could only be caught up by the witness, which
+// can't send it a snapshot, and the range would be unavailable.
+func TestRaftLogQueueWitness(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	// Set maxBytes to something small so that the log is too large without
+	// adding 64MB of logs.
+	const maxBytes = 1 << 16
+
+	zoneConfig := zonepb.DefaultZoneConfig()
+	zoneConfig.RangeMaxBytes = proto.Int64(maxBytes)
+
+	ctx := context.Background()
+	// n1 only holds the system ranges, so that the cluster survives the loss of
+	// the scratch range's leaseholder, n2. n3 is the full voter that falls
+	// behind, and n4 the witness.
+	tc := testcluster.StartTestCluster(t, 4,
+		base.TestClusterArgs{
+			ReplicationMode: base.ReplicationManual,
+			ServerArgs: base.TestServerArgs{
+				Knobs: base.TestingKnobs{
+					Server: &server.TestingKnobs{
+						DefaultZoneConfigOverride: &zoneConfig,
+					},
+				},
+			},
+		})
+	defer tc.Stopper().Stop(ctx)
+
+	key := tc.ScratchRange(t)
+	desc := tc.AddVotersOrFatal(t, key, tc.Targets(1, 2)...)
+	tc.TransferRangeLeaseOrFatal(t, desc, tc.Target(1))
+	desc = tc.RemoveVotersOrFatal(t, key, tc.Target(0))
+	newDesc, err := tc.Server(1).DB().AdminChangeReplicas(
+		ctx, key, desc, kvpb.MakeReplicationChanges(roachpb.ADD_WITNESS, tc.Target(3)),
+	)
+	require.NoError(t, err)
+	require.Len(t, newDesc.Replicas().WitnessDescriptors(), 1)
+
+	store := tc.GetFirstStoreFromServer(t, 1)
+	leaderRepl := store.LookupReplica(roachpb.RKey(key))
+	testutils.SucceedsSoon(t, func() error {
+		if l := tc.GetRaftLeader(t, roachpb.RKey(key)); l.StoreID() != store.StoreID() {
+			return errors.Errorf("raft leader is on s%d, not on the leaseholder", l.StoreID())
+		}
+		return nil
+	})
+
+	// Partition n3 away from the range.
+	laggingStore := tc.GetFirstStoreFromServer(t, 2)
+	laggingRepl := laggingStore.LookupReplica(roachpb.RKey(key))
+	for _, i := range []int{1, 2, 3} {
+		s := tc.GetFirstStoreFromServer(t, i)
+		h := &unreliableRaftHandler{
+			rangeID:                    newDesc.RangeID,
+			IncomingRaftMessageHandler: s,
+		}
+		if i != 2 {
+			h.dropReq = func(req *kvserverpb.RaftMessageRequest) bool {
+				return req.FromReplica.StoreID == laggingStore.StoreID()
+			}
+			h.dropHB = func(hb *kvserverpb.RaftHeartbeat) bool {
+				return hb.FromReplicaID == laggingRepl.ReplicaID()
+			}
+		}
+		tc.Servers[i].RaftTransport().(*kvserver.RaftTransport).ListenIncomingRaftMessages(s.StoreID(), h)
+	}
+
+	// Write a collection of values to make the log too large.
+	value := bytes.Repeat([]byte("v"), 1000) // 1KB
+	k := key
+	for size := int64(0); size < 2*maxBytes; size += int64(len(value)) {
+		k = k.Next()
+		if _, pErr := kv.SendWrapped(ctx, store.TestSender(), putArgs(k, value)); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	leaseDuration := store.GetStoreConfig().RangeLeaseDuration
+	testutils.SucceedsSoon(t, func() error {
+		if leaderRepl.IsFollowerActiveSince(ctx, laggingRepl.ReplicaID(), leaseDuration) {
+			return errors.New("lagging full voter is still considered active")
+		}
+		return nil
+	})
+
+	// Force truncation checks. The log is not truncated past the lagging full
+	// voter.
+	for i := 0; i < 3; i++ {
+		store.MustForceRaftLogScanAndProcess()
+		require.NoError(t, store.TODOEngine().Flush())
+	}
+	require.LessOrEqual(t, leaderRepl.GetFirstIndex(), laggingRepl.GetLastIndex()+1)
+
+	// Take down the leaseholder and heal the partition. The lagging full voter
+	// and the witness still form a quorum, and the full voter is caught up from
+	// the witness's log before it acquires the lease and serves the write.
+	tc.StopServer(1)
+	for _, i := range []int{2, 3} {
+		s := tc.GetFirstStoreFromServer(t, i)
+		tc.Servers[i].RaftTransport().(*kvserver.RaftTransport).ListenIncomingRaftMessages(s.StoreID(), s)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, testutils.DefaultSucceedsSoonDuration)
+	defer cancel()
+	require.NoError(t, tc.Server(2).DB().Put(timeoutCtx, k, "after"))
+	require.True(t, laggingRepl.OwnsValidLease(ctx, tc.Servers[2].Clock().NowAsClockTimestamp()))
+}
@@ -280,6 +280,11 @@ type TransactionManager interface {
 	// transaction either directly or indirectly. The method is used to perform
 	// deadlock detection. See txnWaitQueue for more.
 	GetDependents(uuid.UUID) []uuid.UUID
+
+	// QueryTxnWaitQueueState gathers metadata on the transactional pushers
+	// waiting in the txn wait queue on transactions whose records are anchored
+	// in the provided span.
+	QueryTxnWaitQueueState(ctx context.Context, span roachpb.Span) []roachpb.TxnWaitInfo
 }
 
 // RangeStateListener is concerned with observing updates to the concurrency
@@ -985,6 +990,11 @@ type txnWaitQueue interface {
 	// deadlock detection.
 	GetDependents(uuid.UUID) []uuid.UUID
 
+	// QueryWaitingPushes returns metadata on the transactional pushers waiting
+	// in the queue on transactions whose records are anchored in the provided
+	// span.
+	QueryWaitingPushes(roachpb.Span) []roachpb.TxnWaitInfo
+
 	// MaybeWaitForPush checks whether there is a queue already established for
 	// transaction being pushed by the provided request. If not, or if the
 	// PushTxn request isn't queueable, the method returns immediately. If there
@@ -144,6 +144,9 @@ type Config struct {
 	// Metrics.
 	TxnWaitMetrics *txnwait.Metrics
 	SlowLatchGauge *metric.Gauge
+	// OnContentionEvent, if set, is called with the ContentionEvent of each
+	// conflicting lock that a request waited on.
+	OnContentionEvent func(*kvpb.ContentionEvent)
 	// Configs + Knobs.
 	MaxLockTableSize  int64
 	DisableTxnPushing bool
@@ -181,6 +184,7 @@ func NewManager(cfg Config) Manager {
 			ir:                cfg.IntentResolver,
 			lt:                lt,
 			disableTxnPushing: cfg.DisableTxnPushing,
+			onContentionEvent: cfg.OnContentionEvent,
 		},
 		// TODO(nvanbenschoten): move pkg/storage/txnwait to a new
 		// pkg/storage/concurrency/txnwait package.
@@ -561,6 +565,13 @@ func (m *managerImpl) OnTransactionUpdated(ctx context.Context, txn *roachpb.Tra
 	m.twq.UpdateTxn(ctx, txn)
 }
 
+// QueryTxnWaitQueueState implements the TransactionManager interface.
+func (m *managerImpl) QueryTxnWaitQueueState(
+	ctx context.Context, span roachpb.Span,
+) []roachpb.TxnWaitInfo {
+	return m.twq.QueryWaitingPushes(span)
+}
+
 // GetDependents implements the TransactionManager interface.
 func (m *managerImpl) GetDependents(txnID uuid.UUID) []uuid.UUID {
 	return m.twq.GetDependents(txnID)
@@ -112,6 +112,9 @@ type lockTableWaiterImpl struct {
 	disableTxnPushing bool
 	// When set, called just before each push timer event is processed.
 	onPushTimer func()
+	// When set, called with each contention event, even if the request is not
+	// being traced.
+	onContentionEvent func(*kvpb.ContentionEvent)
 }
 
 // IntentResolver is an interface used by lockTableWaiterImpl to push
@@ -148,6 +151,9 @@ func (w *lockTableWaiterImpl) WaitOn(
 	var lockDeadline time.Time
 
 	tracer := newContentionEventTracer(tracing.SpanFromContext(ctx), w.clock)
+	if w.onContentionEvent != nil {
+		tracer.SetOnContentionEvent(w.onContentionEvent)
+	}
 	// Make sure the contention time info is finalized when exiting the function.
 	defer tracer.notify(ctx, waitingState{kind: doneWaiting})
 
@@ -1074,7 +1080,8 @@ type contentionTag struct {
 // with the provided tracing span. The contentionEventTracer will emit events to
 // the respective span and will also act as a lazy tag on the span.
 //
-// sp can be nil, in which case the tracer will not do anything.
+// sp can be nil, in which case the tracer will not do anything beyond calling
+// the callback registered through SetOnContentionEvent, if any.
 //
 // It is legal to create a tracer on a span that has previously had another
 // tracer. In that case, the new tracer will absorb the counters from the
@@ -1122,8 +1129,8 @@ var _ tracing.LazyTag = &contentionTag{}
 // same event and no action is taken. If they differ, the open event (if any) is
 // finalized and added to the Span, and a new event initialized from the inputs.
 func (h *contentionEventTracer) notify(ctx context.Context, s waitingState) {
-	if h.sp == nil {
-		// No span to manipulate - don't do any work.
+	if h.sp == nil && h.onEvent == nil {
+		// No span to manipulate and no callback to call - don't do any work.
 		return
 	}
 
@@ -0,0 +1,142 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+// Package demandgossip lets the nodes or stores sharing a cluster-wide budget
+// exchange their demand for it over gossip, so that each of them can claim a
+// portion of the budget according to its demand.
+package demandgossip
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/gossip"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// ExpirationIntervals is the number of gossip intervals after which the demand
+// reported by another node or store is ignored, for instance because it went
+// down or stopped gossiping after losing all of its demand.
+const ExpirationIntervals = 3
+
+// Remote tracks the demand last reported by other nodes or stores, keyed by
+// their ID. It is not safe for concurrent use.
+type Remote[ID comparable, D any] struct {
+	m map[ID]remoteDemand[D]
+}
+
+type remoteDemand[D any] struct {
+	updated time.Time
+	demand  D
+}
+
+// Update records the demand reported by the given node or store.
+func (r *Remote[ID, D]) Update(now time.Time, id ID, demand D) {
+	if r.m == nil {
+		r.m = make(map[ID]remoteDemand[D])
+	}
+	r.m[id] = remoteDemand[D]{updated: now, demand: demand}
+}
+
+// Expire discards the demand which hasn't been updated in ExpirationIntervals
+// gossip intervals.
+func (r *Remote[ID, D]) Expire(now time.Time, interval time.Duration) {
+	for id, rd := range r.m {
+		if now.Sub(rd.updated) > ExpirationIntervals*interval {
+			delete(r.m, id)
+		}
+	}
+}
+
+// Range calls f with the demand of every node or store.
+func (r *Remote[ID, D]) Range(f func(id ID, demand D)) {
+	for id, rd := range r.m {
+		f(id, rd.demand)
+	}
+}
+
+// Config describes a demand exchanged over gossip.
+type Config struct {
+	// Name describes the demand in task names and log messages, e.g. "table
+	// write demand".
+	Name string
+	// KeyPrefix is the gossip key prefix of the demand. Each node or store
+	// gossips its demand under the prefix suffixed with its ID.
+	KeyPrefix string
+	// ID is the ID of the local node or store.
+	ID int64
+	// Interval returns the interval at which the demand is refreshed and
+	// gossiped.
+	Interval func() time.Duration
+	// Refresh returns the local demand, and whether it is idle. Nodes or stores
+	// whose demand is idle stop gossiping once they've gossiped the fact.
+	Refresh func() (demand protoutil.Message, idle bool)
+	// NewDemand returns an empty demand to unmarshal gossiped demand into.
+	NewDemand func() protoutil.Message
+	// UpdateRemote is called with the demand gossiped by the other nodes or
+	// stores.
+	UpdateRemote func(id int64, demand protoutil.Message)
+}
+
+// Start registers a callback which reports the demand gossiped by other nodes
+// or stores to cfg.UpdateRemote, and runs a loop in a goroutine which
+// periodically refreshes and gossips the local demand.
+func Start(ctx context.Context, stopper *stop.Stopper, g *gossip.Gossip, cfg Config) {
+	g.RegisterCallback(
+		gossip.MakePrefixPattern(cfg.KeyPrefix),
+		func(key string, content roachpb.Value) {
+			id, err := gossip.DecodeDemandKey(key, cfg.KeyPrefix)
+			if err != nil {
+				log.Errorf(ctx, "unable to decode %s key %q: %v", cfg.Name, key, err)
+				return
+			}
+			if id == cfg.ID {
+				return
+			}
+			demand := cfg.NewDemand()
+			if err := content.GetProto(demand); err != nil {
+				log.Errorf(ctx, "unable to unmarshal %s of %d: %v", cfg.Name, id, err)
+				return
+			}
+			cfg.UpdateRemote(id, demand)
+		},
+	)
+
+	_ = stopper.RunAsyncTask(ctx, cfg.KeyPrefix+"-gossip", func(ctx context.Context) {
+		var timer timeutil.Timer
+		defer timer.Stop()
+		var gossiped bool
+		for {
+			interval := cfg.Interval()
+			timer.Reset(interval)
+			select {
+			case <-timer.C:
+				timer.Read = true
+			case <-stopper.ShouldQuiesce():
+				return
+			}
+			demand, idle := cfg.Refresh()
+			if idle && !gossiped {
+				continue
+			}
+			gossiped = !idle
+			// The demand expires if it isn't refreshed, for instance because the
+			// node went down, so that the others stop accounting for it.
+			ttl := 2 * interval
+			if err := g.AddInfoProto(gossip.MakeDemandKey(cfg.KeyPrefix, cfg.ID), demand, ttl); err != nil {
+				log.Warningf(ctx, "unable to gossip %s: %v", cfg.Name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,58 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package demandgossip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/gossip"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemote(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var r Remote[int, float64]
+	get := func() map[int]float64 {
+		m := make(map[int]float64)
+		r.Range(func(id int, demand float64) { m[id] = demand })
+		return m
+	}
+	require.Empty(t, get())
+
+	start := time.Unix(0, 0)
+	const interval = 10 * time.Second
+	r.Update(start, 1, 10)
+	r.Update(start.Add(interval), 2, 20)
+	r.Update(start.Add(interval), 1, 15)
+	require.Equal(t, map[int]float64{1: 15, 2: 20}, get())
+
+	// Demand expires after ExpirationIntervals intervals without updates.
+	r.Update(start.Add(2*interval), 2, 25)
+	r.Expire(start.Add((ExpirationIntervals+1)*interval), interval)
+	require.Equal(t, map[int]float64{1: 15, 2: 25}, get())
+	r.Expire(start.Add((ExpirationIntervals+1)*interval+time.Second), interval)
+	require.Equal(t, map[int]float64{2: 25}, get())
+}
+
+func TestDemandKey(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	key := gossip.MakeDemandKey(gossip.KeyTableWriteDemandPrefix, 42)
+	id, err := gossip.DecodeDemandKey(key, gossip.KeyTableWriteDemandPrefix)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), id)
+
+	_, err = gossip.DecodeDemandKey(key, gossip.KeySnapshotSchedulerDemandPrefix)
+	require.Error(t, err)
+}
@@ -118,6 +118,12 @@ func ConsistencyQueueShouldQueue(
 		disableLastProcessedCheck, interval})
 }
 
+// WitnessWriteBatchRepr returns the portion of the given WriteBatch
+// representation that is applied by a witness replica.
+func WitnessWriteBatchRepr(repr []byte) ([]byte, error) {
+	return witnessWriteBatchRepr(repr)
+}
+
 // LogReplicaChangeTest adds a fake replica change event to the log for the
 // range which contains the given key.
 func (s *Store) LogReplicaChangeTest(
@@ -40,6 +40,10 @@ type Store interface {
 	// SetQueueActive disables/enables the named queue.
 	SetQueueActive(active bool, queue string) error
 
+	// RotateStoreEncryptionKey rotates the encryption-at-rest store key of the
+	// store and returns the ID of the new key.
+	RotateStoreEncryptionKey(ctx context.Context) (string, error)
+
 	// GetReplicaMutexForTesting returns the mutex of the replica with the given
 	// range ID, or nil if no replica was found. This is used for testing.
 	GetReplicaMutexForTesting(rangeID roachpb.RangeID) *syncutil.RWMutex
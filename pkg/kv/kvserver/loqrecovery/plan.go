@@ -430,7 +430,9 @@ func rankReplicasBySurvivability(replicas []loqrecoverypb.ReplicaInfo) rankedRep
 	isVoter := func(desc loqrecoverypb.ReplicaInfo) int {
 		for _, replica := range desc.Desc.InternalReplicas {
 			if replica.StoreID == desc.StoreID {
-				if replica.IsVoterNewConfig() {
+				// Witnesses don't have any user data, so they can't be used to
+				// recover the range.
+				if replica.IsVoterNewConfig() && !replica.IsWitness() {
 					return 1
 				}
 				return 0
@@ -120,6 +120,24 @@ var (
 		Measurement: "Ranges",
 		Unit:        metric.Unit_COUNT,
 	}
+	metaUnderReplicatedRangeBytes = metric.Metadata{
+		Name:        "ranges.underreplicated.bytes",
+		Help:        "Total size of the ranges counted by ranges.underreplicated",
+		Measurement: "Storage",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaUnderReplicatedRangeETA = metric.Metadata{
+		Name: "ranges.underreplicated.eta",
+		Help: `Estimated time to re-replicate the under-replicated ranges of the cluster
+
+This is the total of ranges.underreplicated.bytes across all stores divided by
+the rate at which all stores recently sent recovery snapshots, as exchanged
+over gossip, and is therefore the same on every store (up to the staleness of
+gossip). It is -1 if no recovery snapshots are being sent while some ranges
+are under-replicated.`,
+		Measurement: "Latency",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
 
 	// Lease request metrics.
 	metaLeaseRequestSuccessCount = metric.Metadata{
@@ -1169,6 +1187,45 @@ state machines on this node.`,
 		Measurement: "Commands",
 		Unit:        metric.Unit_COUNT,
 	}
+	metaRaftCommandsCompressed = metric.Metadata{
+		Name:        "raft.commands.compressed",
+		Help:        "Number of proposed Raft commands whose payload was compressed",
+		Measurement: "Commands",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftCommandCompressionBytesIn = metric.Metadata{
+		Name: "raft.commands.compression.bytes_in",
+		Help: `Size of the payloads of compressed Raft commands before compression.
+
+Together with raft.commands.compression.bytes_out, this measures the
+compression ratio of Raft commands (see kv.raft.command.compression).`,
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaRaftCommandCompressionBytesOut = metric.Metadata{
+		Name:        "raft.commands.compression.bytes_out",
+		Help:        "Size of the payloads of compressed Raft commands after compression",
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaRaftCommandCompressionCPUNanos = metric.Metadata{
+		Name:        "raft.commands.compression.cpu_nanos",
+		Help:        "CPU time spent compressing the payloads of proposed Raft commands",
+		Measurement: "CPU Time",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
+	metaRaftCommandsDecompressed = metric.Metadata{
+		Name:        "raft.commands.decompressed",
+		Help:        "Number of applied Raft commands whose payload was decompressed",
+		Measurement: "Commands",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftCommandDecompressionCPUNanos = metric.Metadata{
+		Name:        "raft.commands.decompression.cpu_nanos",
+		Help:        "CPU time spent decompressing the payloads of applied Raft commands",
+		Measurement: "CPU Time",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
 	metaRaftLogCommitLatency = metric.Metadata{
 		Name: "raft.process.logcommit.latency",
 		Help: `Latency histogram for committing Raft log entries to stable storage
@@ -2183,6 +2240,8 @@ type StoreMetrics struct {
 	UnavailableRangeCount     *metric.Gauge
 	UnderReplicatedRangeCount *metric.Gauge
 	OverReplicatedRangeCount  *metric.Gauge
+	UnderReplicatedRangeBytes *metric.Gauge
+	UnderReplicatedRangeETA   *metric.Gauge
 
 	// Lease request metrics for successful and failed lease requests. These
 	// count proposals (i.e. it does not matter how many replicas apply the
@@ -2382,6 +2441,14 @@ type StoreMetrics struct {
 	WALBytesWritten            *metric.Gauge
 	WALBytesIn                 *metric.Gauge
 
+	// Raft command compression metrics.
+	RaftCommandsCompressed           *metric.Counter
+	RaftCommandCompressionBytesIn    *metric.Counter
+	RaftCommandCompressionBytesOut   *metric.Counter
+	RaftCommandCompressionCPUNanos   *metric.Counter
+	RaftCommandsDecompressed         *metric.Counter
+	RaftCommandDecompressionCPUNanos *metric.Counter
+
 	// Raft message metrics.
 	//
 	// An array for conveniently finding the appropriate metric.
@@ -2833,6 +2900,8 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 		UnavailableRangeCount:     metric.NewGauge(metaUnavailableRangeCount),
 		UnderReplicatedRangeCount: metric.NewGauge(metaUnderReplicatedRangeCount),
 		OverReplicatedRangeCount:  metric.NewGauge(metaOverReplicatedRangeCount),
+		UnderReplicatedRangeBytes: metric.NewGauge(metaUnderReplicatedRangeBytes),
+		UnderReplicatedRangeETA:   metric.NewGauge(metaUnderReplicatedRangeETA),
 
 		// Lease request metrics.
 		LeaseRequestSuccessCount: metric.NewCounter(metaLeaseRequestSuccessCount),
@@ -3058,6 +3127,14 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 		RaftTimeoutCampaign:  metric.NewCounter(metaRaftTimeoutCampaign),
 		RaftStorageReadBytes: metric.NewCounter(metaRaftStorageReadBytes),
 
+		// Raft command compression metrics.
+		RaftCommandsCompressed:           metric.NewCounter(metaRaftCommandsCompressed),
+		RaftCommandCompressionBytesIn:    metric.NewCounter(metaRaftCommandCompressionBytesIn),
+		RaftCommandCompressionBytesOut:   metric.NewCounter(metaRaftCommandCompressionBytesOut),
+		RaftCommandCompressionCPUNanos:   metric.NewCounter(metaRaftCommandCompressionCPUNanos),
+		RaftCommandsDecompressed:         metric.NewCounter(metaRaftCommandsDecompressed),
+		RaftCommandDecompressionCPUNanos: metric.NewCounter(metaRaftCommandDecompressionCPUNanos),
+
 		// Raft message metrics.
 		RaftRcvdMessages: [maxRaftMsgType + 1]*metric.Counter{
 			raftpb.MsgProp:           metric.NewCounter(metaRaftRcvdProp),
@@ -26,10 +26,11 @@ import (
 // MultiQueue.Cancel can be called to release the permit without waiting for the
 // permit.
 type Task struct {
-	priority  float64
-	queueType int
-	heapIdx   int
-	permitC   chan *Permit
+	precedence int
+	priority   float64
+	queueType  int
+	heapIdx    int
+	permitC    chan *Permit
 }
 
 // GetWaitChan returns a permit channel which is used to wait for the permit to
@@ -39,7 +40,8 @@ func (t *Task) GetWaitChan() <-chan *Permit {
 }
 
 func (t *Task) String() string {
-	return redact.Sprintf("{Queue type : %d, Priority :%f}", t.queueType, t.priority).StripMarkers()
+	return redact.Sprintf("{Queue type : %d, Precedence : %d, Priority :%f}",
+		t.queueType, t.precedence, t.priority).StripMarkers()
 }
 
 // notifyHeap is a standard go heap over tasks.
@@ -50,6 +52,9 @@ func (h notifyHeap) Len() int {
 }
 
 func (h notifyHeap) Less(i, j int) bool {
+	if h[i].precedence != h[j].precedence {
+		return h[j].precedence < h[i].precedence
+	}
 	return h[j].priority < h[i].priority
 }
 
@@ -89,7 +94,10 @@ func (h *notifyHeap) tryRemove(task *Task) bool {
 }
 
 // MultiQueue is a type that round-robins through a set of typed queues, each
-// independently prioritized. A MultiQueue is constructed with a concurrencySem
+// independently prioritized. Tasks with a higher precedence are run before
+// tasks with a lower precedence regardless of their queue; round-robin only
+// applies between queues whose next tasks share the highest precedence. A
+// MultiQueue is constructed with a concurrencySem
 // which is the number of concurrent jobs this queue will allow to run. Tasks
 // are added to the queue using MultiQueue.Add. That will return a channel that
 // should be received from. It will be notified when the waiting job is ready to
@@ -121,7 +129,8 @@ type Permit struct {
 }
 
 // tryRunNextLocked will run the next task in order round-robin through the
-// queues and in priority order within a queue.
+// queues holding tasks of the highest precedence, and in precedence and
+// priority order within a queue.
 // MultiQueue.mu lock must be held before calling this function.
 func (m *MultiQueue) tryRunNextLocked() {
 	// If no permits are left, then we can't run anything.
@@ -129,11 +138,25 @@ func (m *MultiQueue) tryRunNextLocked() {
 		return
 	}
 
+	// Determine the highest precedence of any waiting task. Each queue is a heap
+	// ordered by precedence first, so it is enough to look at the queue heads.
+	maxPrecedence, found := 0, false
+	for i := range m.outstanding {
+		if m.outstanding[i].Len() > 0 {
+			if p := m.outstanding[i][0].precedence; !found || p > maxPrecedence {
+				maxPrecedence, found = p, true
+			}
+		}
+	}
+	if !found {
+		return
+	}
+
 	for i := 0; i < len(m.outstanding); i++ {
-		// Start with the next queue in order and iterate through all empty queues.
-		// If all queues are empty then return, as there is nothing to run.
+		// Start with the next queue in order and iterate through all queues which
+		// are empty or only hold tasks of a lower precedence.
 		index := (m.lastQueueIndex + i + 1) % len(m.outstanding)
-		if m.outstanding[index].Len() > 0 {
+		if m.outstanding[index].Len() > 0 && m.outstanding[index][0].precedence == maxPrecedence {
 			task := heap.Pop(&m.outstanding[index]).(*Task)
 			task.permitC <- &Permit{valid: true}
 			m.remainingRuns--
@@ -147,6 +170,15 @@ func (m *MultiQueue) tryRunNextLocked() {
 // release the Permit. The number of types is expected to
 // be relatively small and not be changing over time.
 func (m *MultiQueue) Add(queueType int, priority float64, maxQueueLength int64) (*Task, error) {
+	return m.AddWithPrecedence(queueType, 0 /* precedence */, priority, maxQueueLength)
+}
+
+// AddWithPrecedence is like Add, but the returned Task is run ahead of all
+// waiting tasks with a lower precedence, across all queue types. Tasks added
+// through Add have a precedence of 0.
+func (m *MultiQueue) AddWithPrecedence(
+	queueType int, precedence int, priority float64, maxQueueLength int64,
+) (*Task, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -170,10 +202,11 @@ func (m *MultiQueue) Add(queueType int, priority float64, maxQueueLength int64)
 		m.outstanding = append(m.outstanding, notifyHeap{})
 	}
 	newTask := Task{
-		priority:  priority,
-		permitC:   make(chan *Permit, 1),
-		heapIdx:   -1,
-		queueType: queueType,
+		precedence: precedence,
+		priority:   priority,
+		permitC:    make(chan *Permit, 1),
+		heapIdx:    -1,
+		queueType:  queueType,
 	}
 	heap.Push(&m.outstanding[pos], &newTask)
 
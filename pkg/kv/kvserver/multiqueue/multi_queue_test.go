@@ -94,6 +94,29 @@ func TestMultiQueueComplex(t *testing.T) {
 	verifyOrder(t, queue, a3, b3, c3, a2, b2, c2, b1)
 }
 
+// TestMultiQueuePrecedence verifies that tasks with a higher precedence run
+// before all tasks with a lower precedence, regardless of their queue and
+// priority, and that round-robin applies between queues whose tasks share the
+// highest precedence.
+func TestMultiQueuePrecedence(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	queue := NewMultiQueue(1)
+	blocker, _ := queue.Add(0, 0, -1)
+
+	a1, _ := queue.Add(1, 5.0, -1)
+	a2, _ := queue.AddWithPrecedence(1, 1, 1.0, -1)
+	b1, _ := queue.Add(2, 6.0, -1)
+	b2, _ := queue.AddWithPrecedence(2, 1, 2.0, -1)
+	b3, _ := queue.AddWithPrecedence(2, 1, 3.0, -1)
+	c1, _ := queue.AddWithPrecedence(3, 2, 0.0, -1)
+
+	permit := <-blocker.GetWaitChan()
+	queue.Release(permit)
+	verifyOrder(t, queue, c1, a2, b3, b2, a1, b1)
+}
+
 func TestMultiQueueRemove(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -308,6 +308,14 @@ func newTruncateDecision(ctx context.Context, r *Replica) (truncateDecision, err
 		},
 	)
 	log.Eventf(ctx, "raft status after lastUpdateTimes check: %+v", raftStatus.Progress)
+	var fullVoters []roachpb.ReplicaID
+	if replicas := r.descRLocked().Replicas(); len(replicas.WitnessDescriptors()) > 0 {
+		for _, rDesc := range replicas.VoterDescriptors() {
+			if !rDesc.IsWitness() {
+				fullVoters = append(fullVoters, rDesc.ReplicaID)
+			}
+		}
+	}
 	r.mu.RUnlock()
 
 	input := truncateDecisionInput{
@@ -318,6 +326,7 @@ func newTruncateDecision(ctx context.Context, r *Replica) (truncateDecision, err
 		FirstIndex:           firstIndex,
 		LastIndex:            lastIndex,
 		PendingSnapshotIndex: pendingSnapshotIndex,
+		FullVoters:           fullVoters,
 	}
 
 	decision := computeTruncateDecision(input)
@@ -352,6 +361,7 @@ func updateRaftProgressFromActivity(
 const (
 	truncatableIndexChosenViaCommitIndex     = "commit"
 	truncatableIndexChosenViaFollowers       = "followers"
+	truncatableIndexChosenViaFullVoters      = "full voters"
 	truncatableIndexChosenViaProbingFollower = "probing follower"
 	truncatableIndexChosenViaPendingSnap     = "pending snapshot"
 	truncatableIndexChosenViaFirstIndex      = "first index"
@@ -375,6 +385,9 @@ type truncateDecisionInput struct {
 	LogSizeTrusted        bool // false when LogSize might be off
 	FirstIndex, LastIndex kvpb.RaftIndex
 	PendingSnapshotIndex  kvpb.RaftIndex
+	// FullVoters are the voters of the range that aren't witnesses. It is only
+	// set if the range has witnesses.
+	FullVoters []roachpb.ReplicaID
 }
 
 func (input truncateDecisionInput) LogTooLarge() bool {
@@ -560,6 +573,25 @@ func computeTruncateDecision(input truncateDecisionInput) truncateDecision {
 		// Otherwise, we let it truncate to the committed index.
 	}
 
+	// A witness counts toward the quorum that commits entries, but it can't
+	// send a snapshot to a full voter that has fallen behind. If the leader
+	// failed, a full voter that was cut off from the log could then only be
+	// caught up by the witness, and the range would be unavailable. So with
+	// witnesses, we never truncate past a full voter, regardless of its
+	// activity and of the size of the log. A full voter that stays down is
+	// replaced by the replicate queue, which unblocks truncation again.
+	for _, replicaID := range input.FullVoters {
+		progress, ok := input.RaftStatus.Progress[uint64(replicaID)]
+		if !ok {
+			continue
+		}
+		if progress.State == tracker.StateProbe {
+			decision.ProtectIndex(input.FirstIndex, truncatableIndexChosenViaFullVoters)
+		} else {
+			decision.ProtectIndex(kvpb.RaftIndex(progress.Match), truncatableIndexChosenViaFullVoters)
+		}
+	}
+
 	// The pending snapshot index acts as a placeholder for a replica that is
 	// about to be added to the range (or is in Raft recovery). We don't want to
 	// truncate the log in a way that will require that new replica to be caught
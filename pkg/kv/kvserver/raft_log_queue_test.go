@@ -294,6 +294,53 @@ func TestComputeTruncateDecisionProgressStatusProbe(t *testing.T) {
 	})
 }
 
+// TestComputeTruncateDecisionWitness verifies that when a range has witnesses,
+// the log is never truncated past a full voter, even if it isn't active and the
+// log is too large, while witnesses are treated like any other follower.
+func TestComputeTruncateDecisionWitness(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	status := raft.Status{
+		Progress: map[uint64]tracker.Progress{
+			// The leader.
+			1: {RecentActive: true, State: tracker.StateReplicate, Match: 500, Next: 501},
+			// A full voter that's down and fell behind.
+			2: {RecentActive: false, State: tracker.StateReplicate, Match: 200, Next: 201},
+			// A witness that's down and fell further behind.
+			3: {RecentActive: false, State: tracker.StateReplicate, Match: 50, Next: 51},
+		},
+	}
+	status.Commit = 500
+	input := truncateDecisionInput{
+		RaftStatus:     status,
+		LogSize:        2048,
+		MaxLogSize:     1024,
+		LogSizeTrusted: true,
+		FirstIndex:     10,
+		LastIndex:      500,
+	}
+
+	// Without witnesses, the log is too large and both followers are cut off.
+	decision := computeTruncateDecision(input)
+	require.Equal(t, "should truncate: true [truncate 490 entries to first index 500 (chosen via: last index); "+
+		"log too large (2.0 KiB > 1.0 KiB); implies 2 Raft snapshots]", decision.String())
+
+	// With a witness, the full voter is protected but the witness isn't.
+	input.FullVoters = []roachpb.ReplicaID{1, 2}
+	decision = computeTruncateDecision(input)
+	require.Equal(t, "should truncate: true [truncate 190 entries to first index 200 (chosen via: full voters); "+
+		"log too large (2.0 KiB > 1.0 KiB); implies 1 Raft snapshot]", decision.String())
+
+	// A full voter that is being probed isn't cut off either.
+	pr := input.RaftStatus.Progress[2]
+	pr.State = tracker.StateProbe
+	input.RaftStatus.Progress[2] = pr
+	decision = computeTruncateDecision(input)
+	require.Equal(t, "should truncate: false [truncate 0 entries to first index 10 (chosen via: full voters); "+
+		"log too large (2.0 KiB > 1.0 KiB)]", decision.String())
+}
+
 func TestTruncateDecisionZeroValue(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -0,0 +1,117 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package raftlog
+
+import (
+	"sync"
+
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
+	"github.com/cockroachdb/cockroach/pkg/util/grunning"
+	"github.com/cockroachdb/errors"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionConfig determines whether, and how, EncodeCommand compresses the
+// payload of a command.
+type CompressionConfig struct {
+	// Algorithm is the compression algorithm to use. Commands are not
+	// compressed if it is kvserverpb.RaftCommandCompressionNone.
+	Algorithm kvserverpb.RaftCommandCompression
+	// MinSize is the size of a marshaled command below which it is not
+	// compressed.
+	MinSize int
+}
+
+// CompressionStats describes the compression or decompression of the payload
+// of a command. It is zero if the payload was not compressed.
+type CompressionStats struct {
+	// UncompressedBytes and CompressedBytes are the size of the payload before
+	// and after compression.
+	UncompressedBytes, CompressedBytes int64
+	// CPUNanos is the CPU time spent compressing or decompressing the payload.
+	CPUNanos int64
+}
+
+// zstd encoders and decoders are safe for concurrent use with EncodeAll and
+// DecodeAll, and expensive to create, so they are shared.
+var zstdCodec struct {
+	once    sync.Once
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+	err     error
+}
+
+func getZstdCodec() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdCodec.once.Do(func() {
+		zstdCodec.encoder, zstdCodec.err = zstd.NewWriter(nil,
+			zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+		if zstdCodec.err != nil {
+			return
+		}
+		zstdCodec.decoder, zstdCodec.err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+	return zstdCodec.encoder, zstdCodec.decoder, zstdCodec.err
+}
+
+// compressPayload compresses the marshaled command b using the given
+// algorithm.
+func compressPayload(
+	algorithm kvserverpb.RaftCommandCompression, b []byte,
+) ([]byte, CompressionStats, error) {
+	start := grunning.Time()
+	var compressed []byte
+	switch algorithm {
+	case kvserverpb.RaftCommandCompressionSnappy:
+		compressed = snappy.Encode(nil, b)
+	case kvserverpb.RaftCommandCompressionZstd:
+		encoder, _, err := getZstdCodec()
+		if err != nil {
+			return nil, CompressionStats{}, err
+		}
+		compressed = encoder.EncodeAll(b, nil)
+	default:
+		return nil, CompressionStats{}, errors.AssertionFailedf("unknown raft command compression %d", algorithm)
+	}
+	return compressed, CompressionStats{
+		UncompressedBytes: int64(len(b)),
+		CompressedBytes:   int64(len(compressed)),
+		CPUNanos:          grunning.Elapsed(start, grunning.Time()).Nanoseconds(),
+	}, nil
+}
+
+// decompressPayload decompresses a payload compressed by compressPayload.
+func decompressPayload(
+	algorithm kvserverpb.RaftCommandCompression, b []byte,
+) ([]byte, CompressionStats, error) {
+	start := grunning.Time()
+	var decompressed []byte
+	var err error
+	switch algorithm {
+	case kvserverpb.RaftCommandCompressionSnappy:
+		decompressed, err = snappy.Decode(nil, b)
+	case kvserverpb.RaftCommandCompressionZstd:
+		var decoder *zstd.Decoder
+		if _, decoder, err = getZstdCodec(); err == nil {
+			decompressed, err = decoder.DecodeAll(b, nil)
+		}
+	default:
+		return nil, CompressionStats{}, errors.AssertionFailedf("unknown raft command compression %d", algorithm)
+	}
+	if err != nil {
+		return nil, CompressionStats{}, errors.Wrapf(err, "decompressing %s RaftCommand", algorithm)
+	}
+	return decompressed, CompressionStats{
+		UncompressedBytes: int64(len(decompressed)),
+		CompressedBytes:   int64(len(b)),
+		CPUNanos:          grunning.Elapsed(start, grunning.Time()).Nanoseconds(),
+	}, nil
+}
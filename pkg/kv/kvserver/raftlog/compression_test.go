@@ -0,0 +1,186 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package raftlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvflowcontrol/kvflowcontrolpb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/admission/admissionpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/raft/v3/raftpb"
+)
+
+// mkCompressibleRaftCommand returns a command whose write batch holds
+// repetitive JSON, as written by bulk writes of JSON-heavy rows.
+func mkCompressibleRaftCommand() *kvserverpb.RaftCommand {
+	return &kvserverpb.RaftCommand{
+		ProposerLeaseSequence: 7,
+		ReplicatedEvalResult: kvserverpb.ReplicatedEvalResult{
+			WriteTimestamp: hlc.Timestamp{WallTime: 18581258253},
+			RaftLogDelta:   1300,
+		},
+		WriteBatch: &kvserverpb.WriteBatch{
+			Data: bytes.Repeat([]byte(`{"name": "widget", "tags": ["a", "b"], "count": 12}`), 1000),
+		},
+	}
+}
+
+// appendFooter appends the footer fields to an encoded command, like the
+// proposal buffer does.
+func appendFooter(t *testing.T, data []byte, footer *kvserverpb.RaftCommandFooter) []byte {
+	b, err := protoutil.Marshal(footer)
+	require.NoError(t, err)
+	return append(data, b...)
+}
+
+func TestEncodeCommandCompression(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	footer := &kvserverpb.RaftCommandFooter{
+		MaxLeaseIndex:   1159192591,
+		ClosedTimestamp: hlc.Timestamp{WallTime: 12512591925, Logical: 1},
+	}
+	for _, algorithm := range []kvserverpb.RaftCommandCompression{
+		kvserverpb.RaftCommandCompressionSnappy,
+		kvserverpb.RaftCommandCompressionZstd,
+	} {
+		for _, withAC := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s,raft-ac=%t", algorithm, withAC), func(t *testing.T) {
+				expected := mkCompressibleRaftCommand()
+				expected.MaxLeaseIndex = footer.MaxLeaseIndex
+				expected.ClosedTimestamp = &footer.ClosedTimestamp
+				var raftAdmissionMeta *kvflowcontrolpb.RaftAdmissionMeta
+				expectedEnc := EntryEncodingStandardWithoutAC
+				if withAC {
+					raftAdmissionMeta = &kvflowcontrolpb.RaftAdmissionMeta{
+						AdmissionPriority:   int32(admissionpb.BulkNormalPri),
+						AdmissionCreateTime: 18581258253,
+						AdmissionOriginNode: roachpb.NodeID(3),
+					}
+					expected.AdmissionPriority = raftAdmissionMeta.AdmissionPriority
+					expected.AdmissionCreateTime = raftAdmissionMeta.AdmissionCreateTime
+					expected.AdmissionOriginNode = raftAdmissionMeta.AdmissionOriginNode
+					expectedEnc = EntryEncodingStandardWithAC
+				}
+
+				cmd := mkCompressibleRaftCommand()
+				data, stats, err := EncodeCommand(ctx, cmd, "deadbeef", raftAdmissionMeta,
+					CompressionConfig{Algorithm: algorithm, MinSize: 1 << 10})
+				require.NoError(t, err)
+				require.Less(t, stats.CompressedBytes, stats.UncompressedBytes)
+				require.Less(t, len(data), cmd.Size())
+				data = appendFooter(t, data, footer)
+
+				ent := raftpb.Entry{Term: 1, Index: 1, Type: raftpb.EntryNormal, Data: data}
+				require.True(t, IsCompressed(ent))
+				enc, err := EncodingOf(ent)
+				require.NoError(t, err)
+				require.Equal(t, expectedEnc, enc)
+				if withAC {
+					meta, err := DecodeRaftAdmissionMeta(data)
+					require.NoError(t, err)
+					require.Equal(t, *raftAdmissionMeta, meta)
+				}
+
+				e, err := NewEntry(ent)
+				require.NoError(t, err)
+				defer e.Release()
+				require.Equal(t, *expected, e.Cmd)
+				require.Equal(t, withAC, e.ApplyAdmissionControl)
+				require.Equal(t, stats.UncompressedBytes, e.Decompression.UncompressedBytes)
+				require.Equal(t, stats.CompressedBytes, e.Decompression.CompressedBytes)
+
+				// A corrupted payload fails to decode.
+				corrupted, err := protoutil.Marshal(&kvserverpb.RaftCommand{
+					CompressedCommand:  []byte("not compressed"),
+					CommandCompression: algorithm,
+				})
+				require.NoError(t, err)
+				corrupted = EncodeCommandBytes(expectedEnc, "deadbeef", corrupted)
+				corrupted[0] |= entryEncodingCompressedBit
+				_, err = NewEntry(raftpb.Entry{Term: 1, Index: 1, Type: raftpb.EntryNormal, Data: corrupted})
+				require.ErrorContains(t, err, "decompressing")
+			})
+		}
+	}
+}
+
+func TestEncodeCommandWithoutCompression(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	zstd := CompressionConfig{Algorithm: kvserverpb.RaftCommandCompressionZstd}
+	smallZstd := zstd
+	smallZstd.MinSize = 1 << 20
+	sideloaded := mkCompressibleRaftCommand()
+	sideloaded.ReplicatedEvalResult.AddSSTable = &kvserverpb.ReplicatedEvalResult_AddSSTable{
+		Data: bytes.Repeat([]byte("sst"), 10000),
+	}
+	for name, tc := range map[string]struct {
+		cmd         *kvserverpb.RaftCommand
+		compression CompressionConfig
+		expectedEnc EntryEncoding
+	}{
+		"disabled": {
+			cmd:         mkCompressibleRaftCommand(),
+			expectedEnc: EntryEncodingStandardWithoutAC,
+		},
+		"below min size": {
+			cmd:         mkCompressibleRaftCommand(),
+			compression: smallZstd,
+			expectedEnc: EntryEncodingStandardWithoutAC,
+		},
+		"incompressible": {
+			cmd:         mkRaftCommand(100, 1<<10, 64<<10),
+			compression: zstd,
+			expectedEnc: EntryEncodingStandardWithoutAC,
+		},
+		"sideloaded": {
+			cmd:         sideloaded,
+			compression: zstd,
+			expectedEnc: EntryEncodingSideloadedWithoutAC,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// The footer fields are only set when the command is proposed.
+			tc.cmd.MaxLeaseIndex = 0
+			tc.cmd.ClosedTimestamp = nil
+			data, stats, err := EncodeCommand(ctx, tc.cmd, "deadbeef", nil, tc.compression)
+			require.NoError(t, err)
+			require.Zero(t, stats)
+			require.Len(t, data, RaftCommandPrefixLen+tc.cmd.Size())
+
+			ent := raftpb.Entry{Term: 1, Index: 1, Type: raftpb.EntryNormal, Data: data}
+			require.False(t, IsCompressed(ent))
+			enc, err := EncodingOf(ent)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedEnc, enc)
+			e, err := NewEntry(ent)
+			require.NoError(t, err)
+			defer e.Release()
+			require.Equal(t, *tc.cmd, e.Cmd)
+			require.Zero(t, e.Decompression)
+		})
+	}
+}
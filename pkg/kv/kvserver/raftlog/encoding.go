@@ -120,6 +120,12 @@ const (
 	// raftpb.Entry's Data slice for an Entry of encoding
 	// EntryEncodingSideloadedWithoutAC.
 	entryEncodingSideloadedWithoutACPrefixByte = byte(1) // 0b00000001
+
+	// entryEncodingCompressedBit is set in the first byte of a raftpb.Entry's
+	// Data slice, on top of the prefix byte of its encoding, if the
+	// kvserverpb.RaftCommand's payload is compressed (see EncodeCommand). Only
+	// EntryEncodingStandardWith{,out}AC entries are compressed.
+	entryEncodingCompressedBit = byte(4) // 0b00000100
 )
 
 const (
@@ -157,7 +163,7 @@ func EncodeRaftCommandPrefix(b []byte, enc EntryEncoding, commandID kvserverbase
 // raftpb.Entry.Data. Expects an EntryEncoding{Standard,Sideloaded}WithAC
 // encoding.
 func DecodeRaftAdmissionMeta(data []byte) (kvflowcontrolpb.RaftAdmissionMeta, error) {
-	prefix := data[0]
+	prefix := data[0] &^ entryEncodingCompressedBit
 	if !(prefix == entryEncodingStandardWithACPrefixByte || prefix == entryEncodingSideloadedWithACPrefixByte) {
 		panic(fmt.Sprintf("invalid encoding: prefix %v", prefix))
 	}
@@ -45,7 +45,7 @@ func EncodingOf(ent raftpb.Entry) (EntryEncoding, error) {
 		return 0, errors.AssertionFailedf("unknown EntryType %d", ent.Type)
 	}
 
-	switch ent.Data[0] {
+	switch ent.Data[0] &^ entryEncodingCompressedBit {
 	case entryEncodingStandardWithACPrefixByte:
 		return EntryEncodingStandardWithAC, nil
 	case entryEncodingSideloadedWithACPrefixByte:
@@ -59,6 +59,14 @@ func EncodingOf(ent raftpb.Entry) (EntryEncoding, error) {
 	}
 }
 
+// IsCompressed returns true if the given Entry has an
+// EntryEncoding{Standard,Sideloaded}With{,out}AC encoding and its command's
+// payload is compressed.
+func IsCompressed(ent raftpb.Entry) bool {
+	return ent.Type == raftpb.EntryNormal && len(ent.Data) > 0 &&
+		ent.Data[0]&entryEncodingCompressedBit != 0
+}
+
 // DecomposeRaftEncodingStandardOrSideloaded extracts the CmdIDKey and the
 // marshaled kvserverpb.RaftCommand from a raftpb.Entry slice known to have
 // Entry with type EntryEncoding{Standard,Sideloaded}With{,out}AC.
@@ -81,6 +89,9 @@ type Entry struct {
 	// replication admission control. Only applies for entries with encoding
 	// EntryEncoding{Standard,Sideloaded}WithAC.
 	ApplyAdmissionControl bool
+	// Decompression describes the decompression of the command's payload, if
+	// it was compressed (see EncodeCommand).
+	Decompression CompressionStats
 }
 
 var entryPool = sync.Pool{
@@ -192,7 +203,34 @@ func (e *Entry) load() error {
 		return nil
 	}
 
-	return errors.Wrap(protoutil.Unmarshal(raftCmdBytes, &e.Cmd), "unmarshalling RaftCommand")
+	if err := protoutil.Unmarshal(raftCmdBytes, &e.Cmd); err != nil {
+		return errors.Wrap(err, "unmarshalling RaftCommand")
+	}
+	if IsCompressed(e.Entry) {
+		return e.decompress()
+	}
+	return nil
+}
+
+// decompress decompresses the payload of the command and merges it into the
+// fields which were encoded outside of it.
+func (e *Entry) decompress() error {
+	if len(e.Cmd.CompressedCommand) == 0 {
+		return errors.AssertionFailedf("compressed entry %d has no compressed RaftCommand", e.Index)
+	}
+	payload, stats, err := decompressPayload(e.Cmd.CommandCompression, e.Cmd.CompressedCommand)
+	if err != nil {
+		return err
+	}
+	e.Cmd.CompressedCommand = nil
+	e.Cmd.CommandCompression = kvserverpb.RaftCommandCompressionNone
+	// NB: unlike protoutil.Unmarshal, Unmarshal does not reset the message, but
+	// merges the payload into it.
+	if err := e.Cmd.Unmarshal(payload); err != nil {
+		return errors.Wrap(err, "unmarshalling compressed RaftCommand")
+	}
+	e.Decompression = stats
+	return nil
 }
 
 // ConfChange returns ConfChangeV1 or ConfChangeV2 as an interface, if set.
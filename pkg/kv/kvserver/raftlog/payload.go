@@ -25,12 +25,24 @@ import (
 )
 
 // EncodeCommand encodes the provided command into a slice.
+//
+// If requested by the CompressionConfig, the payload of the command is
+// compressed: the marshaled command, minus the fields which are encoded
+// separately (the below-raft admission control data, and the footer fields
+// appended by the proposal buffer), is stored compressed in the
+// CompressedCommand field of an otherwise empty kvserverpb.RaftCommand, and
+// the entryEncodingCompressedBit is set in the prefix. Decoding the entry (see
+// NewEntry) transparently decompresses the payload. Sideloaded commands are
+// never compressed since their bulk, the SST, is stored outside of the raft
+// log, and neither are configuration changes. The returned CompressionStats
+// are zero if the command was not compressed.
 func EncodeCommand(
 	ctx context.Context,
 	command *kvserverpb.RaftCommand,
 	idKey kvserverbase.CmdIDKey,
 	raftAdmissionMeta *kvflowcontrolpb.RaftAdmissionMeta,
-) ([]byte, error) {
+	compression CompressionConfig,
+) ([]byte, CompressionStats, error) {
 	// Determine the encoding style for the Raft command.
 	prefix := true
 	entryEncoding := EntryEncodingStandardWithoutAC
@@ -53,7 +65,7 @@ func EncodeCommand(
 
 		if command.ReplicatedEvalResult.AddSSTable.Data == nil &&
 			command.ReplicatedEvalResult.AddSSTable.RemoteFileLoc == "" {
-			return nil, errors.Errorf("cannot sideload empty SSTable")
+			return nil, CompressionStats{}, errors.Errorf("cannot sideload empty SSTable")
 		}
 	}
 
@@ -72,7 +84,39 @@ func EncodeCommand(
 		admissionMetaLen = raftAdmissionMeta.Size()
 	}
 
-	cmdLen := command.Size() + admissionMetaLen
+	// Compress the payload of the command, if requested. The admission data
+	// is encoded separately (see below), so it is left out of the payload.
+	body := command
+	var stats CompressionStats
+	if prefix && !entryEncoding.IsSideloaded() &&
+		compression.Algorithm != kvserverpb.RaftCommandCompressionNone &&
+		command.Size() >= compression.MinSize {
+		payload := *command
+		if raftAdmissionMeta != nil {
+			payload.AdmissionPriority = 0
+			payload.AdmissionCreateTime = 0
+			payload.AdmissionOriginNode = 0
+		}
+		b, err := protoutil.Marshal(&payload)
+		if err != nil {
+			return nil, CompressionStats{}, err
+		}
+		compressed, compressionStats, err := compressPayload(compression.Algorithm, b)
+		if err != nil {
+			return nil, CompressionStats{}, err
+		}
+		// Payloads which don't shrink, for example because they hold already
+		// compressed values, are left uncompressed.
+		if len(compressed) < len(b) {
+			body = &kvserverpb.RaftCommand{
+				CompressedCommand:  compressed,
+				CommandCompression: compression.Algorithm,
+			}
+			stats = compressionStats
+		}
+	}
+
+	cmdLen := body.Size() + admissionMetaLen
 	// Allocate the data slice with enough capacity to eventually hold the two
 	// "footers" that are filled later.
 	needed := preLen + cmdLen + kvserverpb.MaxRaftCommandFooterSize()
@@ -80,6 +124,9 @@ func EncodeCommand(
 	// Encode prefix with command ID, if necessary.
 	if prefix {
 		EncodeRaftCommandPrefix(data, entryEncoding, idKey)
+		if body != command {
+			data[0] |= entryEncodingCompressedBit
+		}
 	}
 
 	// Encode the body of the command.
@@ -87,18 +134,18 @@ func EncodeCommand(
 	// Encode below-raft admission data, if any.
 	if raftAdmissionMeta != nil {
 		if !prefix {
-			return nil, errors.AssertionFailedf("expected to encode prefix for raft commands using replication admission control")
+			return nil, CompressionStats{}, errors.AssertionFailedf("expected to encode prefix for raft commands using replication admission control")
 		}
 		if buildutil.CrdbTestBuild {
 			if raftAdmissionMeta.AdmissionOriginNode == roachpb.NodeID(0) {
-				return nil, errors.AssertionFailedf("missing origin node for flow token returns")
+				return nil, CompressionStats{}, errors.AssertionFailedf("missing origin node for flow token returns")
 			}
 		}
 		if _, err := protoutil.MarshalToSizedBuffer(
 			raftAdmissionMeta,
 			data[preLen:preLen+admissionMetaLen],
 		); err != nil {
-			return nil, err
+			return nil, CompressionStats{}, err
 		}
 		log.VInfof(ctx, 1, "encoded raft admission meta: pri=%s create-time=%d proposer=n%s",
 			admissionpb.WorkPriority(raftAdmissionMeta.AdmissionPriority),
@@ -113,8 +160,8 @@ func EncodeCommand(
 	}
 
 	// Encode the rest of the command.
-	if _, err := protoutil.MarshalToSizedBuffer(command, data[preLen+admissionMetaLen:]); err != nil {
-		return nil, err
+	if _, err := protoutil.MarshalToSizedBuffer(body, data[preLen+admissionMetaLen:]); err != nil {
+		return nil, CompressionStats{}, err
 	}
-	return data, nil
+	return data, stats, nil
 }
@@ -189,6 +189,22 @@ func (s *Store) logChange(
 			Reason:         reason,
 			Details:        details,
 		}
+	case roachpb.ADD_WITNESS:
+		logType = kvserverpb.RangeLogEventType_add_witness
+		info = kvserverpb.RangeLogEvent_Info{
+			AddedReplica: &replica,
+			UpdatedDesc:  &desc,
+			Reason:       reason,
+			Details:      details,
+		}
+	case roachpb.REMOVE_WITNESS:
+		logType = kvserverpb.RangeLogEventType_remove_witness
+		info = kvserverpb.RangeLogEvent_Info{
+			RemovedReplica: &replica,
+			UpdatedDesc:    &desc,
+			Reason:         reason,
+			Details:        details,
+		}
 	default:
 		return errors.Errorf("unknown replica change type %s", changeType)
 	}
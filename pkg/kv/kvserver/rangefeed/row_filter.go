@@ -0,0 +1,190 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package rangefeed
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/errors"
+)
+
+// RowPredicate evaluates the predicates of a kvpb.RangeFeedFilter against an
+// individual key-value pair. Implementations need not be safe for concurrent
+// use.
+type RowPredicate interface {
+	// Matches returns whether the row decoded from the given key-value pair
+	// satisfies the predicate.
+	Matches(ctx context.Context, kv roachpb.KeyValue) (bool, error)
+}
+
+// NewRowPredicate constructs a RowPredicate from a RangeFeedFilter with a
+// non-empty set of predicates. Decoding SQL rows requires knowledge of the SQL
+// encoding which this package cannot depend on, so the constructor is injected
+// by the sql/row package.
+var NewRowPredicate func(spec *kvpb.RangeFeedFilter) (RowPredicate, error)
+
+// filteredStream is a Stream which drops RangeFeedValue events that do not
+// match a RangeFeedFilter, and projects the values of the remaining ones,
+// before passing them to the wrapped Stream. Since all events of a
+// registration, both from its catch-up scan and from the Processor, are
+// delivered through its Stream, this applies the filter to both without
+// sending the discarded data over the network.
+type filteredStream struct {
+	Stream
+	familyIDs []uint32
+	// projection is nil if the filter has no projection.
+	projection *kvpb.RangeFeedFilterProjection
+
+	mu struct {
+		syncutil.Mutex
+		// pred is nil if the filter has no predicates.
+		pred RowPredicate
+	}
+}
+
+// NewFilteredStream returns a Stream which applies the given filter to the
+// events sent on the provided Stream. An error is returned if the filter is
+// invalid.
+func NewFilteredStream(stream Stream, spec *kvpb.RangeFeedFilter) (Stream, error) {
+	s := &filteredStream{Stream: stream, familyIDs: spec.FamilyIDs, projection: spec.Projection}
+	if len(spec.Predicates) > 0 {
+		if spec.IndexFetchSpec == nil {
+			return nil, errors.New("rangefeed filter with predicates must specify an index fetch spec")
+		}
+		if NewRowPredicate == nil {
+			return nil, errors.AssertionFailedf("rangefeed row predicates are not supported")
+		}
+		pred, err := NewRowPredicate(spec)
+		if err != nil {
+			return nil, errors.Wrap(err, "constructing rangefeed row predicate")
+		}
+		s.mu.pred = pred
+	}
+	return s, nil
+}
+
+// Send implements the Stream interface.
+func (s *filteredStream) Send(event *kvpb.RangeFeedEvent) error {
+	if event.Val != nil {
+		if !s.matches(event.Val) {
+			return nil
+		}
+		if s.projection != nil {
+			event = s.project(event)
+		}
+	}
+	return s.Stream.Send(event)
+}
+
+// project returns the given value event with its values projected. The event
+// may be shared with other registrations, so it is copied rather than modified
+// in place.
+func (s *filteredStream) project(event *kvpb.RangeFeedEvent) *kvpb.RangeFeedEvent {
+	v := *event.Val
+	var err error
+	if v.Value, err = projectValue(v.Key, v.Value, s.projection.ColumnIDs); err == nil {
+		v.PrevValue, err = projectValue(v.Key, v.PrevValue, s.projection.ColumnIDs)
+	}
+	if err != nil {
+		// Fail open, like for the predicates.
+		log.VEventf(s.Context(), 2, "error projecting rangefeed value of key %s: %v", v.Key, err)
+		return event
+	}
+	projected := *event
+	projected.Val = &v
+	return &projected
+}
+
+// projectValue returns the value stripped of all columns but the given ones,
+// which must be in ascending order, if the value is encoded as a tuple. Other
+// values are returned as is.
+func projectValue(
+	key roachpb.Key, value roachpb.Value, columnIDs []uint32,
+) (roachpb.Value, error) {
+	if value.GetTag() != roachpb.ValueType_TUPLE {
+		return value, nil
+	}
+	b, err := value.GetTuple()
+	if err != nil {
+		return roachpb.Value{}, err
+	}
+	var projected []byte
+	var colID, lastColID uint32
+	for len(b) > 0 {
+		_, dataOffset, colIDDelta, typ, err := encoding.DecodeValueTag(b)
+		if err != nil {
+			return roachpb.Value{}, err
+		}
+		n, err := encoding.PeekValueLengthWithOffsetsAndType(b, dataOffset, typ)
+		if err != nil {
+			return roachpb.Value{}, err
+		}
+		colID += colIDDelta
+		for len(columnIDs) > 0 && columnIDs[0] < colID {
+			columnIDs = columnIDs[1:]
+		}
+		if len(columnIDs) > 0 && columnIDs[0] == colID {
+			projected = encoding.EncodeValueTag(projected, colID-lastColID, typ)
+			projected = append(projected, b[dataOffset:n]...)
+			lastColID = colID
+		}
+		b = b[n:]
+	}
+	res := roachpb.Value{Timestamp: value.Timestamp}
+	res.SetTuple(projected)
+	res.InitChecksum(key)
+	return res, nil
+}
+
+// matches returns whether the value event should be emitted.
+func (s *filteredStream) matches(v *kvpb.RangeFeedValue) bool {
+	// Deletion tombstones are always emitted, since the deleted row cannot be
+	// evaluated against the filter.
+	if !v.Value.IsPresent() {
+		return true
+	}
+	if len(s.familyIDs) > 0 {
+		famID, err := keys.DecodeFamilyKey(v.Key)
+		if err == nil && !containsFamilyID(s.familyIDs, famID) {
+			return false
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mu.pred == nil {
+		return true
+	}
+	ctx := s.Context()
+	ok, err := s.mu.pred.Matches(ctx, roachpb.KeyValue{Key: v.Key, Value: v.Value})
+	if err != nil {
+		// Fail open: the client is expected to re-evaluate its predicate, so
+		// emitting an event that should have been filtered is harmless.
+		log.VEventf(ctx, 2, "error evaluating rangefeed filter on key %s: %v", v.Key, err)
+		return true
+	}
+	return ok
+}
+
+func containsFamilyID(familyIDs []uint32, id uint32) bool {
+	for _, f := range familyIDs {
+		if f == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package rangefeed
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/fetchpb"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// testRowPredicate matches values whose bytes equal "yes", and fails to
+// evaluate values whose bytes equal "err".
+type testRowPredicate struct{}
+
+func (testRowPredicate) Matches(_ context.Context, kv roachpb.KeyValue) (bool, error) {
+	b, err := kv.Value.GetBytes()
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(b, []byte("err")) {
+		return false, errors.New("boom")
+	}
+	return bytes.Equal(b, []byte("yes")), nil
+}
+
+func TestFilteredStream(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	defer func(prev func(*kvpb.RangeFeedFilter) (RowPredicate, error)) {
+		NewRowPredicate = prev
+	}(NewRowPredicate)
+	NewRowPredicate = func(*kvpb.RangeFeedFilter) (RowPredicate, error) {
+		return testRowPredicate{}, nil
+	}
+
+	rowKey := encoding.EncodeUvarintAscending(keys.SystemSQLCodec.IndexPrefix(100, 1), 1)
+	fam0, fam1 := roachpb.Key(keys.MakeFamilyKey(rowKey, 0)), roachpb.Key(keys.MakeFamilyKey(rowKey, 1))
+	val := func(s string) roachpb.Value {
+		v := roachpb.MakeValueFromBytes([]byte(s))
+		v.Timestamp = hlc.Timestamp{WallTime: 1}
+		return v
+	}
+	tombstone := roachpb.Value{Timestamp: hlc.Timestamp{WallTime: 1}}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewFilteredStream(newTestStream(), &kvpb.RangeFeedFilter{
+			Predicates: []kvpb.RangeFeedFilterPredicate{{Op: kvpb.RangeFeedFilterPredicate_IS_NULL}},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("families", func(t *testing.T) {
+		s := newTestStream()
+		fs, err := NewFilteredStream(s, &kvpb.RangeFeedFilter{FamilyIDs: []uint32{1}})
+		require.NoError(t, err)
+
+		events := []*kvpb.RangeFeedEvent{
+			rangeFeedValue(fam0, val("no")),
+			rangeFeedValue(fam1, val("no")),
+			rangeFeedValue(fam0, tombstone),
+			rangeFeedValue(keyA, val("no")),
+			rangeFeedCheckpoint(spAB, hlc.Timestamp{WallTime: 1}),
+		}
+		for _, e := range events {
+			require.NoError(t, fs.Send(e))
+		}
+		require.Equal(t, events[1:], s.Events())
+	})
+
+	t.Run("predicates", func(t *testing.T) {
+		s := newTestStream()
+		fs, err := NewFilteredStream(s, &kvpb.RangeFeedFilter{
+			FamilyIDs:      []uint32{0},
+			IndexFetchSpec: &fetchpb.IndexFetchSpec{},
+			Predicates:     []kvpb.RangeFeedFilterPredicate{{Op: kvpb.RangeFeedFilterPredicate_IS_NOT_NULL}},
+		})
+		require.NoError(t, err)
+
+		events := []*kvpb.RangeFeedEvent{
+			rangeFeedValue(fam0, val("yes")),
+			rangeFeedValue(fam0, val("no")),
+			rangeFeedValue(fam1, val("yes")),
+			rangeFeedValue(fam0, val("err")),
+			rangeFeedValue(fam0, tombstone),
+		}
+		for _, e := range events {
+			require.NoError(t, fs.Send(e))
+		}
+		require.Equal(t, []*kvpb.RangeFeedEvent{events[0], events[3], events[4]}, s.Events())
+	})
+	t.Run("projection", func(t *testing.T) {
+		s := newTestStream()
+		fs, err := NewFilteredStream(s, &kvpb.RangeFeedFilter{
+			FamilyIDs:  []uint32{0},
+			Projection: &kvpb.RangeFeedFilterProjection{ColumnIDs: []uint32{3, 4, 5}},
+		})
+		require.NoError(t, err)
+
+		tuple := func(key roachpb.Key, encode func([]byte) []byte) roachpb.Value {
+			var v roachpb.Value
+			v.SetTuple(encode(nil))
+			v.Timestamp = hlc.Timestamp{WallTime: 1}
+			v.InitChecksum(key)
+			return v
+		}
+		// The tuple stores columns 1, 3 and 4 of the family; the projection only
+		// retains the latter two.
+		full := tuple(fam0, func(b []byte) []byte {
+			b = encoding.EncodeIntValue(b, 1, 10)
+			b = encoding.EncodeBytesValue(b, 2, []byte("x"))
+			return encoding.EncodeIntValue(b, 1, 7)
+		})
+		projected := tuple(fam0, func(b []byte) []byte {
+			b = encoding.EncodeBytesValue(b, 3, []byte("x"))
+			return encoding.EncodeIntValue(b, 1, 7)
+		})
+		prev := tuple(fam0, func(b []byte) []byte {
+			return encoding.EncodeIntValue(b, 1, 9)
+		})
+		empty := tuple(fam0, func(b []byte) []byte { return b })
+
+		withPrev := rangeFeedValueWithPrev(fam0, full, prev)
+		notTuple := rangeFeedValue(fam0, val("x"))
+		for _, e := range []*kvpb.RangeFeedEvent{withPrev, notTuple} {
+			require.NoError(t, fs.Send(e))
+		}
+		require.Equal(t, []*kvpb.RangeFeedEvent{
+			rangeFeedValueWithPrev(fam0, projected, empty),
+			notTuple,
+		}, s.Events())
+		// The events passed to the stream may be shared by other registrations,
+		// so they must not be modified.
+		require.Equal(t, full, withPrev.Val.Value)
+	})
+}
@@ -68,9 +68,14 @@ func (d *replicaDecoder) DecodeAndBind(ctx context.Context, ents []raftpb.Entry)
 func (d *replicaDecoder) decode(ctx context.Context, ents []raftpb.Entry) error {
 	for i := range ents {
 		ent := &ents[i]
-		if err := d.cmdBuf.allocate().Decode(ent); err != nil {
+		cmd := d.cmdBuf.allocate()
+		if err := cmd.Decode(ent); err != nil {
 			return err
 		}
+		if stats := cmd.Decompression; stats.UncompressedBytes > 0 {
+			d.r.store.metrics.RaftCommandsDecompressed.Inc(1)
+			d.r.store.metrics.RaftCommandDecompressionCPUNanos.Inc(stats.CPUNanos)
+		}
 	}
 	return nil
 }
@@ -144,6 +144,7 @@ func (sm *replicaStateMachine) NewBatch() apply.Batch {
 	b.state.Stats = &sm.stats
 	*b.state.Stats = *r.mu.state.Stats
 	b.closedTimestampSetter = r.mu.closedTimestampSetter
+	b.ab.witness = r.isWitnessRLocked()
 	r.mu.RUnlock()
 	b.start = timeutil.Now()
 	return b
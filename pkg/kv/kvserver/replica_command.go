@@ -1032,6 +1032,13 @@ func (r *Replica) changeReplicasImpl(
 		return nil, errors.Mark(err, errMarkInvalidReplicationChange)
 	}
 	targets := SynthesizeTargetsByChangeType(chgs)
+	// Nodes running older versions crash on range descriptors with witnesses.
+	if len(targets.WitnessAdditions) > 0 &&
+		!r.store.ClusterSettings().Version.IsActive(ctx, clusterversion.V23_2_WitnessReplicas) {
+		return nil, errors.Mark(errors.Errorf(
+			"witnesses cannot be added until the cluster version is at least %s",
+			clusterversion.ByKey(clusterversion.V23_2_WitnessReplicas)), errMarkInvalidReplicationChange)
+	}
 
 	// NB: As of the time of this writing,`AdminRelocateRange` will only execute
 	// replication changes one by one. Thus, the order in which we execute the
@@ -1042,14 +1049,16 @@ func (r *Replica) changeReplicasImpl(
 	// 1. Promotions / demotions / swaps between voters and non-voters
 	// 2. Voter additions
 	// 3. Voter removals
-	// 4. Non-voter additions
-	// 5. Non-voter removals
+	// 4. Witness additions
+	// 5. Witness removals
+	// 6. Non-voter additions
+	// 7. Non-voter removals
 	//
 	// This order is meant to be symmetric with how the allocator prioritizes
 	// these actions. Broadly speaking, we first want to add a missing voter (and
 	// promoting an existing non-voter, or swapping with one, is the fastest way
-	// to do that). Then, we consider rebalancing/removing voters. Finally, we
-	// handle non-voter additions & removals.
+	// to do that). Then, we consider rebalancing/removing voters, followed by
+	// witnesses. Finally, we handle non-voter additions & removals.
 
 	// We perform promotions of non-voting replicas to voting replicas, and
 	// likewise, demotions of voting replicas to non-voting replicas. If both
@@ -1118,6 +1127,54 @@ func (r *Replica) changeReplicasImpl(
 		}
 	}
 
+	if adds := targets.WitnessAdditions; len(adds) > 0 {
+		// Witnesses are first added as LEARNERs, just like voters, and caught up
+		// via a snapshot that only contains the range's metadata. They are then
+		// promoted one by one. Unlike voter promotions, these don't require a
+		// joint config since we're only ever adding a single voter to the raft
+		// group.
+		desc, err = r.initializeRaftLearners(
+			ctx, desc, priority, senderName, senderQueuePriority, reason, details, adds, roachpb.WITNESS,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range adds {
+			iChgs := []internalReplicationChange{{target: target, typ: internalChangeTypePromoteLearnerToWitness}}
+			desc, err = execChangeReplicasTxn(ctx, r.store.cfg.Tracer(), desc, reason, details, iChgs,
+				changeReplicasTxnArgs{
+					db:                                   r.store.DB(),
+					liveAndDeadReplicas:                  r.store.cfg.StorePool.LiveAndDeadReplicas,
+					logChange:                            r.store.logChange,
+					testForceJointConfig:                 r.store.TestingKnobs().ReplicationAlwaysUseJointConfig,
+					testAllowDangerousReplicationChanges: r.store.TestingKnobs().AllowDangerousReplicationChanges,
+				})
+			if err != nil {
+				log.Infof(ctx, "could not promote %v to witness, rolling back: %v", target, err)
+				r.tryRollbackRaftLearner(ctx, r.Desc(), target, reason, details)
+				return nil, err
+			}
+		}
+	}
+
+	if removals := targets.WitnessRemovals; len(removals) > 0 {
+		for _, rem := range removals {
+			iChgs := []internalReplicationChange{{target: rem, typ: internalChangeTypeRemoveWitness}}
+			var err error
+			desc, err = execChangeReplicasTxn(ctx, r.store.cfg.Tracer(), desc, reason, details, iChgs,
+				changeReplicasTxnArgs{
+					db:                                   r.store.DB(),
+					liveAndDeadReplicas:                  r.store.cfg.StorePool.LiveAndDeadReplicas,
+					logChange:                            r.store.logChange,
+					testForceJointConfig:                 r.store.TestingKnobs().ReplicationAlwaysUseJointConfig,
+					testAllowDangerousReplicationChanges: r.store.TestingKnobs().AllowDangerousReplicationChanges,
+				})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if adds := targets.NonVoterAdditions; len(adds) > 0 {
 		// Add all non-voters and send them initial snapshots since some callers of
 		// `AdminChangeReplicas` (notably the mergeQueue, via `AdminRelocateRange`)
@@ -1171,6 +1228,7 @@ type TargetsForReplicationChanges struct {
 	VoterDemotions, NonVoterPromotions  []roachpb.ReplicationTarget
 	VoterAdditions, VoterRemovals       []roachpb.ReplicationTarget
 	NonVoterAdditions, NonVoterRemovals []roachpb.ReplicationTarget
+	WitnessAdditions, WitnessRemovals   []roachpb.ReplicationTarget
 }
 
 // SynthesizeTargetsByChangeType groups replication changes in the
@@ -1197,6 +1255,11 @@ func SynthesizeTargetsByChangeType(
 	result.NonVoterAdditions = subtractTargets(chgs.NonVoterAdditions(), chgs.VoterRemovals())
 	result.NonVoterRemovals = subtractTargets(chgs.NonVoterRemovals(), chgs.VoterAdditions())
 
+	// Witnesses can't be promoted or demoted, so their changes are always
+	// executed as plain additions and removals.
+	result.WitnessAdditions = chgs.WitnessAdditions()
+	result.WitnessRemovals = chgs.WitnessRemovals()
+
 	return result
 }
 
@@ -1489,6 +1552,11 @@ func validateRemovals(desc *roachpb.RangeDescriptor, chgsByStoreID changesByStor
 					return errors.AssertionFailedf("type of replica being removed (%s) does not match"+
 						" expectation for change: %+v", t, chg)
 				}
+			case roachpb.WITNESS:
+				if chg.ChangeType != roachpb.REMOVE_WITNESS {
+					return errors.AssertionFailedf("type of replica being removed (%s) does not match"+
+						" expectation for change: %+v", t, chg)
+				}
 			default:
 				return errors.AssertionFailedf("unexpected replica type for removal %+v: %s", chg, t)
 			}
@@ -1554,6 +1622,17 @@ func validatePromotionsAndDemotions(
 	return nil
 }
 
+// validateWitnessChanges ensures that witness additions and removals are
+// carried out on their own. Witnesses can't be promoted or demoted, and a
+// rebalance of a witness is executed as an addition followed by a removal.
+func validateWitnessChanges(chgs kvpb.ReplicationChanges) error {
+	numWitnessChanges := len(chgs.WitnessAdditions()) + len(chgs.WitnessRemovals())
+	if numWitnessChanges > 0 && len(chgs) > 1 {
+		return errors.AssertionFailedf("witness changes cannot be combined with other changes: %+v", chgs)
+	}
+	return nil
+}
+
 // validateOneReplicaPerNode ensures that there are no more than 2 changes for
 // any given node and if a node already has a replica, then adding a second
 // replica is prohibited unless the existing replica is being removed with it.
@@ -1634,10 +1713,15 @@ func validateOneReplicaPerNode(desc *roachpb.RangeDescriptor, chgsByNodeID chang
 // 5. We're not removing a replica that doesn't exist.
 // 6. Additions to stores that already contain a replica are strictly the ones
 // that correspond to a voter demotion and/or a non-voter promotion
+// 7. Witness additions and removals are not combined with any other change.
 func validateReplicationChanges(desc *roachpb.RangeDescriptor, chgs kvpb.ReplicationChanges) error {
 	chgsByStoreID := getChangesByStoreID(chgs)
 	chgsByNodeID := getChangesByNodeID(chgs)
 
+	if err := validateWitnessChanges(chgs); err != nil {
+		return err
+	}
+
 	if err := validateAdditionsPerStore(desc, chgsByStoreID); err != nil {
 		return err
 	}
@@ -1703,9 +1787,15 @@ func (r *Replica) initializeRaftLearners(
 	replicaType roachpb.ReplicaType,
 ) (afterDesc *roachpb.RangeDescriptor, err error) {
 	var iChangeType internalChangeType
+	// learnerType is the type of the replicas that we're adding and sending
+	// snapshots to. Witnesses are added as LEARNERs and promoted by the caller.
+	learnerType := replicaType
 	switch replicaType {
 	case roachpb.LEARNER:
 		iChangeType = internalChangeTypeAddLearner
+	case roachpb.WITNESS:
+		iChangeType = internalChangeTypeAddLearner
+		learnerType = roachpb.LEARNER
 	case roachpb.NON_VOTER:
 		iChangeType = internalChangeTypeAddNonVoter
 	default:
@@ -1819,7 +1909,7 @@ func (r *Replica) initializeRaftLearners(
 			return nil, errors.Errorf("programming error: replica %v not found in %v", target, desc)
 		}
 
-		if rDesc.Type != replicaType {
+		if rDesc.Type != learnerType {
 			return nil, errors.Errorf("programming error: cannot promote replica of type %s", rDesc.Type)
 		}
 
@@ -1845,6 +1935,7 @@ func (r *Replica) initializeRaftLearners(
 		// these, it would be susceptible to future similar issues.
 		if err := r.sendSnapshotUsingDelegate(
 			ctx, rDesc, kvserverpb.SnapshotRequest_INITIAL, priority, senderName, senderQueuePriority,
+			replicaType == roachpb.WITNESS, /* excludeUserData */
 		); err != nil {
 			return nil, err
 		}
@@ -2036,6 +2127,13 @@ const (
 	// https://github.com/cockroachdb/cockroach/pull/40268
 	internalChangeTypeRemoveLearner
 	internalChangeTypeRemoveNonVoter
+	// internalChangeTypePromoteLearnerToWitness promotes a learner to a witness.
+	// Since only a single voter is added to the raft group, this doesn't require
+	// joint consensus.
+	internalChangeTypePromoteLearnerToWitness
+	// internalChangeTypeRemoveWitness removes a witness outright, without first
+	// demoting it to a learner.
+	internalChangeTypeRemoveWitness
 )
 
 // internalReplicationChange is a replication target together with an internal
@@ -2111,6 +2209,25 @@ func prepareChangeReplicasTrigger(
 						chg.target)
 				}
 				added = append(added, rDesc)
+			case internalChangeTypePromoteLearnerToWitness:
+				// NB: witness promotions and removals are always carried out as
+				// simple changes, see internalChangeTypePromoteLearnerToWitness.
+				rDesc, prevTyp, ok := updatedDesc.SetReplicaType(chg.target.NodeID, chg.target.StoreID, roachpb.WITNESS)
+				if !ok || prevTyp != roachpb.LEARNER {
+					return nil, errors.Errorf("cannot promote target %v which is missing as LEARNER",
+						chg.target)
+				}
+				added = append(added, rDesc)
+			case internalChangeTypeRemoveWitness:
+				rDesc, ok := updatedDesc.GetReplicaDescriptor(chg.target.StoreID)
+				if !ok {
+					return nil, errors.Errorf("target %s not found", chg.target)
+				}
+				if prevTyp := rDesc.Type; prevTyp != roachpb.WITNESS {
+					return nil, errors.Errorf("cannot remove target %v of type %s as a witness", chg.target, prevTyp)
+				}
+				rDesc, _ = updatedDesc.RemoveReplica(chg.target.NodeID, chg.target.StoreID)
+				removed = append(removed, rDesc)
 			case internalChangeTypeRemoveLearner, internalChangeTypeRemoveNonVoter:
 				rDesc, ok := updatedDesc.GetReplicaDescriptor(chg.target.StoreID)
 				if !ok {
@@ -2517,16 +2634,21 @@ func recordRangeEventsInLog(
 ) error {
 	for _, repDesc := range repDescs {
 		isNonVoter := repDesc.Type == roachpb.NON_VOTER
+		isWitness := repDesc.Type == roachpb.WITNESS
 		var typ roachpb.ReplicaChangeType
 		if added {
 			typ = roachpb.ADD_VOTER
 			if isNonVoter {
 				typ = roachpb.ADD_NON_VOTER
+			} else if isWitness {
+				typ = roachpb.ADD_WITNESS
 			}
 		} else {
 			typ = roachpb.REMOVE_VOTER
 			if isNonVoter {
 				typ = roachpb.REMOVE_NON_VOTER
+			} else if isWitness {
+				typ = roachpb.REMOVE_WITNESS
 			}
 		}
 		if err := logChange(
@@ -2541,9 +2663,10 @@ func recordRangeEventsInLog(
 // getSenderReplicas returns an ordered list of replica descriptor for a
 // follower replica to act as the sender for delegated snapshots. The replicas
 // should be tried in order, and typically the coordinator is the last entry on
-// the list.
+// the list. A witness coordinator is never on the list unless the snapshot
+// excludes user data.
 func (r *Replica) getSenderReplicas(
-	ctx context.Context, recipient roachpb.ReplicaDescriptor,
+	ctx context.Context, recipient roachpb.ReplicaDescriptor, excludeUserData bool,
 ) ([]roachpb.ReplicaDescriptor, error) {
 
 	coordinator, err := r.GetReplicaDescriptor()
@@ -2552,16 +2675,24 @@ func (r *Replica) getSenderReplicas(
 		return nil, err
 	}
 
+	// A witness holds no user data, so when a witness is the Raft leader it has
+	// to delegate snapshots which include it. Witnesses only exist once
+	// V23_2_WitnessReplicas is active, which implies V23_1.
+	canSelfDelegate := excludeUserData || !coordinator.IsWitness()
+
 	// Unless all nodes are on V23.1, don't delegate. This prevents sending to a
 	// node that doesn't understand the request.
-	if !r.store.ClusterSettings().Version.IsActive(ctx, clusterversion.V23_1) {
+	if canSelfDelegate && !r.store.ClusterSettings().Version.IsActive(ctx, clusterversion.V23_1) {
 		return []roachpb.ReplicaDescriptor{coordinator}, nil
 	}
 
 	// Check follower snapshots, if zero just self-delegate.
 	numFollowers := int(NumDelegateLimit.Get(&r.ClusterSettings().SV))
 	if numFollowers == 0 {
-		return []roachpb.ReplicaDescriptor{coordinator}, nil
+		if canSelfDelegate {
+			return []roachpb.ReplicaDescriptor{coordinator}, nil
+		}
+		numFollowers = 1
 	}
 
 	// Get range descriptor and store pool.
@@ -2583,6 +2714,9 @@ func (r *Replica) getSenderReplicas(
 		},
 	)
 	candidates := nonRecipientReplicas.VoterAndNonVoterDescriptors()
+	if len(candidates) == 0 && !canSelfDelegate {
+		return nil, errors.Errorf("no replica with user data to send a snapshot to %s", recipient)
+	}
 	if len(candidates) == 0 {
 		// Not clear when the coordinator would be considered dead, but if it does
 		// happen, just return the coordinator.
@@ -2634,17 +2768,19 @@ func (r *Replica) getSenderReplicas(
 
 	// Convert to replica descriptors before returning. The list of tiedReplicas
 	// is typically only one element.
-	replicaList := make([]roachpb.ReplicaDescriptor, len(tiedReplicas)+1)
-	for n, replicaId := range tiedReplicas {
+	replicaList := make([]roachpb.ReplicaDescriptor, 0, len(tiedReplicas)+1)
+	for _, replicaId := range tiedReplicas {
 		found := false
 		replDesc, found := rangeDesc.Replicas().GetReplicaDescriptorByID(replicaId)
 		if !found {
 			return nil, errors.Errorf("unable to find replica for replicaId %d", replicaId)
 		}
-		replicaList[n] = replDesc
+		replicaList = append(replicaList, replDesc)
 	}
 	// Set the last replica to be the coordinator.
-	replicaList[len(replicaList)-1] = coordinator
+	if canSelfDelegate {
+		replicaList = append(replicaList, coordinator)
+	}
 	return replicaList, nil
 }
 
@@ -2777,6 +2913,7 @@ func (r *Replica) sendSnapshotUsingDelegate(
 	priority kvserverpb.SnapshotRequest_Priority,
 	senderQueueName kvserverpb.SnapshotRequest_QueueName,
 	senderQueuePriority float64,
+	excludeUserData bool,
 ) (retErr error) {
 
 	defer func() {
@@ -2794,6 +2931,14 @@ func (r *Replica) sendSnapshotUsingDelegate(
 		return err
 	}
 
+	// Receivers which predate witnesses would ignore ExcludeUserData and apply
+	// the snapshot as though it contained the range's data.
+	if excludeUserData &&
+		!r.store.ClusterSettings().Version.IsActive(ctx, clusterversion.V23_2_WitnessReplicas) {
+		return errors.AssertionFailedf("cannot send a snapshot without user data to %s before %s",
+			recipient, clusterversion.ByKey(clusterversion.V23_2_WitnessReplicas))
+	}
+
 	if destPaused {
 		// If the destination is paused, be more hesitant to send snapshots. The destination being
 		// paused implies that we have recently checked that it's not required for quorum, and that
@@ -2841,10 +2986,11 @@ func (r *Replica) sendSnapshotUsingDelegate(
 		DescriptorGeneration: r.Desc().Generation,
 		QueueOnDelegateLen:   MaxQueueOnDelegateLimit.Get(&r.ClusterSettings().SV),
 		SnapId:               snapUUID,
+		ExcludeUserData:      excludeUserData,
 	}
 
 	// Get the list of senders in order.
-	senders, err := r.getSenderReplicas(ctx, recipient)
+	senders, err := r.getSenderReplicas(ctx, recipient, excludeUserData)
 	if err != nil {
 		return err
 	}
@@ -3116,6 +3262,13 @@ func (r *Replica) followerSendSnapshot(
 		return nil, err
 	}
 
+	// A witness doesn't store user data and so can't be the source of a
+	// snapshot for a replica that does.
+	if !req.ExcludeUserData && r.isWitness() {
+		return nil, errors.Errorf("%s: witness cannot send a snapshot to non-witness %s",
+			r, req.RecipientReplica)
+	}
+
 	snapType := req.Type
 	snap, err := r.GetSnapshot(ctx, snapType, req.SnapId)
 	if err != nil {
@@ -3142,7 +3295,8 @@ func (r *Replica) followerSendSnapshot(
 	// a snapshot for a non-system range. This allows us to send metadata of
 	// sstables in shared storage as opposed to streaming their contents. Keys
 	// in higher levels of the LSM are still streamed in the snapshot.
-	sharedReplicate := r.store.cfg.SharedStorageEnabled && snap.State.Desc.StartKey.AsRawKey().Compare(keys.TableDataMin) >= 0
+	sharedReplicate := r.store.cfg.SharedStorageEnabled && snap.State.Desc.StartKey.AsRawKey().Compare(keys.TableDataMin) >= 0 &&
+		!req.ExcludeUserData
 
 	// Create new snapshot request header using the delegate snapshot request.
 	header := kvserverpb.SnapshotRequest_Header{
@@ -3167,6 +3321,7 @@ func (r *Replica) followerSendSnapshot(
 		Strategy:            kvserverpb.SnapshotRequest_KV_BATCH,
 		Type:                req.Type,
 		SharedReplicate:     sharedReplicate,
+		ExcludeUserData:     req.ExcludeUserData,
 	}
 	newBatchFn := func() storage.WriteBatch {
 		return r.store.TODOEngine().NewWriteBatch()
@@ -3177,6 +3332,14 @@ func (r *Replica) followerSendSnapshot(
 	comparisonResult := r.store.getLocalityComparison(ctx, req.CoordinatorReplica.NodeID,
 		req.RecipientReplica.NodeID)
 
+	// Pace the snapshot according to the bandwidth budget of the recipient's
+	// locality, which is shared with all other snapshots sent into it.
+	bandwidth := r.store.cfg.SnapshotScheduler.admit(
+		header.Priority,
+		r.store.cfg.StorePool.GetNodeLocality(req.RecipientReplica.NodeID),
+	)
+	defer bandwidth.release()
+
 	recordBytesSent := func(inc int64) {
 		// Only counts for delegated bytes if we are not self-delegating.
 		if r.NodeID() != req.CoordinatorReplica.NodeID {
@@ -3208,6 +3371,7 @@ func (r *Replica) followerSendSnapshot(
 				newBatchFn,
 				sent,
 				recordBytesSent,
+				bandwidth,
 			)
 			if err != nil {
 				return err
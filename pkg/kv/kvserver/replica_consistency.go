@@ -303,7 +303,11 @@ func (r *Replica) runConsistencyCheck(
 	}
 	ccRes := res.(*kvpb.ComputeChecksumResponse)
 
-	replicas := r.Desc().Replicas().Descriptors()
+	// Witnesses don't store user data, so there is nothing to compare them
+	// against.
+	replicas := r.Desc().Replicas().Filter(func(rDesc roachpb.ReplicaDescriptor) bool {
+		return !rDesc.IsWitness()
+	}).Descriptors()
 	resultCh := make(chan ConsistencyCheckResult, len(replicas))
 	results := make([]ConsistencyCheckResult, 0, len(replicas))
 
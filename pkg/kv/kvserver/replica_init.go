@@ -106,20 +106,21 @@ func newUninitializedReplicaWithoutRaftGroup(
 		creationTime:   timeutil.Now(),
 		store:          store,
 		abortSpan:      abortspan.New(rangeID),
-		concMgr: concurrency.NewManager(concurrency.Config{
-			NodeDesc:          store.nodeDesc,
-			RangeDesc:         uninitState.Desc,
-			Settings:          store.ClusterSettings(),
-			DB:                store.DB(),
-			Clock:             store.Clock(),
-			Stopper:           store.Stopper(),
-			IntentResolver:    store.intentResolver,
-			TxnWaitMetrics:    store.txnWaitMetrics,
-			SlowLatchGauge:    store.metrics.SlowLatchRequests,
-			DisableTxnPushing: store.TestingKnobs().DontPushOnLockConflictError,
-			TxnWaitKnobs:      store.TestingKnobs().TxnWaitKnobs,
-		}),
 	}
+	r.concMgr = concurrency.NewManager(concurrency.Config{
+		NodeDesc:          store.nodeDesc,
+		RangeDesc:         uninitState.Desc,
+		Settings:          store.ClusterSettings(),
+		DB:                store.DB(),
+		Clock:             store.Clock(),
+		Stopper:           store.Stopper(),
+		IntentResolver:    store.intentResolver,
+		TxnWaitMetrics:    store.txnWaitMetrics,
+		SlowLatchGauge:    store.metrics.SlowLatchRequests,
+		OnContentionEvent: r.recordContentionForHotKeyDetection,
+		DisableTxnPushing: store.TestingKnobs().DontPushOnLockConflictError,
+		TxnWaitKnobs:      store.TestingKnobs().TxnWaitKnobs,
+	})
 	r.sideTransportClosedTimestamp.init(store.cfg.ClosedTimestampReceiver, rangeID)
 
 	r.mu.pendingLeaseRequest = makePendingLeaseRequest(r)
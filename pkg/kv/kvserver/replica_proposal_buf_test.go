@@ -335,7 +335,8 @@ func (pc proposalCreator) newProposal(ba *kvpb.BatchRequest) *ProposalData {
 }
 
 func (pc proposalCreator) encodeProposal(p *ProposalData) []byte {
-	b, err := raftlog.EncodeCommand(context.Background(), p.command, p.idKey, nil /* raftAdmissionMeta */)
+	b, _, err := raftlog.EncodeCommand(context.Background(), p.command, p.idKey,
+		nil /* raftAdmissionMeta */, raftlog.CompressionConfig{})
 	if err != nil {
 		panic(err)
 	}
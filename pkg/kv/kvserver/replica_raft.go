@@ -33,6 +33,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/stateloader"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/uncertainty"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/util"
@@ -77,6 +78,45 @@ var (
 		"COCKROACH_DISABLE_LEADER_FOLLOWS_LEASEHOLDER", false)
 )
 
+// raftCommandCompression is the algorithm the payload of proposed raft
+// commands is compressed with, if any.
+var raftCommandCompression = settings.RegisterEnumSetting(
+	settings.SystemOnly,
+	"kv.raft.command.compression",
+	"compression algorithm used for the payload of raft commands larger than "+
+		"kv.raft.command.compression.min_size in the raft log, or `none` to not compress them",
+	"none",
+	map[int64]string{
+		int64(kvserverpb.RaftCommandCompressionNone):   "none",
+		int64(kvserverpb.RaftCommandCompressionSnappy): "snappy",
+		int64(kvserverpb.RaftCommandCompressionZstd):   "zstd",
+	},
+)
+
+// raftCommandCompressionMinSize wraps "kv.raft.command.compression.min_size".
+var raftCommandCompressionMinSize = settings.RegisterByteSizeSetting(
+	settings.SystemOnly,
+	"kv.raft.command.compression.min_size",
+	"size of a marshaled raft command below which it is not compressed",
+	16<<10, // 16 KiB
+	settings.NonNegativeInt,
+)
+
+// raftCommandCompressionConfig returns the configuration with which proposed
+// raft commands are compressed. Compression is disabled until all nodes are
+// able to decode compressed commands.
+func raftCommandCompressionConfig(ctx context.Context, st *cluster.Settings) raftlog.CompressionConfig {
+	algorithm := kvserverpb.RaftCommandCompression(raftCommandCompression.Get(&st.SV))
+	if algorithm == kvserverpb.RaftCommandCompressionNone ||
+		!st.Version.IsActive(ctx, clusterversion.V23_2_RaftCommandCompression) {
+		return raftlog.CompressionConfig{}
+	}
+	return raftlog.CompressionConfig{
+		Algorithm: algorithm,
+		MinSize:   int(raftCommandCompressionMinSize.Get(&st.SV)),
+	}
+}
+
 // evalAndPropose prepares the necessary pending command struct and initializes
 // a client command ID if one hasn't been. A verified lease is supplied as a
 // parameter if the command requires a lease; nil otherwise. It then evaluates
@@ -393,11 +433,18 @@ func (r *Replica) propose(
 	if !p.useReplicationAdmissionControl() {
 		raftAdmissionMeta = nil
 	}
-	data, err := raftlog.EncodeCommand(ctx, p.command, p.idKey, raftAdmissionMeta)
+	data, compressionStats, err := raftlog.EncodeCommand(ctx, p.command, p.idKey, raftAdmissionMeta,
+		raftCommandCompressionConfig(ctx, r.store.cfg.Settings))
 	if err != nil {
 		return kvpb.NewError(err)
 	}
 	p.encodedCommand = data
+	if compressionStats.UncompressedBytes > 0 {
+		r.store.metrics.RaftCommandsCompressed.Inc(1)
+		r.store.metrics.RaftCommandCompressionBytesIn.Inc(compressionStats.UncompressedBytes)
+		r.store.metrics.RaftCommandCompressionBytesOut.Inc(compressionStats.CompressedBytes)
+		r.store.metrics.RaftCommandCompressionCPUNanos.Inc(compressionStats.CPUNanos)
+	}
 
 	// Too verbose even for verbose logging, so manually enable if you want to
 	// debug proposal sizes.
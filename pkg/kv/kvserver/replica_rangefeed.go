@@ -205,7 +205,14 @@ func (r *Replica) RangeFeed(
 		checkTS = r.Clock().Now()
 	}
 
-	lockedStream := &lockedRangefeedStream{wrapped: stream}
+	var regStream rangefeed.Stream = &lockedRangefeedStream{wrapped: stream}
+	if args.Filter != nil {
+		// Apply the filter before events are sent over the network, both for
+		// the catch-up scan and for events published by the processor.
+		if regStream, err = rangefeed.NewFilteredStream(regStream, args.Filter); err != nil {
+			return future.MakeCompletedErrorFuture(err)
+		}
+	}
 
 	// If we will be using a catch-up iterator, wait for the limiter here before
 	// locking raftMu.
@@ -258,7 +265,7 @@ func (r *Replica) RangeFeed(
 	}
 	var done future.ErrorFuture
 	p := r.registerWithRangefeedRaftMuLocked(
-		ctx, rSpan, args.Timestamp, catchUpIterFunc, args.WithDiff, lockedStream, &done,
+		ctx, rSpan, args.Timestamp, catchUpIterFunc, args.WithDiff, regStream, &done,
 	)
 	r.raftMu.Unlock()
 
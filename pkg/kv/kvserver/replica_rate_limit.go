@@ -13,15 +13,25 @@ package kvserver
 import (
 	"context"
 
+	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/tablerate"
 	"github.com/cockroachdb/cockroach/pkg/multitenant/tenantcostmodel"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 )
 
-// maybeRateLimitBatch may block the batch waiting to be rate-limited. Note that
-// the replica must be initialized and thus there is no synchronization issue
-// on the tenantRateLimiter.
+// maybeRateLimitBatch may block the batch waiting to be rate-limited, first by
+// the tenant rate limiter and then by the table rate limiter. Note that the
+// replica must be initialized and thus there is no synchronization issue on
+// the tenantRateLimiter.
 func (r *Replica) maybeRateLimitBatch(ctx context.Context, ba *kvpb.BatchRequest) error {
+	if err := r.maybeTenantRateLimitBatch(ctx, ba); err != nil {
+		return err
+	}
+	return r.maybeTableRateLimitBatch(ctx, ba)
+}
+
+func (r *Replica) maybeTenantRateLimitBatch(ctx context.Context, ba *kvpb.BatchRequest) error {
 	if r.tenantLimiter == nil {
 		return nil
 	}
@@ -33,6 +43,50 @@ func (r *Replica) maybeRateLimitBatch(ctx context.Context, ba *kvpb.BatchRequest
 	return r.tenantLimiter.Wait(ctx, tenantcostmodel.MakeRequestInfo(ba, 1, 1))
 }
 
+// maybeTableRateLimitBatch blocks write batches to a table with write rate
+// limits configured until they're admitted by the table's limiter.
+func (r *Replica) maybeTableRateLimitBatch(ctx context.Context, ba *kvpb.BatchRequest) error {
+	if !ba.IsWrite() {
+		return nil
+	}
+	lim := r.tableRateLimiter(ctx)
+	if lim == nil {
+		return nil
+	}
+	// writeMultiplier isn't needed here since it's only used to calculate RUs.
+	reqInfo := tenantcostmodel.MakeRequestInfo(ba, 1, 1)
+	if !reqInfo.IsWrite() {
+		return nil
+	}
+	return lim.Wait(ctx, reqInfo.WriteCount(), reqInfo.WriteBytes())
+}
+
+// tableRateLimiter returns the limiter for the table the range belongs to, or
+// nil if the range isn't subject to table write rate limits. The system
+// tables are never rate limited.
+func (r *Replica) tableRateLimiter(ctx context.Context) *tablerate.Limiter {
+	r.mu.RLock()
+	limits := tablerate.Limits{
+		WriteBytesPerSecond:    r.mu.conf.MaxWriteBytesPerSecond,
+		WriteRequestsPerSecond: r.mu.conf.MaxWriteRequestsPerSecond,
+	}
+	startKey := r.mu.state.Desc.StartKey
+	tenantID := r.mu.tenantID
+	r.mu.RUnlock()
+	if limits.IsUnlimited() || !tenantID.IsSet() {
+		return nil
+	}
+	_, tableID, err := keys.MakeSQLCodec(tenantID).DecodeTablePrefix(startKey.AsRawKey())
+	if err != nil || tableID <= keys.MaxReservedDescID {
+		return nil
+	}
+	return r.store.tableRateLimiters.GetLimiter(ctx, tablerate.Key{
+		TenantID: tenantID,
+		TableID:  tableID,
+		Limits:   limits,
+	}, r.store.stopper.ShouldQuiesce())
+}
+
 // recordImpactOnRateLimiter is used to record a read against the tenant rate
 // limiter.
 func (r *Replica) recordImpactOnRateLimiter(
@@ -241,12 +241,75 @@ func (r *Replica) recordBatchForLoadBasedSplitting(
 		return getResponseBoundarySpan(ba, br)
 	}
 
-	shouldInitSplit := r.loadBasedSplitter.Record(ctx, r.Clock().PhysicalTime(), loadFn, spanFn)
+	keysFn := func() []split.KeyAccess {
+		return getKeyAccesses(ba)
+	}
+
+	shouldInitSplit := r.loadBasedSplitter.RecordWithKeys(
+		ctx, r.Clock().PhysicalTime(), loadFn, spanFn, keysFn)
 	if shouldInitSplit {
 		r.store.splitQueue.MaybeAddAsync(ctx, r, r.store.Clock().NowAsClockTimestamp())
 	}
 }
 
+// getKeyAccesses returns the keys read and written by the point requests in the
+// batch, to be considered for hot key detection. Ranged requests are only
+// considered if they scan a single key or SQL row, as SQL does for point
+// lookups of rows with multiple column families.
+func getKeyAccesses(ba *kvpb.BatchRequest) []split.KeyAccess {
+	accesses := make([]split.KeyAccess, 0, len(ba.Requests))
+	for _, union := range ba.Requests {
+		req := union.GetInner()
+		if !kvpb.IsTransactional(req) {
+			continue
+		}
+		typ := split.KeyRead
+		if kvpb.IsIntentWrite(req) {
+			typ = split.KeyWrite
+		} else if !kvpb.IsReadOnly(req) {
+			continue
+		}
+		h := req.Header()
+		key := h.Key
+		if len(h.EndKey) != 0 {
+			if !h.EndKey.Equal(h.Key.PrefixEnd()) {
+				continue
+			}
+		} else {
+			key = hotKeyOf(key)
+		}
+		accesses = append(accesses, split.KeyAccess{Key: key, Type: typ})
+	}
+	return accesses
+}
+
+// hotKeyOf returns the key under which accesses to the given key are tracked
+// for hot key detection. Keys of SQL rows are shortened to the row prefix, so
+// that accesses to the row's column families are tracked together.
+func hotKeyOf(key roachpb.Key) roachpb.Key {
+	if rowPrefix, err := keys.EnsureSafeSplitKey(key); err == nil {
+		return rowPrefix
+	}
+	return key
+}
+
+// recordContentionForHotKeyDetection records the key of a conflicting lock
+// that a request waited on, to be considered for hot key detection.
+func (r *Replica) recordContentionForHotKeyDetection(ev *kvpb.ContentionEvent) {
+	if !r.SplitByLoadEnabled() {
+		return
+	}
+	r.loadBasedSplitter.RecordContention(func() []roachpb.Key {
+		return []roachpb.Key{hotKeyOf(ev.Key)}
+	})
+}
+
+// HotKeys returns the keys which receive a large fraction of the load on the
+// range, if the range is hot enough to be considered for load based splitting.
+func (r *Replica) HotKeys(ctx context.Context) []split.HotKey {
+	return r.loadBasedSplitter.HotKeys(ctx, r.Clock().PhysicalTime())
+}
+
 // loadSplitKey returns a suggested load split key for the range if it exists,
 // otherwise it returns nil. If there were any errors encountered when
 // validating the split key, the error is returned as well. It is guaranteed
@@ -16,7 +16,9 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/split"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -358,3 +360,36 @@ func TestSplitPreCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestGetKeyAccesses(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	row := func(pk int64) roachpb.Key {
+		return encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), pk)
+	}
+	family := func(pk int64, famID uint32) roachpb.Key {
+		return keys.MakeFamilyKey(row(pk), famID)
+	}
+
+	ba := &kvpb.BatchRequest{}
+	// A point read and a point write of column families are attributed to
+	// their row.
+	ba.Add(kvpb.NewGet(family(1, 0), false /* forUpdate */))
+	ba.Add(kvpb.NewPut(family(2, 1), roachpb.MakeValueFromString("v")))
+	// A scan of a single row is attributed to the row, a scan of several rows
+	// is ignored.
+	ba.Add(kvpb.NewScan(row(3), row(3).PrefixEnd(), false /* forUpdate */))
+	ba.Add(kvpb.NewScan(row(4), row(6), false /* forUpdate */))
+	// Keys outside of SQL tables are not shortened.
+	ba.Add(kvpb.NewGet(roachpb.Key("a"), false /* forUpdate */))
+	// Requests that neither read nor write user data are ignored.
+	ba.Add(&kvpb.EndTxnRequest{RequestHeader: kvpb.RequestHeader{Key: roachpb.Key("b")}})
+	ba.Add(&kvpb.LeaseInfoRequest{RequestHeader: kvpb.RequestHeader{Key: roachpb.Key("c")}})
+
+	require.Equal(t, []split.KeyAccess{
+		{Key: row(1), Type: split.KeyRead},
+		{Key: row(2), Type: split.KeyWrite},
+		{Key: row(3), Type: split.KeyRead},
+		{Key: roachpb.Key("a"), Type: split.KeyRead},
+	}, getKeyAccesses(ba))
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package kvserver
+
+import (
+	"encoding/binary"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble"
+)
+
+// isWitnessRLocked returns whether the replica is a witness according to its
+// current range descriptor.
+//
+// Witnesses participate in raft elections and the log quorum but only apply
+// the local (range-local and RangeID-local) portion of committed commands,
+// which is all that's needed to track the range's metadata (descriptor, lease,
+// applied state, transaction records, etc). They never hold the lease, serve
+// reads, or act as the source of a snapshot for a non-witness.
+func (r *Replica) isWitnessRLocked() bool {
+	repDesc, err := r.getReplicaDescriptorRLocked()
+	return err == nil && repDesc.IsWitness()
+}
+
+// isWitness is like isWitnessRLocked, but acquires the replica mutex.
+func (r *Replica) isWitness() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.isWitnessRLocked()
+}
+
+// witnessWriteBatchRepr returns the portion of the given WriteBatch
+// representation that is applied by a witness replica: all writes to local
+// keys. Writes to the user keyspace are dropped and ranged operations that
+// extend into it are truncated at keys.LocalMax. If the batch doesn't touch the
+// user keyspace, the original repr is returned.
+func witnessWriteBatchRepr(repr []byte) ([]byte, error) {
+	r, err := storage.NewBatchReader(repr)
+	if err != nil {
+		return nil, err
+	}
+	localMax := storage.EngineKey{Key: keys.LocalMax}.Encode()
+	isLocal := func(rawKey []byte) (bool, error) {
+		ek, ok := storage.DecodeEngineKey(rawKey)
+		if !ok {
+			return false, errors.Errorf("invalid encoded engine key: %x", rawKey)
+		}
+		return ek.Key.Compare(keys.LocalMax) < 0, nil
+	}
+
+	var b pebble.Batch
+	var dropped bool
+	for r.Next() {
+		local, err := isLocal(r.Key())
+		if err != nil {
+			return nil, err
+		}
+		if !local {
+			dropped = true
+			continue
+		}
+		switch kind := r.KeyKind(); kind {
+		case pebble.InternalKeyKindSet, pebble.InternalKeyKindSetWithDelete:
+			err = b.Set(r.Key(), r.Value(), nil)
+		case pebble.InternalKeyKindMerge:
+			err = b.Merge(r.Key(), r.Value(), nil)
+		case pebble.InternalKeyKindDelete:
+			err = b.Delete(r.Key(), nil)
+		case pebble.InternalKeyKindSingleDelete:
+			err = b.SingleDelete(r.Key(), nil)
+		case pebble.InternalKeyKindDeleteSized:
+			// The value of a sized deletion is the varint-encoded size of the
+			// deleted value.
+			size, n := binary.Uvarint(r.Value())
+			if n <= 0 {
+				return nil, errors.Errorf("invalid sized deletion of key %x", r.Key())
+			}
+			err = b.DeleteSized(r.Key(), uint32(size), nil)
+		case pebble.InternalKeyKindRangeDelete,
+			pebble.InternalKeyKindRangeKeySet,
+			pebble.InternalKeyKindRangeKeyUnset,
+			pebble.InternalKeyKindRangeKeyDelete:
+			end, err := r.EndKey()
+			if err != nil {
+				return nil, err
+			}
+			if endLocal, err := isLocal(end); err != nil {
+				return nil, err
+			} else if !endLocal {
+				dropped = true
+				end = localMax
+			}
+			if err := addWitnessRangedOp(&b, r, kind, end); err != nil {
+				return nil, err
+			}
+		default:
+			err = errors.AssertionFailedf("unexpected batch entry key kind %d", kind)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Error(); err != nil {
+		return nil, err
+	}
+	if !dropped {
+		return repr, nil
+	}
+	return b.Repr(), nil
+}
+
+// addWitnessRangedOp adds the ranged operation the BatchReader is positioned
+// at to the given batch, using the (possibly truncated) end key.
+func addWitnessRangedOp(
+	b *pebble.Batch, r *storage.BatchReader, kind pebble.InternalKeyKind, end []byte,
+) error {
+	switch kind {
+	case pebble.InternalKeyKindRangeDelete:
+		return b.DeleteRange(r.Key(), end, nil)
+	case pebble.InternalKeyKindRangeKeyDelete:
+		return b.RangeKeyDelete(r.Key(), end, nil)
+	}
+	rangeKeys, err := r.RawRangeKeys()
+	if err != nil {
+		return err
+	}
+	for _, rk := range rangeKeys {
+		if kind == pebble.InternalKeyKindRangeKeySet {
+			err = b.RangeKeySet(r.Key(), end, rk.Suffix, rk.Value, nil)
+		} else {
+			err = b.RangeKeyUnset(r.Key(), end, rk.Suffix, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
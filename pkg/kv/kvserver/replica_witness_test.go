@@ -0,0 +1,218 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package kvserver_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/testcluster"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWitnessWriteBatchRepr(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+
+	rangeIDLocalKey := keys.RangeGCThresholdKey(1)
+	rangeLocalKey := keys.RangeDescriptorKey(roachpb.RKey("a"))
+	userKey := roachpb.Key("a")
+
+	type entry struct {
+		kind     pebble.InternalKeyKind
+		key, end roachpb.Key
+	}
+	readEntries := func(t *testing.T, repr []byte) []entry {
+		r, err := storage.NewBatchReader(repr)
+		require.NoError(t, err)
+		var entries []entry
+		for r.Next() {
+			key, err := r.EngineKey()
+			require.NoError(t, err)
+			e := entry{kind: r.KeyKind(), key: key.Key}
+			if r.KeyKind() == pebble.InternalKeyKindRangeDelete {
+				end, err := r.EngineEndKey()
+				require.NoError(t, err)
+				e.end = end.Key
+			}
+			entries = append(entries, e)
+		}
+		require.NoError(t, r.Error())
+		return entries
+	}
+
+	t.Run("local-only", func(t *testing.T) {
+		b := eng.NewBatch()
+		defer b.Close()
+		require.NoError(t, b.PutUnversioned(rangeIDLocalKey, []byte("foo")))
+		require.NoError(t, b.PutUnversioned(rangeLocalKey, []byte("bar")))
+
+		repr := b.Repr()
+		filtered, err := kvserver.WitnessWriteBatchRepr(repr)
+		require.NoError(t, err)
+		require.Equal(t, repr, filtered)
+	})
+
+	t.Run("mixed", func(t *testing.T) {
+		b := eng.NewBatch()
+		defer b.Close()
+		require.NoError(t, b.PutUnversioned(rangeIDLocalKey, []byte("foo")))
+		require.NoError(t, b.PutUnversioned(userKey, []byte("bar")))
+		require.NoError(t, b.ClearRawRange(keys.LocalRangePrefix, roachpb.Key("c"), true, false))
+		require.NoError(t, b.ClearRawRange(roachpb.Key("d"), roachpb.Key("e"), true, false))
+
+		filtered, err := kvserver.WitnessWriteBatchRepr(b.Repr())
+		require.NoError(t, err)
+		require.Equal(t, []entry{
+			{kind: pebble.InternalKeyKindSet, key: rangeIDLocalKey},
+			{kind: pebble.InternalKeyKindRangeDelete, key: keys.LocalRangePrefix, end: keys.LocalMax},
+		}, readEntries(t, filtered))
+	})
+}
+
+// TestWitnessReplica verifies that a witness joins a range and votes, but
+// holds no user keys and can't take the lease. It then makes the witness the
+// Raft leader while a full voter needs a snapshot, and verifies that the
+// snapshot is sent by a replica that holds the range's data.
+func TestWitnessReplica(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	tc := testcluster.StartTestCluster(t, 3, base.TestClusterArgs{
+		ReplicationMode: base.ReplicationManual,
+		ServerArgs: base.TestServerArgs{
+			Knobs: base.TestingKnobs{
+				Store: &kvserver.StoreTestingKnobs{
+					// The leader should stay on the witness once it campaigns.
+					DisableLeaderFollowsLeaseholder: true,
+				},
+			},
+		},
+	})
+	defer tc.Stopper().Stop(ctx)
+
+	// n1 is the leaseholder, n2 a full voter and n3 the witness.
+	key := tc.ScratchRange(t)
+	desc := tc.AddVotersOrFatal(t, key, tc.Target(1))
+	newDesc, err := tc.Server(0).DB().AdminChangeReplicas(
+		ctx, key, desc, kvpb.MakeReplicationChanges(roachpb.ADD_WITNESS, tc.Target(2)),
+	)
+	require.NoError(t, err)
+	require.Len(t, newDesc.Replicas().WitnessDescriptors(), 1)
+	desc = *newDesc
+
+	store := tc.GetFirstStoreFromServer(t, 0)
+	laggingStore := tc.GetFirstStoreFromServer(t, 1)
+	witnessStore := tc.GetFirstStoreFromServer(t, 2)
+	leaseholderRepl := store.LookupReplica(roachpb.RKey(key))
+	laggingRepl := laggingStore.LookupReplica(roachpb.RKey(key))
+	witnessRepl := witnessStore.LookupReplica(roachpb.RKey(key))
+	require.NotNil(t, witnessRepl)
+
+	// The witness applies the range's local state, but none of its user keys.
+	_, pErr := kv.SendWrapped(ctx, store.TestSender(), incrementArgs(key, 1))
+	require.NoError(t, pErr.GoError())
+	tc.WaitForValues(t, key, []int64{1, 1, 0})
+	testutils.SucceedsSoon(t, func() error {
+		exp := leaseholderRepl.State(ctx).State.RaftAppliedIndex
+		if act := witnessRepl.State(ctx).State.RaftAppliedIndex; act != exp {
+			return errors.Errorf("witness applied index %d, leaseholder at %d", act, exp)
+		}
+		return nil
+	})
+	require.Equal(t, leaseholderRepl.Desc(), witnessRepl.Desc())
+	kvs, err := storage.Scan(witnessStore.TODOEngine(), desc.StartKey.AsRawKey(), desc.EndKey.AsRawKey(), 0)
+	require.NoError(t, err)
+	require.Empty(t, kvs)
+
+	// The witness can't hold the lease.
+	err = tc.TransferRangeLease(desc, tc.Target(2))
+	require.True(t, testutils.IsError(err, `replica cannot hold lease`), err)
+
+	// Partition n2 away from the range. Writes still succeed, since the witness
+	// votes to commit them.
+	for i := 0; i < 3; i++ {
+		s := tc.GetFirstStoreFromServer(t, i)
+		h := &unreliableRaftHandler{
+			rangeID:                    desc.RangeID,
+			IncomingRaftMessageHandler: s,
+		}
+		if s == laggingStore {
+			h.snapErr = func(*kvserverpb.SnapshotRequest_Header) error {
+				return errors.New("partitioned")
+			}
+		} else {
+			h.dropReq = func(req *kvserverpb.RaftMessageRequest) bool {
+				return req.FromReplica.StoreID == laggingStore.StoreID()
+			}
+			h.dropHB = func(hb *kvserverpb.RaftHeartbeat) bool {
+				return hb.FromReplicaID == laggingRepl.ReplicaID()
+			}
+		}
+		tc.Servers[i].RaftTransport().(*kvserver.RaftTransport).ListenIncomingRaftMessages(s.StoreID(), h)
+	}
+	_, pErr = kv.SendWrapped(ctx, store.TestSender(), incrementArgs(key, 1))
+	require.NoError(t, pErr.GoError())
+	tc.WaitForValues(t, key, []int64{2, 1, 0})
+
+	// Make the witness the Raft leader.
+	witnessRepl.ForceCampaign(ctx)
+	testutils.SucceedsSoon(t, func() error {
+		if l := tc.GetRaftLeader(t, roachpb.RKey(key)); l.StoreID() != witnessStore.StoreID() {
+			return errors.Errorf("raft leader is on s%d, not on the witness", l.StoreID())
+		}
+		return nil
+	})
+
+	// Truncate the log past n2, so that it needs a snapshot.
+	_, pErr = kv.SendWrapped(ctx, store.TestSender(), incrementArgs(key, 1))
+	require.NoError(t, pErr.GoError())
+	index := leaseholderRepl.GetLastIndex()
+	_, pErr = kv.SendWrapped(ctx, store.TestSender(), truncateLogArgs(index+1, desc.RangeID))
+	require.NoError(t, pErr.GoError())
+	testutils.SucceedsSoon(t, func() error {
+		if first := witnessRepl.GetFirstIndex(); first <= index {
+			return errors.Errorf("witness log not truncated: first index %d", first)
+		}
+		return nil
+	})
+
+	// Heal the partition. The witness can't send n2 a snapshot itself, so it's
+	// delegated to n1.
+	snapshotsApplied := laggingStore.Metrics().RangeSnapshotsAppliedByVoters.Count()
+	delegated := witnessStore.Metrics().DelegateSnapshotSuccesses.Count()
+	for i := 0; i < 3; i++ {
+		s := tc.GetFirstStoreFromServer(t, i)
+		tc.Servers[i].RaftTransport().(*kvserver.RaftTransport).ListenIncomingRaftMessages(s.StoreID(), s)
+	}
+	tc.WaitForValues(t, key, []int64{3, 3, 0})
+	require.Equal(t, witnessStore.StoreID(), tc.GetRaftLeader(t, roachpb.RKey(key)).StoreID())
+	require.Greater(t, laggingStore.Metrics().RangeSnapshotsAppliedByVoters.Count(), snapshotsApplied)
+	require.Greater(t, witnessStore.Metrics().DelegateSnapshotSuccesses.Count(), delegated)
+	require.Zero(t, witnessStore.Metrics().RangeSnapshotsGenerated.Count())
+}
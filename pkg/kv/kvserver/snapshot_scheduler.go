@@ -0,0 +1,398 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package kvserver
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/demandgossip"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"golang.org/x/time/rate"
+)
+
+// snapshotPrioritizeRecoveryEnabled controls whether recovery snapshots acquire
+// send and receive reservations ahead of rebalancing snapshots. Recovery
+// snapshots are those sent to restore the replication factor of an
+// under-replicated range or to catch up a follower which fell behind the
+// truncated raft log; rebalancing snapshots only move data around.
+var snapshotPrioritizeRecoveryEnabled = settings.RegisterBoolSetting(
+	settings.SystemOnly,
+	"kv.snapshot_scheduler.prioritize_recovery.enabled",
+	"if enabled, recovery snapshots acquire send and receive reservations "+
+		"ahead of rebalancing snapshots",
+	true,
+)
+
+// snapshotLocalityMaxRate is the bandwidth budget for snapshots sent into any
+// single locality, as determined by snapshotLocalityTier. The budget applies
+// to the cluster as a whole, since every node may be sending snapshots into
+// the same locality at the same time (e.g. when re-replicating the data of a
+// failed node). The nodes divide it between them according to the demand they
+// exchange over gossip (see SnapshotScheduler).
+var snapshotLocalityMaxRate = settings.RegisterByteSizeSetting(
+	settings.SystemOnly,
+	"kv.snapshot_scheduler.locality_max_rate",
+	"the maximum rate (bytes/sec), summed across all nodes in the cluster, at which "+
+		"snapshot data is sent into a single locality, as defined by "+
+		"kv.snapshot_scheduler.locality_tier; 0 disables the limit",
+	0,
+	settings.NonNegativeInt,
+)
+
+// snapshotLocalityTier is the locality tier which partitions the cluster for
+// the purpose of snapshotLocalityMaxRate.
+var snapshotLocalityTier = settings.RegisterStringSetting(
+	settings.SystemOnly,
+	"kv.snapshot_scheduler.locality_tier",
+	"the locality tier key (e.g. region or zone) whose values define the localities "+
+		"that kv.snapshot_scheduler.locality_max_rate applies to",
+	"region",
+)
+
+// snapshotRebalanceFractionDuringRecovery is the fraction of a locality's
+// bandwidth budget which rebalancing snapshots may use while recovery
+// snapshots are being sent into that locality. The remainder is effectively
+// reserved for recovery snapshots.
+var snapshotRebalanceFractionDuringRecovery = settings.RegisterFloatSetting(
+	settings.SystemOnly,
+	"kv.snapshot_scheduler.rebalance_fraction_during_recovery",
+	"the fraction of kv.snapshot_scheduler.locality_max_rate which rebalancing "+
+		"snapshots may use while recovery snapshots are sent into the same locality",
+	0.25,
+	settings.FloatInRange(0, 1),
+)
+
+// snapshotDemandInterval is the interval at which each node shares its
+// snapshot demand with the other nodes.
+var snapshotDemandInterval = settings.RegisterDurationSetting(
+	settings.SystemOnly,
+	"kv.snapshot_scheduler.demand_interval",
+	"the interval at which nodes exchange the snapshots they are sending and "+
+		"redistribute the kv.snapshot_scheduler.locality_max_rate budgets between them",
+	10*time.Second,
+	settings.PositiveDuration,
+)
+
+// snapshotRecoveryPrecedence is the precedence with which recovery snapshots
+// acquire send and receive reservations when snapshotPrioritizeRecoveryEnabled
+// is set. All other snapshots use a precedence of 0.
+const snapshotRecoveryPrecedence = 1
+
+// snapshotPrecedence returns the precedence with which a snapshot of the given
+// priority acquires a send or receive reservation from a snapshot MultiQueue.
+func snapshotPrecedence(st *cluster.Settings, priority kvserverpb.SnapshotRequest_Priority) int {
+	if priority == kvserverpb.SnapshotRequest_RECOVERY &&
+		snapshotPrioritizeRecoveryEnabled.Get(&st.SV) {
+		return snapshotRecoveryPrecedence
+	}
+	return 0
+}
+
+// SnapshotScheduler coordinates the network bandwidth used by the snapshots
+// sent by all stores on a node, on top of the per-snapshot rate limit imposed
+// by kv.snapshot_rebalance.max_rate. It maintains a bandwidth budget for each
+// locality snapshots are sent into (see kv.snapshot_scheduler.locality_max_rate)
+// and, while recovery snapshots are being sent into a locality, restricts
+// rebalancing snapshots into that locality to a fraction of the budget so that
+// re-replication is not slowed down by rebalancing.
+//
+// A locality's budget applies to the cluster as a whole. Periodically, Refresh
+// returns the number of snapshots the node is sending into each locality,
+// which is gossiped to the other nodes and reported to their schedulers
+// through UpdateRemoteDemand. A node sending n_i snapshots into a locality is
+// then assigned maxRate*n_i/Σn_j of its budget, but no less than
+// minSnapshotRate. The budget is therefore exceeded if it is too small to give
+// every sending node minSnapshotRate: with n nodes sending into a locality, the
+// aggregate rate is bounded by max(maxRate, n*minSnapshotRate), up to the
+// staleness of the demand exchanged over gossip. The exchanged demand is also
+// used to estimate the time it will take the cluster to re-replicate its
+// under-replicated ranges.
+//
+// A SnapshotScheduler is normally shared between all stores on a node.
+type SnapshotScheduler struct {
+	st *cluster.Settings
+
+	// gossipOnce ensures that only the first store started on the node starts
+	// gossiping the node's demand.
+	gossipOnce sync.Once
+
+	mu struct {
+		syncutil.Mutex
+		// budgets contains the budgets of all localities which snapshots are
+		// currently being sent into, keyed by locality tier value.
+		budgets map[string]*localitySnapshotBudget
+		// remote contains the demand last reported by the other nodes.
+		remote demandgossip.Remote[roachpb.NodeID, *kvserverpb.SnapshotSchedulerDemand]
+		// reReplication contains the re-replication progress last recorded by
+		// each store on the node.
+		reReplication map[roachpb.StoreID]reReplicationProgress
+	}
+}
+
+// localitySnapshotBudget tracks the bandwidth budget for snapshots sent into a
+// single locality. Like kvBatchSnapshotStrategy's limiter, the limiters are
+// expressed in batches per second (see kv.snapshot_sender.batch_size).
+type localitySnapshotBudget struct {
+	// limiter is shared by all snapshots sent into the locality.
+	limiter *rate.Limiter
+	// rebalanceLimiter additionally limits rebalancing snapshots while
+	// recovering is set.
+	rebalanceLimiter *rate.Limiter
+	// inFlight and recoveryInFlight count the snapshots, respectively the
+	// recovery snapshots, this node is currently sending into the locality.
+	inFlight, recoveryInFlight int
+	// recovering is set if any node is sending recovery snapshots into the
+	// locality.
+	recovering bool
+}
+
+// reReplicationProgress is the re-replication progress of a store.
+type reReplicationProgress struct {
+	underReplicatedBytes int64
+	// rate is the smoothed rate, in bytes/sec, at which the store recently
+	// sent recovery snapshots.
+	rate float64
+}
+
+// NewSnapshotScheduler creates a SnapshotScheduler.
+func NewSnapshotScheduler(st *cluster.Settings) *SnapshotScheduler {
+	s := &SnapshotScheduler{st: st}
+	s.mu.budgets = make(map[string]*localitySnapshotBudget)
+	s.mu.reReplication = make(map[roachpb.StoreID]reReplicationProgress)
+	return s
+}
+
+// admit registers an outgoing snapshot of the given priority to a node in the
+// given locality and returns the grant which paces it. The grant must be
+// released once the snapshot is done. A nil grant is returned if no locality
+// budget is configured; it is valid to call the methods of a nil grant.
+func (s *SnapshotScheduler) admit(
+	priority kvserverpb.SnapshotRequest_Priority, locality roachpb.Locality,
+) *snapshotBandwidthGrant {
+	if s == nil || snapshotLocalityMaxRate.Get(&s.st.SV) == 0 {
+		return nil
+	}
+	key, _ := locality.Find(snapshotLocalityTier.Get(&s.st.SV))
+	recovery := priority == kvserverpb.SnapshotRequest_RECOVERY
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.mu.budgets[key]
+	if !ok {
+		b = &localitySnapshotBudget{
+			limiter:          rate.NewLimiter(rate.Inf, 1 /* burst */),
+			rebalanceLimiter: rate.NewLimiter(rate.Inf, 1 /* burst */),
+		}
+		s.mu.budgets[key] = b
+	}
+	b.inFlight++
+	if recovery {
+		b.recoveryInFlight++
+	}
+	s.updateLimitsLocked(key, b)
+	return &snapshotBandwidthGrant{s: s, key: key, budget: b, recovery: recovery}
+}
+
+// updateLimitsLocked sets the limits of the given locality's budget according
+// to the current settings and the latest local and remote demand.
+func (s *SnapshotScheduler) updateLimitsLocked(key string, b *localitySnapshotBudget) {
+	inFlight, recoveryInFlight := b.inFlight, b.recoveryInFlight
+	s.mu.remote.Range(func(_ roachpb.NodeID, demand *kvserverpb.SnapshotSchedulerDemand) {
+		for _, l := range demand.Localities {
+			if l.Locality == key {
+				inFlight += int(l.InFlight)
+				recoveryInFlight += int(l.RecoveryInFlight)
+			}
+		}
+	})
+	b.recovering = recoveryInFlight > 0
+
+	maxRate := float64(snapshotLocalityMaxRate.Get(&s.st.SV))
+	if maxRate == 0 {
+		// The budget was disabled after the snapshots were admitted.
+		b.limiter.SetLimit(rate.Inf)
+		b.rebalanceLimiter.SetLimit(rate.Inf)
+		return
+	}
+	// This node's share of the budget is never lowered below minSnapshotRate,
+	// for the same reasons that kv.snapshot_rebalance.max_rate is not.
+	batchSize := float64(snapshotSenderBatchSize.Get(&s.st.SV))
+	share := math.Max(maxRate*float64(b.inFlight)/float64(inFlight), minSnapshotRate)
+	rebalanceShare := math.Max(
+		share*snapshotRebalanceFractionDuringRecovery.Get(&s.st.SV), minSnapshotRate)
+	b.limiter.SetLimit(rate.Limit(share / batchSize))
+	b.rebalanceLimiter.SetLimit(rate.Limit(rebalanceShare / batchSize))
+}
+
+// Refresh discards the demand of the nodes which stopped reporting it,
+// redistributes the budgets based on the latest local and remote demand, and
+// returns the local demand, to be shared with the other nodes.
+func (s *SnapshotScheduler) Refresh(now time.Time) kvserverpb.SnapshotSchedulerDemand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.remote.Expire(now, snapshotDemandInterval.Get(&s.st.SV))
+
+	var demand kvserverpb.SnapshotSchedulerDemand
+	for key, b := range s.mu.budgets {
+		s.updateLimitsLocked(key, b)
+		demand.Localities = append(demand.Localities, kvserverpb.SnapshotSchedulerDemand_Locality{
+			Locality:         key,
+			InFlight:         int32(b.inFlight),
+			RecoveryInFlight: int32(b.recoveryInFlight),
+		})
+	}
+	for _, p := range s.mu.reReplication {
+		demand.UnderReplicatedBytes += p.underReplicatedBytes
+		demand.RecoveryBytesPerSecond += p.rate
+	}
+	return demand
+}
+
+// UpdateRemoteDemand records the demand reported by another node. It takes
+// effect on the next call to admit or Refresh.
+func (s *SnapshotScheduler) UpdateRemoteDemand(
+	now time.Time, nodeID roachpb.NodeID, demand *kvserverpb.SnapshotSchedulerDemand,
+) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.remote.Update(now, nodeID, demand)
+}
+
+// reReplicationETA records the re-replication progress of the given store and
+// returns the estimated time it will take the cluster to re-replicate all of
+// its under-replicated ranges, based on the progress of the node's stores and
+// the progress reported by the other nodes. ok is false if no estimate can be
+// made because recovery snapshots are not (or no longer) being sent.
+func (s *SnapshotScheduler) reReplicationETA(
+	storeID roachpb.StoreID, underReplicatedBytes int64, rate float64,
+) (eta time.Duration, ok bool) {
+	if s == nil {
+		return estimateReReplicationETA(underReplicatedBytes, rate)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.reReplication[storeID] = reReplicationProgress{
+		underReplicatedBytes: underReplicatedBytes,
+		rate:                 rate,
+	}
+	var totalBytes int64
+	var totalRate float64
+	for _, p := range s.mu.reReplication {
+		totalBytes += p.underReplicatedBytes
+		totalRate += p.rate
+	}
+	s.mu.remote.Range(func(_ roachpb.NodeID, demand *kvserverpb.SnapshotSchedulerDemand) {
+		totalBytes += demand.UnderReplicatedBytes
+		totalRate += demand.RecoveryBytesPerSecond
+	})
+	return estimateReReplicationETA(totalBytes, totalRate)
+}
+
+// snapshotBandwidthGrant paces a single outgoing snapshot according to the
+// budget of the locality it is sent into.
+type snapshotBandwidthGrant struct {
+	s        *SnapshotScheduler
+	key      string
+	budget   *localitySnapshotBudget
+	recovery bool
+}
+
+// wait blocks until the snapshot may send its next batch.
+func (g *snapshotBandwidthGrant) wait(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+	if !g.recovery {
+		g.s.mu.Lock()
+		recovering := g.budget.recovering
+		g.s.mu.Unlock()
+		if recovering {
+			if err := g.budget.rebalanceLimiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return g.budget.limiter.Wait(ctx)
+}
+
+// release unregisters the snapshot from the scheduler.
+func (g *snapshotBandwidthGrant) release() {
+	if g == nil {
+		return
+	}
+	g.s.mu.Lock()
+	defer g.s.mu.Unlock()
+	g.budget.inFlight--
+	if g.recovery {
+		g.budget.recoveryInFlight--
+	}
+	if g.budget.inFlight == 0 {
+		delete(g.s.mu.budgets, g.key)
+	} else {
+		g.s.updateLimitsLocked(g.key, g.budget)
+	}
+}
+
+// reReplicationEstimator measures the rate at which a store recently sent
+// recovery snapshots, which the SnapshotScheduler uses to estimate the time it
+// will take the cluster to re-replicate its under-replicated ranges.
+type reReplicationEstimator struct {
+	syncutil.Mutex
+	lastSentBytes int64
+	lastUpdate    time.Time
+	// rate is the exponentially smoothed rate, in bytes/sec, at which recovery
+	// snapshots were sent.
+	rate float64
+}
+
+// reReplicationRateSmoothing is the weight given to the most recent interval
+// when smoothing the rate at which recovery snapshots were sent.
+const reReplicationRateSmoothing = 0.5
+
+// update records the total number of recovery snapshot bytes sent by the
+// store as of now and returns the smoothed rate, in bytes/sec, at which they
+// were sent.
+func (e *reReplicationEstimator) update(now time.Time, sentBytes int64) float64 {
+	e.Lock()
+	defer e.Unlock()
+	if !e.lastUpdate.IsZero() {
+		if elapsed := now.Sub(e.lastUpdate).Seconds(); elapsed > 0 {
+			instant := float64(sentBytes-e.lastSentBytes) / elapsed
+			e.rate = reReplicationRateSmoothing*instant + (1-reReplicationRateSmoothing)*e.rate
+		}
+	}
+	e.lastSentBytes, e.lastUpdate = sentBytes, now
+	return e.rate
+}
+
+// estimateReReplicationETA returns the estimated time to re-replicate the given
+// number of under-replicated bytes when recovery snapshots are sent at the
+// given rate, in bytes/sec. ok is false if no estimate can be made because
+// recovery is stalled.
+func estimateReReplicationETA(
+	underReplicatedBytes int64, rate float64,
+) (eta time.Duration, ok bool) {
+	if underReplicatedBytes == 0 {
+		return 0, true
+	}
+	// Below 1 byte/sec, recovery is considered stalled.
+	if rate < 1 {
+		return 0, false
+	}
+	return time.Duration(float64(underReplicatedBytes) / rate * float64(time.Second)), true
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package kvserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/demandgossip"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestSnapshotPrecedence(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	require.Equal(t, snapshotRecoveryPrecedence,
+		snapshotPrecedence(st, kvserverpb.SnapshotRequest_RECOVERY))
+	require.Equal(t, 0, snapshotPrecedence(st, kvserverpb.SnapshotRequest_REBALANCE))
+	require.Equal(t, 0, snapshotPrecedence(st, kvserverpb.SnapshotRequest_UNKNOWN))
+
+	snapshotPrioritizeRecoveryEnabled.Override(ctx, &st.SV, false)
+	require.Equal(t, 0, snapshotPrecedence(st, kvserverpb.SnapshotRequest_RECOVERY))
+}
+
+func TestSnapshotSchedulerLocalityBudgets(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	s := NewSnapshotScheduler(st)
+	east := roachpb.Locality{Tiers: []roachpb.Tier{{Key: "region", Value: "east"}, {Key: "zone", Value: "a"}}}
+	west := roachpb.Locality{Tiers: []roachpb.Tier{{Key: "region", Value: "west"}, {Key: "zone", Value: "a"}}}
+	batchSize := float64(snapshotSenderBatchSize.Get(&st.SV))
+	now := timeutil.Now()
+
+	// Without a budget, snapshots are not paced by the scheduler.
+	g := s.admit(kvserverpb.SnapshotRequest_REBALANCE, east)
+	require.Nil(t, g)
+	require.NoError(t, g.wait(ctx))
+	g.release()
+
+	const maxRate = 96 << 20
+	snapshotLocalityMaxRate.Override(ctx, &st.SV, maxRate)
+
+	// The budget of a locality is split across all nodes in proportion to the
+	// number of snapshots they send into it, and rebalancing snapshots only
+	// use a fraction of it while recovery snapshots are in flight.
+	s.UpdateRemoteDemand(now, 2, &kvserverpb.SnapshotSchedulerDemand{
+		Localities: []kvserverpb.SnapshotSchedulerDemand_Locality{{Locality: "east", InFlight: 2}},
+	})
+	rebalanceEast := s.admit(kvserverpb.SnapshotRequest_REBALANCE, east)
+	require.Equal(t, rate.Limit(maxRate/3/batchSize), rebalanceEast.budget.limiter.Limit())
+	require.False(t, rebalanceEast.budget.recovering)
+	recoveryEast := s.admit(kvserverpb.SnapshotRequest_RECOVERY, east)
+	rebalanceWest := s.admit(kvserverpb.SnapshotRequest_REBALANCE, west)
+	require.Same(t, rebalanceEast.budget, recoveryEast.budget)
+	require.NotSame(t, rebalanceEast.budget, rebalanceWest.budget)
+	require.Equal(t, rate.Limit(maxRate/2/batchSize), rebalanceEast.budget.limiter.Limit())
+	require.Equal(t, rate.Limit(maxRate/2*0.25/batchSize), rebalanceEast.budget.rebalanceLimiter.Limit())
+	require.Equal(t, 2, rebalanceEast.budget.inFlight)
+	require.Equal(t, 1, rebalanceEast.budget.recoveryInFlight)
+	require.True(t, rebalanceEast.budget.recovering)
+	require.Equal(t, 0, rebalanceWest.budget.recoveryInFlight)
+	require.Equal(t, rate.Limit(maxRate/batchSize), rebalanceWest.budget.limiter.Limit())
+
+	// Recovery snapshots sent into a locality by other nodes restrict the
+	// rebalancing snapshots sent by this node too. Refresh reports the demand
+	// of this node.
+	s.UpdateRemoteDemand(now, 3, &kvserverpb.SnapshotSchedulerDemand{
+		Localities: []kvserverpb.SnapshotSchedulerDemand_Locality{
+			{Locality: "west", InFlight: 1, RecoveryInFlight: 1},
+		},
+	})
+	require.False(t, rebalanceWest.budget.recovering)
+	demand := s.Refresh(now)
+	require.ElementsMatch(t, []kvserverpb.SnapshotSchedulerDemand_Locality{
+		{Locality: "east", InFlight: 2, RecoveryInFlight: 1},
+		{Locality: "west", InFlight: 1},
+	}, demand.Localities)
+	require.True(t, rebalanceWest.budget.recovering)
+	require.Equal(t, rate.Limit(maxRate/2/batchSize), rebalanceWest.budget.limiter.Limit())
+	require.Equal(t, rate.Limit(maxRate/2*0.25/batchSize), rebalanceWest.budget.rebalanceLimiter.Limit())
+
+	// Budgets follow the configured locality tier.
+	snapshotLocalityTier.Override(ctx, &st.SV, "zone")
+	zoneA := s.admit(kvserverpb.SnapshotRequest_REBALANCE, west)
+	require.NotSame(t, rebalanceWest.budget, zoneA.budget)
+	zoneA.release()
+
+	// A node's share of the budget is never lower than minSnapshotRate.
+	snapshotLocalityTier.Override(ctx, &st.SV, "region")
+	s.UpdateRemoteDemand(now, 4, &kvserverpb.SnapshotSchedulerDemand{
+		Localities: []kvserverpb.SnapshotSchedulerDemand_Locality{{Locality: "east", InFlight: 1000}},
+	})
+	manyNodes := s.admit(kvserverpb.SnapshotRequest_RECOVERY, east)
+	require.Equal(t, rate.Limit(minSnapshotRate/batchSize), manyNodes.budget.limiter.Limit())
+	require.Equal(t, rate.Limit(minSnapshotRate/batchSize), manyNodes.budget.rebalanceLimiter.Limit())
+	manyNodes.release()
+
+	// The demand of nodes which stop reporting it is eventually ignored.
+	s.Refresh(now.Add(demandgossip.ExpirationIntervals*snapshotDemandInterval.Get(&st.SV) + time.Second))
+	require.Equal(t, rate.Limit(maxRate/batchSize), rebalanceEast.budget.limiter.Limit())
+	require.False(t, rebalanceWest.budget.recovering)
+
+	// Disabling the budget stops pacing the snapshots in flight.
+	snapshotLocalityMaxRate.Override(ctx, &st.SV, 0)
+	s.Refresh(now)
+	require.Equal(t, rate.Inf, rebalanceEast.budget.limiter.Limit())
+	require.Equal(t, rate.Inf, rebalanceEast.budget.rebalanceLimiter.Limit())
+
+	// Budgets are dropped once no more snapshots are sent into the locality.
+	recoveryEast.release()
+	require.Equal(t, 0, rebalanceEast.budget.recoveryInFlight)
+	require.False(t, rebalanceEast.budget.recovering)
+	rebalanceEast.release()
+	rebalanceWest.release()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.Empty(t, s.mu.budgets)
+}
+
+func TestReReplicationEstimator(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	var e reReplicationEstimator
+	start := time.Unix(0, 0)
+
+	// Nothing to re-replicate.
+	eta, ok := estimateReReplicationETA(0 /* underReplicatedBytes */, e.update(start, 0 /* sentBytes */))
+	require.True(t, ok)
+	require.Zero(t, eta)
+
+	// No recovery snapshots were sent yet.
+	_, ok = estimateReReplicationETA(100 /* underReplicatedBytes */, e.update(start.Add(time.Second), 0 /* sentBytes */))
+	require.False(t, ok)
+
+	// 200 bytes were sent in the last second, which is smoothed to 100
+	// bytes/sec.
+	eta, ok = estimateReReplicationETA(100 /* underReplicatedBytes */, e.update(start.Add(2*time.Second), 200 /* sentBytes */))
+	require.True(t, ok)
+	require.Equal(t, time.Second, eta)
+
+	// The estimate grows as recovery slows down.
+	eta, ok = estimateReReplicationETA(100 /* underReplicatedBytes */, e.update(start.Add(3*time.Second), 200 /* sentBytes */))
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, eta)
+}
+
+func TestSnapshotSchedulerReReplicationETA(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	s := NewSnapshotScheduler(cluster.MakeTestingClusterSettings())
+	now := timeutil.Now()
+
+	// A store whose ranges are under-replicated but which isn't sending
+	// recovery snapshots can't tell when they'll be re-replicated...
+	_, ok := s.reReplicationETA(1, 400 /* underReplicatedBytes */, 0 /* rate */)
+	require.False(t, ok)
+
+	// ... but another store on the node, or another node, may be sending the
+	// snapshots. The estimate is for the cluster as a whole.
+	eta, ok := s.reReplicationETA(2, 0 /* underReplicatedBytes */, 100 /* rate */)
+	require.True(t, ok)
+	require.Equal(t, 4*time.Second, eta)
+	s.UpdateRemoteDemand(now, 2, &kvserverpb.SnapshotSchedulerDemand{
+		UnderReplicatedBytes:   400,
+		RecoveryBytesPerSecond: 300,
+	})
+	eta, ok = s.reReplicationETA(1, 400 /* underReplicatedBytes */, 0 /* rate */)
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, eta)
+
+	// The progress of the node's stores is shared with the other nodes.
+	demand := s.Refresh(now)
+	require.Equal(t, int64(400), demand.UnderReplicatedBytes)
+	require.Equal(t, float64(100), demand.RecoveryBytesPerSecond)
+}
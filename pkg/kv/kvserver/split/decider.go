@@ -156,6 +156,7 @@ type Decider struct {
 
 		// Fields tracking split key suggestions.
 		splitFinder         LoadBasedSplitter // populated when engaged or decided
+		hotKeyFinder        *HotKeyFinder     // populated along with splitFinder
 		lastSplitSuggestion time.Time         // last stipulation to client to carry out split
 		suggestionsMade     int               // suggestions made since last reset
 
@@ -190,6 +191,9 @@ func (ld *lockedDecider) SafeFormat(w redact.SafePrinter, r rune) {
 	if ld.mu.splitFinder != nil {
 		w.Printf(" %v", ld.mu.splitFinder)
 	}
+	if ld.mu.hotKeyFinder != nil {
+		w.Printf(" %v", ld.mu.hotKeyFinder)
+	}
 }
 
 func (ld *lockedDecider) String() string {
@@ -206,11 +210,47 @@ func (ld *lockedDecider) String() string {
 // which can call MaybeSplitKey to retrieve the suggested key.
 func (d *Decider) Record(
 	ctx context.Context, now time.Time, load func(SplitObjective) int, span func() roachpb.Span,
+) bool {
+	return d.RecordWithKeys(ctx, now, load, span, nil /* keys */)
+}
+
+// RecordWithKeys is like Record, but additionally records the individual keys
+// accessed by the operations, as returned by the supplied method, for hot key
+// detection. Like the span closure, the keys closure will only be called when
+// the Decider is sampling.
+func (d *Decider) RecordWithKeys(
+	ctx context.Context,
+	now time.Time,
+	load func(SplitObjective) int,
+	span func() roachpb.Span,
+	keys func() []KeyAccess,
 ) bool {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	return d.recordLocked(ctx, now, load(d.mu.objective), span)
+	n := load(d.mu.objective)
+	shouldSplit := d.recordLocked(ctx, now, n, span)
+	if d.mu.hotKeyFinder != nil && keys != nil && n != 0 {
+		for _, access := range keys() {
+			d.mu.hotKeyFinder.Record(access)
+		}
+	}
+	return shouldSplit
+}
+
+// RecordContention notifies the Decider that requests waited on conflicting
+// locks held on the keys returned by the supplied method. The closure will only
+// be called when the Decider is sampling.
+func (d *Decider) RecordContention(keys func() []roachpb.Key) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.mu.hotKeyFinder == nil {
+		return
+	}
+	for _, key := range keys() {
+		d.mu.hotKeyFinder.Record(KeyAccess{Key: key, Type: KeyContention})
+	}
 }
 
 func (d *Decider) recordLocked(
@@ -241,9 +281,11 @@ func (d *Decider) recordLocked(
 		if d.mu.lastStatVal >= d.config.StatThreshold(d.mu.objective) {
 			if d.mu.splitFinder == nil {
 				d.mu.splitFinder = d.config.NewLoadBasedSplitter(now, d.mu.objective)
+				d.mu.hotKeyFinder = NewHotKeyFinder(now)
 			}
 		} else {
 			d.mu.splitFinder = nil
+			d.mu.hotKeyFinder = nil
 		}
 	}
 
@@ -355,6 +397,20 @@ func (d *Decider) MaybeSplitKey(ctx context.Context, now time.Time) roachpb.Key
 	return key
 }
 
+// HotKeys returns the keys which receive a large fraction of the load on the
+// range, ordered by decreasing frequency. The return value will be nil if the
+// Decider isn't sampling the load, which is only the case when the load is
+// above the split threshold, or hasn't sampled enough of it yet.
+//
+// It is legal to call HotKeys at any time.
+func (d *Decider) HotKeys(ctx context.Context, now time.Time) []HotKey {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.recordLocked(ctx, now, 0, nil)
+	return d.mu.hotKeyFinder.HotKeys(now)
+}
+
 // Reset deactivates any current attempt at determining a split key. The method
 // also discards any historical stat tracking information.
 func (d *Decider) Reset(now time.Time) {
@@ -370,6 +426,7 @@ func (d *Decider) resetLocked(now time.Time) {
 	d.mu.count = 0
 	d.mu.maxStat.reset(now, d.config.StatRetention())
 	d.mu.splitFinder = nil
+	d.mu.hotKeyFinder = nil
 	d.mu.suggestionsMade = 0
 	d.mu.lastSplitSuggestion = time.Time{}
 	d.mu.lastNoSplitKeyLoggingMetrics = time.Time{}
@@ -0,0 +1,216 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package split
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/redact"
+)
+
+// Hot key detection.
+//
+// Load-based splitting cannot help a range whose load is concentrated on a
+// single key: no split key divides the load, and the range simply remains hot.
+// While the Decider samples a range's load to find a split key, it also keeps
+// a HotKeyFinder, which counts the individual keys accessed by requests using
+// the Space-Saving algorithm[1]:
+//
+//   - Keep a fixed number of counters, each tracking a key.
+//   - When a key is accessed and it is tracked, increment its counter.
+//   - Otherwise, if there is an unused counter, start tracking the key there.
+//   - Otherwise, replace the key with the smallest counter with the accessed
+//     key, keep the counter (plus one) and remember it as the counter's error.
+//
+// Any key accessed by more than 1/hotKeySampleSize of the recorded accesses is
+// guaranteed to be tracked, and the counter of a tracked key overestimates its
+// accesses by at most its error. The reads, writes and contention of a key are
+// only counted while it is tracked, so they are lower bounds; keys which are
+// accessed frequently are not replaced, so the bounds are tight for hot keys.
+//
+// [1]: Metwally, Agrawal and El Abbadi. Efficient Computation of Frequent and
+// Top-k Elements in Data Streams. ICDT 2005.
+
+const (
+	hotKeySampleSize = 20  // number of keys tracked
+	hotKeyMinCounter = 100 // min accesses recorded before reporting hot keys
+	// hotKeyThreshold is the minimum fraction of the accesses to a range that
+	// a key must receive to be reported as hot.
+	hotKeyThreshold = 0.10
+)
+
+// KeyAccessType is the type of an access to a key recorded by a HotKeyFinder.
+type KeyAccessType int
+
+const (
+	// KeyRead is a read of a key.
+	KeyRead KeyAccessType = iota
+	// KeyWrite is a write of a key.
+	KeyWrite
+	// KeyContention is a request waiting on a conflicting lock held on a key.
+	KeyContention
+)
+
+// KeyAccess is an access to a key by a request.
+type KeyAccess struct {
+	Key  roachpb.Key
+	Type KeyAccessType
+}
+
+// HotKey describes a key which receives a large fraction of the accesses to a
+// range.
+type HotKey struct {
+	Key roachpb.Key
+	// Frequency is the fraction of the accesses to the range which accessed
+	// the key. It is a lower bound.
+	Frequency float64
+	// ReadsPerSecond, WritesPerSecond and ContentionPerSecond are the rates of
+	// reads of, writes to and lock waits on the key since the HotKeyFinder was
+	// created. They are lower bounds.
+	ReadsPerSecond, WritesPerSecond, ContentionPerSecond float64
+}
+
+// SafeFormat implements the redact.SafeFormatter interface.
+func (k HotKey) SafeFormat(w redact.SafePrinter, _ rune) {
+	w.Printf("%s(freq=%.2f reads=%.1f/s writes=%.1f/s contention=%.1f/s)",
+		k.Key, k.Frequency, k.ReadsPerSecond, k.WritesPerSecond, k.ContentionPerSecond)
+}
+
+func (k HotKey) String() string {
+	return redact.StringWithoutMarkers(k)
+}
+
+type hotKeySample struct {
+	key roachpb.Key
+	// count is the counter of the key, err is the part of it which may have
+	// been accesses to the keys it replaced.
+	count, err                int
+	reads, writes, contention int
+}
+
+// SafeFormat implements the redact.SafeFormatter interface.
+func (s hotKeySample) SafeFormat(w redact.SafePrinter, _ rune) {
+	w.Printf("%s(n=%d e=%d r=%d w=%d c=%d)",
+		s.key, s.count, s.err, s.reads, s.writes, s.contention)
+}
+
+func (s hotKeySample) String() string {
+	return redact.StringWithoutMarkers(s)
+}
+
+// HotKeyFinder tracks the most frequently accessed keys of a range, along with
+// their reads, writes and contention.
+type HotKeyFinder struct {
+	startTime time.Time
+	samples   [hotKeySampleSize]hotKeySample
+	used      int // number of samples in use
+	count     int // number of reads and writes recorded
+}
+
+// NewHotKeyFinder initiates a HotKeyFinder with the given time.
+func NewHotKeyFinder(startTime time.Time) *HotKeyFinder {
+	return &HotKeyFinder{startTime: startTime}
+}
+
+// Record records an access to a key.
+func (f *HotKeyFinder) Record(access KeyAccess) {
+	if f == nil || access.Key == nil {
+		return
+	}
+	if access.Type == KeyContention {
+		if idx := f.find(access.Key); idx >= 0 {
+			f.samples[idx].contention++
+		}
+		return
+	}
+
+	f.count++
+	idx := f.find(access.Key)
+	if idx < 0 {
+		if f.used < hotKeySampleSize {
+			idx = f.used
+			f.used++
+			f.samples[idx] = hotKeySample{key: access.Key}
+		} else {
+			// Replace the key with the smallest counter. Its counter is carried
+			// over to the new key, as an error.
+			idx = 0
+			for i := 1; i < len(f.samples); i++ {
+				if f.samples[i].count < f.samples[idx].count {
+					idx = i
+				}
+			}
+			minCount := f.samples[idx].count
+			f.samples[idx] = hotKeySample{key: access.Key, count: minCount, err: minCount}
+		}
+	}
+	f.samples[idx].count++
+	if access.Type == KeyWrite {
+		f.samples[idx].writes++
+	} else {
+		f.samples[idx].reads++
+	}
+}
+
+// find returns the index of the sample tracking the given key, or -1 if the
+// key is not tracked.
+func (f *HotKeyFinder) find(key roachpb.Key) int {
+	for i := 0; i < f.used; i++ {
+		if bytes.Equal(f.samples[i].key, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// HotKeys returns the tracked keys which received at least hotKeyThreshold of
+// the recorded accesses, ordered by decreasing frequency. It returns nil until
+// enough accesses have been recorded.
+func (f *HotKeyFinder) HotKeys(now time.Time) []HotKey {
+	if f == nil || f.count < hotKeyMinCounter {
+		return nil
+	}
+	elapsed := now.Sub(f.startTime).Seconds()
+	if elapsed <= 0 {
+		return nil
+	}
+	var hotKeys []HotKey
+	for _, s := range f.samples[:f.used] {
+		frequency := float64(s.count-s.err) / float64(f.count)
+		if frequency < hotKeyThreshold {
+			continue
+		}
+		hotKeys = append(hotKeys, HotKey{
+			Key:                 s.key,
+			Frequency:           frequency,
+			ReadsPerSecond:      float64(s.reads) / elapsed,
+			WritesPerSecond:     float64(s.writes) / elapsed,
+			ContentionPerSecond: float64(s.contention) / elapsed,
+		})
+	}
+	sort.Slice(hotKeys, func(i, j int) bool {
+		return hotKeys[i].Frequency > hotKeys[j].Frequency
+	})
+	return hotKeys
+}
+
+// SafeFormat implements the redact.SafeFormatter interface.
+func (f *HotKeyFinder) SafeFormat(w redact.SafePrinter, _ rune) {
+	w.Printf("start=%v count=%d hot-key-samples=%v",
+		f.startTime, f.count, f.samples[:f.used])
+}
+
+func (f *HotKeyFinder) String() string {
+	return redact.StringWithoutMarkers(f)
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package split
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHotKeyFinder(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	rng := rand.New(rand.NewSource(1))
+	f := NewHotKeyFinder(ms(0))
+	require.Nil(t, f.HotKeys(ms(10000)))
+
+	// Key "a" receives 40% of the accesses, key "b" 15%, and the rest are
+	// spread uniformly over many more keys than there are samples. Writes to
+	// "a" contend half of the time.
+	const n = 10000
+	for i := 0; i < n; i++ {
+		switch r := rng.Float64(); {
+		case r < 0.2:
+			f.Record(KeyAccess{Key: roachpb.Key("a"), Type: KeyRead})
+		case r < 0.4:
+			f.Record(KeyAccess{Key: roachpb.Key("a"), Type: KeyWrite})
+			if rng.Intn(2) == 0 {
+				f.Record(KeyAccess{Key: roachpb.Key("a"), Type: KeyContention})
+			}
+		case r < 0.55:
+			f.Record(KeyAccess{Key: roachpb.Key("b"), Type: KeyRead})
+		default:
+			key := roachpb.Key(fmt.Sprintf("c%d", rng.Intn(1000)))
+			f.Record(KeyAccess{Key: key, Type: KeyWrite})
+			// Contention on keys which are not tracked is not recorded.
+			f.Record(KeyAccess{Key: roachpb.Key("untracked"), Type: KeyContention})
+		}
+	}
+
+	hotKeys := f.HotKeys(ms(10000))
+	require.Len(t, hotKeys, 2, "%v", hotKeys)
+	a, b := hotKeys[0], hotKeys[1]
+	require.Equal(t, roachpb.Key("a"), a.Key)
+	require.InDelta(t, 0.4, a.Frequency, 0.02)
+	require.InDelta(t, 0.2*n/10, a.ReadsPerSecond, 20)
+	require.InDelta(t, 0.2*n/10, a.WritesPerSecond, 20)
+	require.InDelta(t, 0.1*n/10, a.ContentionPerSecond, 20)
+	require.Equal(t, roachpb.Key("b"), b.Key)
+	require.InDelta(t, 0.15, b.Frequency, 0.02)
+	require.InDelta(t, 0.15*n/10, b.ReadsPerSecond, 20)
+	require.Zero(t, b.WritesPerSecond)
+	require.Zero(t, b.ContentionPerSecond)
+}
+
+func TestDeciderHotKeys(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+
+	loadSplitConfig := testLoadSplitConfig{
+		randSource:    rand.New(rand.NewSource(1)),
+		statRetention: 2 * time.Second,
+		statThreshold: 10,
+	}
+	var d Decider
+	Init(&d, &loadSplitConfig, &LoadSplitterMetrics{
+		PopularKeyCount: metric.NewCounter(metric.Metadata{}),
+		NoSplitKeyCount: metric.NewCounter(metric.Metadata{}),
+	},
+		SplitQPS,
+	)
+
+	span := func() roachpb.Span { return roachpb.Span{Key: roachpb.Key("a")} }
+	keys := func() []KeyAccess {
+		return []KeyAccess{{Key: roachpb.Key("a"), Type: KeyWrite}}
+	}
+	contention := func() []roachpb.Key { return []roachpb.Key{roachpb.Key("a")} }
+
+	// Below the threshold, the Decider doesn't sample keys.
+	for tick := 0; tick <= 2000; tick += 200 {
+		d.RecordWithKeys(ctx, ms(tick), ld(1), span, keys)
+		d.RecordContention(contention)
+	}
+	require.Nil(t, d.HotKeys(ctx, ms(2000)))
+
+	// Above the threshold, it does.
+	for tick := 2100; tick < 5000; tick += 10 {
+		d.RecordWithKeys(ctx, ms(tick), ld(1), span, keys)
+		d.RecordContention(contention)
+	}
+	hotKeys := d.HotKeys(ctx, ms(5000))
+	require.Len(t, hotKeys, 1)
+	require.Equal(t, roachpb.Key("a"), hotKeys[0].Key)
+	require.Equal(t, 1.0, hotKeys[0].Frequency)
+	require.Zero(t, hotKeys[0].ReadsPerSecond)
+	require.Greater(t, hotKeys[0].WritesPerSecond, 10.0)
+	require.Greater(t, hotKeys[0].ContentionPerSecond, 10.0)
+
+	// Hot keys are discarded when the Decider is reset.
+	d.Reset(ms(5000))
+	require.Nil(t, d.HotKeys(ctx, ms(5000)))
+}
@@ -54,6 +54,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/raftentry"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/rangefeed"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/rditer"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/split"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/tablerate"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/tenantrate"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/tscache"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/txnrecovery"
@@ -869,6 +871,10 @@ type Store struct {
 	// Queue to limit concurrent non-empty snapshot sending.
 	snapshotSendQueue *multiqueue.MultiQueue
 
+	// reReplicationEstimator measures the rate at which this store sends
+	// recovery snapshots.
+	reReplicationEstimator reReplicationEstimator
+
 	// draining holds a bool which indicates whether this store is draining. See
 	// SetDraining() for a more detailed explanation of behavior changes.
 	//
@@ -1049,6 +1055,9 @@ type Store struct {
 	// tenantRateLimiters manages tenantrate.Limiters
 	tenantRateLimiters *tenantrate.LimiterFactory
 
+	// tableRateLimiters manages tablerate.Limiters
+	tableRateLimiters *tablerate.LimiterFactory
+
 	// eagerLeaseAcquisitionLimiter limits the number of concurrent eager lease
 	// acquisitions made during Raft ticks.
 	eagerLeaseAcquisitionLimiter *quotapool.IntPool
@@ -1168,6 +1177,11 @@ type StoreConfig struct {
 	// snapshot that are permitted to be sent concurrently.
 	SnapshotSendLimit int64
 
+	// SnapshotScheduler coordinates the bandwidth used by outgoing snapshots.
+	// Normally shared between all stores on a node. Can be nil, which disables
+	// the per-locality snapshot bandwidth budgets.
+	SnapshotScheduler *SnapshotScheduler
+
 	// HistogramWindowInterval is (server.Config).HistogramWindowInterval
 	HistogramWindowInterval time.Duration
 
@@ -1283,6 +1297,11 @@ func (sc *StoreConfig) SetDefaults(numStores int) {
 			sc.RaftEntryCacheSize /= uint64(numStores)
 		}
 	}
+	if sc.SnapshotScheduler == nil && sc.Settings != nil {
+		// SetDefaults is called once on the config shared by all stores on a
+		// node, so the scheduler is too.
+		sc.SnapshotScheduler = NewSnapshotScheduler(sc.Settings)
+	}
 	if raftDisableLeaderFollowsLeaseholder {
 		sc.TestingKnobs.DisableLeaderFollowsLeaseholder = true
 		sc.TestingKnobs.AllowLeaseRequestProposalsWhenNotLeader = true // otherwise lease requests fail
@@ -1532,6 +1551,9 @@ func NewStore(
 	s.tenantRateLimiters = tenantrate.NewLimiterFactory(&cfg.Settings.SV, &cfg.TestingKnobs.TenantRateKnobs, authorizer)
 	s.metrics.registry.AddMetricStruct(s.tenantRateLimiters.Metrics())
 
+	s.tableRateLimiters = tablerate.NewLimiterFactory(&cfg.Settings.SV, &cfg.TestingKnobs.TableRateKnobs)
+	s.metrics.registry.AddMetricStruct(s.tableRateLimiters.Metrics())
+
 	s.systemConfigUpdateQueueRateLimiter = quotapool.NewRateLimiter(
 		"SystemConfigUpdateQueue",
 		quotapool.Limit(queueAdditionOnSystemConfigUpdateRate.Get(&cfg.Settings.SV)),
@@ -2130,6 +2152,12 @@ func (s *Store) Start(ctx context.Context, stopper *stop.Stopper) error {
 		// running.
 		s.startGossip()
 
+		// Share the write demand of rate limited tables with the other stores.
+		s.startTableWriteDemandGossip(s.AnnotateCtx(context.Background()))
+
+		// Share the snapshots sent by this node with the other nodes.
+		s.startSnapshotSchedulerDemandGossip(s.AnnotateCtx(context.Background()))
+
 		// Start the scanner. The construction here makes sure that the scanner
 		// only starts after Gossip has connected, and that it does not block Start
 		// from returning (as doing so might prevent Gossip from ever connecting).
@@ -2963,6 +2991,7 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 		rangeCount                int64
 		unavailableRangeCount     int64
 		underreplicatedRangeCount int64
+		underreplicatedRangeBytes int64
 		overreplicatedRangeCount  int64
 		behindCount               int64
 		pausedFollowerCount       int64
@@ -3039,6 +3068,7 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 			}
 			if metrics.Underreplicated {
 				underreplicatedRangeCount++
+				underreplicatedRangeBytes += rep.GetMVCCStats().Total()
 			}
 			if metrics.Overreplicated {
 				overreplicatedRangeCount++
@@ -3104,6 +3134,16 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 	s.metrics.UnavailableRangeCount.Update(unavailableRangeCount)
 	s.metrics.UnderReplicatedRangeCount.Update(underreplicatedRangeCount)
 	s.metrics.OverReplicatedRangeCount.Update(overreplicatedRangeCount)
+	s.metrics.UnderReplicatedRangeBytes.Update(underreplicatedRangeBytes)
+	recoveryRate := s.reReplicationEstimator.update(
+		timeutil.Now(), s.metrics.RangeSnapshotRecoverySentBytes.Count())
+	if eta, ok := s.cfg.SnapshotScheduler.reReplicationETA(
+		s.StoreID(), underreplicatedRangeBytes, recoveryRate,
+	); ok {
+		s.metrics.UnderReplicatedRangeETA.Update(eta.Nanoseconds())
+	} else {
+		s.metrics.UnderReplicatedRangeETA.Update(-1)
+	}
 	s.metrics.RaftLogFollowerBehindCount.Update(behindCount)
 	s.metrics.RaftPausedFollowerCount.Update(pausedFollowerCount)
 	s.metrics.IOOverload.Update(ioOverload)
@@ -3362,6 +3402,9 @@ type HotReplicaInfo struct {
 	WriteBytesPerSecond float64
 	ReadBytesPerSecond  float64
 	CPUTimePerSecond    float64
+	// HotKeys are the keys which receive a large fraction of the load on the
+	// range, if any.
+	HotKeys []split.HotKey
 }
 
 // HottestReplicas returns the hottest replicas on a store, sorted by their
@@ -3394,6 +3437,9 @@ func mapToHotReplicasInfo(repls []CandidateReplica) []HotReplicaInfo {
 		hotRepls[i].WriteBytesPerSecond = ri.WriteBytesPerSecond
 		hotRepls[i].ReadBytesPerSecond = ri.ReadBytesPerSecond
 		hotRepls[i].CPUTimePerSecond = ri.RaftCPUNanosPerSecond + ri.RequestCPUNanosPerSecond
+		if repl := repls[i].Repl(); repl != nil {
+			hotRepls[i].HotKeys = repl.HotKeys(repl.AnnotateCtx(context.Background()))
+		}
 	}
 	return hotRepls
 }
@@ -3480,6 +3526,17 @@ func (s *Store) AllocatorCheckRange(
 		return action, roachpb.ReplicationTarget{}, sp.FinishAndGetConfiguredRecording(), err
 	}
 
+	if action.TargetReplicaType() == allocatorimpl.WitnessTarget {
+		target, _, err := s.allocator.AllocateWitness(ctx, storePool, conf,
+			desc.Replicas().VoterDescriptors(), desc.Replicas().NonVoterDescriptors(),
+			desc.Replicas().WitnessDescriptors(),
+		)
+		if err == nil {
+			log.Eventf(ctx, "found valid allocation of %s target %v", action.TargetReplicaType(), target)
+		}
+		return action, target, sp.FinishAndGetConfiguredRecording(), err
+	}
+
 	filteredVoters, filteredNonVoters, replacing, nothingToDo, err :=
 		allocatorimpl.FilterReplicasForAction(storePool, desc, action)
 
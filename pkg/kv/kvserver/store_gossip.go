@@ -20,6 +20,9 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/gossip"
 	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/demandgossip"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/tablerate"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/spanconfig/spanconfigstore"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
@@ -27,6 +30,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/retry"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/redact"
 )
@@ -505,3 +509,58 @@ func deltaExceedsThreshold(
 	exceeds = deltaAbsolute >= requiredMinDelta && deltaFraction >= requiredDeltaFraction
 	return exceeds, delta
 }
+
+// startTableWriteDemandGossip shares the write demand of the store's rate
+// limited tables with the other stores, which lets the stores holding leases
+// for the ranges of a table divide its write rate limits between them.
+func (s *Store) startTableWriteDemandGossip(ctx context.Context) {
+	demandgossip.Start(ctx, s.stopper, s.cfg.Gossip, demandgossip.Config{
+		Name:      "table write demand",
+		KeyPrefix: gossip.KeyTableWriteDemandPrefix,
+		ID:        int64(s.StoreID()),
+		Interval: func() time.Duration {
+			return tablerate.DemandInterval.Get(&s.ClusterSettings().SV)
+		},
+		Refresh: func() (protoutil.Message, bool) {
+			demand := s.tableRateLimiters.Refresh()
+			return &demand, len(demand.Entries) == 0
+		},
+		NewDemand: func() protoutil.Message { return &kvserverpb.TableWriteDemand{} },
+		UpdateRemote: func(id int64, demand protoutil.Message) {
+			s.tableRateLimiters.UpdateRemoteDemand(
+				roachpb.StoreID(id), demand.(*kvserverpb.TableWriteDemand))
+		},
+	})
+}
+
+// startSnapshotSchedulerDemandGossip shares the snapshot demand of the node
+// with the other nodes, which lets them divide the snapshot bandwidth budgets
+// of localities between them. The scheduler is shared by all stores on the
+// node, so this is a no-op for all but the first store started.
+func (s *Store) startSnapshotSchedulerDemandGossip(ctx context.Context) {
+	scheduler := s.cfg.SnapshotScheduler
+	if scheduler == nil {
+		return
+	}
+	scheduler.gossipOnce.Do(func() {
+		demandgossip.Start(ctx, s.stopper, s.cfg.Gossip, demandgossip.Config{
+			Name:      "snapshot demand",
+			KeyPrefix: gossip.KeySnapshotSchedulerDemandPrefix,
+			ID:        int64(s.NodeID()),
+			Interval: func() time.Duration {
+				return snapshotDemandInterval.Get(&s.ClusterSettings().SV)
+			},
+			Refresh: func() (protoutil.Message, bool) {
+				demand := scheduler.Refresh(timeutil.Now())
+				idle := len(demand.Localities) == 0 && demand.UnderReplicatedBytes == 0 &&
+					demand.RecoveryBytesPerSecond == 0
+				return &demand, idle
+			},
+			NewDemand: func() protoutil.Message { return &kvserverpb.SnapshotSchedulerDemand{} },
+			UpdateRemote: func(id int64, demand protoutil.Message) {
+				scheduler.UpdateRemoteDemand(timeutil.Now(), roachpb.NodeID(id),
+					demand.(*kvserverpb.SnapshotSchedulerDemand))
+			},
+		})
+	})
+}
@@ -161,6 +161,10 @@ type kvBatchSnapshotStrategy struct {
 	batchSize int64
 	// Limiter for sending KV batches. Only used on the sender side.
 	limiter *rate.Limiter
+	// Paces the sending of KV batches according to the bandwidth budget of the
+	// recipient's locality, in addition to limiter. Only used on the sender
+	// side, and may be nil.
+	bandwidth *snapshotBandwidthGrant
 	// Only used on the sender side.
 	newWriteBatch func() storage.WriteBatch
 
@@ -811,6 +815,13 @@ func (kvSS *kvBatchSnapshotStrategy) Send(
 	if sharedReplicate {
 		replicatedFilter = rditer.ReplicatedSpansExcludeUser
 	}
+	// Witnesses don't store user data, so we don't send them any. The receiver
+	// clears the entire keyspace of the replica before ingesting the snapshot,
+	// so any user data the recipient previously held is dropped as well.
+	if header.ExcludeUserData {
+		sharedReplicate = false
+		replicatedFilter = rditer.ReplicatedSpansExcludeUser
+	}
 
 	iterateRKSpansVisitor := func(iter storage.EngineIterator, _ roachpb.Span, keyType storage.IterKeyType) error {
 		timingTag.start("iter")
@@ -975,6 +986,9 @@ func (kvSS *kvBatchSnapshotStrategy) sendBatch(
 ) error {
 	timerTag.start("rateLimit")
 	err := kvSS.limiter.WaitN(ctx, 1)
+	if err == nil {
+		err = kvSS.bandwidth.wait(ctx)
+	}
 	timerTag.stop("rateLimit")
 	if err != nil {
 		return err
@@ -1020,6 +1034,7 @@ func (s *Store) reserveReceiveSnapshot(
 	return s.throttleSnapshot(ctx,
 		s.snapshotApplyQueue,
 		int(header.SenderQueueName),
+		snapshotPrecedence(s.ClusterSettings(), header.Priority),
 		header.SenderQueuePriority,
 		-1,
 		header.RangeSize,
@@ -1046,6 +1061,7 @@ func (s *Store) reserveSendSnapshot(
 	return s.throttleSnapshot(ctx,
 		s.snapshotSendQueue,
 		int(req.SenderQueueName),
+		snapshotPrecedence(s.ClusterSettings(), req.Priority),
 		req.SenderQueuePriority,
 		req.QueueOnDelegateLen,
 		rangeSize,
@@ -1066,6 +1082,7 @@ func (s *Store) throttleSnapshot(
 	ctx context.Context,
 	snapshotQueue *multiqueue.MultiQueue,
 	requestSource int,
+	requestPrecedence int,
 	requestPriority float64,
 	maxQueueLength int64,
 	rangeSize int64,
@@ -1080,7 +1097,9 @@ func (s *Store) throttleSnapshot(
 	// RESTORE or manual SPLIT AT, since it prevents these empty snapshots from
 	// getting stuck behind large snapshots managed by the replicate queue.
 	if rangeSize != 0 || s.cfg.TestingKnobs.ThrottleEmptySnapshots {
-		task, err := snapshotQueue.Add(requestSource, requestPriority, maxQueueLength)
+		task, err := snapshotQueue.AddWithPrecedence(
+			requestSource, requestPrecedence, requestPriority, maxQueueLength,
+		)
 		if err != nil {
 			return nil, err
 		}
@@ -1354,12 +1373,26 @@ func (s *Store) receiveSnapshot(
 
 	// Defensive check that any snapshot contains this store in the	descriptor.
 	storeID := s.StoreID()
-	if _, ok := header.State.Desc.GetReplicaDescriptor(storeID); !ok {
+	repDesc, ok := header.State.Desc.GetReplicaDescriptor(storeID)
+	if !ok {
 		return errors.AssertionFailedf(
 			`snapshot of type %s was sent to s%d which did not contain it as a replica: %s`,
 			header.Type, storeID, header.State.Desc.Replicas())
 	}
 
+	// A snapshot without user data can only be applied by a witness, or by the
+	// learner that is about to be promoted to one. Anything else would leave the
+	// replica without the range's data. Senders don't exclude user data until
+	// V23_2_WitnessReplicas is active, so receivers which don't know about the
+	// field never see such a snapshot.
+	if header.ExcludeUserData {
+		if typ := repDesc.Type; typ != roachpb.WITNESS && typ != roachpb.LEARNER {
+			return sendSnapshotError(ctx, s, stream, errors.AssertionFailedf(
+				`snapshot of type %s without user data was sent to %s replica on s%d`,
+				header.Type, typ, storeID))
+		}
+	}
+
 	cleanup, err := s.reserveReceiveSnapshot(ctx, header)
 	if err != nil {
 		return err
@@ -1805,6 +1838,7 @@ func SendEmptySnapshot(
 		eng.NewWriteBatch,
 		func() {},
 		nil, /* recordBytesSent */
+		nil, /* bandwidth */
 	); err != nil {
 		return err
 	}
@@ -1816,7 +1850,9 @@ type noopStorePool struct{}
 
 func (n noopStorePool) Throttle(storepool.ThrottleReason, string, roachpb.StoreID) {}
 
-// sendSnapshot sends an outgoing snapshot via a pre-opened GRPC stream.
+// sendSnapshot sends an outgoing snapshot via a pre-opened GRPC stream. The
+// snapshot is paced by the (optional) bandwidth grant in addition to the rate
+// limit set by kv.snapshot_rebalance.max_rate.
 func sendSnapshot(
 	ctx context.Context,
 	st *cluster.Settings,
@@ -1828,6 +1864,7 @@ func sendSnapshot(
 	newWriteBatch func() storage.WriteBatch,
 	sent func(),
 	recordBytesSent snapshotRecordMetrics,
+	bandwidth *snapshotBandwidthGrant,
 ) (*kvserverpb.SnapshotResponse, error) {
 	if recordBytesSent == nil {
 		// NB: Some tests and an offline tool (ResetQuorum) call into `sendSnapshotUsingDelegate`
@@ -1891,6 +1928,7 @@ func sendSnapshot(
 		ss = &kvBatchSnapshotStrategy{
 			batchSize:     batchSize,
 			limiter:       limiter,
+			bandwidth:     bandwidth,
 			newWriteBatch: newWriteBatch,
 			st:            st,
 		}
@@ -3125,7 +3125,7 @@ func TestSendSnapshotThrottling(t *testing.T) {
 		expectedErr := errors.New("")
 		c := fakeSnapshotStream{nil, expectedErr}
 		_, err := sendSnapshot(
-			ctx, st, tr, c, sp, header, nil /* snap */, newBatch, nil /* sent */, nil, /* recordBytesSent */
+			ctx, st, tr, c, sp, header, nil /* snap */, newBatch, nil /* sent */, nil /* recordBytesSent */, nil, /* bandwidth */
 		)
 		if sp.failedThrottles != 1 {
 			t.Fatalf("expected 1 failed throttle, but found %d", sp.failedThrottles)
@@ -3144,7 +3144,7 @@ func TestSendSnapshotThrottling(t *testing.T) {
 		}
 		c := fakeSnapshotStream{resp, nil}
 		_, err := sendSnapshot(
-			ctx, st, tr, c, sp, header, nil /* snap */, newBatch, nil /* sent */, nil, /* recordBytesSent */
+			ctx, st, tr, c, sp, header, nil /* snap */, newBatch, nil /* sent */, nil /* recordBytesSent */, nil, /* bandwidth */
 		)
 		if sp.failedThrottles != 1 {
 			t.Fatalf("expected 1 failed throttle, but found %d", sp.failedThrottles)
@@ -95,6 +95,12 @@ func (s *baseStore) SetQueueActive(active bool, queue string) error {
 	return nil
 }
 
+// RotateStoreEncryptionKey is part of kvserverbase.Store.
+func (s *baseStore) RotateStoreEncryptionKey(ctx context.Context) (string, error) {
+	store := (*Store)(s)
+	return store.TODOEngine().RotateStoreEncryptionKey(ctx)
+}
+
 // GetReplicaMutexForTesting is part of kvserverbase.Store.
 func (s *baseStore) GetReplicaMutexForTesting(rangeID roachpb.RangeID) *syncutil.RWMutex {
 	store := (*Store)(s)
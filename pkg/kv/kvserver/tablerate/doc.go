@@ -0,0 +1,20 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+// Package tablerate contains logic for rate limiting writes on a per-table
+// basis, according to the max_write_bytes_per_second and
+// max_write_requests_per_second zone configuration fields.
+//
+// The package exposes a LimiterFactory which hands out per-table Limiters.
+// The limits apply to the table as a whole, across all of the stores holding
+// leases for its ranges, so the factory periodically exchanges the write
+// demand it observes with the factories on other stores and divides each
+// table's limit among them. See the comment on LimiterFactory for details.
+package tablerate
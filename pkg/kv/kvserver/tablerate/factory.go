@@ -0,0 +1,243 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package tablerate
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/demandgossip"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+const (
+	// minShareFraction is the fraction of a table's limit that each store
+	// with a limiter for the table is entitled to, regardless of its demand.
+	// It lets a store that starts receiving writes for a table make progress
+	// before its demand is known to the other stores.
+	minShareFraction = 0.01
+
+	// demandSmoothing is the weight of the most recent measurement in the
+	// exponentially weighted moving average of a limiter's demand.
+	demandSmoothing = 0.5
+
+	// maxIdleRefreshes is the number of consecutive refreshes without any
+	// writes after which a limiter is discarded.
+	maxIdleRefreshes = 6
+)
+
+// TestingKnobs configures a LimiterFactory for testing.
+type TestingKnobs struct {
+	TimeSource timeutil.TimeSource
+}
+
+// LimiterFactory constructs and manages the per-table Limiters of a store.
+//
+// A table's write rate limits apply to the cluster as a whole, but writes are
+// admitted by the leaseholders of the table's ranges, which can be spread
+// over many stores. The factory divides each limit between the stores in
+// proportion to their recent demand: periodically, Refresh measures the rate
+// at which writes requested admission to each of the store's limiters, and
+// the resulting demand is gossiped to the other stores and reported to their
+// factories through UpdateRemoteDemand. A store whose demand is d_i is then
+// assigned limit*(d_i+f)/Σ(d_j+f) of the limit, where f is a small floor
+// which guarantees every store some budget. Since the portions sum up to the
+// limit, the table's aggregate write rate is bounded by its limit, up to the
+// staleness of the demand exchanged over gossip.
+type LimiterFactory struct {
+	sv      *settings.Values
+	knobs   TestingKnobs
+	metrics Metrics
+
+	mu struct {
+		syncutil.RWMutex
+		tables      map[Key]*Limiter
+		remote      demandgossip.Remote[roachpb.StoreID, map[Key]rates]
+		lastRefresh time.Time
+	}
+}
+
+// NewLimiterFactory constructs a new LimiterFactory.
+func NewLimiterFactory(sv *settings.Values, knobs *TestingKnobs) *LimiterFactory {
+	f := &LimiterFactory{
+		sv:      sv,
+		metrics: makeMetrics(),
+	}
+	if knobs != nil {
+		f.knobs = *knobs
+	}
+	f.mu.tables = make(map[Key]*Limiter)
+	f.mu.lastRefresh = f.now()
+	return f
+}
+
+func (f *LimiterFactory) now() time.Time {
+	if f.knobs.TimeSource != nil {
+		return f.knobs.TimeSource.Now()
+	}
+	return timeutil.Now()
+}
+
+// GetLimiter gets or creates the limiter for the given key. It returns nil if
+// the key's limits are unlimited or table rate limiting is disabled. If the
+// closer channel is non-nil, closing it will lead to any blocked requests
+// becoming unblocked.
+func (f *LimiterFactory) GetLimiter(
+	ctx context.Context, key Key, closer <-chan struct{},
+) *Limiter {
+	if key.Limits.IsUnlimited() || !Enabled.Get(f.sv) {
+		return nil
+	}
+
+	f.mu.RLock()
+	l, ok := f.mu.tables[key]
+	f.mu.RUnlock()
+	if ok {
+		return l
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if l, ok := f.mu.tables[key]; ok {
+		return l
+	}
+	var options []quotapool.Option
+	if f.knobs.TimeSource != nil {
+		options = append(options, quotapool.WithTimeSource(f.knobs.TimeSource))
+	}
+	if closer != nil {
+		options = append(options, quotapool.WithCloser(closer))
+	}
+	l = new(Limiter)
+	l.init(key, &f.metrics, options...)
+	f.mu.tables[key] = l
+	f.metrics.Tables.Update(int64(len(f.mu.tables)))
+	log.VEventf(
+		ctx, 1, "table %s rate limiter initialized (write bytes: %d/s; write requests: %d/s)",
+		key, key.Limits.WriteBytesPerSecond, key.Limits.WriteRequestsPerSecond,
+	)
+	return l
+}
+
+// Refresh measures the write demand of each limiter since the last call,
+// discards the limiters which have been idle for a while, and redistributes
+// the limits based on the latest local and remote demand. It returns the
+// local demand, to be shared with the other stores.
+func (f *LimiterFactory) Refresh() kvserverpb.TableWriteDemand {
+	now := f.now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	elapsed := now.Sub(f.mu.lastRefresh).Seconds()
+	f.mu.lastRefresh = now
+
+	f.mu.remote.Expire(now, DemandInterval.Get(f.sv))
+
+	var demand kvserverpb.TableWriteDemand
+	for key, l := range f.mu.tables {
+		bytes, requests := l.requestedBytes.Swap(0), l.requestedRequests.Swap(0)
+		if bytes == 0 && requests == 0 {
+			l.idleRefreshes++
+		} else {
+			l.idleRefreshes = 0
+		}
+		if l.idleRefreshes > maxIdleRefreshes {
+			// Any writes which are still blocked on the limiter will be admitted
+			// by it; later writes will get a new limiter.
+			delete(f.mu.tables, key)
+			continue
+		}
+		if elapsed > 0 {
+			l.bytesDemand = smooth(l.bytesDemand, float64(bytes)/elapsed)
+			l.requestsDemand = smooth(l.requestsDemand, float64(requests)/elapsed)
+		}
+		l.updateRate(f.shareLocked(key, l))
+
+		demand.Entries = append(demand.Entries, kvserverpb.TableWriteDemand_Entry{
+			TenantID:                  key.TenantID,
+			TableID:                   key.TableID,
+			MaxWriteBytesPerSecond:    key.Limits.WriteBytesPerSecond,
+			MaxWriteRequestsPerSecond: key.Limits.WriteRequestsPerSecond,
+			WriteBytesPerSecond:       l.bytesDemand,
+			WriteRequestsPerSecond:    l.requestsDemand,
+		})
+	}
+	f.metrics.Tables.Update(int64(len(f.mu.tables)))
+	return demand
+}
+
+// UpdateRemoteDemand records the demand reported by another store. It takes
+// effect on the next call to Refresh.
+func (f *LimiterFactory) UpdateRemoteDemand(
+	storeID roachpb.StoreID, demand *kvserverpb.TableWriteDemand,
+) {
+	tables := make(map[Key]rates, len(demand.Entries))
+	for _, e := range demand.Entries {
+		key := Key{
+			TenantID: e.TenantID,
+			TableID:  e.TableID,
+			Limits: Limits{
+				WriteBytesPerSecond:    e.MaxWriteBytesPerSecond,
+				WriteRequestsPerSecond: e.MaxWriteRequestsPerSecond,
+			},
+		}
+		tables[key] = rates{bytes: e.WriteBytesPerSecond, requests: e.WriteRequestsPerSecond}
+	}
+	now := f.now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mu.remote.Update(now, storeID, tables)
+}
+
+// shareLocked returns the portion of the key's limits assigned to the store.
+func (f *LimiterFactory) shareLocked(key Key, l *Limiter) rates {
+	var remoteBytes, remoteRequests []float64
+	f.mu.remote.Range(func(_ roachpb.StoreID, tables map[Key]rates) {
+		if d, ok := tables[key]; ok {
+			remoteBytes = append(remoteBytes, d.bytes)
+			remoteRequests = append(remoteRequests, d.requests)
+		}
+	})
+	return rates{
+		bytes:    share(key.Limits.WriteBytesPerSecond, l.bytesDemand, remoteBytes),
+		requests: share(key.Limits.WriteRequestsPerSecond, l.requestsDemand, remoteRequests),
+	}
+}
+
+// share returns the portion of the limit assigned to a store with the given
+// demand, when the limit is shared with stores with the remote demands.
+func share(limit int64, local float64, remote []float64) float64 {
+	if limit <= 0 {
+		return 0
+	}
+	floor := float64(limit) * minShareFraction
+	total := local + floor
+	for _, d := range remote {
+		total += d + floor
+	}
+	return float64(limit) * (local + floor) / total
+}
+
+func smooth(prev, cur float64) float64 {
+	return demandSmoothing*cur + (1-demandSmoothing)*prev
+}
+
+// Metrics returns the LimiterFactory's metric.Struct.
+func (f *LimiterFactory) Metrics() *Metrics {
+	return &f.metrics
+}
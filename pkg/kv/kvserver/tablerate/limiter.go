@@ -0,0 +1,248 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package tablerate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/tokenbucket"
+)
+
+// Limits are the write rate limits configured for a table. A non-positive
+// value means that the corresponding dimension is unlimited.
+type Limits struct {
+	WriteBytesPerSecond    int64
+	WriteRequestsPerSecond int64
+}
+
+// IsUnlimited returns true if neither dimension is limited.
+func (l Limits) IsUnlimited() bool {
+	return l.WriteBytesPerSecond <= 0 && l.WriteRequestsPerSecond <= 0
+}
+
+// Key identifies the write budget shared by the ranges of a table. Ranges of
+// the same table share a budget as long as they're configured with the same
+// limits; an index or partition configured with different limits than the
+// rest of the table gets a budget of its own.
+type Key struct {
+	TenantID roachpb.TenantID
+	TableID  uint32
+	Limits   Limits
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%s/%d", k.TenantID, k.TableID)
+}
+
+// Limiter is used to rate-limit the writes to a table on a store.
+//
+// The limiter is implemented as a two-dimensional token bucket, with one
+// dimension for write requests and one for written bytes. The rate of each
+// dimension is this store's portion of the table's limit, as assigned by the
+// LimiterFactory, and the burst is one second's worth of that rate. A batch
+// that writes more than the burst may proceed once the bucket is full, which
+// puts the limiter into debt.
+//
+// The Limiter is backed by a FIFO queue which provides fairness.
+type Limiter struct {
+	key     Key
+	qp      *quotapool.AbstractPool
+	metrics *Metrics
+
+	// requestedBytes and requestedRequests accumulate the writes which asked
+	// to be admitted since the last refresh, whether or not they had to wait.
+	// They're drained by the LimiterFactory to measure demand.
+	requestedBytes    atomic.Int64
+	requestedRequests atomic.Int64
+
+	// The fields below are only accessed by the LimiterFactory under its
+	// mutex.
+
+	// bytesDemand and requestsDemand are the smoothed per-second rates at which
+	// writes requested admission.
+	bytesDemand, requestsDemand float64
+	// idleRefreshes is the number of consecutive refreshes during which no
+	// writes requested admission.
+	idleRefreshes int
+	// rate is the portion of the limits currently assigned to the limiter.
+	rate rates
+}
+
+// rates are the per-second rates of the two dimensions of a Limiter. A
+// non-positive rate means that the dimension is unlimited.
+type rates struct {
+	bytes, requests float64
+}
+
+func (l *Limiter) init(key Key, metrics *Metrics, options ...quotapool.Option) {
+	*l = Limiter{
+		key:     key,
+		metrics: metrics,
+	}
+	buckets := &tokenBuckets{}
+	options = append(options,
+		quotapool.OnWaitStart(
+			func(ctx context.Context, poolName string, r quotapool.Request) {
+				l.metrics.CurrentBlocked.Inc(1)
+				l.metrics.BatchesThrottled.Inc(1)
+			}),
+		quotapool.OnWaitFinish(
+			func(ctx context.Context, poolName string, r quotapool.Request, _ time.Time) {
+				l.metrics.CurrentBlocked.Dec(1)
+			}),
+	)
+	l.qp = quotapool.New(key.String(), buckets, options...)
+	// Until the first refresh, assume that this store holds all of the
+	// table's leases.
+	l.rate = rates{
+		bytes:    float64(key.Limits.WriteBytesPerSecond),
+		requests: float64(key.Limits.WriteRequestsPerSecond),
+	}
+	buckets.init(l.rate, l.qp.TimeSource())
+}
+
+// Wait acquires the quota necessary to admit a write batch with the given
+// number of write requests and written bytes. The acquisition cannot be
+// released. The only errors which should be returned are due to the context.
+func (l *Limiter) Wait(ctx context.Context, writeCount, writeBytes int64) error {
+	l.requestedRequests.Add(writeCount)
+	l.requestedBytes.Add(writeBytes)
+
+	r := newWaitRequest(writeCount, writeBytes)
+	defer putWaitRequest(r)
+	if err := l.qp.Acquire(ctx, r); err != nil {
+		return err
+	}
+
+	l.metrics.BatchesAdmitted.Inc(1)
+	l.metrics.RequestsAdmitted.Inc(writeCount)
+	l.metrics.BytesAdmitted.Inc(writeBytes)
+	return nil
+}
+
+// Key returns the key of the budget that the Limiter enforces.
+func (l *Limiter) Key() Key {
+	return l.key
+}
+
+// updateRate changes the rates of the limiter.
+func (l *Limiter) updateRate(rate rates) {
+	l.rate = rate
+	l.qp.Update(func(res quotapool.Resource) (shouldNotify bool) {
+		res.(*tokenBuckets).updateConfig(rate)
+		return true
+	})
+}
+
+// tokenBuckets holds the token buckets for written bytes and write requests.
+// It implements quotapool.Resource.
+type tokenBuckets struct {
+	bytes    tokenbucket.TokenBucket
+	requests tokenbucket.TokenBucket
+	rate     rates
+}
+
+var _ quotapool.Resource = (*tokenBuckets)(nil)
+
+func (tb *tokenBuckets) init(rate rates, timeSource timeutil.TimeSource) {
+	// Unlimited dimensions are still initialized with a positive rate so that
+	// they're well-formed if they're limited later on.
+	tb.bytes.InitWithNowFn(tokensPerSecond(rate.bytes), tokens(rate.bytes), timeSource.Now)
+	tb.requests.InitWithNowFn(tokensPerSecond(rate.requests), tokens(rate.requests), timeSource.Now)
+	tb.rate = rate
+}
+
+func (tb *tokenBuckets) updateConfig(rate rates) {
+	tb.bytes.UpdateConfig(tokensPerSecond(rate.bytes), tokens(rate.bytes))
+	tb.requests.UpdateConfig(tokensPerSecond(rate.requests), tokens(rate.requests))
+	tb.rate = rate
+}
+
+// tryToFulfill removes the given amounts from both buckets if they're
+// available, or returns a time after which the request should be retried.
+func (tb *tokenBuckets) tryToFulfill(
+	writeCount, writeBytes int64,
+) (fulfilled bool, tryAgainAfter time.Duration) {
+	limitBytes, limitRequests := tb.rate.bytes > 0, tb.rate.requests > 0
+	if limitBytes {
+		if ok, after := tb.bytes.TryToFulfill(tokenbucket.Tokens(writeBytes)); !ok {
+			return false, after
+		}
+	}
+	if limitRequests {
+		if ok, after := tb.requests.TryToFulfill(tokenbucket.Tokens(writeCount)); !ok {
+			// Return the bytes, so that the request acquires from both buckets or
+			// from neither.
+			if limitBytes {
+				tb.bytes.Adjust(tokenbucket.Tokens(writeBytes))
+			}
+			return false, after
+		}
+	}
+	return true, 0
+}
+
+func tokensPerSecond(rate float64) tokenbucket.TokensPerSecond {
+	if rate <= 0 {
+		return 1
+	}
+	return tokenbucket.TokensPerSecond(rate)
+}
+
+func tokens(rate float64) tokenbucket.Tokens {
+	if rate <= 0 {
+		return 1
+	}
+	return tokenbucket.Tokens(rate)
+}
+
+// waitRequest is used to wait for adequate resources in the tokenBuckets.
+type waitRequest struct {
+	writeCount, writeBytes int64
+}
+
+var _ quotapool.Request = (*waitRequest)(nil)
+
+var waitRequestSyncPool = sync.Pool{
+	New: func() interface{} { return new(waitRequest) },
+}
+
+// newWaitRequest allocates a waitRequest from the sync.Pool.
+// It should be returned with putWaitRequest.
+func newWaitRequest(writeCount, writeBytes int64) *waitRequest {
+	r := waitRequestSyncPool.Get().(*waitRequest)
+	*r = waitRequest{writeCount: writeCount, writeBytes: writeBytes}
+	return r
+}
+
+func putWaitRequest(r *waitRequest) {
+	*r = waitRequest{}
+	waitRequestSyncPool.Put(r)
+}
+
+// Acquire is part of quotapool.Request.
+func (req *waitRequest) Acquire(
+	ctx context.Context, res quotapool.Resource,
+) (fulfilled bool, tryAgainAfter time.Duration) {
+	return res.(*tokenBuckets).tryToFulfill(req.writeCount, req.writeBytes)
+}
+
+// ShouldWait is part of quotapool.Request.
+func (req *waitRequest) ShouldWait() bool {
+	return true
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package tablerate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/demandgossip"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShare(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		name   string
+		limit  int64
+		local  float64
+		remote []float64
+		exp    float64
+	}{
+		{name: "unlimited", limit: 0, local: 10, exp: 0},
+		{name: "alone", limit: 100, local: 0, exp: 100},
+		{name: "alone with demand", limit: 100, local: 500, exp: 100},
+		{name: "all idle", limit: 100, remote: []float64{0, 0, 0}, exp: 25},
+		{name: "equal demand", limit: 100, local: 50, remote: []float64{50}, exp: 50},
+		{name: "idle local", limit: 100, local: 0, remote: []float64{99}, exp: 1},
+		{name: "proportional", limit: 100, local: 299, remote: []float64{99}, exp: 75},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.InDelta(t, tc.exp, share(tc.limit, tc.local, tc.remote), 1e-9)
+		})
+	}
+}
+
+func TestLimiterFactory(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	timeSource := timeutil.NewManualTime(timeutil.Unix(0, 0))
+	f := NewLimiterFactory(&st.SV, &TestingKnobs{TimeSource: timeSource})
+
+	tenantID := roachpb.MustMakeTenantID(10)
+	key := Key{TenantID: tenantID, TableID: 104, Limits: Limits{
+		WriteBytesPerSecond:    1000,
+		WriteRequestsPerSecond: 10,
+	}}
+	require.Nil(t, f.GetLimiter(ctx, Key{TenantID: tenantID, TableID: 104}, nil /* closer */))
+	l := f.GetLimiter(ctx, key, nil /* closer */)
+	require.NotNil(t, l)
+	require.Same(t, l, f.GetLimiter(ctx, key, nil /* closer */))
+	require.EqualValues(t, 1, f.Metrics().Tables.Value())
+
+	// A different set of limits for the same table gets its own budget.
+	otherKey := key
+	otherKey.Limits.WriteRequestsPerSecond = 0
+	require.NotSame(t, l, f.GetLimiter(ctx, otherKey, nil /* closer */))
+
+	Enabled.Override(ctx, &st.SV, false)
+	require.Nil(t, f.GetLimiter(ctx, key, nil /* closer */))
+	Enabled.Override(ctx, &st.SV, true)
+
+	// The burst is a second's worth of the limit; the request that exhausts
+	// the bytes bucket must wait for it to refill.
+	require.NoError(t, l.Wait(ctx, 1, 1000))
+	errCh := make(chan error, 1)
+	go func() { errCh <- l.Wait(ctx, 1, 500) }()
+	testutils.SucceedsSoon(t, func() error {
+		if timers := timeSource.Timers(); len(timers) != 1 {
+			return errors.Errorf("expected 1 timer, found %d", len(timers))
+		}
+		return nil
+	})
+	require.EqualValues(t, 1, f.Metrics().CurrentBlocked.Value())
+	timeSource.Advance(500 * time.Millisecond)
+	require.NoError(t, <-errCh)
+	require.EqualValues(t, 0, f.Metrics().CurrentBlocked.Value())
+	require.EqualValues(t, 2, f.Metrics().BatchesAdmitted.Count())
+	require.EqualValues(t, 1, f.Metrics().BatchesThrottled.Count())
+	require.EqualValues(t, 1500, f.Metrics().BytesAdmitted.Count())
+
+	// Another store with the same demand gets half of the limit. Stores with
+	// demand for other tables or limits don't matter.
+	timeSource.Advance(500 * time.Millisecond)
+	demand := f.Refresh()
+	require.Len(t, demand.Entries, 2)
+	for _, e := range demand.Entries {
+		if e.MaxWriteRequestsPerSecond == key.Limits.WriteRequestsPerSecond {
+			require.InDelta(t, 750, e.WriteBytesPerSecond, 1e-9)
+			require.InDelta(t, 1, e.WriteRequestsPerSecond, 1e-9)
+		}
+	}
+	f.UpdateRemoteDemand(2, &kvserverpb.TableWriteDemand{
+		Entries: []kvserverpb.TableWriteDemand_Entry{{
+			TenantID:                  tenantID,
+			TableID:                   104,
+			MaxWriteBytesPerSecond:    1000,
+			MaxWriteRequestsPerSecond: 10,
+			WriteBytesPerSecond:       750,
+			WriteRequestsPerSecond:    1,
+		}, {
+			TenantID:                  tenantID,
+			TableID:                   105,
+			MaxWriteBytesPerSecond:    1000,
+			MaxWriteRequestsPerSecond: 10,
+			WriteBytesPerSecond:       1e6,
+			WriteRequestsPerSecond:    1e3,
+		}},
+	})
+	timeSource.Advance(time.Second)
+	f.Refresh()
+	// The local demand decayed to 375 bytes/s and 0.5 requests/s.
+	require.InDelta(t, 1000*(375.0+10)/(375+10+750+10), l.rate.bytes, 1e-9)
+	require.InDelta(t, 10*(0.5+0.1)/(0.5+0.1+1+0.1), l.rate.requests, 1e-9)
+
+	// Remote demand expires if the other store stops reporting it.
+	timeSource.Advance(demandgossip.ExpirationIntervals*DemandInterval.Get(&st.SV) + time.Second)
+	require.NoError(t, l.Wait(ctx, 1, 1))
+	f.Refresh()
+	require.InDelta(t, 1000, l.rate.bytes, 1e-9)
+
+	// Idle limiters are eventually discarded.
+	for i := 0; i <= maxIdleRefreshes; i++ {
+		timeSource.Advance(DemandInterval.Get(&st.SV))
+		f.Refresh()
+	}
+	require.EqualValues(t, 0, f.Metrics().Tables.Value())
+	require.NotSame(t, l, f.GetLimiter(ctx, key, nil /* closer */))
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package tablerate
+
+import "github.com/cockroachdb/cockroach/pkg/util/metric"
+
+// Metrics is a metric.Struct for the LimiterFactory.
+//
+// Unlike the tenant rate limiter, the metrics are not broken down by table,
+// since the number of rate limited tables is unbounded.
+type Metrics struct {
+	Tables           *metric.Gauge
+	CurrentBlocked   *metric.Gauge
+	BatchesAdmitted  *metric.Counter
+	BatchesThrottled *metric.Counter
+	RequestsAdmitted *metric.Counter
+	BytesAdmitted    *metric.Counter
+}
+
+var _ metric.Struct = (*Metrics)(nil)
+
+var (
+	metaTables = metric.Metadata{
+		Name:        "kv.table_rate_limit.num_tables",
+		Help:        "Number of rate limited tables currently being tracked",
+		Measurement: "Tables",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaCurrentBlocked = metric.Metadata{
+		Name:        "kv.table_rate_limit.current_blocked",
+		Help:        "Number of write batches currently blocked by the table rate limiter",
+		Measurement: "Requests",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaBatchesAdmitted = metric.Metadata{
+		Name:        "kv.table_rate_limit.write_batches_admitted",
+		Help:        "Number of write batches admitted by the table rate limiter",
+		Measurement: "Requests",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaBatchesThrottled = metric.Metadata{
+		Name:        "kv.table_rate_limit.write_batches_throttled",
+		Help:        "Number of write batches that had to wait for the table rate limiter",
+		Measurement: "Requests",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRequestsAdmitted = metric.Metadata{
+		Name:        "kv.table_rate_limit.write_requests_admitted",
+		Help:        "Number of write requests admitted by the table rate limiter",
+		Measurement: "Requests",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaBytesAdmitted = metric.Metadata{
+		Name:        "kv.table_rate_limit.write_bytes_admitted",
+		Help:        "Number of write bytes admitted by the table rate limiter",
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+)
+
+func makeMetrics() Metrics {
+	return Metrics{
+		Tables:           metric.NewGauge(metaTables),
+		CurrentBlocked:   metric.NewGauge(metaCurrentBlocked),
+		BatchesAdmitted:  metric.NewCounter(metaBatchesAdmitted),
+		BatchesThrottled: metric.NewCounter(metaBatchesThrottled),
+		RequestsAdmitted: metric.NewCounter(metaRequestsAdmitted),
+		BytesAdmitted:    metric.NewCounter(metaBytesAdmitted),
+	}
+}
+
+// MetricStruct indicates that Metrics is a metric.Struct
+func (m *Metrics) MetricStruct() {}
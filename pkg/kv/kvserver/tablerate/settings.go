@@ -0,0 +1,39 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package tablerate
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+)
+
+// Enabled controls whether the write rate limits configured on tables are
+// enforced. Disabling it is an escape hatch; the limits are off by default
+// for every table regardless.
+var Enabled = settings.RegisterBoolSetting(
+	settings.SystemOnly,
+	"kv.table_rate_limit.enabled",
+	"if set, the per-table write rate limits configured in zone configurations are enforced",
+	true,
+)
+
+// DemandInterval is the interval at which each store measures the write
+// demand of its rate limited tables, shares it with the other stores, and
+// recomputes its portion of each table's limit.
+var DemandInterval = settings.RegisterDurationSetting(
+	settings.SystemOnly,
+	"kv.table_rate_limit.demand_interval",
+	"the interval at which stores exchange the write demand of rate limited tables "+
+		"and redistribute the limits between them",
+	10*time.Second,
+	settings.PositiveDuration,
+)
@@ -164,6 +164,8 @@ func createPushTxnResponse(txn *roachpb.Transaction) *kvpb.PushTxnResponse {
 // dependency cycles.
 type waitingPush struct {
 	req *kvpb.PushTxnRequest
+	// start is the time at which the push began waiting in the queue.
+	start time.Time
 	// pending channel receives updated, pushed txn or nil if queue is cleared.
 	pending chan *roachpb.Transaction
 	mu      struct {
@@ -516,6 +518,7 @@ func (q *Queue) MaybeWaitForPush(
 
 	push := &waitingPush{
 		req:     req,
+		start:   timeutil.Now(),
 		pending: make(chan *roachpb.Transaction, 1),
 	}
 	pushElem := pending.waitingPushes.PushBack(push)
@@ -1022,6 +1025,42 @@ func (q *Queue) forcePushAbort(
 	return b.RawResponse().Responses[0].GetPushTxn(), nil
 }
 
+// QueryWaitingPushes returns information about each PushTxn request from a
+// transactional pusher that is currently waiting in the queue on a pushee whose
+// transaction record is anchored in the specified span. Requests from
+// non-transactional pushers are omitted.
+func (q *Queue) QueryWaitingPushes(span roachpb.Span) []roachpb.TxnWaitInfo {
+	now := timeutil.Now()
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.mu.txns == nil {
+		return nil
+	}
+	var infos []roachpb.TxnWaitInfo
+	for _, pending := range q.mu.txns {
+		if pending.waitingPushes == nil {
+			continue
+		}
+		pushee := pending.getTxn().TxnMeta
+		if !span.ContainsKey(pushee.Key) {
+			continue
+		}
+		for e := pending.waitingPushes.Front(); e != nil; e = e.Next() {
+			push := e.Value.(*waitingPush)
+			if push.req.PusherTxn.ID == uuid.Nil {
+				continue
+			}
+			infos = append(infos, roachpb.TxnWaitInfo{
+				RangeID:      q.cfg.RangeDesc.RangeID,
+				Pushee:       pushee,
+				Pusher:       push.req.PusherTxn.TxnMeta,
+				WaitDuration: now.Sub(push.start),
+			})
+		}
+	}
+	return infos
+}
+
 // TrackedTxns returns a (newly minted) set containing the transaction IDs which
 // are being tracked (i.e. waited on).
 //
@@ -1589,6 +1589,12 @@ func confChangeImpl(
 			if err := checkNotExists(rDesc); err != nil {
 				return nil, err
 			}
+		case WITNESS:
+			// Witnesses are removed directly, without going through joint
+			// consensus, so the target should be gone from the descriptor.
+			if err := checkNotExists(rDesc); err != nil {
+				return nil, err
+			}
 		default:
 			return nil, errors.Errorf("can't remove replica in state %v", rDesc.Type)
 		}
@@ -1611,6 +1617,10 @@ func confChangeImpl(
 			// We're adding a voter, but will transition into a joint config
 			// first.
 			changeType = raftpb.ConfChangeAddNode
+		case WITNESS:
+			// We're promoting a learner to a witness. Witnesses are added without
+			// a joint config.
+			changeType = raftpb.ConfChangeAddNode
 		case LEARNER, NON_VOTER:
 			// We're adding a learner or non-voter.
 			// Note that we're guaranteed by virtue of the upstream ChangeReplicas txn
@@ -433,7 +433,7 @@ func (r ReplicaSet) GetReplicaDescriptorByID(id ReplicaID) (repDesc ReplicaDescr
 // ReplicaDescriptors.Filter(ReplicaDescriptor.IsVoterOldConfig).
 func (r ReplicaDescriptor) IsVoterOldConfig() bool {
 	switch r.Type {
-	case VOTER_FULL, VOTER_OUTGOING, VOTER_DEMOTING_NON_VOTER, VOTER_DEMOTING_LEARNER:
+	case VOTER_FULL, VOTER_OUTGOING, VOTER_DEMOTING_NON_VOTER, VOTER_DEMOTING_LEARNER, WITNESS:
 		return true
 	default:
 		return false
@@ -446,7 +446,7 @@ func (r ReplicaDescriptor) IsVoterOldConfig() bool {
 // ReplicaDescriptors.Filter(ReplicaDescriptor.IsVoterOldConfig).
 func (r ReplicaDescriptor) IsVoterNewConfig() bool {
 	switch r.Type {
-	case VOTER_FULL, VOTER_INCOMING:
+	case VOTER_FULL, VOTER_INCOMING, WITNESS:
 		return true
 	default:
 		return false
@@ -458,7 +458,7 @@ func (r ReplicaDescriptor) IsVoterNewConfig() bool {
 // for ReplicaDescriptors.Filter(ReplicaDescriptor.IsVoterOldConfig).
 func (r ReplicaDescriptor) IsAnyVoter() bool {
 	switch r.Type {
-	case VOTER_FULL, VOTER_INCOMING, VOTER_OUTGOING, VOTER_DEMOTING_NON_VOTER, VOTER_DEMOTING_LEARNER:
+	case VOTER_FULL, VOTER_INCOMING, VOTER_OUTGOING, VOTER_DEMOTING_NON_VOTER, VOTER_DEMOTING_LEARNER, WITNESS:
 		return true
 	default:
 		return false
@@ -476,6 +476,13 @@ func (r ReplicaDescriptor) IsNonVoter() bool {
 	}
 }
 
+// IsWitness returns true if the replica is a witness. Witnesses are voters
+// for the purposes of raft, but store no user data. Can be used as a filter
+// for ReplicaDescriptors.Filter.
+func (r ReplicaDescriptor) IsWitness() bool {
+	return r.Type == WITNESS
+}
+
 // PercentilesFromData derives percentiles from a slice of data points.
 // Sorts the input data if it isn't already sorted.
 func PercentilesFromData(data []float64) Percentiles {
@@ -89,12 +89,16 @@ func predNonVoter(rDesc ReplicaDescriptor) bool {
 	return rDesc.Type == NON_VOTER
 }
 
+func predWitness(rDesc ReplicaDescriptor) bool {
+	return rDesc.Type == WITNESS
+}
+
 func predVoterOrNonVoter(rDesc ReplicaDescriptor) bool {
 	return predVoterFullOrIncoming(rDesc) || predNonVoter(rDesc)
 }
 
-func predVoterFullOrNonVoter(rDesc ReplicaDescriptor) bool {
-	return predVoterFull(rDesc) || predNonVoter(rDesc)
+func predVoterFullOrNonVoterOrWitness(rDesc ReplicaDescriptor) bool {
+	return predVoterFull(rDesc) || predNonVoter(rDesc) || predWitness(rDesc)
 }
 
 // Voters returns a ReplicaSet of current and future voter replicas in `d`. This
@@ -226,13 +230,29 @@ func (d ReplicaSet) NonVoterDescriptors() []ReplicaDescriptor {
 	return d.FilterToDescriptors(predNonVoter)
 }
 
+// Witnesses returns a ReplicaSet containing only the witnesses in `d`.
+// Witnesses are voters as far as raft is concerned: they campaign, vote and
+// count towards the quorum(s). However, they only apply the range-local
+// portion of committed entries and store no user data. For this reason, they
+// are not part of Voters(), which many callers use to find replicas that can
+// serve traffic or hold the lease; callers that care about raft membership
+// must consult the ReplicaDescriptor.IsVoter* predicates instead.
+func (d ReplicaSet) Witnesses() ReplicaSet {
+	return d.Filter(predWitness)
+}
+
+// WitnessDescriptors returns the witness replica descriptors in the set.
+func (d ReplicaSet) WitnessDescriptors() []ReplicaDescriptor {
+	return d.FilterToDescriptors(predWitness)
+}
+
 // VoterFullAndNonVoterDescriptors returns the descriptors of
-// VOTER_FULL/NON_VOTER replicas in the set. This set will not contain learners
-// or, during an atomic replication change, incoming or outgoing voters.
-// Notably, this set must encapsulate all replicas of a range for a range merge
-// to proceed.
+// VOTER_FULL/NON_VOTER/WITNESS replicas in the set. This set will not contain
+// learners or, during an atomic replication change, incoming or outgoing
+// voters. Notably, this set must encapsulate all replicas of a range for a
+// range merge to proceed.
 func (d ReplicaSet) VoterFullAndNonVoterDescriptors() []ReplicaDescriptor {
-	return d.FilterToDescriptors(predVoterFullOrNonVoter)
+	return d.FilterToDescriptors(predVoterFullOrNonVoterOrWitness)
 }
 
 // VoterAndNonVoterDescriptors returns the descriptors of VOTER_FULL,
@@ -322,7 +342,7 @@ func (d ReplicaSet) InAtomicReplicationChange() bool {
 		case VOTER_INCOMING, VOTER_OUTGOING, VOTER_DEMOTING_LEARNER,
 			VOTER_DEMOTING_NON_VOTER:
 			return true
-		case VOTER_FULL, LEARNER, NON_VOTER:
+		case VOTER_FULL, LEARNER, NON_VOTER, WITNESS:
 		default:
 			panic(fmt.Sprintf("unknown replica type %d", rDesc.Type))
 		}
@@ -340,7 +360,7 @@ func (d ReplicaSet) ConfState() raftpb.ConfState {
 	for _, rep := range d.wrapped {
 		id := uint64(rep.ReplicaID)
 		switch rep.Type {
-		case VOTER_FULL:
+		case VOTER_FULL, WITNESS:
 			cs.Voters = append(cs.Voters, id)
 			if joint {
 				cs.VotersOutgoing = append(cs.VotersOutgoing, id)
@@ -448,11 +468,21 @@ func (d ReplicaSet) ReplicationStatus(
 	res.Available = availableIncomingGroup && availableOutgoingGroup
 
 	// Determine over/under-replication of voting replicas. Note that learners
-	// don't matter.
-	underReplicatedOldGroup := len(liveVotersOldGroup) < neededVoters
-	underReplicatedNewGroup := len(liveVotersNewGroup) < neededVoters
-	overReplicatedOldGroup := len(votersOldGroup) > neededVoters
-	overReplicatedNewGroup := len(votersNewGroup) > neededVoters
+	// don't matter, and neither do witnesses, which don't hold a copy of the
+	// data.
+	numNonWitnesses := func(descs []ReplicaDescriptor) int {
+		n := len(descs)
+		for _, rDesc := range descs {
+			if rDesc.IsWitness() {
+				n--
+			}
+		}
+		return n
+	}
+	underReplicatedOldGroup := numNonWitnesses(liveVotersOldGroup) < neededVoters
+	underReplicatedNewGroup := numNonWitnesses(liveVotersNewGroup) < neededVoters
+	overReplicatedOldGroup := numNonWitnesses(votersOldGroup) > neededVoters
+	overReplicatedNewGroup := numNonWitnesses(votersNewGroup) > neededVoters
 	res.UnderReplicated = underReplicatedOldGroup || underReplicatedNewGroup
 	res.OverReplicated = overReplicatedOldGroup || overReplicatedNewGroup
 	if neededNonVoters == -1 {
@@ -506,9 +536,9 @@ func (d ReplicaSet) Subtract(o ReplicaSet) []ReplicaDescriptor {
 // IsAddition returns true if `c` refers to a replica addition operation.
 func (c ReplicaChangeType) IsAddition() bool {
 	switch c {
-	case ADD_NON_VOTER, ADD_VOTER:
+	case ADD_NON_VOTER, ADD_VOTER, ADD_WITNESS:
 		return true
-	case REMOVE_NON_VOTER, REMOVE_VOTER:
+	case REMOVE_NON_VOTER, REMOVE_VOTER, REMOVE_WITNESS:
 		return false
 	default:
 		panic(fmt.Sprintf("unexpected ReplicaChangeType %s", c))
@@ -518,9 +548,9 @@ func (c ReplicaChangeType) IsAddition() bool {
 // IsRemoval returns true if `c` refers a replica removal operation.
 func (c ReplicaChangeType) IsRemoval() bool {
 	switch c {
-	case ADD_NON_VOTER, ADD_VOTER:
+	case ADD_NON_VOTER, ADD_VOTER, ADD_WITNESS:
 		return false
-	case REMOVE_NON_VOTER, REMOVE_VOTER:
+	case REMOVE_NON_VOTER, REMOVE_VOTER, REMOVE_WITNESS:
 		return true
 	default:
 		panic(fmt.Sprintf("unexpected ReplicaChangeType %s", c))
@@ -557,6 +587,8 @@ var ErrReplicaCannotHoldLease = errors.New(`lease target replica cannot hold lea
 // aren't, the CAS call for extending the lease will fail (see
 // wasLastLeaseholder := isExtension in cmd_lease_request.go).
 //
+// Witnesses never receive the lease since they don't hold any user data.
+//
 // An error is also returned is the replica is not part of `replDescs`.
 // NB: This logic should be in sync with constraint_stats_report as report
 // will check voter constraint violations. When changing this method, you need
@@ -568,6 +600,9 @@ func CheckCanReceiveLease(
 	if !ok {
 		return ErrReplicaNotFound
 	}
+	if repDesc.IsWitness() {
+		return ErrReplicaCannotHoldLease
+	}
 	if !(repDesc.IsVoterNewConfig() ||
 		(repDesc.IsVoterOldConfig() && replDescs.containsVoterIncoming() && wasLastLeaseholder)) {
 		// We allow a demoting / incoming voter to receive the lease if there's an incoming voter.
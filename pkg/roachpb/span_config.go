@@ -77,6 +77,15 @@ func (s *SpanConfig) ValidateSystemTargetSpanConfig() error {
 	if s.NumVoters != 0 {
 		return errors.AssertionFailedf("NumVoters set on system span config")
 	}
+	if s.NumWitnesses != 0 {
+		return errors.AssertionFailedf("NumWitnesses set on system span config")
+	}
+	if s.MaxWriteBytesPerSecond != 0 {
+		return errors.AssertionFailedf("MaxWriteBytesPerSecond set on system span config")
+	}
+	if s.MaxWriteRequestsPerSecond != 0 {
+		return errors.AssertionFailedf("MaxWriteRequestsPerSecond set on system span config")
+	}
 	if len(s.Constraints) != 0 {
 		return errors.AssertionFailedf("Constraints set on system span config")
 	}
@@ -469,6 +469,9 @@ type hotRangeInfo struct {
 	SchemaName          string           `json:"schema_name"`
 	ReplicaNodeIDs      []roachpb.NodeID `json:"replica_node_ids"`
 	StoreID             roachpb.StoreID  `json:"store_id"`
+	// HotKeys are the keys which receive a large fraction of the load on the
+	// range, if any.
+	HotKeys []serverpb.HotKey `json:"hot_keys,omitempty"`
 }
 
 // swagger:operation GET /ranges/hot/ listHotRanges
@@ -556,6 +559,7 @@ func (a *apiV2Server) listHotRanges(w http.ResponseWriter, r *http.Request) {
 				ReplicaNodeIDs:      r.ReplicaNodeIds,
 				SchemaName:          r.SchemaName,
 				StoreID:             r.StoreID,
+				HotKeys:             r.HotKeys,
 			}
 		}
 		return hotRangeInfos, nil
@@ -61,6 +61,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/spanconfig"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkeys"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descs"
 	"github.com/cockroachdb/cockroach/pkg/sql/clusterunique"
@@ -2790,6 +2791,8 @@ func (s *systemStatusServer) HotRangesV2(
 						})
 					}
 
+					s.decodeHotKeys(ctx, r.HotKeys)
+
 					ranges = append(ranges, &serverpb.HotRangesResponseV2_HotRange{
 						RangeID:             r.Desc.RangeID,
 						NodeID:              requestedNodeID,
@@ -2806,6 +2809,7 @@ func (s *systemStatusServer) HotRangesV2(
 						ReplicaNodeIds:      replicaNodeIDs,
 						LeaseholderNodeID:   r.LeaseholderNodeID,
 						StoreID:             store.StoreID,
+						HotKeys:             r.HotKeys,
 					})
 				}
 			}
@@ -2873,6 +2877,45 @@ func decodeTableID(codec keys.SQLCodec, key roachpb.Key) (roachpb.Key, uint32, b
 	return remaining, tableID, true
 }
 
+// decodeHotKeys populates the SQL table, index and index key that each of the
+// given hot keys belongs to, if any.
+func (s *systemStatusServer) decodeHotKeys(ctx context.Context, hotKeys []serverpb.HotKey) {
+	codec := s.sqlServer.execCfg.Codec
+	for i := range hotKeys {
+		hotKey := &hotKeys[i]
+		_, tableID, ok := decodeTableID(codec, hotKey.Key)
+		if !ok {
+			continue
+		}
+		if err := s.sqlServer.distSQLServer.DB.DescsTxn(
+			ctx, func(ctx context.Context, txn descs.Txn) error {
+				desc, err := txn.Descriptors().ByID(txn.KV()).WithoutNonPublic().Get().Table(ctx, descpb.ID(tableID))
+				if err != nil {
+					return errors.Wrapf(err, "cannot get table descriptor with tableID: %d", tableID)
+				}
+				hotKey.TableName = desc.GetName()
+				_, _, idxID, err := codec.DecodeIndexPrefix(hotKey.Key)
+				if err != nil {
+					// The key is the prefix of the table.
+					return nil //nolint:returnerrcheck
+				}
+				index := catalog.FindIndexByID(desc, descpb.IndexID(idxID))
+				if index == nil {
+					return errors.Errorf("index with ID %d not found", idxID)
+				}
+				hotKey.IndexName = index.GetName()
+				key, err := codec.StripTenantPrefix(hotKey.Key)
+				if err != nil {
+					return err
+				}
+				hotKey.IndexKey = catalogkeys.PrettyKey(catalogkeys.IndexKeyValDirs(index), key, 2 /* skip */)
+				return nil
+			}); err != nil {
+			log.Warningf(ctx, "cannot decode hot key %s: %v", hotKey.Key, err)
+		}
+	}
+}
+
 func (s *systemStatusServer) localHotRanges(
 	ctx context.Context, tenantID roachpb.TenantID,
 ) serverpb.HotRangesResponse_NodeResponse {
@@ -2901,6 +2944,16 @@ func (s *systemStatusServer) localHotRanges(
 			storeResp.HotRanges[i].WriteBytesPerSecond = r.WriteBytesPerSecond
 			storeResp.HotRanges[i].ReadBytesPerSecond = r.ReadBytesPerSecond
 			storeResp.HotRanges[i].CPUTimePerSecond = r.CPUTimePerSecond
+			for _, k := range r.HotKeys {
+				storeResp.HotRanges[i].HotKeys = append(storeResp.HotRanges[i].HotKeys, serverpb.HotKey{
+					Key:                 k.Key,
+					PrettyKey:           k.Key.String(),
+					Frequency:           k.Frequency,
+					ReadsPerSecond:      k.ReadsPerSecond,
+					WritesPerSecond:     k.WritesPerSecond,
+					ContentionPerSecond: k.ContentionPerSecond,
+				})
+			}
 		}
 		resp.Stores = append(resp.Stores, storeResp)
 		return nil
@@ -0,0 +1,347 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/server/authserver"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/server/srverrors"
+	"github.com/cockroachdb/cockroach/pkg/sql/roleoption"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/errors"
+)
+
+// txnWaitGraphQueryLocksBatchSize is the maximum number of locks requested
+// in each QueryLocks request issued to assemble the transaction wait graph.
+const txnWaitGraphQueryLocksBatchSize = 10000
+
+// TransactionWaitGraph assembles the graph of transactions waiting on one
+// another from the lock tables and txn wait queues of every range in the
+// tenant's keyspace, and links the transactions to the SQL sessions running
+// them.
+func (s *statusServer) TransactionWaitGraph(
+	ctx context.Context, req *serverpb.TransactionWaitGraphRequest,
+) (*serverpb.TransactionWaitGraphResponse, error) {
+	ctx = authserver.ForwardSQLIdentityThroughRPCCalls(ctx)
+	ctx = s.AnnotateCtx(ctx)
+
+	if err := s.privilegeChecker.RequireViewActivityOrViewActivityRedactedPermission(ctx); err != nil {
+		return nil, err
+	}
+	user, isAdmin, err := s.privilegeChecker.GetUserAndRole(ctx)
+	if err != nil {
+		return nil, srverrors.ServerError(ctx, err)
+	}
+	shouldRedactKeys := false
+	if !isAdmin {
+		shouldRedactKeys, err = s.privilegeChecker.HasRoleOption(ctx, user, roleoption.VIEWACTIVITYREDACTED)
+		if err != nil {
+			return nil, srverrors.ServerError(ctx, err)
+		}
+	}
+
+	locks, waits, err := s.queryTxnWaits(ctx)
+	if err != nil {
+		return nil, srverrors.ServerError(ctx, err)
+	}
+	// The caller has VIEWACTIVITY or VIEWACTIVITYREDACTED, so the sessions of
+	// all users are listed.
+	sessions, _, err := s.listSessionsHelper(ctx, &serverpb.ListSessionsRequest{
+		ExcludeClosedSessions: true,
+		IncludeInternal:       true,
+	}, 0 /* limit */, paginationState{})
+	if err != nil {
+		return nil, srverrors.ServerError(ctx, err)
+	}
+
+	resp := buildTransactionWaitGraph(locks, waits, sessions.Sessions)
+	resp.SessionErrors = sessions.Errors
+	if shouldRedactKeys {
+		for i := range resp.Edges {
+			resp.Edges[i].Key = nil
+		}
+	}
+	return resp, nil
+}
+
+// queryTxnWaits returns the contended locks and the waiting pushes across the
+// tenant's keyspace.
+func (s *statusServer) queryTxnWaits(
+	ctx context.Context,
+) ([]roachpb.LockStateInfo, []roachpb.TxnWaitInfo, error) {
+	span := s.sqlServer.execCfg.Codec.TenantSpan()
+	if len(span.Key) == 0 {
+		// The system tenant's keyspace starts at KeyMin. Transactions anchored
+		// below the table data, such as those splitting and merging ranges, are
+		// internal and not of interest.
+		span.Key = keys.TableDataMin
+	}
+
+	var locks []roachpb.LockStateInfo
+	var waits []roachpb.TxnWaitInfo
+	for {
+		b := s.db.NewBatch()
+		b.AddRawRequest(&kvpb.QueryLocksRequest{
+			RequestHeader:       kvpb.RequestHeaderFromSpan(span),
+			IncludeTxnWaitQueue: true,
+		})
+		b.Header.MaxSpanRequestKeys = txnWaitGraphQueryLocksBatchSize
+		if err := s.db.Run(ctx, b); err != nil {
+			return nil, nil, err
+		}
+		if len(b.RawResponse().Responses) != 1 {
+			return nil, nil, errors.AssertionFailedf(
+				"unexpected response length of %d for QueryLocksRequest", len(b.RawResponse().Responses),
+			)
+		}
+		resp := b.RawResponse().Responses[0].GetQueryLocks()
+		locks = append(locks, resp.Locks...)
+		waits = append(waits, resp.TxnWaits...)
+		if resp.ResumeSpan == nil {
+			return locks, waits, nil
+		}
+		span = *resp.ResumeSpan
+	}
+}
+
+// buildTransactionWaitGraph assembles the wait-for edges between transactions
+// described by the given lock table and txn wait queue state, identifies the
+// deadlock cycles and root blockers among them, and links each transaction to
+// the session running it, if any.
+func buildTransactionWaitGraph(
+	locks []roachpb.LockStateInfo, waits []roachpb.TxnWaitInfo, sessions []serverpb.Session,
+) *serverpb.TransactionWaitGraphResponse {
+	resp := &serverpb.TransactionWaitGraphResponse{}
+	for i := range locks {
+		l := &locks[i]
+		if l.LockHolder == nil {
+			continue
+		}
+		for _, w := range l.Waiters {
+			// Non-transactional waiters cannot be part of a wait-for cycle, nor
+			// can they be linked to a session, so they are omitted.
+			if w.WaitingTxn == nil || w.WaitingTxn.ID == l.LockHolder.ID {
+				continue
+			}
+			resp.Edges = append(resp.Edges, serverpb.TransactionWaitGraphResponse_Edge{
+				WaiterTxnID:  w.WaitingTxn.ID,
+				HolderTxnID:  l.LockHolder.ID,
+				Kind:         serverpb.TransactionWaitGraphResponse_Edge_LOCK,
+				RangeID:      l.RangeID,
+				Key:          l.Key,
+				LockStrength: w.Strength.String(),
+				WaitDuration: w.WaitDuration,
+			})
+		}
+	}
+	for _, w := range waits {
+		if w.Pusher.ID == w.Pushee.ID {
+			continue
+		}
+		resp.Edges = append(resp.Edges, serverpb.TransactionWaitGraphResponse_Edge{
+			WaiterTxnID:  w.Pusher.ID,
+			HolderTxnID:  w.Pushee.ID,
+			Kind:         serverpb.TransactionWaitGraphResponse_Edge_PUSH,
+			RangeID:      w.RangeID,
+			Key:          w.Pushee.Key,
+			WaitDuration: w.WaitDuration,
+		})
+	}
+	sort.Slice(resp.Edges, func(i, j int) bool {
+		a, b := &resp.Edges[i], &resp.Edges[j]
+		if c := compareTxnIDs(a.HolderTxnID, b.HolderTxnID); c != 0 {
+			return c < 0
+		}
+		if c := compareTxnIDs(a.WaiterTxnID, b.WaiterTxnID); c != 0 {
+			return c < 0
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Key.Compare(b.Key) < 0
+	})
+
+	g := makeTxnWaitGraph(resp.Edges)
+	cycleIDs := g.cycleIDs()
+
+	sessionsByTxn := make(map[uuid.UUID]*serverpb.Session)
+	for i := range sessions {
+		if txn := sessions[i].ActiveTxn; txn != nil {
+			sessionsByTxn[txn.ID] = &sessions[i]
+		}
+	}
+
+	resp.Txns = make([]serverpb.TransactionWaitGraphResponse_Txn, 0, len(g.txns))
+	for _, id := range g.txns {
+		txn := serverpb.TransactionWaitGraphResponse_Txn{
+			TxnID:       id,
+			NumBlocked:  int32(g.numBlocked(id)),
+			CycleID:     int32(cycleIDs[id]),
+			RootBlocker: g.isRootBlocker(id, cycleIDs),
+		}
+		if session, ok := sessionsByTxn[id]; ok {
+			txn.SessionID = session.ID
+			txn.NodeID = session.NodeID
+			txn.Username = session.Username
+			txn.ApplicationName = session.ApplicationName
+			for _, q := range session.ActiveQueries {
+				if q.TxnID == id {
+					txn.StatementFingerprint = q.SqlNoConstants
+					break
+				}
+			}
+		}
+		resp.Txns = append(resp.Txns, txn)
+	}
+	sort.SliceStable(resp.Txns, func(i, j int) bool {
+		return resp.Txns[i].NumBlocked > resp.Txns[j].NumBlocked
+	})
+	return resp
+}
+
+func compareTxnIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a.GetBytes(), b.GetBytes())
+}
+
+// txnWaitGraph is a directed graph with an edge from each waiting transaction
+// to each transaction it is waiting on.
+type txnWaitGraph struct {
+	// txns contains every transaction in the graph, sorted by ID.
+	txns []uuid.UUID
+	// waitingOn maps each transaction to the transactions it is waiting on.
+	waitingOn map[uuid.UUID][]uuid.UUID
+	// waiters maps each transaction to the transactions waiting on it.
+	waiters map[uuid.UUID][]uuid.UUID
+}
+
+func makeTxnWaitGraph(edges []serverpb.TransactionWaitGraphResponse_Edge) txnWaitGraph {
+	g := txnWaitGraph{
+		waitingOn: make(map[uuid.UUID][]uuid.UUID),
+		waiters:   make(map[uuid.UUID][]uuid.UUID),
+	}
+	type pair struct{ waiter, holder uuid.UUID }
+	seen := make(map[pair]struct{})
+	txns := make(map[uuid.UUID]struct{})
+	for _, e := range edges {
+		txns[e.WaiterTxnID] = struct{}{}
+		txns[e.HolderTxnID] = struct{}{}
+		p := pair{waiter: e.WaiterTxnID, holder: e.HolderTxnID}
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		g.waitingOn[p.waiter] = append(g.waitingOn[p.waiter], p.holder)
+		g.waiters[p.holder] = append(g.waiters[p.holder], p.waiter)
+	}
+	for id := range txns {
+		g.txns = append(g.txns, id)
+	}
+	sort.Slice(g.txns, func(i, j int) bool {
+		return compareTxnIDs(g.txns[i], g.txns[j]) < 0
+	})
+	return g
+}
+
+// cycleIDs returns a mapping from each transaction that is part of a deadlock
+// cycle to the ID of its cycle, starting from 1. Cycles are the strongly
+// connected components of the graph with more than one transaction, computed
+// using Tarjan's algorithm.
+func (g *txnWaitGraph) cycleIDs() map[uuid.UUID]int {
+	var (
+		index   = make(map[uuid.UUID]int)
+		lowLink = make(map[uuid.UUID]int)
+		onStack = make(map[uuid.UUID]bool)
+		stack   []uuid.UUID
+		ids     = make(map[uuid.UUID]int)
+		nextID  = 1
+	)
+	var visit func(id uuid.UUID)
+	visit = func(id uuid.UUID) {
+		index[id] = len(index)
+		lowLink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, next := range g.waitingOn[id] {
+			if _, ok := index[next]; !ok {
+				visit(next)
+				if lowLink[next] < lowLink[id] {
+					lowLink[id] = lowLink[next]
+				}
+			} else if onStack[next] && index[next] < lowLink[id] {
+				lowLink[id] = index[next]
+			}
+		}
+		if lowLink[id] != index[id] {
+			return
+		}
+		// id is the root of a strongly connected component; pop it.
+		i := len(stack) - 1
+		for stack[i] != id {
+			i--
+		}
+		component := stack[i:]
+		stack = stack[:i]
+		for _, member := range component {
+			onStack[member] = false
+		}
+		if len(component) > 1 {
+			for _, member := range component {
+				ids[member] = nextID
+			}
+			nextID++
+		}
+	}
+	for _, id := range g.txns {
+		if _, ok := index[id]; !ok {
+			visit(id)
+		}
+	}
+	return ids
+}
+
+// isRootBlocker returns whether the transaction blocks other transactions
+// without itself waiting on any transaction outside of its deadlock cycle.
+func (g *txnWaitGraph) isRootBlocker(id uuid.UUID, cycleIDs map[uuid.UUID]int) bool {
+	if len(g.waiters[id]) == 0 {
+		return false
+	}
+	for _, holder := range g.waitingOn[id] {
+		if cycleIDs[id] == 0 || cycleIDs[holder] != cycleIDs[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// numBlocked returns the number of transactions waiting on the transaction,
+// directly or transitively.
+func (g *txnWaitGraph) numBlocked(id uuid.UUID) int {
+	visited := map[uuid.UUID]struct{}{id: {}}
+	queue := []uuid.UUID{id}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, waiter := range g.waiters[cur] {
+			if _, ok := visited[waiter]; !ok {
+				visited[waiter] = struct{}{}
+				queue = append(queue, waiter)
+			}
+		}
+	}
+	return len(visited) - 1
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/concurrency/lock"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTransactionWaitGraph(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	txn := func(b byte) *enginepb.TxnMeta {
+		var id uuid.UUID
+		id[15] = b
+		return &enginepb.TxnMeta{ID: id, Key: roachpb.Key{'k', b}}
+	}
+	txnA, txnB, txnC := txn(1), txn(2), txn(3)
+	txnD, txnE, txnF := txn(4), txn(5), txn(6)
+
+	// A waits on a lock held by B, which is pushing C. D and E are deadlocked
+	// on each other's locks, and F is waiting on D.
+	lockInfo := func(holder *enginepb.TxnMeta, key string, waiters ...*enginepb.TxnMeta) roachpb.LockStateInfo {
+		l := roachpb.LockStateInfo{RangeID: 1, Key: roachpb.Key(key), LockHolder: holder}
+		for _, w := range waiters {
+			l.Waiters = append(l.Waiters, lock.Waiter{
+				WaitingTxn: w, Strength: lock.Intent, WaitDuration: time.Second,
+			})
+		}
+		return l
+	}
+	locks := []roachpb.LockStateInfo{
+		lockInfo(txnB, "b", txnA, nil /* non-transactional */),
+		lockInfo(txnD, "d", txnE, txnF),
+		lockInfo(txnE, "e", txnD),
+		lockInfo(txnC, "c"),
+	}
+	waits := []roachpb.TxnWaitInfo{
+		{RangeID: 2, Pushee: *txnC, Pusher: *txnB, WaitDuration: time.Second},
+	}
+	sessions := []serverpb.Session{{
+		ID:              []byte("session"),
+		NodeID:          3,
+		Username:        "alice",
+		ApplicationName: "app",
+		ActiveTxn:       &serverpb.TxnInfo{ID: txnA.ID},
+		ActiveQueries: []serverpb.ActiveQuery{{
+			TxnID:          txnA.ID,
+			SqlNoConstants: "UPDATE t SET v = _ WHERE k = _",
+		}},
+	}}
+
+	resp := buildTransactionWaitGraph(locks, waits, sessions)
+
+	type edge struct {
+		waiter, holder uuid.UUID
+		kind           serverpb.TransactionWaitGraphResponse_Edge_Kind
+	}
+	var edges []edge
+	for _, e := range resp.Edges {
+		edges = append(edges, edge{waiter: e.WaiterTxnID, holder: e.HolderTxnID, kind: e.Kind})
+	}
+	const lockKind, pushKind = serverpb.TransactionWaitGraphResponse_Edge_LOCK, serverpb.TransactionWaitGraphResponse_Edge_PUSH
+	require.Equal(t, []edge{
+		{waiter: txnA.ID, holder: txnB.ID, kind: lockKind},
+		{waiter: txnB.ID, holder: txnC.ID, kind: pushKind},
+		{waiter: txnE.ID, holder: txnD.ID, kind: lockKind},
+		{waiter: txnF.ID, holder: txnD.ID, kind: lockKind},
+		{waiter: txnD.ID, holder: txnE.ID, kind: lockKind},
+	}, edges)
+
+	txns := make(map[uuid.UUID]serverpb.TransactionWaitGraphResponse_Txn)
+	for _, txn := range resp.Txns {
+		txns[txn.TxnID] = txn
+	}
+	require.Len(t, txns, 6)
+
+	require.Equal(t, serverpb.TransactionWaitGraphResponse_Txn{
+		TxnID:                txnA.ID,
+		SessionID:            []byte("session"),
+		NodeID:               3,
+		Username:             "alice",
+		ApplicationName:      "app",
+		StatementFingerprint: "UPDATE t SET v = _ WHERE k = _",
+	}, txns[txnA.ID])
+	require.Equal(t, serverpb.TransactionWaitGraphResponse_Txn{
+		TxnID: txnB.ID, NumBlocked: 1,
+	}, txns[txnB.ID])
+	require.Equal(t, serverpb.TransactionWaitGraphResponse_Txn{
+		TxnID: txnC.ID, NumBlocked: 2, RootBlocker: true,
+	}, txns[txnC.ID])
+
+	// D and E form a cycle; both are root blockers, since aborting either
+	// unblocks the other and F.
+	require.NotZero(t, txns[txnD.ID].CycleID)
+	require.Equal(t, txns[txnD.ID].CycleID, txns[txnE.ID].CycleID)
+	require.Zero(t, txns[txnF.ID].CycleID)
+	require.True(t, txns[txnD.ID].RootBlocker)
+	require.True(t, txns[txnE.ID].RootBlocker)
+	require.False(t, txns[txnF.ID].RootBlocker)
+	require.EqualValues(t, 2, txns[txnD.ID].NumBlocked)
+	require.EqualValues(t, 2, txns[txnE.ID].NumBlocked)
+
+	// Transactions are ordered by the number of transactions they block.
+	for i := 1; i < len(resp.Txns); i++ {
+		require.GreaterOrEqual(t, resp.Txns[i-1].NumBlocked, resp.Txns[i].NumBlocked)
+	}
+}
@@ -53,16 +53,22 @@ var fields = [config.NumFields]Field{
 	constraints,
 	voterConstraints,
 	leasePreferences,
+	numWitnesses,
+	maxWriteBytesPerSecond,
+	maxWriteRequestsPerSecond,
 }
 
 const (
-	rangeMaxBytes    = int64Field(config.RangeMaxBytes)
-	rangeMinBytes    = int64Field(config.RangeMinBytes)
-	globalReads      = boolField(config.GlobalReads)
-	numReplicas      = int32Field(config.NumReplicas)
-	numVoters        = int32Field(config.NumVoters)
-	gcTTLSeconds     = int32Field(config.GCTTL)
-	constraints      = constraintsConjunctionField(config.Constraints)
-	voterConstraints = constraintsConjunctionField(config.VoterConstraints)
-	leasePreferences = leasePreferencesField(config.LeasePreferences)
+	rangeMaxBytes             = int64Field(config.RangeMaxBytes)
+	rangeMinBytes             = int64Field(config.RangeMinBytes)
+	globalReads               = boolField(config.GlobalReads)
+	numReplicas               = int32Field(config.NumReplicas)
+	numVoters                 = int32Field(config.NumVoters)
+	gcTTLSeconds              = int32Field(config.GCTTL)
+	constraints               = constraintsConjunctionField(config.Constraints)
+	voterConstraints          = constraintsConjunctionField(config.VoterConstraints)
+	leasePreferences          = leasePreferencesField(config.LeasePreferences)
+	numWitnesses              = int32Field(config.NumWitnesses)
+	maxWriteBytesPerSecond    = int64Field(config.MaxWriteBytesPerSecond)
+	maxWriteRequestsPerSecond = int64Field(config.MaxWriteRequestsPerSecond)
 )
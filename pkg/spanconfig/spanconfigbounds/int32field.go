@@ -35,6 +35,8 @@ func (f int32Field) FieldBound(b *Bounds) ValueBounds {
 			return b.NumReplicas
 		case numVoters:
 			return b.NumVoters
+		case numWitnesses:
+			return b.NumWitnesses
 		case gcTTLSeconds:
 			return b.GCTTLSeconds
 		default:
@@ -60,6 +62,8 @@ func (f int32Field) fieldValue(c *roachpb.SpanConfig) *int32 {
 		return &c.NumReplicas
 	case numVoters:
 		return &c.NumVoters
+	case numWitnesses:
+		return &c.NumWitnesses
 	case gcTTLSeconds:
 		return &c.GCPolicy.TTLSeconds
 	default:
@@ -37,6 +37,10 @@ func (f int64Field) FieldBound(b *Bounds) ValueBounds {
 			return b.RangeMaxBytes
 		case rangeMinBytes:
 			return b.RangeMinBytes
+		case maxWriteBytesPerSecond:
+			return b.MaxWriteBytesPerSecond
+		case maxWriteRequestsPerSecond:
+			return b.MaxWriteRequestsPerSecond
 		default:
 			// This is safe because we test that all the fields in the proto have
 			// a corresponding field, and we call this for each of them, and the user
@@ -60,6 +64,10 @@ func (f int64Field) fieldValue(c *roachpb.SpanConfig) *int64 {
 		return &c.RangeMaxBytes
 	case rangeMinBytes:
 		return &c.RangeMinBytes
+	case maxWriteBytesPerSecond:
+		return &c.MaxWriteBytesPerSecond
+	case maxWriteRequestsPerSecond:
+		return &c.MaxWriteRequestsPerSecond
 	default:
 		// This is safe because we test that all the fields in the proto have
 		// a corresponding field, and we call this for each of them, and the user
@@ -635,6 +635,15 @@ func PrintSpanConfigDiffedAgainstDefaults(conf roachpb.SpanConfig) string {
 	if conf.NumVoters != defaultConf.NumVoters {
 		diffs = append(diffs, fmt.Sprintf("num_voters=%d", conf.NumVoters))
 	}
+	if conf.NumWitnesses != defaultConf.NumWitnesses {
+		diffs = append(diffs, fmt.Sprintf("num_witnesses=%d", conf.NumWitnesses))
+	}
+	if conf.MaxWriteBytesPerSecond != defaultConf.MaxWriteBytesPerSecond {
+		diffs = append(diffs, fmt.Sprintf("max_write_bytes_per_second=%d", conf.MaxWriteBytesPerSecond))
+	}
+	if conf.MaxWriteRequestsPerSecond != defaultConf.MaxWriteRequestsPerSecond {
+		diffs = append(diffs, fmt.Sprintf("max_write_requests_per_second=%d", conf.MaxWriteRequestsPerSecond))
+	}
 	if conf.RangefeedEnabled != defaultConf.RangefeedEnabled {
 		diffs = append(diffs, fmt.Sprintf("rangefeed_enabled=%t", conf.RangefeedEnabled))
 	}
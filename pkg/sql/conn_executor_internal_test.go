@@ -25,6 +25,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/security/username"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/appstatspb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descs"
 	"github.com/cockroachdb/cockroach/pkg/sql/clusterunique"
 	"github.com/cockroachdb/cockroach/pkg/sql/distsql"
@@ -37,6 +38,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/stmtdiagnostics"
 	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
@@ -459,3 +461,52 @@ CREATE TEMPORARY TABLE foo();
 		require.NoError(t, err)
 	}
 }
+
+func TestRetryErrConflictDetail(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	txnID := uuid.MakeV4()
+	codec := keys.MakeSQLCodec(roachpb.MustMakeTenantID(10))
+	key := codec.IndexPrefix(104, 1)
+
+	for _, tc := range []struct {
+		name             string
+		key              roachpb.Key
+		txn              *enginepb.TxnMeta
+		txnFingerprintID appstatspb.TransactionFingerprintID
+		expected         string
+	}{
+		{
+			name:     "key only",
+			key:      key,
+			expected: "conflicting key: /Table/104/1\nstatement fingerprint ID: 0000000000000002",
+		},
+		{
+			name: "txn with unknown fingerprint",
+			key:  key,
+			txn:  &enginepb.TxnMeta{ID: txnID},
+			expected: "conflicting key: /Table/104/1\n" +
+				"conflicting transaction: " + txnID.String() + "\n" +
+				"statement fingerprint ID: 0000000000000002\n" +
+				"see crdb_internal.transaction_contention_events for more details",
+		},
+		{
+			name:             "txn with known fingerprint",
+			txn:              &enginepb.TxnMeta{ID: txnID},
+			txnFingerprintID: 1,
+			expected: "conflicting transaction: " + txnID.String() +
+				" (fingerprint ID: 0000000000000001)\n" +
+				"statement fingerprint ID: 0000000000000002\n" +
+				"see crdb_internal.transaction_contention_events for more details",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			retryErr := &kvpb.TransactionRetryWithProtoRefreshError{
+				ConflictingKey: tc.key,
+				ConflictingTxn: tc.txn,
+			}
+			require.Equal(t, tc.expected, retryErrConflictDetail(retryErr, tc.txnFingerprintID, 2))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/appstatspb"
+	"github.com/cockroachdb/cockroach/pkg/sql/contentionpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlstats/persistedsqlstats/sqlstatsutil"
+	"github.com/cockroachdb/errors"
+)
+
+// recordRetryErrConflict records the conflict which caused a transaction retry
+// error, if the KV layer reported it, and returns the error annotated with a
+// description of the conflict as a detail, so that it is reported to the
+// client. Errors which are not transaction retry errors, or whose conflict is
+// unknown, are returned unchanged.
+//
+// If the conflicting transaction is known, the conflict is recorded in the
+// contention registry as a SERIALIZATION_CONFLICT contention event, which
+// surfaces it in crdb_internal.transaction_contention_events along with the
+// fingerprints of both transactions.
+func (ex *connExecutor) recordRetryErrConflict(err error, stmt tree.Statement) error {
+	var retryErr *kvpb.TransactionRetryWithProtoRefreshError
+	if !errors.As(err, &retryErr) ||
+		(retryErr.ConflictingKey == nil && retryErr.ConflictingTxn == nil) {
+		return err
+	}
+
+	var stmtFingerprintID appstatspb.StmtFingerprintID
+	if stmt != nil {
+		f := tree.NewFmtCtx(tree.FmtHideConstants)
+		f.FormatNode(stmt)
+		stmtFingerprintID = appstatspb.ConstructStatementFingerprintID(
+			f.CloseAndGetString(),
+			true, /* failed */
+			ex.implicitTxn(),
+			ex.planner.CurrentDatabase(),
+		)
+	}
+
+	var conflictingTxnFingerprintID appstatspb.TransactionFingerprintID
+	if conflictingTxn := retryErr.ConflictingTxn; conflictingTxn != nil {
+		ex.server.cfg.ContentionRegistry.AddContentionEvent(contentionpb.ExtendedContentionEvent{
+			BlockingEvent: kvpb.ContentionEvent{
+				Key:     retryErr.ConflictingKey,
+				TxnMeta: *conflictingTxn,
+			},
+			WaitingTxnID:             retryErr.PrevTxnID,
+			WaitingStmtFingerprintID: stmtFingerprintID,
+			WaitingStmtID:            ex.planner.stmt.QueryID,
+			ContentionType:           contentionpb.ContentionType_SERIALIZATION_CONFLICT,
+		})
+		// The fingerprint of the conflicting transaction is only known here if
+		// it was executed by this node and has finished. Otherwise, it is
+		// resolved asynchronously by the contention event store.
+		conflictingTxnFingerprintID, _ = ex.server.txnIDCache.Lookup(conflictingTxn.ID)
+	}
+
+	return errors.WithDetail(err,
+		retryErrConflictDetail(retryErr, conflictingTxnFingerprintID, stmtFingerprintID))
+}
+
+// retryErrConflictDetail describes the conflict which caused a transaction
+// retry error, for use as the detail of the error.
+func retryErrConflictDetail(
+	retryErr *kvpb.TransactionRetryWithProtoRefreshError,
+	conflictingTxnFingerprintID appstatspb.TransactionFingerprintID,
+	stmtFingerprintID appstatspb.StmtFingerprintID,
+) string {
+	var b strings.Builder
+	if retryErr.ConflictingKey != nil {
+		key, _, _ := keys.DecodeTenantPrefix(retryErr.ConflictingKey)
+		fmt.Fprintf(&b, "conflicting key: %s\n", keys.PrettyPrint(nil /* valDirs */, key))
+	}
+	if conflictingTxn := retryErr.ConflictingTxn; conflictingTxn != nil {
+		fmt.Fprintf(&b, "conflicting transaction: %s", conflictingTxn.ID)
+		if conflictingTxnFingerprintID != appstatspb.InvalidTransactionFingerprintID {
+			fmt.Fprintf(&b, " (fingerprint ID: %s)", encodeFingerprintID(uint64(conflictingTxnFingerprintID)))
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "statement fingerprint ID: %s", encodeFingerprintID(uint64(stmtFingerprintID)))
+	if retryErr.ConflictingTxn != nil {
+		b.WriteString("\nsee crdb_internal.transaction_contention_events for more details")
+	}
+	return b.String()
+}
+
+// encodeFingerprintID encodes a statement or transaction fingerprint ID the
+// way it is displayed in the crdb_internal tables.
+func encodeFingerprintID(id uint64) string {
+	return hex.EncodeToString(sqlstatsutil.EncodeUint64ToBytes(id))
+}
@@ -243,8 +243,10 @@ func (s *eventStore) addEvent(e contentionpb.ExtendedContentionEvent) {
 	}
 
 	// If the duration threshold is set, we only collect contention events whose
-	// duration exceeds the threshold.
-	if threshold := DurationThreshold.Get(&s.st.SV); threshold > 0 {
+	// duration exceeds the threshold. Serialization conflicts have no duration,
+	// and are always collected.
+	if threshold := DurationThreshold.Get(&s.st.SV); threshold > 0 &&
+		e.ContentionType != contentionpb.ContentionType_SERIALIZATION_CONFLICT {
 		if e.BlockingEvent.Duration < threshold {
 			return
 		}
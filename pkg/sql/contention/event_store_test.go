@@ -161,13 +161,23 @@ func TestCollectionThreshold(t *testing.T) {
 				Duration: 2 * time.Second,
 			},
 		},
+		{
+			// Serialization conflicts have no duration, and are collected
+			// regardless of the threshold.
+			BlockingEvent: kvpb.ContentionEvent{
+				TxnMeta: enginepb.TxnMeta{
+					ID: uuid.FastMakeV4(),
+				},
+			},
+			ContentionType: contentionpb.ContentionType_SERIALIZATION_CONFLICT,
+		},
 	}
 
 	for i := range input {
 		store.addEvent(input[i])
 	}
 
-	const expectedNumOfEntries = 1
+	const expectedNumOfEntries = 2
 
 	// Force the contention events to get flushed.
 	testutils.SucceedsWithin(t, func() error {
@@ -175,10 +185,13 @@ func TestCollectionThreshold(t *testing.T) {
 		numOfEntries := 0
 		require.NoError(t,
 			store.ForEachEvent(func(e *contentionpb.ExtendedContentionEvent) error {
+				numOfEntries++
+				if e.ContentionType == contentionpb.ContentionType_SERIALIZATION_CONFLICT {
+					return nil
+				}
 				require.GreaterOrEqualf(t, e.BlockingEvent.Duration, threshold,
 					"expect contention event's duration to exceed the threshold of %s, "+
 						"but it didn't", threshold)
-				numOfEntries++
 				return nil
 			},
 			))
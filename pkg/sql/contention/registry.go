@@ -258,7 +258,15 @@ func (r *Registry) Start(ctx context.Context, stopper *stop.Stopper) {
 }
 
 // AddContentionEvent adds a new ContentionEvent to the Registry.
+//
+// Serialization conflicts are only added to the event store: they don't
+// involve waiting on a lock, so they are not accounted for in the per-index
+// and per-key contention statistics.
 func (r *Registry) AddContentionEvent(event contentionpb.ExtendedContentionEvent) {
+	if event.ContentionType == contentionpb.ContentionType_SERIALIZATION_CONFLICT {
+		r.eventStore.addEvent(event)
+		return
+	}
 	c := event.BlockingEvent
 	r.globalLock.Lock()
 	defer r.globalLock.Unlock()
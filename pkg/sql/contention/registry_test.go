@@ -194,6 +194,27 @@ func TestRegistryConcurrentAdds(t *testing.T) {
 	require.Equal(t, uint64(numGoroutines), contention.CalculateTotalNumContentionEvents(registry))
 }
 
+// TestRegistrySerializationConflicts verifies that serialization conflicts are
+// not accounted for in the per-index and per-key contention statistics.
+func TestRegistrySerializationConflicts(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	st := cluster.MakeTestingClusterSettings()
+	// Disable the event store.
+	contention.TxnIDResolutionInterval.Override(context.Background(), &st.SV, 0)
+	m := contention.NewMetrics()
+	registry := contention.NewRegistry(st, nil /* status */, &m)
+	key := keys.MakeTableIDIndexID(nil /* key */, 1 /* tableID */, 1 /* indexID */)
+	registry.AddContentionEvent(contentionpb.ExtendedContentionEvent{
+		BlockingEvent:  kvpb.ContentionEvent{Key: key},
+		ContentionType: contentionpb.ContentionType_SERIALIZATION_CONFLICT,
+	})
+	require.Zero(t, contention.CalculateTotalNumContentionEvents(registry))
+
+	addContentionEvent(registry, kvpb.ContentionEvent{Key: key})
+	require.Equal(t, uint64(1), contention.CalculateTotalNumContentionEvents(registry))
+}
+
 // TestSerializedRegistryInvariants verifies that the serialized registries
 // maintain all invariants, namely that
 //   - all three levels of objects are subject to the respective maximum size
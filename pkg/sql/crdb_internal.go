@@ -100,6 +100,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing/tracingpb"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/redact"
 	"github.com/lib/pq/oid"
@@ -219,6 +220,8 @@ var crdbInternal = virtualSchema{
 		catconstants.CrdbInternalKVFlowTokenDeductions:              crdbInternalKVFlowTokenDeductions,
 		catconstants.CrdbInternalRepairableCatalogCorruptionsViewID: crdbInternalRepairableCatalogCorruptions,
 		catconstants.CrdbInternalKVProtectedTS:                      crdbInternalKVProtectedTSTable,
+		catconstants.CrdbInternalTransactionWaitGraphTableID:        crdbInternalTransactionWaitGraphTable,
+		catconstants.CrdbInternalHotKeysTableID:                     crdbInternalHotKeysTable,
 	},
 	validWithNoDatabaseContext: true,
 }
@@ -7463,7 +7466,9 @@ CREATE TABLE crdb_internal.transaction_contention_events (
     database_name       				 STRING NOT NULL,
     schema_name         				 STRING NOT NULL,
     table_name          				 STRING NOT NULL,
-    index_name          				 STRING
+    index_name          				 STRING,
+
+    contention_type              STRING NOT NULL
 );`,
 	generator: func(ctx context.Context, p *planner, db catalog.DatabaseDescriptor, stopper *stop.Stopper) (virtualTableGenerator, cleanupFunc, error) {
 		// Check permission first before making RPC fanout.
@@ -7567,6 +7572,7 @@ CREATE TABLE crdb_internal.transaction_contention_events (
 					tree.NewDString(schemaName), // schema_name
 					tree.NewDString(tableName),  // table_name
 					tree.NewDString(indexName),  // index_name
+					tree.NewDString(resp.Events[i].ContentionType.String()), // contention_type
 				)
 
 				if err = pusher.pushRow(row...); err != nil {
@@ -7579,6 +7585,233 @@ CREATE TABLE crdb_internal.transaction_contention_events (
 	},
 }
 
+var crdbInternalTransactionWaitGraphTable = virtualSchemaTable{
+	comment: `cluster-wide graph of transactions waiting on one another, with one row
+		per waiting transaction and blocking transaction pair. Querying this table
+		is an expensive operation since it creates a cluster-wide RPC-fanout.`,
+	schema: `
+CREATE TABLE crdb_internal.transaction_wait_graph (
+    waiting_txn_id                 UUID NOT NULL,
+    blocking_txn_id                UUID NOT NULL,
+    wait_type                      STRING NOT NULL,
+    range_id                       INT NOT NULL,
+    key                            BYTES,
+    key_pretty                     STRING,
+    lock_strength                  STRING,
+    wait_duration                  INTERVAL NOT NULL,
+    cycle_id                       INT,
+
+    blocking_txn_is_root_blocker   BOOL NOT NULL,
+    blocking_txn_num_blocked       INT NOT NULL,
+
+    waiting_session_id             STRING,
+    waiting_node_id                INT,
+    waiting_user_name              STRING,
+    waiting_app_name               STRING,
+    waiting_stmt_fingerprint       STRING,
+
+    blocking_session_id            STRING,
+    blocking_node_id               INT,
+    blocking_user_name             STRING,
+    blocking_app_name              STRING,
+    blocking_stmt_fingerprint      STRING
+);`,
+	populate: func(ctx context.Context, p *planner, _ catalog.DatabaseDescriptor, addRow func(...tree.Datum) error) error {
+		// Check permission first before making RPC fanout.
+		hasPermission, err := p.HasViewActivityOrViewActivityRedactedRole(ctx)
+		if err != nil {
+			return err
+		}
+		if !hasPermission {
+			return noViewActivityOrViewActivityRedactedRoleError(p.User())
+		}
+
+		// If a user has VIEWACTIVITYREDACTED role option but the user does not
+		// have the ADMIN role option, then the keys should be redacted.
+		isAdmin, err := p.HasAdminRole(ctx)
+		if err != nil {
+			return err
+		}
+		shouldRedactKeys := false
+		if !isAdmin {
+			shouldRedactKeys, err = p.HasViewActivityRedacted(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		resp, err := p.extendedEvalCtx.SQLStatusServer.TransactionWaitGraph(
+			ctx, &serverpb.TransactionWaitGraphRequest{})
+		if err != nil {
+			return err
+		}
+
+		txns := make(map[uuid.UUID]*serverpb.TransactionWaitGraphResponse_Txn, len(resp.Txns))
+		for i := range resp.Txns {
+			txns[resp.Txns[i].TxnID] = &resp.Txns[i]
+		}
+		// sessionDatums returns the datums describing the session running the
+		// given transaction, or NULLs if no session was found for it.
+		sessionDatums := func(txn *serverpb.TransactionWaitGraphResponse_Txn) []tree.Datum {
+			if txn == nil || len(txn.SessionID) != 16 {
+				return []tree.Datum{tree.DNull, tree.DNull, tree.DNull, tree.DNull, tree.DNull}
+			}
+			return []tree.Datum{
+				tree.NewDString(clusterunique.IDFromBytes(txn.SessionID).String()),
+				tree.NewDInt(tree.DInt(txn.NodeID)),
+				tree.NewDString(txn.Username),
+				tree.NewDString(txn.ApplicationName),
+				tree.NewDString(txn.StatementFingerprint),
+			}
+		}
+
+		for i := range resp.Edges {
+			e := &resp.Edges[i]
+			waiter, holder := txns[e.WaiterTxnID], txns[e.HolderTxnID]
+			if waiter == nil || holder == nil {
+				return errors.AssertionFailedf(
+					"transaction wait graph edge from %s to %s references unknown transaction",
+					e.WaiterTxnID, e.HolderTxnID,
+				)
+			}
+
+			keyDatum, prettyKeyDatum := tree.DNull, tree.DNull
+			if !shouldRedactKeys && len(e.Key) > 0 {
+				key, _, _ := keys.DecodeTenantPrefix(e.Key)
+				keyDatum = tree.NewDBytes(tree.DBytes(key))
+				prettyKeyDatum = tree.NewDString(keys.PrettyPrint(nil /* valDirs */, key))
+			}
+			waitType := "lock"
+			strengthDatum := tree.DNull
+			if e.Kind == serverpb.TransactionWaitGraphResponse_Edge_PUSH {
+				waitType = "push"
+			} else {
+				strengthDatum = tree.NewDString(e.LockStrength)
+			}
+			// An edge is part of a deadlock cycle only if both of its transactions
+			// are part of the same one.
+			cycleDatum := tree.DNull
+			if waiter.CycleID != 0 && waiter.CycleID == holder.CycleID {
+				cycleDatum = tree.NewDInt(tree.DInt(waiter.CycleID))
+			}
+
+			waitDuration := tree.NewDInterval(
+				duration.MakeDuration(e.WaitDuration.Nanoseconds(), 0 /* days */, 0 /* months */),
+				types.DefaultIntervalTypeMetadata,
+			)
+
+			row := []tree.Datum{
+				tree.NewDUuid(tree.DUuid{UUID: e.WaiterTxnID}), // waiting_txn_id
+				tree.NewDUuid(tree.DUuid{UUID: e.HolderTxnID}), // blocking_txn_id
+				tree.NewDString(waitType),                      // wait_type
+				tree.NewDInt(tree.DInt(e.RangeID)),             // range_id
+				keyDatum,                                       // key
+				prettyKeyDatum,                                 // key_pretty
+				strengthDatum,                                  // lock_strength
+				waitDuration,                                   // wait_duration
+				cycleDatum,                                     // cycle_id
+				tree.MakeDBool(tree.DBool(holder.RootBlocker)), // blocking_txn_is_root_blocker
+				tree.NewDInt(tree.DInt(holder.NumBlocked)),     // blocking_txn_num_blocked
+			}
+			row = append(row, sessionDatums(waiter)...)
+			row = append(row, sessionDatums(holder)...)
+			if err := addRow(row...); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var crdbInternalHotKeysTable = virtualSchemaTable{
+	comment: `keys which receive a large fraction of the load on hot ranges, which
+		load based splitting cannot spread. Querying this table is an expensive
+		operation since it creates a cluster-wide RPC-fanout.`,
+	schema: `
+CREATE TABLE crdb_internal.hot_keys (
+    range_id               INT NOT NULL,
+    node_id                INT NOT NULL,
+    store_id               INT NOT NULL,
+    key                    BYTES,
+    pretty_key             STRING,
+    database_name          STRING,
+    schema_name            STRING,
+    table_name             STRING,
+    index_name             STRING,
+    index_key              STRING,
+    frequency              FLOAT NOT NULL,
+    reads_per_second       FLOAT NOT NULL,
+    writes_per_second      FLOAT NOT NULL,
+    contention_per_second  FLOAT NOT NULL
+);`,
+	populate: func(ctx context.Context, p *planner, _ catalog.DatabaseDescriptor, addRow func(...tree.Datum) error) error {
+		// Check permission first before making RPC fanout.
+		hasPermission, err := p.HasViewActivityOrViewActivityRedactedRole(ctx)
+		if err != nil {
+			return err
+		}
+		if !hasPermission {
+			return noViewActivityOrViewActivityRedactedRoleError(p.User())
+		}
+
+		// If a user has VIEWACTIVITYREDACTED role option but the user does not
+		// have the ADMIN role option, then the keys should be redacted.
+		isAdmin, err := p.HasAdminRole(ctx)
+		if err != nil {
+			return err
+		}
+		shouldRedactKeys := false
+		if !isAdmin {
+			shouldRedactKeys, err = p.HasViewActivityRedacted(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		resp, err := p.extendedEvalCtx.TenantStatusServer.HotRangesV2(ctx, &serverpb.HotRangesRequest{})
+		if err != nil {
+			return err
+		}
+
+		stringOrNull := func(s string) tree.Datum {
+			if s == "" {
+				return tree.DNull
+			}
+			return tree.NewDString(s)
+		}
+		for _, r := range resp.Ranges {
+			for i := range r.HotKeys {
+				k := &r.HotKeys[i]
+				keyDatum, prettyKeyDatum, indexKeyDatum := tree.DNull, tree.DNull, tree.DNull
+				if !shouldRedactKeys {
+					keyDatum = tree.NewDBytes(tree.DBytes(k.Key))
+					prettyKeyDatum = stringOrNull(k.PrettyKey)
+					indexKeyDatum = stringOrNull(k.IndexKey)
+				}
+				if err := addRow(
+					tree.NewDInt(tree.DInt(r.RangeID)),                 // range_id
+					tree.NewDInt(tree.DInt(r.NodeID)),                  // node_id
+					tree.NewDInt(tree.DInt(r.StoreID)),                 // store_id
+					keyDatum,                                           // key
+					prettyKeyDatum,                                     // pretty_key
+					stringOrNull(r.DatabaseName),                       // database_name
+					stringOrNull(r.SchemaName),                         // schema_name
+					stringOrNull(k.TableName),                          // table_name
+					stringOrNull(k.IndexName),                          // index_name
+					indexKeyDatum,                                      // index_key
+					tree.NewDFloat(tree.DFloat(k.Frequency)),           // frequency
+					tree.NewDFloat(tree.DFloat(k.ReadsPerSecond)),      // reads_per_second
+					tree.NewDFloat(tree.DFloat(k.WritesPerSecond)),     // writes_per_second
+					tree.NewDFloat(tree.DFloat(k.ContentionPerSecond)), // contention_per_second
+				); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+}
+
 var crdbInternalIndexSpansTable = virtualSchemaTable{
 	comment: `key spans per table index`,
 	schema: `
@@ -508,6 +508,12 @@ func (c *CloudStorageTestSpec) summary() (string, []string) {
 	return "CloudStorageTestSpec", []string{}
 }
 
+// summary implements the diagramCellType interface.
+func (c *BackupTableReaderSpec) summary() (string, []string) {
+	detail := fmt.Sprintf("%d entries", len(c.Entries))
+	return "BackupTableReaderSpec", []string{detail}
+}
+
 // summary implements the diagramCellType interface.
 func (c *SplitAndScatterSpec) summary() (string, []string) {
 	detail := fmt.Sprintf("%d chunks", len(c.Chunks))
@@ -82,6 +82,8 @@ const (
 	exportSnappyCodec     = "snappy"
 	csvSuffix             = "csv"
 	parquetSuffix         = "parquet"
+	avroSuffix            = "avro"
+	ndjsonSuffix          = "ndjson"
 )
 
 var exportOptionExpectValues = map[string]exprutil.KVStringOptValidate{
@@ -131,7 +133,9 @@ func (ef *execFactory) ConstructExport(
 		return nil, errors.Errorf("EXPORT cannot be used inside a multi-statement transaction")
 	}
 
-	if fileSuffix != csvSuffix && fileSuffix != parquetSuffix {
+	switch fileSuffix {
+	case csvSuffix, parquetSuffix, avroSuffix, ndjsonSuffix:
+	default:
 		return nil, errors.Errorf("unsupported export format: %q", fileSuffix)
 	}
 
@@ -208,6 +212,19 @@ func (ef *execFactory) ConstructExport(
 		}
 		format.Format = roachpb.IOFileFormat_Parquet
 		format.Parquet = parquetOpts
+	case avroSuffix:
+		format.Format = roachpb.IOFileFormat_Avro
+	case ndjsonSuffix:
+		format.Format = roachpb.IOFileFormat_NDJSON
+	}
+
+	if fileSuffix == avroSuffix || fileSuffix == ndjsonSuffix {
+		for _, opt := range []string{exportOptionDelimiter, exportOptionNullAs} {
+			if _, ok := optVals[opt]; ok {
+				return nil, pgerror.Newf(pgcode.InvalidParameterValue,
+					"%s option is not supported for %s file format", opt, fileSuffix)
+			}
+		}
 	}
 
 	chunkRows := exportChunkRowsDefault
@@ -239,7 +256,8 @@ func (ef *execFactory) ConstructExport(
 		switch {
 		case strings.EqualFold(name, exportGzipCodec):
 			codec = roachpb.IOFileFormat_Gzip
-		case strings.EqualFold(name, exportSnappyCodec) && fileSuffix == parquetSuffix:
+		case strings.EqualFold(name, exportSnappyCodec) &&
+			(fileSuffix == parquetSuffix || fileSuffix == avroSuffix):
 			codec = roachpb.IOFileFormat_Snappy
 		default:
 			return nil, pgerror.Newf(pgcode.InvalidParameterValue,
@@ -0,0 +1,398 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowexec"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/avro"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/errors"
+	"github.com/linkedin/goavro/v2"
+)
+
+const (
+	exportAvroFilePatternDefault = exportFilePatternPart + ".avro"
+
+	// exportAvroBlockRows is the number of rows written to each block of an
+	// exported OCF file. Blocks are compressed independently, so they should
+	// not be too small, but the size of the file is only checked between
+	// blocks.
+	exportAvroBlockRows = 1000
+)
+
+// The Avro types of the exported columns are the ones used by changefeeds (see
+// pkg/util/avro), so that the files can be consumed by the same systems: every
+// field is optional, by unioning its type with null, and each SQL type is
+// mapped to an Avro type as faithfully as possible. The types which have no
+// Avro mapping, such as decimals without a precision, are exported as strings
+// formatted like in CSV exports.
+
+// avroExportColumn is an exported column.
+type avroExportColumn struct {
+	*avro.Field
+	// asString is set if the column is exported as a string because its type
+	// has no Avro mapping.
+	asString bool
+}
+
+// makeAvroExportColumn returns the column used to export values of the given
+// SQL type.
+func makeAvroExportColumn(name string, typ *types.T) avroExportColumn {
+	field, err := avro.TypeToSchema(typ)
+	if err != nil {
+		return avroExportColumn{
+			Field: &avro.Field{
+				SchemaType: []avro.SchemaType{avro.SchemaNull, avro.SchemaString},
+				Name:       name,
+				Metadata:   typ.SQLString(),
+			},
+			asString: true,
+		}
+	}
+	field.Name = name
+	field.Metadata = typ.SQLString()
+	return avroExportColumn{Field: field}
+}
+
+// encode encodes a datum as the Go native value of the column. The value is
+// retained until the block of the record is written, so it is newly
+// allocated.
+func (c avroExportColumn) encode(d tree.Datum) (interface{}, error) {
+	if !c.asString {
+		return c.EncodeNew(d)
+	}
+	if d == tree.DNull {
+		return nil, nil
+	}
+	return map[string]interface{}{avro.SchemaString: tree.AsStringWithFlags(d, tree.FmtExport)}, nil
+}
+
+// avroExportFieldName returns a valid Avro field name for a column name. Avro
+// names must start with a letter or an underscore, and contain only letters,
+// digits and underscores, so any other character is replaced by an
+// underscore.
+func avroExportFieldName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+		default:
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// avroExporter encodes rows as records of an Avro object container file.
+type avroExporter struct {
+	buf        bytes.Buffer
+	schemaJSON string
+	codec      string
+	columns    []avroExportColumn
+
+	writer *goavro.OCFWriter
+	// pending are the records which have not been written to a block yet.
+	pending []interface{}
+}
+
+func newAvroExporter(sp execinfrapb.ExportSpec, typs []*types.T) (*avroExporter, error) {
+	e := &avroExporter{
+		columns: make([]avroExportColumn, len(typs)),
+	}
+	switch sp.Format.Compression {
+	case roachpb.IOFileFormat_Gzip:
+		// Avro files are compressed per block, using the same algorithm as gzip.
+		e.codec = goavro.CompressionDeflateLabel
+	case roachpb.IOFileFormat_Snappy:
+		e.codec = goavro.CompressionSnappyLabel
+	case roachpb.IOFileFormat_Auto, roachpb.IOFileFormat_None:
+		e.codec = goavro.CompressionNullLabel
+	default:
+		return nil, pgerror.Newf(pgcode.FeatureNotSupported,
+			"avro writer does not support compression format %s", sp.Format.Compression)
+	}
+
+	fields := make([]*avro.Field, len(typs))
+	seen := make(map[string]int, len(typs))
+	for i, typ := range typs {
+		name := fmt.Sprintf("col%d", i)
+		if i < len(sp.ColNames) {
+			name = avroExportFieldName(sp.ColNames[i])
+		}
+		// Disambiguate columns with the same name, or whose names only differ by
+		// characters which are replaced.
+		if n := seen[name]; n > 0 {
+			seen[name]++
+			name = fmt.Sprintf("%s_%d", name, n)
+		}
+		seen[name]++
+
+		e.columns[i] = makeAvroExportColumn(name, typ)
+		fields[i] = e.columns[i].Field
+	}
+	schemaJSON, err := json.Marshal(map[string]interface{}{
+		"type":   "record",
+		"name":   "export",
+		"fields": fields,
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.schemaJSON = string(schemaJSON)
+	return e, nil
+}
+
+// Reset starts a new file.
+func (e *avroExporter) Reset() error {
+	e.buf.Reset()
+	e.pending = e.pending[:0]
+	var err error
+	e.writer, err = goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               &e.buf,
+		Schema:          e.schemaJSON,
+		CompressionName: e.codec,
+	})
+	return err
+}
+
+// Write appends a row to the file.
+func (e *avroExporter) Write(row tree.Datums) error {
+	record := make(map[string]interface{}, len(row))
+	for i, d := range row {
+		native, err := e.columns[i].encode(d)
+		if err != nil {
+			return errors.Wrapf(err, "encoding column %s", e.columns[i].Name)
+		}
+		record[e.columns[i].Name] = native
+	}
+	e.pending = append(e.pending, record)
+	if len(e.pending) >= exportAvroBlockRows {
+		return e.Flush()
+	}
+	return nil
+}
+
+// Flush writes the pending rows to a block of the file.
+func (e *avroExporter) Flush() error {
+	if len(e.pending) == 0 {
+		return nil
+	}
+	if err := e.writer.Append(e.pending); err != nil {
+		return err
+	}
+	e.pending = e.pending[:0]
+	return nil
+}
+
+// Len returns the size of the blocks written to the file so far.
+func (e *avroExporter) Len() int {
+	return e.buf.Len()
+}
+
+// FileName returns the name of the file for the given part.
+func (e *avroExporter) FileName(spec execinfrapb.ExportSpec, part string) string {
+	pattern := exportAvroFilePatternDefault
+	if spec.NamePattern != "" {
+		pattern = spec.NamePattern
+	}
+	// The compression is internal to the file, so it does not change its name.
+	return strings.Replace(pattern, exportFilePatternPart, part, -1)
+}
+
+func newAvroWriterProcessor(
+	ctx context.Context,
+	flowCtx *execinfra.FlowCtx,
+	processorID int32,
+	spec execinfrapb.ExportSpec,
+	post *execinfrapb.PostProcessSpec,
+	input execinfra.RowSource,
+) (execinfra.Processor, error) {
+	c := &avroWriterProcessor{
+		flowCtx:     flowCtx,
+		processorID: processorID,
+		spec:        spec,
+		input:       input,
+	}
+	semaCtx := tree.MakeSemaContext()
+	if err := c.out.Init(ctx, post, colinfo.ExportColumnTypes, &semaCtx, flowCtx.NewEvalCtx()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type avroWriterProcessor struct {
+	flowCtx     *execinfra.FlowCtx
+	processorID int32
+	spec        execinfrapb.ExportSpec
+	input       execinfra.RowSource
+	out         execinfra.ProcOutputHelper
+}
+
+var _ execinfra.Processor = &avroWriterProcessor{}
+
+func (sp *avroWriterProcessor) OutputTypes() []*types.T {
+	return sp.out.OutputTypes
+}
+
+func (sp *avroWriterProcessor) MustBeStreaming() bool {
+	return false
+}
+
+func (sp *avroWriterProcessor) Run(ctx context.Context, output execinfra.RowReceiver) {
+	ctx, span := tracing.ChildSpan(ctx, "avroWriter")
+	defer span.Finish()
+
+	instanceID := sp.flowCtx.EvalCtx.NodeID.SQLInstanceID()
+	uniqueID := builtins.GenerateUniqueInt(builtins.ProcessUniqueID(instanceID))
+
+	err := func() error {
+		typs := sp.input.OutputTypes()
+		sp.input.Start(ctx)
+		input := execinfra.MakeNoMetadataRowSource(sp.input, output)
+		alloc := &tree.DatumAlloc{}
+		datumRow := make(tree.Datums, len(typs))
+
+		writer, err := newAvroExporter(sp.spec, typs)
+		if err != nil {
+			return err
+		}
+
+		chunk := 0
+		done := false
+		for {
+			var rows int64
+			if err := writer.Reset(); err != nil {
+				return err
+			}
+			for {
+				// If the file exceeds the target size of an Avro file, we flush
+				// before exporting any additional rows.
+				if int64(writer.Len()) >= sp.spec.ChunkSize {
+					break
+				}
+				if sp.spec.ChunkRows > 0 && rows >= sp.spec.ChunkRows {
+					break
+				}
+				row, err := input.NextRow()
+				if err != nil {
+					return err
+				}
+				if row == nil {
+					done = true
+					break
+				}
+				rows++
+
+				for i, ed := range row {
+					if err := ed.EnsureDecoded(typs[i], alloc); err != nil {
+						return err
+					}
+					datumRow[i] = tree.UnwrapDOidWrapper(ed.Datum)
+				}
+				if err := writer.Write(datumRow); err != nil {
+					return err
+				}
+			}
+			if rows < 1 {
+				break
+			}
+			if err := writer.Flush(); err != nil {
+				return errors.Wrap(err, "failed to flush avro writer")
+			}
+
+			conf, err := cloud.ExternalStorageConfFromURI(sp.spec.Destination, sp.spec.User())
+			if err != nil {
+				return err
+			}
+			es, err := sp.flowCtx.Cfg.ExternalStorage(ctx, conf)
+			if err != nil {
+				return err
+			}
+			defer es.Close()
+
+			part := fmt.Sprintf("n%d.%d", uniqueID, chunk)
+			chunk++
+			filename := writer.FileName(sp.spec, part)
+
+			size := writer.Len()
+
+			if err := cloud.WriteFile(ctx, es, filename, bytes.NewReader(writer.buf.Bytes())); err != nil {
+				return err
+			}
+			res := rowenc.EncDatumRow{
+				rowenc.DatumToEncDatum(
+					types.String,
+					tree.NewDString(filename),
+				),
+				rowenc.DatumToEncDatum(
+					types.Int,
+					tree.NewDInt(tree.DInt(rows)),
+				),
+				rowenc.DatumToEncDatum(
+					types.Int,
+					tree.NewDInt(tree.DInt(size)),
+				),
+			}
+
+			cs, err := sp.out.EmitRow(ctx, res, output)
+			if err != nil {
+				return err
+			}
+			if cs != execinfra.NeedMoreRows {
+				// We don't return an error here because we want the error (if any) that
+				// actually caused the consumer to enter a closed/draining state to take precendence.
+				return nil
+			}
+			if done {
+				break
+			}
+		}
+
+		return nil
+	}()
+
+	execinfra.DrainAndClose(
+		ctx, output, err, func(context.Context, execinfra.RowReceiver) {} /* pushTrailingMeta */, sp.input)
+}
+
+// Resume is part of the execinfra.Processor interface.
+func (sp *avroWriterProcessor) Resume(output execinfra.RowReceiver) {
+	panic("not implemented")
+}
+
+func init() {
+	rowexec.NewAvroWriterProcessor = newAvroWriterProcessor
+}
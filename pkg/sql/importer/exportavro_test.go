@@ -0,0 +1,132 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package importer_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// readAvroRecords reads the records of the OCF files matching the pattern.
+func readAvroRecords(t *testing.T, pattern string) (records []map[string]interface{}, codec string) {
+	paths, err := filepath.Glob(pattern)
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+	for _, path := range paths {
+		r, err := goavro.NewOCFReader(bytes.NewReader(readFileByGlob(t, path)))
+		require.NoError(t, err)
+		codec = r.CompressionName()
+		for r.Scan() {
+			record, err := r.Read()
+			require.NoError(t, err)
+			records = append(records, record.(map[string]interface{}))
+		}
+		require.NoError(t, r.Err())
+	}
+	return records, codec
+}
+
+func TestExportAvro(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	dir, cleanupDir := testutils.TempDir(t)
+	defer cleanupDir()
+
+	srv, db, _ := serverutils.StartServer(t, base.TestServerArgs{ExternalIODir: dir})
+	defer srv.Stopper().Stop(context.Background())
+	sqlDB := sqlutils.MakeSQLRunner(db)
+
+	sqlDB.Exec(t, `CREATE TYPE mood AS ENUM ('happy', 'sad')`)
+	sqlDB.Exec(t, `CREATE TABLE foo (
+  i INT PRIMARY KEY, "s-1" STRING, b BYTES, f FLOAT, d DECIMAL, bo BOOL, dt DATE,
+  ts TIMESTAMP, tstz TIMESTAMPTZ, t TIME, a INT[], j JSONB, m mood, iv INTERVAL
+)`)
+	sqlDB.Exec(t, `INSERT INTO foo VALUES
+  (1, 'a', 'b', 1.5, 1.25, true, '2024-01-02', '2024-01-02 03:04:05.678',
+   '2024-01-02 03:04:05.678+00', '01:02:03', ARRAY[1, NULL], '{"k": 1}', 'happy', '1 day'),
+  (2, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL),
+  (3, 'c', 'd', -2, 3, false, '1970-01-01', '1970-01-01', '1970-01-01', '00:00', ARRAY[]::INT[],
+   'null', 'sad', '-1 hour')`)
+
+	t.Run("types", func(t *testing.T) {
+		sqlDB.Exec(t, `EXPORT INTO AVRO 'nodelocal://1/types' FROM SELECT * FROM foo ORDER BY i`)
+		records, codec := readAvroRecords(t, filepath.Join(dir, "types", "export*-n*.0.avro"))
+		require.Equal(t, goavro.CompressionNullLabel, codec)
+		require.Len(t, records, 3)
+
+		ts := time.Date(2024, 1, 2, 3, 4, 5, 678000000, time.UTC)
+		require.Equal(t, map[string]interface{}{
+			"i":    map[string]interface{}{"long": int64(1)},
+			"s_1":  map[string]interface{}{"string": "a"},
+			"b":    map[string]interface{}{"bytes": []byte("b")},
+			"f":    map[string]interface{}{"double": 1.5},
+			"d":    map[string]interface{}{"string": "1.25"},
+			"bo":   map[string]interface{}{"boolean": true},
+			"dt":   map[string]interface{}{"int.date": time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+			"ts":   map[string]interface{}{"long.timestamp-micros": ts},
+			"tstz": map[string]interface{}{"long.timestamp-micros": ts},
+			"t":    map[string]interface{}{"long.time-micros": time.Hour + 2*time.Minute + 3*time.Second},
+			"a": map[string]interface{}{"array": []interface{}{
+				map[string]interface{}{"long": int64(1)}, nil,
+			}},
+			"j":  map[string]interface{}{"string": `{"k": 1}`},
+			"m":  map[string]interface{}{"string": "happy"},
+			"iv": map[string]interface{}{"string": "P1D"},
+		}, records[0])
+		require.Nil(t, records[1]["s_1"])
+		require.Nil(t, records[1]["dt"])
+	})
+
+	t.Run("unsupported-values", func(t *testing.T) {
+		sqlDB.ExpectErr(t, "infinite date not yet supported with avro",
+			`EXPORT INTO AVRO 'nodelocal://1/infinity' FROM SELECT 'infinity'::DATE`)
+	})
+
+	t.Run("chunks-and-compression", func(t *testing.T) {
+		sqlDB.Exec(t, `EXPORT INTO AVRO 'nodelocal://1/chunks' WITH chunk_rows = '2', compression = 'snappy'
+FROM SELECT * FROM foo`)
+		records, codec := readAvroRecords(t, filepath.Join(dir, "chunks", "export*-n*.avro"))
+		require.Equal(t, goavro.CompressionSnappyLabel, codec)
+		require.Len(t, records, 3)
+
+		sqlDB.Exec(t, `EXPORT INTO AVRO 'nodelocal://1/gzip' WITH compression = 'gzip' FROM SELECT * FROM foo`)
+		_, codec = readAvroRecords(t, filepath.Join(dir, "gzip", "export*-n*.0.avro"))
+		require.Equal(t, goavro.CompressionDeflateLabel, codec)
+	})
+
+	t.Run("round-trip", func(t *testing.T) {
+		sqlDB.Exec(t, `EXPORT INTO AVRO 'nodelocal://1/round-trip' FROM SELECT * FROM foo`)
+		sqlDB.Exec(t, `CREATE TABLE bar (
+  i INT PRIMARY KEY, s_1 STRING, b BYTES, f FLOAT, d DECIMAL, bo BOOL, dt DATE,
+  ts TIMESTAMP, tstz TIMESTAMPTZ, t TIME, a INT[], j JSONB, m mood, iv INTERVAL
+)`)
+		sqlDB.Exec(t, `IMPORT INTO bar AVRO DATA ('nodelocal://1/round-trip/export*-n*.0.avro')`)
+		sqlDB.CheckQueryResults(t, `SELECT * FROM bar ORDER BY i`,
+			sqlDB.QueryStr(t, `SELECT * FROM foo ORDER BY i`))
+	})
+
+	t.Run("unsupported-options", func(t *testing.T) {
+		sqlDB.ExpectErr(t, "nullas option is not supported for avro file format",
+			`EXPORT INTO AVRO 'nodelocal://1/nullas' WITH nullas = '' FROM SELECT * FROM foo`)
+	})
+}
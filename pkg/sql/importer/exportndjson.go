@@ -0,0 +1,287 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowexec"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondatapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/errors"
+)
+
+const exportNDJSONFilePatternDefault = exportFilePatternPart + ".ndjson"
+
+// ndjsonExporter encodes rows as JSON objects, one per line, keyed by column
+// name. Datums are converted to JSON like in changefeeds' JSON encoder.
+type ndjsonExporter struct {
+	compressor *gzip.Writer
+	buf        *bytes.Buffer
+	w          io.Writer
+	colNames   []string
+	line       bytes.Buffer
+}
+
+func newNDJSONExporter(sp execinfrapb.ExportSpec, numCols int) *ndjsonExporter {
+	buf := bytes.NewBuffer([]byte{})
+	exporter := &ndjsonExporter{
+		buf:      buf,
+		w:        buf,
+		colNames: make([]string, numCols),
+	}
+	if sp.Format.Compression == roachpb.IOFileFormat_Gzip {
+		exporter.compressor = gzip.NewWriter(buf)
+		exporter.w = exporter.compressor
+	}
+	for i := range exporter.colNames {
+		if i < len(sp.ColNames) {
+			exporter.colNames[i] = sp.ColNames[i]
+		} else {
+			exporter.colNames[i] = fmt.Sprintf("col%d", i)
+		}
+	}
+	return exporter
+}
+
+// Write appends a row to the file.
+func (n *ndjsonExporter) Write(row tree.Datums) error {
+	b := json.NewObjectBuilder(len(row))
+	for i, d := range row {
+		j, err := tree.AsJSON(d, sessiondatapb.DataConversionConfig{}, time.UTC)
+		if err != nil {
+			return errors.Wrapf(err, "encoding column %s", n.colNames[i])
+		}
+		b.Add(n.colNames[i], j)
+	}
+	n.line.Reset()
+	b.Build().Format(&n.line)
+	n.line.WriteByte('\n')
+	_, err := n.w.Write(n.line.Bytes())
+	return err
+}
+
+// Close closes the compressor writer which appends archive footers.
+func (n *ndjsonExporter) Close() error {
+	if n.compressor != nil {
+		return n.compressor.Close()
+	}
+	return nil
+}
+
+// ResetBuffer resets the buffer and compressor state.
+func (n *ndjsonExporter) ResetBuffer() {
+	n.buf.Reset()
+	if n.compressor != nil {
+		// Brings compressor to its initial state.
+		n.compressor.Reset(n.buf)
+	}
+}
+
+// Bytes results in the slice of bytes with compressed content.
+func (n *ndjsonExporter) Bytes() []byte {
+	return n.buf.Bytes()
+}
+
+// Len returns length of the buffer with content.
+func (n *ndjsonExporter) Len() int {
+	return n.buf.Len()
+}
+
+func (n *ndjsonExporter) FileName(spec execinfrapb.ExportSpec, part string) string {
+	pattern := exportNDJSONFilePatternDefault
+	if spec.NamePattern != "" {
+		pattern = spec.NamePattern
+	}
+
+	fileName := strings.Replace(pattern, exportFilePatternPart, part, -1)
+	if n.compressor != nil {
+		fileName += ".gz"
+	}
+	return fileName
+}
+
+func newNDJSONWriterProcessor(
+	ctx context.Context,
+	flowCtx *execinfra.FlowCtx,
+	processorID int32,
+	spec execinfrapb.ExportSpec,
+	post *execinfrapb.PostProcessSpec,
+	input execinfra.RowSource,
+) (execinfra.Processor, error) {
+	c := &ndjsonWriterProcessor{
+		flowCtx:     flowCtx,
+		processorID: processorID,
+		spec:        spec,
+		input:       input,
+	}
+	semaCtx := tree.MakeSemaContext()
+	if err := c.out.Init(ctx, post, colinfo.ExportColumnTypes, &semaCtx, flowCtx.NewEvalCtx()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type ndjsonWriterProcessor struct {
+	flowCtx     *execinfra.FlowCtx
+	processorID int32
+	spec        execinfrapb.ExportSpec
+	input       execinfra.RowSource
+	out         execinfra.ProcOutputHelper
+}
+
+var _ execinfra.Processor = &ndjsonWriterProcessor{}
+
+func (sp *ndjsonWriterProcessor) OutputTypes() []*types.T {
+	return sp.out.OutputTypes
+}
+
+func (sp *ndjsonWriterProcessor) MustBeStreaming() bool {
+	return false
+}
+
+func (sp *ndjsonWriterProcessor) Run(ctx context.Context, output execinfra.RowReceiver) {
+	ctx, span := tracing.ChildSpan(ctx, "ndjsonWriter")
+	defer span.Finish()
+
+	instanceID := sp.flowCtx.EvalCtx.NodeID.SQLInstanceID()
+	uniqueID := builtins.GenerateUniqueInt(builtins.ProcessUniqueID(instanceID))
+
+	err := func() error {
+		typs := sp.input.OutputTypes()
+		sp.input.Start(ctx)
+		input := execinfra.MakeNoMetadataRowSource(sp.input, output)
+		alloc := &tree.DatumAlloc{}
+		datumRow := make(tree.Datums, len(typs))
+
+		writer := newNDJSONExporter(sp.spec, len(typs))
+
+		chunk := 0
+		done := false
+		for {
+			var rows int64
+			writer.ResetBuffer()
+			for {
+				// If the bytes.Buffer sink exceeds the target size of a file, we flush
+				// before exporting any additional rows.
+				if int64(writer.Len()) >= sp.spec.ChunkSize {
+					break
+				}
+				if sp.spec.ChunkRows > 0 && rows >= sp.spec.ChunkRows {
+					break
+				}
+				row, err := input.NextRow()
+				if err != nil {
+					return err
+				}
+				if row == nil {
+					done = true
+					break
+				}
+				rows++
+
+				for i, ed := range row {
+					if err := ed.EnsureDecoded(typs[i], alloc); err != nil {
+						return err
+					}
+					datumRow[i] = ed.Datum
+				}
+				if err := writer.Write(datumRow); err != nil {
+					return err
+				}
+			}
+			if rows < 1 {
+				break
+			}
+
+			conf, err := cloud.ExternalStorageConfFromURI(sp.spec.Destination, sp.spec.User())
+			if err != nil {
+				return err
+			}
+			es, err := sp.flowCtx.Cfg.ExternalStorage(ctx, conf)
+			if err != nil {
+				return err
+			}
+			defer es.Close()
+
+			part := fmt.Sprintf("n%d.%d", uniqueID, chunk)
+			chunk++
+			filename := writer.FileName(sp.spec, part)
+			// Close writer to ensure buffer and any compression footer is flushed.
+			if err := writer.Close(); err != nil {
+				return errors.Wrapf(err, "failed to close exporting writer")
+			}
+
+			size := writer.Len()
+
+			if err := cloud.WriteFile(ctx, es, filename, bytes.NewReader(writer.Bytes())); err != nil {
+				return err
+			}
+			res := rowenc.EncDatumRow{
+				rowenc.DatumToEncDatum(
+					types.String,
+					tree.NewDString(filename),
+				),
+				rowenc.DatumToEncDatum(
+					types.Int,
+					tree.NewDInt(tree.DInt(rows)),
+				),
+				rowenc.DatumToEncDatum(
+					types.Int,
+					tree.NewDInt(tree.DInt(size)),
+				),
+			}
+
+			cs, err := sp.out.EmitRow(ctx, res, output)
+			if err != nil {
+				return err
+			}
+			if cs != execinfra.NeedMoreRows {
+				// We don't return an error here because we want the error (if any) that
+				// actually caused the consumer to enter a closed/draining state to take precendence.
+				return nil
+			}
+			if done {
+				break
+			}
+		}
+
+		return nil
+	}()
+
+	execinfra.DrainAndClose(
+		ctx, output, err, func(context.Context, execinfra.RowReceiver) {} /* pushTrailingMeta */, sp.input)
+}
+
+// Resume is part of the execinfra.Processor interface.
+func (sp *ndjsonWriterProcessor) Resume(output execinfra.RowReceiver) {
+	panic("not implemented")
+}
+
+func init() {
+	rowexec.NewNDJSONWriterProcessor = newNDJSONWriterProcessor
+}
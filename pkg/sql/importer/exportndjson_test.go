@@ -0,0 +1,79 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package importer_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportNDJSON(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	dir, cleanupDir := testutils.TempDir(t)
+	defer cleanupDir()
+
+	srv, db, _ := serverutils.StartServer(t, base.TestServerArgs{ExternalIODir: dir})
+	defer srv.Stopper().Stop(context.Background())
+	sqlDB := sqlutils.MakeSQLRunner(db)
+
+	sqlDB.Exec(t, `CREATE TABLE foo (
+  i INT PRIMARY KEY, s STRING, f FLOAT, d DECIMAL, ts TIMESTAMPTZ, a STRING[], j JSONB
+)`)
+	sqlDB.Exec(t, `INSERT INTO foo VALUES
+  (1, 'a"b', 1.5, 1.25, '2024-01-02 03:04:05+00', ARRAY['x', NULL], '{"k": [1]}'),
+  (2, NULL, NULL, NULL, NULL, NULL, NULL)`)
+
+	t.Run("export", func(t *testing.T) {
+		sqlDB.Exec(t, `EXPORT INTO NDJSON 'nodelocal://1/export' FROM SELECT * FROM foo ORDER BY i`)
+		content := readFileByGlob(t, filepath.Join(dir, "export", "export*-n*.0.ndjson"))
+		require.Equal(t,
+			`{"a": ["x", null], "d": 1.25, "f": 1.5, "i": 1, "j": {"k": [1]}, "s": "a\"b", "ts": "2024-01-02T03:04:05Z"}`+"\n"+
+				`{"a": null, "d": null, "f": null, "i": 2, "j": null, "s": null, "ts": null}`+"\n",
+			string(content))
+	})
+
+	t.Run("chunks-and-compression", func(t *testing.T) {
+		sqlDB.Exec(t, `EXPORT INTO NDJSON 'nodelocal://1/gzip' WITH chunk_rows = '1', compression = 'gzip'
+FROM SELECT i FROM foo ORDER BY i`)
+		for i, expected := range []string{`{"i": 1}` + "\n", `{"i": 2}` + "\n"} {
+			compressed := readFileByGlob(t, filepath.Join(dir, "gzip", "export*-n*."+string(rune('0'+i))+".ndjson.gz"))
+			gzipReader, err := gzip.NewReader(bytes.NewReader(compressed))
+			require.NoError(t, err)
+			content, err := io.ReadAll(gzipReader)
+			require.NoError(t, err)
+			require.NoError(t, gzipReader.Close())
+			require.Equal(t, expected, string(content))
+		}
+		sqlDB.ExpectErr(t, "unsupported compression codec snappy for ndjson file format",
+			`EXPORT INTO NDJSON 'nodelocal://1/snappy' WITH compression = 'snappy' FROM SELECT * FROM foo`)
+	})
+
+	t.Run("round-trip", func(t *testing.T) {
+		sqlDB.Exec(t, `EXPORT INTO NDJSON 'nodelocal://1/round-trip' FROM SELECT * FROM foo`)
+		sqlDB.Exec(t, `CREATE TABLE bar (LIKE foo INCLUDING ALL)`)
+		sqlDB.Exec(t, `IMPORT INTO bar NDJSON DATA ('nodelocal://1/round-trip/export*-n*.0.ndjson')`)
+		sqlDB.CheckQueryResults(t, `SELECT * FROM bar ORDER BY i`,
+			sqlDB.QueryStr(t, `SELECT * FROM foo ORDER BY i`))
+	})
+}
@@ -105,6 +105,10 @@ const (
 	pgDumpUnsupportedSchemaStmtLog = "unsupported_schema_stmts"
 	pgDumpUnsupportedDataStmtLog   = "unsupported_data_stmts"
 
+	// postgresPartitions is the number of key ranges the source table of an
+	// IMPORT ... FROM POSTGRES is split into.
+	postgresPartitions = "partitions"
+
 	// RunningStatusImportBundleParseSchema indicates to the user that a bundle format
 	// schema is being parsed
 	runningStatusImportBundleParseSchema jobs.RunningStatus = "parsing schema on Import Bundle"
@@ -144,6 +148,8 @@ var importOptionExpectValues = map[string]exprutil.KVStringOptValidate{
 
 	pgDumpIgnoreAllUnsupported: exprutil.KVStringOptRequireNoValue,
 	pgDumpIgnoreShuntFileDest:  exprutil.KVStringOptRequireValue,
+
+	postgresPartitions: exprutil.KVStringOptRequireValue,
 }
 
 var pgDumpMaxLoggedStmts = 1024
@@ -175,6 +181,11 @@ var avroAllowedOptions = makeStringSet(
 	avroRecordsSeparatedBy, avroSchema, avroSchemaURI, optMaxRowSize, csvRowLimit,
 )
 
+var (
+	parquetAllowedOptions = makeStringSet(avroStrict, csvRowLimit)
+	ndjsonAllowedOptions  = makeStringSet(avroStrict, csvRowLimit, optMaxRowSize)
+)
+
 var csvAllowedOptions = makeStringSet(
 	csvDelimiter, csvComment, csvNullIf, csvSkip, csvStrictQuotes, csvRowLimit, csvAllowQuotedNulls,
 )
@@ -189,6 +200,7 @@ var (
 	pgCopyAllowedOptions    = makeStringSet(pgCopyDelimiter, pgCopyNull, optMaxRowSize)
 	pgDumpAllowedOptions    = makeStringSet(optMaxRowSize, importOptionSkipFKs, csvRowLimit,
 		pgDumpIgnoreAllUnsupported, pgDumpIgnoreShuntFileDest)
+	postgresAllowedOptions = makeStringSet(postgresPartitions)
 )
 
 // DROP is required because the target table needs to be take offline during
@@ -201,6 +213,9 @@ var allowedIntoFormats = map[string]struct{}{
 	"AVRO":      {},
 	"DELIMITED": {},
 	"PGCOPY":    {},
+	"PARQUET":   {},
+	"NDJSON":    {},
+	"POSTGRES":  {},
 }
 
 // featureImportEnabled is used to enable and disable the IMPORT feature.
@@ -235,7 +250,13 @@ func importJobDescription(
 	stmt := *orig
 	stmt.Files = nil
 	for _, file := range files {
-		clean, err := cloud.SanitizeExternalStorageURI(file, nil /* extraParams */)
+		var clean string
+		var err error
+		if orig.SourceTable != nil {
+			clean, err = sanitizePostgresSourceURL(file)
+		} else {
+			clean, err = cloud.SanitizeExternalStorageURI(file, nil /* extraParams */)
+		}
 		if err != nil {
 			return "", err
 		}
@@ -403,9 +424,35 @@ func importPlanHook(
 		return nil, nil, nil, false, err
 	}
 
+	isSourceImport := importStmt.SourceTable != nil
+	if isSourceImport != (importStmt.FileFormat == "POSTGRES") {
+		if isSourceImport {
+			return nil, nil, nil, false, pgerror.Newf(pgcode.FeatureNotSupported,
+				"%s format cannot be imported from a source table", importStmt.FileFormat)
+		}
+		return nil, nil, nil, false, pgerror.New(pgcode.Syntax,
+			"POSTGRES format requires a source table: use IMPORT INTO ... FROM POSTGRES <url> TABLE <name>")
+	}
+
+	if isSourceImport {
+		// Importing from a live database makes the nodes of the cluster open
+		// connections to arbitrary hosts, so it is restricted to admins.
+		hasAdmin, err := p.HasAdminRole(ctx)
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+		if !hasAdmin {
+			return nil, nil, nil, false, pgerror.New(pgcode.InsufficientPrivilege,
+				"only users with the admin role are allowed to IMPORT FROM POSTGRES")
+		}
+	}
+
 	// Certain ExternalStorage URIs require super-user access. Check all the
 	// URIs passed to the IMPORT command.
 	for _, file := range filenamePatterns {
+		if isSourceImport {
+			break
+		}
 		_, err := cloud.ExternalStorageConfFromURI(file, p.User())
 		if err != nil {
 			// If it is a workload URI, it won't parse as a storage config, but it
@@ -430,7 +477,7 @@ func importPlanHook(
 		}
 
 		var files []string
-		if _, ok := opts[importOptionDisableGlobMatch]; ok {
+		if _, ok := opts[importOptionDisableGlobMatch]; ok || isSourceImport {
 			files = filenamePatterns
 		} else {
 			for _, file := range filenamePatterns {
@@ -532,6 +579,7 @@ func importPlanHook(
 		}
 
 		format := roachpb.IOFileFormat{}
+		sourcePartitions := defaultPostgresSourcePartitions
 		switch importStmt.FileFormat {
 		case "CSV":
 			if err = validateFormatOptions(importStmt.FileFormat, opts, csvAllowedOptions); err != nil {
@@ -751,6 +799,70 @@ func importPlanHook(
 			if err != nil {
 				return err
 			}
+		case "PARQUET":
+			if err = validateFormatOptions(importStmt.FileFormat, opts, parquetAllowedOptions); err != nil {
+				return err
+			}
+			// Parquet files compress their pages internally.
+			if _, ok := opts[importOptionDecompress]; ok {
+				return errors.Errorf("invalid option %q specified for %s import format",
+					importOptionDecompress, importStmt.FileFormat)
+			}
+			format.Format = roachpb.IOFileFormat_Parquet
+			_, format.Parquet.StrictMode = opts[avroStrict]
+			if override, ok := opts[csvRowLimit]; ok {
+				rowLimit, err := strconv.Atoi(override)
+				if err != nil {
+					return pgerror.Wrapf(err, pgcode.Syntax, "invalid numeric %s value", csvRowLimit)
+				}
+				if rowLimit <= 0 {
+					return pgerror.Newf(pgcode.Syntax, "%s must be > 0", csvRowLimit)
+				}
+				format.Parquet.RowLimit = int64(rowLimit)
+			}
+		case "NDJSON":
+			if err = validateFormatOptions(importStmt.FileFormat, opts, ndjsonAllowedOptions); err != nil {
+				return err
+			}
+			format.Format = roachpb.IOFileFormat_NDJSON
+			_, format.NDJSON.StrictMode = opts[avroStrict]
+			maxRowSize := int32(defaultScanBuffer)
+			if override, ok := opts[optMaxRowSize]; ok {
+				sz, err := humanizeutil.ParseBytes(override)
+				if err != nil {
+					return err
+				}
+				if sz < 1 || sz > math.MaxInt32 {
+					return errors.Errorf("%s out of range: %d", override, sz)
+				}
+				maxRowSize = int32(sz)
+			}
+			format.NDJSON.MaxRowSize = maxRowSize
+			if override, ok := opts[csvRowLimit]; ok {
+				rowLimit, err := strconv.Atoi(override)
+				if err != nil {
+					return pgerror.Wrapf(err, pgcode.Syntax, "invalid numeric %s value", csvRowLimit)
+				}
+				if rowLimit <= 0 {
+					return pgerror.Newf(pgcode.Syntax, "%s must be > 0", csvRowLimit)
+				}
+				format.NDJSON.RowLimit = int64(rowLimit)
+			}
+		case "POSTGRES":
+			if err = validateFormatOptions(importStmt.FileFormat, opts, postgresAllowedOptions); err != nil {
+				return err
+			}
+			format.Format = roachpb.IOFileFormat_Postgres
+			if override, ok := opts[postgresPartitions]; ok {
+				n, err := strconv.Atoi(override)
+				if err != nil {
+					return pgerror.Wrapf(err, pgcode.Syntax, "invalid numeric %s value", postgresPartitions)
+				}
+				if n <= 0 {
+					return pgerror.Newf(pgcode.Syntax, "%s must be > 0", postgresPartitions)
+				}
+				sourcePartitions = n
+			}
 		default:
 			return unimplemented.Newf("import.format", "unsupported import format: %q", importStmt.FileFormat)
 		}
@@ -862,6 +974,28 @@ func importPlanHook(
 				}
 			}
 
+			if isSourceImport {
+				// Read all the columns that can be written to, by name, if no
+				// target columns were specified.
+				if len(intoCols) == 0 {
+					for _, col := range found.VisibleColumns() {
+						if !col.IsComputed() {
+							intoCols = append(intoCols, col.GetName())
+						}
+					}
+				}
+				format.Postgres, err = planPostgresSource(
+					ctx, files[0], importStmt.SourceTable, intoCols, sourcePartitions)
+				if err != nil {
+					return err
+				}
+				// Each partition of the source table is imported like a file.
+				files = make([]string, len(format.Postgres.Partitions))
+				for i := range files {
+					files[i] = filenamePatterns[0]
+				}
+			}
+
 			tableDetails = []jobspb.ImportDetails_Table{{Desc: &found.TableDescriptor, IsNew: false, TargetCols: intoCols}}
 		} else if importStmt.Bundle {
 			// If we target a single table, populate details with one entry of tableName.
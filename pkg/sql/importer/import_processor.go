@@ -38,6 +38,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
 	"github.com/cockroachdb/cockroach/pkg/util/errorutil/unimplemented"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
@@ -262,6 +263,7 @@ func makeInputConverter(
 	kvCh chan row.KVBatch,
 	seqChunkProvider *row.SeqChunkProvider,
 	db *kv.DB,
+	memMonitor *mon.BytesMonitor,
 ) (inputConverter, error) {
 	injectTimeIntoEvalCtx(evalCtx, spec.WalltimeNanos)
 	var singleTable catalog.TableDescriptor
@@ -338,6 +340,17 @@ func makeInputConverter(
 		return newAvroInputReader(
 			semaCtx, kvCh, singleTable, spec.Format.Avro, spec.WalltimeNanos,
 			readerParallelism, evalCtx, db)
+	case roachpb.IOFileFormat_Parquet:
+		return newParquetInputReader(
+			semaCtx, kvCh, singleTable, spec.Format.Parquet, spec.WalltimeNanos,
+			readerParallelism, evalCtx, db, memMonitor), nil
+	case roachpb.IOFileFormat_NDJSON:
+		return newNDJSONInputReader(
+			semaCtx, kvCh, singleTable, spec.Format.NDJSON, spec.WalltimeNanos,
+			readerParallelism, evalCtx, db), nil
+	case roachpb.IOFileFormat_Postgres:
+		return newPostgresSourceReader(semaCtx, spec.Format.Postgres, spec.ResumeKey, kvCh, spec.WalltimeNanos,
+			readerParallelism, singleTable, singleTableTargetCols, evalCtx, db), nil
 	default:
 		return nil, errors.Errorf(
 			"Requested IMPORT format (%d) not supported by this node", spec.Format.Format)
@@ -425,13 +438,25 @@ func ingestKvs(
 	//  - writtenFraction contains % of the input finished as of last batch.
 	//  - pkFlushedRow contains `writtenRow` as of the last pk adder flush.
 	//  - idxFlushedRow contains `writtenRow` as of the last index adder flush.
+	//  - writtenKey, flushedKeys.pk and flushedKeys.idx contain the keys of the
+	//    rows above, for inputs whose rows are addressed by key.
 	// In pkFlushedRow, idxFlushedRow and writtenFaction values are written via
-	// `atomic` so the progress reporting go goroutine can read them.
+	// `atomic` so the progress reporting go goroutine can read them. The flushed
+	// rows are also written under flushedKeys, so that the progress reporting
+	// goroutine reads each of them along with its key.
 	writtenRow := make([]int64, len(spec.Uri))
+	writtenKey := make([]string, len(spec.Uri))
 	writtenFraction := make([]uint32, len(spec.Uri))
 
 	pkFlushedRow := make([]int64, len(spec.Uri))
 	idxFlushedRow := make([]int64, len(spec.Uri))
+	flushedKeys := &struct {
+		syncutil.Mutex
+		pk, idx []string
+	}{
+		pk:  make([]string, len(spec.Uri)),
+		idx: make([]string, len(spec.Uri)),
+	}
 
 	bulkSummaryMu := &struct {
 		syncutil.Mutex
@@ -442,6 +467,9 @@ func ingestKvs(
 	// pkFlushedRow to writtenRow. Additionally if the indexAdder is empty then we
 	// can treat it as flushed as well (in case we're not adding anything to it).
 	pkIndexAdder.SetOnFlush(func(summary kvpb.BulkOpSummary) {
+		flushedKeys.Lock()
+		defer flushedKeys.Unlock()
+		copy(flushedKeys.pk, writtenKey)
 		for i, emitted := range writtenRow {
 			atomic.StoreInt64(&pkFlushedRow[i], emitted)
 			bulkSummaryMu.Lock()
@@ -449,12 +477,16 @@ func ingestKvs(
 			bulkSummaryMu.Unlock()
 		}
 		if indexAdder.IsEmpty() {
+			copy(flushedKeys.idx, writtenKey)
 			for i, emitted := range writtenRow {
 				atomic.StoreInt64(&idxFlushedRow[i], emitted)
 			}
 		}
 	})
 	indexAdder.SetOnFlush(func(summary kvpb.BulkOpSummary) {
+		flushedKeys.Lock()
+		defer flushedKeys.Unlock()
+		copy(flushedKeys.idx, writtenKey)
 		for i, emitted := range writtenRow {
 			atomic.StoreInt64(&idxFlushedRow[i], emitted)
 			bulkSummaryMu.Lock()
@@ -476,8 +508,14 @@ func ingestKvs(
 		prog.ResumePos = make(map[int32]int64)
 		prog.CompletedFraction = make(map[int32]float32)
 		for file, offset := range offsets {
+			flushedKeys.Lock()
 			pk := atomic.LoadInt64(&pkFlushedRow[offset])
 			idx := atomic.LoadInt64(&idxFlushedRow[offset])
+			key := flushedKeys.idx[offset]
+			if idx > pk {
+				key = flushedKeys.pk[offset]
+			}
+			flushedKeys.Unlock()
 			// On resume we'll be able to skip up the last row for which both the
 			// PK and index adders have flushed KVs.
 			if idx > pk {
@@ -485,6 +523,12 @@ func ingestKvs(
 			} else {
 				prog.ResumePos[file] = idx
 			}
+			if key != "" {
+				if prog.ResumeKey == nil {
+					prog.ResumeKey = make(map[int32]string)
+				}
+				prog.ResumeKey[file] = key
+			}
 			prog.CompletedFraction[file] = math.Float32frombits(atomic.LoadUint32(&writtenFraction[offset]))
 			// Write down the summary of how much we've ingested since the last update.
 			bulkSummaryMu.Lock()
@@ -571,6 +615,7 @@ func ingestKvs(
 			}
 			offset := offsets[kvBatch.Source]
 			writtenRow[offset] = kvBatch.LastRow
+			writtenKey[offset] = kvBatch.ResumeKey
 			atomic.StoreUint32(&writtenFraction[offset], math.Float32bits(kvBatch.Progress))
 			if flowCtx.Cfg.TestingKnobs.BulkAdderFlushesEveryBatch {
 				_ = pkIndexAdder.Flush(ctx)
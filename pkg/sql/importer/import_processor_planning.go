@@ -72,6 +72,18 @@ func distImport(
 	ctx, sp := tracing.ChildSpan(ctx, "importer.distImport")
 	defer sp.Finish()
 
+	if format.Format == roachpb.IOFileFormat_Postgres && format.Postgres.AsOf == "" {
+		// All the partitions of the source table are read from a snapshot that
+		// lives as long as the transaction that exported it, so that transaction
+		// is kept open until they have been read.
+		snapshot, release, err := exportPostgresSourceSnapshot(ctx, from[0])
+		if err != nil {
+			return kvpb.BulkOpSummary{}, err
+		}
+		defer release(ctx)
+		format.Postgres.Snapshot = snapshot
+	}
+
 	dsp := execCtx.DistSQLPlanner()
 	makePlan := func(ctx context.Context, dsp *sql.DistSQLPlanner) (*sql.PhysicalPlan, *sql.PlanningCtx, error) {
 		evalCtx := execCtx.ExtendedEvalContext()
@@ -159,6 +171,14 @@ func distImport(
 
 	rowProgress := make([]int64, len(from))
 	fractionProgress := make([]uint32, len(from))
+	// resumeKeys holds the key of the row at rowProgress of the inputs whose
+	// rows are addressed by key. The row progress of such inputs is written
+	// under it too, so that each row is recorded along with its key.
+	resumeKeys := struct {
+		syncutil.Mutex
+		keys []string
+	}{keys: make([]string, len(from))}
+	copy(resumeKeys.keys, importDetails.ResumeKey)
 
 	updateJobProgress := func() error {
 		return job.NoTxn().FractionProgressed(ctx, func(
@@ -166,9 +186,12 @@ func distImport(
 		) float32 {
 			var overall float32
 			prog := details.(*jobspb.Progress_Import).Import
+			resumeKeys.Lock()
 			for i := range rowProgress {
 				prog.ResumePos[i] = atomic.LoadInt64(&rowProgress[i])
 			}
+			prog.ResumeKey = append(prog.ResumeKey[:0], resumeKeys.keys...)
+			resumeKeys.Unlock()
 			for i := range fractionProgress {
 				fileProgress := math.Float32frombits(atomic.LoadUint32(&fractionProgress[i]))
 				prog.ReadProgress[i] = fileProgress
@@ -186,9 +209,14 @@ func distImport(
 
 	metaFn := func(_ context.Context, meta *execinfrapb.ProducerMetadata) error {
 		if meta.BulkProcessorProgress != nil {
+			resumeKeys.Lock()
 			for i, v := range meta.BulkProcessorProgress.ResumePos {
 				atomic.StoreInt64(&rowProgress[i], v)
 			}
+			for i, k := range meta.BulkProcessorProgress.ResumeKey {
+				resumeKeys.keys[i] = k
+			}
+			resumeKeys.Unlock()
 			for i, v := range meta.BulkProcessorProgress.CompletedFraction {
 				atomic.StoreUint32(&fractionProgress[i], math.Float32bits(v))
 			}
@@ -336,6 +364,12 @@ func makeImportReaderSpecs(
 		if importProgress.ResumePos != nil {
 			inputSpecs[n].ResumePos[int32(i)] = importProgress.ResumePos[int32(i)]
 		}
+		if i < len(importProgress.ResumeKey) && importProgress.ResumeKey[i] != "" {
+			if inputSpecs[n].ResumeKey == nil {
+				inputSpecs[n].ResumeKey = make(map[int32]string)
+			}
+			inputSpecs[n].ResumeKey[int32(i)] = importProgress.ResumeKey[i]
+		}
 	}
 
 	for i := range inputSpecs {
@@ -126,7 +126,7 @@ func TestConverterFlushesBatches(t *testing.T) {
 				kvCh := make(chan row.KVBatch, batchSize)
 				semaCtx := tree.MakeSemaContext()
 				conv, err := makeInputConverter(ctx, &semaCtx, converterSpec, &evalCtx, kvCh,
-					nil /* seqChunkProvider */, db)
+					nil /* seqChunkProvider */, db, evalCtx.TestingMon)
 				if err != nil {
 					t.Fatalf("makeInputConverter() error = %v", err)
 				}
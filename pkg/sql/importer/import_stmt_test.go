@@ -6840,6 +6840,215 @@ func TestImportAvro(t *testing.T) {
 	})
 }
 
+func TestImportParquet(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	baseDir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	tc := serverutils.StartCluster(
+		t, 3, base.TestClusterArgs{ServerArgs: base.TestServerArgs{ExternalIODir: baseDir}})
+	defer tc.Stopper().Stop(ctx)
+	sqlDB := sqlutils.MakeSQLRunner(tc.ServerConn(0))
+
+	sqlDB.Exec(t, `CREATE DATABASE foo; SET DATABASE = foo`)
+	sqlDB.Exec(t, `CREATE TABLE src (
+  i INT8 PRIMARY KEY, s STRING, b BYTES, f FLOAT8, d DECIMAL(10, 2), bo BOOL,
+  dt DATE, ts TIMESTAMP, tstz TIMESTAMPTZ, u UUID, a INT8[], j JSONB
+)`)
+	sqlDB.Exec(t, `INSERT INTO src SELECT
+  g, 'str' || g::STRING, ('bytes' || g::STRING)::BYTES, g::FLOAT8 / 3, g::DECIMAL / 4, g % 2 = 0,
+  '2024-01-01'::DATE + g, '2024-01-01 12:34:56.789'::TIMESTAMP + g * '1s'::INTERVAL,
+  '2024-01-01 12:34:56.789+00'::TIMESTAMPTZ, gen_random_uuid(), ARRAY[g, NULL, g * 2],
+  json_build_object('k', g)
+FROM generate_series(1, 1000) AS g`)
+	sqlDB.Exec(t, `INSERT INTO src (i) VALUES (0)`)
+	sqlDB.Exec(t, `EXPORT INTO PARQUET 'nodelocal://1/src' FROM SELECT * FROM src`)
+	const data = `'nodelocal://1/src/export*-n*.0.parquet'`
+
+	t.Run("round-trip", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE dst (LIKE src INCLUDING ALL)`)
+		defer sqlDB.Exec(t, `DROP TABLE dst`)
+		sqlDB.Exec(t, `IMPORT INTO dst PARQUET DATA (`+data+`) WITH strict_validation`)
+		sqlDB.CheckQueryResults(t, `SELECT * FROM dst ORDER BY i`,
+			sqlDB.QueryStr(t, `SELECT * FROM src ORDER BY i`))
+	})
+
+	t.Run("columns-by-name", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE dst (s STRING, extra INT8, i INT8 PRIMARY KEY)`)
+		defer sqlDB.Exec(t, `DROP TABLE dst`)
+		sqlDB.Exec(t, `IMPORT INTO dst PARQUET DATA (`+data+`)`)
+		sqlDB.CheckQueryResults(t, `SELECT s, extra, i FROM dst ORDER BY i`,
+			sqlDB.QueryStr(t, `SELECT s, NULL::INT8, i FROM src ORDER BY i`))
+	})
+
+	t.Run("nested-to-jsonb", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE dst (i INT8 PRIMARY KEY, a JSONB)`)
+		defer sqlDB.Exec(t, `DROP TABLE dst`)
+		sqlDB.Exec(t, `IMPORT INTO dst PARQUET DATA (`+data+`)`)
+		sqlDB.CheckQueryResults(t, `SELECT a FROM dst WHERE i = 3`, [][]string{{`[3, null, 6]`}})
+	})
+
+	t.Run("row-limit", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE dst (i INT8 PRIMARY KEY)`)
+		defer sqlDB.Exec(t, `DROP TABLE dst`)
+		sqlDB.Exec(t, `IMPORT INTO dst PARQUET DATA (`+data+`) WITH row_limit = '10'`)
+		sqlDB.CheckQueryResults(t, `SELECT count(*) FROM dst`, [][]string{{"10"}})
+	})
+
+	t.Run("strict-errors-extra-fields", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE dst (i INT8 PRIMARY KEY)`)
+		defer sqlDB.Exec(t, `DROP TABLE dst`)
+		sqlDB.ExpectErr(t, "could not find column for parquet column s",
+			`IMPORT INTO dst PARQUET DATA (`+data+`) WITH strict_validation`)
+	})
+
+	t.Run("strict-errors-missing-fields", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE dst (LIKE src, z INT8)`)
+		defer sqlDB.Exec(t, `DROP TABLE dst`)
+		sqlDB.ExpectErr(t, "column z is missing from the parquet file",
+			`IMPORT INTO dst PARQUET DATA (`+data+`) WITH strict_validation`)
+	})
+}
+
+func TestImportNDJSON(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	baseDir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	tc := serverutils.StartCluster(
+		t, 3, base.TestClusterArgs{ServerArgs: base.TestServerArgs{ExternalIODir: baseDir}})
+	defer tc.Stopper().Stop(ctx)
+	sqlDB := sqlutils.MakeSQLRunner(tc.ServerConn(0))
+
+	sqlDB.Exec(t, `CREATE DATABASE foo; SET DATABASE = foo`)
+
+	var buf bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&buf,
+			`{"i": %[1]d, "S": "str%[1]d", "f": %[1]d.5, "d": "2024-01-01", "a": [%[1]d, null], "j": {"k": [%[1]d]}, "extra": true}`+"\n",
+			i)
+		if i%100 == 0 {
+			// Blank lines are ignored.
+			buf.WriteString("\n")
+		}
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "data.ndjson"), buf.Bytes(), 0644))
+	const data = `'nodelocal://1/data.ndjson'`
+
+	t.Run("import", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE t (i INT8 PRIMARY KEY, s STRING, f FLOAT8, d DATE, a INT8[], j JSONB, z INT8)`)
+		defer sqlDB.Exec(t, `DROP TABLE t`)
+		sqlDB.Exec(t, `IMPORT INTO t NDJSON DATA (`+data+`)`)
+		sqlDB.CheckQueryResults(t, `SELECT count(*), sum(i), count(z) FROM t`,
+			[][]string{{"1000", "499500", "0"}})
+		sqlDB.CheckQueryResults(t, `SELECT s, f, d::STRING, a, j FROM t WHERE i = 7`,
+			[][]string{{"str7", "7.5", "2024-01-01", "{7,NULL}", `{"k": [7]}`}})
+	})
+
+	t.Run("row-limit", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE t (i INT8 PRIMARY KEY)`)
+		defer sqlDB.Exec(t, `DROP TABLE t`)
+		sqlDB.Exec(t, `IMPORT INTO t NDJSON DATA (`+data+`) WITH row_limit = '10'`)
+		sqlDB.CheckQueryResults(t, `SELECT count(*) FROM t`, [][]string{{"10"}})
+	})
+
+	t.Run("strict-errors-extra-fields", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE t (i INT8 PRIMARY KEY, s STRING, f FLOAT8, d DATE, a INT8[], j JSONB)`)
+		defer sqlDB.Exec(t, `DROP TABLE t`)
+		sqlDB.ExpectErr(t, "could not find column for field extra",
+			`IMPORT INTO t NDJSON DATA (`+data+`) WITH strict_validation`)
+	})
+
+	t.Run("max-row-size", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE t (i INT8 PRIMARY KEY)`)
+		defer sqlDB.Exec(t, `DROP TABLE t`)
+		sqlDB.ExpectErr(t, "line exceeds max_row_size",
+			`IMPORT INTO t NDJSON DATA (`+data+`) WITH max_row_size = '10B'`)
+	})
+}
+
+func TestImportFromPostgres(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	tc := serverutils.StartCluster(t, 3, base.TestClusterArgs{})
+	defer tc.Stopper().Stop(ctx)
+	sqlDB := sqlutils.MakeSQLRunner(tc.ServerConn(0))
+
+	// The source of the import is another database of the same cluster,
+	// accessed over the PostgreSQL wire protocol like any other source.
+	sqlDB.Exec(t, `CREATE DATABASE src`)
+	sqlDB.Exec(t, `CREATE TABLE src.t (a INT8, b STRING, c DECIMAL, d INT8[], j JSONB, PRIMARY KEY (a, b))`)
+	sqlDB.Exec(t, `INSERT INTO src.t
+		SELECT i % 10, 'k' || i::STRING || e'\t\n\\', i::DECIMAL / 3, ARRAY[i, NULL], json_build_object('i', i)
+		FROM generate_series(1, 1000) AS g(i)`)
+
+	pgURL, cleanup := sqlutils.PGUrl(t, tc.ApplicationLayer(0).AdvSQLAddr(),
+		"TestImportFromPostgres", url.UserPassword(username.RootUser, "hunter2"))
+	defer cleanup()
+	pgURL.Path = "src"
+	source := `'` + pgURL.String() + `'`
+
+	sqlDB.Exec(t, `CREATE DATABASE foo; SET DATABASE = foo`)
+	const cmpQuery = `SELECT count(*) FROM (
+		(SELECT a, b, c, d, j::STRING FROM %[1]s EXCEPT SELECT a, b, c, d, j::STRING FROM %[2]s)
+		UNION ALL
+		(SELECT a, b, c, d, j::STRING FROM %[2]s EXCEPT SELECT a, b, c, d, j::STRING FROM %[1]s))`
+
+	t.Run("import", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE t (a INT8, b STRING, c DECIMAL, d INT8[], j JSONB, PRIMARY KEY (a, b))`)
+		defer sqlDB.Exec(t, `DROP TABLE t`)
+		sqlDB.Exec(t, `IMPORT INTO t FROM POSTGRES `+source+` TABLE t WITH partitions = '7'`)
+		sqlDB.CheckQueryResults(t, `SELECT count(*) FROM t`, [][]string{{"1000"}})
+		sqlDB.CheckQueryResults(t, fmt.Sprintf(cmpQuery, "t", "src.t"), [][]string{{"0"}})
+
+		// The credentials of the source are not part of the job description.
+		var description string
+		sqlDB.QueryRow(t, `SELECT description FROM [SHOW JOBS] WHERE job_type = 'IMPORT'`).Scan(&description)
+		require.Contains(t, description, "TABLE t")
+		require.NotContains(t, description, "hunter2")
+	})
+
+	t.Run("target-columns", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE t (a INT8, b STRING, z INT8 DEFAULT 7, PRIMARY KEY (a, b))`)
+		defer sqlDB.Exec(t, `DROP TABLE t`)
+		sqlDB.Exec(t, `IMPORT INTO t (a, b) FROM POSTGRES `+source+` TABLE public.t`)
+		sqlDB.CheckQueryResults(t, `SELECT count(*), sum(z) FROM t`, [][]string{{"1000", "7000"}})
+	})
+
+	t.Run("empty-source", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE src.empty (a INT8 PRIMARY KEY)`)
+		sqlDB.Exec(t, `CREATE TABLE t (a INT8 PRIMARY KEY)`)
+		defer sqlDB.Exec(t, `DROP TABLE t`)
+		sqlDB.Exec(t, `IMPORT INTO t FROM POSTGRES `+source+` TABLE empty`)
+		sqlDB.CheckQueryResults(t, `SELECT count(*) FROM t`, [][]string{{"0"}})
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE TABLE t (a INT8, b STRING, missing INT8, PRIMARY KEY (a, b))`)
+		defer sqlDB.Exec(t, `DROP TABLE t`)
+		sqlDB.ExpectErr(t, `column "missing" does not exist in source table t`,
+			`IMPORT INTO t FROM POSTGRES `+source+` TABLE t`)
+		sqlDB.ExpectErr(t, `source table nope does not exist`,
+			`IMPORT INTO t (a, b) FROM POSTGRES `+source+` TABLE nope`)
+		sqlDB.ExpectErr(t, `must not specify a database`,
+			`IMPORT INTO t (a, b) FROM POSTGRES `+source+` TABLE src.public.t`)
+		sqlDB.ExpectErr(t, `CSV format cannot be imported from a source table`,
+			`IMPORT INTO t (a, b) FROM CSV `+source+` TABLE t`)
+		sqlDB.ExpectErr(t, `POSTGRES format requires a source table`,
+			`IMPORT INTO t (a, b) POSTGRES DATA (`+source+`)`)
+		sqlDB.ExpectErr(t, `partitions must be > 0`,
+			`IMPORT INTO t (a, b) FROM POSTGRES `+source+` TABLE t WITH partitions = '0'`)
+	})
+}
+
 // TestImportClientDisconnect ensures that an import job can complete even if
 // the client connection which started it closes. This test uses a helper
 // subprocess to force a closed client connection without needing to rely
@@ -6953,6 +7162,8 @@ func TestDisallowsInvalidFormatOptions(t *testing.T) {
 	addOpts(mysqlOutAllowedOptions)
 	addOpts(pgDumpAllowedOptions)
 	addOpts(pgCopyAllowedOptions)
+	addOpts(parquetAllowedOptions)
+	addOpts(ndjsonAllowedOptions)
 
 	// Helper to pick num options from the set of allowed and the set
 	// of all other options.  Returns generated options plus a flag indicating
@@ -6999,6 +7210,8 @@ func TestDisallowsInvalidFormatOptions(t *testing.T) {
 		{"mysqldump", mysqlDumpAllowedOptions},
 		{"pgdump", pgDumpAllowedOptions},
 		{"pgcopy", pgCopyAllowedOptions},
+		{"parquet", parquetAllowedOptions},
+		{"ndjson", ndjsonAllowedOptions},
 	}
 
 	for _, tc := range tests {
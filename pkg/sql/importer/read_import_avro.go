@@ -44,6 +44,8 @@ func nativeTimeToDatum(t time.Time, targetT *types.T) (tree.Datum, error) {
 		return tree.NewDDateFromTime(t)
 	case types.TimestampFamily:
 		return tree.MakeDTimestamp(t, duration)
+	case types.TimestampTZFamily:
+		return tree.MakeDTimestampTZ(t, duration)
 	default:
 		return nil, errors.New("type not supported")
 	}
@@ -182,9 +184,10 @@ var familyToAvroT = map[types.Family][]string{
 	types.TimestampFamily: {"string", "long.timestamp-micros", "long.timestamp-millis"},
 
 	// goavro does not yet support times with local timezones. So, CRDB can only
-	// import these datum types if the goAvro type is string.
+	// import times with time zones if the goAvro type is string. Timestamps are
+	// instants in UTC, which can be imported as timestamps with time zones.
 	types.TimeTZFamily:      {"string"},
-	types.TimestampTZFamily: {"string"},
+	types.TimestampTZFamily: {"string", "long.timestamp-micros", "long.timestamp-millis"},
 
 	// goavro does no support the interval logical type
 	types.IntervalFamily: {"string"},
@@ -79,6 +79,8 @@ func logicalEncoder(datum tree.Datum, avroType string) (ans interface{}, err err
 		ans = datum.(*tree.DDate).Date.UnixEpochDays()
 	case types.TimestampFamily:
 		ans = datum.(*tree.DTimestamp).Time
+	case types.TimestampTZFamily:
+		ans = datum.(*tree.DTimestampTZ).Time
 	case types.TimeFamily:
 		// CRDB's TimeFamily stores time as Microseconds since midnight, while the
 		// goAvro package creates columns of logical type Time using go's
@@ -225,6 +227,18 @@ func TestImportAvroLogicalTypes(t *testing.T) {
 			crdbType: "TIMESTAMP(3)",
 			nullable: true,
 		},
+		{
+			name:     "tstz_micros",
+			avroType: "long.timestamp-micros",
+			crdbType: "TIMESTAMPTZ",
+			nullable: true,
+		},
+		{
+			name:     "tstz_millis",
+			avroType: "long.timestamp-millis",
+			crdbType: "TIMESTAMPTZ(3)",
+			nullable: true,
+		},
 		{
 			name:     "dt_not_null",
 			avroType: "int.date",
@@ -273,7 +287,9 @@ func TestImportAvroLogicalTypes(t *testing.T) {
 	// MaxSuppportedTime make the test fail
 	maxTime := time.Date(3000, time.January, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339Nano)
 	minTime := time.Date(-2000, time.January, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339Nano)
-	for _, tsCol := range []string{"ts_micros", "ts_micros_not_null", "ts_millis", "ts_millis_not_null"} {
+	for _, tsCol := range []string{
+		"ts_micros", "ts_micros_not_null", "ts_millis", "ts_millis_not_null", "tstz_micros", "tstz_millis",
+	} {
 		sqlDB.Exec(t, fmt.Sprintf(`ALTER TABLE %s ADD CONSTRAINT no_out_of_bounds_ts_%s CHECK (%s BETWEEN '%v' AND '%v')`, origTableName, tsCol, tsCol, minTime, maxTime))
 	}
 
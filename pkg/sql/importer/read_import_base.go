@@ -73,7 +73,9 @@ func runImport(
 	evalCtx.Regions = makeImportRegionOperator(spec.DatabasePrimaryRegion)
 	semaCtx := tree.MakeSemaContext()
 	semaCtx.TypeResolver = importResolver
-	conv, err := makeInputConverter(ctx, &semaCtx, spec, evalCtx, kvCh, seqChunkProvider, flowCtx.Cfg.DB.KV())
+	conv, err := makeInputConverter(
+		ctx, &semaCtx, spec, evalCtx, kvCh, seqChunkProvider, flowCtx.Cfg.DB.KV(), flowCtx.Mon,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -391,6 +393,8 @@ type inputConverter interface {
 func formatHasNamedColumns(format roachpb.IOFileFormat_FileFormat) bool {
 	switch format {
 	case roachpb.IOFileFormat_Avro,
+		roachpb.IOFileFormat_Parquet,
+		roachpb.IOFileFormat_NDJSON,
 		roachpb.IOFileFormat_Mysqldump,
 		roachpb.IOFileFormat_PgDump:
 		return true
@@ -476,6 +480,10 @@ type importFileContext struct {
 	skip     int64       // Number of records to skip
 	rejected chan string // Channel for reporting corrupt "rows"
 	rowLimit int64       // Number of records to process before we stop importing from a file.
+	// For sources resumed by key, the number of records read before the
+	// resumption and the key of the last of them.
+	resumed   int64
+	resumeKey string
 }
 
 // handleCorruptRow reports an error encountered while processing a row
@@ -536,6 +544,20 @@ type importRowConsumer interface {
 	FillDatums(ctx context.Context, row interface{}, rowNum int64, conv *row.DatumRowConverter) error
 }
 
+// importRowKeyer is implemented by the consumers of sources whose rows are
+// addressed by key, so that the import of such a source can be resumed after
+// the key of the last imported row rather than by skipping rows.
+type importRowKeyer interface {
+	// RowKey returns the key of the row.
+	RowKey(row interface{}) string
+}
+
+// emittedRowKey is the index and key of a row emitted by a worker.
+type emittedRowKey struct {
+	row int64
+	key string
+}
+
 // batch represents batch of data to convert.
 type batch struct {
 	data     []interface{}
@@ -590,6 +612,13 @@ func runParallelImport(
 	// Start consumers.
 
 	minEmited := make([]int64, importCtx.numWorkers)
+	var emittedKeys []atomic.Pointer[emittedRowKey]
+	if _, ok := consumer.(importRowKeyer); ok {
+		emittedKeys = make([]atomic.Pointer[emittedRowKey], importCtx.numWorkers)
+		for i := range emittedKeys {
+			emittedKeys[i].Store(&emittedRowKey{row: fileCtx.resumed, key: fileCtx.resumeKey})
+		}
+	}
 	group.GoCtx(func(ctx context.Context) error {
 		var span *tracing.Span
 		ctx, span = tracing.ChildSpan(ctx, "import-rows-to-datums")
@@ -598,7 +627,7 @@ func runParallelImport(
 			return errors.AssertionFailedf("invalid parallelism: %d", importCtx.numWorkers)
 		}
 		return ctxgroup.GroupWorkers(ctx, importCtx.numWorkers, func(ctx context.Context, id int) error {
-			return importer.importWorker(ctx, id, consumer, importCtx, fileCtx, minEmited)
+			return importer.importWorker(ctx, id, consumer, importCtx, fileCtx, minEmited, emittedKeys)
 		})
 	})
 
@@ -608,8 +637,9 @@ func runParallelImport(
 		var span *tracing.Span
 		ctx, span = tracing.ChildSpan(ctx, "import-file-to-rows")
 		defer span.Finish()
-		var numSkipped int64
-		var count int64
+		// The rows read before a resumption by key are counted as skipped.
+		numSkipped := fileCtx.resumed
+		count := fileCtx.resumed
 		for producer.Scan() {
 			// Skip rows if needed.
 			count++
@@ -700,6 +730,7 @@ func (p *parallelImporter) importWorker(
 	importCtx *parallelImportContext,
 	fileCtx *importFileContext,
 	minEmitted []int64,
+	emittedKeys []atomic.Pointer[emittedRowKey],
 ) error {
 	conv, err := makeDatumConverter(ctx, importCtx, fileCtx, importCtx.db)
 	if err != nil {
@@ -716,11 +747,29 @@ func (p *parallelImporter) importWorker(
 		m := emittedRowLowWatermark(workerID, rowNum, minEmitted)
 		return m
 	}
+	keyer, _ := consumer.(importRowKeyer)
+	if keyer != nil {
+		// The key reported with a row index must be the key of that very row,
+		// so the index and key of the rows emitted by each worker are published
+		// together.
+		var resumeKey string
+		conv.CompletedRowFn = func() int64 {
+			m := emittedKeyLowWatermark(emittedKeys)
+			resumeKey = m.key
+			return m.row
+		}
+		conv.ResumeKeyFn = func() string {
+			return resumeKey
+		}
+	}
 
 	for batch := range p.recordCh {
 		conv.KvBatch.Progress = batch.progress
 		for batchIdx, record := range batch.data {
 			rowNum = batch.startPos + int64(batchIdx)
+			if keyer != nil {
+				emittedKeys[workerID].Store(&emittedRowKey{row: rowNum, key: keyer.RowKey(record)})
+			}
 			if err := consumer.FillDatums(ctx, record, rowNum, conv); err != nil {
 				if err = handleCorruptRow(ctx, fileCtx, err); err != nil {
 					return err
@@ -757,3 +806,15 @@ func emittedRowLowWatermark(workerID int, emittedRow int64, minEmitted []int64)
 
 	return emittedRow
 }
+
+// emittedKeyLowWatermark returns the index and key of the lowest row emitted
+// across all workers.
+func emittedKeyLowWatermark(emittedKeys []atomic.Pointer[emittedRowKey]) emittedRowKey {
+	m := *emittedKeys[0].Load()
+	for i := 1; i < len(emittedKeys); i++ {
+		if e := emittedKeys[i].Load(); e.row < m.row {
+			m = *e
+		}
+	}
+	return m
+}
@@ -12,6 +12,7 @@ package importer
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
 	"testing"
 	"time"
@@ -200,3 +201,90 @@ func TestParallelImportProducerHandlesCancellation(t *testing.T) {
 			return nil
 		}))
 }
+
+// countingProducer produces the numbers from start+1 to end.
+// It implements importRowProducer.
+type countingProducer struct {
+	cur, end int64
+}
+
+func (p *countingProducer) Scan() bool {
+	p.cur++
+	return p.cur <= p.end
+}
+
+func (p *countingProducer) Err() error {
+	return nil
+}
+
+func (p *countingProducer) Skip() error {
+	return nil
+}
+
+func (p *countingProducer) Row() (interface{}, error) {
+	return p.cur, nil
+}
+
+func (p *countingProducer) Progress() float32 {
+	return 0.0
+}
+
+var _ importRowProducer = &countingProducer{}
+
+// keyedConsumer emits its rows, keyed by their number.
+// It implements importRowConsumer and importRowKeyer.
+type keyedConsumer struct {
+	nilDataConsumer
+}
+
+func (k *keyedConsumer) RowKey(row interface{}) string {
+	return fmt.Sprint(row)
+}
+
+var _ importRowKeyer = &keyedConsumer{}
+
+func TestParallelImportReportsResumeKeys(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	// Dummy descriptor for import
+	descr := descpb.TableDescriptor{
+		Name: "test",
+		Columns: []descpb.ColumnDescriptor{
+			{Name: "column", ID: 1, Type: types.Int, Nullable: true},
+		},
+	}
+
+	// Flush datum converter frequently
+	defer row.TestingSetDatumRowConverterBatchSize(1)()
+
+	const resumed, end = 100, 1000
+	kvCh := make(chan row.KVBatch)
+	semaCtx := tree.MakeSemaContext()
+	importCtx := &parallelImportContext{
+		semaCtx:    &semaCtx,
+		numWorkers: 4,
+		batchSize:  3,
+		evalCtx:    testEvalCtx,
+		tableDesc:  tabledesc.NewBuilder(&descr).BuildImmutableTable(),
+		kvCh:       kvCh,
+	}
+	fileCtx := &importFileContext{resumed: resumed, resumeKey: fmt.Sprint(resumed)}
+
+	g := ctxgroup.WithContext(context.Background())
+	g.GoCtx(func(ctx context.Context) error {
+		defer close(kvCh)
+		return runParallelImport(ctx, importCtx, fileCtx,
+			&countingProducer{cur: resumed, end: end}, &keyedConsumer{})
+	})
+	var batches int
+	for b := range kvCh {
+		batches++
+		// Each batch reports the key of the very row it reports the index of.
+		require.Equal(t, fmt.Sprint(b.LastRow), b.ResumeKey)
+		require.GreaterOrEqual(t, b.LastRow, int64(resumed))
+		require.LessOrEqual(t, b.LastRow, int64(end))
+	}
+	require.NoError(t, g.Wait())
+	require.NotZero(t, batches)
+}
@@ -0,0 +1,291 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/lexbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
+	"github.com/cockroachdb/errors"
+)
+
+// jsonToDatum converts a JSON value to a datum of the given type. Strings are
+// parsed as the target type, so that any type can be imported from its string
+// representation; JSON arrays are converted element-wise to array columns, and
+// any value can be imported into a JSONB column as is.
+func jsonToDatum(
+	ctx context.Context, j json.JSON, targetT *types.T, evalCtx *eval.Context,
+) (tree.Datum, error) {
+	if j.Type() == json.NullJSONType {
+		return tree.DNull, nil
+	}
+	if targetT.Family() == types.JsonFamily {
+		return tree.NewDJSON(j), nil
+	}
+
+	var d tree.Datum
+	switch j.Type() {
+	case json.StringJSONType:
+		s, err := j.AsText()
+		if err != nil {
+			return nil, err
+		}
+		return rowenc.ParseDatumStringAs(ctx, targetT, *s, evalCtx)
+	case json.TrueJSONType, json.FalseJSONType:
+		if targetT.Family() != types.BoolFamily {
+			return rowenc.ParseDatumStringAs(ctx, targetT, j.String(), evalCtx)
+		}
+		d = tree.MakeDBool(tree.DBool(j.Type() == json.TrueJSONType))
+	case json.NumberJSONType:
+		dec, _ := j.AsDecimal()
+		switch targetT.Family() {
+		case types.DecimalFamily:
+			dd := &tree.DDecimal{}
+			dd.Set(dec)
+			d = dd
+		case types.FloatFamily:
+			f, err := dec.Float64()
+			if err != nil {
+				return nil, err
+			}
+			d = tree.NewDFloat(tree.DFloat(f))
+		default:
+			return rowenc.ParseDatumStringAs(ctx, targetT, dec.Text('f'), evalCtx)
+		}
+	case json.ArrayJSONType:
+		if targetT.Family() != types.ArrayFamily {
+			return nil, fmt.Errorf("cannot convert JSON array to non-array type %s", targetT)
+		}
+		arr := tree.NewDArray(targetT.ArrayContents())
+		for i := 0; i < j.Len(); i++ {
+			elt, err := j.FetchValIdx(i)
+			if err != nil {
+				return nil, err
+			}
+			eltDatum, err := jsonToDatum(ctx, elt, targetT.ArrayContents(), evalCtx)
+			if err == nil {
+				err = arr.Append(eltDatum)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		d = arr
+	case json.ObjectJSONType:
+		return nil, fmt.Errorf("cannot convert JSON object to %s", targetT)
+	}
+
+	if d == nil {
+		return nil, fmt.Errorf("cannot handle JSON value %s when converting to %s", j, targetT)
+	}
+	if !targetT.Equivalent(d.ResolvedType()) {
+		return nil, fmt.Errorf("cannot convert type %s to %s", d.ResolvedType(), targetT)
+	}
+	return d, nil
+}
+
+// ndjsonConsumer implements importRowConsumer interface.
+type ndjsonConsumer struct {
+	fieldNameToIdx map[string]int
+	strict         bool
+}
+
+var _ importRowConsumer = &ndjsonConsumer{}
+
+// FillDatums implements importRowConsumer interface.
+func (n *ndjsonConsumer) FillDatums(
+	ctx context.Context, native interface{}, rowIndex int64, conv *row.DatumRowConverter,
+) error {
+	line := native.([]byte)
+	j, err := json.ParseJSON(string(line))
+	if err != nil {
+		return newImportRowError(err, string(line), rowIndex)
+	}
+	if j.Type() != json.ObjectJSONType {
+		return newImportRowError(errors.New("expected a JSON object"), string(line), rowIndex)
+	}
+
+	it, err := j.ObjectIter()
+	if err != nil {
+		return err
+	}
+	for it.Next() {
+		field := lexbase.NormalizeName(it.Key())
+		idx, ok := n.fieldNameToIdx[field]
+		if !ok {
+			if n.strict {
+				return newImportRowError(
+					errors.Newf("could not find column for field %s", field), string(line), rowIndex)
+			}
+			continue
+		}
+		datum, err := jsonToDatum(ctx, it.Value(), conv.VisibleColTypes[idx], conv.EvalCtx)
+		if err != nil {
+			return newImportRowError(
+				errors.Wrapf(err, "field %s", field), string(line), rowIndex)
+		}
+		conv.Datums[idx] = datum
+	}
+
+	// Set any nil datums to DNull (in case the object didn't have the field set
+	// at all).
+	for i := range conv.Datums {
+		if conv.TargetColOrds.Contains(i) && conv.Datums[i] == nil {
+			if n.strict {
+				return newImportRowError(
+					errors.Newf("field %s was not set in the object", conv.VisibleCols[i].GetName()),
+					string(line), rowIndex)
+			}
+			conv.Datums[i] = tree.DNull
+		}
+	}
+	return nil
+}
+
+// ndjsonStream produces the lines of a newline-delimited JSON file. Lines are
+// parsed by the consumers so that parsing happens in parallel. Empty lines are
+// ignored.
+type ndjsonStream struct {
+	input *fileReader
+	scan  *bufio.Scanner
+	line  []byte
+}
+
+var _ importRowProducer = &ndjsonStream{}
+
+// Scan implements importRowProducer interface.
+func (s *ndjsonStream) Scan() bool {
+	for s.scan.Scan() {
+		if line := bytes.TrimSpace(s.scan.Bytes()); len(line) > 0 {
+			// The scanner reuses its buffer, so copy the line for the consumers.
+			s.line = append([]byte(nil), line...)
+			return true
+		}
+	}
+	return false
+}
+
+// Err implements importRowProducer interface.
+func (s *ndjsonStream) Err() error {
+	if err := s.scan.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return errors.Wrapf(err, "line exceeds %s", optMaxRowSize)
+		}
+		return err
+	}
+	return nil
+}
+
+// Skip implements importRowProducer interface.
+func (s *ndjsonStream) Skip() error {
+	s.line = nil
+	return nil
+}
+
+// Row implements importRowProducer interface.
+func (s *ndjsonStream) Row() (interface{}, error) {
+	line := s.line
+	s.line = nil
+	return line, nil
+}
+
+// Progress implements importRowProducer interface.
+func (s *ndjsonStream) Progress() float32 {
+	return s.input.ReadFraction()
+}
+
+type ndjsonInputReader struct {
+	importContext *parallelImportContext
+	opts          roachpb.NDJSONOptions
+}
+
+var _ inputConverter = &ndjsonInputReader{}
+
+func newNDJSONInputReader(
+	semaCtx *tree.SemaContext,
+	kvCh chan row.KVBatch,
+	tableDesc catalog.TableDescriptor,
+	opts roachpb.NDJSONOptions,
+	walltime int64,
+	parallelism int,
+	evalCtx *eval.Context,
+	db *kv.DB,
+) *ndjsonInputReader {
+	return &ndjsonInputReader{
+		importContext: &parallelImportContext{
+			semaCtx:    semaCtx,
+			walltime:   walltime,
+			numWorkers: parallelism,
+			evalCtx:    evalCtx,
+			tableDesc:  tableDesc,
+			kvCh:       kvCh,
+			db:         db,
+		},
+		opts: opts,
+	}
+}
+
+func (n *ndjsonInputReader) start(group ctxgroup.Group) {}
+
+func (n *ndjsonInputReader) readFiles(
+	ctx context.Context,
+	dataFiles map[int32]string,
+	resumePos map[int32]int64,
+	format roachpb.IOFileFormat,
+	makeExternalStorage cloud.ExternalStorageFactory,
+	user username.SQLUsername,
+) error {
+	return readInputFiles(ctx, dataFiles, resumePos, format, n.readFile, makeExternalStorage, user)
+}
+
+func (n *ndjsonInputReader) readFile(
+	ctx context.Context, input *fileReader, inputIdx int32, resumePos int64, rejected chan string,
+) error {
+	fieldIdxByName := make(map[string]int)
+	for idx, col := range n.importContext.tableDesc.VisibleColumns() {
+		fieldIdxByName[col.GetName()] = idx
+	}
+	consumer := &ndjsonConsumer{
+		fieldNameToIdx: fieldIdxByName,
+		strict:         n.opts.StrictMode,
+	}
+
+	maxRowSize := int(n.opts.MaxRowSize)
+	if maxRowSize <= 0 {
+		maxRowSize = defaultScanBuffer
+	}
+	scan := bufio.NewScanner(input)
+	scan.Buffer(make([]byte, 0, 64<<10), maxRowSize)
+	producer := &ndjsonStream{input: input, scan: scan}
+
+	fileCtx := &importFileContext{
+		source:   inputIdx,
+		skip:     resumePos,
+		rejected: rejected,
+		rowLimit: n.opts.RowLimit,
+	}
+	return runParallelImport(ctx, n.importContext, fileCtx, producer, consumer)
+}
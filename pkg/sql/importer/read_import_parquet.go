@@ -0,0 +1,909 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package importer
+
+import (
+	"context"
+	"encoding/base64"
+	gojson "encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/apache/arrow/go/v11/arrow"
+	"github.com/apache/arrow/go/v11/arrow/array"
+	"github.com/apache/arrow/go/v11/arrow/memory"
+	"github.com/apache/arrow/go/v11/parquet/file"
+	"github.com/apache/arrow/go/v11/parquet/metadata"
+	"github.com/apache/arrow/go/v11/parquet/pqarrow"
+	"github.com/cockroachdb/apd/v3"
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/lexbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/ioctx"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeofday"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/errors"
+)
+
+// arrowListLike is implemented by the arrow arrays of lists and maps.
+type arrowListLike interface {
+	arrow.Array
+	ListValues() arrow.Array
+	ValueOffsets(i int) (start, end int64)
+}
+
+// arrowToNative returns the value at the given index of a primitive arrow
+// array as one of: bool, int64, uint64, float64, string, []byte, time.Time,
+// timeofday.TimeOfDay or *apd.Decimal.
+func arrowToNative(arr arrow.Array, i int) (interface{}, error) {
+	switch a := arr.(type) {
+	case *array.Boolean:
+		return a.Value(i), nil
+	case *array.Int8:
+		return int64(a.Value(i)), nil
+	case *array.Int16:
+		return int64(a.Value(i)), nil
+	case *array.Int32:
+		return int64(a.Value(i)), nil
+	case *array.Int64:
+		return a.Value(i), nil
+	case *array.Uint8:
+		return uint64(a.Value(i)), nil
+	case *array.Uint16:
+		return uint64(a.Value(i)), nil
+	case *array.Uint32:
+		return uint64(a.Value(i)), nil
+	case *array.Uint64:
+		return a.Value(i), nil
+	case *array.Float32:
+		return float64(a.Value(i)), nil
+	case *array.Float64:
+		return a.Value(i), nil
+	case *array.String:
+		return a.Value(i), nil
+	case *array.LargeString:
+		return a.Value(i), nil
+	case *array.Binary:
+		return a.Value(i), nil
+	case *array.LargeBinary:
+		return a.Value(i), nil
+	case *array.FixedSizeBinary:
+		return a.Value(i), nil
+	case *array.Date32:
+		return time.Unix(int64(a.Value(i))*24*60*60, 0).UTC(), nil
+	case *array.Date64:
+		return time.UnixMilli(int64(a.Value(i))).UTC(), nil
+	case *array.Timestamp:
+		unit := a.DataType().(*arrow.TimestampType).Unit
+		return time.Unix(0, int64(a.Value(i))*int64(unit.Multiplier())).UTC(), nil
+	case *array.Time32:
+		unit := a.DataType().(*arrow.Time32Type).Unit
+		return timeofday.TimeOfDay(int64(a.Value(i)) * int64(unit.Multiplier()/time.Microsecond)), nil
+	case *array.Time64:
+		unit := a.DataType().(*arrow.Time64Type).Unit
+		return timeofday.TimeOfDay(int64(a.Value(i)) * int64(unit.Multiplier()) / int64(time.Microsecond)), nil
+	case *array.Decimal128:
+		b := a.Value(i).BigInt()
+		d := &apd.Decimal{
+			Negative: b.Sign() < 0,
+			Exponent: -a.DataType().(*arrow.Decimal128Type).Scale,
+		}
+		d.Coeff.SetMathBigInt(b.Abs(b))
+		return d, nil
+	}
+	return nil, errors.Newf("unsupported parquet type %s", arr.DataType())
+}
+
+// arrowToDatum converts the value at the given index of an arrow array to a
+// datum of the given type. Lists are converted element-wise to array columns,
+// and any value, including structs and maps, can be imported into a JSONB
+// column. Other values are converted to the target type directly when their
+// types match, and parsed from their string representation otherwise.
+func arrowToDatum(
+	ctx context.Context, arr arrow.Array, i int, targetT *types.T, evalCtx *eval.Context,
+) (tree.Datum, error) {
+	if arr.IsNull(i) {
+		return tree.DNull, nil
+	}
+	if dict, ok := arr.(*array.Dictionary); ok {
+		return arrowToDatum(ctx, dict.Dictionary(), dict.GetValueIndex(i), targetT, evalCtx)
+	}
+	if targetT.Family() == types.JsonFamily {
+		j, err := arrowToJSON(arr, i)
+		if err != nil {
+			return nil, err
+		}
+		return tree.NewDJSON(j), nil
+	}
+
+	var d tree.Datum
+	switch a := arr.(type) {
+	case *array.Map, *array.Struct:
+		return nil, fmt.Errorf("cannot convert parquet %s to %s", arr.DataType(), targetT)
+	case arrowListLike:
+		if targetT.Family() != types.ArrayFamily {
+			return nil, fmt.Errorf("cannot convert parquet list to non-array type %s", targetT)
+		}
+		res := tree.NewDArray(targetT.ArrayContents())
+		values := a.ListValues()
+		start, end := a.ValueOffsets(i)
+		for j := int(start); j < int(end); j++ {
+			elt, err := arrowToDatum(ctx, values, j, targetT.ArrayContents(), evalCtx)
+			if err == nil {
+				err = res.Append(elt)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		d = res
+	default:
+		v, err := arrowToNative(arr, i)
+		if err != nil {
+			return nil, err
+		}
+		if d, err = nativeParquetToDatum(ctx, v, targetT, evalCtx); err != nil {
+			return nil, err
+		}
+	}
+
+	if !targetT.Equivalent(d.ResolvedType()) {
+		return nil, fmt.Errorf("cannot convert type %s to %s", d.ResolvedType(), targetT)
+	}
+	return d, nil
+}
+
+// nativeParquetToDatum converts a value returned by arrowToNative to a datum of
+// the given type, parsing its string representation if their types differ.
+func nativeParquetToDatum(
+	ctx context.Context, x interface{}, targetT *types.T, evalCtx *eval.Context,
+) (tree.Datum, error) {
+	parse := func(s string) (tree.Datum, error) {
+		return rowenc.ParseDatumStringAs(ctx, targetT, s, evalCtx)
+	}
+	family := targetT.Family()
+	switch v := x.(type) {
+	case bool:
+		if family == types.BoolFamily {
+			return tree.MakeDBool(tree.DBool(v)), nil
+		}
+		return parse(strconv.FormatBool(v))
+	case int64:
+		switch family {
+		case types.IntFamily:
+			return tree.NewDInt(tree.DInt(v)), nil
+		case types.FloatFamily:
+			return tree.NewDFloat(tree.DFloat(v)), nil
+		case types.DecimalFamily:
+			d := &tree.DDecimal{}
+			d.SetInt64(v)
+			return d, nil
+		}
+		return parse(strconv.FormatInt(v, 10))
+	case uint64:
+		if v <= math.MaxInt64 {
+			return nativeParquetToDatum(ctx, int64(v), targetT, evalCtx)
+		}
+		return parse(strconv.FormatUint(v, 10))
+	case float64:
+		switch family {
+		case types.FloatFamily:
+			return tree.NewDFloat(tree.DFloat(v)), nil
+		case types.DecimalFamily:
+			d := &tree.DDecimal{}
+			if _, err := d.SetFloat64(v); err != nil {
+				return nil, err
+			}
+			return d, nil
+		}
+		return parse(strconv.FormatFloat(v, 'g', -1, 64))
+	case *apd.Decimal:
+		if family == types.DecimalFamily {
+			d := &tree.DDecimal{}
+			d.Set(v)
+			return d, nil
+		}
+		return parse(v.Text('f'))
+	case string:
+		return parse(v)
+	case []byte:
+		switch family {
+		case types.BytesFamily:
+			return tree.NewDBytes(tree.DBytes(v)), nil
+		case types.UuidFamily:
+			// UUIDs are stored as 16 byte fixed length byte arrays.
+			if len(v) == uuid.Size {
+				u, err := uuid.FromBytes(v)
+				if err != nil {
+					return nil, err
+				}
+				return tree.NewDUuid(tree.DUuid{UUID: u}), nil
+			}
+		}
+		return parse(string(v))
+	case time.Time:
+		duration := tree.TimeFamilyPrecisionToRoundDuration(targetT.Precision())
+		switch family {
+		case types.DateFamily:
+			return tree.NewDDateFromTime(v)
+		case types.TimestampFamily:
+			return tree.MakeDTimestamp(v, duration)
+		case types.TimestampTZFamily:
+			return tree.MakeDTimestampTZ(v, duration)
+		}
+		return parse(v.Format(time.RFC3339Nano))
+	case timeofday.TimeOfDay:
+		if family == types.TimeFamily {
+			return tree.MakeDTime(v), nil
+		}
+		return parse(v.String())
+	}
+	return nil, errors.AssertionFailedf("unexpected native type %T", x)
+}
+
+// arrowToJSON converts the value at the given index of an arrow array to JSON.
+// Structs and maps are converted to objects and lists to arrays. Binary values
+// which are not valid UTF-8 are base64 encoded.
+func arrowToJSON(arr arrow.Array, i int) (json.JSON, error) {
+	if arr.IsNull(i) {
+		return json.NullJSONValue, nil
+	}
+	switch a := arr.(type) {
+	case *array.Dictionary:
+		return arrowToJSON(a.Dictionary(), a.GetValueIndex(i))
+	case *array.Struct:
+		st := a.DataType().(*arrow.StructType)
+		b := json.NewObjectBuilder(a.NumField())
+		for f := 0; f < a.NumField(); f++ {
+			v, err := arrowToJSON(a.Field(f), i)
+			if err != nil {
+				return nil, err
+			}
+			b.Add(st.Field(f).Name, v)
+		}
+		return b.Build(), nil
+	case *array.Map:
+		start, end := a.ValueOffsets(i)
+		b := json.NewObjectBuilder(int(end - start))
+		for j := int(start); j < int(end); j++ {
+			k, err := arrowToJSON(a.Keys(), j)
+			if err != nil {
+				return nil, err
+			}
+			key := k.String()
+			if s, err := k.AsText(); err == nil && s != nil {
+				key = *s
+			}
+			v, err := arrowToJSON(a.Items(), j)
+			if err != nil {
+				return nil, err
+			}
+			b.Add(key, v)
+		}
+		return b.Build(), nil
+	case arrowListLike:
+		start, end := a.ValueOffsets(i)
+		b := json.NewArrayBuilder(int(end - start))
+		for j := int(start); j < int(end); j++ {
+			v, err := arrowToJSON(a.ListValues(), j)
+			if err != nil {
+				return nil, err
+			}
+			b.Add(v)
+		}
+		return b.Build(), nil
+	}
+
+	v, err := arrowToNative(arr, i)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case bool:
+		return json.FromBool(v), nil
+	case int64:
+		return json.FromInt64(v), nil
+	case uint64:
+		return json.FromNumber(gojson.Number(strconv.FormatUint(v, 10)))
+	case float64:
+		return json.FromFloat64(v)
+	case *apd.Decimal:
+		return json.FromDecimal(*v), nil
+	case string:
+		return json.FromString(v), nil
+	case []byte:
+		if utf8.Valid(v) {
+			return json.FromString(string(v)), nil
+		}
+		return json.FromString(base64.StdEncoding.EncodeToString(v)), nil
+	case time.Time:
+		return json.FromString(v.Format(time.RFC3339Nano)), nil
+	case timeofday.TimeOfDay:
+		return json.FromString(v.String()), nil
+	}
+	return nil, errors.AssertionFailedf("unexpected native type %T", v)
+}
+
+// parquetRow is a row of a parquet file: the row at the given index of a
+// record read from the file.
+type parquetRow struct {
+	rec arrow.Record
+	idx int
+}
+
+// parquetConsumer implements importRowConsumer interface.
+type parquetConsumer struct {
+	// fieldToCol maps the index of each column of the parquet file to the index
+	// of the visible column it is imported into, or -1 if it is ignored.
+	fieldToCol []int
+}
+
+var _ importRowConsumer = &parquetConsumer{}
+
+// FillDatums implements importRowConsumer interface.
+func (p *parquetConsumer) FillDatums(
+	ctx context.Context, native interface{}, rowIndex int64, conv *row.DatumRowConverter,
+) error {
+	r := native.(parquetRow)
+	for field, idx := range p.fieldToCol {
+		if idx < 0 {
+			continue
+		}
+		datum, err := arrowToDatum(ctx, r.rec.Column(field), r.idx, conv.VisibleColTypes[idx], conv.EvalCtx)
+		if err != nil {
+			return wrapRowErr(err, rowIndex, pgcode.Uncategorized, "column %s", r.rec.ColumnName(field))
+		}
+		conv.Datums[idx] = datum
+	}
+
+	// Set any nil datums to DNull, for the columns which are missing from the
+	// file. In strict mode, readFile already verified that there are none.
+	for i := range conv.Datums {
+		if conv.TargetColOrds.Contains(i) && conv.Datums[i] == nil {
+			conv.Datums[i] = tree.DNull
+		}
+	}
+	return nil
+}
+
+// parquetRowGroup is the result of reading a row group of a parquet file.
+type parquetRowGroup struct {
+	records []arrow.Record
+	err     error
+}
+
+// parquetRowStream produces the rows of a parquet file. The row groups of the
+// file are read and decoded ahead of the rows being consumed, up to
+// parallelism row groups at a time and within the memory budget of the
+// stream. The row groups before the row from which the import resumes are not
+// read at all.
+type parquetRowStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	// wg tracks the goroutines which read the row groups.
+	wg sync.WaitGroup
+	// rowGroupRows is the number of rows of each row group of the file, and
+	// rowGroupBytes the memory reserved to read and decode it.
+	rowGroupRows, rowGroupBytes []int64
+	// totalRows is the number of rows in the file, and rowsBefore the number
+	// of rows in the row groups before the current one.
+	totalRows, rowsBefore int64
+	// firstRowGroup is the first row group which is read.
+	firstRowGroup int
+	// rowGroups delivers the results of reading the row groups, in order.
+	rowGroups chan chan parquetRowGroup
+
+	mu struct {
+		syncutil.Mutex
+		// acc accounts for the row groups which are read and not consumed yet,
+		// of which there are reserved.
+		acc      mon.BoundAccount
+		reserved int
+	}
+	// released is signaled when the memory of a row group is released.
+	released chan struct{}
+
+	// rowGroup and row are the indexes of the current row group and of the
+	// current row within it.
+	rowGroup int
+	row      int64
+	// records are the records of the current row group, or nil if it was not
+	// read because it is skipped, and rec and recRow are the index of the
+	// record of the current row and the index of the row within it.
+	records     []arrow.Record
+	rec, recRow int
+	err         error
+}
+
+var _ importRowProducer = &parquetRowStream{}
+
+func newParquetRowStream(
+	ctx context.Context,
+	src *storageReaderAt,
+	reader *file.Reader,
+	memMonitor *mon.BytesMonitor,
+	resumePos int64,
+	parallelism int,
+) *parquetRowStream {
+	ctx, cancel := context.WithCancel(ctx)
+	meta := reader.MetaData()
+	s := &parquetRowStream{
+		ctx:           ctx,
+		cancel:        cancel,
+		rowGroupRows:  make([]int64, reader.NumRowGroups()),
+		rowGroupBytes: make([]int64, reader.NumRowGroups()),
+		rowGroup:      -1,
+		rowGroups:     make(chan chan parquetRowGroup, parallelism),
+		released:      make(chan struct{}, 1),
+	}
+	s.mu.acc = memMonitor.MakeBoundAccount()
+	s.firstRowGroup = len(s.rowGroupRows)
+	for i := range s.rowGroupRows {
+		rg := meta.RowGroup(i)
+		s.rowGroupRows[i] = rg.NumRows()
+		// A row group is held both as the raw bytes of its column chunks and
+		// decoded into arrow records, whose size is about the uncompressed size
+		// of the row group.
+		s.rowGroupBytes[i] = rg.TotalCompressedSize() + rg.TotalByteSize()
+		if s.totalRows+s.rowGroupRows[i] > resumePos && s.firstRowGroup == len(s.rowGroupRows) {
+			s.firstRowGroup = i
+		}
+		s.totalRows += s.rowGroupRows[i]
+	}
+
+	// The row groups are read by a goroutine per row group. At most parallelism
+	// row groups wait in rowGroups to be consumed, which bounds the number of
+	// row groups read ahead of the consumption of the rows, and a row group is
+	// only read once its memory is reserved.
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(s.rowGroups)
+		for i := s.firstRowGroup; i < len(s.rowGroupRows); i++ {
+			ch := make(chan parquetRowGroup, 1)
+			select {
+			case s.rowGroups <- ch:
+			case <-ctx.Done():
+				return
+			}
+			if err := s.reserve(ctx, s.rowGroupBytes[i]); err != nil {
+				ch <- parquetRowGroup{err: errors.Wrapf(err, "reading row group %d", i)}
+				return
+			}
+			s.wg.Add(1)
+			go func(i int) {
+				defer s.wg.Done()
+				records, err := readParquetRowGroup(ctx, src, meta, i)
+				ch <- parquetRowGroup{records: records, err: err}
+			}(i)
+		}
+	}()
+	return s
+}
+
+// reserve reserves the memory of a row group. If the memory budget is
+// exhausted, it waits for the row groups read ahead to be consumed, and it
+// fails only if the row group does not fit in the budget on its own.
+func (s *parquetRowStream) reserve(ctx context.Context, n int64) error {
+	for {
+		s.mu.Lock()
+		err := s.mu.acc.Grow(ctx, n)
+		if err == nil {
+			s.mu.reserved++
+		}
+		reserved := s.mu.reserved
+		s.mu.Unlock()
+		if err == nil || reserved == 0 {
+			return err
+		}
+		select {
+		case <-s.released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release releases the memory of a row group once it is consumed.
+func (s *parquetRowStream) release(n int64) {
+	s.mu.Lock()
+	s.mu.acc.Shrink(s.ctx, n)
+	s.mu.reserved--
+	s.mu.Unlock()
+	select {
+	case s.released <- struct{}{}:
+	default:
+	}
+}
+
+// close stops reading the row groups ahead, which may happen before the end
+// of the file if there is a row limit, and releases the memory of the stream.
+func (s *parquetRowStream) close(ctx context.Context) {
+	s.cancel()
+	s.wg.Wait()
+	s.mu.acc.Close(ctx)
+}
+
+// readParquetRowGroup reads and decodes a row group of a parquet file. The
+// column chunks of the row group are read from external storage in a single
+// ranged read.
+func readParquetRowGroup(
+	ctx context.Context, src *storageReaderAt, meta *metadata.FileMetaData, rowGroup int,
+) ([]arrow.Record, error) {
+	start, end, err := parquetRowGroupRange(meta.RowGroup(rowGroup))
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, end-start)
+	if len(buf) > 0 {
+		n, err := src.ReadAt(buf, start)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, errors.Wrapf(err, "reading row group %d", rowGroup)
+		}
+		buf = buf[:n]
+	}
+	reader, err := file.NewParquetReader(
+		&rowGroupReaderAt{storageReaderAt: *src, buf: buf, off: start}, file.WithMetadata(meta),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	fr, err := pqarrow.NewFileReader(reader, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]int, meta.Schema.NumColumns())
+	for i := range cols {
+		cols[i] = i
+	}
+	tbl, err := fr.ReadRowGroups(ctx, cols, []int{rowGroup})
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading row group %d", rowGroup)
+	}
+	defer tbl.Release()
+	tr := array.NewTableReader(tbl, -1 /* chunkSize */)
+	defer tr.Release()
+	var records []arrow.Record
+	for tr.Next() {
+		rec := tr.Record()
+		rec.Retain()
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// parquetRowGroupRange returns the range of the file in which the column
+// chunks of a row group are stored.
+func parquetRowGroupRange(rg *metadata.RowGroupMetaData) (start, end int64, _ error) {
+	start = math.MaxInt64
+	for i := 0; i < rg.NumColumns(); i++ {
+		col, err := rg.ColumnChunk(i)
+		if err != nil {
+			return 0, 0, err
+		}
+		colStart := col.DataPageOffset()
+		if col.HasDictionaryPage() && col.DictionaryPageOffset() > 0 && col.DictionaryPageOffset() < colStart {
+			colStart = col.DictionaryPageOffset()
+		}
+		if colStart < start {
+			start = colStart
+		}
+		if colEnd := colStart + col.TotalCompressedSize(); colEnd > end {
+			end = colEnd
+		}
+	}
+	if start > end {
+		return 0, 0, nil
+	}
+	return start, end, nil
+}
+
+// Scan implements importRowProducer interface.
+func (s *parquetRowStream) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+	s.row++
+	for s.rowGroup < 0 || s.row >= s.rowGroupRows[s.rowGroup] {
+		if s.rowGroup >= 0 {
+			s.rowsBefore += s.rowGroupRows[s.rowGroup]
+			if s.records != nil {
+				s.release(s.rowGroupBytes[s.rowGroup])
+			}
+			s.records = nil
+		}
+		s.rowGroup++
+		if s.rowGroup >= len(s.rowGroupRows) {
+			return false
+		}
+		s.row = 0
+		if s.rowGroup < s.firstRowGroup {
+			continue
+		}
+		select {
+		case ch := <-s.rowGroups:
+			res := <-ch
+			if res.err != nil {
+				s.err = res.err
+				return false
+			}
+			s.records, s.rec, s.recRow = res.records, 0, -1
+		case <-s.ctx.Done():
+			s.err = s.ctx.Err()
+			return false
+		}
+	}
+	if s.records != nil {
+		s.recRow++
+		for int64(s.recRow) >= s.records[s.rec].NumRows() {
+			s.rec++
+			s.recRow = 0
+		}
+	}
+	return true
+}
+
+// Err implements importRowProducer interface.
+func (s *parquetRowStream) Err() error {
+	return s.err
+}
+
+// Skip implements importRowProducer interface.
+func (s *parquetRowStream) Skip() error {
+	return nil
+}
+
+// Row implements importRowProducer interface.
+func (s *parquetRowStream) Row() (interface{}, error) {
+	if s.records == nil {
+		return nil, errors.AssertionFailedf("row %d of row group %d was not read", s.row, s.rowGroup)
+	}
+	// The rows are consumed concurrently with the scan of the next rows, so the
+	// records are not released when the scan moves past them. They are
+	// allocated by the Go allocator and are garbage collected instead.
+	return parquetRow{rec: s.records[s.rec], idx: s.recRow}, nil
+}
+
+// Progress implements importRowProducer interface.
+func (s *parquetRowStream) Progress() float32 {
+	if s.totalRows == 0 {
+		return 0
+	}
+	return float32(s.rowsBefore+s.row) / float32(s.totalRows)
+}
+
+// storageReaderAt reads a file in external storage at arbitrary offsets, as
+// required by parquet readers, which read the metadata at the end of a file
+// before reading the row groups it points to. io.ReaderAt doesn't take a
+// context, so the context used for the reads is stored.
+type storageReaderAt struct {
+	ctx  context.Context
+	es   cloud.ExternalStorage
+	size int64
+	pos  int64
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *storageReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	raw, _, err := r.es.ReadFile(r.ctx, "", cloud.ReadOptions{
+		Offset:     off,
+		LengthHint: int64(len(p)),
+		NoFileSize: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer raw.Close(r.ctx)
+	n, err := io.ReadFull(ioctx.ReaderCtxAdapter(r.ctx, raw), p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *storageReaderAt) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.Errorf("invalid whence: %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.Errorf("negative position: %d", offset)
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+// rowGroupReaderAt reads a row group of a parquet file from the bytes of its
+// column chunks, which are read ahead, rather than with a ranged read of
+// external storage per column chunk. Reads outside of these bytes go to
+// external storage.
+type rowGroupReaderAt struct {
+	storageReaderAt
+	buf []byte
+	off int64
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *rowGroupReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.off && off+int64(len(p)) <= r.off+int64(len(r.buf)) {
+		return copy(p, r.buf[off-r.off:]), nil
+	}
+	return r.storageReaderAt.ReadAt(p, off)
+}
+
+type parquetInputReader struct {
+	importContext *parallelImportContext
+	opts          roachpb.ParquetOptions
+	// memMonitor accounts for the row groups read ahead.
+	memMonitor *mon.BytesMonitor
+}
+
+var _ inputConverter = &parquetInputReader{}
+
+func newParquetInputReader(
+	semaCtx *tree.SemaContext,
+	kvCh chan row.KVBatch,
+	tableDesc catalog.TableDescriptor,
+	opts roachpb.ParquetOptions,
+	walltime int64,
+	parallelism int,
+	evalCtx *eval.Context,
+	db *kv.DB,
+	memMonitor *mon.BytesMonitor,
+) *parquetInputReader {
+	return &parquetInputReader{
+		importContext: &parallelImportContext{
+			semaCtx:    semaCtx,
+			walltime:   walltime,
+			numWorkers: parallelism,
+			evalCtx:    evalCtx,
+			tableDesc:  tableDesc,
+			kvCh:       kvCh,
+			db:         db,
+		},
+		opts:       opts,
+		memMonitor: memMonitor,
+	}
+}
+
+func (p *parquetInputReader) start(group ctxgroup.Group) {}
+
+// readFiles implements inputConverter interface. Unlike the other formats,
+// parquet files are not read sequentially, so they are not read through
+// readInputFiles.
+func (p *parquetInputReader) readFiles(
+	ctx context.Context,
+	dataFiles map[int32]string,
+	resumePos map[int32]int64,
+	_ roachpb.IOFileFormat,
+	makeExternalStorage cloud.ExternalStorageFactory,
+	user username.SQLUsername,
+) error {
+	for dataFileIndex, dataFile := range dataFiles {
+		if err := func() error {
+			conf, err := cloud.ExternalStorageConfFromURI(dataFile, user)
+			if err != nil {
+				return err
+			}
+			es, err := makeExternalStorage(ctx, conf)
+			if err != nil {
+				return err
+			}
+			defer es.Close()
+			return p.readFile(ctx, es, dataFileIndex, resumePos[dataFileIndex])
+		}(); err != nil {
+			return errors.Wrapf(err, "%s", dataFile)
+		}
+	}
+	return nil
+}
+
+func (p *parquetInputReader) readFile(
+	ctx context.Context, es cloud.ExternalStorage, inputIdx int32, resumePos int64,
+) error {
+	size, err := es.Size(ctx, "")
+	if err != nil {
+		return err
+	}
+	src := &storageReaderAt{ctx: ctx, es: es, size: size}
+	reader, err := file.NewParquetReader(src)
+	if err != nil {
+		return errors.Wrap(err, "reading parquet file")
+	}
+	defer reader.Close()
+
+	fr, err := pqarrow.NewFileReader(reader, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		return err
+	}
+	schema, err := fr.Schema()
+	if err != nil {
+		return err
+	}
+	consumer, err := p.makeConsumer(schema)
+	if err != nil {
+		return err
+	}
+
+	producer := newParquetRowStream(
+		ctx, src, reader, p.memMonitor, resumePos, p.importContext.numWorkers,
+	)
+	defer producer.close(ctx)
+
+	fileCtx := &importFileContext{
+		source:   inputIdx,
+		skip:     resumePos,
+		rowLimit: p.opts.RowLimit,
+	}
+	return runParallelImport(ctx, p.importContext, fileCtx, producer, consumer)
+}
+
+// makeConsumer maps the columns of the parquet file to the visible columns of
+// the table by name.
+func (p *parquetInputReader) makeConsumer(schema *arrow.Schema) (*parquetConsumer, error) {
+	colIdxByName := make(map[string]int)
+	for idx, col := range p.importContext.tableDesc.VisibleColumns() {
+		colIdxByName[col.GetName()] = idx
+	}
+	consumer := &parquetConsumer{
+		fieldToCol: make([]int, len(schema.Fields())),
+	}
+	seen := make(map[int]struct{})
+	for i, f := range schema.Fields() {
+		name := lexbase.NormalizeName(f.Name)
+		idx, ok := colIdxByName[name]
+		if !ok {
+			if p.opts.StrictMode {
+				return nil, errors.Errorf("could not find column for parquet column %s", name)
+			}
+			idx = -1
+		}
+		consumer.fieldToCol[i] = idx
+		seen[idx] = struct{}{}
+	}
+	if p.opts.StrictMode {
+		for idx, col := range p.importContext.tableDesc.VisibleColumns() {
+			if _, ok := seen[idx]; !ok && !col.IsComputed() {
+				return nil, errors.Errorf("column %s is missing from the parquet file", col.GetName())
+			}
+		}
+	}
+	return consumer, nil
+}
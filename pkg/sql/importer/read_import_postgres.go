@@ -0,0 +1,518 @@
+// Copyright 2026 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package importer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/cloud"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security/username"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/lexbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/errors"
+	"github.com/jackc/pgconn"
+)
+
+const (
+	// defaultPostgresSourcePartitions is the number of key ranges a source
+	// table is split into, unless overridden with the partitions option. Each
+	// key range is read by its own COPY stream, and the streams are spread
+	// across the nodes of the cluster.
+	defaultPostgresSourcePartitions = 8
+
+	postgresSourcePrimaryKeyQuery = `SELECT kcu.column_name
+		FROM information_schema.table_constraints AS tc
+		JOIN information_schema.key_column_usage AS kcu
+			ON tc.constraint_schema = kcu.constraint_schema
+			AND tc.constraint_name = kcu.constraint_name
+			AND tc.table_name = kcu.table_name
+		WHERE tc.constraint_type = 'PRIMARY KEY'
+			AND tc.table_schema = COALESCE(NULLIF($1, ''), current_schema())
+			AND tc.table_name = $2
+		ORDER BY kcu.ordinal_position`
+	postgresSourceColumnsQuery = `SELECT column_name FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2`
+)
+
+// sanitizePostgresSourceURL removes the credentials from the connection URL
+// of a source database, so that it can be displayed or logged.
+func sanitizePostgresSourceURL(sourceURL string) (string, error) {
+	clean, err := cloud.SanitizeExternalStorageURI(sourceURL, []string{"password", "sslpassword"})
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(clean)
+	if err != nil {
+		return "", err
+	}
+	return u.Redacted(), nil
+}
+
+// planPostgresSource inspects the source table of an IMPORT ... FROM POSTGRES
+// and splits it into numPartitions ranges of its primary key of roughly equal
+// size. cols are the source columns to read, in the order of the target
+// columns.
+func planPostgresSource(
+	ctx context.Context, sourceURL string, source *tree.TableName, cols []string, numPartitions int,
+) (roachpb.PostgresSourceOptions, error) {
+	var opts roachpb.PostgresSourceOptions
+	if source.ExplicitCatalog {
+		return opts, pgerror.Newf(pgcode.InvalidName,
+			"source table %s must not specify a database; specify the database in the connection URL",
+			source)
+	}
+
+	conn, err := pgconn.Connect(ctx, sourceURL)
+	if err != nil {
+		return opts, errors.Wrap(err, "connecting to source database")
+	}
+	defer func() { _ = conn.Close(ctx) }()
+
+	// A CockroachDB source is read as of a fixed timestamp, kept in the job so
+	// that all partitions, and all resumptions of the import, see the same
+	// state of the table. Other sources are read from a snapshot exported by
+	// the coordinator of the import, see exportPostgresSourceSnapshot.
+	if conn.ParameterStatus("crdb_version") != "" {
+		ts, err := queryPostgresSource(ctx, conn, `SELECT cluster_logical_timestamp()`)
+		if err != nil {
+			return opts, errors.Wrap(err, "reading the timestamp of the source database")
+		}
+		opts.AsOf = ts[0][0]
+		if err := beginPostgresSourceTxn(ctx, conn, opts); err != nil {
+			return opts, err
+		}
+	}
+
+	var schema string
+	if source.ExplicitSchema {
+		schema = string(source.SchemaName)
+	}
+	name := string(source.ObjectName)
+
+	srcCols, err := queryPostgresSource(ctx, conn, postgresSourceColumnsQuery, schema, name)
+	if err != nil {
+		return opts, errors.Wrapf(err, "fetching columns of source table %s", source)
+	}
+	if len(srcCols) == 0 {
+		return opts, pgerror.Newf(pgcode.UndefinedTable, "source table %s does not exist", source)
+	}
+	isSrcCol := make(map[string]bool, len(srcCols))
+	for _, r := range srcCols {
+		isSrcCol[r[0]] = true
+	}
+	for _, col := range cols {
+		if !isSrcCol[col] {
+			return opts, pgerror.Newf(pgcode.UndefinedColumn,
+				"column %q does not exist in source table %s", col, source)
+		}
+		opts.Columns = append(opts.Columns, lexbase.EscapeSQLIdent(col))
+	}
+
+	keyCols, err := queryPostgresSource(ctx, conn, postgresSourcePrimaryKeyQuery, schema, name)
+	if err != nil {
+		return opts, errors.Wrapf(err, "fetching primary key of source table %s", source)
+	}
+	if len(keyCols) == 0 {
+		// Without a key, there is no stable order in which rows can be read,
+		// which is needed both to partition the table and to resume the import.
+		return opts, pgerror.Newf(pgcode.FeatureNotSupported,
+			"source table %s must have a primary key", source)
+	}
+	for _, r := range keyCols {
+		opts.KeyColumns = append(opts.KeyColumns, lexbase.EscapeSQLIdent(r[0]))
+	}
+
+	opts.Table = lexbase.EscapeSQLIdent(name)
+	if schema != "" {
+		opts.Table = lexbase.EscapeSQLIdent(schema) + "." + opts.Table
+	}
+
+	// Find the upper bound and the number of rows of each bucket of the key
+	// space, in a single scan of the source table.
+	keys := strings.Join(opts.KeyColumns, ", ")
+	keysDesc := strings.Join(opts.KeyColumns, " DESC, ") + " DESC"
+	bucketsQuery := fmt.Sprintf(
+		`SELECT DISTINCT ON (crdb_import_bucket) count(*) OVER (PARTITION BY crdb_import_bucket), %[1]s
+		FROM (SELECT %[1]s, ntile(%[2]d) OVER (ORDER BY %[1]s) AS crdb_import_bucket FROM %[3]s) AS buckets
+		ORDER BY crdb_import_bucket, %[4]s`,
+		keys, numPartitions, opts.Table, keysDesc,
+	)
+	buckets, err := queryPostgresSource(ctx, conn, bucketsQuery)
+	if err != nil {
+		return opts, errors.Wrapf(err, "partitioning source table %s", source)
+	}
+	if len(buckets) == 0 {
+		// The source table is empty. We still read it, in case rows were added
+		// since we looked.
+		opts.Partitions = []roachpb.PostgresSourcePartition{{}}
+		return opts, nil
+	}
+
+	bounds := make([][]string, len(buckets))
+	for i, r := range buckets {
+		bounds[i] = r[1:]
+	}
+	for i, pred := range postgresPartitionPredicates(opts.KeyColumns, bounds) {
+		var estimatedRows int64
+		if _, err := fmt.Sscan(buckets[i][0], &estimatedRows); err != nil {
+			return opts, errors.Wrapf(err, "parsing row count of source partition")
+		}
+		opts.Partitions = append(opts.Partitions, roachpb.PostgresSourcePartition{
+			Predicate:     pred,
+			EstimatedRows: estimatedRows,
+		})
+	}
+	return opts, nil
+}
+
+// postgresPartitionPredicates returns the predicates selecting the rows of
+// each partition, given the quoted names of the key columns and the upper
+// bound of the key of each partition, as text. The upper bound of the last
+// partition is ignored, so that rows added to the end of the key space after
+// planning are imported too.
+func postgresPartitionPredicates(keyCols []string, bounds [][]string) []string {
+	keys := "(" + strings.Join(keyCols, ", ") + ")"
+	preds := make([]string, len(bounds))
+	for i := range bounds {
+		var conds []string
+		if i > 0 {
+			conds = append(conds, keys+" > "+postgresKeyTuple(bounds[i-1]))
+		}
+		if i < len(bounds)-1 {
+			conds = append(conds, keys+" <= "+postgresKeyTuple(bounds[i]))
+		}
+		preds[i] = strings.Join(conds, " AND ")
+	}
+	return preds
+}
+
+// postgresKeyTuple returns a tuple of string literals holding the passed
+// values of the key columns, as text. The source database casts the literals
+// to the types of the key columns when the tuple is compared to them.
+func postgresKeyTuple(vals []string) string {
+	lits := make([]string, len(vals))
+	for i, v := range vals {
+		lits[i] = lexbase.EscapeSQLString(v)
+	}
+	return "(" + strings.Join(lits, ", ") + ")"
+}
+
+// beginPostgresSourceTxn starts a transaction on a connection to the source
+// database that reads as of opts.AsOf, or from the snapshot opts.Snapshot, if
+// either is set.
+func beginPostgresSourceTxn(
+	ctx context.Context, conn *pgconn.PgConn, opts roachpb.PostgresSourceOptions,
+) error {
+	var stmt string
+	switch {
+	case opts.AsOf != "":
+		stmt = "BEGIN TRANSACTION AS OF SYSTEM TIME " + lexbase.EscapeSQLString(opts.AsOf)
+	case opts.Snapshot != "":
+		stmt = "BEGIN ISOLATION LEVEL REPEATABLE READ; SET TRANSACTION SNAPSHOT " +
+			lexbase.EscapeSQLString(opts.Snapshot)
+	default:
+		return nil
+	}
+	_, err := conn.Exec(ctx, stmt).ReadAll()
+	return errors.Wrap(err, "starting transaction on source database")
+}
+
+// exportPostgresSourceSnapshot exports a snapshot of the source database with
+// pg_export_snapshot(), from which all partitions of the source table are then
+// read. The snapshot only lives as long as the transaction that exported it,
+// so the returned function, which ends that transaction, must only be called
+// once the partitions have been read. The transaction is idle meanwhile, so
+// the import fails if the source database ends it first, for instance because
+// of idle_in_transaction_session_timeout.
+func exportPostgresSourceSnapshot(
+	ctx context.Context, sourceURL string,
+) (string, func(context.Context), error) {
+	conn, err := pgconn.Connect(ctx, sourceURL)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "connecting to source database")
+	}
+	release := func(ctx context.Context) { _ = conn.Close(ctx) }
+	if _, err := conn.Exec(ctx, "BEGIN ISOLATION LEVEL REPEATABLE READ").ReadAll(); err != nil {
+		release(ctx)
+		return "", nil, errors.Wrap(err, "starting transaction on source database")
+	}
+	res, err := queryPostgresSource(ctx, conn, "SELECT pg_export_snapshot()")
+	if err != nil {
+		release(ctx)
+		return "", nil, errors.Wrap(err, "exporting snapshot of source database")
+	}
+	return res[0][0], release, nil
+}
+
+// queryPostgresSource runs a query against the source database, and returns
+// its result rows in text format. NULL values are returned as empty strings.
+func queryPostgresSource(
+	ctx context.Context, conn *pgconn.PgConn, query string, args ...string,
+) ([][]string, error) {
+	params := make([][]byte, len(args))
+	for i, arg := range args {
+		params[i] = []byte(arg)
+	}
+	res := conn.ExecParams(ctx, query, params, nil /* paramOIDs */, nil /* paramFormats */, nil /* resultFormats */).Read()
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	rows := make([][]string, len(res.Rows))
+	for i, r := range res.Rows {
+		rows[i] = make([]string, len(r))
+		for j, v := range r {
+			rows[i][j] = string(v)
+		}
+	}
+	return rows, nil
+}
+
+// postgresSourceReader imports the data of a table in a live database that
+// speaks the PostgreSQL wire protocol. Each "file" of the import is one
+// partition of the source table, read with COPY TO STDOUT.
+type postgresSourceReader struct {
+	importCtx *parallelImportContext
+	opts      roachpb.PostgresSourceOptions
+	// resumeKeys are the keys of the last imported row of each partition, as
+	// returned by postgresSourceConsumer.RowKey.
+	resumeKeys map[int32]string
+}
+
+var _ inputConverter = &postgresSourceReader{}
+
+func newPostgresSourceReader(
+	semaCtx *tree.SemaContext,
+	opts roachpb.PostgresSourceOptions,
+	resumeKeys map[int32]string,
+	kvCh chan row.KVBatch,
+	walltime int64,
+	parallelism int,
+	tableDesc catalog.TableDescriptor,
+	targetCols tree.NameList,
+	evalCtx *eval.Context,
+	db *kv.DB,
+) *postgresSourceReader {
+	return &postgresSourceReader{
+		importCtx: &parallelImportContext{
+			semaCtx:    semaCtx,
+			walltime:   walltime,
+			numWorkers: parallelism,
+			evalCtx:    evalCtx,
+			tableDesc:  tableDesc,
+			targetCols: targetCols,
+			kvCh:       kvCh,
+			db:         db,
+		},
+		opts:       opts,
+		resumeKeys: resumeKeys,
+	}
+}
+
+func (d *postgresSourceReader) start(ctx ctxgroup.Group) {
+}
+
+func (d *postgresSourceReader) readFiles(
+	ctx context.Context,
+	dataFiles map[int32]string,
+	resumePos map[int32]int64,
+	_ roachpb.IOFileFormat,
+	_ cloud.ExternalStorageFactory,
+	_ username.SQLUsername,
+) error {
+	for idx, sourceURL := range dataFiles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := d.readPartition(ctx, sourceURL, idx, resumePos[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyQuery returns the COPY statement reading the given partition, after the
+// row with the given key, if any. The key columns are read after the columns
+// that are imported, and rows are ordered by key, so that the import can be
+// resumed after the key of the last imported row.
+func (d *postgresSourceReader) copyQuery(
+	partition roachpb.PostgresSourcePartition, resumeKey string,
+) string {
+	keys := strings.Join(d.opts.KeyColumns, ", ")
+	var conds []string
+	if partition.Predicate != "" {
+		conds = append(conds, partition.Predicate)
+	}
+	if resumeKey != "" {
+		conds = append(conds, "("+keys+") > "+resumeKey)
+	}
+	var where string
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+	return fmt.Sprintf("COPY (SELECT %s, %s FROM %s%s ORDER BY %s) TO STDOUT",
+		strings.Join(d.opts.Columns, ", "), keys, d.opts.Table, where, keys)
+}
+
+func (d *postgresSourceReader) readPartition(
+	ctx context.Context, sourceURL string, inputIdx int32, resumePos int64,
+) error {
+	if int(inputIdx) >= len(d.opts.Partitions) {
+		return errors.AssertionFailedf("no source partition for input %d", inputIdx)
+	}
+	partition := d.opts.Partitions[inputIdx]
+
+	conn, err := pgconn.Connect(ctx, sourceURL)
+	if err != nil {
+		return errors.Wrap(err, "connecting to source database")
+	}
+	defer func() { _ = conn.Close(ctx) }()
+	if err := beginPostgresSourceTxn(ctx, conn, d.opts); err != nil {
+		return err
+	}
+
+	// Without a key, no row of the partition was recorded as imported, and it
+	// is read from the start.
+	fileCtx := &importFileContext{source: inputIdx}
+	if key := d.resumeKeys[inputIdx]; key != "" {
+		fileCtx.resumed, fileCtx.resumeKey = resumePos, key
+	}
+
+	pr, pw := io.Pipe()
+	g := ctxgroup.WithContext(ctx)
+	g.GoCtx(func(ctx context.Context) error {
+		_, err := conn.CopyTo(ctx, pw, d.copyQuery(partition, fileCtx.resumeKey))
+		// A nil error makes the reader observe io.EOF.
+		_ = pw.CloseWithError(err)
+		return errors.Wrap(err, "reading from source table")
+	})
+	g.GoCtx(func(ctx context.Context) error {
+		// Closing the reader unblocks the COPY if we stop early.
+		defer pr.Close()
+
+		s := bufio.NewScanner(pr)
+		s.Split(bufio.ScanLines)
+		s.Buffer(nil, defaultScanBuffer)
+		producer := &postgresSourceProducer{
+			copyStream:    newPostgreStreamCopy(s, copyDefaultDelimiter, copyDefaultNull),
+			rows:          fileCtx.resumed,
+			estimatedRows: partition.EstimatedRows,
+		}
+		consumer := &postgresSourceConsumer{numKeyCols: len(d.opts.KeyColumns)}
+		return runParallelImport(ctx, d.importCtx, fileCtx, producer, consumer)
+	})
+	return g.Wait()
+}
+
+// postgresSourceProducer produces the rows of a COPY TO STDOUT stream. Since
+// the size of the stream is not known in advance, progress is estimated from
+// the number of rows in the partition when the import was planned.
+type postgresSourceProducer struct {
+	copyStream    *postgreStreamCopy
+	row           copyData
+	err           error
+	rows          int64
+	estimatedRows int64
+}
+
+var _ importRowProducer = &postgresSourceProducer{}
+
+// Scan implements importRowProducer
+func (p *postgresSourceProducer) Scan() bool {
+	p.row, p.err = p.copyStream.Next()
+	if p.err == io.EOF {
+		p.err = nil
+		return false
+	}
+	if p.err == nil {
+		p.rows++
+	}
+	return p.err == nil
+}
+
+// Err implements importRowProducer
+func (p *postgresSourceProducer) Err() error {
+	return p.err
+}
+
+// Skip implements importRowProducer
+func (p *postgresSourceProducer) Skip() error {
+	return nil // no-op
+}
+
+// Row implements importRowProducer
+func (p *postgresSourceProducer) Row() (interface{}, error) {
+	return p.row, p.err
+}
+
+// Progress implements importRowProducer
+func (p *postgresSourceProducer) Progress() float32 {
+	if p.estimatedRows <= 0 {
+		return 0
+	}
+	if p.rows >= p.estimatedRows {
+		// Rows may have been added since the import was planned.
+		return 1
+	}
+	return float32(p.rows) / float32(p.estimatedRows)
+}
+
+// postgresSourceConsumer consumes the rows of a COPY TO STDOUT stream, which
+// end with the values of the key columns of the source table.
+type postgresSourceConsumer struct {
+	pgCopyConsumer
+	numKeyCols int
+}
+
+var _ importRowConsumer = &postgresSourceConsumer{}
+var _ importRowKeyer = &postgresSourceConsumer{}
+
+// FillDatums implements importRowConsumer
+func (p *postgresSourceConsumer) FillDatums(
+	ctx context.Context, row interface{}, rowNum int64, conv *row.DatumRowConverter,
+) error {
+	data := row.(copyData)
+	if len(data) < p.numKeyCols {
+		return newImportRowError(errors.Newf(
+			"unexpected number of columns, expected at least %d values, got %d",
+			p.numKeyCols, len(data)), data.String(), rowNum)
+	}
+	return p.pgCopyConsumer.FillDatums(ctx, data[:len(data)-p.numKeyCols], rowNum, conv)
+}
+
+// RowKey implements importRowKeyer. The key is a tuple that can be compared
+// to the key columns of the source table, see postgresKeyTuple.
+func (p *postgresSourceConsumer) RowKey(row interface{}) string {
+	data := row.(copyData)
+	if len(data) < p.numKeyCols {
+		// FillDatums rejects the row.
+		return ""
+	}
+	vals := make([]string, p.numKeyCols)
+	for i, v := range data[len(data)-p.numKeyCols:] {
+		if v != nil {
+			vals[i] = *v
+		}
+	}
+	return postgresKeyTuple(vals)
+}
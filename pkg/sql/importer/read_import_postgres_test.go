@@ -0,0 +1,49 @@
+// Copyright 2026 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package importer
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresSourceCopyQuery(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	d := &postgresSourceReader{opts: roachpb.PostgresSourceOptions{
+		Table:      "public.t",
+		Columns:    []string{"a", "b", "c"},
+		KeyColumns: []string{"a", "b"},
+	}}
+	partition := roachpb.PostgresSourcePartition{Predicate: "(a, b) <= ('3', 'x')"}
+
+	require.Equal(t,
+		"COPY (SELECT a, b, c, a, b FROM public.t ORDER BY a, b) TO STDOUT",
+		d.copyQuery(roachpb.PostgresSourcePartition{}, ""))
+	require.Equal(t,
+		"COPY (SELECT a, b, c, a, b FROM public.t WHERE (a, b) <= ('3', 'x') ORDER BY a, b) TO STDOUT",
+		d.copyQuery(partition, ""))
+
+	// The key of a row holds the values of the key columns, which are read
+	// last, and the import is resumed after it.
+	consumer := &postgresSourceConsumer{numKeyCols: len(d.opts.KeyColumns)}
+	a, b, c := "2", "it's", "c"
+	key := consumer.RowKey(copyData{&a, &b, &c, &a, &b})
+	require.Equal(t, `('2', e'it\'s')`, key)
+	require.Equal(t,
+		`COPY (SELECT a, b, c, a, b FROM public.t WHERE (a, b) <= ('3', 'x') AND (a, b) > ('2', e'it\'s') ORDER BY a, b) TO STDOUT`,
+		d.copyQuery(partition, key))
+}
@@ -2340,6 +2340,13 @@ func TestLogic_with(
 	runLogicTest(t, "with")
 }
 
+func TestLogic_witness_replicas_mixed(
+	t *testing.T,
+) {
+	defer leaktest.AfterTest(t)()
+	runLogicTest(t, "witness_replicas_mixed")
+}
+
 func TestLogic_workload_indexrecs(
 	t *testing.T,
 ) {
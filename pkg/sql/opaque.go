@@ -406,6 +406,7 @@ func init() {
 		&tree.AlterBackup{},
 		&tree.AlterBackupSchedule{},
 		&tree.AlterTenantReplication{},
+		&tree.AttachBackup{},
 		&tree.Backup{},
 		&tree.ShowBackup{},
 		&tree.Restore{},
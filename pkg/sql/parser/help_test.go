@@ -43,6 +43,9 @@ func TestContextualHelp(t *testing.T) {
 
 		{`ALTER BACKUP foo ADD NEW_KMS=bar WITH OLD_KMS=foobar ??`, `ALTER BACKUP`},
 
+		{`ATTACH ??`, `ATTACH BACKUP`},
+		{`ATTACH BACKUP FROM LATEST IN 'foo' AS bar ??`, `ATTACH BACKUP`},
+
 		{`ALTER TABLE IF ??`, `ALTER TABLE`},
 		{`ALTER TABLE blah ??`, `ALTER TABLE`},
 		{`ALTER TABLE blah ADD ??`, `ALTER TABLE`},
@@ -87,6 +87,26 @@ func (l *lexer) Lex(lval *sqlSymType) int {
 		if l.lastPos > 0 && l.tokens[l.lastPos-1].id == RETURNING {
 			lval.id = NOTHING_AFTER_RETURNING
 		}
+	case ROWS:
+		// RESTORE ROWS FROM <objname> WHERE ...
+		// RESTORE ROWS FROM <objname> FROM ...
+		//
+		// This is ambiguous with restoring a table called "rows" (`RESTORE
+		// rows FROM 'location'`) until we see the token that follows the
+		// object name after FROM, which is at most 5 tokens long.
+		if l.lastPos > 0 && l.tokens[l.lastPos-1].id == RESTORE &&
+			l.lastPos+1 < len(l.tokens) && l.tokens[l.lastPos+1].id == FROM {
+			for i := l.lastPos + 2; i < len(l.tokens) && i < l.lastPos+8; i++ {
+				curToken := l.tokens[i].id
+				if (curToken == WHERE || curToken == FROM) && i > l.lastPos+2 {
+					lval.id = ROWS_LA
+					break
+				}
+				if curToken < 255 /* not ident/keyword */ && curToken != '.' {
+					break
+				}
+			}
+		}
 	case INDEX:
 		// The following complex logic is a consternation, really.
 		//
@@ -901,7 +901,7 @@ func (u *sqlSymUnion) showCreateFormatOption() tree.ShowCreateFormatOption {
 // Ordinary key words in alphabetical order.
 %token <str> ABORT ABSOLUTE ACCESS ACTION ADD ADMIN AFTER AGGREGATE
 %token <str> ALL ALTER ALWAYS ANALYSE ANALYZE AND AND_AND ANY ANNOTATE_TYPE ARRAY AS ASC AS_JSON AT_AT
-%token <str> ASENSITIVE ASYMMETRIC AT ATOMIC ATTRIBUTE AUTHORIZATION AUTOMATIC AVAILABILITY
+%token <str> ASENSITIVE ASYMMETRIC AT ATOMIC ATTACH ATTRIBUTE AUTHORIZATION AUTOMATIC AVAILABILITY
 
 %token <str> BACKUP BACKUPS BACKWARD BATCH BEFORE BEGIN BETWEEN BIGINT BIGSERIAL BINARY BIT
 %token <str> BUCKET_COUNT
@@ -911,7 +911,7 @@ func (u *sqlSymUnion) showCreateFormatOption() tree.ShowCreateFormatOption {
 %token <str> CHARACTER CHARACTERISTICS CHECK CHECK_FILES CLOSE
 %token <str> CLUSTER CLUSTERS COALESCE COLLATE COLLATION COLUMN COLUMNS COMMENT COMMENTS COMMIT
 %token <str> COMMITTED COMPACT COMPLETE COMPLETIONS CONCAT CONCURRENTLY CONFIGURATION CONFIGURATIONS CONFIGURE
-%token <str> CONFLICT CONNECTION CONNECTIONS CONSTRAINT CONSTRAINTS CONTAINS CONTROLCHANGEFEED CONTROLJOB
+%token <str> CONFLICT CONNECTION CONNECTIONS CONSTRAINT CONSTRAINTS CONTAINS CONTENT_ADDRESSED CONTROLCHANGEFEED CONTROLJOB
 %token <str> CONVERSION CONVERT COPY COST COVERING CREATE CREATEDB CREATELOGIN CREATEROLE
 %token <str> CROSS CSV CUBE CURRENT CURRENT_CATALOG CURRENT_DATE CURRENT_SCHEMA
 %token <str> CURRENT_ROLE CURRENT_TIME CURRENT_TIMESTAMP
@@ -919,7 +919,7 @@ func (u *sqlSymUnion) showCreateFormatOption() tree.ShowCreateFormatOption {
 
 %token <str> DATA DATABASE DATABASES DATE DAY DEBUG_IDS DEBUG_PAUSE_ON DEC DEBUG_DUMP_METADATA_SST DECIMAL DEFAULT DEFAULTS DEFINER
 %token <str> DEALLOCATE DECLARE DEFERRABLE DEFERRED DELETE DELIMITER DEPENDS DESC DESTINATION DETACHED DETAILS
-%token <str> DISCARD DISTINCT DO DOMAIN DOUBLE DROP
+%token <str> DIFF DISCARD DISTINCT DO DOMAIN DOUBLE DROP
 
 %token <str> ELSE ENCODING ENCRYPTED ENCRYPTION_INFO_DIR ENCRYPTION_PASSPHRASE END ENUM ENUMS ESCAPE EXCEPT EXCLUDE EXCLUDING
 %token <str> EXISTS EXECUTE EXECUTION EXPERIMENTAL
@@ -1000,7 +1000,7 @@ func (u *sqlSymUnion) showCreateFormatOption() tree.ShowCreateFormatOption {
 %token <str> TRUNCATE TRUSTED TYPE TYPES
 %token <str> TRACING
 
-%token <str> UNBOUNDED UNCOMMITTED UNION UNIQUE UNKNOWN UNLISTEN UNLOGGED UNSAFE_RESTORE_INCOMPATIBLE_VERSION UNSPLIT
+%token <str> UNBOUNDED UNCOMMITTED UNION UNIQUE UNKNOWN UNLISTEN UNLOGGED UNREFERENCED UNSAFE_RESTORE_INCOMPATIBLE_VERSION UNSPLIT
 %token <str> UPDATE UPSERT UNSET UNTIL USE USER USERS USING UUID
 
 %token <str> VALID VALIDATE VALUE VALUES VARBIT VARCHAR VARIADIC VERIFY_BACKUP_TABLE_DATA VIEW VARYING VIEWACTIVITY VIEWACTIVITYREDACTED VIEWDEBUG
@@ -1032,8 +1032,10 @@ func (u *sqlSymUnion) showCreateFormatOption() tree.ShowCreateFormatOption {
 // references.
 // - TENANT_ALL is used to differentiate `ALTER TENANT <id>` from
 // `ALTER TENANT ALL`. Ditto `CLUSTER_ALL` and `CLUSTER ALL`.
+// - ROWS_LA is used to differentiate `RESTORE ROWS FROM <table> ...` from
+// restoring a table named "rows".
 %token NOT_LA NULLS_LA WITH_LA AS_LA GENERATED_ALWAYS GENERATED_BY_DEFAULT RESET_ALL ROLE_ALL
-%token USER_ALL ON_LA TENANT_ALL CLUSTER_ALL SET_TRACING
+%token USER_ALL ON_LA TENANT_ALL CLUSTER_ALL SET_TRACING ROWS_LA
 
 %union {
   id    int32
@@ -1049,6 +1051,7 @@ func (u *sqlSymUnion) showCreateFormatOption() tree.ShowCreateFormatOption {
 %type <tree.Statement> alter_stmt
 %type <tree.Statement> alter_changefeed_stmt
 %type <tree.Statement> alter_backup_stmt
+%type <tree.Statement> attach_backup_stmt
 %type <tree.Statement> alter_ddl_stmt
 %type <tree.Statement> alter_table_stmt
 %type <tree.Statement> alter_index_stmt
@@ -3175,6 +3178,7 @@ opt_clear_data:
 //    detached: execute backup job asynchronously, without waiting for its completion
 //    incremental_location: specify a different path to store the incremental backup
 //    include_all_virtual_clusters: enable backups of all virtual clusters during a cluster backup
+//    content_addressed: store data files by content hash in a pool shared by the backups in the collection
 //
 // %SeeAlso: RESTORE, WEBDOCS/backup.html
 backup_stmt:
@@ -3309,6 +3313,14 @@ backup_options:
   {
     $$.val = &tree.BackupOptions{IncludeAllSecondaryTenants: $3.expr()}
   }
+| CONTENT_ADDRESSED
+  {
+    $$.val = &tree.BackupOptions{ContentAddressed: tree.MakeDBool(true)}
+  }
+| CONTENT_ADDRESSED '=' a_expr
+  {
+    $$.val = &tree.BackupOptions{ContentAddressed: $3.expr()}
+  }
 
 include_all_clusters:
   INCLUDE_ALL_SECONDARY_TENANTS { /* SKIP DOC */ }
@@ -3385,6 +3397,9 @@ include_all_clusters:
 //     If backups were already created in the destination in which a new schedule references,
 //     this flag must be passed in to acknowledge that the new schedule may be backing up different
 //     objects.
+//   * compact_after_incrementals='<n>':
+//     Once a full backup taken by the schedule has <n> incremental backups, compact them
+//     into a new full backup that subsequent incremental backups are taken into.
 //
 // %SeeAlso: BACKUP
 create_schedule_for_backup_stmt:
@@ -3604,6 +3619,10 @@ drop_external_connection_stmt:
 // RESTORE SYSTEM USERS FROM <location...>
 //         [ AS OF SYSTEM TIME <expr> ]
 //         [ WITH <option> [= <value>] [, ...] ]
+// or
+// RESTORE ROWS FROM <tablename> [ WHERE <expr> ] FROM <subdir> IN <location...>
+//         [ AS OF SYSTEM TIME <expr> ] INTO <tablename>
+//         [ WITH <option> [= <value>] [, ...] ]
 //
 // Targets:
 //    TABLE <pattern> [, ...]
@@ -3685,6 +3704,18 @@ restore_stmt:
       Options: *($9.restoreOptions()),
     }
   }
+| RESTORE ROWS_LA FROM table_name opt_where_clause FROM string_or_placeholder IN list_of_string_or_placeholder_opt_list opt_as_of_clause INTO table_name opt_with_restore_options
+  {
+    $$.val = &tree.Restore{
+      RowsTable: $4.unresolvedObjectName(),
+      RowsFilter: $5.expr(),
+      Subdir: $7.expr(),
+      From: $9.listOfStringOrPlaceholderOptList(),
+      AsOf: $10.asOfClause(),
+      RowsInto: $12.unresolvedObjectName(),
+      Options: *($13.restoreOptions()),
+    }
+  }
 | RESTORE error // SHOW HELP: RESTORE
 
 string_or_placeholder_opt_list:
@@ -3870,6 +3901,11 @@ alter_unsupported_stmt:
 // Use CREATE TABLE followed by IMPORT INTO to create and import into a table
 // from external files that only have table data.
 //
+// -- Import table data directly from a live PostgreSQL-compatible database:
+// IMPORT INTO <tablename> [ ( <colnames...> ) ]
+//        FROM POSTGRES <url> TABLE <source tablename>
+//        [ WITH <option> [= <value>] [, ...] ]
+//
 // %SeeAlso: CREATE TABLE, WEBDOCS/import-into.html
 import_stmt:
  IMPORT import_format '(' string_or_placeholder ')' opt_with_options
@@ -3902,6 +3938,18 @@ import_stmt:
     name := $3.unresolvedObjectName().ToTableName()
     $$.val = &tree.Import{Table: &name, Into: true, IntoCols: nil, FileFormat: $4, Files: $7.exprs(), Options: $9.kvOptions()}
   }
+| IMPORT INTO table_name '(' insert_column_list ')' FROM import_format string_or_placeholder TABLE table_name opt_with_options
+  {
+    name := $3.unresolvedObjectName().ToTableName()
+    src := $11.unresolvedObjectName().ToTableName()
+    $$.val = &tree.Import{Table: &name, Into: true, IntoCols: $5.nameList(), FileFormat: $8, Files: tree.Exprs{$9.expr()}, SourceTable: &src, Options: $12.kvOptions()}
+  }
+| IMPORT INTO table_name FROM import_format string_or_placeholder TABLE table_name opt_with_options
+  {
+    name := $3.unresolvedObjectName().ToTableName()
+    src := $8.unresolvedObjectName().ToTableName()
+    $$.val = &tree.Import{Table: &name, Into: true, IntoCols: nil, FileFormat: $5, Files: tree.Exprs{$6.expr()}, SourceTable: &src, Options: $9.kvOptions()}
+  }
 | IMPORT error // SHOW HELP: IMPORT
 
 // %Help: EXPORT - export data to file in a distributed manner
@@ -3912,6 +3960,8 @@ import_stmt:
 // Formats:
 //    CSV
 //    Parquet
+//    Avro
+//    NDJSON
 //
 // Options:
 //    delimiter = '...'   [CSV-specific]
@@ -5788,6 +5838,7 @@ explainable_stmt:
 
 preparable_stmt:
   alter_stmt     // help texts in sub-rule
+| attach_backup_stmt // EXTEND WITH HELP: ATTACH BACKUP
 | backup_stmt    // EXTEND WITH HELP: BACKUP
 | cancel_stmt    // help texts in sub-rule
 | create_stmt    // help texts in sub-rule
@@ -5899,17 +5950,32 @@ alter_changefeed_cmd:
     }
   }
 
-// %Help: ALTER BACKUP - alter an existing backup's encryption keys
+// %Help: ALTER BACKUP - alter an existing backup's encryption keys or compact it
 // %Category: CCL
 // %Text:
 // ALTER BACKUP <location...>
 //        [ ADD NEW_KMS = <kms...> ]
 //        [ WITH OLD_KMS = <kms...> ]
+// ALTER BACKUP <subdir> IN <collection>
+//        COMPACT [ WITH <option> [= <value>] [, ...] ]
+// ALTER BACKUP <collection> DELETE UNREFERENCED FILES
 // Locations:
 //    "[scheme]://[host]/[path to backup]?[parameters]"
 //
 // KMS:
 //    "[kms_provider]://[kms_host]/[master_key_identifier]?[parameters]" : add new kms keys to backup
+//
+// COMPACT merges a full backup and its incremental backups into a new full
+// backup in the collection.
+//
+// Options:
+//    revision_history: preserve the revision history of the backups
+//    encryption_passphrase='...': the passphrase the backups are encrypted with
+//    kms='...': a KMS URI the backups are encrypted with
+//
+// DELETE UNREFERENCED FILES deletes the files in the shared file pool of a
+// collection of content_addressed backups that no backup in the collection
+// references any longer.
 alter_backup_stmt:
   ALTER BACKUP string_or_placeholder alter_backup_cmds
   {
@@ -5945,6 +6011,16 @@ alter_backup_cmd:
       KMSInfo:	$2.backupKMS(),
     }
 	}
+|	COMPACT opt_with_backup_options
+	{
+    $$.val = &tree.AlterBackupCompact{
+      Options:	*$2.backupOptions(),
+    }
+	}
+|	DELETE UNREFERENCED FILES
+	{
+    $$.val = &tree.AlterBackupDeleteUnreferencedFiles{}
+	}
 
 backup_kms:
 	NEW_KMS '=' string_or_placeholder_opt_list WITH OLD_KMS '=' string_or_placeholder_opt_list
@@ -7516,9 +7592,66 @@ show_histogram_stmt:
   }
 | SHOW HISTOGRAM error // SHOW HELP: SHOW HISTOGRAM
 
+// %Help: ATTACH BACKUP - query the tables of a backup in place
+// %Category: CCL
+// %Text:
+// ATTACH BACKUP [DATABASE <backup_database>] FROM <subdir> IN <collection> AS <database_name>
+//   [AS OF SYSTEM TIME <expr>] [WITH <options>]
+//
+// Creates a database with a view for each table of a database in the backup,
+// which returns the rows of the table read directly from the backup files, as
+// of the end time of the backup or the given time if the backup has revision
+// history:
+//   SELECT ... FROM <database_name>.<table_name>
+// Every query of a view reads all of the rows of its table from the backup,
+// whatever its WHERE clause; use RESTORE ROWS to read a few rows by primary
+// key. The database in the backup must be specified if the backup contains
+// more than one. DROP DATABASE detaches the backup.
+//
+// Options:
+//    kms="[kms_provider]://[kms_host]/[master_key_identifier]?[parameters]": decrypt backups using KMS
+//    incremental_location: the location of the incremental backups
+//
+// %SeeAlso: SHOW BACKUP, RESTORE, DROP DATABASE, WEBDOCS/show-backup.html
+attach_backup_stmt:
+  ATTACH BACKUP FROM string_or_placeholder IN string_or_placeholder_opt_list AS database_name opt_as_of_clause opt_with_show_backup_options
+	{
+		$$.val = &tree.AttachBackup{
+			Subdir:       $4.expr(),
+			InCollection: $6.stringOrPlaceholderOptList(),
+			Database:     tree.Name($8),
+			AsOf:         $9.asOfClause(),
+			Options:      *$10.showBackupOptions(),
+		}
+	}
+| ATTACH BACKUP DATABASE database_name FROM string_or_placeholder IN string_or_placeholder_opt_list AS database_name opt_as_of_clause opt_with_show_backup_options
+	{
+		$$.val = &tree.AttachBackup{
+			BackupDatabase: tree.Name($4),
+			Subdir:         $6.expr(),
+			InCollection:   $8.stringOrPlaceholderOptList(),
+			Database:       tree.Name($10),
+			AsOf:           $11.asOfClause(),
+			Options:        *$12.showBackupOptions(),
+		}
+	}
+| ATTACH error // SHOW HELP: ATTACH BACKUP
+
 // %Help: SHOW BACKUP - list backup contents
 // %Category: CCL
-// %Text: SHOW BACKUP [SCHEMAS|FILES|RANGES] <location>
+// %Text:
+// SHOW BACKUP [SCHEMAS|FILES|RANGES] <location>
+// SHOW BACKUP TABLE <tablename> FROM <subdir> IN <collection> [AS OF SYSTEM TIME <expr>] [WITH <options>]
+//
+// SHOW BACKUP DIFF TABLE <tablename> FROM <subdir> IN <collection> BETWEEN <expr> AND <expr> [WITH <options>]
+//
+// SHOW BACKUP TABLE returns the rows of a table in a backup, read directly
+// from the backup files, and can be used as a data source:
+//   SELECT ... FROM [SHOW BACKUP TABLE ...]
+// See also ATTACH BACKUP to query the tables of a backup by name.
+//
+// SHOW BACKUP DIFF TABLE returns the rows of a table that were inserted,
+// updated or deleted between two times covered by a backup.
 // %SeeAlso: WEBDOCS/show-backup.html
 show_backup_stmt:
   SHOW BACKUPS IN string_or_placeholder_opt_list
@@ -7537,6 +7670,31 @@ show_backup_stmt:
 			Options: *$8.showBackupOptions(),
 		}
 	}
+| SHOW BACKUP TABLE table_name FROM string_or_placeholder IN string_or_placeholder_opt_list opt_as_of_clause opt_with_show_backup_options
+	{
+		$$.val = &tree.ShowBackup{
+			From:         true,
+			Details:      tree.BackupTableRowsDetails,
+			Table:        $4.unresolvedObjectName(),
+			Path:         $6.expr(),
+			InCollection: $8.stringOrPlaceholderOptList(),
+			AsOf:         $9.asOfClause(),
+			Options:      *$10.showBackupOptions(),
+		}
+	}
+| SHOW BACKUP DIFF TABLE table_name FROM string_or_placeholder IN string_or_placeholder_opt_list BETWEEN b_expr AND a_expr opt_with_show_backup_options
+	{
+		$$.val = &tree.ShowBackup{
+			From:         true,
+			Details:      tree.BackupTableDiffDetails,
+			Table:        $5.unresolvedObjectName(),
+			Path:         $7.expr(),
+			InCollection: $9.stringOrPlaceholderOptList(),
+			DiffStart:    $11.expr(),
+			DiffEnd:      $13.expr(),
+			Options:      *$14.showBackupOptions(),
+		}
+	}
 | SHOW BACKUP string_or_placeholder IN string_or_placeholder_opt_list opt_with_show_backup_options
 	{
 		$$.val = &tree.ShowBackup{
@@ -16541,6 +16699,7 @@ unreserved_keyword:
 | AS_JSON
 | AT
 | ATOMIC
+| ATTACH
 | ATTRIBUTE
 | AUTOMATIC
 | AVAILABILITY
@@ -16582,6 +16741,7 @@ unreserved_keyword:
 | CONNECTION
 | CONNECTIONS
 | CONSTRAINTS
+| CONTENT_ADDRESSED
 | CONTROLCHANGEFEED
 | CONTROLJOB
 | CONVERSION
@@ -16615,6 +16775,7 @@ unreserved_keyword:
 | DESTINATION
 | DETACHED
 | DETAILS
+| DIFF
 | DISCARD
 | DOMAIN
 | DOUBLE
@@ -16954,6 +17115,7 @@ unreserved_keyword:
 | UNKNOWN
 | UNLISTEN
 | UNLOGGED
+| UNREFERENCED
 | UNSAFE_RESTORE_INCOMPATIBLE_VERSION
 | UNSET
 | UNSPLIT
@@ -17012,6 +17174,7 @@ bare_label_keywords:
 | AS_JSON
 | AT
 | ATOMIC
+| ATTACH
 | ATTRIBUTE
 | AUTHORIZATION
 | AUTOMATIC
@@ -17069,6 +17232,7 @@ bare_label_keywords:
 | CONNECTIONS
 | CONSTRAINT
 | CONSTRAINTS
+| CONTENT_ADDRESSED
 | CONTROLCHANGEFEED
 | CONTROLJOB
 | CONVERSION
@@ -17114,6 +17278,7 @@ bare_label_keywords:
 | DESTINATION
 | DETACHED
 | DETAILS
+| DIFF
 | DISCARD
 | DISTINCT
 | DO
@@ -17528,6 +17693,7 @@ bare_label_keywords:
 | UNKNOWN
 | UNLISTEN
 | UNLOGGED
+| UNREFERENCED
 | UNSAFE_RESTORE_INCOMPATIBLE_VERSION
 | UNSET
 | UNSPLIT
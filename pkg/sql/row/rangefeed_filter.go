@@ -0,0 +1,264 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package row
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/rangefeed"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/fetchpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc/valueside"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/errors"
+)
+
+func init() {
+	rangefeed.NewRowPredicate = newRangefeedRowPredicate
+}
+
+// RangefeedFilterPredicate is a comparison between a column and a constant
+// which is evaluated by the server when filtering rangefeed events.
+type RangefeedFilterPredicate struct {
+	ColumnID descpb.ColumnID
+	Op       kvpb.RangeFeedFilterPredicate_Operator
+	// Value is unused for the IS_NULL and IS_NOT_NULL operators.
+	Value tree.Datum
+}
+
+// MakeRangefeedFilter returns a filter for rangefeeds over the primary index
+// of the given table which only emits the events of the given column family
+// for rows matching all of the given predicates.
+//
+// Every event carries a single column family's worth of data, so an error is
+// returned if a predicate references a column which is neither a key column
+// nor stored in the family.
+func MakeRangefeedFilter(
+	codec keys.SQLCodec,
+	desc catalog.TableDescriptor,
+	familyID descpb.FamilyID,
+	preds []RangefeedFilterPredicate,
+) (*kvpb.RangeFeedFilter, error) {
+	family, err := catalog.MustFindFamilyByID(desc, familyID)
+	if err != nil {
+		return nil, err
+	}
+	filter := &kvpb.RangeFeedFilter{FamilyIDs: []uint32{uint32(familyID)}}
+	if len(preds) == 0 {
+		return filter, nil
+	}
+
+	watched := desc.GetPrimaryIndex().CollectKeyColumnIDs()
+	watched.UnionWith(catalog.MakeTableColSet(family.ColumnIDs...))
+	var fetchColumnIDs []descpb.ColumnID
+	ordinals := make(map[descpb.ColumnID]uint32)
+	for _, pred := range preds {
+		col, err := catalog.MustFindColumnByID(desc, pred.ColumnID)
+		if err != nil {
+			return nil, err
+		}
+		if !watched.Contains(pred.ColumnID) {
+			return nil, pgerror.Newf(pgcode.InvalidParameterValue,
+				"rangefeed filter predicate references column %s, which is not stored in column family %s",
+				col.GetName(), family.Name)
+		}
+		if _, ok := ordinals[pred.ColumnID]; !ok {
+			ordinals[pred.ColumnID] = uint32(len(fetchColumnIDs))
+			fetchColumnIDs = append(fetchColumnIDs, pred.ColumnID)
+		}
+		p := kvpb.RangeFeedFilterPredicate{ColumnOrdinal: ordinals[pred.ColumnID], Op: pred.Op}
+		switch pred.Op {
+		case kvpb.RangeFeedFilterPredicate_IS_NULL, kvpb.RangeFeedFilterPredicate_IS_NOT_NULL:
+		default:
+			if p.Value, err = valueside.Encode(nil, valueside.NoColumnID, pred.Value, nil); err != nil {
+				return nil, err
+			}
+		}
+		filter.Predicates = append(filter.Predicates, p)
+	}
+
+	filter.IndexFetchSpec = &fetchpb.IndexFetchSpec{}
+	if err := rowenc.InitIndexFetchSpec(
+		filter.IndexFetchSpec, codec, desc, desc.GetPrimaryIndex(), fetchColumnIDs,
+	); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// rangefeedPredicate is a single comparison of a kvpb.RangeFeedFilter, with
+// its constant operand decoded.
+type rangefeedPredicate struct {
+	colIdx int
+	op     kvpb.RangeFeedFilterPredicate_Operator
+	// val is nil for the IS_NULL and IS_NOT_NULL operators.
+	val tree.Datum
+}
+
+// rangefeedRowPredicate implements rangefeed.RowPredicate by decoding each
+// key-value pair into a row using a Fetcher and evaluating the filter's
+// predicates against the decoded datums.
+type rangefeedRowPredicate struct {
+	tableID    descpb.ID
+	indexID    descpb.IndexID
+	fetcher    Fetcher
+	kvProvider KVProvider
+	alloc      tree.DatumAlloc
+	preds      []rangefeedPredicate
+	// evalCtx is an empty evaluation context used for comparing datums of the
+	// same type. It lacks a session, so UTC is used as the time zone.
+	evalCtx eval.Context
+}
+
+var _ rangefeed.RowPredicate = &rangefeedRowPredicate{}
+
+func newRangefeedRowPredicate(spec *kvpb.RangeFeedFilter) (rangefeed.RowPredicate, error) {
+	p := &rangefeedRowPredicate{
+		tableID: spec.IndexFetchSpec.TableID,
+		indexID: spec.IndexFetchSpec.IndexID,
+		preds:   make([]rangefeedPredicate, len(spec.Predicates)),
+	}
+	cols := spec.IndexFetchSpec.FetchedColumns
+	for i, pred := range spec.Predicates {
+		if int(pred.ColumnOrdinal) >= len(cols) {
+			return nil, errors.Errorf(
+				"rangefeed filter predicate references column ordinal %d, but only %d columns are fetched",
+				pred.ColumnOrdinal, len(cols),
+			)
+		}
+		p.preds[i] = rangefeedPredicate{colIdx: int(pred.ColumnOrdinal), op: pred.Op}
+		switch pred.Op {
+		case kvpb.RangeFeedFilterPredicate_IS_NULL, kvpb.RangeFeedFilterPredicate_IS_NOT_NULL:
+			continue
+		case kvpb.RangeFeedFilterPredicate_EQ, kvpb.RangeFeedFilterPredicate_NE,
+			kvpb.RangeFeedFilterPredicate_LT, kvpb.RangeFeedFilterPredicate_LE,
+			kvpb.RangeFeedFilterPredicate_GT, kvpb.RangeFeedFilterPredicate_GE:
+		default:
+			return nil, errors.Errorf("unknown rangefeed filter operator %s", pred.Op)
+		}
+		col := &cols[pred.ColumnOrdinal]
+		val, _, err := valueside.Decode(&p.alloc, col.Type, pred.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding rangefeed filter operand for column %s", col.Name)
+		}
+		p.preds[i].val = val
+	}
+
+	// Every key-value pair carries a single column family, so columns stored in
+	// other families are expected to be missing.
+	p.fetcher.IgnoreUnexpectedNulls = true
+	if err := p.fetcher.Init(
+		context.Background(),
+		FetcherInitArgs{
+			WillUseKVProvider: true,
+			Alloc:             &p.alloc,
+			Spec:              spec.IndexFetchSpec,
+		},
+	); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Matches implements the rangefeed.RowPredicate interface.
+func (p *rangefeedRowPredicate) Matches(ctx context.Context, kv roachpb.KeyValue) (bool, error) {
+	// The keys of other indexes, such as the new primary index of a table whose
+	// primary key is being changed, can't be decoded with the filter's spec.
+	_, tenantID, err := keys.DecodeTenantPrefixE(kv.Key)
+	if err != nil {
+		return false, err
+	}
+	_, tableID, indexID, err := keys.MakeSQLCodec(tenantID).DecodeIndexPrefix(kv.Key)
+	if err != nil {
+		return false, err
+	}
+	if descpb.ID(tableID) != p.tableID || descpb.IndexID(indexID) != p.indexID {
+		return true, nil
+	}
+
+	p.kvProvider.KVs = append(p.kvProvider.KVs[:0], kv)
+	if err := p.fetcher.ConsumeKVProvider(ctx, &p.kvProvider); err != nil {
+		return false, err
+	}
+	datums, err := p.fetcher.NextRowDecoded(ctx)
+	if err != nil {
+		return false, err
+	}
+	if datums == nil {
+		return false, errors.AssertionFailedf("no row decoded from key %s", kv.Key)
+	}
+	// Evaluate before draining the fetcher, since the datums are reused.
+	match, err := p.eval(datums)
+	if err != nil {
+		return false, err
+	}
+	// Exhaust the fetcher, which was fed a single key-value pair, so that it's
+	// ready for the next one.
+	if next, _, err := p.fetcher.NextRow(ctx); err != nil {
+		return false, err
+	} else if next != nil {
+		return false, errors.AssertionFailedf("unexpected second row decoded from key %s", kv.Key)
+	}
+	return match, nil
+}
+
+func (p *rangefeedRowPredicate) eval(datums tree.Datums) (bool, error) {
+	for _, pred := range p.preds {
+		d := datums[pred.colIdx]
+		switch pred.op {
+		case kvpb.RangeFeedFilterPredicate_IS_NULL:
+			if d != tree.DNull {
+				return false, nil
+			}
+			continue
+		case kvpb.RangeFeedFilterPredicate_IS_NOT_NULL:
+			if d == tree.DNull {
+				return false, nil
+			}
+			continue
+		}
+		// Comparisons with NULL never match.
+		if d == tree.DNull || pred.val == tree.DNull {
+			return false, nil
+		}
+		cmp, err := d.CompareError(&p.evalCtx, pred.val)
+		if err != nil {
+			return false, err
+		}
+		var ok bool
+		switch pred.op {
+		case kvpb.RangeFeedFilterPredicate_EQ:
+			ok = cmp == 0
+		case kvpb.RangeFeedFilterPredicate_NE:
+			ok = cmp != 0
+		case kvpb.RangeFeedFilterPredicate_LT:
+			ok = cmp < 0
+		case kvpb.RangeFeedFilterPredicate_LE:
+			ok = cmp <= 0
+		case kvpb.RangeFeedFilterPredicate_GT:
+			ok = cmp > 0
+		case kvpb.RangeFeedFilterPredicate_GE:
+			ok = cmp >= 0
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2023 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package row_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/rangefeed"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/desctestutils"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/fetchpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc/valueside"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangefeedRowPredicate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	srv, db, kvDB := serverutils.StartServer(t, base.TestServerArgs{})
+	defer srv.Stopper().Stop(ctx)
+	s := srv.ApplicationLayer()
+	sqlDB := sqlutils.MakeSQLRunner(db)
+
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	sqlDB.Exec(t, `CREATE TABLE d.t (
+		a INT PRIMARY KEY, b STRING, c INT,
+		FAMILY f0 (a, b), FAMILY f1 (c)
+	)`)
+	sqlDB.Exec(t, `INSERT INTO d.t VALUES (1, 'x', 10), (2, 'y', 20), (3, NULL, 30)`)
+
+	desc := desctestutils.TestingGetPublicTableDescriptor(kvDB, s.Codec(), `d`, `t`)
+	var spec fetchpb.IndexFetchSpec
+	require.NoError(t, rowenc.InitIndexFetchSpec(
+		&spec, s.Codec(), desc, desc.GetPrimaryIndex(), desc.PublicColumnIDs(),
+	))
+	kvs, err := kvDB.Scan(ctx, desc.PrimaryIndexSpan(s.Codec()).Key, desc.PrimaryIndexSpan(s.Codec()).EndKey, 0)
+	require.NoError(t, err)
+
+	encode := func(d tree.Datum) []byte {
+		b, err := valueside.Encode(nil, valueside.NoColumnID, d, nil)
+		require.NoError(t, err)
+		return b
+	}
+	pred := func(col uint32, op kvpb.RangeFeedFilterPredicate_Operator, d tree.Datum) kvpb.RangeFeedFilterPredicate {
+		p := kvpb.RangeFeedFilterPredicate{ColumnOrdinal: col, Op: op}
+		if d != nil {
+			p.Value = encode(d)
+		}
+		return p
+	}
+
+	// matching returns the primary keys and families of the KVs matching the
+	// given predicates.
+	type famKey struct {
+		pk     int64
+		family uint32
+	}
+	matching := func(preds ...kvpb.RangeFeedFilterPredicate) []famKey {
+		p, err := rangefeed.NewRowPredicate(&kvpb.RangeFeedFilter{
+			IndexFetchSpec: &spec,
+			Predicates:     preds,
+		})
+		require.NoError(t, err)
+		var res []famKey
+		for _, kv := range kvs {
+			ok, err := p.Matches(ctx, kv)
+			require.NoError(t, err)
+			if !ok {
+				continue
+			}
+			famID, err := keys.DecodeFamilyKey(kv.Key)
+			require.NoError(t, err)
+			rest, err := keys.StripIndexPrefix(kv.Key)
+			require.NoError(t, err)
+			_, pk, err := encoding.DecodeVarintAscending(rest)
+			require.NoError(t, err)
+			res = append(res, famKey{pk: pk, family: famID})
+		}
+		return res
+	}
+
+	// Column ordinals: a=0, b=1, c=2.
+	const a, b, c = 0, 1, 2
+	require.Equal(t,
+		[]famKey{{1, 0}},
+		matching(pred(b, kvpb.RangeFeedFilterPredicate_EQ, tree.NewDString("x"))),
+	)
+	require.Equal(t,
+		[]famKey{{2, 0}},
+		matching(pred(b, kvpb.RangeFeedFilterPredicate_NE, tree.NewDString("x"))),
+	)
+	// Columns stored in other families are NULL when decoding a single KV,
+	// which is why predicates must only reference the watched family.
+	require.Equal(t,
+		[]famKey{{1, 1}, {2, 1}, {3, 0}, {3, 1}},
+		matching(pred(b, kvpb.RangeFeedFilterPredicate_IS_NULL, nil)),
+	)
+	require.Equal(t,
+		[]famKey{{2, 1}, {3, 1}},
+		matching(pred(c, kvpb.RangeFeedFilterPredicate_GE, tree.NewDInt(20))),
+	)
+	require.Equal(t,
+		[]famKey{{2, 0}, {2, 1}},
+		matching(
+			pred(a, kvpb.RangeFeedFilterPredicate_GT, tree.NewDInt(1)),
+			pred(a, kvpb.RangeFeedFilterPredicate_LT, tree.NewDInt(3)),
+		),
+	)
+
+	_, err = rangefeed.NewRowPredicate(&kvpb.RangeFeedFilter{
+		IndexFetchSpec: &spec,
+		Predicates:     []kvpb.RangeFeedFilterPredicate{pred(5, kvpb.RangeFeedFilterPredicate_IS_NULL, nil)},
+	})
+	require.Error(t, err)
+
+	// Filters built at plan time may only reference key columns and columns
+	// stored in the watched family.
+	cols := desc.PublicColumns()
+	filter, err := row.MakeRangefeedFilter(s.Codec(), desc, 1 /* familyID */, []row.RangefeedFilterPredicate{
+		{ColumnID: cols[a].GetID(), Op: kvpb.RangeFeedFilterPredicate_GT, Value: tree.NewDInt(1)},
+		{ColumnID: cols[c].GetID(), Op: kvpb.RangeFeedFilterPredicate_LE, Value: tree.NewDInt(20)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint32{1}, filter.FamilyIDs)
+	p, err := rangefeed.NewRowPredicate(filter)
+	require.NoError(t, err)
+	var res []famKey
+	for _, kv := range kvs {
+		if famID, err := keys.DecodeFamilyKey(kv.Key); err != nil || famID != 1 {
+			continue
+		}
+		ok, err := p.Matches(ctx, kv)
+		require.NoError(t, err)
+		if ok {
+			rest, err := keys.StripIndexPrefix(kv.Key)
+			require.NoError(t, err)
+			_, pk, err := encoding.DecodeVarintAscending(rest)
+			require.NoError(t, err)
+			res = append(res, famKey{pk: pk, family: 1})
+		}
+	}
+	require.Equal(t, []famKey{{2, 1}}, res)
+
+	_, err = row.MakeRangefeedFilter(s.Codec(), desc, 0 /* familyID */, []row.RangefeedFilterPredicate{
+		{ColumnID: cols[c].GetID(), Op: kvpb.RangeFeedFilterPredicate_IS_NULL},
+	})
+	require.ErrorContains(t, err, "column c, which is not stored in column family f0")
+}
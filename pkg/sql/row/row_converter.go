@@ -190,6 +190,9 @@ type KVBatch struct {
 	Source int32
 	// LastRow is the index of the last converted row in source in this batch.
 	LastRow int64
+	// ResumeKey, if set, is the key in source of the row at LastRow, for
+	// sources whose rows are addressed by key rather than by index.
+	ResumeKey string
 	// Progress represents the fraction of the input that generated this row.
 	Progress float32
 	// KVs is the actual converted KV data.
@@ -230,6 +233,10 @@ type DatumRowConverter struct {
 	// FractionFn is used to set the progress header in KVBatches.
 	CompletedRowFn func() int64
 	FractionFn     func() float32
+	// ResumeKeyFn, if set, returns the key in the source of the row returned
+	// by the last call to CompletedRowFn, and is used to set the ResumeKey
+	// header in KVBatches.
+	ResumeKeyFn func() string
 
 	db *kv.DB
 }
@@ -585,6 +592,9 @@ func (c *DatumRowConverter) SendBatch(ctx context.Context) error {
 	if c.CompletedRowFn != nil {
 		c.KvBatch.LastRow = c.CompletedRowFn()
 	}
+	if c.ResumeKeyFn != nil {
+		c.KvBatch.ResumeKey = c.ResumeKeyFn()
+	}
 	select {
 	case c.KvCh <- c.KvBatch:
 	case <-ctx.Done():
@@ -261,6 +261,15 @@ func NewProcessor(
 		}
 		return NewIngestStoppedProcessor(ctx, flowCtx, processorID, *core.IngestStopped, post)
 	}
+	if core.BackupTableReader != nil {
+		if err := checkNumIn(inputs, 0); err != nil {
+			return nil, err
+		}
+		if NewBackupTableReaderProcessor == nil {
+			return nil, errors.New("BackupTableReader processor unimplemented")
+		}
+		return NewBackupTableReaderProcessor(ctx, flowCtx, processorID, *core.BackupTableReader, post)
+	}
 	if core.BackupData != nil {
 		if err := checkNumIn(inputs, 0); err != nil {
 			return nil, err
@@ -302,8 +311,13 @@ func NewProcessor(
 			return nil, err
 		}
 
-		if core.Exporter.Format.Format == roachpb.IOFileFormat_Parquet {
+		switch core.Exporter.Format.Format {
+		case roachpb.IOFileFormat_Parquet:
 			return NewParquetWriterProcessor(ctx, flowCtx, processorID, *core.Exporter, post, inputs[0])
+		case roachpb.IOFileFormat_Avro:
+			return NewAvroWriterProcessor(ctx, flowCtx, processorID, *core.Exporter, post, inputs[0])
+		case roachpb.IOFileFormat_NDJSON:
+			return NewNDJSONWriterProcessor(ctx, flowCtx, processorID, *core.Exporter, post, inputs[0])
 		}
 		return NewCSVWriterProcessor(ctx, flowCtx, processorID, *core.Exporter, post, inputs[0])
 	}
@@ -419,6 +433,9 @@ var NewIngestStoppedProcessor func(context.Context, *execinfra.FlowCtx, int32, e
 // NewBackupDataProcessor is implemented in the non-free (CCL) codebase and then injected here via runtime initialization.
 var NewBackupDataProcessor func(context.Context, *execinfra.FlowCtx, int32, execinfrapb.BackupDataSpec, *execinfrapb.PostProcessSpec) (execinfra.Processor, error)
 
+// NewBackupTableReaderProcessor is implemented in the non-free (CCL) codebase and then injected here via runtime initialization.
+var NewBackupTableReaderProcessor func(context.Context, *execinfra.FlowCtx, int32, execinfrapb.BackupTableReaderSpec, *execinfrapb.PostProcessSpec) (execinfra.Processor, error)
+
 // NewSplitAndScatterProcessor is implemented in the non-free (CCL) codebase and then injected here via runtime initialization.
 var NewSplitAndScatterProcessor func(context.Context, *execinfra.FlowCtx, int32, execinfrapb.SplitAndScatterSpec, *execinfrapb.PostProcessSpec) (execinfra.Processor, error)
 
@@ -434,6 +451,12 @@ var NewCSVWriterProcessor func(context.Context, *execinfra.FlowCtx, int32, execi
 // NewParquetWriterProcessor is implemented in the non-free (CCL) codebase and then injected here via runtime initialization.
 var NewParquetWriterProcessor func(context.Context, *execinfra.FlowCtx, int32, execinfrapb.ExportSpec, *execinfrapb.PostProcessSpec, execinfra.RowSource) (execinfra.Processor, error)
 
+// NewAvroWriterProcessor is implemented in the non-free (CCL) codebase and then injected here via runtime initialization.
+var NewAvroWriterProcessor func(context.Context, *execinfra.FlowCtx, int32, execinfrapb.ExportSpec, *execinfrapb.PostProcessSpec, execinfra.RowSource) (execinfra.Processor, error)
+
+// NewNDJSONWriterProcessor is implemented in the non-free (CCL) codebase and then injected here via runtime initialization.
+var NewNDJSONWriterProcessor func(context.Context, *execinfra.FlowCtx, int32, execinfrapb.ExportSpec, *execinfrapb.PostProcessSpec, execinfra.RowSource) (execinfra.Processor, error)
+
 // NewChangeAggregatorProcessor is implemented in the non-free (CCL) codebase and then injected here via runtime initialization.
 var NewChangeAggregatorProcessor func(context.Context, *execinfra.FlowCtx, int32, execinfrapb.ChangeAggregatorSpec, *execinfrapb.PostProcessSpec) (execinfra.Processor, error)
 
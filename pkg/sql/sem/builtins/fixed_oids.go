@@ -2453,6 +2453,8 @@ var builtinOidsArray = []string{
 	2482: `bitmask_xor(a: varbit, b: string) -> varbit`,
 	2483: `bitmask_xor(a: string, b: varbit) -> varbit`,
 	2484: `oidvectortypes(vector: oidvector) -> string`,
+	2485: `crdb_internal.kv_rotate_store_encryption_key() -> bool`,
+	2486: `crdb_internal.kv_rotate_store_encryption_key(store_id: int) -> bool`,
 }
 
 var builtinOidsBySignature map[string]oid.Oid
@@ -200,6 +200,8 @@ const (
 	CrdbInternalKVFlowTokenDeductions
 	CrdbInternalRepairableCatalogCorruptionsViewID
 	CrdbInternalKVProtectedTS
+	CrdbInternalTransactionWaitGraphTableID
+	CrdbInternalHotKeysTableID
 	InformationSchemaID
 	InformationSchemaAdministrableRoleAuthorizationsID
 	InformationSchemaApplicableRolesID
@@ -52,9 +52,13 @@ type AlterBackupCmd interface {
 	alterBackupCmd()
 }
 
-func (node *AlterBackupKMS) alterBackupCmd() {}
+func (node *AlterBackupKMS) alterBackupCmd()                     {}
+func (node *AlterBackupCompact) alterBackupCmd()                 {}
+func (node *AlterBackupDeleteUnreferencedFiles) alterBackupCmd() {}
 
 var _ AlterBackupCmd = &AlterBackupKMS{}
+var _ AlterBackupCmd = &AlterBackupCompact{}
+var _ AlterBackupCmd = &AlterBackupDeleteUnreferencedFiles{}
 
 // AlterBackupKMS represents a possible alter_backup_cmd option.
 type AlterBackupKMS struct {
@@ -75,3 +79,30 @@ type BackupKMS struct {
 	NewKMSURI StringOrPlaceholderOptList
 	OldKMSURI StringOrPlaceholderOptList
 }
+
+// AlterBackupCompact represents an ALTER BACKUP ... COMPACT command, which
+// merges a full backup and its incremental backups into a new full backup.
+type AlterBackupCompact struct {
+	Options BackupOptions
+}
+
+// Format implements the NodeFormatter interface.
+func (node *AlterBackupCompact) Format(ctx *FmtCtx) {
+	ctx.WriteString(" COMPACT")
+	if !node.Options.IsDefault() {
+		ctx.WriteString(" WITH OPTIONS (")
+		ctx.FormatNode(&node.Options)
+		ctx.WriteString(")")
+	}
+}
+
+// AlterBackupDeleteUnreferencedFiles represents an ALTER BACKUP ... DELETE
+// UNREFERENCED FILES command, which deletes the files in the shared file pool
+// of a collection of content-addressed backups that are no longer referenced
+// by any backup in the collection.
+type AlterBackupDeleteUnreferencedFiles struct{}
+
+// Format implements the NodeFormatter interface.
+func (node *AlterBackupDeleteUnreferencedFiles) Format(ctx *FmtCtx) {
+	ctx.WriteString(" DELETE UNREFERENCED FILES")
+}
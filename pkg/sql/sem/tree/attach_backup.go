@@ -0,0 +1,52 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package tree
+
+// AttachBackup represents an ATTACH BACKUP statement, which creates a
+// database with a view for each table of a database in a backup that returns
+// the rows of the table read directly from the backup files.
+type AttachBackup struct {
+	// BackupDatabase is the database in the backup whose tables are attached.
+	// It may be empty if the backup contains a single database.
+	BackupDatabase Name
+	Subdir         Expr
+	InCollection   StringOrPlaceholderOptList
+	// Database is the name of the database that is created.
+	Database Name
+	AsOf     AsOfClause
+	Options  ShowBackupOptions
+}
+
+var _ Statement = &AttachBackup{}
+
+// Format implements the NodeFormatter interface.
+func (node *AttachBackup) Format(ctx *FmtCtx) {
+	ctx.WriteString("ATTACH BACKUP ")
+	if node.BackupDatabase != "" {
+		ctx.WriteString("DATABASE ")
+		ctx.FormatNode(&node.BackupDatabase)
+		ctx.WriteString(" ")
+	}
+	ctx.WriteString("FROM ")
+	ctx.FormatNode(node.Subdir)
+	ctx.WriteString(" IN ")
+	ctx.FormatNode(&node.InCollection)
+	ctx.WriteString(" AS ")
+	ctx.FormatNode(&node.Database)
+	if node.AsOf.Expr != nil {
+		ctx.WriteString(" ")
+		ctx.FormatNode(&node.AsOf)
+	}
+	if !node.Options.IsDefault() {
+		ctx.WriteString(" WITH ")
+		ctx.FormatNode(&node.Options)
+	}
+}
@@ -47,6 +47,7 @@ type BackupOptions struct {
 	EncryptionKMSURI           StringOrPlaceholderOptList
 	IncrementalStorage         StringOrPlaceholderOptList
 	ExecutionLocality          Expr
+	ContentAddressed           Expr
 }
 
 var _ NodeFormatter = &BackupOptions{}
@@ -172,6 +173,14 @@ type Restore struct {
 	// ... FROM 'from' IN 'subdir'...`. Alternatively, restore_planning.go will set
 	// it for the query `RESTORE ... FROM 'from' IN LATEST...`
 	Subdir Expr
+
+	// RowsTable is set for RESTORE ROWS FROM <table> [WHERE <filter>] ... INTO
+	// <target>, which copies the rows of a table in the backup that match
+	// RowsFilter into the existing table RowsInto rather than restoring any
+	// descriptors. RowsFilter is nil if no WHERE clause was specified.
+	RowsTable  *UnresolvedObjectName
+	RowsFilter Expr
+	RowsInto   *UnresolvedObjectName
 }
 
 var _ Statement = &Restore{}
@@ -179,7 +188,15 @@ var _ Statement = &Restore{}
 // Format implements the NodeFormatter interface.
 func (node *Restore) Format(ctx *FmtCtx) {
 	ctx.WriteString("RESTORE ")
-	if node.DescriptorCoverage == RequestedDescriptors {
+	if node.RowsTable != nil {
+		ctx.WriteString("ROWS FROM ")
+		ctx.FormatNode(node.RowsTable)
+		if node.RowsFilter != nil {
+			ctx.WriteString(" WHERE ")
+			ctx.FormatNode(node.RowsFilter)
+		}
+		ctx.WriteString(" ")
+	} else if node.DescriptorCoverage == RequestedDescriptors {
 		ctx.FormatNode(&node.Targets)
 		ctx.WriteString(" ")
 	}
@@ -198,6 +215,10 @@ func (node *Restore) Format(ctx *FmtCtx) {
 		ctx.WriteString(" ")
 		ctx.FormatNode(&node.AsOf)
 	}
+	if node.RowsInto != nil {
+		ctx.WriteString(" INTO ")
+		ctx.FormatNode(node.RowsInto)
+	}
 	if !node.Options.IsDefault() {
 		if ctx.HasFlags(FmtHideConstants) {
 			ctx.WriteString(" WITH OPTIONS (")
@@ -319,6 +340,12 @@ func (o *BackupOptions) Format(ctx *FmtCtx) {
 		ctx.WriteString("include_all_virtual_clusters = ")
 		ctx.FormatNode(o.IncludeAllSecondaryTenants)
 	}
+
+	if o.ContentAddressed != nil {
+		maybeAddSep()
+		ctx.WriteString("content_addressed = ")
+		ctx.FormatNode(o.ContentAddressed)
+	}
 }
 
 // CombineWith merges other backup options into this backup options struct.
@@ -372,6 +399,14 @@ func (o *BackupOptions) CombineWith(other *BackupOptions) error {
 		o.IncludeAllSecondaryTenants = other.IncludeAllSecondaryTenants
 	}
 
+	if o.ContentAddressed != nil {
+		if other.ContentAddressed != nil {
+			return errors.New("content_addressed option specified multiple times")
+		}
+	} else {
+		o.ContentAddressed = other.ContentAddressed
+	}
+
 	return nil
 }
 
@@ -384,7 +419,8 @@ func (o BackupOptions) IsDefault() bool {
 		o.EncryptionPassphrase == options.EncryptionPassphrase &&
 		cmp.Equal(o.IncrementalStorage, options.IncrementalStorage) &&
 		o.ExecutionLocality == options.ExecutionLocality &&
-		o.IncludeAllSecondaryTenants == options.IncludeAllSecondaryTenants
+		o.IncludeAllSecondaryTenants == options.IncludeAllSecondaryTenants &&
+		o.ContentAddressed == options.ContentAddressed
 }
 
 // Format implements the NodeFormatter interface.
@@ -19,6 +19,10 @@ type Import struct {
 	Files      Exprs
 	Bundle     bool
 	Options    KVOptions
+	// SourceTable is set when importing directly from a table in a live
+	// database, e.g. IMPORT INTO t FROM POSTGRES 'postgres://...' TABLE src.
+	// In that case Files contains the connection URL of the source.
+	SourceTable *TableName
 }
 
 var _ Statement = &Import{}
@@ -37,7 +41,21 @@ func (node *Import) Format(ctx *FmtCtx) {
 		ctx.WriteByte(' ')
 		ctx.FormatNode(&node.Files)
 	} else {
-		if node.Into {
+		if node.SourceTable != nil {
+			ctx.WriteString("INTO ")
+			ctx.FormatNode(node.Table)
+			if node.IntoCols != nil {
+				ctx.WriteByte('(')
+				ctx.FormatNode(&node.IntoCols)
+				ctx.WriteByte(')')
+			}
+			ctx.WriteString(" FROM ")
+			ctx.WriteString(node.FileFormat)
+			ctx.WriteByte(' ')
+			ctx.FormatNode(&node.Files)
+			ctx.WriteString(" TABLE ")
+			ctx.FormatNode(node.SourceTable)
+		} else if node.Into {
 			ctx.WriteString("INTO ")
 			ctx.FormatNode(node.Table)
 			if node.IntoCols != nil {
@@ -2186,7 +2186,12 @@ func (node *Restore) doc(p *PrettyCfg) pretty.Doc {
 	items := make([]pretty.TableRow, 0, 6)
 
 	items = append(items, p.row("RESTORE", pretty.Nil))
-	if node.DescriptorCoverage == RequestedDescriptors {
+	if node.RowsTable != nil {
+		items = append(items, p.row("ROWS FROM", p.Doc(node.RowsTable)))
+		if node.RowsFilter != nil {
+			items = append(items, p.row("WHERE", p.Doc(node.RowsFilter)))
+		}
+	} else if node.DescriptorCoverage == RequestedDescriptors {
 		items = append(items, node.Targets.docRow(p))
 	}
 	from := make([]pretty.Doc, len(node.From))
@@ -2203,6 +2208,9 @@ func (node *Restore) doc(p *PrettyCfg) pretty.Doc {
 	if node.AsOf.Expr != nil {
 		items = append(items, node.AsOf.docRow(p))
 	}
+	if node.RowsInto != nil {
+		items = append(items, p.row("INTO", p.Doc(node.RowsInto)))
+	}
 	if !node.Options.IsDefault() {
 		items = append(items, p.row("WITH", p.Doc(&node.Options)))
 	}
@@ -2277,6 +2285,14 @@ func (node *Import) doc(p *PrettyCfg) pretty.Doc {
 			items = append(items, p.row("FROM", pretty.Nil))
 		}
 		items = append(items, p.row(node.FileFormat, p.Doc(&node.Files)))
+	} else if node.SourceTable != nil {
+		into := p.Doc(node.Table)
+		if node.IntoCols != nil {
+			into = p.nestUnder(into, p.bracket("(", p.Doc(&node.IntoCols), ")"))
+		}
+		items = append(items, p.row("INTO", into))
+		items = append(items, p.row("FROM", pretty.ConcatSpace(pretty.Keyword(node.FileFormat), p.Doc(&node.Files))))
+		items = append(items, p.row("TABLE", p.Doc(node.SourceTable)))
 	} else if node.Into {
 		into := p.Doc(node.Table)
 		if node.IntoCols != nil {
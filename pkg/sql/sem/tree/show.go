@@ -93,6 +93,13 @@ const (
 	BackupValidateDetails
 	// BackupConnectionTest identifies a SHOW BACKUP CONNECTION statement
 	BackupConnectionTest
+	// BackupTableRowsDetails identifies a SHOW BACKUP TABLE statement, which
+	// returns the rows of a table in a backup.
+	BackupTableRowsDetails
+	// BackupTableDiffDetails identifies a SHOW BACKUP DIFF TABLE statement,
+	// which returns the rows of a table that changed between two times covered
+	// by a backup.
+	BackupTableDiffDetails
 )
 
 // TODO (msbutler): 22.2 after removing old style show backup syntax, rename
@@ -107,6 +114,16 @@ type ShowBackup struct {
 	From         bool
 	Details      ShowBackupDetails
 	Options      ShowBackupOptions
+
+	// Table is only set for SHOW BACKUP TABLE and SHOW BACKUP DIFF TABLE, and
+	// AsOf only for the former.
+	Table *UnresolvedObjectName
+	AsOf  AsOfClause
+
+	// DiffStart and DiffEnd are the times between which SHOW BACKUP DIFF TABLE
+	// returns the changes.
+	DiffStart Expr
+	DiffEnd   Expr
 }
 
 // Format implements the NodeFormatter interface.
@@ -127,6 +144,14 @@ func (node *ShowBackup) Format(ctx *FmtCtx) {
 		ctx.WriteString("SCHEMAS ")
 	case BackupConnectionTest:
 		ctx.WriteString("CONNECTION ")
+	case BackupTableRowsDetails:
+		ctx.WriteString("TABLE ")
+		ctx.FormatNode(node.Table)
+		ctx.WriteString(" ")
+	case BackupTableDiffDetails:
+		ctx.WriteString("DIFF TABLE ")
+		ctx.FormatNode(node.Table)
+		ctx.WriteString(" ")
 	}
 
 	if node.From {
@@ -138,6 +163,16 @@ func (node *ShowBackup) Format(ctx *FmtCtx) {
 		ctx.WriteString(" IN ")
 		ctx.FormatNode(&node.InCollection)
 	}
+	if node.AsOf.Expr != nil {
+		ctx.WriteString(" ")
+		ctx.FormatNode(&node.AsOf)
+	}
+	if node.Details == BackupTableDiffDetails {
+		ctx.WriteString(" BETWEEN ")
+		ctx.FormatNode(node.DiffStart)
+		ctx.WriteString(" AND ")
+		ctx.FormatNode(node.DiffEnd)
+	}
 	if !node.Options.IsDefault() {
 		ctx.WriteString(" WITH ")
 		ctx.FormatNode(&node.Options)
@@ -236,6 +236,7 @@ type CCLOnlyStatement interface {
 
 var _ CCLOnlyStatement = &AlterBackup{}
 var _ CCLOnlyStatement = &AlterBackupSchedule{}
+var _ CCLOnlyStatement = &AttachBackup{}
 var _ CCLOnlyStatement = &Backup{}
 var _ CCLOnlyStatement = &ShowBackup{}
 var _ CCLOnlyStatement = &Restore{}
@@ -604,6 +605,17 @@ func (*Analyze) StatementType() StatementType { return TypeDDL }
 // StatementTag returns a short string identifying the type of statement.
 func (*Analyze) StatementTag() string { return "ANALYZE" }
 
+// StatementReturnType implements the Statement interface.
+func (*AttachBackup) StatementReturnType() StatementReturnType { return Ack }
+
+// StatementType implements the Statement interface.
+func (*AttachBackup) StatementType() StatementType { return TypeDDL }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*AttachBackup) StatementTag() string { return "ATTACH BACKUP" }
+
+func (*AttachBackup) cclOnlyStatement() {}
+
 // StatementReturnType implements the Statement interface.
 func (*Backup) StatementReturnType() StatementReturnType { return Rows }
 
@@ -2219,6 +2231,7 @@ func (n *AlterRole) String() string                           { return AsString(
 func (n *AlterRoleSet) String() string                        { return AsString(n) }
 func (n *AlterSequence) String() string                       { return AsString(n) }
 func (n *Analyze) String() string                             { return AsString(n) }
+func (n *AttachBackup) String() string                        { return AsString(n) }
 func (n *Backup) String() string                              { return AsString(n) }
 func (n *BeginTransaction) String() string                    { return AsString(n) }
 func (n *ControlJobs) String() string                         { return AsString(n) }
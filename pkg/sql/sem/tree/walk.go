@@ -1266,6 +1266,16 @@ func (stmt *Backup) walkStmt(v Visitor) Statement {
 		}
 	}
 
+	if stmt.Options.ContentAddressed != nil {
+		ca, changed := WalkExpr(v, stmt.Options.ContentAddressed)
+		if changed {
+			if ret == stmt {
+				ret = stmt.copyNode()
+			}
+			ret.Options.ContentAddressed = ca
+		}
+	}
+
 	if stmt.Options.ExecutionLocality != nil {
 		rh, changed := WalkExpr(v, stmt.Options.ExecutionLocality)
 		if changed {
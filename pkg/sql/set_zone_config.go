@@ -17,6 +17,7 @@ import (
 	"strings"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/clusterversion"
 	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/config/zonepb"
 	"github.com/cockroachdb/cockroach/pkg/keys"
@@ -114,6 +115,25 @@ func init() {
 			requiredType: types.Int,
 			setter:       func(c *zonepb.ZoneConfig, d tree.Datum) { c.NumVoters = proto.Int32(int32(tree.MustBeDInt(d))) },
 		},
+		{
+			field:        config.NumWitnesses,
+			requiredType: types.Int,
+			setter:       func(c *zonepb.ZoneConfig, d tree.Datum) { c.NumWitnesses = proto.Int32(int32(tree.MustBeDInt(d))) },
+		},
+		{
+			field:        config.MaxWriteBytesPerSecond,
+			requiredType: types.Int,
+			setter: func(c *zonepb.ZoneConfig, d tree.Datum) {
+				c.MaxWriteBytesPerSecond = proto.Int64(int64(tree.MustBeDInt(d)))
+			},
+		},
+		{
+			field:        config.MaxWriteRequestsPerSecond,
+			requiredType: types.Int,
+			setter: func(c *zonepb.ZoneConfig, d tree.Datum) {
+				c.MaxWriteRequestsPerSecond = proto.Int64(int64(tree.MustBeDInt(d)))
+			},
+		},
 		{
 			field:        config.GCTTL,
 			requiredType: types.Int,
@@ -743,6 +763,16 @@ func (n *setZoneConfigNode) startExec(params runParams) error {
 				}
 			}
 
+			// Nodes running older versions can't handle witness replicas, so
+			// they can't be requested until the whole cluster has been
+			// upgraded.
+			if finalZone.NumWitnesses != nil && *finalZone.NumWitnesses != 0 &&
+				!params.p.ExecCfg().Settings.Version.IsActive(params.ctx, clusterversion.V23_2_WitnessReplicas) {
+				return pgerror.Newf(pgcode.FeatureNotSupported,
+					"num_witnesses cannot be set until the cluster version is at least %s",
+					clusterversion.ByKey(clusterversion.V23_2_WitnessReplicas))
+			}
+
 			// Validate that there are no conflicts in the zone setup.
 			if err := validateNoRepeatKeysInZone(&newZone); err != nil {
 				return err
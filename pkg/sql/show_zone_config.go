@@ -226,6 +226,18 @@ func zoneConfigToSQL(zs *tree.ZoneSpecifier, zone *zonepb.ZoneConfig) (string, e
 		maybeWriteComma(f)
 		f.Printf("\tnum_voters = %d", *zone.NumVoters)
 	}
+	if zone.NumWitnesses != nil {
+		maybeWriteComma(f)
+		f.Printf("\tnum_witnesses = %d", *zone.NumWitnesses)
+	}
+	if zone.MaxWriteBytesPerSecond != nil {
+		maybeWriteComma(f)
+		f.Printf("\tmax_write_bytes_per_second = %d", *zone.MaxWriteBytesPerSecond)
+	}
+	if zone.MaxWriteRequestsPerSecond != nil {
+		maybeWriteComma(f)
+		f.Printf("\tmax_write_requests_per_second = %d", *zone.MaxWriteRequestsPerSecond)
+	}
 	if !zone.InheritedConstraints {
 		maybeWriteComma(f)
 		f.Printf("\tconstraints = %s", lexbase.EscapeSQLString(constraints))
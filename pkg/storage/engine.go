@@ -971,6 +971,11 @@ type Engine interface {
 	// GetEncryptionRegistries returns the file and key registries when encryption is enabled
 	// on the store.
 	GetEncryptionRegistries() (*EncryptionRegistries, error)
+	// RotateStoreEncryptionKey makes a newly generated key the active store
+	// key for encryption-at-rest, and returns its ID. It returns an error if
+	// the store isn't encrypted or its key source doesn't support online
+	// rotation.
+	RotateStoreEncryptionKey(ctx context.Context) (string, error)
 	// GetEnvStats retrieves stats about the engine's environment
 	// For RocksDB, this includes details of at-rest encryption.
 	GetEnvStats() (*EnvStats, error)
@@ -141,7 +141,12 @@ func TestMinVersion_IsNotEncrypted(t *testing.T) {
 }
 
 func fauxNewEncryptedEnvFunc(
-	fs vfs.FS, fr *PebbleFileRegistry, dbDir string, readOnly bool, optionBytes []byte,
+	fs vfs.FS,
+	fr *PebbleFileRegistry,
+	dbDir string,
+	readOnly bool,
+	optionBytes []byte,
+	st *cluster.Settings,
 ) (*EncryptionEnv, error) {
 	return &EncryptionEnv{
 		Closer: nopCloser{},
@@ -831,6 +831,18 @@ type EncryptionEnv struct {
 	FS vfs.FS
 	// StatsHandler exposes encryption-at-rest state for observability.
 	StatsHandler EncryptionStatsHandler
+	// KeyRotator rotates the store key while the store is running. It is nil
+	// if the store key source doesn't support online rotation, or if the
+	// store is read-only.
+	KeyRotator EncryptionKeyRotator
+}
+
+// EncryptionKeyRotator rotates the store key of an encryption-at-rest
+// environment.
+type EncryptionKeyRotator interface {
+	// RotateStoreKey makes a newly generated key the active store key,
+	// re-encrypts the data keys with it, and returns its ID.
+	RotateStoreKey(ctx context.Context) (string, error)
 }
 
 var _ Engine = &Pebble{}
@@ -843,7 +855,12 @@ var WorkloadCollectorEnabled = envutil.EnvOrDefaultBool("COCKROACH_STORAGE_WORKL
 // NewPebble(). The optionBytes is a binary serialized baseccl.EncryptionOptions, so that non-CCL
 // code does not depend on CCL code.
 var NewEncryptedEnvFunc func(
-	fs vfs.FS, fr *PebbleFileRegistry, dbDir string, readOnly bool, optionBytes []byte,
+	fs vfs.FS,
+	fr *PebbleFileRegistry,
+	dbDir string,
+	readOnly bool,
+	optionBytes []byte,
+	st *cluster.Settings,
 ) (*EncryptionEnv, error)
 
 // SetCompactionConcurrency will return the previous compaction concurrency.
@@ -931,6 +948,7 @@ func ResolveEncryptedEnvOptions(
 			cfg.Dir,
 			readOnly,
 			cfg.EncryptionOptions,
+			cfg.Settings,
 		)
 		if err != nil {
 			return nil, nil, err
@@ -1963,6 +1981,18 @@ func (p *Pebble) GetEncryptionRegistries() (*EncryptionRegistries, error) {
 	return rv, nil
 }
 
+// RotateStoreEncryptionKey implements the Engine interface.
+func (p *Pebble) RotateStoreEncryptionKey(ctx context.Context) (string, error) {
+	if p.encryption == nil {
+		return "", errors.New("store is not encrypted")
+	}
+	if p.encryption.KeyRotator == nil {
+		return "", errors.New("store key cannot be rotated online; " +
+			"use a KMS to wrap store keys, or restart with a new key file")
+	}
+	return p.encryption.KeyRotator.RotateStoreKey(ctx)
+}
+
 // GetEnvStats implements the Engine interface.
 func (p *Pebble) GetEnvStats() (*EnvStats, error) {
 	// TODO(sumeer): make the stats complete. There are no bytes stats. The TotalFiles is missing
@@ -775,13 +775,15 @@ func (p *pebbleMVCCScanner) maybeFailOnMoreRecent() {
 	p.intents.Reset()
 }
 
-// Returns an uncertainty error with the specified value and local timestamps,
-// along with context about the reader.
+// Returns an uncertainty error with the specified key, value and local
+// timestamps, along with context about the reader.
 func (p *pebbleMVCCScanner) uncertaintyError(
-	valueTs hlc.Timestamp, localTs hlc.ClockTimestamp,
+	key roachpb.Key, valueTs hlc.Timestamp, localTs hlc.ClockTimestamp,
 ) (ok bool) {
-	p.err = kvpb.NewReadWithinUncertaintyIntervalError(
+	err := kvpb.NewReadWithinUncertaintyIntervalError(
 		p.ts, p.uncertainty.LocalLimit, p.txn, valueTs, localTs)
+	err.Key = key.Clone()
+	p.err = err
 	p.results.clear()
 	p.intents.Reset()
 	return false
@@ -896,7 +898,7 @@ func (p *pebbleMVCCScanner) getOne(ctx context.Context) (ok, added bool) {
 			// errors.
 			localTS := p.curUnsafeValue.GetLocalTimestamp(p.curUnsafeKey.Timestamp)
 			if p.uncertainty.IsUncertain(p.curUnsafeKey.Timestamp, localTS) {
-				return p.uncertaintyError(p.curUnsafeKey.Timestamp, localTS), false
+				return p.uncertaintyError(p.curUnsafeKey.Key, p.curUnsafeKey.Timestamp, localTS), false
 			}
 
 			// This value is not within the reader's uncertainty window, but
@@ -1399,7 +1401,7 @@ func (p *pebbleMVCCScanner) seekVersion(
 			// is uncertain.
 			localTS := p.curUnsafeValue.GetLocalTimestamp(p.curUnsafeKey.Timestamp)
 			if p.uncertainty.IsUncertain(p.curUnsafeKey.Timestamp, localTS) {
-				return p.uncertaintyError(p.curUnsafeKey.Timestamp, localTS), false
+				return p.uncertaintyError(p.curUnsafeKey.Key, p.curUnsafeKey.Timestamp, localTS), false
 			}
 		}
 	}
@@ -1436,7 +1438,7 @@ func (p *pebbleMVCCScanner) seekVersion(
 		// error.
 		localTS := p.curUnsafeValue.GetLocalTimestamp(p.curUnsafeKey.Timestamp)
 		if p.uncertainty.IsUncertain(p.curUnsafeKey.Timestamp, localTS) {
-			return p.uncertaintyError(p.curUnsafeKey.Timestamp, localTS), false
+			return p.uncertaintyError(p.curUnsafeKey.Key, p.curUnsafeKey.Timestamp, localTS), false
 		}
 		if !p.iterNext() {
 			p.setAdvanceKeyAtEnd()
@@ -1546,7 +1548,7 @@ func (p *pebbleMVCCScanner) processRangeKeys(seeked bool, reverse bool) bool {
 					}
 					localTS := value.GetLocalTimestamp(version.Timestamp)
 					if p.uncertainty.IsUncertain(version.Timestamp, localTS) {
-						return p.uncertaintyError(version.Timestamp, localTS)
+						return p.uncertaintyError(p.parent.UnsafeKey().Key, version.Timestamp, localTS)
 					}
 				}
 			}
@@ -0,0 +1,124 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package avro
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cockroachdb/apd/v3"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/randutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecimalRatRoundtrip(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	t.Run(`table`, func(t *testing.T) {
+		tests := []struct {
+			scale int32
+			dec   *apd.Decimal
+		}{
+			{0, apd.New(0, 0)},
+			{0, apd.New(1, 0)},
+			{0, apd.New(-1, 0)},
+			{0, apd.New(123, 0)},
+			{1, apd.New(0, -1)},
+			{1, apd.New(1, -1)},
+			{1, apd.New(123, -1)},
+			{5, apd.New(1, -5)},
+		}
+		for d, test := range tests {
+			rat, err := DecimalToRat(*test.dec, test.scale)
+			require.NoError(t, err)
+			roundtrip := RatToDecimal(rat, test.scale)
+			if test.dec.CmpTotal(&roundtrip) != 0 {
+				t.Errorf(`%d: %s != %s`, d, test.dec, &roundtrip)
+			}
+		}
+	})
+	t.Run(`error`, func(t *testing.T) {
+		_, err := DecimalToRat(*apd.New(1, -2), 1)
+		require.EqualError(t, err, "0.01 will not roundtrip at scale 1")
+		_, err = DecimalToRat(*apd.New(1, -1), 2)
+		require.EqualError(t, err, "0.1 will not roundtrip at scale 2")
+		_, err = DecimalToRat(apd.Decimal{Form: apd.Infinite}, 0)
+		require.EqualError(t, err, "cannot convert Infinite form decimal")
+	})
+	t.Run(`rand`, func(t *testing.T) {
+		rng, _ := randutil.NewTestRand()
+		precision := rng.Int31n(10) + 1
+		scale := rng.Int31n(precision + 1)
+		coeff := rng.Int63n(int64(math.Pow10(int(precision))))
+		dec := apd.New(coeff, -scale)
+		rat, err := DecimalToRat(*dec, scale)
+		require.NoError(t, err)
+		roundtrip := RatToDecimal(rat, scale)
+		if dec.CmpTotal(&roundtrip) != 0 {
+			t.Errorf(`%s != %s`, dec, &roundtrip)
+		}
+	})
+}
+
+func TestEncodeNew(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	t.Run(`array`, func(t *testing.T) {
+		field, err := TypeToSchema(types.IntArray)
+		require.NoError(t, err)
+		arr := func(vals ...int) tree.Datum {
+			d := tree.NewDArray(types.Int)
+			for _, v := range vals {
+				require.NoError(t, d.Append(tree.NewDInt(tree.DInt(v))))
+			}
+			return d
+		}
+
+		// The values returned by Encode are overwritten by the next call.
+		memo, err := field.Encode(arr(1, 2))
+		require.NoError(t, err)
+		_, err = field.Encode(arr(3, 4))
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{`array`: []interface{}{
+			map[string]interface{}{`long`: int64(3)}, map[string]interface{}{`long`: int64(4)},
+		}}, memo)
+
+		// The values returned by EncodeNew are not.
+		first, err := field.EncodeNew(arr(1, 2))
+		require.NoError(t, err)
+		_, err = field.EncodeNew(arr(3, 4))
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{`array`: []interface{}{
+			map[string]interface{}{`long`: int64(1)}, map[string]interface{}{`long`: int64(2)},
+		}}, first)
+
+		null, err := field.EncodeNew(tree.DNull)
+		require.NoError(t, err)
+		require.Nil(t, null)
+	})
+
+	t.Run(`string-fallback`, func(t *testing.T) {
+		field, err := TypeToSchema(types.MakeDecimal(3, 1))
+		require.NoError(t, err)
+		nan, err := field.EncodeNew(&tree.DDecimal{Decimal: apd.Decimal{Form: apd.NaN}})
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{`string`: `NaN`}, nan)
+		dec, err := field.EncodeNew(&tree.DDecimal{Decimal: *apd.New(15, -1)})
+		require.NoError(t, err)
+		require.Contains(t, dec, `bytes.decimal`)
+	})
+}